@@ -3,6 +3,8 @@ package ebpf
 import (
 	"context"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/aquasecurity/tracee/pkg/containers"
 	"github.com/aquasecurity/tracee/pkg/events"
@@ -23,6 +25,51 @@ func (t *Tracee) initDerivationTable() error {
 	pathResolver := containers.InitPathResolver(&t.pidsInMntns)
 	soLoader := sharedobjs.InitContainersSymbolsLoader(&pathResolver, 1024)
 
+	// symbols_unreadable, packed_object_loaded, symbols_extraction_slow, weak_symbol_overridden,
+	// soname_build_id_seen, soname_path_changed, symbols_loaded_profile and symbols_capability_gained depend on
+	// symbols_loaded, so the generator is initialized if any of them is needed
+	var symbolsLoadedFunc, symbolsUnreadableFunc, packedObjectLoadedFunc, symbolsExtractionSlowFunc,
+		weakSymbolOverriddenFunc, sonameBuildIDSeenFunc, sonamePathChangedFunc, symbolsLoadedProfileFunc,
+		symbolsCapabilityGainedFunc events.DeriveFunction
+	if t.events[events.SymbolsLoaded].submit {
+		symbolsLoadedFilters := t.config.Filter.ArgFilter.Filters[events.SymbolsLoaded]
+		// The events depending on symbols_loaded are derived with no watched symbols, so only a selected
+		// symbols_loaded event with no symbols is a mistake
+		if t.events[events.SymbolsLoaded].emit && len(symbolsLoadedFilters["symbols"].Equal) == 0 {
+			fmt.Fprintf(os.Stderr, "symbols_loaded is selected with no symbols filter, so it will never be derived\n")
+		}
+		var summaryInterval time.Duration
+		if t.events[events.SymbolsLoadedSummary].submit {
+			summaryInterval = derive.DefaultSummaryInterval
+		}
+		symbolsLoadedGen, err := derive.InitSymbolsLoadedEventGenerator(
+			soLoader,
+			derive.SymbolsLoadedConfig{
+				WatchedSymbols:    symbolsLoadedFilters["symbols"].Equal,
+				ExcludedSymbols:   symbolsLoadedFilters["symbols"].NotEqual,
+				WhitelistedLibs:   symbolsLoadedFilters["library_path"].NotEqual,
+				SummaryInterval:   summaryInterval,
+				TrackBuildIDs:     t.events[events.SonameBuildIDSeen].submit,
+				TrackSonamePaths:  t.events[events.SonamePathChanged].submit,
+				ProfileProcesses:  t.events[events.SymbolsLoadedProfile].submit,
+				TrackCapabilities: t.events[events.SymbolsCapabilityGained].submit,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		t.symbolsLoadedGen = symbolsLoadedGen
+		symbolsLoadedFunc = derive.SymbolsLoadedFromGenerator(symbolsLoadedGen)
+		symbolsUnreadableFunc = derive.SymbolsUnreadable(symbolsLoadedGen)
+		packedObjectLoadedFunc = derive.PackedObjectLoaded(symbolsLoadedGen)
+		symbolsExtractionSlowFunc = derive.SymbolsExtractionSlow(symbolsLoadedGen)
+		weakSymbolOverriddenFunc = derive.WeakSymbolOverridden(symbolsLoadedGen)
+		sonameBuildIDSeenFunc = derive.SonameBuildIDSeen(symbolsLoadedGen)
+		sonamePathChangedFunc = derive.SonamePathChanged(symbolsLoadedGen)
+		symbolsLoadedProfileFunc = derive.SymbolsLoadedProfile(symbolsLoadedGen)
+		symbolsCapabilityGainedFunc = derive.SymbolsCapabilityGained(symbolsLoadedGen)
+	}
+
 	t.eventDerivations = events.DerivationTable{
 		events.CgroupMkdir: {
 			events.ContainerCreate: {
@@ -60,14 +107,44 @@ func (t *Tracee) initDerivationTable() error {
 				Function: derive.HookedSeqOps(t.kernelSymbols),
 			},
 		},
+		events.SchedProcessExit: {
+			events.SymbolsLoadedProfile: {
+				Enabled:  t.events[events.SymbolsLoadedProfile].submit,
+				Function: symbolsLoadedProfileFunc,
+			},
+		},
 		events.SharedObjectLoaded: {
 			events.SymbolsLoaded: {
-				Enabled: t.events[events.SymbolsLoaded].submit,
-				Function: derive.SymbolsLoaded(
-					soLoader,
-					t.config.Filter.ArgFilter.Filters[events.SymbolsLoaded]["symbols"].Equal,
-					t.config.Filter.ArgFilter.Filters[events.SymbolsLoaded]["library_path"].NotEqual,
-				),
+				Enabled:  t.events[events.SymbolsLoaded].submit,
+				Function: symbolsLoadedFunc,
+			},
+			events.SymbolsUnreadable: {
+				Enabled:  t.events[events.SymbolsUnreadable].submit,
+				Function: symbolsUnreadableFunc,
+			},
+			events.PackedObjectLoaded: {
+				Enabled:  t.events[events.PackedObjectLoaded].submit,
+				Function: packedObjectLoadedFunc,
+			},
+			events.SymbolsExtractionSlow: {
+				Enabled:  t.events[events.SymbolsExtractionSlow].submit,
+				Function: symbolsExtractionSlowFunc,
+			},
+			events.WeakSymbolOverridden: {
+				Enabled:  t.events[events.WeakSymbolOverridden].submit,
+				Function: weakSymbolOverriddenFunc,
+			},
+			events.SonameBuildIDSeen: {
+				Enabled:  t.events[events.SonameBuildIDSeen].submit,
+				Function: sonameBuildIDSeenFunc,
+			},
+			events.SonamePathChanged: {
+				Enabled:  t.events[events.SonamePathChanged].submit,
+				Function: sonamePathChangedFunc,
+			},
+			events.SymbolsCapabilityGained: {
+				Enabled:  t.events[events.SymbolsCapabilityGained].submit,
+				Function: symbolsCapabilityGainedFunc,
 			},
 		},
 	}
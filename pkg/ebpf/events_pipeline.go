@@ -56,6 +56,12 @@ func (t *Tracee) handleEvents(ctx gocontext.Context) {
 	eventsChan, errc = t.deriveEvents(ctx, eventsChan)
 	errcList = append(errcList, errc)
 
+	// Summary events are emitted periodically rather than derived from other events, so they are merged into the
+	// stream of derived events
+	if t.symbolsLoadedGen != nil && t.symbolsLoadedGen.Summaries() != nil {
+		eventsChan = t.mergeEvents(ctx, eventsChan, t.symbolsLoadedGen.Summaries())
+	}
+
 	// Sink pipeline stage.
 	errc = t.sinkEvents(ctx, eventsChan)
 	errcList = append(errcList, errc)
@@ -260,6 +266,40 @@ func (t *Tracee) processEvents(ctx gocontext.Context, in <-chan *trace.Event) (<
 	return out, errc
 }
 
+// mergeEvents merges the events of the periodic events channel into the events stream.
+// The merged stream is closed when the events stream is closed.
+func (t *Tracee) mergeEvents(ctx gocontext.Context, in <-chan *trace.Event, periodic <-chan trace.Event) <-chan *trace.Event {
+	out := make(chan *trace.Event, 10000)
+	go func() {
+		defer close(out)
+		for {
+			var event *trace.Event
+			select {
+			case e, ok := <-in:
+				if !ok {
+					return
+				}
+				event = e
+			case e, ok := <-periodic:
+				if !ok {
+					// Reading from a nil channel blocks, so only the events stream is read from now on
+					periodic = nil
+					continue
+				}
+				event = &e
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
 func (t *Tracee) sinkEvents(ctx gocontext.Context, in <-chan *trace.Event) <-chan error {
 	errc := make(chan error, 1)
 
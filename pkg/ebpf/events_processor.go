@@ -264,6 +264,11 @@ func (t *Tracee) processEvent(event *trace.Event) error {
 			return err
 		}
 	case events.SchedProcessExit:
+		if t.symbolsLoadedGen != nil {
+			if groupExit, err := parse.ArgBoolVal(event, "process_group_exit"); err == nil && groupExit {
+				t.symbolsLoadedGen.ProcessExited(event.HostProcessID)
+			}
+		}
 		if t.config.ProcessInfo {
 			if t.config.Capture.NetPerProcess {
 				pcapContext, _, err := t.getPcapContextFromTid(uint32(event.HostThreadID))
@@ -29,6 +29,7 @@ import (
 	"github.com/aquasecurity/tracee/pkg/ebpf/initialization"
 	"github.com/aquasecurity/tracee/pkg/ebpf/probes"
 	"github.com/aquasecurity/tracee/pkg/events"
+	"github.com/aquasecurity/tracee/pkg/events/derive"
 	"github.com/aquasecurity/tracee/pkg/events/queue"
 	"github.com/aquasecurity/tracee/pkg/events/sorting"
 	"github.com/aquasecurity/tracee/pkg/metrics"
@@ -201,6 +202,7 @@ type Tracee struct {
 	eventsSorter      *sorting.EventsChronologicalSorter
 	eventDerivations  events.DerivationTable
 	kernelSymbols     *helpers.KernelSymbolTable
+	symbolsLoadedGen  *derive.SymbolsLoadedEventGenerator
 	running           bool
 }
 
@@ -1056,6 +1058,13 @@ func (t *Tracee) Close() {
 			fmt.Fprintf(os.Stderr, "failed to clean containers module when closing tracee: %s", err)
 		}
 	}
+
+	if t.symbolsLoadedGen != nil {
+		err := t.symbolsLoadedGen.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to close symbols_loaded generator when closing tracee: %s", err)
+		}
+	}
 	t.running = false
 }
 
@@ -15,6 +15,8 @@ type eventSkeleton struct {
 	Name   string
 	ID     int
 	Params []trace.ArgMeta
+	// The arguments values are checked to be of the Go types of their metadata types (see newTypedArgument)
+	Typed bool
 }
 
 // deriveArgsFunction is the main logic of the derived event.
@@ -28,7 +30,13 @@ type deriveArgsFunction func(event trace.Event) ([]interface{}, error)
 // the order of the params in the events.event struct of the event under events.Definitions.
 // If the arguments given is nil, than no event will be derived.
 func singleEventDeriveFunc(id events.ID, deriveArgsFunc deriveArgsFunction) events.DeriveFunction {
-	skeleton := makeEventSkeleton(id)
+	return singleSkeletonDeriveFunc(makeEventSkeleton(id), deriveArgsFunc)
+}
+
+// singleSkeletonDeriveFunc is like singleEventDeriveFunc, but uses the given skeleton instead of the one of the
+// event definition.
+// This is useful for derived events which add optional arguments to the ones in their definition.
+func singleSkeletonDeriveFunc(skeleton eventSkeleton, deriveArgsFunc deriveArgsFunction) events.DeriveFunction {
 	return func(event trace.Event) ([]trace.Event, []error) {
 		args, err := deriveArgsFunc(event)
 		if err != nil {
@@ -56,9 +64,17 @@ func newEvent(baseEvent *trace.Event, skeleton eventSkeleton, argsValues []inter
 	de.EventName = skeleton.Name
 	de.ReturnValue = 0
 	de.StackAddresses = make([]uint64, 1)
-	de.Args = make([]trace.Argument, len(skeleton.Params))
-	for i, value := range argsValues {
-		de.Args[i] = trace.Argument{ArgMeta: skeleton.Params[i], Value: value}
+	if skeleton.Typed {
+		args, err := newTypedArguments(skeleton.Params, argsValues)
+		if err != nil {
+			return trace.Event{}, fmt.Errorf("error while building derived event '%s' - %v", skeleton.Name, err)
+		}
+		de.Args = args
+	} else {
+		de.Args = make([]trace.Argument, len(skeleton.Params))
+		for i, value := range argsValues {
+			de.Args[i] = trace.Argument{ArgMeta: skeleton.Params[i], Value: value}
+		}
 	}
 	de.ArgsNum = len(de.Args)
 	return de, nil
@@ -1,17 +1,73 @@
 package derive
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+	"time"
+
 	"github.com/aquasecurity/tracee/pkg/events"
-	"github.com/aquasecurity/tracee/pkg/events/parse"
 	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
 	"github.com/aquasecurity/tracee/types/trace"
-	"path"
-	"strings"
 )
 
-func SymbolsLoaded(soLoader sharedobjs.DynamicSymbolsLoader, watchedSymbols []string, whitelistedLibsPrefixes []string) events.DeriveFunction {
-	gen := initSymbolsLoadedEventGenerator(soLoader, watchedSymbols, whitelistedLibsPrefixes)
-	return singleEventDeriveFunc(events.SymbolsLoaded, gen.deriveArgs)
+// symbolsLoadedSettings is the configuration of the symbols_loaded event derivation, built by the options of
+// NewSymbolsLoadedGenerator. The options of its features are grouped by what the features do, and new options should
+// be added to the group of their feature.
+type symbolsLoadedSettings struct {
+	Matching   SymbolsMatchingConfig   // Which symbols are watched, and in which SOs
+	Detection  SymbolsDetectionConfig  // Properties of SOs flagged beyond the names of their symbols
+	Reporting  SymbolsReportingConfig  // What is added to the derived event
+	Tracking   SymbolsTrackingConfig   // State kept across the examined SOs
+	Extraction SymbolsExtractionConfig // How the SOs are examined
+	// Name of the event to derive instead of symbols_loaded. The event must be defined, and its arguments must
+	// start with the symbols_loaded arguments.
+	EventName string
+	Logger    SymbolsLoadedLogger // Receives the decisions taken for each SO. If nil, nothing is logged
+	// The source of time of the extraction deadline and the summaries interval. If nil, the sharedobjs.SystemClock
+	// is used.
+	Clock sharedobjs.Clock
+}
+
+// SymbolsLoadedFromGenerator receives a generator as a closure argument, which holds the configuration of the event.
+// If it receives a shared_object_loaded event, it can derive a symbols_loaded event from it.
+func SymbolsLoadedFromGenerator(gen *SymbolsLoadedEventGenerator) events.DeriveFunction {
+	if gen.asyncQueue != nil {
+		return singleSkeletonDeriveFunc(gen.skeleton, gen.enqueueArgs)
+	}
+	if gen.pending != nil {
+		return func(event trace.Event) ([]trace.Event, []error) {
+			return gen.deriveCorrelated(event, true)
+		}
+	}
+	return singleSkeletonDeriveFunc(gen.skeleton, gen.withDeadline(gen.deriveArgs))
+}
+
+// SymbolsUnreadable receives the generator of the symbols_loaded event as a closure argument.
+// If it receives a shared_object_loaded event of a SO which the generator has no permissions to read, it
+// derives a symbols_unreadable event from it, to inform of the coverage gap of the symbols_loaded event.
+func SymbolsUnreadable(gen *SymbolsLoadedEventGenerator) events.DeriveFunction {
+	return singleSkeletonDeriveFunc(makeTypedEventSkeleton(events.SymbolsUnreadable), gen.withDeadline(gen.deriveUnreadableArgs))
+}
+
+// SymbolsExtractionSlow receives the generator of the symbols_loaded event as a closure argument.
+// If it receives a shared_object_loaded event of a SO whose symbols extraction took longer than the configured
+// threshold, it derives a symbols_extraction_slow event from it, once for each extraction.
+func SymbolsExtractionSlow(gen *SymbolsLoadedEventGenerator) events.DeriveFunction {
+	return singleSkeletonDeriveFunc(makeTypedEventSkeleton(events.SymbolsExtractionSlow),
+		gen.withDeadline(gen.deriveSlowExtractionArgs))
+}
+
+// DefaultSlowExtractionThreshold is the default minimal duration of a slow SO symbols extraction
+const DefaultSlowExtractionThreshold = 100 * time.Millisecond
+
+// PackedObjectLoaded receives the generator of the symbols_loaded event as a closure argument.
+// If it receives a shared_object_loaded event of a SO which is packed (e.g. by UPX), it derives a
+// packed_object_loaded event from it, as the symbols_loaded event can't examine the symbols of such SOs.
+func PackedObjectLoaded(gen *SymbolsLoadedEventGenerator) events.DeriveFunction {
+	return singleSkeletonDeriveFunc(makeTypedEventSkeleton(events.PackedObjectLoaded), gen.withDeadline(gen.derivePackedArgs))
 }
 
 // Most specific paths should be at the top, to prevent bugs with iterations over the list
@@ -23,119 +79,546 @@ var knownLibrariesDirs = []string{
 	"/lib/",
 }
 
-// symbolsLoadedEventGenerator is responsible of generating event if shared object loaded to a process
+// SymbolsLoadedEventGenerator is responsible of generating event if shared object loaded to a process
 // export one or more from given watched sybmols.
-type symbolsLoadedEventGenerator struct {
-	soLoader            sharedobjs.DynamicSymbolsLoader
-	watchedSymbols      map[string]bool
-	pathPrefixWhitelist []string
-	librariesWhitelist  []string
+type SymbolsLoadedEventGenerator struct {
+	symbolsExtractor // How the SOs are examined
+	symbolsMatcher   // Which symbols are watched, and in which SOs
+	symbolsDetector  // Properties of SOs flagged beyond the names of their symbols
+	symbolsReporter  // What is added to the derived event
+	symbolsTracker   // State kept across the examined SOs
+	// The loaders shared by several features, set only if a configured feature needs them
+	symbolsInfoLoader   sharedobjs.SymbolsInfoLoader
+	symbolChecker       sharedobjs.ExportedSymbolChecker // Set only if the watched symbols are checked one by one
+	importsInfoLoader   sharedobjs.ImportsInfoLoader
+	interpreterDetector sharedobjs.InterpreterDetector
+	sonameLoader        sharedobjs.SonameLoader
+	stats               SymbolsLoadedStats
+	skeleton            eventSkeleton
+	extraArgs           []symbolsLoadedExtraArg
+	eventID             events.ID
+	logger              SymbolsLoadedLogger
+	clock               sharedobjs.Clock
+	closeMutex          sync.RWMutex // Held for reading by derivations in progress, and for writing by Close
+	closed              bool
+	disabled            int32 // Set atomically by SetEnabled
 }
 
-func initSymbolsLoadedEventGenerator(
+// symbolsMatch is the result of matching the watched symbols with the symbols of a loaded SO
+type symbolsMatch struct {
+	objInfo     sharedobjs.ObjInfo
+	symbols     []string
+	symbolsInfo []sharedobjs.SymbolInfo         // The information of the matched symbols, if it was loaded
+	rules       []string                        // The names of the matched rules
+	imports     []string                        // The matched watched imports
+	tls         []string                        // The matched watched TLS symbols
+	versioned   []string                        // The matched watched versioned symbols entries
+	demangled   []string                        // The matched watched demangled symbols
+	importsInfo []sharedobjs.ImportedSymbolInfo // The information of the matched imports, if it was loaded
+	total       int                             // The amount of matched symbols, before truncation
+	missing     []string                        // The expected symbols which the SO doesn't export
+	groups      []string                        // The names of the matched watch groups
+	symbolSet   string                          // The name of the matched symbol set, if any
+	setRatio    float64                         // The part of the symbols of the matched set which the SO exports
+	wxSegments  []sharedobjs.Segment            // The segments which are both writable and executable, if examined
+	dynamicTags []string                        // The names of the flagged dynamic tags the SO declares
+	canonical   []string                        // The canonical name of each of the reported symbols, if aliased
+	suspicious  string                          // The suspicious directory the SO was loaded from, if any
+	sequence    uint64                          // The sequence number of the load in the process, if reported
+	exported    int                             // The amount of symbols exported by the SO, if reported
+	fingerprint string                          // The fingerprint of the symbols exported by the SO, if reported
+	initArray   int                             // The amount of init array entries of the SO, if reported
+	interp      string                          // The interpreter the SO requests, if examined
+	partial     bool                            // Whether only the first symbols of the SO were read, if reported
+	entropy     float64                         // The entropy of the code of the SO, if high entropy is flagged
+	relocCounts []string                        // The counts of the relocation types with thresholds ("<type>=<count>")
+	overLimit   []string                        // The relocation types whose count exceeds their threshold
+	unexpected  []string                        // The matched symbols at an unexpected index ("<symbol>=<index>")
+	soname      string                          // The DT_SONAME of the SO, in the metadata only mode
+	buildID     string                          // The GNU build ID of the SO, in the metadata only mode
+	interpreter bool                            // Whether the SO is the dynamic loader, if it is reported
+	changed     bool                            // Whether the match changed since the last load, if tracked
+	confidence  float64                         // The confidence score of the match, if reported
+	enriched    map[string]interface{}          // The fields returned by the enrichment callback, if configured
+	passthrough []interface{}                   // The values of the passed through arguments, if configured
+	uid         int                             // The UID of the process which loaded the SO
+	container   symbolsContainer                // The container of the process which loaded the SO
+	truncated   bool
+}
+
+// truncate limits the amount of matched symbols to the given maximum.
+// The symbols are sorted before truncation (whether or not they exceed the maximum), so the same symbols are reported
+// in the same order for the same SO.
+func (match *symbolsMatch) truncate(maxSymbols int) {
+	match.total = len(match.symbols)
+	indexes := make([]int, len(match.symbols))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return match.symbols[indexes[i]] < match.symbols[indexes[j]]
+	})
+	kept := len(indexes)
+	if maxSymbols > 0 && kept > maxSymbols {
+		kept = maxSymbols
+		match.truncated = true
+	}
+	symbols := make([]string, kept)
+	for i := range symbols {
+		symbols[i] = match.symbols[indexes[i]]
+	}
+	if len(match.symbolsInfo) == len(match.symbols) {
+		symbolsInfo := make([]sharedobjs.SymbolInfo, kept)
+		for i := range symbolsInfo {
+			symbolsInfo[i] = match.symbolsInfo[indexes[i]]
+		}
+		match.symbolsInfo = symbolsInfo
+	}
+	if match.symbols != nil {
+		match.symbols = symbols
+	}
+}
+
+// symbolsLoadedExtraArg is an optional argument of the derived event, which is added after the arguments in
+// the event definition according to the configuration.
+type symbolsLoadedExtraArg struct {
+	meta  trace.ArgMeta
+	value func(match *symbolsMatch) interface{}
+}
+
+// newSymbolsLoadedEventGenerator creates a generator with the given configuration, after validating it
+func newSymbolsLoadedEventGenerator(
 	soLoader sharedobjs.DynamicSymbolsLoader,
-	watchedSymbols []string,
-	whitelistedLibsPrefixes []string) *symbolsLoadedEventGenerator {
-	watchedSymbolsMap := make(map[string]bool)
-	for _, sym := range watchedSymbols {
-		watchedSymbolsMap[sym] = true
-	}
-	var libraries, prefixes []string
-	for _, path := range whitelistedLibsPrefixes {
-		if strings.HasPrefix(path, "/") {
-			prefixes = append(prefixes, path)
-		} else {
-			libraries = append(libraries, path)
+	config symbolsLoadedSettings) (*SymbolsLoadedEventGenerator, error) {
+	if len(config.Matching.WhitelistFiles) > 0 {
+		merged, err := mergeWhitelistFiles(
+			whitelistEntries{libs: config.Matching.WhitelistedLibs, regexps: config.Matching.WhitelistedRegexps},
+			config.Matching.WhitelistFiles)
+		if err != nil {
+			return nil, fmt.Errorf("invalid symbols_loaded whitelist files: %v", err)
+		}
+		config.Matching.WhitelistedLibs, config.Matching.WhitelistedRegexps = merged.libs, merged.regexps
+		// The merged entries are validated like the configured ones
+		config.Matching.WhitelistFiles = nil
+	}
+	if problems := validateSettings(config); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid symbols_loaded configuration: %v", problems)
+	}
+	gen := &SymbolsLoadedEventGenerator{
+		eventID: events.SymbolsLoaded,
+		logger:  config.Logger,
+		clock:   config.Clock,
+	}
+	if gen.clock == nil {
+		gen.clock = sharedobjs.SystemClock
+	}
+	if gen.logger == nil {
+		gen.logger = nopSymbolsLoadedLogger{}
+	}
+	// The features read the SOs through the loader of the extractor
+	if err := gen.symbolsExtractor.init(soLoader, config.Extraction); err != nil {
+		return nil, err
+	}
+	if err := gen.symbolsMatcher.init(gen.soLoader, config.Matching); err != nil {
+		return nil, err
+	}
+	if err := gen.symbolsDetector.init(gen.soLoader, config.Detection, config.Reporting.ReportConfidence); err != nil {
+		return nil, err
+	}
+	if err := gen.symbolsReporter.init(gen.soLoader, config.Reporting, gen.hashedSymbols()); err != nil {
+		return nil, err
+	}
+	if err := gen.symbolsTracker.init(gen.soLoader, config.Tracking); err != nil {
+		return nil, err
+	}
+	if err := gen.initSharedLoaders(config); err != nil {
+		return nil, err
+	}
+
+	// The events depending on the generator (e.g. symbols_unreadable) are derived with nothing watched too
+	if nothingWatched(config) {
+		gen.log(LogLevelWarn, DecisionNothingWatched, sharedobjs.ObjInfo{},
+			"no watched symbols or rules given - the symbols_loaded event will never be derived")
+	}
+	if config.EventName != "" {
+		gen.eventID = events.Definitions.NamesToIDs()[config.EventName]
+	}
+	gen.skeleton = makeTypedEventSkeleton(gen.eventID)
+	if err := gen.addExtraArgs(config); err != nil {
+		return nil, err
+	}
+
+	if gen.summary != nil {
+		gen.startSummaries(config.Tracking.SummaryInterval)
+	}
+	if config.Extraction.AsyncQueueSize > 0 {
+		gen.startAsync(config.Extraction.AsyncQueueSize)
+	}
+	return gen, nil
+}
+
+// initSharedLoaders sets the loaders which are shared by several features, if a configured feature needs them
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) initSharedLoaders(config symbolsLoadedSettings) error {
+	soLoader := symbsLoadedGen.soLoader
+	if config.Reporting.ReportPLTSlots || hasVersionedImports(config.Matching.WatchedImports) ||
+		symbsLoadedGen.libraryImports != nil {
+		infoLoader, ok := soLoader.(sharedobjs.ImportsInfoLoader)
+		if !ok {
+			return importsInfoProblem(config)
+		}
+		symbsLoadedGen.importsInfoLoader = infoLoader
+	}
+
+	if config.Matching.Interpreter != InterpreterDefault || config.Reporting.ReportInterpreter {
+		detector, ok := soLoader.(sharedobjs.InterpreterDetector)
+		if !ok {
+			return fmt.Errorf("dynamic loader detection is configured, but the SO loader can't detect it")
+		}
+		symbsLoadedGen.interpreterDetector = detector
+	}
+
+	if len(config.Matching.BaselineSymbols) > 0 || len(config.Detection.ExpectedSymbols) > 0 ||
+		len(config.Detection.WeakSymbols) > 0 || config.Tracking.TrackBuildIDs || config.Tracking.TrackSonamePaths ||
+		config.Reporting.Enrichment.Enrich != nil {
+		sonameLoader, ok := soLoader.(sharedobjs.SonameLoader)
+		if !ok {
+			return fmt.Errorf("symbols by soname are configured, but the SO loader can't read sonames")
 		}
+		symbsLoadedGen.sonameLoader = sonameLoader
 	}
-	return &symbolsLoadedEventGenerator{
-		soLoader:            soLoader,
-		watchedSymbols:      watchedSymbolsMap,
-		pathPrefixWhitelist: prefixes,
-		librariesWhitelist:  libraries,
+
+	// Checking the watched symbols one by one doesn't count (or hash) the exported symbols
+	if checker, ok := soLoader.(sharedobjs.ExportedSymbolChecker); ok && symbsLoadedGen.countBoundaries == nil &&
+		symbsLoadedGen.fingerprints == nil &&
+		len(symbsLoadedGen.watchedSymbols)+len(symbsLoadedGen.librarySymbols) <= maxCheckedWatchedSymbols {
+		symbsLoadedGen.symbolChecker = checker
+	}
+
+	if symbsLoadedGen.needsSymbolsInfo(config) {
+		infoLoader, ok := soLoader.(sharedobjs.SymbolsInfoLoader)
+		if !ok {
+			return fmt.Errorf("symbols information is configured, but the SO loader doesn't supply symbols information")
+		}
+		symbsLoadedGen.symbolsInfoLoader = infoLoader
+	} else if infoLoader, ok := soLoader.(sharedobjs.SymbolsInfoLoader); ok && config.Reporting.ReportConfidence {
+		// The bind and section signals of the confidence are known only if the information is loaded
+		symbsLoadedGen.symbolsInfoLoader = infoLoader
+	}
+	return nil
+}
+
+// importsInfoProblem returns the error of the first configured feature which needs the imports information
+func importsInfoProblem(config symbolsLoadedSettings) error {
+	switch {
+	case config.Reporting.ReportPLTSlots:
+		return fmt.Errorf("PLT slots reporting is configured, but the SO loader doesn't supply imports information")
+	case hasVersionedImports(config.Matching.WatchedImports):
+		return fmt.Errorf("versioned watched imports are configured, but the SO loader doesn't supply imports information")
+	default:
+		return fmt.Errorf("library limited watched imports are configured, but the SO loader doesn't supply imports information")
+	}
+}
+
+// needsSymbolsInfo checks if a configured feature matches or reports the information of the matched symbols
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) needsSymbolsInfo(config symbolsLoadedSettings) bool {
+	matching := symbsLoadedGen.watchedVisibilities != nil || symbsLoadedGen.executableOnly ||
+		symbsLoadedGen.watchedTLS != nil || symbsLoadedGen.watchedVersioned != nil
+	detection := symbsLoadedGen.expectedIndexes != nil || symbsLoadedGen.expectedSizes != nil
+	reporting := config.Reporting.ReportVisibility || config.Reporting.ReportSection ||
+		config.Reporting.ReportSymbolIndex || config.Reporting.ReportSymbolSize
+	return matching || detection || reporting
+}
+
+// EventID returns the ID of the event derived by the generator
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) EventID() events.ID {
+	return symbsLoadedGen.eventID
+}
+
+// addExtraArg adds an optional argument to the derived event
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) addExtraArg(meta trace.ArgMeta, value func(match *symbolsMatch) interface{}) {
+	symbsLoadedGen.skeleton.Params = append(symbsLoadedGen.skeleton.Params, meta)
+	symbsLoadedGen.extraArgs = append(symbsLoadedGen.extraArgs, symbolsLoadedExtraArg{meta: meta, value: value})
+}
+
+// nothingWatched checks if the configuration watches nothing which derives the symbols_loaded event
+func nothingWatched(config symbolsLoadedSettings) bool {
+	return len(config.Matching.WatchedSymbols) == 0 && len(config.Matching.AlwaysWatchedSymbols) == 0 &&
+		len(config.Matching.Rules) == 0 && len(config.Matching.WatchedImports) == 0 &&
+		len(config.Matching.WatchedTLSSymbols) == 0 && len(config.Matching.WatchedVersionedSymbols) == 0 &&
+		len(config.Matching.WatchedDemangledSymbols) == 0 &&
+		len(config.Detection.ExpectedSymbols) == 0 && len(config.Matching.WatchGroups) == 0 &&
+		len(config.Detection.SymbolSets) == 0 && config.Detection.WXSegments != WXSegmentsReport &&
+		len(config.Detection.FlaggedDynamicTags) == 0 && !config.Detection.FlagUnusualInterpreter &&
+		!config.Detection.FlagHighEntropy && len(config.Detection.RelocationThresholds) == 0 &&
+		!config.Extraction.MetadataOnly
+}
+
+// validateSettings checks the given symbols_loaded configuration for mistakes, and returns all the problems found.
+// An empty result means that the configuration can be used safely.
+func validateSettings(config symbolsLoadedSettings) []error {
+	var problems []error
+	problems = append(problems, config.Matching.validate()...)
+	problems = append(problems, config.Detection.validate()...)
+	problems = append(problems, config.Reporting.validate()...)
+	problems = append(problems, config.Tracking.validate()...)
+	problems = append(problems, config.Extraction.validate()...)
+
+	// Problems of features spanning several groups
+	if config.Extraction.MetadataOnly {
+		problems = append(problems, metadataOnlyProblems(config)...)
+	}
+	if config.Reporting.ReportPLTSlots && len(config.Matching.WatchedImports) == 0 {
+		problems = append(problems, fmt.Errorf("PLT slots reporting is configured with no watched imports"))
+	}
+	if config.Tracking.CorrelateExecMapping && config.Extraction.AsyncQueueSize > 0 {
+		problems = append(problems, fmt.Errorf("exec mapping correlation can't be used in the asynchronous mode"))
+	}
+	problems = append(problems, validateConfidence(config)...)
+	if config.EventName != "" {
+		if problem := validateDerivedEvent(config.EventName); problem != nil {
+			problems = append(problems, problem)
+		}
 	}
+	return problems
 }
 
-func (symbsLoadedGen *symbolsLoadedEventGenerator) deriveArgs(event trace.Event) ([]interface{}, error) {
+// validateDerivedEvent checks that the event with the given name can be derived instead of symbols_loaded
+func validateDerivedEvent(eventName string) error {
+	eventID, ok := events.Definitions.NamesToIDs()[eventName]
+	if !ok {
+		return fmt.Errorf("derived event '%s' is not defined", eventName)
+	}
+	expectedParams := events.Definitions.Get(events.SymbolsLoaded).Params
+	params := events.Definitions.Get(eventID).Params
+	if len(params) < len(expectedParams) {
+		return fmt.Errorf("derived event '%s' arguments don't match the symbols_loaded arguments", eventName)
+	}
+	for i, param := range expectedParams {
+		if params[i] != param {
+			return fmt.Errorf("derived event '%s' arguments don't match the symbols_loaded arguments", eventName)
+		}
+	}
+	return nil
+}
+
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deriveArgs(event trace.Event) ([]interface{}, error) {
+	if !symbsLoadedGen.acquire() {
+		return nil, nil
+	}
+	defer symbsLoadedGen.release()
+
 	loadingObjectInfo, err := getSharedObjectInfo(event)
 	if err != nil {
 		return nil, err
 	}
+	return symbsLoadedGen.deriveObjectArgs(event, loadingObjectInfo)
+}
 
-	if symbsLoadedGen.isWhitelist(loadingObjectInfo.Path) {
+// deriveObjectArgs derives the arguments of the symbols_loaded event from the SO loading event, given the information
+// of the loaded SO which was parsed from it. It should be called with the generator acquired.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deriveObjectArgs(event trace.Event,
+	loadingObjectInfo sharedobjs.ObjInfo) ([]interface{}, error) {
+	// The process scope is checked first, so nothing is done for SOs loaded by processes out of the scope
+	if !symbsLoadedGen.inScope(&event) {
+		symbsLoadedGen.log(LogLevelDebug, DecisionOutOfScope, loadingObjectInfo, "")
 		return nil, nil
 	}
 
-	soSyms, err := symbsLoadedGen.soLoader.GetExportedSymbols(loadingObjectInfo)
-	if err != nil {
-		return nil, err
+	// The load is counted before any decision, so the sequence includes all the SOs loaded by the process
+	var sequence uint64
+	if symbsLoadedGen.loadSequences != nil {
+		sequence = symbsLoadedGen.loadSequences.next(loadingObjectInfo.Pid)
 	}
 
-	var exportedWatchSymbols []string
-	for sym := range soSyms {
-		if symbsLoadedGen.watchedSymbols[sym] {
-			exportedWatchSymbols = append(exportedWatchSymbols, sym)
+	passthrough := symbsLoadedGen.passthroughValues(&event)
+	container := containerOf(&event)
+
+	// SOs in suspicious directories are examined regardless of the whitelist and the trust marker
+	suspicious := symbsLoadedGen.suspiciousDir(loadingObjectInfo.Path)
+	pathIgnored := suspicious == "" && symbsLoadedGen.isIgnored(loadingObjectInfo.Path)
+	ignored, interpreter, err := symbsLoadedGen.checkInterpreter(loadingObjectInfo, pathIgnored)
+	if err == nil && ignored {
+		decision := DecisionWhitelisted
+		if !pathIgnored {
+			decision = DecisionInterpreter
+		} else if symbsLoadedGen.allowlistMode {
+			decision = DecisionNotAllowed
 		}
+		symbsLoadedGen.log(LogLevelDebug, decision, loadingObjectInfo, "")
+		return symbsLoadedGen.deriveAlwaysWatchedArgs(&symbolsMatch{objInfo: loadingObjectInfo, sequence: sequence,
+			interpreter: interpreter, passthrough: passthrough, uid: event.UserID, container: container}, decision)
+	}
+	if err == nil && suspicious == "" && symbsLoadedGen.isTrusted(loadingObjectInfo) {
+		symbsLoadedGen.log(LogLevelDebug, DecisionTrusted, loadingObjectInfo, "")
+		return symbsLoadedGen.deriveAlwaysWatchedArgs(&symbolsMatch{objInfo: loadingObjectInfo, sequence: sequence,
+			interpreter: interpreter, passthrough: passthrough, uid: event.UserID, container: container}, DecisionTrusted)
 	}
 
-	if len(exportedWatchSymbols) > 0 {
-		return []interface{}{loadingObjectInfo.Path, exportedWatchSymbols}, nil
-	} else {
+	// The match is kept on the stack, so SOs with no match don't allocate it
+	match := symbolsMatch{objInfo: loadingObjectInfo, suspicious: suspicious, sequence: sequence,
+		interpreter: interpreter, passthrough: passthrough, uid: event.UserID, container: container}
+	if err == nil {
+		err = symbsLoadedGen.examineObject(&match)
+	}
+	if err != nil {
+		symbsLoadedGen.logLoadingError(loadingObjectInfo, err)
+		// SOs which can't be read due to permissions are skipped, and reported by the symbols_unreadable event.
+		// SOs skipped by the policy of their filesystem are skipped by the derivation too, and so are paths which
+		// are not regular files.
+		if errors.Is(err, fs.ErrPermission) || errors.Is(err, sharedobjs.ErrFilesystemSkipped) ||
+			errors.Is(err, sharedobjs.ErrNotRegularFile) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if symbsLoadedGen.history != nil {
+		// Matches with no symbols are recorded too, so matching symbols again is considered as a change
+		match.changed = symbsLoadedGen.history.update(loadingObjectInfo.Pid, loadingObjectInfo.Path, &match)
+	}
+	if !symbsLoadedGen.hasMatch(&match) {
+		symbsLoadedGen.log(LogLevelDebug, DecisionNoSymbols, loadingObjectInfo, "")
+		return nil, nil
+	}
+	if symbsLoadedGen.suppressUnchanged && !match.changed {
+		symbsLoadedGen.log(LogLevelDebug, DecisionUnchanged, loadingObjectInfo, "")
+		return nil, nil
+	}
+	symbsLoadedGen.scoreConfidence(&match, false)
+	if match.confidence < symbsLoadedGen.minConfidence {
+		symbsLoadedGen.log(LogLevelDebug, DecisionLowConfidence, loadingObjectInfo,
+			fmt.Sprintf("confidence: %v", match.confidence))
 		return nil, nil
 	}
+	// Self executing SOs are logged with a higher severity
+	level, decision := LogLevelInfo, DecisionMatched
+	if symbsLoadedGen.isSelfExecuting(&match) {
+		level, decision = LogLevelWarn, DecisionSelfExecuting
+	}
+	symbsLoadedGen.log(level, decision, loadingObjectInfo,
+		fmt.Sprintf("symbols: %v, rules: %v, imports: %v, missing: %v, groups: %v, dynamic tags: %v",
+			match.symbols, match.rules, match.imports, match.missing, match.groups, match.dynamicTags))
+	if err := symbsLoadedGen.enrich(&match); err != nil {
+		symbsLoadedGen.logLoadingError(loadingObjectInfo, err)
+		return nil, err
+	}
+	return symbsLoadedGen.reportMatch(&match), nil
 }
 
-// isWhitelist check if a SO's path is in the whitelist given in initialization
-func (symbsLoadedGen *symbolsLoadedEventGenerator) isWhitelist(soPath string) bool {
-	// Check absolute path libraries whitelist
-	for _, prefix := range symbsLoadedGen.pathPrefixWhitelist {
-		if strings.HasPrefix(soPath, prefix) {
-			return true
-		}
+// examineObject examines the SO of the match by each of the features, and stops at the first error
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) examineObject(match *symbolsMatch) error {
+	if err := symbsLoadedGen.matchObject(match); err != nil {
+		return err
 	}
-
-	// Check if SO is whitelisted library which resides in one of the known libs paths
-	if len(symbsLoadedGen.librariesWhitelist) > 0 {
-		for _, libsDirectory := range knownLibrariesDirs {
-			if strings.HasPrefix(soPath, libsDirectory) {
-				for _, wlLib := range symbsLoadedGen.librariesWhitelist {
-					if strings.HasPrefix(soPath, path.Join(libsDirectory, wlLib)) {
-						return true
-					}
-				}
-				break
-			}
-		}
+	if err := symbsLoadedGen.detectObject(match); err != nil {
+		return err
+	}
+	if err := symbsLoadedGen.readReportedProperties(match); err != nil {
+		return err
 	}
-	return false
+	return symbsLoadedGen.readMetadata(match)
 }
 
-// getSharedObjectInfo extract from SO loading event the information available about the SO
-func getSharedObjectInfo(event trace.Event) (sharedobjs.ObjInfo, error) {
-	var objInfo sharedobjs.ObjInfo
-	loadedObjectInode, err := parse.ArgUint64Val(&event, "inode")
-	if err != nil {
-		return objInfo, err
+// hasMatch checks if the examination of the SO of the match found anything which derives the event. In the metadata
+// only mode, every examined SO derives the event.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) hasMatch(match *symbolsMatch) bool {
+	return match.hasWatched() || symbsLoadedGen.hasDetection(match) || symbsLoadedGen.metadataLoader != nil
+}
+
+// deriveUnreadableArgs derive the arguments of the symbols_unreadable event, if the loaded SO can't be read
+// because of missing permissions.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deriveUnreadableArgs(event trace.Event) ([]interface{}, error) {
+	if !symbsLoadedGen.acquire() {
+		return nil, nil
 	}
-	loadedObjectDevice, err := parse.ArgUint32Val(&event, "dev")
+	defer symbsLoadedGen.release()
+
+	loadingObjectInfo, err := getSharedObjectInfo(event)
 	if err != nil {
-		return objInfo, err
+		return nil, err
 	}
-	loadedObjectCtime, err := parse.ArgUint64Val(&event, "ctime")
+
+	if !symbsLoadedGen.inScope(&event) || symbsLoadedGen.isIgnored(loadingObjectInfo.Path) {
+		return nil, nil
+	}
+
+	// Other errors are reported by the symbols_loaded event derivation
+	if symbsLoadedGen.unreadableDetector != nil {
+		// The loader classifies the SO once, and the classification is shared with the other derivations
+		err = symbsLoadedGen.unreadableDetector.GetPermissionError(loadingObjectInfo)
+	} else {
+		_, err = symbsLoadedGen.soLoader.GetExportedSymbols(loadingObjectInfo)
+	}
+	if err != nil && errors.Is(err, fs.ErrPermission) {
+		return []interface{}{loadingObjectInfo.Path, err.Error()}, nil
+	}
+	return nil, nil
+}
+
+// derivePackedArgs derive the arguments of the packed_object_loaded event, if the loaded SO is packed
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) derivePackedArgs(event trace.Event) ([]interface{}, error) {
+	if !symbsLoadedGen.acquire() {
+		return nil, nil
+	}
+	defer symbsLoadedGen.release()
+
+	if symbsLoadedGen.packerDetector == nil {
+		return nil, nil
+	}
+	loadingObjectInfo, err := getSharedObjectInfo(event)
 	if err != nil {
-		return objInfo, err
+		return nil, err
+	}
+
+	if !symbsLoadedGen.inScope(&event) || symbsLoadedGen.isIgnored(loadingObjectInfo.Path) ||
+		symbsLoadedGen.isTrusted(loadingObjectInfo) {
+		return nil, nil
+	}
+
+	// Errors are reported by the symbols_loaded event derivation
+	packer, err := symbsLoadedGen.packerDetector.GetPacker(loadingObjectInfo)
+	if err != nil || packer == "" {
+		return nil, nil
+	}
+	symbsLoadedGen.log(LogLevelWarn, DecisionPacked, loadingObjectInfo, packer)
+	return []interface{}{loadingObjectInfo.Path, packer}, nil
+}
+
+// deriveSlowExtractionArgs derive the arguments of the symbols_extraction_slow event, if the symbols extraction
+// of the loaded SO took longer than the threshold. Each extraction is reported only once, even if the SO is loaded
+// again while its symbols are cached.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deriveSlowExtractionArgs(event trace.Event) ([]interface{}, error) {
+	if !symbsLoadedGen.acquire() {
+		return nil, nil
 	}
-	loadedObjectPath, err := parse.ArgStringVal(&event, "pathname")
+	defer symbsLoadedGen.release()
+
+	if symbsLoadedGen.extractionTimer == nil {
+		return nil, nil
+	}
+	loadingObjectInfo, err := getSharedObjectInfo(event)
 	if err != nil {
-		return objInfo, err
+		return nil, err
 	}
-	objInfo = sharedobjs.ObjInfo{
-		Id: sharedobjs.ObjID{
-			Inode:  loadedObjectInode,
-			Device: loadedObjectDevice,
-			Ctime:  loadedObjectCtime},
-		Path:    loadedObjectPath,
-		MountNS: event.MountNS,
+
+	if !symbsLoadedGen.inScope(&event) || symbsLoadedGen.isIgnored(loadingObjectInfo.Path) ||
+		symbsLoadedGen.isTrusted(loadingObjectInfo) {
+		return nil, nil
+	}
+
+	// Errors are reported by the symbols_loaded event derivation
+	duration, first, err := symbsLoadedGen.extractionTimer.GetExtractionDuration(loadingObjectInfo)
+	if err != nil || !first || duration < symbsLoadedGen.slowThreshold {
+		return nil, nil
 	}
-	return objInfo, nil
+	return []interface{}{loadingObjectInfo.Path, uint64(duration.Nanoseconds())}, nil
+}
+
+// SharedObjectInfoError lists all the fields of a shared_object_loaded event which couldn't be parsed
+type SharedObjectInfoError = sharedobjs.ObjInfoError
+
+// getSharedObjectInfo extract from SO loading event the information available about the SO.
+// See sharedobjs.ObjInfoFromEvent.
+func getSharedObjectInfo(event trace.Event) (sharedobjs.ObjInfo, error) {
+	return sharedobjs.ObjInfoFromEvent(event)
 }
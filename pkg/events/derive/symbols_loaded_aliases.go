@@ -0,0 +1,86 @@
+package derive
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// expandAliases adds the members of each alias class with a watched member to the watched symbols, so watching
+// any name of a function (e.g. "malloc") matches all of its names (e.g. "__libc_malloc").
+// Excluded aliases are not watched. It returns the canonical name of each watched symbol which is an alias.
+func expandAliases(classes map[string][]string, watched map[string]bool, excluded map[string]bool) map[string]string {
+	canonicals := make(map[string]string)
+	for canonical, aliases := range classes {
+		classWatched := watched[canonical]
+		for _, alias := range aliases {
+			classWatched = classWatched || watched[alias]
+		}
+		if !classWatched {
+			continue
+		}
+		if !excluded[canonical] {
+			watched[canonical] = true
+		}
+		for _, alias := range aliases {
+			if excluded[alias] {
+				continue
+			}
+			watched[alias] = true
+			canonicals[alias] = canonical
+		}
+	}
+	return canonicals
+}
+
+// resolveAliases sets the canonical name of each of the reported symbols of the match, if aliases are configured
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) resolveAliases(match *symbolsMatch) {
+	if symbsLoadedGen.canonicalSymbols == nil {
+		return
+	}
+	match.canonical = make([]string, len(match.symbols))
+	for i, sym := range match.symbols {
+		if canonical, ok := symbsLoadedGen.canonicalSymbols[sym]; ok {
+			match.canonical[i] = canonical
+		} else {
+			match.canonical[i] = sym
+		}
+	}
+}
+
+// validateAliases checks the configured alias classes for mistakes
+func validateAliases(classes map[string][]string) []error {
+	var problems []error
+	canonicalNames := make([]string, 0, len(classes))
+	for canonical := range classes {
+		canonicalNames = append(canonicalNames, canonical)
+	}
+	// The classes are checked in order, so the problems of names in several classes are consistent
+	sort.Strings(canonicalNames)
+	classOf := make(map[string]string)
+	for _, canonical := range canonicalNames {
+		if canonical == "" {
+			problems = append(problems, fmt.Errorf("symbol aliases with an empty canonical name"))
+		}
+		if len(classes[canonical]) == 0 {
+			problems = append(problems, fmt.Errorf("symbol '%s' has no aliases", canonical))
+		}
+		for i, sym := range append([]string{canonical}, classes[canonical]...) {
+			switch {
+			case sym == "":
+				if i > 0 {
+					problems = append(problems, fmt.Errorf("empty alias of symbol '%s'", canonical))
+				}
+			case strings.HasSuffix(sym, prefixWildcard) || strings.Contains(sym, librarySymbolSeparator):
+				problems = append(problems, fmt.Errorf("alias '%s' of symbol '%s' should be a full symbol name",
+					sym, canonical))
+			case classOf[sym] != "" && classOf[sym] != canonical:
+				problems = append(problems, fmt.Errorf("symbol '%s' is an alias of both '%s' and '%s'",
+					sym, classOf[sym], canonical))
+			default:
+				classOf[sym] = canonical
+			}
+		}
+	}
+	return problems
+}
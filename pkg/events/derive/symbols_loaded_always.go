@@ -0,0 +1,64 @@
+package derive
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// deriveAlwaysWatchedArgs matches the always watched symbols in a SO which the derivation otherwise ignores (e.g. a
+// whitelisted or trusted SO), and returns the arguments of the event if it exports any of them. Only the always
+// watched symbols are matched, and the other matches (e.g. rules or imports) are not examined.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deriveAlwaysWatchedArgs(match *symbolsMatch, ignoredDecision string) (
+	[]interface{}, error) {
+	if symbsLoadedGen.alwaysWatched == nil {
+		return nil, nil
+	}
+	objInfo := match.objInfo
+	var err error
+	var names func() []string
+	if symbsLoadedGen.symbolsInfoLoader != nil {
+		// The information of the symbols is loaded, so the optional arguments of the symbols are reported
+		var soSymsInfo map[string]sharedobjs.SymbolInfo
+		soSymsInfo, err = symbsLoadedGen.symbolsInfoLoader.GetExportedSymbolsInfo(objInfo)
+		names = symbolsInfoNames(soSymsInfo)
+		for sym, info := range soSymsInfo {
+			if symbsLoadedGen.alwaysWatched[sym] {
+				match.symbols = append(match.symbols, sym)
+				match.symbolsInfo = append(match.symbolsInfo, info)
+			}
+		}
+	} else {
+		var soSyms map[string]bool
+		soSyms, err = symbsLoadedGen.soLoader.GetExportedSymbols(objInfo)
+		names = symbolsNames(soSyms)
+		match.symbols = MatchWatchedSymbols(soSyms, symbsLoadedGen.alwaysWatched)
+	}
+	if err != nil {
+		symbsLoadedGen.logLoadingError(objInfo, err)
+		if errors.Is(err, fs.ErrPermission) || errors.Is(err, sharedobjs.ErrFilesystemSkipped) ||
+			errors.Is(err, sharedobjs.ErrNotRegularFile) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(match.symbols) == 0 {
+		return nil, nil
+	}
+	symbsLoadedGen.setFingerprint(match, names)
+
+	symbsLoadedGen.log(LogLevelWarn, DecisionAlwaysMatched, objInfo,
+		fmt.Sprintf("symbols: %v, overriding: %s", match.symbols, ignoredDecision))
+	symbsLoadedGen.scoreConfidence(match, true)
+	if err := symbsLoadedGen.enrich(match); err != nil {
+		symbsLoadedGen.logLoadingError(objInfo, err)
+		return nil, err
+	}
+	reported := *match
+	reported.truncate(symbsLoadedGen.maxSymbols)
+	symbsLoadedGen.resolveAliases(&reported)
+	symbsLoadedGen.hashReported(&reported)
+	return symbsLoadedGen.makeArgs(&reported), nil
+}
@@ -0,0 +1,67 @@
+package derive
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/aquasecurity/tracee/pkg/events"
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// argValueTypes are the Go types of the values of the arguments of the symbols_loaded events, by the type in their
+// metadata. The consumers of the events (e.g. the printers and the signatures) rely on these types.
+var argValueTypes = map[string]reflect.Type{
+	"const char*":       reflect.TypeOf(""),
+	"const char*const*": reflect.TypeOf([]string{}),
+	"unsigned long[]":   reflect.TypeOf([]uint64{}), // Addresses
+	"bool":              reflect.TypeOf(false),
+	"int":               reflect.TypeOf(0),
+	"double":            reflect.TypeOf(float64(0)),
+	"unsigned long":     reflect.TypeOf(uint64(0)),
+	"dev_t":             reflect.TypeOf(uint32(0)),
+	"u64":               reflect.TypeOf(uint64(0)),
+}
+
+// makeTypedEventSkeleton is like makeEventSkeleton, but the values of the event arguments are typed
+func makeTypedEventSkeleton(eventID events.ID) eventSkeleton {
+	skeleton := makeEventSkeleton(eventID)
+	skeleton.Typed = true
+	return skeleton
+}
+
+// newTypedArgument creates an argument with the given metadata, making sure its value is of the Go type of its
+// metadata type. Untyped nil values of slice arguments are converted to nil slices of the argument type.
+func newTypedArgument(meta trace.ArgMeta, value interface{}) (trace.Argument, error) {
+	expectedType, ok := argValueTypes[meta.Type]
+	if !ok {
+		return trace.Argument{}, fmt.Errorf("argument '%s' type '%s' is not supported", meta.Name, meta.Type)
+	}
+	if value == nil {
+		if expectedType.Kind() != reflect.Slice {
+			return trace.Argument{}, fmt.Errorf("argument '%s' has no value", meta.Name)
+		}
+		value = reflect.Zero(expectedType).Interface()
+	}
+	valueType := reflect.TypeOf(value)
+	if valueType != expectedType {
+		return trace.Argument{}, fmt.Errorf("argument '%s' of type '%s' has a value of type %v instead of %v",
+			meta.Name, meta.Type, valueType, expectedType)
+	}
+	return trace.Argument{ArgMeta: meta, Value: value}, nil
+}
+
+// newTypedArguments creates the arguments of a derived event from their values, in the order of the given metadata
+func newTypedArguments(params []trace.ArgMeta, values []interface{}) ([]trace.Argument, error) {
+	if len(params) != len(values) {
+		return nil, fmt.Errorf("expected %d arguments but given %d", len(params), len(values))
+	}
+	args := make([]trace.Argument, len(params))
+	for i, value := range values {
+		arg, err := newTypedArgument(params[i], value)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = arg
+	}
+	return args, nil
+}
@@ -0,0 +1,119 @@
+package derive
+
+import (
+	"github.com/aquasecurity/tracee/pkg/counter"
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// asyncEventsBuffer is the amount of asynchronously derived events kept until they are read
+const asyncEventsBuffer = 64
+
+// SymbolsLoadedStats are statistics of the symbols_loaded event generator operation
+type SymbolsLoadedStats struct {
+	AsyncQueued     counter.Counter // SO loading events queued to be examined in the background
+	AsyncDropped    counter.Counter // SO loading events dropped because the queue was full
+	AsyncFailures   counter.Counter // Queued SO loading events which failed to be examined
+	AsyncQueueDepth counter.Counter // SO loading events currently in the queue
+	// SO loading events whose derivation was abandoned, as it took longer than the extraction deadline
+	ExtractionTimeouts counter.Counter
+	// Held loads whose SO was not made executable before the exec mapping timeout elapsed
+	ExecMappingTimeouts counter.Counter
+}
+
+// Stats returns the statistics of the generator operation
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) Stats() *SymbolsLoadedStats {
+	return &symbsLoadedGen.stats
+}
+
+// AsyncEvents returns the channel of the events derived in the background, or nil if the asynchronous mode is
+// not configured. In the asynchronous mode, the derive function of the event doesn't derive it, so the events should
+// be merged into the events stream by the caller. If the events are not read, the examination of the queued SOs
+// stops and further SOs are dropped.
+// The channel is closed when the generator is closed.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) AsyncEvents() <-chan trace.Event {
+	if symbsLoadedGen.asyncQueue == nil {
+		return nil
+	}
+	return symbsLoadedGen.asyncEvents
+}
+
+// startAsync starts the background worker examining the queued SO loading events, until the generator is closed
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) startAsync(queueSize int) {
+	symbsLoadedGen.asyncQueue = make(chan trace.Event, queueSize)
+	symbsLoadedGen.asyncEvents = make(chan trace.Event, asyncEventsBuffer)
+	symbsLoadedGen.asyncDone = make(chan struct{})
+	symbsLoadedGen.asyncWG.Add(1)
+	go func() {
+		defer symbsLoadedGen.asyncWG.Done()
+		defer close(symbsLoadedGen.asyncEvents)
+		// A single worker examines the SOs, so the events are emitted in the order of the loads
+		for {
+			select {
+			case event := <-symbsLoadedGen.asyncQueue:
+				symbsLoadedGen.stats.AsyncQueueDepth.Decrement()
+				if !symbsLoadedGen.deriveAsync(event) {
+					return
+				}
+			case <-symbsLoadedGen.asyncDone:
+				return
+			}
+		}
+	}()
+}
+
+// deriveAsync derives the event from the queued SO loading event, and sends it to the events channel.
+// It returns false if the generator was closed while waiting for the event to be read.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deriveAsync(event trace.Event) bool {
+	args, err := symbsLoadedGen.withDeadline(symbsLoadedGen.deriveArgs)(event)
+	if err != nil {
+		symbsLoadedGen.stats.AsyncFailures.Increment()
+		return true
+	}
+	if args == nil {
+		return true
+	}
+	derived, err := newEvent(&event, symbsLoadedGen.skeleton, args)
+	if err != nil {
+		symbsLoadedGen.stats.AsyncFailures.Increment()
+		return true
+	}
+	select {
+	case symbsLoadedGen.asyncEvents <- derived:
+		return true
+	case <-symbsLoadedGen.asyncDone:
+		return false
+	}
+}
+
+// enqueueArgs queues the SO loading event to be examined in the background, and derives no event on the hot path.
+// If the queue is full, the event is dropped rather than waiting for the queue.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) enqueueArgs(event trace.Event) ([]interface{}, error) {
+	if !symbsLoadedGen.acquire() {
+		return nil, nil
+	}
+	defer symbsLoadedGen.release()
+	// SOs loaded by processes out of the scope are not queued, so they don't take the place of relevant SOs
+	if !symbsLoadedGen.inScope(&event) {
+		return nil, nil
+	}
+	select {
+	case symbsLoadedGen.asyncQueue <- event:
+		symbsLoadedGen.stats.AsyncQueued.Increment()
+		symbsLoadedGen.stats.AsyncQueueDepth.Increment()
+	default:
+		symbsLoadedGen.stats.AsyncDropped.Increment()
+	}
+	return nil, nil
+}
+
+// stopAsync stops the background worker, and waits for the examination in progress to complete.
+// The events left in the queue are not examined.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) stopAsync() {
+	if symbsLoadedGen.asyncDone == nil {
+		return
+	}
+	symbsLoadedGen.asyncStop.Do(func() {
+		close(symbsLoadedGen.asyncDone)
+		symbsLoadedGen.asyncWG.Wait()
+	})
+}
@@ -0,0 +1,92 @@
+package derive
+
+import (
+	"fmt"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// BaselineSymbolsFromObjects builds the BaselineSymbols configuration from known-good copies of SOs (e.g. taken
+// from a clean installation of the distribution), keyed by their soname. All the given SOs must have a soname.
+func BaselineSymbolsFromObjects(paths []string) (map[string][]string, error) {
+	baselines := make(map[string][]string, len(paths))
+	for _, hostPath := range paths {
+		soname, exported, err := sharedobjs.GetSonameSymbols(hostPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read baseline object '%s': %v", hostPath, err)
+		}
+		if soname == "" {
+			return nil, fmt.Errorf("baseline object '%s' has no soname", hostPath)
+		}
+		if _, ok := baselines[soname]; ok {
+			return nil, fmt.Errorf("baseline object '%s' soname '%s' is given more than once", hostPath, soname)
+		}
+		syms := make([]string, 0, len(exported))
+		for sym := range exported {
+			syms = append(syms, sym)
+		}
+		baselines[soname] = syms
+	}
+	return baselines, nil
+}
+
+// newSonameSymbolsSets converts a symbols by soname configuration to sets, so they are built once rather than per match
+func newSonameSymbolsSets(config map[string][]string) map[string]map[string]bool {
+	baselines := make(map[string]map[string]bool, len(config))
+	for soname, syms := range config {
+		baseline := make(map[string]bool, len(syms))
+		for _, sym := range syms {
+			baseline[sym] = true
+		}
+		baselines[soname] = baseline
+	}
+	return baselines
+}
+
+// validateSonameSymbols checks the given symbols by soname configuration of the given kind for empty entries
+func validateSonameSymbols(kind string, sonameSymbols map[string][]string) []error {
+	var problems []error
+	for soname, syms := range sonameSymbols {
+		if soname == "" {
+			problems = append(problems, fmt.Errorf("%s symbols with an empty soname", kind))
+		}
+		for _, sym := range syms {
+			if sym == "" {
+				problems = append(problems, fmt.Errorf("empty %s symbol entry of soname '%s'", kind, soname))
+			}
+		}
+	}
+	return problems
+}
+
+// removeBaselineSymbols removes the matched symbols which are in the baseline of the SO soname, so only the
+// symbols which were added relative to the known-good SO are kept. SOs with no baseline are matched as usual.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) removeBaselineSymbols(match *symbolsMatch) error {
+	if symbsLoadedGen.baselines == nil || len(match.symbols) == 0 {
+		return nil
+	}
+	soname, err := symbsLoadedGen.sonameLoader.GetSoname(match.objInfo)
+	if err != nil {
+		return err
+	}
+	baseline, ok := symbsLoadedGen.baselines[soname]
+	if soname == "" || !ok {
+		return nil
+	}
+	kept := match.symbols[:0]
+	var keptInfo []sharedobjs.SymbolInfo
+	for i, sym := range match.symbols {
+		if baseline[sym] {
+			continue
+		}
+		kept = append(kept, sym)
+		if match.symbolsInfo != nil {
+			keptInfo = append(keptInfo, match.symbolsInfo[i])
+		}
+	}
+	match.symbols = kept
+	if match.symbolsInfo != nil {
+		match.symbolsInfo = keptInfo
+	}
+	return nil
+}
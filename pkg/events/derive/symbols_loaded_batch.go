@@ -0,0 +1,55 @@
+package derive
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// DeriveBatch derives the arguments of the symbols_loaded event for each of the given SO loading events.
+// The symbols extraction is done in parallel by a bounded amount of workers, while the result in each index
+// matches the event in the same index of the input (nil if no event is derived).
+// If some of the events failed, the results of the rest of the events are still returned, alongside an error
+// describing the failures.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) DeriveBatch(events []trace.Event) ([][]interface{}, error) {
+	results := make([][]interface{}, len(events))
+	errs := make([]error, len(events))
+
+	workers := symbsLoadedGen.batchWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(events) {
+		workers = len(events)
+	}
+	derive := symbsLoadedGen.withDeadline(symbsLoadedGen.deriveArgs)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index], errs[index] = derive(events[index])
+			}
+		}()
+	}
+	for i := range events {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var failures []string
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("event %d: %v", i, err))
+		}
+	}
+	if len(failures) > 0 {
+		return results, fmt.Errorf("failed to derive %d of %d events: %v", len(failures), len(events), failures)
+	}
+	return results, nil
+}
@@ -0,0 +1,115 @@
+package derive
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aquasecurity/tracee/pkg/events"
+	"github.com/aquasecurity/tracee/types/trace"
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// DefaultCapabilitiesProcesses is the default maximal amount of processes whose gained watched symbols are kept
+const DefaultCapabilitiesProcesses = 4096
+
+// processCapabilities keeps the watched symbols which each process gained, i.e. which were exported by a SO it
+// loaded, so only the first SO providing each watched symbol to a process is reported.
+// The amount of processes is bounded - the process which gained a symbol least recently is evicted, and its symbols
+// are gained again by the next SOs it loads. The symbols of a process are also forgotten when it exits. It is safe
+// for concurrent use.
+type processCapabilities struct {
+	mutex     sync.Mutex
+	processes *simplelru.LRU // pid -> map[string]bool of the gained symbols
+}
+
+func newProcessCapabilities(size int) *processCapabilities {
+	processes, _ := simplelru.NewLRU(size, nil)
+	return &processCapabilities{processes: processes}
+}
+
+// gain records that the process gained the given symbols, and returns the ones it didn't have before, in
+// alphabetical order
+func (capabilities *processCapabilities) gain(pid int, symbols []string) []string {
+	capabilities.mutex.Lock()
+	defer capabilities.mutex.Unlock()
+	var gained map[string]bool
+	if existing, ok := capabilities.processes.Get(pid); ok {
+		gained = existing.(map[string]bool)
+	} else {
+		gained = make(map[string]bool, len(symbols))
+		capabilities.processes.Add(pid, gained)
+	}
+	var newSymbols []string
+	for _, sym := range symbols {
+		if !gained[sym] {
+			gained[sym] = true
+			newSymbols = append(newSymbols, sym)
+		}
+	}
+	sort.Strings(newSymbols)
+	return newSymbols
+}
+
+// forgetProcess forgets the symbols gained by the process
+func (capabilities *processCapabilities) forgetProcess(pid int) {
+	capabilities.mutex.Lock()
+	defer capabilities.mutex.Unlock()
+	capabilities.processes.Remove(pid)
+}
+
+// SymbolsCapabilityGained receives the generator of the symbols_loaded event as a closure argument.
+// If it receives a shared_object_loaded event of a SO which exports watched symbols that no SO previously loaded by
+// the process exported, it derives a symbols_capability_gained event from it.
+func SymbolsCapabilityGained(gen *SymbolsLoadedEventGenerator) events.DeriveFunction {
+	return singleSkeletonDeriveFunc(makeTypedEventSkeleton(events.SymbolsCapabilityGained),
+		gen.withDeadline(gen.deriveCapabilityGainedArgs))
+}
+
+// deriveCapabilityGainedArgs derive the arguments of the symbols_capability_gained event, if the loaded SO exports
+// watched symbols which the loading process didn't gain before. Like the symbols matching, whitelisted and trusted
+// SOs are not examined, so they don't give the process any symbol.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deriveCapabilityGainedArgs(event trace.Event) ([]interface{}, error) {
+	if !symbsLoadedGen.acquire() {
+		return nil, nil
+	}
+	defer symbsLoadedGen.release()
+
+	if symbsLoadedGen.capabilities == nil {
+		return nil, nil
+	}
+	loadingObjectInfo, err := getSharedObjectInfo(event)
+	if err != nil {
+		return nil, err
+	}
+
+	if !symbsLoadedGen.inScope(&event) || symbsLoadedGen.isIgnored(loadingObjectInfo.Path) ||
+		symbsLoadedGen.isTrusted(loadingObjectInfo) {
+		return nil, nil
+	}
+
+	// Errors are reported by the symbols_loaded event derivation
+	soSyms, err := symbsLoadedGen.soLoader.GetExportedSymbols(loadingObjectInfo)
+	if err != nil {
+		return nil, nil
+	}
+	var watched []string
+	for sym := range soSyms {
+		if symbsLoadedGen.isWatched(sym, loadingObjectInfo.Path) {
+			watched = append(watched, sym)
+		}
+	}
+	if len(watched) == 0 {
+		return nil, nil
+	}
+	gained := symbsLoadedGen.capabilities.gain(loadingObjectInfo.Pid, watched)
+	if len(gained) == 0 {
+		return nil, nil
+	}
+	symbsLoadedGen.log(LogLevelInfo, DecisionNewCapability, loadingObjectInfo, fmt.Sprintf("symbols: %v", gained))
+	reported := gained
+	if symbsLoadedGen.hashOnly {
+		reported = symbsLoadedGen.hasher.hashAll(gained)
+	}
+	return []interface{}{loadingObjectInfo.Path, reported}, nil
+}
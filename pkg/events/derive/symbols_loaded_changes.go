@@ -0,0 +1,99 @@
+package derive
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// DefaultMatchHistorySize is the default maximal amount of (process, SO path) pairs whose last match is kept
+const DefaultMatchHistorySize = 4096
+
+// processObject identifies a SO path loaded by a process
+type processObject struct {
+	pid  int
+	path string
+}
+
+// matchHistory keeps the last match of each SO path loaded by each process, so reloads of the same path by the same
+// process can be checked for changes of the matched symbols (e.g. if the file was swapped).
+// The history is bounded, and the entries of a process are evicted when it exits. It is safe for concurrent use.
+type matchHistory struct {
+	mutex     sync.Mutex
+	lastMatch *simplelru.LRU          // processObject -> the signature of the last match
+	processes map[int]map[string]bool // The paths kept for each process, to evict them when it exits
+}
+
+func newMatchHistory(size int) *matchHistory {
+	history := &matchHistory{processes: make(map[int]map[string]bool)}
+	history.lastMatch, _ = simplelru.NewLRU(size, func(key interface{}, _ interface{}) {
+		obj := key.(processObject)
+		delete(history.processes[obj.pid], obj.path)
+		if len(history.processes[obj.pid]) == 0 {
+			delete(history.processes, obj.pid)
+		}
+	})
+	return history
+}
+
+// update records the match of the SO path loaded by the process, and returns whether it differs from the last
+// match recorded for them. The first match recorded for them is considered as changed.
+func (history *matchHistory) update(pid int, path string, match *symbolsMatch) bool {
+	signature := match.signature()
+	obj := processObject{pid: pid, path: path}
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+	last, ok := history.lastMatch.Get(obj)
+	if ok && last.(string) == signature {
+		return false
+	}
+	history.lastMatch.Add(obj, signature)
+	if history.processes[pid] == nil {
+		history.processes[pid] = make(map[string]bool)
+	}
+	history.processes[pid][path] = true
+	return true
+}
+
+// forgetProcess evicts all the matches recorded for the process
+func (history *matchHistory) forgetProcess(pid int) {
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+	paths := make([]string, 0, len(history.processes[pid]))
+	for path := range history.processes[pid] {
+		paths = append(paths, path)
+	}
+	for _, path := range paths {
+		history.lastMatch.Remove(processObject{pid: pid, path: path})
+	}
+}
+
+// signature returns a string representing all the matched symbols, rules, imports, groups and W^X segments,
+// regardless of their order
+func (match *symbolsMatch) signature() string {
+	parts := make([]string, 0, 6)
+	for _, matched := range [][]string{match.symbols, match.rules, match.imports, match.missing, match.groups,
+		formatWXSegments(match.wxSegments)} {
+		sorted := append([]string{}, matched...)
+		sort.Strings(sorted)
+		parts = append(parts, strings.Join(sorted, ","))
+	}
+	return strings.Join(parts, "|")
+}
+
+// ProcessExited evicts the matches recorded for the process with the given host PID, if changes are tracked,
+// resets its loads sequence, if the load order is reported, and forgets its gained symbols, if they are tracked.
+// It should be called when a process exits, so the history doesn't keep SOs of processes which don't exist.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) ProcessExited(pid int) {
+	if symbsLoadedGen.history != nil {
+		symbsLoadedGen.history.forgetProcess(pid)
+	}
+	if symbsLoadedGen.loadSequences != nil {
+		symbsLoadedGen.loadSequences.forgetProcess(pid)
+	}
+	if symbsLoadedGen.capabilities != nil {
+		symbsLoadedGen.capabilities.forgetProcess(pid)
+	}
+}
@@ -0,0 +1,68 @@
+package derive
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// Close stops the generator, and closes its SO loader if it can be closed.
+// After Close returns, no event is derived by the generator, and derivations which were in progress when
+// Close was called are complete. Callers must call Close when the generator is no longer needed.
+// Close can be called multiple times, and is safe to call on a zero value generator.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) Close() error {
+	// The background workers are stopped before locking, as they may wait for the lock in the derivation in progress.
+	// The derivations under the deadline are cancelled, so abandoned reads of SOs don't delay closing.
+	symbsLoadedGen.stopAsync()
+	symbsLoadedGen.stopDeadlineWorkers()
+	err := symbsLoadedGen.closeLocked()
+	// Derivations of the deadline workers may be waiting for the lock, so they are waited for after it is released
+	symbsLoadedGen.deadlineWG.Wait()
+	return err
+}
+
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) closeLocked() error {
+	symbsLoadedGen.closeMutex.Lock()
+	defer symbsLoadedGen.closeMutex.Unlock()
+	if symbsLoadedGen.closed {
+		return nil
+	}
+	symbsLoadedGen.closed = true
+	symbsLoadedGen.stopSummaries()
+	symbsLoadedGen.stopCorrelation()
+	if closer, ok := symbsLoadedGen.soLoader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// SetEnabled enables or disables the derivations of the generator, without closing it. While disabled, the
+// generator derives no events, and returns before examining the received events. It is safe to call concurrently
+// with derivations, which are fast to check it. Generators are enabled when initialized.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) SetEnabled(enabled bool) {
+	var disabled int32
+	if !enabled {
+		disabled = 1
+	}
+	atomic.StoreInt32(&symbsLoadedGen.disabled, disabled)
+}
+
+// acquire marks the beginning of a derivation, and returns false if the generator can't derive events
+// because it was disabled, closed or not initialized.
+// Every successful acquire must be followed by a release when the derivation is complete.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) acquire() bool {
+	// The disabled flag is checked before the lock, so disabled derivations are not delayed by Close
+	if atomic.LoadInt32(&symbsLoadedGen.disabled) != 0 {
+		return false
+	}
+	symbsLoadedGen.closeMutex.RLock()
+	if symbsLoadedGen.closed || symbsLoadedGen.soLoader == nil {
+		symbsLoadedGen.closeMutex.RUnlock()
+		return false
+	}
+	return true
+}
+
+// release marks the end of a derivation which was acquired
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) release() {
+	symbsLoadedGen.closeMutex.RUnlock()
+}
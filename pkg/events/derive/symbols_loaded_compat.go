@@ -0,0 +1,263 @@
+package derive
+
+import (
+	"debug/elf"
+	"time"
+
+	"github.com/aquasecurity/tracee/pkg/events"
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// SymbolsLoadedConfig is the flat configuration of the symbols_loaded event derivation, accepted by
+// InitSymbolsLoadedEventGenerator and ValidateConfig for their existing callers. Its fields are documented in the
+// configuration groups of the features they belong to. Options added since the configuration was grouped are set
+// only through the options of NewSymbolsLoadedGenerator (e.g. WithMinMatches).
+type SymbolsLoadedConfig struct {
+	// The matching of the watched symbols (see SymbolsMatchingConfig)
+	WatchedSymbols          []string
+	ExcludedSymbols         []string
+	AlwaysWatchedSymbols    []string
+	WhitelistedLibs         []string
+	WhitelistedRegexps      []string
+	WhitelistFiles          []string
+	AllowlistMode           bool
+	WatchedVisibilities     []elf.SymVis
+	ExecutableSectionsOnly  bool
+	Rules                   []SymbolsRule
+	WatchedImports          []string
+	WatchedTLSSymbols       []string
+	WatchedVersionedSymbols []string
+	Interpreter             InterpreterMode
+	BaselineSymbols         map[string][]string
+	TrustedNote             sharedobjs.NoteID
+	WatchGroups             []SymbolsWatchGroup
+	StopOnFirstMatch        bool
+	SuspiciousPaths         []string
+	LdSoConfPath            string
+	LibraryPath             string
+	SymbolAliases           map[string][]string
+	ProcessScope            SymbolsProcessScope
+
+	// The detections of suspicious properties of SOs (see SymbolsDetectionConfig)
+	ExpectedSymbolIndexes  map[string]int
+	ExpectedSymbolSizes    map[string]SymbolSizeRange
+	ExpectedSymbols        map[string][]string
+	SymbolSets             []SymbolSet
+	WXSegments             WXSegmentsMode
+	FlaggedDynamicTags     []elf.DynTag
+	FlagUnusualInterpreter bool
+	WeakSymbols            map[string][]string
+	FlagHighEntropy        bool
+	EntropyThreshold       float64
+	RelocationThresholds   map[string]int
+	ConfidenceScorer       ConfidenceScorer
+	ConfidenceWeights      ConfidenceWeights
+	MinConfidence          float64
+
+	// What is added to the derived event (see SymbolsReportingConfig)
+	ReportVisibility         bool
+	ReportSection            bool
+	ReportSymbolIndex        bool
+	ReportSymbolSize         bool
+	MaxSymbolsPerEvent       int
+	CompactSymbols           bool
+	ReportPLTSlots           bool
+	ReportInterpreter        bool
+	SymbolsHash              SymbolsHashMode
+	SymbolsHashKey           []byte
+	ReportSymbolsCount       bool
+	SymbolsCountBoundaries   []int
+	ReportObjectID           bool
+	PassthroughArgs          []string
+	ReportContainer          bool
+	UserSeverities           map[int]SymbolsSeverity
+	DefaultSeverity          SymbolsSeverity
+	ReportSymbolsFingerprint bool
+	FingerprintCacheSize     int
+	ReportConstructors       bool
+	ConstructorsBaseline     int
+	ReportSymbolsTruncation  bool
+	ReportConfidence         bool
+	Enrichment               SymbolsEnrichment
+
+	// The state kept across the examined SOs (see SymbolsTrackingConfig)
+	ReportChanges         bool
+	SuppressUnchanged     bool
+	MatchHistorySize      int
+	SummaryInterval       time.Duration
+	SummaryKey            SummaryKey
+	MaxSummaryEntries     int
+	ReportLoadOrder       bool
+	LoadOrderProcesses    int
+	CorrelateExecMapping  bool
+	ExecMappingTimeout    time.Duration
+	MaxPendingLoads       int
+	TrackBuildIDs         bool
+	MaxSeenBuildIDs       int
+	TrackSonamePaths      bool
+	MaxTrackedSonames     int
+	MaxSonamePaths        int
+	ProfileProcesses      bool
+	MaxProfiledProcesses  int
+	TrackCapabilities     bool
+	CapabilitiesProcesses int
+	MaxProfileEntries     int
+
+	// How the SOs are examined (see SymbolsExtractionConfig)
+	BatchWorkers            int
+	SlowExtractionThreshold time.Duration
+	SelfTestLibrary         string
+	AsyncQueueSize          int
+	ExtractionDeadline      time.Duration
+	MetadataOnly            bool
+
+	// Name of the event to derive instead of symbols_loaded. The event must be defined, and its arguments must
+	// start with the symbols_loaded arguments.
+	EventName string
+	Logger    SymbolsLoadedLogger // Receives the decisions taken for each SO. If nil, nothing is logged
+	// The source of time of the extraction deadline and the summaries interval. If nil, the sharedobjs.SystemClock
+	// is used.
+	Clock sharedobjs.Clock
+}
+
+// InitSymbolsLoadedEventGenerator creates a generator with the given flat configuration. It is kept for existing
+// callers, and is equivalent to NewSymbolsLoadedGenerator with the options of the configuration groups.
+func InitSymbolsLoadedEventGenerator(
+	soLoader sharedobjs.DynamicSymbolsLoader,
+	config SymbolsLoadedConfig) (*SymbolsLoadedEventGenerator, error) {
+	return newSymbolsLoadedEventGenerator(soLoader, config.settings())
+}
+
+// SymbolsLoaded receives the watched symbols and the whitelisted libraries prefixes as closure arguments.
+// If it receives a shared_object_loaded event, it can derive a symbols_loaded event from it. It is kept for existing
+// callers, and is equivalent to SymbolsLoadedFromGenerator with a generator of these options only.
+func SymbolsLoaded(
+	soLoader sharedobjs.DynamicSymbolsLoader,
+	watchedSymbols []string,
+	whitelistedLibsPrefixes []string,
+) events.DeriveFunction {
+	gen, err := InitSymbolsLoadedEventGenerator(soLoader, SymbolsLoadedConfig{
+		WatchedSymbols:  watchedSymbols,
+		WhitelistedLibs: whitelistedLibsPrefixes,
+	})
+	if err != nil {
+		return func(event trace.Event) ([]trace.Event, []error) {
+			return nil, []error{err}
+		}
+	}
+	return SymbolsLoadedFromGenerator(gen)
+}
+
+// ValidateConfig checks the given symbols_loaded configuration for mistakes, and returns all the problems found.
+// An empty result means that the configuration can be used safely.
+func ValidateConfig(config SymbolsLoadedConfig) []error {
+	return validateSettings(config.settings())
+}
+
+// settings returns the configuration grouped by the features
+func (config SymbolsLoadedConfig) settings() symbolsLoadedSettings {
+	return symbolsLoadedSettings{
+		Matching: SymbolsMatchingConfig{
+			WatchedSymbols:          config.WatchedSymbols,
+			ExcludedSymbols:         config.ExcludedSymbols,
+			AlwaysWatchedSymbols:    config.AlwaysWatchedSymbols,
+			WhitelistedLibs:         config.WhitelistedLibs,
+			WhitelistedRegexps:      config.WhitelistedRegexps,
+			WhitelistFiles:          config.WhitelistFiles,
+			AllowlistMode:           config.AllowlistMode,
+			WatchedVisibilities:     config.WatchedVisibilities,
+			ExecutableSectionsOnly:  config.ExecutableSectionsOnly,
+			Rules:                   config.Rules,
+			WatchedImports:          config.WatchedImports,
+			WatchedTLSSymbols:       config.WatchedTLSSymbols,
+			WatchedVersionedSymbols: config.WatchedVersionedSymbols,
+			Interpreter:             config.Interpreter,
+			BaselineSymbols:         config.BaselineSymbols,
+			TrustedNote:             config.TrustedNote,
+			WatchGroups:             config.WatchGroups,
+			StopOnFirstMatch:        config.StopOnFirstMatch,
+			SuspiciousPaths:         config.SuspiciousPaths,
+			LdSoConfPath:            config.LdSoConfPath,
+			LibraryPath:             config.LibraryPath,
+			SymbolAliases:           config.SymbolAliases,
+			ProcessScope:            config.ProcessScope,
+		},
+		Detection: SymbolsDetectionConfig{
+			ExpectedSymbolIndexes:  config.ExpectedSymbolIndexes,
+			ExpectedSymbolSizes:    config.ExpectedSymbolSizes,
+			ExpectedSymbols:        config.ExpectedSymbols,
+			SymbolSets:             config.SymbolSets,
+			WXSegments:             config.WXSegments,
+			FlaggedDynamicTags:     config.FlaggedDynamicTags,
+			FlagUnusualInterpreter: config.FlagUnusualInterpreter,
+			WeakSymbols:            config.WeakSymbols,
+			FlagHighEntropy:        config.FlagHighEntropy,
+			EntropyThreshold:       config.EntropyThreshold,
+			RelocationThresholds:   config.RelocationThresholds,
+			ConfidenceScorer:       config.ConfidenceScorer,
+			ConfidenceWeights:      config.ConfidenceWeights,
+			MinConfidence:          config.MinConfidence,
+		},
+		Reporting: SymbolsReportingConfig{
+			ReportVisibility:         config.ReportVisibility,
+			ReportSection:            config.ReportSection,
+			ReportSymbolIndex:        config.ReportSymbolIndex,
+			ReportSymbolSize:         config.ReportSymbolSize,
+			MaxSymbolsPerEvent:       config.MaxSymbolsPerEvent,
+			CompactSymbols:           config.CompactSymbols,
+			ReportPLTSlots:           config.ReportPLTSlots,
+			ReportInterpreter:        config.ReportInterpreter,
+			SymbolsHash:              config.SymbolsHash,
+			SymbolsHashKey:           config.SymbolsHashKey,
+			ReportSymbolsCount:       config.ReportSymbolsCount,
+			SymbolsCountBoundaries:   config.SymbolsCountBoundaries,
+			ReportObjectID:           config.ReportObjectID,
+			PassthroughArgs:          config.PassthroughArgs,
+			ReportContainer:          config.ReportContainer,
+			UserSeverities:           config.UserSeverities,
+			DefaultSeverity:          config.DefaultSeverity,
+			ReportSymbolsFingerprint: config.ReportSymbolsFingerprint,
+			FingerprintCacheSize:     config.FingerprintCacheSize,
+			ReportConstructors:       config.ReportConstructors,
+			ConstructorsBaseline:     config.ConstructorsBaseline,
+			ReportSymbolsTruncation:  config.ReportSymbolsTruncation,
+			ReportConfidence:         config.ReportConfidence,
+			Enrichment:               config.Enrichment,
+		},
+		Tracking: SymbolsTrackingConfig{
+			ReportChanges:         config.ReportChanges,
+			SuppressUnchanged:     config.SuppressUnchanged,
+			MatchHistorySize:      config.MatchHistorySize,
+			SummaryInterval:       config.SummaryInterval,
+			SummaryKey:            config.SummaryKey,
+			MaxSummaryEntries:     config.MaxSummaryEntries,
+			ReportLoadOrder:       config.ReportLoadOrder,
+			LoadOrderProcesses:    config.LoadOrderProcesses,
+			CorrelateExecMapping:  config.CorrelateExecMapping,
+			ExecMappingTimeout:    config.ExecMappingTimeout,
+			MaxPendingLoads:       config.MaxPendingLoads,
+			TrackBuildIDs:         config.TrackBuildIDs,
+			MaxSeenBuildIDs:       config.MaxSeenBuildIDs,
+			TrackSonamePaths:      config.TrackSonamePaths,
+			MaxTrackedSonames:     config.MaxTrackedSonames,
+			MaxSonamePaths:        config.MaxSonamePaths,
+			ProfileProcesses:      config.ProfileProcesses,
+			MaxProfiledProcesses:  config.MaxProfiledProcesses,
+			TrackCapabilities:     config.TrackCapabilities,
+			CapabilitiesProcesses: config.CapabilitiesProcesses,
+			MaxProfileEntries:     config.MaxProfileEntries,
+		},
+		Extraction: SymbolsExtractionConfig{
+			BatchWorkers:            config.BatchWorkers,
+			SlowExtractionThreshold: config.SlowExtractionThreshold,
+			SelfTestLibrary:         config.SelfTestLibrary,
+			AsyncQueueSize:          config.AsyncQueueSize,
+			ExtractionDeadline:      config.ExtractionDeadline,
+			MetadataOnly:            config.MetadataOnly,
+		},
+		EventName: config.EventName,
+		Logger:    config.Logger,
+		Clock:     config.Clock,
+	}
+}
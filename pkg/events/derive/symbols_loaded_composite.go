@@ -0,0 +1,210 @@
+package derive
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/aquasecurity/tracee/pkg/events"
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// SymbolsLoadedCompositeGenerator derives the symbols_loaded event for multiple watch configurations, while loading
+// the symbols of each SO only once for all of them. Each configuration keeps its own whitelist and options, and
+// its events are derived as if it had a generator of its own: configurations with an extraction deadline are
+// derived under it, the events of asynchronous configurations are sent to the AsyncEvents channel of their
+// generator, and configurations correlating exec mappings hold their loads until SymbolsLoadedCompositeExecMapping
+// receives the mapping granting execution.
+type SymbolsLoadedCompositeGenerator struct {
+	generators   []*SymbolsLoadedEventGenerator
+	derivations  []events.DeriveFunction // The symbols_loaded derive function of each generator
+	execMappings []events.DeriveFunction // The exec mappings derive function of each generator
+	soLoader     *sharedSymbolsLoader
+}
+
+// InitSymbolsLoadedCompositeGenerator creates a generator for each of the given configurations, all sharing the
+// given SO loader.
+func InitSymbolsLoadedCompositeGenerator(
+	soLoader sharedobjs.DynamicSymbolsLoader,
+	configs []SymbolsLoadedConfig) (*SymbolsLoadedCompositeGenerator, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no symbols_loaded configurations given")
+	}
+	composite := &SymbolsLoadedCompositeGenerator{soLoader: &sharedSymbolsLoader{soLoader: soLoader}}
+	for i, config := range configs {
+		gen, err := InitSymbolsLoadedEventGenerator(soLoader, config)
+		if err != nil {
+			return nil, fmt.Errorf("configuration %d: %v", i, err)
+		}
+		// The capabilities of the loader are detected by each generator, and only the loading of the symbols is shared
+		gen.soLoader = composite.soLoader
+		if gen.metadataLoader != nil {
+			// Generators in the metadata only mode still read the SOs through their metadata only
+			gen.metadataLoader = composite.soLoader
+			gen.soLoader = metadataSymbolsLoader{loader: composite.soLoader}
+		}
+		if gen.symbolsInfoLoader != nil {
+			gen.symbolsInfoLoader = composite.soLoader
+		}
+		if gen.importsInfoLoader != nil {
+			gen.importsInfoLoader = composite.soLoader
+		}
+		composite.generators = append(composite.generators, gen)
+		// The derive functions of the generator apply its deadline, asynchronous mode and exec mapping correlation
+		composite.derivations = append(composite.derivations, SymbolsLoadedFromGenerator(gen))
+		composite.execMappings = append(composite.execMappings, SymbolsLoadedExecMapping(gen))
+	}
+	return composite, nil
+}
+
+// Generators returns the generator of each of the configurations, in the order of the configurations.
+// They can be used to derive the other events of specific configurations (e.g. symbols_unreadable).
+func (composite *SymbolsLoadedCompositeGenerator) Generators() []*SymbolsLoadedEventGenerator {
+	return composite.generators
+}
+
+// Close stops all the generators, and closes the shared SO loader if it can be closed
+func (composite *SymbolsLoadedCompositeGenerator) Close() error {
+	for _, gen := range composite.generators {
+		_ = gen.Close() // The shared loader doesn't implement io.Closer, so it is closed only once below
+	}
+	if closer, ok := composite.soLoader.soLoader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// SymbolsLoadedComposite receives a composite generator as a closure argument.
+// For each shared_object_loaded event, it derives an event for each configuration which matched the loaded SO.
+// The event of each configuration is the one given in its EventName, so the function should be registered once
+// in the derivation table, and not once per configuration.
+func SymbolsLoadedComposite(composite *SymbolsLoadedCompositeGenerator) events.DeriveFunction {
+	return func(event trace.Event) ([]trace.Event, []error) {
+		// The symbols are shared only between the derivations of the same event, so loading errors aren't kept
+		composite.soLoader.reset()
+		return deriveAll(composite.derivations, event)
+	}
+}
+
+// SymbolsLoadedCompositeExecMapping receives a composite generator as a closure argument, and correlates the loads
+// of SOs with the mappings which made them executable for each configuration which correlates them (see
+// SymbolsLoadedExecMapping). It should receive the security_mmap_file and security_file_mprotect events.
+func SymbolsLoadedCompositeExecMapping(composite *SymbolsLoadedCompositeGenerator) events.DeriveFunction {
+	return func(event trace.Event) ([]trace.Event, []error) {
+		composite.soLoader.reset()
+		return deriveAll(composite.execMappings, event)
+	}
+}
+
+// deriveAll derives the events of all the given derive functions from the event, in their order
+func deriveAll(deriveFuncs []events.DeriveFunction, event trace.Event) ([]trace.Event, []error) {
+	derived := []trace.Event{}
+	var errs []error
+	for _, deriveFunc := range deriveFuncs {
+		funcDerived, funcErrs := deriveFunc(event)
+		derived = append(derived, funcDerived...)
+		errs = append(errs, funcErrs...)
+	}
+	return derived, errs
+}
+
+// sharedSymbolsLoader is the SO loader of the generators of a composite generator.
+// It keeps the results of loading the symbols of the last SO, so they are loaded (and copied by the underlying
+// loader) only once for all the generators which examine it. The results are shared, so they must not be modified.
+type sharedSymbolsLoader struct {
+	soLoader sharedobjs.DynamicSymbolsLoader
+	mutex    sync.Mutex
+	objInfo  sharedobjs.ObjInfo
+	results  map[string]loadingResult // The results of loading the symbols of objInfo, by the loading method
+}
+
+// loadingResult is the result of a single call to the underlying loader
+type loadingResult struct {
+	value interface{}
+	err   error
+}
+
+// reset forgets the results of the last loaded SO
+func (loader *sharedSymbolsLoader) reset() {
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+	loader.results = nil
+}
+
+// load returns the result of the given loading method for the given SO, calling it only if it wasn't called for
+// the SO since the last reset or since another SO was loaded.
+func (loader *sharedSymbolsLoader) load(objInfo sharedobjs.ObjInfo, method string,
+	loadFunc func() (interface{}, error)) (interface{}, error) {
+	loader.mutex.Lock()
+	if loader.results != nil && loader.objInfo == objInfo {
+		if result, ok := loader.results[method]; ok {
+			loader.mutex.Unlock()
+			return result.value, result.err
+		}
+	}
+	loader.mutex.Unlock()
+
+	// The lock is not held while loading, so SOs loaded concurrently don't wait for each other
+	value, err := loadFunc()
+
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+	if loader.results == nil || loader.objInfo != objInfo {
+		loader.objInfo = objInfo
+		loader.results = make(map[string]loadingResult)
+	}
+	loader.results[method] = loadingResult{value: value, err: err}
+	return value, err
+}
+
+func (loader *sharedSymbolsLoader) GetDynamicSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
+	value, err := loader.load(info, "dynamic", func() (interface{}, error) {
+		return loader.soLoader.GetDynamicSymbols(info)
+	})
+	syms, _ := value.(map[string]bool)
+	return syms, err
+}
+
+func (loader *sharedSymbolsLoader) GetExportedSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
+	value, err := loader.load(info, "exported", func() (interface{}, error) {
+		return loader.soLoader.GetExportedSymbols(info)
+	})
+	syms, _ := value.(map[string]bool)
+	return syms, err
+}
+
+func (loader *sharedSymbolsLoader) GetImportedSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
+	value, err := loader.load(info, "imported", func() (interface{}, error) {
+		return loader.soLoader.GetImportedSymbols(info)
+	})
+	syms, _ := value.(map[string]bool)
+	return syms, err
+}
+
+// GetExportedSymbolsInfo must be called only if the underlying loader is a sharedobjs.SymbolsInfoLoader
+func (loader *sharedSymbolsLoader) GetExportedSymbolsInfo(info sharedobjs.ObjInfo) (map[string]sharedobjs.SymbolInfo, error) {
+	value, err := loader.load(info, "exported-info", func() (interface{}, error) {
+		return loader.soLoader.(sharedobjs.SymbolsInfoLoader).GetExportedSymbolsInfo(info)
+	})
+	symsInfo, _ := value.(map[string]sharedobjs.SymbolInfo)
+	return symsInfo, err
+}
+
+// GetMetadata must be called only if the underlying loader is a sharedobjs.MetadataLoader
+func (loader *sharedSymbolsLoader) GetMetadata(info sharedobjs.ObjInfo) (sharedobjs.ObjMetadata, error) {
+	value, err := loader.load(info, "metadata", func() (interface{}, error) {
+		return loader.soLoader.(sharedobjs.MetadataLoader).GetMetadata(info)
+	})
+	metadata, _ := value.(sharedobjs.ObjMetadata)
+	return metadata, err
+}
+
+// GetImportedSymbolsInfo must be called only if the underlying loader is a sharedobjs.ImportsInfoLoader
+func (loader *sharedSymbolsLoader) GetImportedSymbolsInfo(info sharedobjs.ObjInfo) (map[string]sharedobjs.ImportedSymbolInfo, error) {
+	value, err := loader.load(info, "imported-info", func() (interface{}, error) {
+		return loader.soLoader.(sharedobjs.ImportsInfoLoader).GetImportedSymbolsInfo(info)
+	})
+	importsInfo, _ := value.(map[string]sharedobjs.ImportedSymbolInfo)
+	return importsInfo, err
+}
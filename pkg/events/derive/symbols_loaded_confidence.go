@@ -0,0 +1,186 @@
+package derive
+
+import (
+	"debug/elf"
+	"fmt"
+	"strings"
+)
+
+// ConfidenceSignals are the signals of a match which its confidence score is combined from
+type ConfidenceSignals struct {
+	Symbols int // The amount of matched watched symbols, before truncation
+	// Whether the information of the matched symbols was loaded, so their binding and sections are known
+	SymbolsInfo bool
+	// The matched symbols which are globally bound (strong definitions, as opposed to weak ones), and the matched
+	// symbols which reside in executable sections. Set only if the information of the symbols was loaded.
+	GlobalSymbols     int
+	ExecutableSymbols int
+	Suspicious        bool // The SO was loaded from a suspicious directory
+	LibrariesDir      bool // The SO resides in the libraries directories
+	Ignored           bool // The SO is whitelisted or trusted, and matched its always watched symbols only
+	// Whether watched versioned symbols are configured, and the amount of them which the SO matched
+	VersionedWatched bool
+	Versioned        int
+	// Whether watch groups are configured, and the amount of them which the SO matched (exporting at least their
+	// MinMatches symbols)
+	GroupsWatched bool
+	Groups        int
+}
+
+// ConfidenceScorer scores the confidence of a match, between 0 (least confident) and 1, from its signals. It is
+// called for each match during the derivation, so it should be fast and not block. Scores out of this range are
+// clamped into it.
+type ConfidenceScorer func(signals ConfidenceSignals) float64
+
+// ConfidenceWeights are the weights of the signals in the default confidence scorer (see WeightedConfidence)
+type ConfidenceWeights struct {
+	Bind      float64 // The part of the matched symbols which are globally bound
+	Section   float64 // The part of the matched symbols which reside in executable sections
+	Path      float64 // How untrusted the path of the SO is
+	Versioned float64 // Whether a watched versioned symbol was matched
+	Groups    float64 // Whether a watch group was matched
+}
+
+// DefaultConfidenceWeights are the weights of the signals in the default confidence scorer
+var DefaultConfidenceWeights = ConfidenceWeights{Bind: 0.25, Section: 0.25, Path: 0.2, Versioned: 0.15, Groups: 0.15}
+
+// WeightedConfidence returns a confidence scorer which scores a match by the weighted average of its signals, each
+// between 0 and 1:
+//   - bind: the part of the matched symbols which are globally bound, as weak definitions are often default stubs
+//   - section: the part of the matched symbols which reside in executable sections
+//   - path: 1 for SOs loaded from a suspicious directory, 0 for whitelisted or trusted SOs and SOs residing in the
+//     libraries directories, and 0.5 for other paths
+//   - versioned: 1 if a watched versioned symbol was matched, and 0 otherwise
+//   - groups: 1 if a watch group was matched, and 0 otherwise
+//
+// Signals which are not known for the match are left out of the average: bind and section if the information of the
+// symbols wasn't loaded (or no symbol was matched), versioned if no versioned symbols are watched, and groups if no
+// watch groups are configured. A match with no known signal of a positive weight scores 0.
+func WeightedConfidence(weights ConfidenceWeights) ConfidenceScorer {
+	return func(signals ConfidenceSignals) float64 {
+		var sum, total float64
+		add := func(weight float64, value float64) {
+			sum += weight * value
+			total += weight
+		}
+		if signals.SymbolsInfo && signals.Symbols > 0 {
+			add(weights.Bind, float64(signals.GlobalSymbols)/float64(signals.Symbols))
+			add(weights.Section, float64(signals.ExecutableSymbols)/float64(signals.Symbols))
+		}
+		switch {
+		case signals.Suspicious:
+			add(weights.Path, 1)
+		case signals.Ignored || signals.LibrariesDir:
+			add(weights.Path, 0)
+		default:
+			add(weights.Path, 0.5)
+		}
+		if signals.VersionedWatched {
+			add(weights.Versioned, boolSignal(signals.Versioned > 0))
+		}
+		if signals.GroupsWatched {
+			add(weights.Groups, boolSignal(signals.Groups > 0))
+		}
+		if total == 0 {
+			return 0
+		}
+		return sum / total
+	}
+}
+
+// boolSignal returns the value of a signal which is either present or absent
+func boolSignal(present bool) float64 {
+	if present {
+		return 1
+	}
+	return 0
+}
+
+// confidenceSignals collects the signals of the match
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) confidenceSignals(match *symbolsMatch, ignored bool) ConfidenceSignals {
+	signals := ConfidenceSignals{
+		Symbols:          len(match.symbols),
+		SymbolsInfo:      len(match.symbolsInfo) == len(match.symbols) && symbsLoadedGen.symbolsInfoLoader != nil,
+		Suspicious:       match.suspicious != "",
+		LibrariesDir:     symbsLoadedGen.inLibrariesDir(match.objInfo.Path),
+		Ignored:          ignored,
+		VersionedWatched: symbsLoadedGen.watchedVersioned != nil,
+		Versioned:        len(match.versioned),
+		GroupsWatched:    len(symbsLoadedGen.watchGroups) > 0,
+		Groups:           len(match.groups),
+	}
+	if signals.SymbolsInfo {
+		for _, info := range match.symbolsInfo {
+			if info.Bind == elf.STB_GLOBAL {
+				signals.GlobalSymbols++
+			}
+			if info.ExecutableSection {
+				signals.ExecutableSymbols++
+			}
+		}
+	}
+	return signals
+}
+
+// scoreConfidence sets the confidence of the match, if it is reported
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) scoreConfidence(match *symbolsMatch, ignored bool) {
+	if symbsLoadedGen.confidenceScorer == nil {
+		return
+	}
+	confidence := symbsLoadedGen.confidenceScorer(symbsLoadedGen.confidenceSignals(match, ignored))
+	if confidence < 0 {
+		confidence = 0
+	} else if confidence > 1 {
+		confidence = 1
+	}
+	match.confidence = confidence
+}
+
+// inLibrariesDir checks if the SO resides in one of the libraries directories
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) inLibrariesDir(soPath string) bool {
+	librariesDirs := symbsLoadedGen.librariesDirs
+	if librariesDirs == nil {
+		librariesDirs = knownLibrariesDirs
+	}
+	for _, libsDirectory := range librariesDirs {
+		if strings.HasPrefix(soPath, libsDirectory) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateConfidence checks the confidence settings for mistakes
+func validateConfidence(config symbolsLoadedSettings) []error {
+	var problems []error
+	weights := config.Detection.ConfidenceWeights
+	if config.Detection.ConfidenceScorer != nil || weights != (ConfidenceWeights{}) ||
+		config.Detection.MinConfidence != 0 {
+		if !config.Reporting.ReportConfidence {
+			problems = append(problems, fmt.Errorf("confidence settings are configured, but the confidence isn't reported"))
+		}
+	}
+	if config.Detection.ConfidenceScorer != nil && weights != (ConfidenceWeights{}) {
+		problems = append(problems, fmt.Errorf("confidence weights are configured with a custom confidence scorer"))
+	}
+	namedWeights := []struct {
+		name   string
+		weight float64
+	}{
+		{"bind", weights.Bind},
+		{"section", weights.Section},
+		{"path", weights.Path},
+		{"versioned", weights.Versioned},
+		{"groups", weights.Groups},
+	}
+	for _, named := range namedWeights {
+		if named.weight < 0 {
+			problems = append(problems, fmt.Errorf("negative %s confidence weight %v", named.name, named.weight))
+		}
+	}
+	if config.Detection.MinConfidence < 0 || config.Detection.MinConfidence > 1 {
+		problems = append(problems, fmt.Errorf("minimal confidence %v is not between 0 and 1",
+			config.Detection.MinConfidence))
+	}
+	return problems
+}
@@ -0,0 +1,24 @@
+package derive
+
+import (
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// DefaultConstructorsBaseline is the default amount of init array entries which SOs hold regardless of their code,
+// as GCC adds a frame_dummy entry to the init array of every SO
+const DefaultConstructorsBaseline = 1
+
+// countConstructors returns the amount of entries of the init array of the SO, if constructors are reported
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) countConstructors(objInfo sharedobjs.ObjInfo) (int, error) {
+	if symbsLoadedGen.constructorsCounter == nil {
+		return 0, nil
+	}
+	return symbsLoadedGen.constructorsCounter.GetConstructorsCount(objInfo)
+}
+
+// isSelfExecuting checks if the SO of the match both exports watched symbols and declares constructors (init array
+// entries beyond the baseline), so it runs its code as soon as it is loaded
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) isSelfExecuting(match *symbolsMatch) bool {
+	return symbsLoadedGen.constructorsCounter != nil && len(match.symbols) > 0 &&
+		match.initArray > symbsLoadedGen.baseConstructors
+}
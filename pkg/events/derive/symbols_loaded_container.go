@@ -0,0 +1,24 @@
+package derive
+
+import (
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// HostContainerImage is the container image reported for SOs loaded by processes which don't run in a container
+const HostContainerImage = "host"
+
+// symbolsContainer is the container of the process which loaded a SO
+type symbolsContainer struct {
+	id    string
+	image string
+}
+
+// containerOf returns the container of the process of the SO loading event, from its container context. Processes
+// which don't run in a container have an empty ID and the HostContainerImage image. The image of containers whose
+// image wasn't resolved (e.g. as the container enrichment is disabled) is empty.
+func containerOf(event *trace.Event) symbolsContainer {
+	if event.ContainerID == "" {
+		return symbolsContainer{image: HostContainerImage}
+	}
+	return symbolsContainer{id: event.ContainerID, image: event.ContainerImage}
+}
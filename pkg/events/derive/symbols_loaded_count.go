@@ -0,0 +1,43 @@
+package derive
+
+import (
+	"fmt"
+)
+
+// symbolsCountClasses are the labels of the exported symbols count classes, from the smallest SOs to the largest
+var symbolsCountClasses = []string{"tiny", "small", "medium", "large"}
+
+// DefaultSymbolsCountBoundaries are the default lower bounds of the exported symbols count of the "small",
+// "medium" and "large" classes
+var DefaultSymbolsCountBoundaries = []int{16, 256, 4096}
+
+// symbolsCountClass returns the class of the given amount of exported symbols, classified by the lower bounds of
+// the classes after the first one
+func symbolsCountClass(count int, boundaries []int) string {
+	class := 0
+	for class < len(boundaries) && count >= boundaries[class] {
+		class++
+	}
+	return symbolsCountClasses[class]
+}
+
+// validateSymbolsCountBoundaries checks the configured symbols count classes boundaries for mistakes
+func validateSymbolsCountBoundaries(boundaries []int) []error {
+	if len(boundaries) == 0 {
+		return nil
+	}
+	if len(boundaries) != len(symbolsCountClasses)-1 {
+		return []error{fmt.Errorf("symbols count boundaries should have %d entries, got %d",
+			len(symbolsCountClasses)-1, len(boundaries))}
+	}
+	var problems []error
+	for i, boundary := range boundaries {
+		if boundary <= 0 {
+			problems = append(problems, fmt.Errorf("symbols count boundary %d should be positive", boundary))
+		} else if i > 0 && boundary <= boundaries[i-1] {
+			problems = append(problems, fmt.Errorf("symbols count boundaries should be increasing, got %d after %d",
+				boundary, boundaries[i-1]))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,176 @@
+package derive
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// ErrExtractionTimeout is returned when examining a SO took longer than the configured extraction deadline
+var ErrExtractionTimeout = errors.New("symbols extraction deadline exceeded")
+
+// DefaultDeadlineWorkers is the default amount of workers examining SOs under the extraction deadline
+const DefaultDeadlineWorkers = 8
+
+// derivation is the result of a derivation which runs under a deadline
+type derivation struct {
+	args []interface{}
+	err  error
+}
+
+// deadlineJob is a derivation of the event of a SO, run by a deadline worker under the context of its deadline
+type deadlineJob struct {
+	ctx     context.Context
+	event   trace.Event
+	objInfo sharedobjs.ObjInfo
+	derive  deriveArgsFunction
+	result  chan derivation
+}
+
+// withDeadline returns a derivation function which abandons the given derivation if it takes longer than the
+// configured extraction deadline, so a single slow SO doesn't stall the worker deriving the events.
+// The derivations run on a fixed set of deadline workers. An abandoned derivation is cancelled: loaders which read
+// SOs under a context (see sharedobjs.ContextLoader) stop reading the file of the SO, and the derivation ends without
+// examining it. With other loaders, the abandoned derivation completes in the background, holding its worker. Until
+// it ends, loads of the same SO time out immediately rather than examining it again. The generator waits for the
+// deadline workers when it is closed.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) withDeadline(derive deriveArgsFunction) deriveArgsFunction {
+	return func(event trace.Event) ([]interface{}, error) {
+		// SOs loaded by processes out of the scope are not examined, so they don't need a deadline
+		if symbsLoadedGen.extractionDeadline <= 0 || !symbsLoadedGen.inScope(&event) {
+			return derive(event)
+		}
+		objInfo, err := getSharedObjectInfo(event)
+		if err != nil {
+			return nil, err
+		}
+		return symbsLoadedGen.deriveWithDeadline(event, objInfo, derive)
+	}
+}
+
+// deriveWithDeadline runs the derivation of the event of the given SO under the extraction deadline (see
+// withDeadline)
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deriveWithDeadline(event trace.Event, objInfo sharedobjs.ObjInfo,
+	derive deriveArgsFunction) ([]interface{}, error) {
+	symbsLoadedGen.abandonedMutex.Lock()
+	if symbsLoadedGen.abandoned[objInfo.Id] {
+		symbsLoadedGen.abandonedMutex.Unlock()
+		return symbsLoadedGen.extractionTimedOut(objInfo)
+	}
+	symbsLoadedGen.abandonedMutex.Unlock()
+
+	jobs, done := symbsLoadedGen.deadlineQueue()
+	if jobs == nil {
+		// The generator was closed
+		return nil, nil
+	}
+	ctx, cancel := context.WithCancel(symbsLoadedGen.deadlineCtx)
+	// Cancelling the context stops the abandoned derivation
+	defer cancel()
+	job := deadlineJob{ctx: ctx, event: event, objInfo: objInfo, derive: derive, result: make(chan derivation, 1)}
+	// The deadline includes waiting for a free worker, as the workers may be busy with abandoned derivations
+	deadline := symbsLoadedGen.clock.After(symbsLoadedGen.extractionDeadline)
+	select {
+	case jobs <- job:
+	case <-deadline:
+		return symbsLoadedGen.extractionTimedOut(objInfo)
+	case <-done:
+		return nil, nil
+	}
+
+	select {
+	case completed := <-job.result:
+		return completed.args, completed.err
+	case <-deadline:
+	}
+	symbsLoadedGen.abandonedMutex.Lock()
+	select {
+	case completed := <-job.result:
+		symbsLoadedGen.abandonedMutex.Unlock()
+		return completed.args, completed.err
+	default:
+	}
+	symbsLoadedGen.abandoned[objInfo.Id] = true
+	symbsLoadedGen.abandonedMutex.Unlock()
+	return symbsLoadedGen.extractionTimedOut(objInfo)
+}
+
+// deadlineQueue returns the channel of the jobs of the deadline workers and the channel closed when they are
+// stopped, starting the workers on the first derivation under the deadline. The channels are nil once the
+// generator is closed.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deadlineQueue() (chan<- deadlineJob, <-chan struct{}) {
+	symbsLoadedGen.deadlineMutex.Lock()
+	defer symbsLoadedGen.deadlineMutex.Unlock()
+	if symbsLoadedGen.deadlineStopped {
+		return nil, nil
+	}
+	if symbsLoadedGen.deadlineJobs == nil {
+		symbsLoadedGen.deadlineJobs = make(chan deadlineJob)
+		symbsLoadedGen.deadlineDone = make(chan struct{})
+		symbsLoadedGen.deadlineCtx, symbsLoadedGen.cancelDeadline = context.WithCancel(context.Background())
+		for i := 0; i < symbsLoadedGen.deadlineWorkers; i++ {
+			symbsLoadedGen.deadlineWG.Add(1)
+			go symbsLoadedGen.runDeadlineWorker(symbsLoadedGen.deadlineJobs, symbsLoadedGen.deadlineDone)
+		}
+	}
+	return symbsLoadedGen.deadlineJobs, symbsLoadedGen.deadlineDone
+}
+
+// runDeadlineWorker runs the derivations sent to the deadline workers, until the workers are stopped
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) runDeadlineWorker(jobs <-chan deadlineJob, done <-chan struct{}) {
+	defer symbsLoadedGen.deadlineWG.Done()
+	for {
+		select {
+		case job := <-jobs:
+			completed := symbsLoadedGen.runDeadlineJob(job)
+			// The result is sent with the lock held, so a derivation is either abandoned before it completes, or
+			// its result is received
+			symbsLoadedGen.abandonedMutex.Lock()
+			delete(symbsLoadedGen.abandoned, job.objInfo.Id)
+			job.result <- completed
+			symbsLoadedGen.abandonedMutex.Unlock()
+		case <-done:
+			return
+		}
+	}
+}
+
+// runDeadlineJob runs the derivation of the job. If the loader reads SOs under a context, the SO is read under the
+// context of the job before the derivation (which then examines its cached symbols), so abandoning the job stops the
+// reading of its file, and the SO isn't examined while the generator may be closing.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) runDeadlineJob(job deadlineJob) derivation {
+	if symbsLoadedGen.contextLoader != nil {
+		// Failures unrelated to the context are returned by the derivation, which reads the SO again. Once the
+		// context is done, the derivation was abandoned or the generator was closed, so nothing is derived.
+		if err := symbsLoadedGen.contextLoader.LoadContext(job.ctx, job.objInfo); err != nil && job.ctx.Err() != nil {
+			return derivation{}
+		}
+	}
+	args, err := job.derive(job.event)
+	return derivation{args: args, err: err}
+}
+
+// stopDeadlineWorkers cancels the derivations of the deadline workers, and stops the workers once they end. It
+// doesn't wait for them, as their derivations may be waiting for the lock of Close.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) stopDeadlineWorkers() {
+	symbsLoadedGen.deadlineMutex.Lock()
+	defer symbsLoadedGen.deadlineMutex.Unlock()
+	if symbsLoadedGen.deadlineStopped {
+		return
+	}
+	symbsLoadedGen.deadlineStopped = true
+	if symbsLoadedGen.deadlineJobs != nil {
+		symbsLoadedGen.cancelDeadline()
+		close(symbsLoadedGen.deadlineDone)
+	}
+}
+
+// extractionTimedOut counts and logs a SO whose derivation was abandoned, and returns the timeout error
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) extractionTimedOut(objInfo sharedobjs.ObjInfo) (
+	[]interface{}, error) {
+	symbsLoadedGen.stats.ExtractionTimeouts.Increment()
+	symbsLoadedGen.log(LogLevelWarn, DecisionTimeout, objInfo, symbsLoadedGen.extractionDeadline.String())
+	return nil, ErrExtractionTimeout
+}
@@ -0,0 +1,75 @@
+package derive
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// DemangledMatchMode is how the watched demangled symbols are matched with the demangled exported C++ symbols
+type DemangledMatchMode int
+
+const (
+	// MatchDemangledNames matches the qualified names of the exported C++ symbols (e.g. "Foo::get"), so all the
+	// overloads of a watched name are matched
+	MatchDemangledNames DemangledMatchMode = iota
+	// MatchDemangledSignatures matches the full signatures of the exported C++ symbols, as printed by c++filt (e.g.
+	// "Foo::get(int) const"), so a single overload is matched
+	MatchDemangledSignatures
+)
+
+// mangledSymbolPrefix starts the names of the symbols mangled by the Itanium C++ ABI
+const mangledSymbolPrefix = "_Z"
+
+// matchWatchedDemangledSymbols returns the watched demangled symbols which the SO exports, sorted. Every mangled
+// exported symbol of the SO is demangled, in the configured match mode, and symbols which can't be demangled are
+// not matched.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchWatchedDemangledSymbols(
+	objInfo sharedobjs.ObjInfo) ([]string, error) {
+	if symbsLoadedGen.watchedDemangled == nil {
+		return nil, nil
+	}
+	soSyms, err := symbsLoadedGen.soLoader.GetExportedSymbols(objInfo)
+	if err != nil {
+		return nil, err
+	}
+	demangle := sharedobjs.DemangleName
+	if symbsLoadedGen.demangledMode == MatchDemangledSignatures {
+		demangle = sharedobjs.DemangleSignature
+	}
+	matchedSet := make(map[string]bool)
+	for sym := range soSyms {
+		if !strings.HasPrefix(sym, mangledSymbolPrefix) {
+			continue
+		}
+		demangled, err := demangle(sym)
+		if err != nil {
+			continue
+		}
+		if symbsLoadedGen.watchedDemangled[demangled] {
+			matchedSet[demangled] = true
+		}
+	}
+	return sortedKeys(matchedSet), nil
+}
+
+// validateDemangledSymbols checks the watched demangled symbols and their match mode
+func validateDemangledSymbols(config SymbolsMatchingConfig) []error {
+	var problems []error
+	if config.DemangledMatch != MatchDemangledNames && config.DemangledMatch != MatchDemangledSignatures {
+		problems = append(problems, fmt.Errorf("unknown demangled match mode %d", config.DemangledMatch))
+	} else if config.DemangledMatch == MatchDemangledSignatures && len(config.WatchedDemangledSymbols) == 0 {
+		problems = append(problems, fmt.Errorf("demangled signatures are matched with no watched demangled symbols"))
+	}
+	for _, entry := range config.WatchedDemangledSymbols {
+		switch {
+		case entry == "":
+			problems = append(problems, fmt.Errorf("empty watched demangled symbol entry"))
+		case strings.HasPrefix(entry, mangledSymbolPrefix):
+			problems = append(problems, fmt.Errorf("watched demangled symbol '%s' is mangled, and should be a "+
+				"watched symbol", entry))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,238 @@
+package derive
+
+import (
+	"debug/elf"
+	"fmt"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// SymbolsDetectionConfig is the configuration of the detections of suspicious properties of SOs and of their symbols,
+// beyond the names of the watched symbols
+type SymbolsDetectionConfig struct {
+	// The expected indexes of watched symbols in the dynamic symbols table of the SOs exporting them (see
+	// sharedobjs.SymbolInfo.Index), for libraries whose table order is part of their ABI. Matched symbols at another
+	// index are added to the event, as "<symbol>=<index>".
+	ExpectedSymbolIndexes map[string]int
+	// The expected size ranges of watched symbols, by their names. A watched symbol with an expected range is matched
+	// only if its size is out of the range, or is 0, e.g. to detect a function replaced by a hollow stub. The size of
+	// each matched symbol is added to the event.
+	ExpectedSymbolSizes map[string]SymbolSizeRange
+	// Symbols which SOs should export, by their soname. The expected symbols which a SO with the soname doesn't
+	// export are added to the event, and the event is derived if any of them is missing.
+	ExpectedSymbols map[string][]string
+	// Named sets of symbols characteristic of libraries (e.g. of libc), matched by the part of their symbols which a
+	// SO exports rather than by its name, to recognize libraries shipped under a disguised name. The name of the
+	// matched set and the part of its symbols which the SO exports are added to the event.
+	SymbolSets []SymbolSet
+	// How SOs with segments which are both writable and executable are reported. The offending segments are added
+	// to the event.
+	WXSegments WXSegmentsMode
+	// Dynamic tags whose declaration by a SO is suspicious (e.g. DefaultFlaggedDynamicTags). SOs declaring any of
+	// them derive the event even if they match nothing else, and the declared flagged tags are added to the event.
+	FlaggedDynamicTags []elf.DynTag
+	// Flag ELF files which request an interpreter (PT_INTERP) that doesn't reside in the libraries directories, as
+	// the dynamic loaders of the distributions do. Such files derive the event even if they match nothing else, and
+	// the requested interpreter is added to the event.
+	FlagUnusualInterpreter bool
+	// The weakly defined exported symbols of standard libraries, by their soname (see WeakSymbolsFromObjects).
+	// SOs providing a strong definition of a watched symbol which is weak in another of these libraries derive the
+	// weak_symbol_overridden event.
+	WeakSymbols map[string][]string
+	// Flag SOs whose code has a higher entropy than EntropyThreshold, as packed or encrypted code has. Such SOs
+	// derive the event even if they match nothing else, and the measured entropy is added to the event. The SO
+	// loader should measure the entropy (see sharedobjs.HostSymbolsLoaderConfig.MeasureEntropy).
+	FlagHighEntropy bool
+	// The entropy of code (in bits per byte, up to 8) above which it is flagged. If 0, DefaultEntropyThreshold is
+	// used.
+	EntropyThreshold float64
+	// Relocation types (by their name, e.g. "R_X86_64_IRELATIVE" or "R_X86_64_COPY") and the amount of dynamic
+	// relocations of the type which a SO may have. SOs with more relocations of any of the types derive the event even
+	// if they match nothing else, and the counts of the types are added to the event. The SO loader should count the
+	// relocations (see sharedobjs.HostSymbolsLoaderConfig.CountRelocations).
+	RelocationThresholds map[string]int
+	// The scorer of the confidence of the matches. If nil, WeightedConfidence with ConfidenceWeights is used.
+	ConfidenceScorer ConfidenceScorer
+	// The weights of the signals in the default confidence scorer. If zero, DefaultConfidenceWeights are used.
+	ConfidenceWeights ConfidenceWeights
+	// Matches whose confidence is lower than this are not derived. Matches of always watched symbols are derived
+	// regardless of their confidence.
+	MinConfidence float64
+}
+
+// symbolsDetector is the detection feature of the generator: the suspicious properties of SOs and of their symbols,
+// beyond the names of the watched symbols
+type symbolsDetector struct {
+	expectedIndexes    map[string]int                  // The expected indexes of watched symbols, set only if configured
+	expectedSizes      map[string]SymbolSizeRange      // The expected size ranges of watched symbols, set only if configured
+	expectedSymbols    map[string]map[string]bool      // The expected symbols by soname, set only if configured
+	weakSymbols        map[string][]string             // The sonames defining each symbol weakly, set only if configured
+	weakInfoLoader     sharedobjs.SymbolsInfoLoader    // Set only if weak symbols are configured
+	symbolSets         []symbolSet                     // In their configured order
+	wxDetector         sharedobjs.WritableCodeDetector // Set only if W^X violations are examined
+	reportWXOnly       bool                            // Derive the event for SOs with W^X violations and no match
+	dynTagsLoader      sharedobjs.DynamicTagsLoader    // Set only if flagged dynamic tags are configured
+	flaggedTags        map[elf.DynTag]bool             // The configured flagged dynamic tags
+	interpLoader       sharedobjs.InterpreterLoader    // Set only if unusual interpreters are flagged
+	entropyMeasurer    sharedobjs.EntropyMeasurer      // Set only if high entropy code is flagged
+	entropyThreshold   float64                         // The entropy above which code is flagged
+	relocationsCounter sharedobjs.RelocationsCounter   // Set only if relocation thresholds are configured
+	relocThresholds    map[string]int                  // The configured amount of relocations of each type
+	confidenceScorer   ConfidenceScorer                // Set only if the confidence is reported
+	minConfidence      float64
+}
+
+// init sets up the detections with the given configuration. The confidence of the matches is scored only if it is
+// reported.
+func (detector *symbolsDetector) init(soLoader sharedobjs.DynamicSymbolsLoader, config SymbolsDetectionConfig,
+	scoreConfidence bool) error {
+	if len(config.ExpectedSymbolSizes) > 0 {
+		detector.expectedSizes = make(map[string]SymbolSizeRange, len(config.ExpectedSymbolSizes))
+		for sym, sizeRange := range config.ExpectedSymbolSizes {
+			detector.expectedSizes[sym] = sizeRange
+		}
+	}
+	if len(config.ExpectedSymbolIndexes) > 0 {
+		detector.expectedIndexes = make(map[string]int, len(config.ExpectedSymbolIndexes))
+		for sym, index := range config.ExpectedSymbolIndexes {
+			detector.expectedIndexes[sym] = index
+		}
+	}
+	if len(config.ExpectedSymbols) > 0 {
+		detector.expectedSymbols = newSonameSymbolsSets(config.ExpectedSymbols)
+	}
+	if len(config.WeakSymbols) > 0 {
+		infoLoader, ok := soLoader.(sharedobjs.SymbolsInfoLoader)
+		if !ok {
+			return fmt.Errorf("weak symbols are configured, but the SO loader doesn't supply symbols information")
+		}
+		detector.weakInfoLoader = infoLoader
+		detector.weakSymbols = newWeakSymbols(config.WeakSymbols)
+	}
+	if len(config.SymbolSets) > 0 {
+		detector.symbolSets = newSymbolSets(config.SymbolSets)
+	}
+
+	if config.WXSegments != WXSegmentsIgnore {
+		wxDetector, ok := soLoader.(sharedobjs.WritableCodeDetector)
+		if !ok {
+			return fmt.Errorf("W^X violations detection is configured, but the SO loader can't read segments")
+		}
+		detector.wxDetector = wxDetector
+		detector.reportWXOnly = config.WXSegments == WXSegmentsReport
+	}
+	if len(config.FlaggedDynamicTags) > 0 {
+		tagsLoader, ok := soLoader.(sharedobjs.DynamicTagsLoader)
+		if !ok {
+			return fmt.Errorf("flagged dynamic tags are configured, but the SO loader can't read dynamic tags")
+		}
+		detector.dynTagsLoader = tagsLoader
+		detector.flaggedTags = make(map[elf.DynTag]bool, len(config.FlaggedDynamicTags))
+		for _, tag := range config.FlaggedDynamicTags {
+			detector.flaggedTags[tag] = true
+		}
+	}
+	if config.FlagUnusualInterpreter {
+		interpLoader, ok := soLoader.(sharedobjs.InterpreterLoader)
+		if !ok {
+			return fmt.Errorf("unusual interpreters are flagged, but the SO loader can't read interpreters")
+		}
+		detector.interpLoader = interpLoader
+	}
+	if config.FlagHighEntropy {
+		measurer, ok := soLoader.(sharedobjs.EntropyMeasurer)
+		if !ok {
+			return fmt.Errorf("high entropy code is flagged, but the SO loader can't measure entropy")
+		}
+		detector.entropyMeasurer = measurer
+		detector.entropyThreshold = config.EntropyThreshold
+		if detector.entropyThreshold <= 0 {
+			detector.entropyThreshold = DefaultEntropyThreshold
+		}
+	}
+	if len(config.RelocationThresholds) > 0 {
+		counter, ok := soLoader.(sharedobjs.RelocationsCounter)
+		if !ok {
+			return fmt.Errorf("relocation thresholds are configured, but the SO loader can't count relocations")
+		}
+		detector.relocationsCounter = counter
+		detector.relocThresholds = make(map[string]int, len(config.RelocationThresholds))
+		for relType, threshold := range config.RelocationThresholds {
+			detector.relocThresholds[relType] = threshold
+		}
+	}
+
+	if scoreConfidence {
+		detector.confidenceScorer = config.ConfidenceScorer
+		if detector.confidenceScorer == nil {
+			weights := config.ConfidenceWeights
+			if weights == (ConfidenceWeights{}) {
+				weights = DefaultConfidenceWeights
+			}
+			detector.confidenceScorer = WeightedConfidence(weights)
+		}
+		detector.minConfidence = config.MinConfidence
+	}
+	return nil
+}
+
+// validate checks the detection configuration for mistakes
+func (config SymbolsDetectionConfig) validate() []error {
+	var problems []error
+	if config.WXSegments < WXSegmentsIgnore || config.WXSegments > WXSegmentsReportMatched {
+		problems = append(problems, fmt.Errorf("unknown W^X segments mode %d", config.WXSegments))
+	}
+	for _, tag := range config.FlaggedDynamicTags {
+		if tag == elf.DT_NULL {
+			problems = append(problems, fmt.Errorf("DT_NULL can't be a flagged dynamic tag, as it terminates the dynamic section"))
+		}
+	}
+	if config.EntropyThreshold < 0 || config.EntropyThreshold > 8 {
+		problems = append(problems, fmt.Errorf("entropy threshold %v is not between 0 and 8",
+			config.EntropyThreshold))
+	}
+	if config.EntropyThreshold != 0 && !config.FlagHighEntropy {
+		problems = append(problems, fmt.Errorf("entropy threshold is configured with no high entropy flagging"))
+	}
+	problems = append(problems, validateSonameSymbols("expected", config.ExpectedSymbols)...)
+	problems = append(problems, validateSonameSymbols("weak", config.WeakSymbols)...)
+	problems = append(problems, validateSymbolSets(config.SymbolSets)...)
+	problems = append(problems, validateRelocationThresholds(config.RelocationThresholds)...)
+	problems = append(problems, validateSymbolIndexes(config.ExpectedSymbolIndexes)...)
+	problems = append(problems, validateSymbolSizes(config.ExpectedSymbolSizes)...)
+	return problems
+}
+
+// detectObject examines the SO of the match for the configured suspicious properties
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) detectObject(match *symbolsMatch) error {
+	var err error
+	objInfo := match.objInfo
+	if match.missing, err = symbsLoadedGen.matchMissingSymbols(match); err != nil {
+		return err
+	}
+	if match.symbolSet, match.setRatio, err = symbsLoadedGen.matchSymbolSets(objInfo); err != nil {
+		return err
+	}
+	if match.wxSegments, err = symbsLoadedGen.matchWXSegments(objInfo); err != nil {
+		return err
+	}
+	if match.dynamicTags, err = symbsLoadedGen.matchFlaggedDynamicTags(objInfo); err != nil {
+		return err
+	}
+	if match.interp, err = symbsLoadedGen.requestedInterpreter(objInfo); err != nil {
+		return err
+	}
+	if match.entropy, err = symbsLoadedGen.measureEntropy(objInfo); err != nil {
+		return err
+	}
+	match.relocCounts, match.overLimit, err = symbsLoadedGen.matchRelocationThresholds(objInfo)
+	return err
+}
+
+// hasDetection checks if the SO of the match has any of the suspicious properties which derive the event
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) hasDetection(match *symbolsMatch) bool {
+	return len(match.missing) > 0 || match.symbolSet != "" ||
+		(symbsLoadedGen.reportWXOnly && len(match.wxSegments) > 0) || len(match.dynamicTags) > 0 ||
+		symbsLoadedGen.isUnusualInterpreter(match.interp) || symbsLoadedGen.isHighEntropy(match.entropy) ||
+		len(match.overLimit) > 0
+}
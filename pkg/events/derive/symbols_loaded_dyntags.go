@@ -0,0 +1,41 @@
+package derive
+
+import (
+	"debug/elf"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// DefaultFlaggedDynamicTags are dynamic tags which are abused to hook the dynamic loader: DT_AUDIT and DT_DEPAUDIT
+// load auditing libraries which intercept the symbols binding, and DT_PREINIT_ARRAY runs code before the
+// initialization of any SO
+var DefaultFlaggedDynamicTags = []elf.DynTag{elf.DT_AUDIT, elf.DT_DEPAUDIT, elf.DT_PREINIT_ARRAY}
+
+// matchFlaggedDynamicTags returns the names of the flagged dynamic tags which the given SO declares, if they are
+// examined. The tags are returned in the order of their values.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchFlaggedDynamicTags(objInfo sharedobjs.ObjInfo) (
+	[]string, error) {
+	if symbsLoadedGen.dynTagsLoader == nil {
+		return nil, nil
+	}
+	tags, err := symbsLoadedGen.dynTagsLoader.GetDynamicTags(objInfo)
+	if err != nil {
+		return nil, err
+	}
+	var flagged []string
+	for _, tag := range tags {
+		if symbsLoadedGen.flaggedTags[tag] {
+			flagged = append(flagged, dynamicTagName(tag))
+		}
+	}
+	return flagged, nil
+}
+
+// dynamicTagName returns the name of the dynamic tag. DT_ENCODING is not a tag but the start of the range of tags
+// whose values follow the encoding rules, and it shares its value with DT_PREINIT_ARRAY, which is named instead.
+func dynamicTagName(tag elf.DynTag) string {
+	if tag == elf.DT_PREINIT_ARRAY {
+		return "DT_PREINIT_ARRAY"
+	}
+	return tag.String()
+}
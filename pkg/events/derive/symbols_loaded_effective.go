@@ -0,0 +1,155 @@
+package derive
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// SymbolsLoadedEffectiveConfig is the configuration which a generator applies, as read from its state rather than
+// from the configuration it was initialized with. The entries are as the generator matches them, so they may differ
+// from the configured ones: alias classes are expanded, redundant prefixes are dropped, and whitelisted paths are
+// normalized. The lists are sorted, except for the ordered ones.
+type SymbolsLoadedEffectiveConfig struct {
+	Enabled              bool                // Whether the generator derives events (see SetEnabled)
+	Closed               bool                // Whether the generator was closed
+	WatchedSymbols       []string            // The full names of the watched symbols
+	WatchedPrefixes      []string            // The watched prefixes, without the wildcard
+	ExcludedSymbols      []string            // Set only if prefixes are watched
+	AlwaysWatchedSymbols []string            // The symbols matched in ignored SOs too
+	LibrarySymbols       map[string][]string // The libraries each library limited watched symbol is watched in
+	WatchedImports       []string
+	WatchedTLSSymbols    []string
+	WatchedVersioned     []string // The watched "<symbol>@<version>" entries
+	WatchedDemangled     []string
+	WatchGroups          []string // The names of the watch groups, in order of priority
+	Rules                []string // The names of the rules, in their configured order
+	Whitelist            SymbolsLoadedWhitelist
+	LibrariesDirs        []string // The libraries directories the whitelisted libraries are matched in
+	Interpreter          InterpreterMode
+	// The names of the enabled modes of the generator (e.g. "allowlist" or "async"). See effectiveModes for the
+	// possible modes.
+	Modes []string
+	// The names of the arguments of the derived event, in their order
+	Arguments []string
+}
+
+// SymbolsLoadedWhitelist is the effective whitelist of a generator, by the kind of its entries
+type SymbolsLoadedWhitelist struct {
+	PathPrefixes []string // Absolute paths prefixes, ending with a slash if they are directories
+	Libraries    []string // Libraries names prefixes, matched in the libraries directories
+	Globs        []string // Glob patterns of libraries names
+	Regexps      []string // Regular expressions of full paths
+}
+
+// EffectiveConfig returns the configuration which the generator currently applies, for auditing what it watches.
+// It reflects the changes to the generator state since it was initialized, and is safe to call concurrently with
+// derivations.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) EffectiveConfig() SymbolsLoadedEffectiveConfig {
+	symbsLoadedGen.closeMutex.RLock()
+	closed := symbsLoadedGen.closed
+	symbsLoadedGen.closeMutex.RUnlock()
+
+	config := SymbolsLoadedEffectiveConfig{
+		Enabled:              atomic.LoadInt32(&symbsLoadedGen.disabled) == 0 && !closed,
+		Closed:               closed,
+		WatchedSymbols:       sortedKeys(symbsLoadedGen.watchedSymbols),
+		ExcludedSymbols:      sortedKeys(symbsLoadedGen.excludedSymbols),
+		AlwaysWatchedSymbols: sortedKeys(symbsLoadedGen.alwaysWatched),
+		WatchedImports:       sortedKeys(symbsLoadedGen.watchedImports),
+		WatchedTLSSymbols:    sortedKeys(symbsLoadedGen.watchedTLS),
+		WatchedVersioned:     sortedKeys(symbsLoadedGen.watchedVersioned),
+		WatchedDemangled:     sortedKeys(symbsLoadedGen.watchedDemangled),
+		Whitelist: SymbolsLoadedWhitelist{
+			PathPrefixes: sortedCopy(symbsLoadedGen.pathPrefixWhitelist),
+			Libraries:    sortedCopy(symbsLoadedGen.librariesWhitelist),
+			Globs:        sortedCopy(symbsLoadedGen.librariesGlobs),
+		},
+		LibrariesDirs: symbsLoadedGen.librariesDirs,
+		Interpreter:   symbsLoadedGen.interpreterMode,
+		Modes:         symbsLoadedGen.effectiveModes(),
+	}
+	if config.LibrariesDirs == nil {
+		config.LibrariesDirs = knownLibrariesDirs
+	}
+	config.LibrariesDirs = append([]string(nil), config.LibrariesDirs...)
+	if symbsLoadedGen.watchedPrefixes != nil {
+		config.WatchedPrefixes = symbsLoadedGen.watchedPrefixes.prefixes()
+	}
+	if len(symbsLoadedGen.librarySymbols) > 0 {
+		config.LibrarySymbols = make(map[string][]string, len(symbsLoadedGen.librarySymbols))
+		for sym, libraries := range symbsLoadedGen.librarySymbols {
+			config.LibrarySymbols[sym] = sortedCopy(libraries)
+		}
+	}
+	for _, group := range symbsLoadedGen.watchGroups {
+		config.WatchGroups = append(config.WatchGroups, group.name)
+	}
+	for _, rule := range symbsLoadedGen.rules {
+		config.Rules = append(config.Rules, rule.Name)
+	}
+	for _, expr := range symbsLoadedGen.regexpsWhitelist {
+		config.Whitelist.Regexps = append(config.Whitelist.Regexps, expr.String())
+	}
+	sort.Strings(config.Whitelist.Regexps)
+	for _, param := range symbsLoadedGen.skeleton.Params {
+		config.Arguments = append(config.Arguments, param.Name)
+	}
+	return config
+}
+
+// effectiveModes returns the names of the enabled modes of the generator, in alphabetical order
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) effectiveModes() []string {
+	modes := []struct {
+		name    string
+		enabled bool
+	}{
+		{"allowlist", symbsLoadedGen.allowlistMode},
+		{"async", symbsLoadedGen.asyncQueue != nil},
+		{"build-ids", symbsLoadedGen.inventory != nil},
+		{"capabilities", symbsLoadedGen.capabilities != nil},
+		{"changes", symbsLoadedGen.history != nil},
+		{"compact-symbols", symbsLoadedGen.compactSymbols},
+		{"executable-sections-only", symbsLoadedGen.executableOnly},
+		{"hash-only", symbsLoadedGen.hasher != nil && symbsLoadedGen.hashOnly},
+		{"high-entropy", symbsLoadedGen.entropyMeasurer != nil},
+		{"metadata-only", symbsLoadedGen.metadataLoader != nil},
+		{"process-scope", symbsLoadedGen.scope != nil},
+		{"profiles", symbsLoadedGen.profiles != nil},
+		{"soname-paths", symbsLoadedGen.sonamePaths != nil},
+		{"stop-on-first-match", symbsLoadedGen.stopOnFirstMatch},
+		{"summaries", symbsLoadedGen.summary != nil},
+		{"suppress-unchanged", symbsLoadedGen.suppressUnchanged},
+		{"unusual-interpreter", symbsLoadedGen.interpLoader != nil},
+		{"wx-segments-only", symbsLoadedGen.reportWXOnly},
+	}
+	var enabled []string
+	for _, mode := range modes {
+		if mode.enabled {
+			enabled = append(enabled, mode.name)
+		}
+	}
+	return enabled
+}
+
+// sortedKeys returns the keys of the set in alphabetical order, or nil if it is empty
+func sortedKeys(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedCopy returns a sorted copy of the list, or nil if it is empty
+func sortedCopy(list []string) []string {
+	if len(list) == 0 {
+		return nil
+	}
+	sorted := append([]string(nil), list...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,93 @@
+package derive
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// MatchContext is a match of the symbols_loaded derivation, given to the enrichment callback
+type MatchContext struct {
+	Symbols []string // The matched symbols, by their names (even if their hashes are reported) and before truncation
+	Path    string   // The path of the loaded SO
+	Soname  string   // The DT_SONAME of the SO, or empty if it has none
+	Pid     int      // The host PID of the process which loaded the SO
+}
+
+// SymbolsEnrichment adds fields from external sources (e.g. threat intelligence tags of symbols) to the
+// symbols_loaded events
+type SymbolsEnrichment struct {
+	// The fields added to the events, appended to their arguments in this order. Their types are of the types of
+	// the symbols_loaded arguments (e.g. "const char*const*" for tags).
+	Fields []trace.ArgMeta
+	// Called for each match during the derivation, with the extraction deadline running, so it should be fast and
+	// not block. It returns the values of the fields by their names: fields it doesn't return are empty, and values
+	// which are not of a field are ignored. A value of the wrong Go type fails the derivation of the event.
+	Enrich func(match MatchContext) map[string]interface{}
+}
+
+// validateEnrichment checks the enrichment fields for mistakes
+func validateEnrichment(enrichment SymbolsEnrichment) []error {
+	var problems []error
+	if len(enrichment.Fields) > 0 && enrichment.Enrich == nil {
+		problems = append(problems, fmt.Errorf("enrichment fields are configured without an enrichment callback"))
+	}
+	names := make(map[string]bool, len(enrichment.Fields))
+	for _, field := range enrichment.Fields {
+		if field.Name == "" {
+			problems = append(problems, fmt.Errorf("enrichment field of type '%s' has no name", field.Type))
+			continue
+		}
+		if names[field.Name] {
+			problems = append(problems, fmt.Errorf("enrichment field '%s' is configured more than once", field.Name))
+		}
+		names[field.Name] = true
+		if _, ok := argValueTypes[field.Type]; !ok {
+			problems = append(problems, fmt.Errorf("enrichment field '%s' type '%s' is not supported", field.Name,
+				field.Type))
+		}
+	}
+	return problems
+}
+
+// addEnrichmentArgs adds the enrichment fields to the arguments of the derived event. It should be called after
+// all the other optional arguments are added, so fields which clash with any of them are found.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) addEnrichmentArgs(enrichment SymbolsEnrichment) error {
+	params := make(map[string]bool, len(symbsLoadedGen.skeleton.Params))
+	for _, param := range symbsLoadedGen.skeleton.Params {
+		params[param.Name] = true
+	}
+	for _, field := range enrichment.Fields {
+		if params[field.Name] {
+			return fmt.Errorf("enrichment field '%s' is already an argument of the event", field.Name)
+		}
+		emptyValue := reflect.Zero(argValueTypes[field.Type]).Interface()
+		name := field.Name
+		symbsLoadedGen.addExtraArg(field, func(match *symbolsMatch) interface{} {
+			if value, ok := match.enriched[name]; ok {
+				return value
+			}
+			return emptyValue
+		})
+	}
+	return nil
+}
+
+// enrich calls the enrichment callback with the match, and keeps the fields it returns in the match
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) enrich(match *symbolsMatch) error {
+	if symbsLoadedGen.enrichment == nil {
+		return nil
+	}
+	soname, err := symbsLoadedGen.sonameLoader.GetSoname(match.objInfo)
+	if err != nil {
+		return err
+	}
+	match.enriched = symbsLoadedGen.enrichment(MatchContext{
+		Symbols: append([]string(nil), match.symbols...),
+		Path:    match.objInfo.Path,
+		Soname:  soname,
+		Pid:     match.objInfo.Pid,
+	})
+	return nil
+}
@@ -0,0 +1,22 @@
+package derive
+
+import (
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// DefaultEntropyThreshold is the default entropy of code (in bits per byte) above which a SO is flagged as packed or
+// encrypted. Compiled code rarely exceeds 6.5, while compressed or encrypted content is close to 8.
+const DefaultEntropyThreshold = 7.2
+
+// measureEntropy returns the entropy of the code of the SO, if high entropy code is flagged
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) measureEntropy(objInfo sharedobjs.ObjInfo) (float64, error) {
+	if symbsLoadedGen.entropyMeasurer == nil {
+		return 0, nil
+	}
+	return symbsLoadedGen.entropyMeasurer.GetCodeEntropy(objInfo)
+}
+
+// isHighEntropy checks if code of the given entropy is flagged as packed or encrypted
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) isHighEntropy(entropy float64) bool {
+	return symbsLoadedGen.entropyMeasurer != nil && entropy > symbsLoadedGen.entropyThreshold
+}
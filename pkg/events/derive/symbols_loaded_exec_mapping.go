@@ -0,0 +1,322 @@
+package derive
+
+import (
+	"debug/elf"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aquasecurity/tracee/pkg/events"
+	"github.com/aquasecurity/tracee/pkg/events/parse"
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+	"github.com/aquasecurity/tracee/types/trace"
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+const (
+	// DefaultExecMappingTimeout is the default duration which a load is held for until its SO is mapped executable
+	DefaultExecMappingTimeout = 2 * time.Second
+	// DefaultMaxPendingLoads is the default maximal amount of loads held until their SO is mapped executable
+	DefaultMaxPendingLoads = 1024
+)
+
+// expiredLoadsBuffer is the amount of events of expired loads kept until they are read
+const expiredLoadsBuffer = 64
+
+// protExec is the protection flag of executable mappings (PROT_EXEC)
+const protExec = 0x4
+
+// pendingLoadKey identifies the loads of a SO by a process
+type pendingLoadKey struct {
+	id  sharedobjs.ObjID
+	pid int
+}
+
+// pendingLoad is a load whose event is held until its SO is mapped executable
+type pendingLoad struct {
+	event   trace.Event // The SO loading event, whose context the derived event is emitted with
+	args    []interface{}
+	objInfo sharedobjs.ObjInfo
+	taken   chan struct{} // Closed when the load is taken before it expires
+}
+
+// pendingLoads holds the loads which wait for their SO to be mapped executable. The amount of loads is bounded, and
+// the load which was held longest expires early when a load is held beyond the bound. It is safe for concurrent use.
+type pendingLoads struct {
+	mutex sync.Mutex
+	loads *simplelru.LRU // pendingLoadKey -> *pendingLoad
+	size  int
+}
+
+func newPendingLoads(size int) *pendingLoads {
+	loads, _ := simplelru.NewLRU(size, nil)
+	return &pendingLoads{loads: loads, size: size}
+}
+
+// has returns whether a load of the SO by the process is held
+func (pending *pendingLoads) has(key pendingLoadKey) bool {
+	pending.mutex.Lock()
+	defer pending.mutex.Unlock()
+	return pending.loads.Contains(key)
+}
+
+// add holds the load, and returns the load which was evicted to keep the bound, if any
+func (pending *pendingLoads) add(key pendingLoadKey, load *pendingLoad) *pendingLoad {
+	pending.mutex.Lock()
+	defer pending.mutex.Unlock()
+	var evicted *pendingLoad
+	if pending.loads.Len() >= pending.size {
+		if _, oldest, ok := pending.loads.RemoveOldest(); ok {
+			evicted = oldest.(*pendingLoad)
+			close(evicted.taken)
+		}
+	}
+	pending.loads.Add(key, load)
+	return evicted
+}
+
+// take removes the held load of the SO by the process and returns it, or nil if none is held
+func (pending *pendingLoads) take(key pendingLoadKey) *pendingLoad {
+	pending.mutex.Lock()
+	defer pending.mutex.Unlock()
+	value, ok := pending.loads.Peek(key)
+	if !ok {
+		return nil
+	}
+	pending.loads.Remove(key)
+	load := value.(*pendingLoad)
+	close(load.taken)
+	return load
+}
+
+// takeFile removes the held loads of the file with the given path and ctime by the process and returns them, for
+// mapping events which don't identify the file by its device and inode
+func (pending *pendingLoads) takeFile(pid int, path string, ctime uint64) []*pendingLoad {
+	pending.mutex.Lock()
+	defer pending.mutex.Unlock()
+	var taken []*pendingLoad
+	for _, key := range pending.loads.Keys() {
+		loadKey := key.(pendingLoadKey)
+		if loadKey.pid != pid || loadKey.id.Ctime != ctime {
+			continue
+		}
+		value, _ := pending.loads.Peek(key)
+		load := value.(*pendingLoad)
+		if load.objInfo.Path != path {
+			continue
+		}
+		pending.loads.Remove(key)
+		close(load.taken)
+		taken = append(taken, load)
+	}
+	return taken
+}
+
+// expire removes the given load if it is still held, and returns whether it was
+func (pending *pendingLoads) expire(key pendingLoadKey, load *pendingLoad) bool {
+	pending.mutex.Lock()
+	defer pending.mutex.Unlock()
+	value, ok := pending.loads.Peek(key)
+	if !ok || value.(*pendingLoad) != load {
+		return false
+	}
+	pending.loads.Remove(key)
+	return true
+}
+
+// SymbolsLoadedExecMapping receives the generator of the symbols_loaded event as a closure argument, and correlates
+// the loads of SOs with the mappings which made them executable (see SymbolsTrackingConfig.CorrelateExecMapping).
+// It should receive the security_mmap_file and security_file_mprotect events. Readable mappings of SOs are examined
+// as loads, whose symbols_loaded event is held until a later mapping of the same process grants the SO execution.
+// The held event is derived from the mapping which grants execution, with the context of the readable mapping.
+func SymbolsLoadedExecMapping(gen *SymbolsLoadedEventGenerator) events.DeriveFunction {
+	return func(event trace.Event) ([]trace.Event, []error) {
+		if gen.pending == nil {
+			return []trace.Event{}, nil
+		}
+		switch events.ID(event.EventID) {
+		case events.SecurityMmapFile:
+			return gen.deriveCorrelated(event, false)
+		case events.SecurityFileMprotect:
+			return gen.deriveMprotect(event)
+		}
+		return []trace.Event{}, nil
+	}
+}
+
+// deriveCorrelated derives the symbols_loaded event of a mapping of a SO in the exec mapping correlation mode.
+// Mappings which grant execution release the held load of the SO by the process, if any. Otherwise, loading events
+// (as opposed to mapping events of any file) derive the event as usual, noting that execution was granted.
+// Readable mappings are examined, and their event is held until the SO is mapped executable or the load expires.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deriveCorrelated(event trace.Event, loading bool) (
+	[]trace.Event, []error) {
+	objInfo, err := getSharedObjectInfo(event)
+	if err != nil {
+		return nil, []error{err}
+	}
+	executable, err := grantsExecution(&event)
+	if err != nil {
+		return nil, []error{err}
+	}
+	key := pendingLoadKey{id: objInfo.Id, pid: objInfo.Pid}
+	derive := symbsLoadedGen.withDeadline(symbsLoadedGen.deriveArgs)
+
+	if executable {
+		if load := symbsLoadedGen.pending.take(key); load != nil {
+			return symbsLoadedGen.grantExecution(load)
+		}
+		// Executable mappings of SOs which were not held are derived from their loading event
+		if !loading {
+			return []trace.Event{}, nil
+		}
+		return singleSkeletonDeriveFunc(symbsLoadedGen.skeleton, func(event trace.Event) ([]interface{}, error) {
+			args, err := derive(event)
+			if args != nil {
+				args[symbsLoadedGen.execGrantedArg] = true
+			}
+			return args, err
+		})(event)
+	}
+
+	// The SO is examined once until it is mapped executable, however many readable mappings it has
+	if symbsLoadedGen.pending.has(key) {
+		return []trace.Event{}, nil
+	}
+	args, err := derive(event)
+	if err != nil {
+		// Most readable mappings are of data files rather than SOs
+		var formatErr *elf.FormatError
+		if !loading && (errors.As(err, &formatErr) || errors.Is(err, io.EOF)) {
+			return []trace.Event{}, nil
+		}
+		return nil, []error{err}
+	}
+	if args != nil {
+		symbsLoadedGen.hold(key, &pendingLoad{event: event, args: args, objInfo: objInfo, taken: make(chan struct{})})
+	}
+	return []trace.Event{}, nil
+}
+
+// deriveMprotect releases the held loads of the SO which the security_file_mprotect event grants execution to
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deriveMprotect(event trace.Event) ([]trace.Event, []error) {
+	prot, err := parse.ArgInt32Val(&event, "prot")
+	if err != nil {
+		return nil, []error{err}
+	}
+	if prot&protExec == 0 {
+		return []trace.Event{}, nil
+	}
+	path, err := parse.ArgStringVal(&event, "pathname")
+	if err != nil {
+		return nil, []error{err}
+	}
+	ctime, err := parse.ArgUint64Val(&event, "ctime")
+	if err != nil {
+		return nil, []error{err}
+	}
+	derived := []trace.Event{}
+	var errs []error
+	for _, load := range symbsLoadedGen.pending.takeFile(event.HostProcessID,
+		strings.TrimSuffix(path, sharedobjs.DeletedSuffix), ctime) {
+		loadEvents, loadErrs := symbsLoadedGen.grantExecution(load)
+		derived = append(derived, loadEvents...)
+		errs = append(errs, loadErrs...)
+	}
+	return derived, errs
+}
+
+// grantsExecution returns whether the mapping event maps its file executable. Events with no protection argument
+// are executable, as shared_object_loaded is submitted for executable mappings only.
+func grantsExecution(event *trace.Event) (bool, error) {
+	for _, arg := range event.Args {
+		if arg.Name == "prot" {
+			prot, err := parse.ArgInt32Val(event, "prot")
+			return prot&protExec != 0, err
+		}
+	}
+	return true, nil
+}
+
+// hold keeps the load until its SO is mapped executable, or until the exec mapping timeout elapses
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) hold(key pendingLoadKey, load *pendingLoad) {
+	if !symbsLoadedGen.acquire() {
+		return
+	}
+	defer symbsLoadedGen.release()
+	if evicted := symbsLoadedGen.pending.add(key, load); evicted != nil {
+		symbsLoadedGen.expireLoad(evicted)
+	}
+	symbsLoadedGen.pendingWG.Add(1)
+	go func() {
+		defer symbsLoadedGen.pendingWG.Done()
+		select {
+		case <-symbsLoadedGen.clock.After(symbsLoadedGen.execTimeout):
+			if symbsLoadedGen.pending.expire(key, load) {
+				symbsLoadedGen.expireLoad(load)
+			}
+		case <-load.taken:
+		case <-symbsLoadedGen.pendingDone:
+		}
+	}()
+}
+
+// grantExecution returns the event of the held load, noting that its SO was granted execution
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) grantExecution(load *pendingLoad) ([]trace.Event, []error) {
+	if !symbsLoadedGen.acquire() {
+		return []trace.Event{}, nil
+	}
+	defer symbsLoadedGen.release()
+	load.args[symbsLoadedGen.execGrantedArg] = true
+	derived, err := newEvent(&load.event, symbsLoadedGen.skeleton, load.args)
+	if err != nil {
+		return []trace.Event{}, []error{err}
+	}
+	return []trace.Event{derived}, nil
+}
+
+// expireLoad sends the event of the load whose SO was never mapped executable, noting that it wasn't.
+// The emission doesn't block, so events which are not read are dropped.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) expireLoad(load *pendingLoad) {
+	symbsLoadedGen.stats.ExecMappingTimeouts.Increment()
+	symbsLoadedGen.log(LogLevelInfo, DecisionNotExecutable, load.objInfo, symbsLoadedGen.execTimeout.String())
+	derived, err := newEvent(&load.event, symbsLoadedGen.skeleton, load.args)
+	if err != nil {
+		return
+	}
+	select {
+	case symbsLoadedGen.expiredEvents <- derived:
+	default:
+	}
+}
+
+// ExpiredLoads returns the channel of the symbols_loaded events of loads whose SO was not mapped executable before
+// the exec mapping timeout elapsed, or nil if the correlation is not configured. The events are emitted when the
+// timeout elapses rather than derived from other events, so they should be merged into the events stream by the
+// caller. If the events are not read fast enough, they are dropped.
+// The channel is closed when the generator is closed.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) ExpiredLoads() <-chan trace.Event {
+	if symbsLoadedGen.pending == nil {
+		return nil
+	}
+	return symbsLoadedGen.expiredEvents
+}
+
+// startCorrelation starts holding loads until their SO is mapped executable
+func (tracker *symbolsTracker) startCorrelation(timeout time.Duration, maxPending int) {
+	tracker.pending = newPendingLoads(maxPending)
+	tracker.execTimeout = timeout
+	tracker.expiredEvents = make(chan trace.Event, expiredLoadsBuffer)
+	tracker.pendingDone = make(chan struct{})
+}
+
+// stopCorrelation drops the held loads, and waits for the expirations in progress to complete
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) stopCorrelation() {
+	if symbsLoadedGen.pendingDone == nil {
+		return
+	}
+	close(symbsLoadedGen.pendingDone)
+	symbsLoadedGen.pendingWG.Wait()
+	close(symbsLoadedGen.expiredEvents)
+}
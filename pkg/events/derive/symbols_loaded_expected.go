@@ -0,0 +1,34 @@
+package derive
+
+import (
+	"sort"
+)
+
+// matchMissingSymbols returns the expected symbols of the SO soname which the SO doesn't export, sorted.
+// A SO which should export symbols but doesn't export some of them may be a stub or a hollowed replacement of the
+// genuine SO. SOs with no expected symbols for their soname have no missing symbols.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchMissingSymbols(match *symbolsMatch) ([]string, error) {
+	if symbsLoadedGen.expectedSymbols == nil {
+		return nil, nil
+	}
+	soname, err := symbsLoadedGen.sonameLoader.GetSoname(match.objInfo)
+	if err != nil {
+		return nil, err
+	}
+	expected, ok := symbsLoadedGen.expectedSymbols[soname]
+	if soname == "" || !ok {
+		return nil, nil
+	}
+	soSyms, err := symbsLoadedGen.soLoader.GetExportedSymbols(match.objInfo)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for sym := range expected {
+		if !soSyms[sym] {
+			missing = append(missing, sym)
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
@@ -0,0 +1,142 @@
+package derive
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// SymbolsExtractionConfig is the configuration of how the SOs are examined: the reading of their symbols, its deadline
+// and its workers
+type SymbolsExtractionConfig struct {
+	BatchWorkers int // Amount of workers used by DeriveBatch. If 0, the amount of CPUs is used
+	// Minimal duration of symbols extraction of a SO to derive the symbols_extraction_slow event for.
+	// If 0, DefaultSlowExtractionThreshold is used.
+	SlowExtractionThreshold time.Duration
+	// The library whose symbols are extracted by SelfTest, as an absolute path or as a file name looked up in the
+	// libraries directories. If empty, DefaultSelfTestLibrary is used.
+	SelfTestLibrary string
+	// Size of the queue of SO loading events examined by a background worker. If 0, the SOs are examined when the
+	// events are received. Otherwise, the derive function returns immediately, the derived events are emitted by
+	// AsyncEvents, and events received while the queue is full are dropped.
+	AsyncQueueSize int
+	// Maximal duration of examining a single SO. Examinations which take longer are abandoned and fail with
+	// ErrExtractionTimeout, so a slow or malicious SO doesn't stall the derivations of other SOs. If 0, there is
+	// no deadline.
+	ExtractionDeadline time.Duration
+	// Amount of workers examining SOs under the extraction deadline, which bounds the examinations in progress,
+	// including the abandoned ones which didn't end yet. If 0, DefaultDeadlineWorkers is used.
+	DeadlineWorkers int
+	// Never extract the symbols of SOs, and derive the event for every examined SO (e.g. loaded from a suspicious
+	// directory, or not in the allowlist) with its metadata only: its soname and build ID are added to the event,
+	// alongside its flagged dynamic tags and W^X segments if configured. Features which match symbols can't be
+	// configured.
+	MetadataOnly bool
+}
+
+// symbolsExtractor is the extraction feature of the generator: the loader reading the SOs, the deadline of their
+// examinations and the workers examining them
+type symbolsExtractor struct {
+	soLoader           sharedobjs.DynamicSymbolsLoader
+	metadataLoader     sharedobjs.MetadataLoader     // Set only in the metadata only mode
+	packerDetector     sharedobjs.PackerDetector     // Nil if the loader can't detect packed SOs
+	extractionTimer    sharedobjs.ExtractionTimer    // Nil if the loader doesn't measure extractions
+	unreadableDetector sharedobjs.UnreadableDetector // Nil if the loader doesn't classify permission failures
+	slowThreshold      time.Duration
+	selfTestLibrary    string
+	batchWorkers       int
+	extractionDeadline time.Duration
+	abandoned          map[sharedobjs.ObjID]bool // SOs whose derivation was abandoned and is still in progress
+	abandonedMutex     sync.Mutex
+	contextLoader      sharedobjs.ContextLoader // Nil if the loader can't read SOs under a context
+	deadlineWorkers    int
+	deadlineJobs       chan deadlineJob // Set once the first SO is examined under the deadline
+	deadlineDone       chan struct{}
+	deadlineCtx        context.Context // Cancelled when the generator is closed
+	cancelDeadline     context.CancelFunc
+	deadlineStopped    bool
+	deadlineMutex      sync.Mutex
+	deadlineWG         sync.WaitGroup
+	asyncQueue         chan trace.Event // Set only if the asynchronous mode is configured
+	asyncEvents        chan trace.Event
+	asyncDone          chan struct{}
+	asyncWG            sync.WaitGroup
+	asyncStop          sync.Once
+}
+
+// init sets up the examination of the SOs read by the given loader with the given configuration. In the metadata
+// only mode, the other features read the SOs through the loader of the extractor, which reads their metadata only.
+// The asynchronous worker is started only once the generator is started.
+func (extractor *symbolsExtractor) init(soLoader sharedobjs.DynamicSymbolsLoader, config SymbolsExtractionConfig) error {
+	extractor.soLoader = soLoader
+	if config.MetadataOnly {
+		metadataLoader, ok := soLoader.(sharedobjs.MetadataLoader)
+		if !ok {
+			return fmt.Errorf("the metadata only mode is configured, but the SO loader can't read metadata")
+		}
+		extractor.metadataLoader = metadataLoader
+		// The features of the derivation read the SO through the metadata, so its symbols are never extracted
+		extractor.soLoader = metadataSymbolsLoader{loader: metadataLoader}
+	}
+	extractor.selfTestLibrary = config.SelfTestLibrary
+	if extractor.selfTestLibrary == "" {
+		extractor.selfTestLibrary = DefaultSelfTestLibrary
+	}
+	extractor.batchWorkers = config.BatchWorkers
+	if extractor.batchWorkers <= 0 {
+		extractor.batchWorkers = runtime.NumCPU()
+	}
+	extractor.extractionDeadline = config.ExtractionDeadline
+	extractor.abandoned = make(map[sharedobjs.ObjID]bool)
+	extractor.contextLoader, _ = extractor.soLoader.(sharedobjs.ContextLoader)
+	extractor.deadlineWorkers = config.DeadlineWorkers
+	if extractor.deadlineWorkers <= 0 {
+		extractor.deadlineWorkers = DefaultDeadlineWorkers
+	}
+	extractor.packerDetector, _ = extractor.soLoader.(sharedobjs.PackerDetector)
+	extractor.extractionTimer, _ = extractor.soLoader.(sharedobjs.ExtractionTimer)
+	extractor.unreadableDetector, _ = extractor.soLoader.(sharedobjs.UnreadableDetector)
+	extractor.slowThreshold = config.SlowExtractionThreshold
+	if extractor.slowThreshold <= 0 {
+		extractor.slowThreshold = DefaultSlowExtractionThreshold
+	}
+	return nil
+}
+
+// validate checks the extraction configuration for mistakes
+func (config SymbolsExtractionConfig) validate() []error {
+	var problems []error
+	if strings.Contains(config.SelfTestLibrary, "/") && !path.IsAbs(config.SelfTestLibrary) {
+		problems = append(problems, fmt.Errorf("self test library '%s' should be an absolute path or a file name",
+			config.SelfTestLibrary))
+	}
+	if config.ExtractionDeadline < 0 {
+		problems = append(problems, fmt.Errorf("negative extraction deadline %v", config.ExtractionDeadline))
+	}
+	if config.DeadlineWorkers < 0 {
+		problems = append(problems, fmt.Errorf("negative deadline workers %d", config.DeadlineWorkers))
+	} else if config.DeadlineWorkers > 0 && config.ExtractionDeadline == 0 {
+		problems = append(problems, fmt.Errorf("deadline workers are configured with no extraction deadline"))
+	}
+	if config.AsyncQueueSize < 0 {
+		problems = append(problems, fmt.Errorf("negative async queue size %d", config.AsyncQueueSize))
+	}
+	return problems
+}
+
+// readMetadata adds the metadata of the SO of the match to the match, in the metadata only mode
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) readMetadata(match *symbolsMatch) error {
+	if symbsLoadedGen.metadataLoader == nil {
+		return nil
+	}
+	metadata, err := symbsLoadedGen.metadataLoader.GetMetadata(match.objInfo)
+	match.soname, match.buildID = metadata.Soname, metadata.BuildID
+	return err
+}
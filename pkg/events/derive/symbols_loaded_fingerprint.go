@@ -0,0 +1,86 @@
+package derive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// DefaultFingerprintCacheSize is the default maximal amount of SOs whose exported symbols fingerprint is kept
+const DefaultFingerprintCacheSize = 4096
+
+// symbolsFingerprint returns the SHA-256 (hex encoded) of the given symbols names, sorted so it doesn't depend on
+// their order. Each name is terminated by a NUL byte, so different sets can't have the same concatenation.
+func symbolsFingerprint(names []string) string {
+	sort.Strings(names)
+	hash := sha256.New()
+	for _, name := range names {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// fingerprintCache keeps the exported symbols fingerprint of the recently loaded SOs, by their ObjID (like the
+// symbols of the SOs are cached by the loaders), so the symbols of a SO are hashed once however many times it is
+// loaded. It is safe for concurrent use.
+type fingerprintCache struct {
+	mutex        sync.Mutex
+	fingerprints *simplelru.LRU // sharedobjs.ObjID -> the fingerprint of the SO
+}
+
+func newFingerprintCache(size int) *fingerprintCache {
+	fingerprints, _ := simplelru.NewLRU(size, nil)
+	return &fingerprintCache{fingerprints: fingerprints}
+}
+
+// get returns the fingerprint of the SO, calculating it from the names of its exported symbols if it isn't kept
+func (cache *fingerprintCache) get(id sharedobjs.ObjID, names func() []string) string {
+	cache.mutex.Lock()
+	fingerprint, ok := cache.fingerprints.Get(id)
+	cache.mutex.Unlock()
+	if ok {
+		return fingerprint.(string)
+	}
+	// The hash is calculated without the lock, so concurrent derivations of other SOs don't wait for it
+	calculated := symbolsFingerprint(names())
+	cache.mutex.Lock()
+	cache.fingerprints.Add(id, calculated)
+	cache.mutex.Unlock()
+	return calculated
+}
+
+// setFingerprint sets the exported symbols fingerprint of the match SO, if fingerprints are reported
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) setFingerprint(match *symbolsMatch, names func() []string) {
+	if symbsLoadedGen.fingerprints == nil {
+		return
+	}
+	match.fingerprint = symbsLoadedGen.fingerprints.get(match.objInfo.Id, names)
+}
+
+// symbolsNames returns the names of the symbols of the given set
+func symbolsNames(syms map[string]bool) func() []string {
+	return func() []string {
+		names := make([]string, 0, len(syms))
+		for sym := range syms {
+			names = append(names, sym)
+		}
+		return names
+	}
+}
+
+// symbolsInfoNames returns the names of the symbols of the given information map
+func symbolsInfoNames(symsInfo map[string]sharedobjs.SymbolInfo) func() []string {
+	return func() []string {
+		names := make([]string, 0, len(symsInfo))
+		for sym := range symsInfo {
+			names = append(names, sym)
+		}
+		return names
+	}
+}
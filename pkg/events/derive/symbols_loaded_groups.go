@@ -0,0 +1,117 @@
+package derive
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// SymbolsWatchGroup is a named list of watched symbols. The SO matches the group if it exports at least MinMatches
+// of its symbols, so groups can describe a capability which requires several symbols (e.g. a hooking library
+// exporting both "open" and "openat").
+type SymbolsWatchGroup struct {
+	Name    string
+	Symbols []string // Full symbol names - prefixes and library limited entries are not supported in groups
+	// Minimal amount of the group symbols which the SO should export to match the group. If 0, one symbol is enough.
+	MinMatches int
+}
+
+// watchGroup is a SymbolsWatchGroup prepared for matching
+type watchGroup struct {
+	name       string
+	symbols    map[string]bool
+	minMatches int
+}
+
+// newWatchGroups converts the configured groups to sets, keeping their order. Excluded symbols are removed from
+// the groups, like they are removed from the watched symbols.
+func newWatchGroups(config []SymbolsWatchGroup, excluded map[string]bool) []watchGroup {
+	groups := make([]watchGroup, 0, len(config))
+	for _, group := range config {
+		symbols := make(map[string]bool, len(group.Symbols))
+		for _, sym := range group.Symbols {
+			if !excluded[sym] {
+				symbols[sym] = true
+			}
+		}
+		minMatches := group.MinMatches
+		if minMatches == 0 {
+			minMatches = 1
+		}
+		groups = append(groups, watchGroup{name: group.Name, symbols: symbols, minMatches: minMatches})
+	}
+	return groups
+}
+
+// matchWatchGroups returns the names of the watch groups which the given SO satisfies, in their configured order.
+// If stopOnFirstMatch is configured, the matching returns at the first satisfied group.
+// If anySymbol is set, each group is satisfied by any of its symbols, regardless of its minimal matches.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchWatchGroups(objInfo sharedobjs.ObjInfo, anySymbol bool) (
+	[]string, error) {
+	if len(symbsLoadedGen.watchGroups) == 0 {
+		return nil, nil
+	}
+	soSyms, err := symbsLoadedGen.soLoader.GetExportedSymbols(objInfo)
+	if err != nil {
+		return nil, err
+	}
+	var matchedGroups []string
+	for _, group := range symbsLoadedGen.watchGroups {
+		minMatches := group.minMatches
+		if anySymbol {
+			minMatches = 1
+		}
+		if countExported(soSyms, group.symbols) < minMatches {
+			continue
+		}
+		matchedGroups = append(matchedGroups, group.name)
+		if symbsLoadedGen.stopOnFirstMatch {
+			break
+		}
+	}
+	return matchedGroups, nil
+}
+
+// countExported returns the amount of the given symbols which are in the SO symbols, without allocating them
+func countExported(soSyms map[string]bool, symbols map[string]bool) int {
+	count := 0
+	for sym := range symbols {
+		if soSyms[sym] {
+			count++
+		}
+	}
+	return count
+}
+
+// validateWatchGroups checks the configured watch groups for mistakes
+func validateWatchGroups(groups []SymbolsWatchGroup, stopOnFirstMatch bool) []error {
+	var problems []error
+	if stopOnFirstMatch && len(groups) == 0 {
+		problems = append(problems, fmt.Errorf("stop on first match is configured with no watch groups"))
+	}
+	names := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		if group.Name == "" {
+			problems = append(problems, fmt.Errorf("watch group with no name"))
+		} else if names[group.Name] {
+			problems = append(problems, fmt.Errorf("watch group '%s' is defined more than once", group.Name))
+		}
+		names[group.Name] = true
+		if len(group.Symbols) == 0 {
+			problems = append(problems, fmt.Errorf("watch group '%s' has no symbols", group.Name))
+		}
+		for _, sym := range group.Symbols {
+			if sym == "" {
+				problems = append(problems, fmt.Errorf("empty symbol entry of watch group '%s'", group.Name))
+			} else if strings.HasSuffix(sym, prefixWildcard) || strings.Contains(sym, librarySymbolSeparator) {
+				problems = append(problems, fmt.Errorf("watch group '%s' symbol '%s' should be a full symbol name", group.Name, sym))
+			}
+		}
+		if group.MinMatches < 0 || group.MinMatches > len(group.Symbols) {
+			problems = append(problems, fmt.Errorf("watch group '%s' minimal matches %d is not between 0 and its %d symbols",
+				group.Name, group.MinMatches, len(group.Symbols)))
+		}
+	}
+	return problems
+}
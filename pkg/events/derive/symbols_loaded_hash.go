@@ -0,0 +1,82 @@
+package derive
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SymbolsHashMode configures whether the matched symbols are reported by their keyed hash, so their names are not
+// exported off the host
+type SymbolsHashMode int
+
+const (
+	// SymbolsHashNone reports the names of the matched symbols
+	SymbolsHashNone SymbolsHashMode = iota
+	// SymbolsHashAlongside reports the names of the matched symbols, and adds their hashes to the event
+	SymbolsHashAlongside
+	// SymbolsHashOnly reports the hashes of the matched symbols instead of their names, in all the arguments of the
+	// event which hold symbol names and in the summary event
+	SymbolsHashOnly
+)
+
+// minSymbolsHashKeySize is the minimal size of the key of the symbols hashes. The symbols names are guessable, so
+// the hashes are only as secret as the key.
+const minSymbolsHashKeySize = 16
+
+// symbolsHasher calculates the keyed hash (HMAC-SHA256) of symbols names.
+// The hashes of the configured symbols are calculated once, so matches of them are not hashed again.
+// It is safe for concurrent use.
+type symbolsHasher struct {
+	key         []byte
+	precomputed map[string]string
+}
+
+// newSymbolsHasher creates a hasher with the given key, calculating the hashes of the given symbols
+func newSymbolsHasher(key []byte, symbols []string) *symbolsHasher {
+	hasher := &symbolsHasher{key: append([]byte{}, key...), precomputed: make(map[string]string, len(symbols))}
+	for _, sym := range symbols {
+		hasher.precomputed[sym] = hasher.calc(sym)
+	}
+	return hasher
+}
+
+// hash returns the hex encoded keyed hash of the symbol
+func (hasher *symbolsHasher) hash(sym string) string {
+	if hashed, ok := hasher.precomputed[sym]; ok {
+		return hashed
+	}
+	return hasher.calc(sym)
+}
+
+func (hasher *symbolsHasher) calc(sym string) string {
+	mac := hmac.New(sha256.New, hasher.key)
+	mac.Write([]byte(sym))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// hashAll returns the hashes of the given symbols, in their order
+func (hasher *symbolsHasher) hashAll(symbols []string) []string {
+	if symbols == nil {
+		return nil
+	}
+	hashed := make([]string, len(symbols))
+	for i, sym := range symbols {
+		hashed[i] = hasher.hash(sym)
+	}
+	return hashed
+}
+
+// hashReported replaces the symbols names of the reported match with their hashes, if configured
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) hashReported(match *symbolsMatch) {
+	if symbsLoadedGen.hasher == nil || !symbsLoadedGen.hashOnly {
+		return
+	}
+	match.symbols = symbsLoadedGen.hasher.hashAll(match.symbols)
+	match.imports = symbsLoadedGen.hasher.hashAll(match.imports)
+	match.tls = symbsLoadedGen.hasher.hashAll(match.tls)
+	match.versioned = symbsLoadedGen.hasher.hashAll(match.versioned)
+	match.demangled = symbsLoadedGen.hasher.hashAll(match.demangled)
+	match.missing = symbsLoadedGen.hasher.hashAll(match.missing)
+	match.canonical = symbsLoadedGen.hasher.hashAll(match.canonical)
+}
@@ -0,0 +1,111 @@
+package derive
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// importVersionSeparator separates the symbol from the version it is expected to be resolved from in versioned
+// watched imports entries (e.g. "memcpy@GLIBC_2.2.5"), as in the notation of versioned symbols
+const importVersionSeparator = "@"
+
+// splitImportVersion splits a versioned watched import entry to its symbol and version. The version of entries
+// which are not versioned is empty.
+func splitImportVersion(entry string) (string, string) {
+	parts := strings.SplitN(entry, importVersionSeparator, 2)
+	if len(parts) < 2 {
+		return entry, ""
+	}
+	return parts[0], parts[1]
+}
+
+// hasVersionedImports checks if any of the watched imports entries is versioned
+func hasVersionedImports(entries []string) bool {
+	for _, entry := range entries {
+		if strings.Contains(entry, importVersionSeparator) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWatchedImports loads the imported symbols of given SO, and returns the watched imports among them with
+// their information if PLT slots are reported. Imports matching a versioned or a library limited entry are returned
+// as the entry, e.g. "memcpy@GLIBC_2.2.5" or "libcrypt!crypt".
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchWatchedImports(objInfo sharedobjs.ObjInfo) (
+	[]string, []sharedobjs.ImportedSymbolInfo, error) {
+	if symbsLoadedGen.watchedImports == nil {
+		return nil, nil, nil
+	}
+	if symbsLoadedGen.importsInfoLoader != nil {
+		importsInfo, err := symbsLoadedGen.importsInfoLoader.GetImportedSymbolsInfo(objInfo)
+		if err != nil {
+			return nil, nil, err
+		}
+		var imports []string
+		var matchedInfo []sharedobjs.ImportedSymbolInfo
+		for sym, info := range importsInfo {
+			if symbsLoadedGen.watchedImports[sym] {
+				imports = append(imports, sym)
+				matchedInfo = append(matchedInfo, info)
+			}
+			for _, entry := range symbsLoadedGen.matchLibraryImports(sym, info) {
+				imports = append(imports, entry)
+				matchedInfo = append(matchedInfo, info)
+			}
+			if info.Version == "" {
+				continue
+			}
+			if versioned := sym + importVersionSeparator + info.Version; symbsLoadedGen.watchedImports[versioned] {
+				imports = append(imports, versioned)
+				matchedInfo = append(matchedInfo, info)
+			}
+		}
+		return imports, matchedInfo, nil
+	}
+
+	soImports, err := symbsLoadedGen.soLoader.GetImportedSymbols(objInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+	return MatchWatchedSymbols(soImports, symbsLoadedGen.watchedImports), nil, nil
+}
+
+// matchLibraryImports returns the library limited watched imports entries matching the given import. The library
+// part of an entry is matched as a prefix of the file name of the library which the import is expected to be resolved
+// from, so "libcrypt" matches "libcrypt.so.1". Imports which are not versioned have no expected library, and match
+// no entry.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchLibraryImports(sym string,
+	info sharedobjs.ImportedSymbolInfo) []string {
+	if symbsLoadedGen.libraryImports == nil || info.Library == "" {
+		return nil
+	}
+	imported := []string{sym}
+	if info.Version != "" {
+		imported = append(imported, sym+importVersionSeparator+info.Version)
+	}
+	var entries []string
+	for _, entry := range imported {
+		for _, library := range symbsLoadedGen.libraryImports[entry] {
+			if strings.HasPrefix(path.Base(info.Library), library) {
+				entries = append(entries, library+librarySymbolSeparator+entry)
+			}
+		}
+	}
+	return entries
+}
+
+// formatPLTSlots formats the PLT slot of each import as "<slot index>:<GOT entry offset>".
+// Imports with no PLT slot (e.g. imported data objects) are formatted as an empty string.
+func formatPLTSlots(importsInfo []sharedobjs.ImportedSymbolInfo) []string {
+	slots := make([]string, len(importsInfo))
+	for i, info := range importsInfo {
+		if info.HasPLTSlot {
+			slots[i] = fmt.Sprintf("%d:0x%x", info.PLTIndex, info.GOTOffset)
+		}
+	}
+	return slots
+}
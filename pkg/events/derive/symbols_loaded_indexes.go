@@ -0,0 +1,44 @@
+package derive
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// unexpectedIndexes returns the matched symbols with an expected index which are at another index of the dynamic
+// symbols table, as "<symbol>=<index>" sorted by the symbol name. The information of each symbol is given in the
+// order of the symbols.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) unexpectedIndexes(
+	symbols []string, symbolsInfo []sharedobjs.SymbolInfo) []string {
+	var unexpected []string
+	for i, sym := range symbols {
+		expected, ok := symbsLoadedGen.expectedIndexes[sym]
+		if ok && symbolsInfo[i].Index != expected {
+			unexpected = append(unexpected, fmt.Sprintf("%s=%d", sym, symbolsInfo[i].Index))
+		}
+	}
+	sort.Strings(unexpected)
+	return unexpected
+}
+
+// validateSymbolIndexes checks the expected indexes of symbols for mistakes
+func validateSymbolIndexes(indexes map[string]int) []error {
+	symbols := make([]string, 0, len(indexes))
+	for sym := range indexes {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+	var problems []error
+	for _, sym := range symbols {
+		if sym == "" {
+			problems = append(problems, fmt.Errorf("expected index of an empty symbol"))
+		}
+		// The first entry of the table is the null symbol
+		if indexes[sym] < 1 {
+			problems = append(problems, fmt.Errorf("expected index %d of symbol '%s' should be at least 1", indexes[sym], sym))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,75 @@
+package derive
+
+import (
+	"path"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// InterpreterMode configures how the dynamic loader (e.g. ld-linux.so) is examined by the symbols_loaded event
+type InterpreterMode int
+
+const (
+	// InterpreterDefault examines the dynamic loader as any other SO, according to the whitelist
+	InterpreterDefault InterpreterMode = iota
+	// InterpreterExclude never examines the dynamic loader
+	InterpreterExclude
+	// InterpreterInclude always examines the dynamic loader, even if it is whitelisted
+	InterpreterInclude
+)
+
+// checkInterpreter checks if the SO is the dynamic loader, and whether it should be ignored according to the
+// configured interpreter mode, given whether it is ignored according to its path.
+// The SO is read only if the result may change the decision or is reported.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) checkInterpreter(objInfo sharedobjs.ObjInfo, ignored bool) (
+	bool, bool, error) {
+	mode := symbsLoadedGen.interpreterMode
+	needed := (ignored && mode == InterpreterInclude) ||
+		(!ignored && (mode == InterpreterExclude || symbsLoadedGen.reportInterpreter))
+	if symbsLoadedGen.interpreterDetector == nil || !needed {
+		return ignored, false, nil
+	}
+	interpreter, err := symbsLoadedGen.interpreterDetector.IsInterpreter(objInfo)
+	if err != nil {
+		return ignored, false, err
+	}
+	if interpreter {
+		switch mode {
+		case InterpreterExclude:
+			ignored = true
+		case InterpreterInclude:
+			ignored = false
+		}
+	}
+	return ignored, interpreter, nil
+}
+
+// requestedInterpreter returns the interpreter which the ELF file requests (PT_INTERP), if unusual interpreters are
+// flagged. Executables request the dynamic loader, and SOs usually request no interpreter.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) requestedInterpreter(objInfo sharedobjs.ObjInfo) (string, error) {
+	if symbsLoadedGen.interpLoader == nil {
+		return "", nil
+	}
+	return symbsLoadedGen.interpLoader.GetInterpreter(objInfo)
+}
+
+// isUnusualInterpreter checks if the requested interpreter doesn't reside directly in one of the libraries
+// directories, where the dynamic loaders of the distributions are installed (e.g. /lib64/ld-linux-x86-64.so.2).
+// An empty interpreter (of ELF files requesting none) is not unusual.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) isUnusualInterpreter(interp string) bool {
+	if interp == "" {
+		return false
+	}
+	librariesDirs := symbsLoadedGen.librariesDirs
+	if librariesDirs == nil {
+		librariesDirs = knownLibrariesDirs
+	}
+	// The path is cleaned, so paths escaping the directory (e.g. /lib64/../tmp/ld.so) are unusual
+	dir := path.Dir(path.Clean(interp)) + "/"
+	for _, libsDirectory := range librariesDirs {
+		if dir == libsDirectory {
+			return false
+		}
+	}
+	return true
+}
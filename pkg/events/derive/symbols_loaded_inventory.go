@@ -0,0 +1,96 @@
+package derive
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aquasecurity/tracee/pkg/events"
+	"github.com/aquasecurity/tracee/types/trace"
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// DefaultMaxSeenBuildIDs is the default maximal amount of (soname, build ID) pairs remembered as seen
+const DefaultMaxSeenBuildIDs = 8192
+
+// sonameBuildID is a build of a library, identified by its soname and the build ID of the SO
+type sonameBuildID struct {
+	soname  string
+	buildID string
+}
+
+// buildIDInventory remembers the (soname, build ID) pairs which were seen on the host, and the last build ID seen
+// for each soname. It is kept in memory only, so the pairs are seen again after a restart.
+// The inventory is bounded - the pairs seen least recently are forgotten, and reported again if they are seen
+// again. It is safe for concurrent use.
+type buildIDInventory struct {
+	mutex     sync.Mutex
+	seen      *simplelru.LRU // sonameBuildID -> nothing
+	lastBuild *simplelru.LRU // soname -> the last build ID seen with the soname
+}
+
+func newBuildIDInventory(size int) *buildIDInventory {
+	seen, _ := simplelru.NewLRU(size, nil)
+	lastBuild, _ := simplelru.NewLRU(size, nil)
+	return &buildIDInventory{seen: seen, lastBuild: lastBuild}
+}
+
+// observe records that the build was seen, and returns whether it wasn't seen before, and the build ID last seen
+// with the soname before it (empty if none was)
+func (inventory *buildIDInventory) observe(build sonameBuildID) (bool, string) {
+	inventory.mutex.Lock()
+	defer inventory.mutex.Unlock()
+	var previous string
+	if last, ok := inventory.lastBuild.Get(build.soname); ok {
+		previous = last.(string)
+	}
+	inventory.lastBuild.Add(build.soname, build.buildID)
+	if inventory.seen.Contains(build) {
+		inventory.seen.Get(build) // Refresh the recency of the pair
+		return false, previous
+	}
+	inventory.seen.Add(build, struct{}{})
+	return true, previous
+}
+
+// SonameBuildIDSeen receives the generator of the symbols_loaded event as a closure argument.
+// If it receives a shared_object_loaded event of a SO whose (soname, build ID) pair wasn't seen before, it derives
+// a soname_build_id_seen event from it, to inventory the libraries builds loaded on the host.
+func SonameBuildIDSeen(gen *SymbolsLoadedEventGenerator) events.DeriveFunction {
+	return singleSkeletonDeriveFunc(makeTypedEventSkeleton(events.SonameBuildIDSeen),
+		gen.withDeadline(gen.deriveBuildIDSeenArgs))
+}
+
+// deriveBuildIDSeenArgs derive the arguments of the soname_build_id_seen event, if the loaded SO has a soname and a
+// build ID, and their pair wasn't seen before. Unlike the symbols matching, whitelisted and trusted SOs are
+// inventoried too.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deriveBuildIDSeenArgs(event trace.Event) ([]interface{}, error) {
+	if !symbsLoadedGen.acquire() {
+		return nil, nil
+	}
+	defer symbsLoadedGen.release()
+
+	if symbsLoadedGen.inventory == nil || !symbsLoadedGen.inScope(&event) {
+		return nil, nil
+	}
+	loadingObjectInfo, err := getSharedObjectInfo(event)
+	if err != nil {
+		return nil, err
+	}
+
+	// Errors are reported by the symbols_loaded event derivation
+	soname, err := symbsLoadedGen.sonameLoader.GetSoname(loadingObjectInfo)
+	if err != nil || soname == "" {
+		return nil, nil
+	}
+	buildID, err := symbsLoadedGen.buildIDLoader.GetBuildID(loadingObjectInfo)
+	if err != nil || buildID == "" {
+		return nil, nil
+	}
+	isNew, previous := symbsLoadedGen.inventory.observe(sonameBuildID{soname: soname, buildID: buildID})
+	if !isNew {
+		return nil, nil
+	}
+	symbsLoadedGen.log(LogLevelInfo, DecisionNewBuild, loadingObjectInfo,
+		fmt.Sprintf("soname: %s, build ID: %s, previous build ID: %s", soname, buildID, previous))
+	return []interface{}{loadingObjectInfo.Path, soname, buildID, previous}, nil
+}
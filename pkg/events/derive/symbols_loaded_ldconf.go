@@ -0,0 +1,98 @@
+package derive
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultLdSoConfPath is the path of the configuration of the dynamic loader search paths
+const DefaultLdSoConfPath = "/etc/ld.so.conf"
+
+// maxLdSoConfIncludeDepth is the maximal depth of nested include directives which are followed
+const maxLdSoConfIncludeDepth = 8
+
+// loadLibrariesDirs returns the libraries directories of the system, parsed from the given ld.so.conf file and its
+// includes, and from the given LD_LIBRARY_PATH value (colon separated). The hardcoded known libraries directories
+// are always included, as the dynamic loader searches the trusted directories (e.g. /lib) regardless of its
+// configuration. If the configuration can't be read, only the hardcoded directories and the LD_LIBRARY_PATH
+// directories are returned.
+// The directories are ordered so nested directories come before the directories containing them, as the libraries
+// whitelist is matched against the first directory containing the SO.
+func loadLibrariesDirs(confPath string, libraryPath string) []string {
+	var dirs []string
+	if confPath != "" {
+		dirs, _ = parseLdSoConf(confPath, make(map[string]bool), 0)
+	}
+	dirs = append(dirs, strings.Split(libraryPath, ":")...)
+	dirs = append(dirs, knownLibrariesDirs...)
+
+	unique := make(map[string]bool, len(dirs))
+	librariesDirs := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if !path.IsAbs(dir) {
+			continue
+		}
+		// A trailing slash limits the prefix to the directory
+		dir = strings.TrimSuffix(path.Clean(dir), "/") + "/"
+		if unique[dir] {
+			continue
+		}
+		unique[dir] = true
+		librariesDirs = append(librariesDirs, dir)
+	}
+	sort.SliceStable(librariesDirs, func(i, j int) bool {
+		return len(librariesDirs[i]) > len(librariesDirs[j])
+	})
+	return librariesDirs
+}
+
+// parseLdSoConf returns the directories listed in the ld.so.conf file and the files it includes, in their order.
+// Include directives are glob patterns, relative to the directory of the including file if not absolute.
+// Files already parsed are skipped, so include loops are not followed.
+func parseLdSoConf(confPath string, parsed map[string]bool, depth int) ([]string, error) {
+	if parsed[confPath] || depth > maxLdSoConfIncludeDepth {
+		return nil, nil
+	}
+	parsed[confPath] = true
+	file, err := os.Open(confPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var dirs []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if comment := strings.IndexByte(line, '#'); comment >= 0 {
+			line = line[:comment]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "include":
+			for _, pattern := range fields[1:] {
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(filepath.Dir(confPath), pattern)
+				}
+				matches, _ := filepath.Glob(pattern) // Malformed patterns are ignored
+				for _, match := range matches {
+					// Unreadable included files are ignored, like the dynamic loader configuration does
+					included, _ := parseLdSoConf(match, parsed, depth+1)
+					dirs = append(dirs, included...)
+				}
+			}
+		case "hwcap":
+			// Hardware capabilities directives don't list directories
+		default:
+			dirs = append(dirs, fields...)
+		}
+	}
+	return dirs, scanner.Err()
+}
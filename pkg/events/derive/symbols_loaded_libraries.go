@@ -0,0 +1,48 @@
+package derive
+
+import (
+	"path"
+	"strings"
+)
+
+// librarySymbolSeparator separates the library part from the symbol part of a watched symbol entry.
+// An entry of the form "<library>!<symbol>" is matched only if the symbol is exported by the given library.
+const librarySymbolSeparator = "!"
+
+// splitLibrarySymbol splits a watched symbol entry to its library and symbol parts.
+// The library part is empty for entries which are not limited to a library.
+func splitLibrarySymbol(entry string) (string, string) {
+	parts := strings.SplitN(entry, librarySymbolSeparator, 2)
+	if len(parts) < 2 {
+		return "", entry
+	}
+	return parts[0], parts[1]
+}
+
+// isLibrarySymbol checks if the symbol is watched when exported by the SO in the given path.
+// The library part of an entry is matched as a prefix of the SO file name, so "libc" matches "libc.so.6".
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) isLibrarySymbol(sym string, soPath string) bool {
+	libraries, ok := symbsLoadedGen.librarySymbols[sym]
+	if !ok {
+		return false
+	}
+	soName := path.Base(soPath)
+	for _, library := range libraries {
+		if strings.HasPrefix(soName, library) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLibrarySymbols returns the symbols of the given symbols set which are watched for the SO in the given path
+// by library limited entries.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchLibrarySymbols(soSyms map[string]bool, soPath string) []string {
+	var matched []string
+	for sym := range symbsLoadedGen.librarySymbols {
+		if soSyms[sym] && symbsLoadedGen.isLibrarySymbol(sym, soPath) {
+			matched = append(matched, sym)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,140 @@
+package derive
+
+import (
+	"debug/elf"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// LogLevel is the severity of a symbols_loaded log entry
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+func (level LogLevel) String() string {
+	switch level {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelWarn:
+		return "warn"
+	case LogLevelError:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(level))
+}
+
+// Decisions of the symbols_loaded derivation regarding a loaded SO
+const (
+	DecisionWhitelisted   = "whitelisted"
+	DecisionNotAllowed    = "not-allowlisted"
+	DecisionNotELF        = "not-elf"
+	DecisionNoSymbols     = "no-symbols"
+	DecisionUnreadable    = "unreadable"
+	DecisionMatched       = "matched"
+	DecisionPacked        = "packed"
+	DecisionInterpreter   = "interpreter"
+	DecisionUnchanged     = "unchanged"
+	DecisionTrusted       = "trusted"
+	DecisionFailed        = "failed"
+	DecisionWeakOverride  = "weak-override"
+	DecisionTimeout       = "timeout"
+	DecisionOutOfScope    = "out-of-scope"
+	DecisionNewBuild      = "new-build"
+	DecisionSkippedFS     = "skipped-filesystem"
+	DecisionAlwaysMatched = "always-matched"
+	DecisionSelfExecuting = "self-executing"
+	DecisionNewCapability = "new-capability"
+	DecisionNotRegular    = "not-regular-file"
+	DecisionNotExecutable = "not-executable"
+	DecisionLowConfidence = "low-confidence"
+	DecisionRelocated     = "relocated"
+	// Logged once by the constructor, with no SO, if the configuration watches nothing
+	DecisionNothingWatched = "nothing-watched"
+)
+
+// SymbolsLoadedLogEntry describes a decision taken by the symbols_loaded derivation regarding a loaded SO
+type SymbolsLoadedLogEntry struct {
+	Level    LogLevel
+	Decision string
+	ObjInfo  sharedobjs.ObjInfo
+	Reason   string
+}
+
+// SymbolsLoadedLogger receives an entry for every decision taken by the symbols_loaded derivation
+type SymbolsLoadedLogger interface {
+	Log(entry SymbolsLoadedLogEntry)
+}
+
+type nopSymbolsLoadedLogger struct{}
+
+func (nopSymbolsLoadedLogger) Log(SymbolsLoadedLogEntry) {}
+
+// writerSymbolsLoadedLogger writes the entries from a minimal level to the writer, as key=value pairs
+type writerSymbolsLoadedLogger struct {
+	writer   io.Writer
+	minLevel LogLevel
+}
+
+// NewWriterSymbolsLoadedLogger creates a logger which writes the entries with at least the given level to the
+// writer, one entry per line.
+func NewWriterSymbolsLoadedLogger(writer io.Writer, minLevel LogLevel) SymbolsLoadedLogger {
+	return &writerSymbolsLoadedLogger{writer: writer, minLevel: minLevel}
+}
+
+func (logger *writerSymbolsLoadedLogger) Log(entry SymbolsLoadedLogEntry) {
+	if entry.Level < logger.minLevel {
+		return
+	}
+	var line strings.Builder
+	fmt.Fprintf(&line, "level=%s decision=%s path=%q inode=%d mount_ns=%d",
+		entry.Level, entry.Decision, entry.ObjInfo.Path, entry.ObjInfo.Id.Inode, entry.ObjInfo.MountNS)
+	if entry.Reason != "" {
+		fmt.Fprintf(&line, " reason=%q", entry.Reason)
+	}
+	line.WriteString("\n")
+	fmt.Fprint(logger.writer, line.String())
+}
+
+// log sends an entry about the SO to the configured logger
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) log(level LogLevel, decision string, objInfo sharedobjs.ObjInfo, reason string) {
+	if symbsLoadedGen.logger == nil {
+		return
+	}
+	symbsLoadedGen.logger.Log(SymbolsLoadedLogEntry{
+		Level:    level,
+		Decision: decision,
+		ObjInfo:  objInfo,
+		Reason:   reason,
+	})
+}
+
+// logLoadingError logs the failure to load the symbols of the SO, according to the failure reason
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) logLoadingError(objInfo sharedobjs.ObjInfo, err error) {
+	var formatErr *elf.FormatError
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		symbsLoadedGen.log(LogLevelWarn, DecisionUnreadable, objInfo, err.Error())
+	case errors.Is(err, sharedobjs.ErrFilesystemSkipped):
+		symbsLoadedGen.log(LogLevelDebug, DecisionSkippedFS, objInfo, err.Error())
+	case errors.Is(err, sharedobjs.ErrNotRegularFile):
+		symbsLoadedGen.log(LogLevelDebug, DecisionNotRegular, objInfo, err.Error())
+	case errors.Is(err, sharedobjs.ErrFilesystemTimeout):
+		symbsLoadedGen.log(LogLevelWarn, DecisionTimeout, objInfo, err.Error())
+	case errors.As(err, &formatErr):
+		symbsLoadedGen.log(LogLevelDebug, DecisionNotELF, objInfo, err.Error())
+	default:
+		symbsLoadedGen.log(LogLevelError, DecisionFailed, objInfo, err.Error())
+	}
+}
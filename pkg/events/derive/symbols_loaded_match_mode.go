@@ -0,0 +1,99 @@
+package derive
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// SymbolsMatchMode is how many of the watched symbols a SO should export for its watched symbols to be matched
+type SymbolsMatchMode int
+
+const (
+	// MatchAnySymbol matches the watched symbols which a SO exports, if it exports at least MinMatches of them
+	MatchAnySymbol SymbolsMatchMode = iota
+	// MatchAllSymbols matches the watched symbols which a SO exports only if it exports all of them, for watch lists
+	// describing a single capability (e.g. a hooking library exporting both "open" and "openat")
+	MatchAllSymbols
+)
+
+// requiredMatches returns the amount of watched symbols which a SO should export for them to be matched. The always
+// watched symbols are not counted, as they are matched regardless of the other symbols.
+func requiredMatches(config SymbolsMatchingConfig, watchedSymbols map[string]bool, alwaysWatched map[string]bool) int {
+	if config.MatchMode != MatchAllSymbols {
+		return config.MinMatches
+	}
+	required := 0
+	for sym := range watchedSymbols {
+		if !alwaysWatched[sym] {
+			required++
+		}
+	}
+	return required
+}
+
+// applyMinMatches removes the matched watched symbols of a SO which exports fewer of them than the required matches,
+// keeping its always watched symbols. SOs loaded from suspicious directories are matched with any watched symbol, as
+// with the watch groups.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) applyMinMatches(match *symbolsMatch) {
+	if symbsLoadedGen.minMatches <= 1 || match.suspicious != "" {
+		return
+	}
+	matched := 0
+	for _, sym := range match.symbols {
+		if !symbsLoadedGen.alwaysWatched[sym] {
+			matched++
+		}
+	}
+	if matched == 0 || matched >= symbsLoadedGen.minMatches {
+		return
+	}
+	kept := match.symbols[:0]
+	var keptInfo []sharedobjs.SymbolInfo
+	for i, sym := range match.symbols {
+		if !symbsLoadedGen.alwaysWatched[sym] {
+			continue
+		}
+		kept = append(kept, sym)
+		if match.symbolsInfo != nil {
+			keptInfo = append(keptInfo, match.symbolsInfo[i])
+		}
+	}
+	match.symbols = kept
+	if match.symbolsInfo != nil {
+		match.symbolsInfo = keptInfo
+	}
+	if symbsLoadedGen.expectedIndexes != nil {
+		match.unexpected = symbsLoadedGen.unexpectedIndexes(match.symbols, match.symbolsInfo)
+	}
+}
+
+// validateMatchMode checks the match mode and the minimal matches of the watched symbols for mistakes
+func validateMatchMode(config SymbolsMatchingConfig) []error {
+	var problems []error
+	if config.MatchMode != MatchAnySymbol && config.MatchMode != MatchAllSymbols {
+		problems = append(problems, fmt.Errorf("unknown match mode %d", config.MatchMode))
+	}
+	if config.MinMatches < 0 {
+		problems = append(problems, fmt.Errorf("negative minimal matches %d", config.MinMatches))
+	} else if config.MinMatches > 0 && len(config.WatchedSymbols) == 0 {
+		problems = append(problems, fmt.Errorf("minimal matches are configured with no watched symbols"))
+	}
+	if config.MatchMode != MatchAllSymbols {
+		return problems
+	}
+	if config.MinMatches != 0 {
+		problems = append(problems, fmt.Errorf("minimal matches can't be configured in the all symbols match mode"))
+	}
+	if len(config.SymbolAliases) > 0 {
+		problems = append(problems, fmt.Errorf("symbol aliases can't be used in the all symbols match mode"))
+	}
+	for _, entry := range config.WatchedSymbols {
+		if strings.HasSuffix(entry, prefixWildcard) || strings.Contains(entry, librarySymbolSeparator) {
+			problems = append(problems, fmt.Errorf("watched symbol '%s' should be a full symbol name in the all "+
+				"symbols match mode", entry))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,638 @@
+package derive
+
+import (
+	"debug/elf"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// SymbolsMatchingConfig is the configuration of the matching of the watched symbols: which symbols are watched, and
+// which SOs they are watched in
+type SymbolsMatchingConfig struct {
+	WatchedSymbols  []string // Symbols to alert on when exported by a loaded SO, or by a specific library ("<library>!<symbol>"). Entries ending with "*" are prefixes
+	ExcludedSymbols []string // Symbols which should never be watched
+	// Symbols which are matched even in SOs which are otherwise ignored (whitelisted, not in the allowlist, trusted
+	// or the excluded dynamic loader), as they are dangerous regardless of the SO exporting them
+	AlwaysWatchedSymbols []string
+	WhitelistedLibs      []string // Paths prefixes, or libraries names prefixes or glob patterns of SOs to ignore
+	// Regular expressions of SOs to ignore, matched against the full path of the SO. Unlike the WhitelistedLibs
+	// entries, they are not prefixes, so they should be anchored to match a whole path.
+	WhitelistedRegexps []string
+	// Files of whitelist entries shared across configurations (e.g. a base whitelist extended by each team), merged
+	// after the WhitelistedLibs and WhitelistedRegexps entries when the generator is initialized. Each line of a
+	// file is a WhitelistedLibs entry, or one of the directives "include <glob patterns>" (merging the matching
+	// files, relative to the directory of the including file), "regexp <expression>" (a WhitelistedRegexps entry)
+	// and "remove <entry>" (removing the equal entries merged before it, so a file can override the files it
+	// includes). Lines starting with "#" are comments. Missing files and cyclic includes are errors.
+	WhitelistFiles []string
+	// Invert the whitelist, so only SOs matching the WhitelistedLibs entries are examined, and all others are ignored
+	AllowlistMode bool
+	// Visibilities of watched symbols to alert on (e.g. only hidden ones). If empty, all visibilities are watched.
+	WatchedVisibilities []elf.SymVis
+	// Match only symbols residing in executable sections (e.g. .text), and not data objects with a watched name
+	ExecutableSectionsOnly bool
+	// Rules matched against the imported and exported symbols of each SO. The names of the matched rules are
+	// added to the event.
+	Rules []SymbolsRule
+	// Imported symbols to alert on when imported by a loaded SO. The matched imports are added to the event.
+	// Entries of the form "<symbol>@<version>" (e.g. "memcpy@GLIBC_2.2.5") match only imports expected to be
+	// resolved from that version, e.g. to detect imports downgraded to an old version of a symbol. Entries of the
+	// form "<library>!<symbol>" (e.g. "libcrypt!crypt") match only imports expected to be resolved from that library
+	// by their version needed entry, matched as a prefix of its file name, to detect SOs depending on sensitive APIs.
+	// Both forms can be combined, e.g. "libc!memcpy@GLIBC_2.2.5".
+	WatchedImports []string
+	// Thread-local symbols (STT_TLS) to alert on when exported by a loaded SO, as TLS variables can be abused to
+	// keep state out of sight. They are matched only with exported TLS symbols, and the matched ones are added to
+	// the event separately from the matched symbols.
+	WatchedTLSSymbols []string
+	// Symbols to alert on when a loaded SO defines them in a specific version, as "<symbol>@<version>" entries (e.g.
+	// "memcpy@GLIBC_2.14"). The versions are read from the SO file as seen in the mount namespace of the loading
+	// process (with a namespace aware loader), so in a container running another glibc than the host, the versions
+	// of the container's glibc are matched. The matched entries are added to the event.
+	WatchedVersionedSymbols []string
+	// Demangled C++ symbols to alert on when a loaded SO exports them, matched with every mangled exported symbol
+	// of the SO once demangled - by their qualified names (e.g. "Foo::get") or their full signatures (e.g.
+	// "Foo::get(int) const"), by DemangledMatch. The matched entries are added to the event.
+	WatchedDemangledSymbols []string
+	// How the watched demangled symbols are matched
+	DemangledMatch DemangledMatchMode
+	// How the dynamic loader (e.g. ld-linux.so) is examined, regardless of whether its path is whitelisted
+	Interpreter InterpreterMode
+	// Known-good exported symbols of SOs, by their soname (see BaselineSymbolsFromObjects). Watched symbols exported
+	// by a SO with a baseline are matched only if they are absent from its baseline.
+	BaselineSymbols map[string][]string
+	// SOs carrying this ELF note are trusted, and treated as whitelisted. If the name of the note is empty,
+	// the notes of the SOs are not checked.
+	TrustedNote sharedobjs.NoteID
+	// Named groups of watched symbols, in order of priority. The names of the groups which the SO matches are added
+	// to the event, and the event is derived if any group is matched.
+	WatchGroups []SymbolsWatchGroup
+	// Match the groups only until the first (highest priority) group which the SO matches, so overlapping groups
+	// report a single group
+	StopOnFirstMatch bool
+	// Minimal amount of the watched symbols which a SO should export for them to be matched, so common symbols are
+	// matched only alongside others. If 0, one symbol is enough. The always watched symbols are matched regardless.
+	MinMatches int
+	// How many of the watched symbols a SO should export for them to be matched
+	MatchMode SymbolsMatchMode
+	// Directories (e.g. DefaultSuspiciousPaths) which SOs loaded from are examined even if they are whitelisted or
+	// trusted, and match every watch group with any of its symbols. "$HOME" stands for the home directories.
+	// The suspicious directory is added to the event.
+	SuspiciousPaths []string
+	// Path of the dynamic loader configuration (e.g. DefaultLdSoConfPath), whose directories and includes are parsed
+	// for the libraries directories which the WhitelistedLibs names are matched in. If empty, the known libraries
+	// directories are used.
+	LdSoConfPath string
+	// LD_LIBRARY_PATH value whose directories are added to the libraries directories
+	LibraryPath string
+	// Alias classes of symbols, by their canonical name (e.g. "malloc": {"__libc_malloc"}). If any name of a class
+	// is watched, all of its names are watched, and the canonical name of each matched symbol is added to the event.
+	SymbolAliases map[string][]string
+	// The processes whose loaded SOs are examined. SOs loaded by processes out of the scope are never examined,
+	// regardless of the whitelist and suspicious paths. If empty, the SOs of all processes are examined.
+	ProcessScope SymbolsProcessScope
+}
+
+// symbolsMatcher is the matching feature of the generator: the watched symbols, and the SOs they are watched in
+type symbolsMatcher struct {
+	watchedSymbols      map[string]bool
+	alwaysWatched       map[string]bool     // Set only if always watched symbols are configured
+	librarySymbols      map[string][]string // The libraries each library limited watched symbol is watched in
+	canonicalSymbols    map[string]string   // The canonical name of each watched alias, set only if configured
+	watchedPrefixes     *prefixTree         // Nil if no prefix entries are watched
+	excludedSymbols     map[string]bool     // Set only if prefixes are watched
+	watchedVisibilities map[elf.SymVis]bool
+	executableOnly      bool
+	pathPrefixWhitelist []string
+	librariesWhitelist  []string
+	librariesGlobs      []string // Glob patterns of libraries names, not normalized as they are not prefixes
+	regexpsWhitelist    []*regexp.Regexp
+	librariesDirs       []string // Nil if the known libraries directories are used
+	allowlistMode       bool
+	watchedImports      map[string]bool
+	libraryImports      map[string][]string // The libraries each library limited watched import is expected from
+	watchedTLS          map[string]bool
+	watchedVersioned    map[string]bool // The watched "<symbol>@<version>" entries, set only if configured
+	watchedDemangled    map[string]bool // Set only if watched demangled symbols are configured
+	demangledMode       DemangledMatchMode
+	interpreterMode     InterpreterMode
+	baselines           map[string]map[string]bool // The baseline symbols by soname, set only if configured
+	trustedNote         sharedobjs.NoteID          // The trust marker note, if configured
+	noteChecker         sharedobjs.NoteChecker     // Set only if a trust marker note is configured
+	watchGroups         []watchGroup               // In order of priority
+	stopOnFirstMatch    bool
+	minMatches          int           // The watched symbols a SO should export to match them
+	suspiciousDirs      []string      // Set only if suspicious paths are configured
+	scope               *processScope // Nil if the SOs of all processes are examined
+	rules               []SymbolsRule
+}
+
+// init sets up the matching of the watched symbols with the given configuration
+func (matcher *symbolsMatcher) init(soLoader sharedobjs.DynamicSymbolsLoader, config SymbolsMatchingConfig) error {
+	matcher.watchedSymbols = make(map[string]bool)
+	matcher.librarySymbols = make(map[string][]string)
+	var prefixes []string
+	excluded := make(map[string]bool, len(config.ExcludedSymbols))
+	for _, sym := range config.ExcludedSymbols {
+		excluded[sym] = true
+	}
+	for _, entry := range config.WatchedSymbols {
+		library, sym := splitLibrarySymbol(entry)
+		if excluded[entry] || excluded[sym] {
+			continue
+		}
+		switch {
+		case library != "":
+			matcher.librarySymbols[sym] = append(matcher.librarySymbols[sym], library)
+		case strings.HasSuffix(sym, prefixWildcard):
+			prefixes = append(prefixes, strings.TrimSuffix(sym, prefixWildcard))
+		default:
+			matcher.watchedSymbols[sym] = true
+		}
+	}
+	if len(config.AlwaysWatchedSymbols) > 0 {
+		matcher.alwaysWatched = make(map[string]bool, len(config.AlwaysWatchedSymbols))
+		for _, sym := range config.AlwaysWatchedSymbols {
+			matcher.alwaysWatched[sym] = true
+			matcher.watchedSymbols[sym] = true
+		}
+	}
+	if len(config.SymbolAliases) > 0 {
+		matcher.canonicalSymbols = expandAliases(config.SymbolAliases, matcher.watchedSymbols, excluded)
+	}
+	// Symbols watched in any library are matched regardless of the library limited entries
+	for sym := range matcher.watchedSymbols {
+		delete(matcher.librarySymbols, sym)
+	}
+	if len(prefixes) > 0 {
+		matcher.watchedPrefixes = newPrefixTree(prefixes)
+		// Excluded symbols are matched when examining each symbol, as they may start with a watched prefix
+		matcher.excludedSymbols = excluded
+	}
+
+	var libraries, pathPrefixes []string
+	for _, path := range config.WhitelistedLibs {
+		if strings.HasPrefix(path, "/") {
+			pathPrefixes = append(pathPrefixes, path)
+		} else if isLibraryGlob(path) {
+			matcher.librariesGlobs = append(matcher.librariesGlobs, path)
+		} else {
+			libraries = append(libraries, path)
+		}
+	}
+	for _, expr := range config.WhitelistedRegexps {
+		// The regexps are validated by ValidateConfig
+		matcher.regexpsWhitelist = append(matcher.regexpsWhitelist, regexp.MustCompile(expr))
+	}
+	matcher.pathPrefixWhitelist = normalizeWhitelist(pathPrefixes, true)
+	matcher.librariesWhitelist = normalizeWhitelist(libraries, false)
+	if len(libraries) > 0 && (config.LdSoConfPath != "" || config.LibraryPath != "") {
+		matcher.librariesDirs = loadLibrariesDirs(config.LdSoConfPath, config.LibraryPath)
+	}
+	matcher.allowlistMode = config.AllowlistMode
+	matcher.interpreterMode = config.Interpreter
+	matcher.scope = newProcessScope(config.ProcessScope)
+	if len(config.SuspiciousPaths) > 0 {
+		matcher.suspiciousDirs = newSuspiciousDirs(config.SuspiciousPaths)
+	}
+
+	if len(config.WatchedVisibilities) > 0 {
+		matcher.watchedVisibilities = make(map[elf.SymVis]bool)
+		for _, vis := range config.WatchedVisibilities {
+			matcher.watchedVisibilities[vis] = true
+		}
+	}
+	matcher.executableOnly = config.ExecutableSectionsOnly
+	matcher.rules = config.Rules
+	if len(config.WatchedImports) > 0 {
+		matcher.watchedImports = make(map[string]bool, len(config.WatchedImports))
+		for _, sym := range config.WatchedImports {
+			matcher.watchedImports[sym] = true
+			if library, imported := splitLibrarySymbol(sym); library != "" {
+				if matcher.libraryImports == nil {
+					matcher.libraryImports = make(map[string][]string)
+				}
+				matcher.libraryImports[imported] = append(matcher.libraryImports[imported], library)
+			}
+		}
+	}
+	if len(config.WatchedTLSSymbols) > 0 {
+		matcher.watchedTLS = make(map[string]bool, len(config.WatchedTLSSymbols))
+		for _, sym := range config.WatchedTLSSymbols {
+			matcher.watchedTLS[sym] = true
+		}
+	}
+	if len(config.WatchedVersionedSymbols) > 0 {
+		matcher.watchedVersioned = make(map[string]bool, len(config.WatchedVersionedSymbols))
+		for _, entry := range config.WatchedVersionedSymbols {
+			matcher.watchedVersioned[entry] = true
+		}
+	}
+	if len(config.WatchedDemangledSymbols) > 0 {
+		matcher.watchedDemangled = make(map[string]bool, len(config.WatchedDemangledSymbols))
+		for _, entry := range config.WatchedDemangledSymbols {
+			matcher.watchedDemangled[entry] = true
+		}
+		matcher.demangledMode = config.DemangledMatch
+	}
+	if len(config.BaselineSymbols) > 0 {
+		matcher.baselines = newSonameSymbolsSets(config.BaselineSymbols)
+	}
+
+	matcher.minMatches = requiredMatches(config, matcher.watchedSymbols, matcher.alwaysWatched)
+	if len(config.WatchGroups) > 0 {
+		matcher.watchGroups = newWatchGroups(config.WatchGroups, excluded)
+		matcher.stopOnFirstMatch = config.StopOnFirstMatch
+	}
+
+	if config.TrustedNote.Name != "" {
+		noteChecker, ok := soLoader.(sharedobjs.NoteChecker)
+		if !ok {
+			return fmt.Errorf("trusted note is configured, but the SO loader can't read notes")
+		}
+		matcher.noteChecker = noteChecker
+		matcher.trustedNote = config.TrustedNote
+	}
+	return nil
+}
+
+// validate checks the matching configuration for mistakes
+func (config SymbolsMatchingConfig) validate() []error {
+	var problems []error
+	checkEntries := func(kind string, entries []string) {
+		for _, entry := range entries {
+			if entry == "" {
+				problems = append(problems, fmt.Errorf("empty %s entry", kind))
+			}
+		}
+	}
+	checkEntries("watched symbol", config.WatchedSymbols)
+	for _, entry := range config.WatchedSymbols {
+		if !strings.Contains(entry, librarySymbolSeparator) {
+			continue
+		}
+		library, sym := splitLibrarySymbol(entry)
+		if library == "" || sym == "" {
+			problems = append(problems, fmt.Errorf("watched symbol entry '%s' is missing its library or symbol", entry))
+		} else if strings.Contains(library, "/") || strings.Contains(sym, librarySymbolSeparator) {
+			problems = append(problems, fmt.Errorf("watched symbol entry '%s' library should be a file name", entry))
+		} else if strings.Contains(sym, prefixWildcard) {
+			problems = append(problems, fmt.Errorf("watched symbol entry '%s' can't be both a prefix and limited to a library", entry))
+		}
+	}
+	checkEntries("excluded symbol", config.ExcludedSymbols)
+	for _, sym := range config.AlwaysWatchedSymbols {
+		if sym == "" || strings.HasSuffix(sym, prefixWildcard) || strings.Contains(sym, librarySymbolSeparator) {
+			problems = append(problems, fmt.Errorf("always watched symbol '%s' should be a full symbol name", sym))
+		}
+	}
+	checkEntries("whitelist", config.WhitelistedLibs)
+	// Only library globs are matched as patterns - paths and library names are prefixes, which may hold any character
+	for _, entry := range config.WhitelistedLibs {
+		if strings.HasPrefix(entry, "/") || !isLibraryGlob(entry) {
+			continue
+		}
+		if _, err := path.Match(entry, ""); err != nil {
+			problems = append(problems, fmt.Errorf("whitelist entry '%s' is not a valid pattern: %v", entry, err))
+		}
+	}
+	checkEntries("watched import", config.WatchedImports)
+	for _, entry := range config.WatchedImports {
+		if strings.Contains(entry, librarySymbolSeparator) {
+			library, sym := splitLibrarySymbol(entry)
+			if library == "" || sym == "" {
+				problems = append(problems, fmt.Errorf("watched import entry '%s' is missing its library or symbol", entry))
+				continue
+			}
+			if strings.Contains(library, "/") || strings.Contains(sym, librarySymbolSeparator) {
+				problems = append(problems, fmt.Errorf("watched import entry '%s' library should be a file name", entry))
+				continue
+			}
+		}
+		if !strings.Contains(entry, importVersionSeparator) {
+			continue
+		}
+		_, imported := splitLibrarySymbol(entry)
+		sym, version := splitImportVersion(imported)
+		if sym == "" || version == "" || strings.Contains(version, importVersionSeparator) {
+			problems = append(problems, fmt.Errorf("watched import entry '%s' should be '<symbol>@<version>'", entry))
+		}
+	}
+	for _, sym := range config.WatchedTLSSymbols {
+		if sym == "" || strings.HasSuffix(sym, prefixWildcard) || strings.Contains(sym, librarySymbolSeparator) {
+			problems = append(problems, fmt.Errorf("watched TLS symbol '%s' should be a full symbol name", sym))
+		}
+	}
+	for _, entry := range config.WatchedVersionedSymbols {
+		sym, version := splitImportVersion(entry)
+		if sym == "" || version == "" || strings.Contains(version, importVersionSeparator) ||
+			strings.HasSuffix(sym, prefixWildcard) || strings.Contains(sym, librarySymbolSeparator) {
+			problems = append(problems, fmt.Errorf("watched versioned symbol '%s' should be '<symbol>@<version>'", entry))
+		}
+	}
+	for _, expr := range config.WhitelistedRegexps {
+		if _, err := regexp.Compile(expr); err != nil {
+			problems = append(problems, fmt.Errorf("whitelist regexp entry '%s' is invalid: %v", expr, err))
+		}
+	}
+	if config.AllowlistMode && len(config.WhitelistedLibs) == 0 &&
+		len(config.WhitelistedRegexps) == 0 && len(config.WhitelistFiles) == 0 {
+		problems = append(problems, fmt.Errorf("allowlist mode is configured with no libraries - the event will never be derived"))
+	}
+
+	watched := make(map[string]bool, len(config.WatchedSymbols))
+	for _, sym := range config.WatchedSymbols {
+		watched[sym] = true
+	}
+	for _, sym := range config.AlwaysWatchedSymbols {
+		watched[sym] = true
+	}
+	for _, sym := range config.ExcludedSymbols {
+		if watched[sym] {
+			problems = append(problems, fmt.Errorf("symbol '%s' is both watched and excluded", sym))
+		}
+	}
+
+	if config.Interpreter < InterpreterDefault || config.Interpreter > InterpreterInclude {
+		problems = append(problems, fmt.Errorf("unknown interpreter mode %d", config.Interpreter))
+	}
+
+	problems = append(problems, validateSuspiciousPaths(config.SuspiciousPaths)...)
+	problems = append(problems, validateProcessScope(config.ProcessScope)...)
+	problems = append(problems, validateAliases(config.SymbolAliases)...)
+	problems = append(problems, validateSonameSymbols("baseline", config.BaselineSymbols)...)
+	problems = append(problems, validateWatchGroups(config.WatchGroups, config.StopOnFirstMatch)...)
+	problems = append(problems, validateMatchMode(config)...)
+	problems = append(problems, validateDemangledSymbols(config)...)
+
+	rulesNames := make(map[string]bool, len(config.Rules))
+	for _, rule := range config.Rules {
+		if rule.Name == "" {
+			problems = append(problems, fmt.Errorf("rule with no name"))
+		} else if rulesNames[rule.Name] {
+			problems = append(problems, fmt.Errorf("rule '%s' is defined more than once", rule.Name))
+		}
+		rulesNames[rule.Name] = true
+		if rule.Predicate == nil {
+			problems = append(problems, fmt.Errorf("rule '%s' has no predicate", rule.Name))
+		}
+	}
+	return problems
+}
+
+// matchObject matches the watched symbols, imports, rules and groups with the SO of the match
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchObject(match *symbolsMatch) error {
+	if err := symbsLoadedGen.matchWatchedSymbols(match); err != nil {
+		return err
+	}
+	if err := symbsLoadedGen.removeBaselineSymbols(match); err != nil {
+		return err
+	}
+	symbsLoadedGen.applyMinMatches(match)
+
+	var err error
+	objInfo := match.objInfo
+	if match.rules, err = symbsLoadedGen.matchRules(objInfo); err != nil {
+		return err
+	}
+	if match.imports, match.importsInfo, err = symbsLoadedGen.matchWatchedImports(objInfo); err != nil {
+		return err
+	}
+	if match.tls, err = symbsLoadedGen.matchWatchedTLSSymbols(objInfo); err != nil {
+		return err
+	}
+	if match.versioned, err = symbsLoadedGen.matchWatchedVersionedSymbols(objInfo); err != nil {
+		return err
+	}
+	if match.demangled, err = symbsLoadedGen.matchWatchedDemangledSymbols(objInfo); err != nil {
+		return err
+	}
+	match.groups, err = symbsLoadedGen.matchWatchGroups(objInfo, match.suspicious != "")
+	return err
+}
+
+// hasWatched checks if the SO of the match matched any of the watched symbols, imports, rules or groups
+func (match *symbolsMatch) hasWatched() bool {
+	return len(match.symbols) > 0 || len(match.rules) > 0 || len(match.imports) > 0 || len(match.tls) > 0 ||
+		len(match.versioned) > 0 || len(match.demangled) > 0 || len(match.groups) > 0
+}
+
+// matchWatchedSymbols loads the exported symbols of the SO of the match, and adds the watched symbols among them to
+// the match.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchWatchedSymbols(match *symbolsMatch) error {
+	objInfo := match.objInfo
+	if symbsLoadedGen.symbolsInfoLoader != nil {
+		soSymsInfo, err := symbsLoadedGen.symbolsInfoLoader.GetExportedSymbolsInfo(objInfo)
+		if err != nil {
+			return err
+		}
+		match.exported = len(soSymsInfo)
+		symbsLoadedGen.setFingerprint(match, symbolsInfoNames(soSymsInfo))
+		for sym, info := range soSymsInfo {
+			if !symbsLoadedGen.isWatched(sym, objInfo.Path) {
+				continue
+			}
+			if symbsLoadedGen.watchedVisibilities != nil && !symbsLoadedGen.watchedVisibilities[info.Visibility] {
+				continue
+			}
+			if symbsLoadedGen.executableOnly && !info.ExecutableSection {
+				continue
+			}
+			if symbsLoadedGen.expectedSizes != nil && !symbsLoadedGen.isUnexpectedSize(sym, info) {
+				continue
+			}
+			match.symbols = append(match.symbols, sym)
+			match.symbolsInfo = append(match.symbolsInfo, info)
+		}
+		if symbsLoadedGen.expectedIndexes != nil {
+			match.unexpected = symbsLoadedGen.unexpectedIndexes(match.symbols, match.symbolsInfo)
+		}
+		return nil
+	}
+
+	if symbsLoadedGen.symbolChecker != nil && symbsLoadedGen.watchedPrefixes == nil {
+		return symbsLoadedGen.checkWatchedSymbols(match)
+	}
+
+	soSyms, err := symbsLoadedGen.soLoader.GetExportedSymbols(objInfo)
+	if err != nil {
+		return err
+	}
+	match.exported = len(soSyms)
+	symbsLoadedGen.setFingerprint(match, symbolsNames(soSyms))
+	if symbsLoadedGen.watchedPrefixes != nil {
+		// Each symbol of the SO has to be examined against the prefixes
+		for sym := range soSyms {
+			if symbsLoadedGen.isWatched(sym, objInfo.Path) {
+				match.symbols = append(match.symbols, sym)
+			}
+		}
+		return nil
+	}
+	match.symbols = MatchWatchedSymbols(soSyms, symbsLoadedGen.watchedSymbols)
+	match.symbols = append(match.symbols, symbsLoadedGen.matchLibrarySymbols(soSyms, objInfo.Path)...)
+	return nil
+}
+
+// isWatched checks if the symbol is watched when exported by the SO in the given path
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) isWatched(sym string, soPath string) bool {
+	if symbsLoadedGen.watchedSymbols[sym] || symbsLoadedGen.isLibrarySymbol(sym, soPath) {
+		return true
+	}
+	return symbsLoadedGen.watchedPrefixes != nil && !symbsLoadedGen.excludedSymbols[sym] &&
+		symbsLoadedGen.watchedPrefixes.matches(sym)
+}
+
+// MatchWatchedSymbols returns the symbols of the given symbols set which are watched.
+// The smaller of the sets is iterated, and the result is allocated only if a symbol is matched.
+// The order of the returned symbols is not defined.
+func MatchWatchedSymbols(soSyms map[string]bool, watched map[string]bool) []string {
+	iterated, looked := soSyms, watched
+	if len(watched) < len(soSyms) {
+		iterated, looked = watched, soSyms
+	}
+	var matched []string
+	for sym, ok := range iterated {
+		if ok && looked[sym] {
+			matched = append(matched, sym)
+		}
+	}
+	return matched
+}
+
+// maxCheckedWatchedSymbols is the maximal amount of watched symbols which are checked one by one with loaders which
+// can check single symbols. Checking more symbols costs more than copying the symbols of a typical SO.
+const maxCheckedWatchedSymbols = 256
+
+// checkWatchedSymbols matches the watched symbols by checking each of them with the loader, so the symbols of the
+// SO are not copied. It is used only if no prefixes are watched, as they have to be matched against all the symbols.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) checkWatchedSymbols(match *symbolsMatch) error {
+	for sym := range symbsLoadedGen.watchedSymbols {
+		exported, err := symbsLoadedGen.symbolChecker.IsSymbolExported(match.objInfo, sym)
+		if err != nil {
+			return err
+		}
+		if exported {
+			match.symbols = append(match.symbols, sym)
+		}
+	}
+	for sym := range symbsLoadedGen.librarySymbols {
+		if !symbsLoadedGen.isLibrarySymbol(sym, match.objInfo.Path) {
+			continue
+		}
+		exported, err := symbsLoadedGen.symbolChecker.IsSymbolExported(match.objInfo, sym)
+		if err != nil {
+			return err
+		}
+		if exported {
+			match.symbols = append(match.symbols, sym)
+		}
+	}
+	return nil
+}
+
+// isIgnored check if a SO should not be examined, according to the whitelist or the allowlist if configured
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) isIgnored(soPath string) bool {
+	if symbsLoadedGen.allowlistMode {
+		return !symbsLoadedGen.isWhitelist(soPath)
+	}
+	return symbsLoadedGen.isWhitelist(soPath)
+}
+
+// isWhitelist check if a SO's path is in the whitelist given in initialization
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) isWhitelist(soPath string) bool {
+	// Check absolute path libraries whitelist
+	for _, prefix := range symbsLoadedGen.pathPrefixWhitelist {
+		if strings.HasPrefix(soPath, prefix) {
+			return true
+		}
+	}
+
+	// Check full path regular expressions whitelist
+	for _, expr := range symbsLoadedGen.regexpsWhitelist {
+		if expr.MatchString(soPath) {
+			return true
+		}
+	}
+
+	// Check if SO is whitelisted library which resides in one of the known libs paths
+	if len(symbsLoadedGen.librariesWhitelist) > 0 || len(symbsLoadedGen.librariesGlobs) > 0 {
+		librariesDirs := symbsLoadedGen.librariesDirs
+		if librariesDirs == nil {
+			librariesDirs = knownLibrariesDirs
+		}
+		for _, libsDirectory := range librariesDirs {
+			if strings.HasPrefix(soPath, libsDirectory) {
+				for _, wlLib := range symbsLoadedGen.librariesWhitelist {
+					if strings.HasPrefix(soPath, path.Join(libsDirectory, wlLib)) {
+						return true
+					}
+				}
+				relativePath := strings.TrimPrefix(soPath, libsDirectory)
+				for _, pattern := range symbsLoadedGen.librariesGlobs {
+					if matchLibraryGlob(pattern, relativePath) {
+						return true
+					}
+				}
+				break
+			}
+		}
+	}
+	return false
+}
+
+// normalizeWhitelist removes whitelist entries which are duplicate or subsumed by a broader entry, as matching
+// them is redundant. Paths are cleaned before comparing them (keeping a trailing slash, which limits the prefix to
+// a directory). The remaining entries are ordered from the most specific to the broadest.
+func normalizeWhitelist(entries []string, cleanPaths bool) []string {
+	normalized := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if cleanPaths {
+			entry = cleanWhitelistEntry(entry)
+		}
+		normalized = append(normalized, entry)
+	}
+	// Sorting from the broadest entry guarantees that an entry subsuming another is examined first
+	sort.Slice(normalized, func(i, j int) bool {
+		if len(normalized[i]) != len(normalized[j]) {
+			return len(normalized[i]) < len(normalized[j])
+		}
+		return normalized[i] < normalized[j]
+	})
+	var kept []string
+	for _, entry := range normalized {
+		subsumed := false
+		for _, broader := range kept {
+			if strings.HasPrefix(entry, broader) {
+				subsumed = true
+				break
+			}
+		}
+		if !subsumed {
+			kept = append(kept, entry)
+		}
+	}
+	// Most specific entries first
+	for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+		kept[i], kept[j] = kept[j], kept[i]
+	}
+	return kept
+}
+
+// cleanWhitelistEntry cleans the path of a whitelist paths prefix entry, keeping a trailing slash, which limits the
+// prefix to a directory. Entries which are not paths are returned as they are.
+func cleanWhitelistEntry(entry string) string {
+	if !strings.HasPrefix(entry, "/") {
+		return entry
+	}
+	cleaned := path.Clean(entry)
+	if strings.HasSuffix(entry, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
@@ -0,0 +1,109 @@
+package derive
+
+import (
+	"debug/elf"
+	"fmt"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// metadataSymbolsLoader adapts a metadata loader to the loaders interfaces which the derivation uses in the
+// metadata only mode. The SOs are read for their metadata only, and they are considered as exporting and importing
+// no symbols.
+type metadataSymbolsLoader struct {
+	loader sharedobjs.MetadataLoader
+}
+
+func (metaLoader metadataSymbolsLoader) noSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
+	if _, err := metaLoader.loader.GetMetadata(info); err != nil {
+		return nil, err
+	}
+	return map[string]bool{}, nil
+}
+
+func (metaLoader metadataSymbolsLoader) GetDynamicSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
+	return metaLoader.noSymbols(info)
+}
+
+func (metaLoader metadataSymbolsLoader) GetExportedSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
+	return metaLoader.noSymbols(info)
+}
+
+func (metaLoader metadataSymbolsLoader) GetImportedSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
+	return metaLoader.noSymbols(info)
+}
+
+func (metaLoader metadataSymbolsLoader) GetSoname(info sharedobjs.ObjInfo) (string, error) {
+	metadata, err := metaLoader.loader.GetMetadata(info)
+	return metadata.Soname, err
+}
+
+func (metaLoader metadataSymbolsLoader) GetBuildID(info sharedobjs.ObjInfo) (string, error) {
+	metadata, err := metaLoader.loader.GetMetadata(info)
+	return metadata.BuildID, err
+}
+
+func (metaLoader metadataSymbolsLoader) HasNote(info sharedobjs.ObjInfo, note sharedobjs.NoteID) (bool, error) {
+	metadata, err := metaLoader.loader.GetMetadata(info)
+	return metadata.Notes[note], err
+}
+
+func (metaLoader metadataSymbolsLoader) IsInterpreter(info sharedobjs.ObjInfo) (bool, error) {
+	metadata, err := metaLoader.loader.GetMetadata(info)
+	return metadata.Interpreter, err
+}
+
+func (metaLoader metadataSymbolsLoader) GetWritableCodeSegments(info sharedobjs.ObjInfo) ([]sharedobjs.Segment, error) {
+	metadata, err := metaLoader.loader.GetMetadata(info)
+	return metadata.WXSegments, err
+}
+
+func (metaLoader metadataSymbolsLoader) GetInterpreter(info sharedobjs.ObjInfo) (string, error) {
+	metadata, err := metaLoader.loader.GetMetadata(info)
+	return metadata.Interp, err
+}
+
+func (metaLoader metadataSymbolsLoader) GetDynamicTags(info sharedobjs.ObjInfo) ([]elf.DynTag, error) {
+	metadata, err := metaLoader.loader.GetMetadata(info)
+	return metadata.DynamicTags, err
+}
+
+// metadataOnlyProblems returns the problems of configuring features which match symbols in the metadata only mode
+func metadataOnlyProblems(config symbolsLoadedSettings) []error {
+	features := []struct {
+		name       string
+		configured bool
+	}{
+		{"watched symbols", len(config.Matching.WatchedSymbols) > 0},
+		{"always watched symbols", len(config.Matching.AlwaysWatchedSymbols) > 0},
+		{"watched imports", len(config.Matching.WatchedImports) > 0},
+		{"watched TLS symbols", len(config.Matching.WatchedTLSSymbols) > 0},
+		{"watched versioned symbols", len(config.Matching.WatchedVersionedSymbols) > 0},
+		{"watched demangled symbols", len(config.Matching.WatchedDemangledSymbols) > 0},
+		{"rules", len(config.Matching.Rules) > 0},
+		{"watch groups", len(config.Matching.WatchGroups) > 0},
+		{"symbol sets", len(config.Detection.SymbolSets) > 0},
+		{"baseline symbols", len(config.Matching.BaselineSymbols) > 0},
+		{"expected symbols", len(config.Detection.ExpectedSymbols) > 0},
+		{"weak symbols", len(config.Detection.WeakSymbols) > 0},
+		{"symbol aliases", len(config.Matching.SymbolAliases) > 0},
+		{"symbols information", len(config.Matching.WatchedVisibilities) > 0 || config.Reporting.ReportVisibility ||
+			config.Matching.ExecutableSectionsOnly || config.Reporting.ReportSection ||
+			config.Reporting.ReportSymbolIndex || len(config.Detection.ExpectedSymbolIndexes) > 0 ||
+			config.Reporting.ReportSymbolSize || len(config.Detection.ExpectedSymbolSizes) > 0},
+		{"symbols count", config.Reporting.ReportSymbolsCount},
+		{"symbols fingerprint", config.Reporting.ReportSymbolsFingerprint},
+		{"constructors", config.Reporting.ReportConstructors},
+		{"symbols truncation", config.Reporting.ReportSymbolsTruncation},
+		{"code entropy", config.Detection.FlagHighEntropy},
+		{"relocation thresholds", len(config.Detection.RelocationThresholds) > 0},
+		{"symbols hashes", config.Reporting.SymbolsHash != SymbolsHashNone},
+	}
+	var problems []error
+	for _, feature := range features {
+		if feature.configured {
+			problems = append(problems, fmt.Errorf("%s can't be configured in the metadata only mode", feature.name))
+		}
+	}
+	return problems
+}
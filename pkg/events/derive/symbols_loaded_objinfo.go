@@ -0,0 +1,41 @@
+package derive
+
+import (
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// ObjInfoDeriveFunction is like events.DeriveFunction, but it is also given the information of the loaded SO, which
+// was already parsed from the event
+type ObjInfoDeriveFunction func(event trace.Event, objInfo sharedobjs.ObjInfo) ([]trace.Event, []error)
+
+// SymbolsLoadedFromObjInfo is like SymbolsLoaded, but the function it returns is given the information of the
+// loaded SO alongside the shared_object_loaded event, so pipelines which already parsed it (e.g. for another
+// derivation of the same event) don't parse the event arguments again. The information should be parsed from the
+// given event, whose context (e.g. its process) is still used.
+// In the asynchronous mode, the event is queued as usual, and the background worker parses it. In the exec mapping
+// correlation mode, the event is parsed again.
+func SymbolsLoadedFromObjInfo(gen *SymbolsLoadedEventGenerator) ObjInfoDeriveFunction {
+	return func(event trace.Event, objInfo sharedobjs.ObjInfo) ([]trace.Event, []error) {
+		if gen.asyncQueue != nil {
+			return singleSkeletonDeriveFunc(gen.skeleton, gen.enqueueArgs)(event)
+		}
+		if gen.pending != nil {
+			return gen.deriveCorrelated(event, true)
+		}
+		deriveArgs := func(event trace.Event) ([]interface{}, error) {
+			if !gen.acquire() {
+				return nil, nil
+			}
+			defer gen.release()
+			return gen.deriveObjectArgs(event, objInfo)
+		}
+		// SOs loaded by processes out of the scope are not examined, so they don't need a deadline
+		if gen.extractionDeadline > 0 && gen.inScope(&event) {
+			return singleSkeletonDeriveFunc(gen.skeleton, func(event trace.Event) ([]interface{}, error) {
+				return gen.deriveWithDeadline(event, objInfo, deriveArgs)
+			})(event)
+		}
+		return singleSkeletonDeriveFunc(gen.skeleton, deriveArgs)(event)
+	}
+}
@@ -0,0 +1,202 @@
+package derive
+
+import (
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// SymbolsLoadedOption sets a part of the configuration of a generator created by NewSymbolsLoadedGenerator.
+// Options of lists append to the entries given by earlier options, so they can be composed (e.g. a base watch list
+// extended by the options of a specific deployment). The groups of options of the features are set as a whole by
+// WithMatching, WithDetection, WithReporting, WithTracking and WithExtraction, which set the options having no option
+// function of their own.
+type SymbolsLoadedOption func(config *symbolsLoadedSettings)
+
+// NewSymbolsLoadedGenerator creates a generator with the configuration built by applying the given options, in their
+// order, to an empty configuration. The built configuration is validated like the configuration given to
+// InitSymbolsLoadedEventGenerator.
+func NewSymbolsLoadedGenerator(
+	soLoader sharedobjs.DynamicSymbolsLoader,
+	opts ...SymbolsLoadedOption) (*SymbolsLoadedEventGenerator, error) {
+	return newSymbolsLoadedEventGenerator(soLoader, newSymbolsLoadedSettings(opts...))
+}
+
+// newSymbolsLoadedSettings returns the configuration built by applying the given options, in their order, to an empty
+// configuration
+func newSymbolsLoadedSettings(opts ...SymbolsLoadedOption) symbolsLoadedSettings {
+	var config symbolsLoadedSettings
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
+// WithWatchedSymbols adds entries to the watched symbols (see SymbolsMatchingConfig.WatchedSymbols)
+func WithWatchedSymbols(symbols ...string) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching.WatchedSymbols = append(config.Matching.WatchedSymbols, symbols...)
+	}
+}
+
+// WithExcludedSymbols adds symbols which are never watched
+func WithExcludedSymbols(symbols ...string) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching.ExcludedSymbols = append(config.Matching.ExcludedSymbols, symbols...)
+	}
+}
+
+// WithAlwaysWatchedSymbols adds symbols which are watched even in ignored SOs
+func WithAlwaysWatchedSymbols(symbols ...string) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching.AlwaysWatchedSymbols = append(config.Matching.AlwaysWatchedSymbols, symbols...)
+	}
+}
+
+// WithWatchedImports adds entries to the watched imports (see SymbolsMatchingConfig.WatchedImports)
+func WithWatchedImports(imports ...string) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching.WatchedImports = append(config.Matching.WatchedImports, imports...)
+	}
+}
+
+// WithWhitelist adds entries of SOs to ignore: paths prefixes, libraries names prefixes or glob patterns
+func WithWhitelist(libs ...string) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching.WhitelistedLibs = append(config.Matching.WhitelistedLibs, libs...)
+	}
+}
+
+// WithWhitelistedRegexps adds regular expressions of the full paths of SOs to ignore
+func WithWhitelistedRegexps(exprs ...string) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching.WhitelistedRegexps = append(config.Matching.WhitelistedRegexps, exprs...)
+	}
+}
+
+// WithWhitelistFiles adds whitelist files, merged after the files given by earlier options (see
+// SymbolsMatchingConfig.WhitelistFiles)
+func WithWhitelistFiles(files ...string) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching.WhitelistFiles = append(config.Matching.WhitelistFiles, files...)
+	}
+}
+
+// WithAllowlist inverts the whitelist, so only SOs matching its entries (including the given ones) are examined
+func WithAllowlist(libs ...string) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching.AllowlistMode = true
+		config.Matching.WhitelistedLibs = append(config.Matching.WhitelistedLibs, libs...)
+	}
+}
+
+// WithRules adds rules matched against the symbols of each SO
+func WithRules(rules ...SymbolsRule) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching.Rules = append(config.Matching.Rules, rules...)
+	}
+}
+
+// WithWatchGroups adds watch groups, after the groups given by earlier options in the order of priority
+func WithWatchGroups(groups ...SymbolsWatchGroup) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching.WatchGroups = append(config.Matching.WatchGroups, groups...)
+	}
+}
+
+// WithStopOnFirstMatch stops matching the watch groups of a SO once one of them matches it
+func WithStopOnFirstMatch() SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching.StopOnFirstMatch = true
+	}
+}
+
+// WithMinMatches sets the minimal amount of the watched symbols which a SO should export for them to be matched
+func WithMinMatches(minMatches int) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching.MinMatches = minMatches
+	}
+}
+
+// WithMatchMode sets how many of the watched symbols a SO should export for them to be matched
+func WithMatchMode(mode SymbolsMatchMode) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching.MatchMode = mode
+	}
+}
+
+// WithDemangledSymbols adds demangled C++ symbols to watch, matched as names or full signatures by the mode
+func WithDemangledSymbols(mode DemangledMatchMode, symbols ...string) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching.WatchedDemangledSymbols = append(config.Matching.WatchedDemangledSymbols, symbols...)
+		config.Matching.DemangledMatch = mode
+	}
+}
+
+// WithMaxSymbolsPerEvent limits the amount of symbols reported in a single event
+func WithMaxSymbolsPerEvent(maxSymbols int) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Reporting.MaxSymbolsPerEvent = maxSymbols
+	}
+}
+
+// WithEventName derives the given event instead of symbols_loaded
+func WithEventName(eventName string) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.EventName = eventName
+	}
+}
+
+// WithLogger sets the logger receiving the decisions taken for each SO
+func WithLogger(logger SymbolsLoadedLogger) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Logger = logger
+	}
+}
+
+// WithMetadataOnly never extracts the symbols of SOs, and derives the event with their metadata only
+func WithMetadataOnly() SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Extraction.MetadataOnly = true
+	}
+}
+
+// WithClock sets the source of time of the generator
+func WithClock(clock sharedobjs.Clock) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Clock = clock
+	}
+}
+
+// WithMatching sets the matching of the watched symbols, replacing the entries given by earlier options
+func WithMatching(matching SymbolsMatchingConfig) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Matching = matching
+	}
+}
+
+// WithDetection sets the detections of suspicious properties of SOs
+func WithDetection(detection SymbolsDetectionConfig) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Detection = detection
+	}
+}
+
+// WithReporting sets what is added to the derived event, replacing the limit given by WithMaxSymbolsPerEvent
+func WithReporting(reporting SymbolsReportingConfig) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Reporting = reporting
+	}
+}
+
+// WithTracking sets the state kept across the examined SOs
+func WithTracking(tracking SymbolsTrackingConfig) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Tracking = tracking
+	}
+}
+
+// WithExtraction sets how the SOs are examined, replacing the mode given by WithMetadataOnly
+func WithExtraction(extraction SymbolsExtractionConfig) SymbolsLoadedOption {
+	return func(config *symbolsLoadedSettings) {
+		config.Extraction = extraction
+	}
+}
@@ -0,0 +1,70 @@
+package derive
+
+import (
+	"fmt"
+
+	"github.com/aquasecurity/tracee/pkg/events/parse"
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// passthroughArgs are the arguments of the shared_object_loaded event which can be passed through to the derived
+// events, by their names, with the metadata they are added with
+var passthroughArgs = map[string]trace.ArgMeta{
+	"pathname": {Type: "const char*", Name: "pathname"},
+	"flags":    {Type: "int", Name: "flags"},
+	"dev":      {Type: "dev_t", Name: "dev"},
+	"inode":    {Type: "unsigned long", Name: "inode"},
+	"ctime":    {Type: "unsigned long", Name: "ctime"},
+}
+
+// validatePassthroughArgs checks the passed through arguments for mistakes
+func validatePassthroughArgs(config SymbolsReportingConfig) []error {
+	var problems []error
+	names := make(map[string]bool, len(config.PassthroughArgs))
+	for _, name := range config.PassthroughArgs {
+		if _, ok := passthroughArgs[name]; !ok {
+			problems = append(problems, fmt.Errorf("passthrough argument '%s' is not an argument of shared_object_loaded", name))
+			continue
+		}
+		if names[name] {
+			problems = append(problems, fmt.Errorf("passthrough argument '%s' is configured more than once", name))
+		}
+		names[name] = true
+		if config.ReportObjectID && name != "pathname" && name != "flags" {
+			problems = append(problems, fmt.Errorf("passthrough argument '%s' is already reported as the SO identity", name))
+		}
+	}
+	return problems
+}
+
+// passthroughValue parses the value of a passed through argument from the SO loading event, converted to the Go type
+// of its metadata type
+func passthroughValue(event *trace.Event, name string) (interface{}, error) {
+	switch name {
+	case "pathname":
+		return parse.ArgStringVal(event, name)
+	case "flags":
+		flags, err := parse.ArgInt32Val(event, name)
+		return int(flags), err
+	case "dev":
+		return parse.ArgUint32Val(event, name)
+	default:
+		return parse.ArgUint64Val(event, name)
+	}
+}
+
+// passthroughValues returns the values of the passed through arguments of the SO loading event, in their configured
+// order, or nil if no argument is passed through. Arguments which can't be parsed have a nil value, which fails the
+// derivation of the event (see newTypedArgument), as other arguments of the event which are missing do.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) passthroughValues(event *trace.Event) []interface{} {
+	if len(symbsLoadedGen.passthrough) == 0 {
+		return nil
+	}
+	values := make([]interface{}, len(symbsLoadedGen.passthrough))
+	for i, name := range symbsLoadedGen.passthrough {
+		if value, err := passthroughValue(event, name); err == nil {
+			values[i] = value
+		}
+	}
+	return values
+}
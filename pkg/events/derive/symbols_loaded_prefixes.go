@@ -0,0 +1,118 @@
+package derive
+
+import (
+	"sort"
+	"strings"
+)
+
+// prefixWildcard marks a watched symbol entry as a prefix, e.g. "EVP_*" watches all the symbols starting with "EVP_"
+const prefixWildcard = "*"
+
+// prefixTree is a radix tree of watched symbols prefixes, which checks if a symbol starts with any of the prefixes
+// in a time depending on the symbol length, rather than on the amount of prefixes.
+type prefixTree struct {
+	root prefixNode
+}
+
+// prefixNode is a node of the prefix tree. The label of the node is the part of the prefixes which is shared by all
+// the prefixes under the node, following the labels of its ancestors.
+type prefixNode struct {
+	label    string
+	children map[byte]*prefixNode
+	terminal bool // A prefix ends in this node, so all the prefixes under it are redundant
+}
+
+// newPrefixTree builds a prefix tree of the given prefixes
+func newPrefixTree(prefixes []string) *prefixTree {
+	tree := &prefixTree{}
+	for _, prefix := range prefixes {
+		tree.insert(prefix)
+	}
+	return tree
+}
+
+// insert adds a prefix to the tree.
+// Prefixes starting with a prefix which is already in the tree are ignored, and prefixes of the prefixes in the tree
+// replace them.
+func (tree *prefixTree) insert(prefix string) {
+	node := &tree.root
+	for {
+		if node.terminal {
+			return
+		}
+		if prefix == "" {
+			node.terminal = true
+			node.children = nil
+			return
+		}
+		if node.children == nil {
+			node.children = make(map[byte]*prefixNode)
+		}
+		child, ok := node.children[prefix[0]]
+		if !ok {
+			node.children[prefix[0]] = &prefixNode{label: prefix, terminal: true}
+			return
+		}
+		common := commonPrefixLength(child.label, prefix)
+		if common < len(child.label) {
+			// Split the child, so the common part of the labels is a node of its own
+			split := &prefixNode{
+				label:    child.label[:common],
+				children: map[byte]*prefixNode{child.label[common]: child},
+			}
+			child.label = child.label[common:]
+			node.children[prefix[0]] = split
+			child = split
+		}
+		prefix = prefix[common:]
+		node = child
+	}
+}
+
+// matches checks if the symbol starts with any of the prefixes in the tree
+func (tree *prefixTree) matches(sym string) bool {
+	node := &tree.root
+	for {
+		if node.terminal {
+			return true
+		}
+		if sym == "" {
+			return false
+		}
+		child, ok := node.children[sym[0]]
+		if !ok || !strings.HasPrefix(sym, child.label) {
+			return false
+		}
+		sym = sym[len(child.label):]
+		node = child
+	}
+}
+
+// prefixes returns the prefixes in the tree, in alphabetical order. Redundant prefixes which were ignored or
+// replaced when they were inserted are not returned.
+func (tree *prefixTree) prefixes() []string {
+	var prefixes []string
+	var walk func(node *prefixNode, prefix string)
+	walk = func(node *prefixNode, prefix string) {
+		prefix += node.label
+		if node.terminal {
+			prefixes = append(prefixes, prefix)
+			return
+		}
+		for _, child := range node.children {
+			walk(child, prefix)
+		}
+	}
+	walk(&tree.root, "")
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// commonPrefixLength returns the length of the longest common prefix of the given strings
+func commonPrefixLength(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,131 @@
+package derive
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/aquasecurity/tracee/pkg/events"
+	"github.com/aquasecurity/tracee/pkg/events/parse"
+	"github.com/aquasecurity/tracee/types/trace"
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+const (
+	// DefaultMaxProfiledProcesses is the default maximal amount of processes whose lifetime profile is kept
+	DefaultMaxProfiledProcesses = 4096
+	// DefaultMaxProfileEntries is the default maximal amount of symbols and SOs kept in the profile of a process
+	DefaultMaxProfileEntries = 256
+)
+
+// processProfile is the union of the matches of the SOs loaded by a process during its lifetime
+type processProfile struct {
+	symbols   map[string]bool
+	libraries map[string]bool // The paths of the SOs which provided the symbols
+	loads     int
+	truncated bool
+}
+
+// processProfiles accumulates the profile of each process, until it is taken when the process exits.
+// The amount of processes is bounded - the profile of the process which matched least recently is dropped - and so
+// is the amount of entries of each profile, beyond which further symbols and SOs are dropped. It is safe for
+// concurrent use.
+type processProfiles struct {
+	mutex      sync.Mutex
+	profiles   *simplelru.LRU // pid -> *processProfile
+	maxEntries int
+}
+
+func newProcessProfiles(size int, maxEntries int) *processProfiles {
+	profiles, _ := simplelru.NewLRU(size, nil)
+	return &processProfiles{profiles: profiles, maxEntries: maxEntries}
+}
+
+// record adds the matched symbols of the SO loaded by the process to its profile.
+// The symbols are added in alphabetical order, so the same symbols are kept when the profile bound is exceeded.
+func (profiles *processProfiles) record(pid int, soPath string, symbols []string) {
+	sorted := make([]string, len(symbols))
+	copy(sorted, symbols)
+	sort.Strings(sorted)
+	profiles.mutex.Lock()
+	defer profiles.mutex.Unlock()
+	var profile *processProfile
+	if existing, ok := profiles.profiles.Get(pid); ok {
+		profile = existing.(*processProfile)
+	} else {
+		profile = &processProfile{symbols: make(map[string]bool), libraries: make(map[string]bool)}
+		profiles.profiles.Add(pid, profile)
+	}
+	profile.loads++
+	profile.add(profile.libraries, soPath, profiles.maxEntries)
+	for _, sym := range sorted {
+		profile.add(profile.symbols, sym, profiles.maxEntries)
+	}
+}
+
+// add adds the entry to the given set of the profile, unless the profile is full
+func (profile *processProfile) add(set map[string]bool, entry string, maxEntries int) {
+	if set[entry] {
+		return
+	}
+	if len(profile.symbols)+len(profile.libraries) >= maxEntries {
+		profile.truncated = true
+		return
+	}
+	set[entry] = true
+}
+
+// take removes the profile of the process and returns it, or nil if no match was recorded for the process
+func (profiles *processProfiles) take(pid int) *processProfile {
+	profiles.mutex.Lock()
+	defer profiles.mutex.Unlock()
+	profile, ok := profiles.profiles.Get(pid)
+	if !ok {
+		return nil
+	}
+	profiles.profiles.Remove(pid)
+	return profile.(*processProfile)
+}
+
+// sortedSet returns the entries of the set in alphabetical order
+func sortedSet(set map[string]bool) []string {
+	entries := make([]string, 0, len(set))
+	for entry := range set {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// SymbolsLoadedProfile receives the generator of the symbols_loaded event as a closure argument.
+// If it receives the sched_process_exit event of a process which loaded SOs with watched symbols, it derives
+// a symbols_loaded_profile event from it, with the union of the matches of the process during its lifetime.
+func SymbolsLoadedProfile(gen *SymbolsLoadedEventGenerator) events.DeriveFunction {
+	return singleSkeletonDeriveFunc(makeTypedEventSkeleton(events.SymbolsLoadedProfile), gen.deriveProfileArgs)
+}
+
+// deriveProfileArgs derive the arguments of the symbols_loaded_profile event, if the whole process exited and
+// matches were recorded for it
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deriveProfileArgs(event trace.Event) ([]interface{}, error) {
+	if !symbsLoadedGen.acquire() {
+		return nil, nil
+	}
+	defer symbsLoadedGen.release()
+
+	if symbsLoadedGen.profiles == nil {
+		return nil, nil
+	}
+	// The profile is of the whole process, so the exit of a thread doesn't end it
+	groupExit, err := parse.ArgBoolVal(&event, "process_group_exit")
+	if err != nil {
+		return nil, err
+	}
+	if !groupExit {
+		return nil, nil
+	}
+	profile := symbsLoadedGen.profiles.take(event.HostProcessID)
+	if profile == nil {
+		return nil, nil
+	}
+	return []interface{}{sortedSet(profile.symbols), sortedSet(profile.libraries), profile.loads, profile.truncated},
+		nil
+}
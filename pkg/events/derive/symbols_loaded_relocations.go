@@ -0,0 +1,58 @@
+package derive
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// matchRelocationThresholds returns the counts of the relocation types with a threshold which the SO has (as
+// "<type>=<count>"), and the types whose count exceeds their threshold, both sorted by the type name
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchRelocationThresholds(
+	objInfo sharedobjs.ObjInfo) ([]string, []string, error) {
+	if symbsLoadedGen.relocationsCounter == nil {
+		return nil, nil, nil
+	}
+	counts, err := symbsLoadedGen.relocationsCounter.GetRelocationCounts(objInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+	var relocCounts, overLimit []string
+	for _, relType := range sortedThresholdTypes(symbsLoadedGen.relocThresholds) {
+		count := counts[relType]
+		if count == 0 {
+			continue
+		}
+		relocCounts = append(relocCounts, fmt.Sprintf("%s=%d", relType, count))
+		if count > symbsLoadedGen.relocThresholds[relType] {
+			overLimit = append(overLimit, relType)
+		}
+	}
+	return relocCounts, overLimit, nil
+}
+
+// sortedThresholdTypes returns the relocation types of the thresholds, sorted by their name
+func sortedThresholdTypes(thresholds map[string]int) []string {
+	types := make([]string, 0, len(thresholds))
+	for relType := range thresholds {
+		types = append(types, relType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// validateRelocationThresholds checks the relocation thresholds for mistakes
+func validateRelocationThresholds(thresholds map[string]int) []error {
+	var problems []error
+	for _, relType := range sortedThresholdTypes(thresholds) {
+		if !strings.HasPrefix(relType, "R_") {
+			problems = append(problems, fmt.Errorf("relocation type '%s' should be a relocation name (e.g. R_X86_64_COPY)", relType))
+		}
+		if thresholds[relType] < 0 {
+			problems = append(problems, fmt.Errorf("negative threshold %d of relocation type '%s'", thresholds[relType], relType))
+		}
+	}
+	return problems
+}
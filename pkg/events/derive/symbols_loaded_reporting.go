@@ -0,0 +1,501 @@
+package derive
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// SymbolsReportingConfig is the configuration of what is added to the derived event for each match
+type SymbolsReportingConfig struct {
+	ReportVisibility  bool // Add the visibility of each matched symbol to the event
+	ReportSection     bool // Add the section name of each matched symbol to the event
+	ReportSymbolIndex bool // Add the index of each matched symbol in the dynamic symbols table to the event
+	ReportSymbolSize  bool // Add the size of each matched symbol (see sharedobjs.SymbolInfo.Size) to the event
+	// Maximal amount of symbols reported in a single event. If more symbols are matched, the event symbols are
+	// truncated, and the total amount of matched symbols is added to the event. If 0, there is no limit.
+	MaxSymbolsPerEvent int
+	// Report the matched symbols as a single comma joined string (the symbols_compact argument) with their amount,
+	// and leave the symbols argument empty. A single string is much cheaper to marshal than a list of strings, for
+	// consumers ingesting many events. The symbols are joined after they are truncated, aliased and hashed.
+	CompactSymbols bool
+	// Add the PLT slot of each matched import to the event, which can be used to attach uprobes to the calls to it
+	ReportPLTSlots bool
+	// Add whether the SO is the dynamic loader to the event
+	ReportInterpreter bool
+	// Whether the matched symbols are reported by their keyed hash (HMAC-SHA256 with SymbolsHashKey), instead of or
+	// alongside their names. The symbols are matched by their names.
+	SymbolsHash    SymbolsHashMode
+	SymbolsHashKey []byte // At least 16 bytes, and should be kept secret
+	// Add the amount of symbols exported by the SO, and its class ("tiny", "small", "medium" or "large"), to the
+	// event
+	ReportSymbolsCount bool
+	// The lower bounds of the exported symbols count of the "small", "medium" and "large" classes, in increasing
+	// order. If empty, DefaultSymbolsCountBoundaries are used.
+	SymbolsCountBoundaries []int
+	// Add the identity of the SO file (its device, inode and ctime, as in the shared_object_loaded event) to the
+	// event, for correlating it with other events of the same file
+	ReportObjectID bool
+	// The arguments of the shared_object_loaded event (pathname, flags, dev, inode and ctime) to add to the event as
+	// they are, in this order, so it can be used without correlating it with the event it was derived from. None are
+	// added by default.
+	PassthroughArgs []string
+	// Add the ID and image of the container of the process which loaded the SO (from the container context of the
+	// shared_object_loaded event) to the event, so it can be filtered by image. SOs loaded by processes which don't
+	// run in a container have an empty container ID and the HostContainerImage image.
+	ReportContainer bool
+	// The severity and action to annotate the event with, by the UID of the process which loaded the SO (the UserID
+	// of the shared_object_loaded event). If empty, the event is not annotated.
+	UserSeverities map[int]SymbolsSeverity
+	// The severity and action of processes whose UID has no configured severity. If empty, their events are
+	// annotated with an empty severity.
+	DefaultSeverity SymbolsSeverity
+	// Add a fingerprint of the exported symbols of the SO (the SHA-256 of their sorted names) to the event, for
+	// comparing the symbols of SOs of the same soname across hosts
+	ReportSymbolsFingerprint bool
+	// Maximal amount of SOs whose fingerprint is kept. If 0, DefaultFingerprintCacheSize is used.
+	FingerprintCacheSize int
+	// Add the amount of constructors of the SO (the entries of its init array) to the event, and whether the SO is
+	// self executing: it both exports watched symbols and declares constructors, so it runs on load
+	ReportConstructors bool
+	// The amount of init array entries which SOs hold regardless of their code, above which a SO declares
+	// constructors. If 0, DefaultConstructorsBaseline is used.
+	ConstructorsBaseline int
+	// Add whether only the first symbols of the SO were read to the event, for SO loaders which cap the amount of
+	// symbols read from each SO (see sharedobjs.HostSymbolsLoaderConfig.MaxSymbols). The watched symbols of a
+	// truncated SO are matched with the symbols which were read only.
+	ReportSymbolsTruncation bool
+	// Add the confidence of each match to the event, between 0 and 1, as scored by ConfidenceScorer from the signals
+	// of the match (see ConfidenceSignals)
+	ReportConfidence bool
+	// Fields from external sources added to the event by a callback for each match, after the other arguments
+	Enrichment SymbolsEnrichment
+}
+
+// symbolsReporter is the reporting feature of the generator: what is added to the derived event for each match
+type symbolsReporter struct {
+	maxSymbols          int
+	compactSymbols      bool // Report the symbols as a single string instead of a list
+	reportInterpreter   bool
+	hasher              *symbolsHasher                 // Set only if symbols hashes are reported
+	hashOnly            bool                           // Report the hashes instead of the symbols names
+	countBoundaries     []int                          // Set only if the exported symbols count is reported
+	fingerprints        *fingerprintCache              // Set only if the exported symbols fingerprint is reported
+	constructorsCounter sharedobjs.ConstructorsCounter // Set only if constructors are reported
+	baseConstructors    int                            // The init array entries of SOs with no constructors
+	truncationDetector  sharedobjs.TruncationDetector  // Set only if symbols truncation is reported
+	passthrough         []string                       // The names of the passed through arguments of the SO loading event
+	defaultSeverity     SymbolsSeverity
+	userSeverities      map[int]SymbolsSeverity                         // Set only if severities by UID are configured
+	enrichment          func(match MatchContext) map[string]interface{} // Set only if enrichment is configured
+}
+
+// init sets up the reporting of the matches with the given configuration. The hashed symbols are the configured
+// symbols whose hashes are precomputed, if the symbols are reported by their hashes.
+func (reporter *symbolsReporter) init(soLoader sharedobjs.DynamicSymbolsLoader, config SymbolsReportingConfig,
+	hashedSymbols []string) error {
+	reporter.maxSymbols = config.MaxSymbolsPerEvent
+	reporter.compactSymbols = config.CompactSymbols
+	reporter.reportInterpreter = config.ReportInterpreter
+	if config.SymbolsHash != SymbolsHashNone {
+		reporter.hasher = newSymbolsHasher(config.SymbolsHashKey, hashedSymbols)
+		reporter.hashOnly = config.SymbolsHash == SymbolsHashOnly
+	}
+	if config.ReportSymbolsCount {
+		reporter.countBoundaries = config.SymbolsCountBoundaries
+		if len(reporter.countBoundaries) == 0 {
+			reporter.countBoundaries = DefaultSymbolsCountBoundaries
+		}
+	}
+	if config.ReportSymbolsFingerprint {
+		cacheSize := config.FingerprintCacheSize
+		if cacheSize <= 0 {
+			cacheSize = DefaultFingerprintCacheSize
+		}
+		reporter.fingerprints = newFingerprintCache(cacheSize)
+	}
+	if config.ReportConstructors {
+		counter, ok := soLoader.(sharedobjs.ConstructorsCounter)
+		if !ok {
+			return fmt.Errorf("constructors are configured, but the SO loader can't count constructors")
+		}
+		reporter.constructorsCounter = counter
+		reporter.baseConstructors = config.ConstructorsBaseline
+		if reporter.baseConstructors <= 0 {
+			reporter.baseConstructors = DefaultConstructorsBaseline
+		}
+	}
+	if config.ReportSymbolsTruncation {
+		detector, ok := soLoader.(sharedobjs.TruncationDetector)
+		if !ok {
+			return fmt.Errorf("symbols truncation is reported, but the SO loader can't detect truncation")
+		}
+		reporter.truncationDetector = detector
+	}
+	if len(config.UserSeverities) > 0 {
+		reporter.userSeverities = config.UserSeverities
+		reporter.defaultSeverity = config.DefaultSeverity
+	}
+	reporter.passthrough = append(reporter.passthrough, config.PassthroughArgs...)
+	reporter.enrichment = config.Enrichment.Enrich
+	return nil
+}
+
+// validate checks the reporting configuration for mistakes
+func (config SymbolsReportingConfig) validate() []error {
+	var problems []error
+	if config.SymbolsHash < SymbolsHashNone || config.SymbolsHash > SymbolsHashOnly {
+		problems = append(problems, fmt.Errorf("unknown symbols hash mode %d", config.SymbolsHash))
+	} else if config.SymbolsHash != SymbolsHashNone && len(config.SymbolsHashKey) < minSymbolsHashKeySize {
+		problems = append(problems, fmt.Errorf("symbols hash key should be at least %d bytes", minSymbolsHashKeySize))
+	}
+
+	problems = append(problems, validatePassthroughArgs(config)...)
+	problems = append(problems, validateUserSeverities(config)...)
+	problems = append(problems, validateSymbolsCountBoundaries(config.SymbolsCountBoundaries)...)
+	if len(config.SymbolsCountBoundaries) > 0 && !config.ReportSymbolsCount {
+		problems = append(problems, fmt.Errorf("symbols count boundaries are configured, but the count isn't reported"))
+	}
+	if config.FingerprintCacheSize < 0 {
+		problems = append(problems, fmt.Errorf("negative fingerprint cache size %d", config.FingerprintCacheSize))
+	}
+	if config.ConstructorsBaseline < 0 {
+		problems = append(problems, fmt.Errorf("negative constructors baseline %d", config.ConstructorsBaseline))
+	}
+	problems = append(problems, validateEnrichment(config.Enrichment)...)
+	if config.MaxSymbolsPerEvent < 0 {
+		problems = append(problems, fmt.Errorf("negative maximal symbols per event %d", config.MaxSymbolsPerEvent))
+	}
+	return problems
+}
+
+// readReportedProperties reads the properties of the SO of the match which are only reported
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) readReportedProperties(match *symbolsMatch) error {
+	var err error
+	if match.initArray, err = symbsLoadedGen.countConstructors(match.objInfo); err != nil {
+		return err
+	}
+	match.partial, err = symbsLoadedGen.isTruncated(match.objInfo)
+	return err
+}
+
+// hashedSymbols returns the configured symbols whose hashes may be reported: the watched symbols, imports and
+// entries, and the expected symbols
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) hashedSymbols() []string {
+	var configured []string
+	for sym := range symbsLoadedGen.watchedSymbols {
+		configured = append(configured, sym)
+	}
+	for sym := range symbsLoadedGen.librarySymbols {
+		configured = append(configured, sym)
+	}
+	for sym := range symbsLoadedGen.watchedImports {
+		configured = append(configured, sym)
+	}
+	for sym := range symbsLoadedGen.watchedTLS {
+		configured = append(configured, sym)
+	}
+	for entry := range symbsLoadedGen.watchedVersioned {
+		configured = append(configured, entry)
+	}
+	for entry := range symbsLoadedGen.watchedDemangled {
+		configured = append(configured, entry)
+	}
+	for _, expected := range symbsLoadedGen.expectedSymbols {
+		for sym := range expected {
+			configured = append(configured, sym)
+		}
+	}
+	return configured
+}
+
+// addExtraArgs adds the optional arguments of the configured features to the derived event. The arguments are
+// always added in the same order, as the consumers of the event rely on their positions.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) addExtraArgs(config symbolsLoadedSettings) error {
+	if config.Reporting.ReportVisibility {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "symbols_visibility"}, func(match *symbolsMatch) interface{} {
+			visibilities := make([]string, len(match.symbolsInfo))
+			for i, info := range match.symbolsInfo {
+				visibilities[i] = info.Visibility.String()
+			}
+			return visibilities
+		})
+	}
+	if config.Reporting.ReportSection {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "symbols_section"}, func(match *symbolsMatch) interface{} {
+			sections := make([]string, len(match.symbolsInfo))
+			for i, info := range match.symbolsInfo {
+				sections[i] = info.SectionName
+			}
+			return sections
+		})
+	}
+	if config.Reporting.ReportSymbolIndex {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "unsigned long[]", Name: "symbols_index"}, func(match *symbolsMatch) interface{} {
+			indexes := make([]uint64, len(match.symbolsInfo))
+			for i, info := range match.symbolsInfo {
+				indexes[i] = uint64(info.Index)
+			}
+			return indexes
+		})
+	}
+	if config.Reporting.ReportSymbolSize || symbsLoadedGen.expectedSizes != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "unsigned long[]", Name: "symbols_size"}, func(match *symbolsMatch) interface{} {
+			sizes := make([]uint64, len(match.symbolsInfo))
+			for i, info := range match.symbolsInfo {
+				sizes[i] = info.Size
+			}
+			return sizes
+		})
+	}
+	if symbsLoadedGen.expectedIndexes != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "unexpected_indexes"}, func(match *symbolsMatch) interface{} {
+			return match.unexpected
+		})
+	}
+
+	if config.Reporting.MaxSymbolsPerEvent > 0 {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "bool", Name: "truncated"}, func(match *symbolsMatch) interface{} {
+			return match.truncated
+		})
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "int", Name: "symbols_count"}, func(match *symbolsMatch) interface{} {
+			return match.total
+		})
+	}
+	if config.Reporting.CompactSymbols {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*", Name: "symbols_compact"}, func(match *symbolsMatch) interface{} {
+			return strings.Join(match.symbols, ",")
+		})
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "int", Name: "symbols_compact_count"}, func(match *symbolsMatch) interface{} {
+			return len(match.symbols)
+		})
+	}
+
+	if symbsLoadedGen.watchedImports != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "imported_symbols"}, func(match *symbolsMatch) interface{} {
+			return match.imports
+		})
+	}
+	if symbsLoadedGen.watchedTLS != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "tls_symbols"}, func(match *symbolsMatch) interface{} {
+			return match.tls
+		})
+	}
+	if symbsLoadedGen.watchedVersioned != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "versioned_symbols"}, func(match *symbolsMatch) interface{} {
+			return match.versioned
+		})
+	}
+	if symbsLoadedGen.watchedDemangled != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "demangled_symbols"}, func(match *symbolsMatch) interface{} {
+			return match.demangled
+		})
+	}
+	if config.Reporting.ReportPLTSlots {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "plt_slots"}, func(match *symbolsMatch) interface{} {
+			return formatPLTSlots(match.importsInfo)
+		})
+	}
+	if len(symbsLoadedGen.rules) > 0 {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "matched_rules"}, func(match *symbolsMatch) interface{} {
+			return match.rules
+		})
+	}
+	if config.Reporting.ReportInterpreter {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "bool", Name: "is_interpreter"}, func(match *symbolsMatch) interface{} {
+			return match.interpreter
+		})
+	}
+
+	if config.Tracking.ReportChanges {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "bool", Name: "changed"}, func(match *symbolsMatch) interface{} {
+			return match.changed
+		})
+	}
+	if symbsLoadedGen.loadSequences != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "u64", Name: "load_sequence"}, func(match *symbolsMatch) interface{} {
+			return match.sequence
+		})
+	}
+
+	if len(config.Detection.ExpectedSymbols) > 0 {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "missing_symbols"}, func(match *symbolsMatch) interface{} {
+			return match.missing
+		})
+	}
+	if len(config.Matching.WatchGroups) > 0 {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "matched_groups"}, func(match *symbolsMatch) interface{} {
+			return match.groups
+		})
+	}
+	if len(config.Detection.SymbolSets) > 0 {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*", Name: "symbol_set"}, func(match *symbolsMatch) interface{} {
+			return match.symbolSet
+		})
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "double", Name: "symbol_set_ratio"}, func(match *symbolsMatch) interface{} {
+			return match.setRatio
+		})
+	}
+	if symbsLoadedGen.wxDetector != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "wx_segments"}, func(match *symbolsMatch) interface{} {
+			return formatWXSegments(match.wxSegments)
+		})
+	}
+	if symbsLoadedGen.dynTagsLoader != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "dynamic_tags"}, func(match *symbolsMatch) interface{} {
+			return match.dynamicTags
+		})
+	}
+	if symbsLoadedGen.interpLoader != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*", Name: "requested_interpreter"}, func(match *symbolsMatch) interface{} {
+			return match.interp
+		})
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "bool", Name: "unusual_interpreter"}, func(match *symbolsMatch) interface{} {
+			return symbsLoadedGen.isUnusualInterpreter(match.interp)
+		})
+	}
+	if symbsLoadedGen.canonicalSymbols != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "symbols_canonical"}, func(match *symbolsMatch) interface{} {
+			return match.canonical
+		})
+	}
+	if config.Reporting.SymbolsHash == SymbolsHashAlongside {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "symbols_hmac"}, func(match *symbolsMatch) interface{} {
+			return symbsLoadedGen.hasher.hashAll(match.symbols)
+		})
+	}
+	if len(config.Matching.SuspiciousPaths) > 0 {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*", Name: "suspicious_path"}, func(match *symbolsMatch) interface{} {
+			return match.suspicious
+		})
+	}
+
+	if symbsLoadedGen.countBoundaries != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "int", Name: "exported_symbols_count"}, func(match *symbolsMatch) interface{} {
+			return match.exported
+		})
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*", Name: "exported_symbols_class"}, func(match *symbolsMatch) interface{} {
+			return symbolsCountClass(match.exported, symbsLoadedGen.countBoundaries)
+		})
+	}
+	if config.Reporting.ReportObjectID {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "dev_t", Name: "dev"}, func(match *symbolsMatch) interface{} {
+			return match.objInfo.Id.Device
+		})
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "unsigned long", Name: "inode"}, func(match *symbolsMatch) interface{} {
+			return match.objInfo.Id.Inode
+		})
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "unsigned long", Name: "ctime"}, func(match *symbolsMatch) interface{} {
+			return match.objInfo.Id.Ctime
+		})
+	}
+	if symbsLoadedGen.fingerprints != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*", Name: "symbols_fingerprint"}, func(match *symbolsMatch) interface{} {
+			return match.fingerprint
+		})
+	}
+	if symbsLoadedGen.constructorsCounter != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "int", Name: "constructors_count"}, func(match *symbolsMatch) interface{} {
+			return match.initArray
+		})
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "bool", Name: "self_executing"}, func(match *symbolsMatch) interface{} {
+			return symbsLoadedGen.isSelfExecuting(match)
+		})
+	}
+	if symbsLoadedGen.truncationDetector != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "bool", Name: "symbols_truncated"}, func(match *symbolsMatch) interface{} {
+			return match.partial
+		})
+	}
+
+	if symbsLoadedGen.entropyMeasurer != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "double", Name: "code_entropy"}, func(match *symbolsMatch) interface{} {
+			return match.entropy
+		})
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "bool", Name: "high_entropy"}, func(match *symbolsMatch) interface{} {
+			return symbsLoadedGen.isHighEntropy(match.entropy)
+		})
+	}
+	if symbsLoadedGen.relocationsCounter != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "relocation_counts"}, func(match *symbolsMatch) interface{} {
+			return match.relocCounts
+		})
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*const*", Name: "flagged_relocations"}, func(match *symbolsMatch) interface{} {
+			return match.overLimit
+		})
+	}
+	if symbsLoadedGen.metadataLoader != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "bool", Name: "metadata_only"}, func(match *symbolsMatch) interface{} {
+			return true
+		})
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*", Name: "soname"}, func(match *symbolsMatch) interface{} {
+			return match.soname
+		})
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*", Name: "build_id"}, func(match *symbolsMatch) interface{} {
+			return match.buildID
+		})
+	}
+
+	if symbsLoadedGen.userSeverities != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*", Name: "severity"}, func(match *symbolsMatch) interface{} {
+			return symbsLoadedGen.userSeverity(match.uid).Severity
+		})
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*", Name: "action"}, func(match *symbolsMatch) interface{} {
+			return symbsLoadedGen.userSeverity(match.uid).Action
+		})
+	}
+	for i, name := range symbsLoadedGen.passthrough {
+		index := i
+		symbsLoadedGen.addExtraArg(passthroughArgs[name], func(match *symbolsMatch) interface{} {
+			return match.passthrough[index]
+		})
+	}
+	if config.Reporting.ReportContainer {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*", Name: "container_id"}, func(match *symbolsMatch) interface{} {
+			return match.container.id
+		})
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "const char*", Name: "container_image"}, func(match *symbolsMatch) interface{} {
+			return match.container.image
+		})
+	}
+	if symbsLoadedGen.pending != nil {
+		symbsLoadedGen.execGrantedArg = len(symbsLoadedGen.skeleton.Params)
+		// The argument is set once the load is correlated with its executable mapping (see deriveCorrelated)
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "bool", Name: "exec_granted"}, func(match *symbolsMatch) interface{} {
+			return false
+		})
+	}
+	if symbsLoadedGen.confidenceScorer != nil {
+		symbsLoadedGen.addExtraArg(trace.ArgMeta{Type: "double", Name: "confidence"}, func(match *symbolsMatch) interface{} {
+			return match.confidence
+		})
+	}
+	return symbsLoadedGen.addEnrichmentArgs(config.Reporting.Enrichment)
+}
+
+// reportMatch makes the arguments of the event derived from the match. The symbols of the event are truncated,
+// aliased and hashed, while the summaries and the profiles record all the matched symbols.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) reportMatch(match *symbolsMatch) []interface{} {
+	reported := *match
+	reported.truncate(symbsLoadedGen.maxSymbols)
+	symbsLoadedGen.resolveAliases(&reported)
+	symbsLoadedGen.hashReported(&reported)
+	symbsLoadedGen.recordMatch(match)
+	return symbsLoadedGen.makeArgs(&reported)
+}
+
+// makeArgs create the arguments of the derived event from the match, including the configured optional arguments
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) makeArgs(match *symbolsMatch) []interface{} {
+	args := make([]interface{}, 0, 2+len(symbsLoadedGen.extraArgs))
+	symbols := match.symbols
+	if symbsLoadedGen.compactSymbols {
+		symbols = nil
+	}
+	args = append(args, match.objInfo.Path, symbols)
+	for _, extraArg := range symbsLoadedGen.extraArgs {
+		args = append(args, extraArg.value(match))
+	}
+	return args
+}
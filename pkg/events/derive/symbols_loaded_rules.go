@@ -0,0 +1,106 @@
+package derive
+
+import (
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// SymbolsRule is a behavioral signature of a SO, given as a boolean expression over its imported and exported
+// symbols (e.g. "imports dlopen AND exports __libc_start_main").
+type SymbolsRule struct {
+	Name      string
+	Predicate SymbolsPredicate
+}
+
+// SymbolsPredicate is a condition on the dynamic symbols of a SO
+type SymbolsPredicate interface {
+	Eval(exported map[string]bool, imported map[string]bool) bool
+}
+
+type exportsPredicate string
+
+func (sym exportsPredicate) Eval(exported map[string]bool, imported map[string]bool) bool {
+	return exported[string(sym)]
+}
+
+type importsPredicate string
+
+func (sym importsPredicate) Eval(exported map[string]bool, imported map[string]bool) bool {
+	return imported[string(sym)]
+}
+
+type allOfPredicate []SymbolsPredicate
+
+func (preds allOfPredicate) Eval(exported map[string]bool, imported map[string]bool) bool {
+	for _, pred := range preds {
+		if !pred.Eval(exported, imported) {
+			return false
+		}
+	}
+	return true
+}
+
+type anyOfPredicate []SymbolsPredicate
+
+func (preds anyOfPredicate) Eval(exported map[string]bool, imported map[string]bool) bool {
+	for _, pred := range preds {
+		if pred.Eval(exported, imported) {
+			return true
+		}
+	}
+	return false
+}
+
+type notPredicate struct {
+	pred SymbolsPredicate
+}
+
+func (not notPredicate) Eval(exported map[string]bool, imported map[string]bool) bool {
+	return !not.pred.Eval(exported, imported)
+}
+
+// Exports is satisfied if the SO exports the given symbol
+func Exports(symbol string) SymbolsPredicate {
+	return exportsPredicate(symbol)
+}
+
+// Imports is satisfied if the SO imports the given symbol
+func Imports(symbol string) SymbolsPredicate {
+	return importsPredicate(symbol)
+}
+
+// AllOf is satisfied if all the given predicates are satisfied
+func AllOf(preds ...SymbolsPredicate) SymbolsPredicate {
+	return allOfPredicate(preds)
+}
+
+// AnyOf is satisfied if at least one of the given predicates is satisfied
+func AnyOf(preds ...SymbolsPredicate) SymbolsPredicate {
+	return anyOfPredicate(preds)
+}
+
+// Not is satisfied if the given predicate is not satisfied
+func Not(pred SymbolsPredicate) SymbolsPredicate {
+	return notPredicate{pred: pred}
+}
+
+// matchRules returns the names of the configured rules which are satisfied by the given SO
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchRules(objInfo sharedobjs.ObjInfo) ([]string, error) {
+	if len(symbsLoadedGen.rules) == 0 {
+		return nil, nil
+	}
+	exported, err := symbsLoadedGen.soLoader.GetExportedSymbols(objInfo)
+	if err != nil {
+		return nil, err
+	}
+	imported, err := symbsLoadedGen.soLoader.GetImportedSymbols(objInfo)
+	if err != nil {
+		return nil, err
+	}
+	var matchedRules []string
+	for _, rule := range symbsLoadedGen.rules {
+		if rule.Predicate.Eval(exported, imported) {
+			matchedRules = append(matchedRules, rule.Name)
+		}
+	}
+	return matchedRules, nil
+}
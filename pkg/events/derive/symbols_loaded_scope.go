@@ -0,0 +1,94 @@
+package derive
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// SymbolsProcessScope limits the processes whose loaded SOs are examined by the symbols_loaded derivation.
+// A process is in the scope if it matches all the non-empty fields.
+type SymbolsProcessScope struct {
+	UserIDs        []int // The user IDs of the processes. If empty, processes of all users are in the scope
+	HostProcessIDs []int // The host PIDs of the processes. If empty, all processes are in the scope
+	// The IDs of the containers of the processes, or their prefixes (e.g. the short IDs). If empty, processes in all
+	// containers and on the host are in the scope
+	ContainerIDs []string
+}
+
+// processScope is the compiled form of the SymbolsProcessScope configuration
+type processScope struct {
+	userIDs        map[int]bool // Nil if all users are in the scope
+	hostProcessIDs map[int]bool // Nil if all processes are in the scope
+	containerIDs   []string
+}
+
+// newProcessScope compiles the scope configuration, and returns nil if it doesn't limit any process
+func newProcessScope(config SymbolsProcessScope) *processScope {
+	if len(config.UserIDs) == 0 && len(config.HostProcessIDs) == 0 && len(config.ContainerIDs) == 0 {
+		return nil
+	}
+	scope := &processScope{containerIDs: config.ContainerIDs}
+	if len(config.UserIDs) > 0 {
+		scope.userIDs = make(map[int]bool, len(config.UserIDs))
+		for _, uid := range config.UserIDs {
+			scope.userIDs[uid] = true
+		}
+	}
+	if len(config.HostProcessIDs) > 0 {
+		scope.hostProcessIDs = make(map[int]bool, len(config.HostProcessIDs))
+		for _, pid := range config.HostProcessIDs {
+			scope.hostProcessIDs[pid] = true
+		}
+	}
+	return scope
+}
+
+// inScope checks if the process which loaded the SO of the event is in the configured scope, using only the
+// context of the event, so SOs of processes out of the scope are never read
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) inScope(event *trace.Event) bool {
+	scope := symbsLoadedGen.scope
+	if scope == nil {
+		return true
+	}
+	if scope.userIDs != nil && !scope.userIDs[event.UserID] {
+		return false
+	}
+	if scope.hostProcessIDs != nil && !scope.hostProcessIDs[event.HostProcessID] {
+		return false
+	}
+	if len(scope.containerIDs) == 0 {
+		return true
+	}
+	if event.ContainerID == "" {
+		return false
+	}
+	for _, containerID := range scope.containerIDs {
+		if strings.HasPrefix(event.ContainerID, containerID) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateProcessScope checks the configured process scope for mistakes
+func validateProcessScope(scope SymbolsProcessScope) []error {
+	var problems []error
+	for _, uid := range scope.UserIDs {
+		if uid < 0 {
+			problems = append(problems, fmt.Errorf("process scope user ID %d is negative", uid))
+		}
+	}
+	for _, pid := range scope.HostProcessIDs {
+		if pid <= 0 {
+			problems = append(problems, fmt.Errorf("process scope PID %d should be positive", pid))
+		}
+	}
+	for _, containerID := range scope.ContainerIDs {
+		if containerID == "" {
+			problems = append(problems, fmt.Errorf("empty process scope container ID"))
+		}
+	}
+	return problems
+}
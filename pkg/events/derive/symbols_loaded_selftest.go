@@ -0,0 +1,115 @@
+package derive
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// DefaultSelfTestLibrary is the library whose symbols are extracted by the self test if no other library is
+// configured, as it is present in practically any environment
+const DefaultSelfTestLibrary = "libc.so.6"
+
+// SymbolsSelfTestResult is the health of the symbols extraction of a generator, as found by its self test
+type SymbolsSelfTestResult struct {
+	Library  string // The path of the library the extraction was tested with, if it was found
+	Readable bool   // Whether the library file could be opened by the process
+	Parsed   bool   // Whether the SO loader extracted the symbols of the library
+	Symbols  int    // The amount of symbols exported by the library, if they were extracted
+	Err      error  // The error which failed the test, if it failed
+}
+
+// Healthy checks if the self test extracted symbols from the library
+func (result SymbolsSelfTestResult) Healthy() bool {
+	return result.Err == nil
+}
+
+// SelfTest extracts the exported symbols of a known system library (see SymbolsExtractionConfig.SelfTestLibrary) with
+// the SO loader of the generator, in the environment of the current process, to confirm that the extraction works
+// before any SO is loaded. A failure indicates a misconfiguration which would otherwise go unnoticed as SOs matching
+// no symbols, e.g. running in a container with no access to the host filesystem, missing permissions or a loader
+// which can't resolve the paths of the mount namespace. Namespace aware loaders resolve the library in the mount
+// namespace of the current process. The library is added to the cache of the loader like any loaded SO.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) SelfTest() SymbolsSelfTestResult {
+	var result SymbolsSelfTestResult
+	symbsLoadedGen.closeMutex.RLock()
+	defer symbsLoadedGen.closeMutex.RUnlock()
+	if symbsLoadedGen.closed || symbsLoadedGen.soLoader == nil {
+		result.Err = fmt.Errorf("the generator is closed")
+		return result
+	}
+
+	result.Library, result.Err = symbsLoadedGen.findSelfTestLibrary()
+	if result.Err != nil {
+		return result
+	}
+	objInfo, err := selfTestObjInfo(result.Library)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to open self test library %s: %w", result.Library, err)
+		return result
+	}
+	result.Readable = true
+
+	soSyms, err := symbsLoadedGen.soLoader.GetExportedSymbols(objInfo)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to extract the symbols of self test library %s: %w", result.Library, err)
+		return result
+	}
+	result.Parsed = true
+	result.Symbols = len(soSyms)
+	if result.Symbols == 0 {
+		result.Err = fmt.Errorf("no exported symbols were extracted from self test library %s", result.Library)
+	}
+	return result
+}
+
+// findSelfTestLibrary returns the path of the self test library. Libraries configured by their file name are looked
+// up in the libraries directories, in their order.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) findSelfTestLibrary() (string, error) {
+	if path.IsAbs(symbsLoadedGen.selfTestLibrary) {
+		return symbsLoadedGen.selfTestLibrary, nil
+	}
+	librariesDirs := symbsLoadedGen.librariesDirs
+	if librariesDirs == nil {
+		librariesDirs = knownLibrariesDirs
+	}
+	for _, libsDirectory := range librariesDirs {
+		libraryPath := path.Join(libsDirectory, symbsLoadedGen.selfTestLibrary)
+		if _, err := os.Stat(libraryPath); err == nil {
+			return libraryPath, nil
+		}
+	}
+	return "", fmt.Errorf("self test library %s was not found in the libraries directories",
+		symbsLoadedGen.selfTestLibrary)
+}
+
+// selfTestObjInfo returns the information of the SO in the given path, as if it was loaded by the current process
+func selfTestObjInfo(soPath string) (sharedobjs.ObjInfo, error) {
+	file, err := os.Open(soPath)
+	if err != nil {
+		return sharedobjs.ObjInfo{}, err
+	}
+	defer file.Close()
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return sharedobjs.ObjInfo{}, err
+	}
+	objInfo := sharedobjs.ObjInfo{Path: soPath, Pid: os.Getpid()}
+	if stat, ok := fileInfo.Sys().(*syscall.Stat_t); ok {
+		objInfo.Id = sharedobjs.ObjID{
+			Inode:  stat.Ino,
+			Device: uint32(stat.Dev),
+			Ctime:  uint64(stat.Ctim.Sec)*uint64(1e9) + uint64(stat.Ctim.Nsec),
+		}
+	}
+	// The mount namespace is identified by the inode of its procfs entry, as in the events
+	if nsInfo, err := os.Stat("/proc/self/ns/mnt"); err == nil {
+		if stat, ok := nsInfo.Sys().(*syscall.Stat_t); ok {
+			objInfo.MountNS = int(stat.Ino)
+		}
+	}
+	return objInfo, nil
+}
@@ -0,0 +1,45 @@
+package derive
+
+import (
+	"sync"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// DefaultLoadOrderProcesses is the default maximal amount of processes whose SO loads sequence is kept
+const DefaultLoadOrderProcesses = 4096
+
+// loadSequences keeps the amount of SOs loaded by each process, so each loaded SO is given its sequence number in
+// the process. The sequence numbers of a process start from 1.
+// The amount of processes kept is bounded - the process which loaded a SO least recently is evicted, and its
+// sequence restarts if it loads another SO. The sequence of a process is also reset when it exits.
+// It is safe for concurrent use.
+type loadSequences struct {
+	mutex     sync.Mutex
+	processes *simplelru.LRU // pid -> the sequence number of the last SO loaded by the process
+}
+
+func newLoadSequences(size int) *loadSequences {
+	processes, _ := simplelru.NewLRU(size, nil)
+	return &loadSequences{processes: processes}
+}
+
+// next returns the sequence number of a SO loaded by the process
+func (sequences *loadSequences) next(pid int) uint64 {
+	sequences.mutex.Lock()
+	defer sequences.mutex.Unlock()
+	var sequence uint64
+	if last, ok := sequences.processes.Get(pid); ok {
+		sequence = last.(uint64)
+	}
+	sequence++
+	sequences.processes.Add(pid, sequence)
+	return sequence
+}
+
+// forgetProcess resets the sequence of the process
+func (sequences *loadSequences) forgetProcess(pid int) {
+	sequences.mutex.Lock()
+	defer sequences.mutex.Unlock()
+	sequences.processes.Remove(pid)
+}
@@ -0,0 +1,45 @@
+package derive
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SymbolsSeverity is the severity and action which the symbols_loaded events of the processes of a user are
+// annotated with. Both are opaque to the derivation, and are interpreted by the consumers of the events.
+type SymbolsSeverity struct {
+	Severity string // e.g. "high" for root processes
+	Action   string // e.g. "page" or "ticket", or empty if the user has no specific action
+}
+
+// userSeverity returns the severity which the events of the processes of the given UID are annotated with
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) userSeverity(uid int) SymbolsSeverity {
+	if severity, ok := symbsLoadedGen.userSeverities[uid]; ok {
+		return severity
+	}
+	return symbsLoadedGen.defaultSeverity
+}
+
+// validateUserSeverities checks the severities by UID for mistakes. The UIDs are checked in order, so the problems
+// are reported in the same order.
+func validateUserSeverities(config SymbolsReportingConfig) []error {
+	var problems []error
+	uids := make([]int, 0, len(config.UserSeverities))
+	for uid := range config.UserSeverities {
+		uids = append(uids, uid)
+	}
+	sort.Ints(uids)
+	for _, uid := range uids {
+		severity := config.UserSeverities[uid]
+		if uid < 0 {
+			problems = append(problems, fmt.Errorf("severity user ID %d is negative", uid))
+		}
+		if severity.Severity == "" {
+			problems = append(problems, fmt.Errorf("severity of user ID %d is empty", uid))
+		}
+	}
+	if len(config.UserSeverities) == 0 && config.DefaultSeverity != (SymbolsSeverity{}) {
+		problems = append(problems, fmt.Errorf("default severity is configured with no severities by user ID"))
+	}
+	return problems
+}
@@ -0,0 +1,49 @@
+package derive
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// SymbolSizeRange is the expected range of the size of a watched symbol (see sharedobjs.SymbolInfo.Size), inclusive
+type SymbolSizeRange struct {
+	Min uint64
+	Max uint64 // If 0, the size has no upper bound
+}
+
+// contains checks if the size is within the range
+func (sizeRange SymbolSizeRange) contains(size uint64) bool {
+	return size >= sizeRange.Min && (sizeRange.Max == 0 || size <= sizeRange.Max)
+}
+
+// isUnexpectedSize checks if a watched symbol should be matched by its size: symbols with an expected size range are
+// matched only if their size is out of the range, or is 0, and symbols with no expected range regardless of their size
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) isUnexpectedSize(sym string, info sharedobjs.SymbolInfo) bool {
+	sizeRange, ranged := symbsLoadedGen.expectedSizes[sym]
+	if !ranged {
+		return true
+	}
+	return info.Size == 0 || !sizeRange.contains(info.Size)
+}
+
+// validateSymbolSizes checks the expected size ranges of symbols for mistakes
+func validateSymbolSizes(sizes map[string]SymbolSizeRange) []error {
+	symbols := make([]string, 0, len(sizes))
+	for sym := range sizes {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+	var problems []error
+	for _, sym := range symbols {
+		if sym == "" {
+			problems = append(problems, fmt.Errorf("expected size range of an empty symbol"))
+		}
+		if sizeRange := sizes[sym]; sizeRange.Max != 0 && sizeRange.Max < sizeRange.Min {
+			problems = append(problems, fmt.Errorf("expected size range %d-%d of symbol '%s' is empty",
+				sizeRange.Min, sizeRange.Max, sym))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,136 @@
+package derive
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/aquasecurity/tracee/pkg/events"
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+	"github.com/aquasecurity/tracee/types/trace"
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+const (
+	// DefaultMaxTrackedSonames is the default maximal amount of sonames whose paths are remembered
+	DefaultMaxTrackedSonames = 4096
+	// DefaultMaxSonamePaths is the default maximal amount of paths remembered for each soname
+	DefaultMaxSonamePaths = 16
+)
+
+// sonamePathsHistory remembers the paths which each soname was seen loaded from. It is kept in memory only, so the
+// paths are seen again after a restart. The paths are compared after resolving their symbolic links, so aliases of
+// the same directory (e.g. /lib linked to /usr/lib) are the same path.
+// The history is bounded - the sonames seen least recently are forgotten, and so are the paths added first to a
+// soname with too many paths, so a forgotten path is reported again if it is seen again. It is safe for concurrent
+// use.
+type sonamePathsHistory struct {
+	mutex    sync.Mutex
+	paths    *simplelru.LRU // soname -> the paths seen with the soname, in the order they were first seen
+	maxPaths int
+	resolve  func(path string) (string, error) // Resolves the symbolic links of a path
+}
+
+func newSonamePathsHistory(maxSonames int, maxPaths int) *sonamePathsHistory {
+	paths, _ := simplelru.NewLRU(maxSonames, nil)
+	return &sonamePathsHistory{paths: paths, maxPaths: maxPaths, resolve: filepath.EvalSymlinks}
+}
+
+// canonicalPath returns the path with its symbolic links resolved, or only cleaned if they can't be resolved (e.g.
+// the path doesn't exist anymore)
+func (history *sonamePathsHistory) canonicalPath(soPath string) string {
+	cleaned := filepath.Clean(soPath)
+	if resolved, err := history.resolve(cleaned); err == nil {
+		return resolved
+	}
+	return cleaned
+}
+
+// observe records that the soname was seen loaded from the given path, and returns the paths it was seen loaded
+// from before if the path is new to it. A soname seen for the first time has no previous paths, and is not
+// considered to be relocated.
+func (history *sonamePathsHistory) observe(soname string, soPath string) []string {
+	// The links are resolved before locking, as resolving them reads the filesystem
+	soPath = history.canonicalPath(soPath)
+	history.mutex.Lock()
+	defer history.mutex.Unlock()
+	var seen []string
+	if value, ok := history.paths.Get(soname); ok {
+		seen = value.([]string)
+	}
+	for _, seenPath := range seen {
+		if seenPath == soPath {
+			return nil
+		}
+	}
+	// The seen paths are never modified, so they are returned as they are
+	updated := append(append(make([]string, 0, len(seen)+1), seen...), soPath)
+	if len(updated) > history.maxPaths {
+		updated = updated[len(updated)-history.maxPaths:]
+	}
+	history.paths.Add(soname, updated)
+	return seen
+}
+
+// SonamePathChanged receives the generator of the symbols_loaded event as a closure argument.
+// If it receives a shared_object_loaded event of a SO exporting watched symbols whose soname was seen loaded from
+// other paths before, but not from its path, it derives a soname_path_changed event from it, as a library appearing
+// from a new path may be a tampered or preloaded copy of it. The event is derived for SOs exporting no watched symbols
+// too if SymbolsTrackingConfig.ReportAllSonamePaths is configured.
+func SonamePathChanged(gen *SymbolsLoadedEventGenerator) events.DeriveFunction {
+	return singleSkeletonDeriveFunc(makeTypedEventSkeleton(events.SonamePathChanged),
+		gen.withDeadline(gen.deriveSonamePathArgs))
+}
+
+// deriveSonamePathArgs derive the arguments of the soname_path_changed event, if the loaded SO has a soname which
+// was seen loaded from other paths, but not from the path of the SO. The paths of whitelisted SOs and of SOs exporting
+// no watched symbols are recorded, so their copies in other paths are detected, but they don't derive the event
+// themselves.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deriveSonamePathArgs(event trace.Event) ([]interface{}, error) {
+	if !symbsLoadedGen.acquire() {
+		return nil, nil
+	}
+	defer symbsLoadedGen.release()
+
+	if symbsLoadedGen.sonamePaths == nil || !symbsLoadedGen.inScope(&event) {
+		return nil, nil
+	}
+	loadingObjectInfo, err := getSharedObjectInfo(event)
+	if err != nil {
+		return nil, err
+	}
+
+	// Errors are reported by the symbols_loaded event derivation
+	soname, err := symbsLoadedGen.sonameLoader.GetSoname(loadingObjectInfo)
+	if err != nil || soname == "" {
+		return nil, nil
+	}
+	previous := symbsLoadedGen.sonamePaths.observe(soname, loadingObjectInfo.Path)
+	if len(previous) == 0 {
+		return nil, nil
+	}
+	if symbsLoadedGen.suspiciousDir(loadingObjectInfo.Path) == "" && symbsLoadedGen.isIgnored(loadingObjectInfo.Path) {
+		return nil, nil
+	}
+	if !symbsLoadedGen.reportAllPaths && !symbsLoadedGen.exportsWatchedSymbols(loadingObjectInfo) {
+		return nil, nil
+	}
+	symbsLoadedGen.log(LogLevelWarn, DecisionRelocated, loadingObjectInfo,
+		fmt.Sprintf("soname: %s, previous paths: %v", soname, previous))
+	return []interface{}{loadingObjectInfo.Path, soname, previous}, nil
+}
+
+// exportsWatchedSymbols checks if the SO exports any of the watched symbols. SOs whose symbols can't be loaded are
+// considered as exporting none, as their errors are reported by the symbols_loaded event derivation.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) exportsWatchedSymbols(objInfo sharedobjs.ObjInfo) bool {
+	soSyms, err := symbsLoadedGen.soLoader.GetExportedSymbols(objInfo)
+	if err != nil {
+		return false
+	}
+	for sym := range soSyms {
+		if symbsLoadedGen.isWatched(sym, objInfo.Path) {
+			return true
+		}
+	}
+	return false
+}
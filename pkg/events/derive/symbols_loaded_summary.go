@@ -0,0 +1,172 @@
+package derive
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aquasecurity/tracee/pkg/events"
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// SummaryKey configures by what the matches of the symbols_loaded_summary event are aggregated
+type SummaryKey int
+
+const (
+	// SummaryBySymbol aggregates the processes which loaded each watched symbol
+	SummaryBySymbol SummaryKey = iota
+	// SummaryByProcess aggregates the watched symbols loaded by each process
+	SummaryByProcess
+)
+
+func (key SummaryKey) String() string {
+	switch key {
+	case SummaryBySymbol:
+		return "symbol"
+	case SummaryByProcess:
+		return "process"
+	}
+	return fmt.Sprintf("unknown(%d)", int(key))
+}
+
+const (
+	// DefaultSummaryInterval is the default interval of emitting the symbols_loaded_summary event
+	DefaultSummaryInterval = 5 * time.Minute
+	// DefaultMaxSummaryEntries is the default maximal amount of (key, value) pairs kept for a summary
+	DefaultMaxSummaryEntries = 1024
+)
+
+// summaryEventsBuffer is the amount of summary events kept until they are read
+const summaryEventsBuffer = 16
+
+// symbolsSummary accumulates the matches of the symbols_loaded event between emissions of the summary event.
+// The amount of (key, value) pairs it keeps is bounded, and pairs beyond the bound are dropped until the next emission.
+// It is safe for concurrent use.
+type symbolsSummary struct {
+	mutex      sync.Mutex
+	key        SummaryKey
+	maxEntries int
+	entries    map[string]map[string]bool
+	size       int // The amount of (key, value) pairs in the entries
+	loads      int
+	truncated  bool
+}
+
+func newSymbolsSummary(key SummaryKey, maxEntries int) *symbolsSummary {
+	return &symbolsSummary{key: key, maxEntries: maxEntries, entries: make(map[string]map[string]bool)}
+}
+
+// record adds the watched symbols loaded by the process to the summary.
+// The symbols are added in alphabetical order, so the same pairs are kept when the summary bound is exceeded.
+func (summary *symbolsSummary) record(pid int, symbols []string) {
+	sorted := make([]string, len(symbols))
+	copy(sorted, symbols)
+	sort.Strings(sorted)
+	summary.mutex.Lock()
+	defer summary.mutex.Unlock()
+	summary.loads++
+	process := strconv.Itoa(pid)
+	for _, sym := range sorted {
+		key, value := sym, process
+		if summary.key == SummaryByProcess {
+			key, value = process, sym
+		}
+		if summary.entries[key][value] {
+			continue
+		}
+		if summary.size >= summary.maxEntries {
+			summary.truncated = true
+			continue
+		}
+		if summary.entries[key] == nil {
+			summary.entries[key] = make(map[string]bool)
+		}
+		summary.entries[key][value] = true
+		summary.size++
+	}
+}
+
+// flush returns the arguments of the summary event of the matches recorded since the last flush, and starts
+// a new summary. If no match was recorded, nil is returned.
+func (summary *symbolsSummary) flush() []interface{} {
+	summary.mutex.Lock()
+	entries, loads, truncated := summary.entries, summary.loads, summary.truncated
+	summary.entries = make(map[string]map[string]bool)
+	summary.size, summary.loads, summary.truncated = 0, 0, false
+	summary.mutex.Unlock()
+
+	if loads == 0 {
+		return nil
+	}
+	formatted := make([]string, 0, len(entries))
+	for key, values := range entries {
+		sortedValues := make([]string, 0, len(values))
+		for value := range values {
+			sortedValues = append(sortedValues, value)
+		}
+		sort.Strings(sortedValues)
+		formatted = append(formatted, fmt.Sprintf("%s: %s", key, strings.Join(sortedValues, ",")))
+	}
+	sort.Strings(formatted)
+	return []interface{}{summary.key.String(), formatted, loads, truncated}
+}
+
+// Summaries returns the channel of the symbols_loaded_summary events, or nil if summaries are not configured.
+// The events are emitted periodically rather than derived from other events, so they should be merged into the
+// events stream by the caller. If the events are not read fast enough, summaries are dropped.
+// The channel is closed when the generator is closed.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) Summaries() <-chan trace.Event {
+	if symbsLoadedGen.summary == nil {
+		return nil
+	}
+	return symbsLoadedGen.summaryEvents
+}
+
+// startSummaries starts emitting the summary events in the given interval, until the generator is closed
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) startSummaries(interval time.Duration) {
+	symbsLoadedGen.summaryEvents = make(chan trace.Event, summaryEventsBuffer)
+	symbsLoadedGen.summaryDone = make(chan struct{})
+	symbsLoadedGen.summaryWG.Add(1)
+	go func() {
+		defer symbsLoadedGen.summaryWG.Done()
+		defer close(symbsLoadedGen.summaryEvents)
+		for {
+			select {
+			case now := <-symbsLoadedGen.clock.After(interval):
+				symbsLoadedGen.emitSummary(now)
+			case <-symbsLoadedGen.summaryDone:
+				return
+			}
+		}
+	}()
+}
+
+// emitSummary sends the summary event of the matches since the last emission, if there were any
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) emitSummary(now time.Time) {
+	args := symbsLoadedGen.summary.flush()
+	if args == nil {
+		return
+	}
+	skeleton := makeTypedEventSkeleton(events.SymbolsLoadedSummary)
+	summaryEvent, err := newEvent(&trace.Event{Timestamp: int(now.UnixNano())}, skeleton, args)
+	if err != nil {
+		return
+	}
+	// The emission doesn't block, so a reader which stopped reading doesn't stop the generator from closing
+	select {
+	case symbsLoadedGen.summaryEvents <- summaryEvent:
+	default:
+	}
+}
+
+// stopSummaries stops emitting the summary events, and waits for the emission in progress to complete
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) stopSummaries() {
+	if symbsLoadedGen.summaryDone == nil {
+		return
+	}
+	close(symbsLoadedGen.summaryDone)
+	symbsLoadedGen.summaryWG.Wait()
+}
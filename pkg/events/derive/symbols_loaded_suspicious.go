@@ -0,0 +1,54 @@
+package derive
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// homeDirsEntry is the suspicious paths entry which stands for the home directories of the users
+const homeDirsEntry = "$HOME"
+
+// homeDirs are the directories which the homeDirsEntry is expanded to. The home directory of the process loading the
+// SO is not known to the derivation, so all the home directories are suspicious.
+var homeDirs = []string{"/root", "/home"}
+
+// DefaultSuspiciousPaths are the world-writable and user owned directories which legitimate SOs are rarely loaded
+// from
+var DefaultSuspiciousPaths = []string{"/tmp", "/dev/shm", homeDirsEntry}
+
+// newSuspiciousDirs expands and cleans the configured suspicious paths
+func newSuspiciousDirs(entries []string) []string {
+	var dirs []string
+	for _, entry := range entries {
+		if entry == homeDirsEntry {
+			dirs = append(dirs, homeDirs...)
+			continue
+		}
+		dirs = append(dirs, path.Clean(entry))
+	}
+	return dirs
+}
+
+// suspiciousDir returns the configured suspicious directory which the SO path is under, or an empty string if it is
+// under none of them
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) suspiciousDir(soPath string) string {
+	for _, dir := range symbsLoadedGen.suspiciousDirs {
+		if strings.HasPrefix(soPath, dir) && (len(soPath) == len(dir) || soPath[len(dir)] == '/' || dir == "/") {
+			return dir
+		}
+	}
+	return ""
+}
+
+// validateSuspiciousPaths checks the configured suspicious paths for mistakes
+func validateSuspiciousPaths(entries []string) []error {
+	var problems []error
+	for _, entry := range entries {
+		if entry != homeDirsEntry && !path.IsAbs(entry) {
+			problems = append(problems, fmt.Errorf("suspicious path '%s' should be an absolute path or %s",
+				entry, homeDirsEntry))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,123 @@
+package derive
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// DefaultSymbolSetRatio is the default part of the symbols of a symbol set which a SO should export to match it
+const DefaultSymbolSetRatio = 0.8
+
+// SymbolSet is a named set of symbols characteristic of a library (e.g. 20 symbols which only libc exports), which
+// recognizes copies of the library by their exports, regardless of their names. A SO matches the set if it exports
+// at least MinRatio of its symbols, unless its file name is a name of the library - so the library shipped under an
+// innocuous name is flagged as disguised.
+type SymbolSet struct {
+	Name    string
+	Symbols []string // Full symbol names
+	// The part of the symbols which the SO should export to match the set, above 0 and up to 1. If 0,
+	// DefaultSymbolSetRatio is used.
+	MinRatio float64
+	// Prefixes of the file names of the genuine library (e.g. "libc.so" and "libc-"), whose SOs don't match the set
+	LibraryNames []string
+}
+
+// symbolSet is a SymbolSet prepared for matching
+type symbolSet struct {
+	name         string
+	symbols      map[string]bool
+	minRatio     float64
+	libraryNames []string
+}
+
+// newSymbolSets converts the configured symbol sets to sets, keeping their order
+func newSymbolSets(config []SymbolSet) []symbolSet {
+	sets := make([]symbolSet, 0, len(config))
+	for _, set := range config {
+		symbols := make(map[string]bool, len(set.Symbols))
+		for _, sym := range set.Symbols {
+			symbols[sym] = true
+		}
+		minRatio := set.MinRatio
+		if minRatio == 0 {
+			minRatio = DefaultSymbolSetRatio
+		}
+		sets = append(sets, symbolSet{name: set.Name, symbols: symbols, minRatio: minRatio,
+			libraryNames: set.LibraryNames})
+	}
+	return sets
+}
+
+// matchSymbolSets returns the name of the symbol set which the SO matches with the highest ratio (the earliest
+// configured one among equal ratios), and the part of its symbols which the SO exports. An empty name is returned if
+// the SO matches no set.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchSymbolSets(objInfo sharedobjs.ObjInfo) (string, float64, error) {
+	if len(symbsLoadedGen.symbolSets) == 0 {
+		return "", 0, nil
+	}
+	soSyms, err := symbsLoadedGen.soLoader.GetExportedSymbols(objInfo)
+	if err != nil {
+		return "", 0, err
+	}
+	fileName := path.Base(objInfo.Path)
+	var matched string
+	var matchedRatio float64
+	for _, set := range symbsLoadedGen.symbolSets {
+		if set.isLibraryName(fileName) {
+			continue
+		}
+		ratio := float64(countExported(soSyms, set.symbols)) / float64(len(set.symbols))
+		if ratio >= set.minRatio && ratio > matchedRatio {
+			matched, matchedRatio = set.name, ratio
+		}
+	}
+	return matched, matchedRatio, nil
+}
+
+// isLibraryName checks if the file name is a name of the genuine library of the set
+func (set *symbolSet) isLibraryName(fileName string) bool {
+	for _, name := range set.libraryNames {
+		if strings.HasPrefix(fileName, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSymbolSets checks the symbol sets for mistakes
+func validateSymbolSets(sets []SymbolSet) []error {
+	var problems []error
+	names := make(map[string]bool, len(sets))
+	for _, set := range sets {
+		if set.Name == "" {
+			problems = append(problems, fmt.Errorf("symbol set with no name"))
+		} else if names[set.Name] {
+			problems = append(problems, fmt.Errorf("symbol set '%s' is defined more than once", set.Name))
+		}
+		names[set.Name] = true
+		if len(set.Symbols) == 0 {
+			problems = append(problems, fmt.Errorf("symbol set '%s' has no symbols", set.Name))
+		}
+		for _, sym := range set.Symbols {
+			if sym == "" {
+				problems = append(problems, fmt.Errorf("empty symbol entry of symbol set '%s'", set.Name))
+			} else if strings.HasSuffix(sym, prefixWildcard) || strings.Contains(sym, librarySymbolSeparator) {
+				problems = append(problems, fmt.Errorf("symbol set '%s' symbol '%s' should be a full symbol name", set.Name, sym))
+			}
+		}
+		if set.MinRatio < 0 || set.MinRatio > 1 {
+			problems = append(problems, fmt.Errorf("symbol set '%s' minimal ratio %v is not between 0 and 1",
+				set.Name, set.MinRatio))
+		}
+		for _, name := range set.LibraryNames {
+			if name == "" || strings.Contains(name, "/") {
+				problems = append(problems, fmt.Errorf("symbol set '%s' library name '%s' should be a file name prefix",
+					set.Name, name))
+			}
+		}
+	}
+	return problems
+}
@@ -1,45 +1,291 @@
 package derive
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/aquasecurity/tracee/pkg/events"
 	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
 	"github.com/aquasecurity/tracee/types/trace"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
 )
 
 type soInstance struct {
-	info sharedobjs.ObjInfo
-	syms []string
+	info        sharedobjs.ObjInfo
+	syms        []string
+	symsInfo    []sharedobjs.SymbolInfo // Information of symbols, for symbols with non-default information
+	importsSyms []string
+	importsInfo []sharedobjs.ImportedSymbolInfo // Information of imported symbols, for imports with PLT slots
+	loadErr     error                           // Error returned by the loader for the SO
+	packer      string                          // The packer which packed the SO
+	extraction  time.Duration                   // The time it took to extract the SO symbols
+	interpreter bool                            // Whether the SO is the dynamic loader
+	soname      string                          // The DT_SONAME of the SO
+	buildID     string                          // The GNU build ID of the SO
+	notes       []sharedobjs.NoteID             // The ELF notes the SO carries
+	wxSegments  []sharedobjs.Segment            // The segments of the SO which are writable and executable
+	dynamicTags []elf.DynTag                    // The tags of the dynamic section of the SO
+	initArray   int                             // The amount of init array entries of the SO
+	interp      string                          // The interpreter the SO requests (PT_INTERP)
+	truncated   bool                            // Whether only the first symbols of the SO were read
+	entropy     float64                         // The entropy of the code of the SO
+	relocations map[string]int                  // The amount of dynamic relocations of each type of the SO
 }
 
 type symbolsLoaderMock struct {
-	cache map[sharedobjs.ObjInfo]map[string]bool
+	cache        map[sharedobjs.ObjID]map[string]bool
+	infoCache    map[sharedobjs.ObjID]map[string]sharedobjs.SymbolInfo
+	importsCache map[sharedobjs.ObjID]map[string]bool
+	importsInfo  map[sharedobjs.ObjID]map[string]sharedobjs.ImportedSymbolInfo
+	errs         map[sharedobjs.ObjID]error
+	packers      map[sharedobjs.ObjID]string
+	extractions  map[sharedobjs.ObjID]time.Duration
+	taken        map[sharedobjs.ObjID]bool
+	interpreters map[sharedobjs.ObjID]bool
+	sonames      map[sharedobjs.ObjID]string
+	buildIDs     map[sharedobjs.ObjID]string
+	notes        map[sharedobjs.ObjID][]sharedobjs.NoteID
+	wxSegments   map[sharedobjs.ObjID][]sharedobjs.Segment
+	dynamicTags  map[sharedobjs.ObjID][]elf.DynTag
+	initArrays   map[sharedobjs.ObjID]int
+	interps      map[sharedobjs.ObjID]string
+	truncated    map[sharedobjs.ObjID]bool
+	entropies    map[sharedobjs.ObjID]float64
+	relocations  map[sharedobjs.ObjID]map[string]int
 }
 
 func initLoaderMock() symbolsLoaderMock {
-	return symbolsLoaderMock{cache: make(map[sharedobjs.ObjInfo]map[string]bool)}
+	return symbolsLoaderMock{
+		cache:        make(map[sharedobjs.ObjID]map[string]bool),
+		infoCache:    make(map[sharedobjs.ObjID]map[string]sharedobjs.SymbolInfo),
+		importsCache: make(map[sharedobjs.ObjID]map[string]bool),
+		importsInfo:  make(map[sharedobjs.ObjID]map[string]sharedobjs.ImportedSymbolInfo),
+		errs:         make(map[sharedobjs.ObjID]error),
+		packers:      make(map[sharedobjs.ObjID]string),
+		extractions:  make(map[sharedobjs.ObjID]time.Duration),
+		taken:        make(map[sharedobjs.ObjID]bool),
+		interpreters: make(map[sharedobjs.ObjID]bool),
+		sonames:      make(map[sharedobjs.ObjID]string),
+		buildIDs:     make(map[sharedobjs.ObjID]string),
+		notes:        make(map[sharedobjs.ObjID][]sharedobjs.NoteID),
+		wxSegments:   make(map[sharedobjs.ObjID][]sharedobjs.Segment),
+		dynamicTags:  make(map[sharedobjs.ObjID][]elf.DynTag),
+		initArrays:   make(map[sharedobjs.ObjID]int),
+		interps:      make(map[sharedobjs.ObjID]string),
+		truncated:    make(map[sharedobjs.ObjID]bool),
+		entropies:    make(map[sharedobjs.ObjID]float64),
+		relocations:  make(map[sharedobjs.ObjID]map[string]int),
+	}
 }
 
 func (loader symbolsLoaderMock) GetDynamicSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
-	return loader.cache[info], nil
+	return loader.cache[info.Id], nil
 }
 
 func (loader symbolsLoaderMock) GetExportedSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
-	return loader.cache[info], nil
+	if err := loader.errs[info.Id]; err != nil {
+		return nil, err
+	}
+	return loader.cache[info.Id], nil
 }
 
 func (loader symbolsLoaderMock) GetImportedSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
-	return nil, nil
+	if err := loader.errs[info.Id]; err != nil {
+		return nil, err
+	}
+	return loader.importsCache[info.Id], nil
+}
+
+func (loader symbolsLoaderMock) GetExportedSymbolsInfo(info sharedobjs.ObjInfo) (map[string]sharedobjs.SymbolInfo, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return nil, err
+	}
+	return loader.infoCache[info.Id], nil
+}
+
+func (loader symbolsLoaderMock) GetImportedSymbolsInfo(info sharedobjs.ObjInfo) (map[string]sharedobjs.ImportedSymbolInfo, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return nil, err
+	}
+	return loader.importsInfo[info.Id], nil
+}
+
+func (loader symbolsLoaderMock) GetPacker(info sharedobjs.ObjInfo) (string, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return "", err
+	}
+	return loader.packers[info.Id], nil
+}
+
+func (loader symbolsLoaderMock) GetExtractionDuration(info sharedobjs.ObjInfo) (time.Duration, bool, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return 0, false, err
+	}
+	first := !loader.taken[info.Id]
+	loader.taken[info.Id] = true
+	return loader.extractions[info.Id], first, nil
+}
+
+func (loader symbolsLoaderMock) IsInterpreter(info sharedobjs.ObjInfo) (bool, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return false, err
+	}
+	return loader.interpreters[info.Id], nil
+}
+
+func (loader symbolsLoaderMock) GetSoname(info sharedobjs.ObjInfo) (string, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return "", err
+	}
+	return loader.sonames[info.Id], nil
+}
+
+func (loader symbolsLoaderMock) GetBuildID(info sharedobjs.ObjInfo) (string, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return "", err
+	}
+	return loader.buildIDs[info.Id], nil
+}
+
+func (loader symbolsLoaderMock) HasNote(info sharedobjs.ObjInfo, note sharedobjs.NoteID) (bool, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return false, err
+	}
+	for _, soNote := range loader.notes[info.Id] {
+		if soNote == note {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (loader symbolsLoaderMock) GetWritableCodeSegments(info sharedobjs.ObjInfo) ([]sharedobjs.Segment, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return nil, err
+	}
+	return loader.wxSegments[info.Id], nil
+}
+
+func (loader symbolsLoaderMock) GetDynamicTags(info sharedobjs.ObjInfo) ([]elf.DynTag, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return nil, err
+	}
+	return loader.dynamicTags[info.Id], nil
+}
+
+func (loader symbolsLoaderMock) GetInterpreter(info sharedobjs.ObjInfo) (string, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return "", err
+	}
+	return loader.interps[info.Id], nil
+}
+
+func (loader symbolsLoaderMock) IsTruncated(info sharedobjs.ObjInfo) (bool, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return false, err
+	}
+	return loader.truncated[info.Id], nil
+}
+
+func (loader symbolsLoaderMock) GetCodeEntropy(info sharedobjs.ObjInfo) (float64, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return 0, err
+	}
+	return loader.entropies[info.Id], nil
+}
+
+func (loader symbolsLoaderMock) GetRelocationCounts(info sharedobjs.ObjInfo) (map[string]int, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return nil, err
+	}
+	return loader.relocations[info.Id], nil
+}
+
+func (loader symbolsLoaderMock) GetMetadata(info sharedobjs.ObjInfo) (sharedobjs.ObjMetadata, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return sharedobjs.ObjMetadata{}, err
+	}
+	notes := make(map[sharedobjs.NoteID]bool)
+	for _, note := range loader.notes[info.Id] {
+		notes[note] = true
+	}
+	return sharedobjs.ObjMetadata{
+		Soname:       loader.sonames[info.Id],
+		BuildID:      loader.buildIDs[info.Id],
+		Notes:        notes,
+		Interpreter:  loader.interpreters[info.Id],
+		WXSegments:   loader.wxSegments[info.Id],
+		DynamicTags:  loader.dynamicTags[info.Id],
+		Constructors: loader.initArrays[info.Id],
+		Interp:       loader.interps[info.Id],
+	}, nil
+}
+
+func (loader symbolsLoaderMock) GetConstructorsCount(info sharedobjs.ObjInfo) (int, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return 0, err
+	}
+	return loader.initArrays[info.Id], nil
 }
 
 func (loader symbolsLoaderMock) addSOSymbols(info soInstance) {
 	symsMap := make(map[string]bool)
+	symsInfoMap := make(map[string]sharedobjs.SymbolInfo)
 	for _, s := range info.syms {
 		symsMap[s] = true
+		symsInfoMap[s] = sharedobjs.SymbolInfo{Name: s, Bind: elf.STB_GLOBAL, Type: elf.STT_FUNC, Visibility: elf.STV_DEFAULT}
+	}
+	for _, symInfo := range info.symsInfo {
+		symsMap[symInfo.Name] = true
+		symsInfoMap[symInfo.Name] = symInfo
+	}
+	importsMap := make(map[string]bool)
+	importsInfoMap := make(map[string]sharedobjs.ImportedSymbolInfo)
+	for _, s := range info.importsSyms {
+		importsMap[s] = true
+		importsInfoMap[s] = sharedobjs.ImportedSymbolInfo{Name: s}
 	}
-	loader.cache[info.info] = symsMap
+	for _, importInfo := range info.importsInfo {
+		importsMap[importInfo.Name] = true
+		importsInfoMap[importInfo.Name] = importInfo
+	}
+	loader.cache[info.info.Id] = symsMap
+	loader.infoCache[info.info.Id] = symsInfoMap
+	loader.importsCache[info.info.Id] = importsMap
+	loader.importsInfo[info.info.Id] = importsInfoMap
+	if info.loadErr != nil {
+		loader.errs[info.info.Id] = info.loadErr
+	}
+	loader.packers[info.info.Id] = info.packer
+	loader.extractions[info.info.Id] = info.extraction
+	loader.interpreters[info.info.Id] = info.interpreter
+	loader.sonames[info.info.Id] = info.soname
+	loader.buildIDs[info.info.Id] = info.buildID
+	loader.notes[info.info.Id] = info.notes
+	loader.wxSegments[info.info.Id] = info.wxSegments
+	loader.dynamicTags[info.info.Id] = info.dynamicTags
+	loader.initArrays[info.info.Id] = info.initArray
+	loader.interps[info.info.Id] = info.interp
+	loader.truncated[info.info.Id] = info.truncated
+	loader.entropies[info.info.Id] = info.entropy
+	loader.relocations[info.info.Id] = info.relocations
 }
 
 func generateSOLoadedEvent(pid int, so sharedobjs.ObjInfo) trace.Event {
@@ -50,7 +296,7 @@ func generateSOLoadedEvent(pid int, so sharedobjs.ObjInfo) trace.Event {
 		ProcessID:     pid,
 		Args: []trace.Argument{
 			{ArgMeta: trace.ArgMeta{Type: "const char*", Name: "pathname"}, Value: so.Path},
-			{ArgMeta: trace.ArgMeta{Type: "int", Name: "flags"}, Value: 0},
+			{ArgMeta: trace.ArgMeta{Type: "int", Name: "flags"}, Value: int32(0)},
 			{ArgMeta: trace.ArgMeta{Type: "dev_t", Name: "dev"}, Value: so.Id.Device},
 			{ArgMeta: trace.ArgMeta{Type: "unsigned long", Name: "inode"}, Value: so.Id.Inode},
 			{ArgMeta: trace.ArgMeta{Type: "unsigned long", Name: "ctime"}, Value: so.Id.Ctime},
@@ -156,7 +402,11 @@ func TestDeriveSharedObjectExportWatchedSymbols(t *testing.T) {
 			t.Run(testCase.name, func(t *testing.T) {
 				mockLoader := initLoaderMock()
 				mockLoader.addSOSymbols(testCase.loadingSO)
-				gen := initSymbolsLoadedEventGenerator(mockLoader, testCase.watchedSymbols, testCase.whitelistedLibs)
+				gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+					WatchedSymbols:  testCase.watchedSymbols,
+					WhitelistedLibs: testCase.whitelistedLibs,
+				})
+				require.NoError(t, err)
 				eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(pid, testCase.loadingSO.info))
 				require.NoError(t, err)
 				if len(testCase.expectedSymbols) > 0 {
@@ -174,3 +424,6121 @@ func TestDeriveSharedObjectExportWatchedSymbols(t *testing.T) {
 		}
 	})
 }
+
+func TestDeriveSharedObjectAllowlistMode(t *testing.T) {
+	testCases := []struct {
+		name            string
+		loadingSO       soInstance
+		expectedDerived bool
+	}{
+		{
+			name: "Allowlisted full path SO",
+			loadingSO: soInstance{
+				info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/test.so"},
+				syms: []string{"open"},
+			},
+			expectedDerived: true,
+		},
+		{
+			name: "Allowlisted library SO",
+			loadingSO: soInstance{
+				info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/libc.so.6"},
+				syms: []string{"open"},
+			},
+			expectedDerived: true,
+		},
+		{
+			name: "Not allowlisted SO",
+			loadingSO: soInstance{
+				info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/var/test.so"},
+				syms: []string{"open"},
+			},
+			expectedDerived: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			mockLoader.addSOSymbols(testCase.loadingSO)
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+				WatchedSymbols:  []string{"open"},
+				WhitelistedLibs: []string{"/tmp/", "libc"},
+				AllowlistMode:   true,
+			})
+			require.NoError(t, err)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.loadingSO.info))
+			require.NoError(t, err)
+			if testCase.expectedDerived {
+				assert.Len(t, eventArgs, 2)
+			} else {
+				assert.Nil(t, eventArgs)
+			}
+		})
+	}
+}
+
+func TestDeriveSharedObjectMaxSymbolsPerEvent(t *testing.T) {
+	testCases := []struct {
+		name              string
+		syms              []string
+		expectedSymbols   []string
+		expectedTruncated bool
+	}{
+		{
+			name:            "Less symbols than maximum",
+			syms:            []string{"open"},
+			expectedSymbols: []string{"open"},
+		},
+		{
+			name:            "Symbols exactly at maximum",
+			syms:            []string{"open", "close"},
+			expectedSymbols: []string{"open", "close"},
+		},
+		{
+			name:              "More symbols than maximum",
+			syms:              []string{"open", "close", "write"},
+			expectedSymbols:   []string{"close", "open"},
+			expectedTruncated: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			so := soInstance{
+				info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"},
+				syms: testCase.syms,
+			}
+			mockLoader := initLoaderMock()
+			mockLoader.addSOSymbols(so)
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+				WatchedSymbols:     []string{"open", "close", "write"},
+				MaxSymbolsPerEvent: 2,
+			})
+			require.NoError(t, err)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+			require.NoError(t, err)
+			require.Len(t, eventArgs, 4)
+			assert.ElementsMatch(t, testCase.expectedSymbols, eventArgs[1])
+			assert.Equal(t, testCase.expectedTruncated, eventArgs[2])
+			assert.Equal(t, len(testCase.syms), eventArgs[3])
+		})
+	}
+}
+
+func TestNormalizeWhitelist(t *testing.T) {
+	testCases := []struct {
+		name       string
+		entries    []string
+		cleanPaths bool
+		expected   []string
+	}{
+		{
+			name:       "Duplicate paths",
+			entries:    []string{"/tmp/", "/usr/lib", "/tmp/"},
+			cleanPaths: true,
+			expected:   []string{"/usr/lib", "/tmp/"},
+		},
+		{
+			name:       "Subsumed paths",
+			entries:    []string{"/usr/lib/foo", "/usr/lib", "/usr/lib64/bar"},
+			cleanPaths: true,
+			expected:   []string{"/usr/lib"},
+		},
+		{
+			name:       "Unclean paths",
+			entries:    []string{"/usr//lib/../lib/foo/", "/tmp/./test", "/usr/lib/foo/bar.so"},
+			cleanPaths: true,
+			expected:   []string{"/usr/lib/foo/", "/tmp/test"},
+		},
+		{
+			name:       "Subsumed libraries",
+			entries:    []string{"libcrypto", "libc", "libssl", "libc"},
+			cleanPaths: false,
+			expected:   []string{"libssl", "libc"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, normalizeWhitelist(testCase.entries, testCase.cleanPaths))
+		})
+	}
+
+	t.Run("Matching is unchanged", func(t *testing.T) {
+		whitelist := []string{"/usr/lib", "/usr/lib/foo", "/tmp/", "/tmp/", "libc", "libcrypto"}
+		paths := []string{"/usr/lib/foo/1.so", "/usr/lib64/1.so", "/tmp/1.so", "/tmpfs/1.so", "/lib/libcrypto.so",
+			"/lib/libssl.so", "/var/1.so"}
+		var libraries, prefixes []string
+		for _, entry := range whitelist {
+			if entry[0] == '/' {
+				prefixes = append(prefixes, entry)
+			} else {
+				libraries = append(libraries, entry)
+			}
+		}
+		rawGen := SymbolsLoadedEventGenerator{
+			symbolsMatcher: symbolsMatcher{pathPrefixWhitelist: prefixes, librariesWhitelist: libraries},
+		}
+		gen, err := InitSymbolsLoadedEventGenerator(initLoaderMock(), SymbolsLoadedConfig{
+			WatchedSymbols:  []string{"open"},
+			WhitelistedLibs: whitelist,
+		})
+		require.NoError(t, err)
+		assert.Len(t, gen.pathPrefixWhitelist, 2)
+		assert.Len(t, gen.librariesWhitelist, 1)
+		for _, soPath := range paths {
+			assert.Equal(t, rawGen.isWhitelist(soPath), gen.isWhitelist(soPath), soPath)
+		}
+	})
+}
+
+func TestDeriveSharedObjectWatchedImports(t *testing.T) {
+	loadingSO := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"},
+		importsSyms: []string{"environ"},
+		importsInfo: []sharedobjs.ImportedSymbolInfo{
+			{Name: "dlopen", HasPLTSlot: true, PLTIndex: 3, GOTOffset: 0x4018},
+			{Name: "puts", HasPLTSlot: true, PLTIndex: 0, GOTOffset: 0x4000},
+		},
+	}
+	testCases := []struct {
+		name            string
+		config          SymbolsLoadedConfig
+		expectedArgs    int
+		expectedImports map[string]string
+	}{
+		{
+			name:            "Watched imports",
+			config:          SymbolsLoadedConfig{WatchedImports: []string{"dlopen", "environ", "write"}},
+			expectedArgs:    3,
+			expectedImports: map[string]string{"dlopen": "", "environ": ""},
+		},
+		{
+			name:            "Watched imports with PLT slots",
+			config:          SymbolsLoadedConfig{WatchedImports: []string{"dlopen", "environ", "write"}, ReportPLTSlots: true},
+			expectedArgs:    4,
+			expectedImports: map[string]string{"dlopen": "3:0x4018", "environ": ""},
+		},
+		{
+			name:         "No watched import",
+			config:       SymbolsLoadedConfig{WatchedImports: []string{"write"}},
+			expectedArgs: 0,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			mockLoader.addSOSymbols(loadingSO)
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, testCase.config)
+			require.NoError(t, err)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, loadingSO.info))
+			require.NoError(t, err)
+			require.Len(t, eventArgs, testCase.expectedArgs)
+			if testCase.expectedArgs == 0 {
+				return
+			}
+			assert.Empty(t, eventArgs[1])
+			imports := eventArgs[2].([]string)
+			matchedImports := make(map[string]string)
+			for i, sym := range imports {
+				matchedImports[sym] = ""
+				if testCase.config.ReportPLTSlots {
+					matchedImports[sym] = eventArgs[3].([]string)[i]
+				}
+			}
+			assert.Equal(t, testCase.expectedImports, matchedImports)
+		})
+	}
+}
+
+func TestDeriveSharedObjectWhitelistRegexps(t *testing.T) {
+	paths := map[string]bool{
+		"/usr/lib/x86_64-linux-gnu/libc.so.6":  true,
+		"/usr/lib/aarch64-linux-gnu/libc.so.6": true,
+		"/usr/lib/libc.so.6":                   false,
+		"/tmp/usr/lib/x86_64/libc.so.6":        false,
+		"/usr/lib/x86_64-linux-gnu/libc.so":    false,
+	}
+	mockLoader := initLoaderMock()
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:     []string{"open"},
+		WhitelistedRegexps: []string{`^/usr/lib/[^/]+/libc\.so\.[0-9]+$`},
+	})
+	require.NoError(t, err)
+	for soPath, expectedWhitelisted := range paths {
+		so := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: soPath}, syms: []string{"open"}}
+		mockLoader.addSOSymbols(so)
+		eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+		require.NoError(t, err)
+		if expectedWhitelisted {
+			assert.Nil(t, eventArgs, soPath)
+		} else {
+			assert.Len(t, eventArgs, 2, soPath)
+		}
+	}
+}
+
+func TestDeriveSharedObjectWhitelistGlobs(t *testing.T) {
+	paths := map[string]bool{
+		"/usr/lib/x86_64-linux-gnu/libnss_files.so.2":                            true,
+		"/usr/lib/x86_64-linux-gnu/libnss_dns.so.2":                              true,
+		"/usr/lib/i386-linux-gnu/libnss_files.so.2":                              true,
+		"/lib64/libnss_systemd.so.2":                                             true,
+		"/usr/lib/python3.10/lib-dynload/_ssl.cpython-310-x86_64-linux-gnu.so":   true,
+		"/usr/lib/python3.10/site-packages/_ssl.cpython-310-x86_64-linux-gnu.so": false,
+		"/usr/lib/x86_64-linux-gnu/libnsl.so.1":                                  false,
+		"/tmp/libnss_files.so.2":                                                 false,
+		"/usr/lib/x86_64-linux-gnu/libnss_evil.so":                               false,
+	}
+	mockLoader := initLoaderMock()
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:  []string{"open"},
+		WhitelistedLibs: []string{"nss_*.so.[0-9]*", "python3.*/lib-dynload/*"},
+	})
+	require.NoError(t, err)
+	for soPath, expectedWhitelisted := range paths {
+		so := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: soPath}, syms: []string{"open"}}
+		mockLoader.addSOSymbols(so)
+		eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+		require.NoError(t, err)
+		if expectedWhitelisted {
+			assert.Nil(t, eventArgs, soPath)
+		} else {
+			assert.Len(t, eventArgs, 2, soPath)
+		}
+	}
+}
+
+func TestDeriveSharedObjectLibrarySymbols(t *testing.T) {
+	testCases := []struct {
+		name            string
+		watchedSymbols  []string
+		excludedSymbols []string
+		soPath          string
+		expectedSymbols []string
+	}{
+		{
+			name:            "Matching library",
+			watchedSymbols:  []string{"libc!write"},
+			soPath:          "/usr/lib/libc.so.6",
+			expectedSymbols: []string{"write"},
+		},
+		{
+			name:           "Non matching library",
+			watchedSymbols: []string{"libc!write"},
+			soPath:         "/tmp/libevil.so",
+		},
+		{
+			name:           "Library matched by file name and not by directory",
+			watchedSymbols: []string{"libc!write"},
+			soPath:         "/tmp/libc/libevil.so",
+		},
+		{
+			name:            "One of several libraries",
+			watchedSymbols:  []string{"libc!write", "libevil!write", "libevil!open"},
+			soPath:          "/tmp/libevil.so",
+			expectedSymbols: []string{"write", "open"},
+		},
+		{
+			name:            "Mixed with symbols watched in any library",
+			watchedSymbols:  []string{"libc!write", "open"},
+			soPath:          "/tmp/libevil.so",
+			expectedSymbols: []string{"open"},
+		},
+		{
+			name:            "Symbol watched in any library subsumes library entry",
+			watchedSymbols:  []string{"libc!write", "write"},
+			soPath:          "/usr/lib/libc.so.6",
+			expectedSymbols: []string{"write"},
+		},
+		{
+			name:            "Excluded symbol of library entry",
+			watchedSymbols:  []string{"libc!write", "libc!open"},
+			excludedSymbols: []string{"write"},
+			soPath:          "/usr/lib/libc.so.6",
+			expectedSymbols: []string{"open"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			so := soInstance{
+				info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: testCase.soPath},
+				syms: []string{"write", "open", "read"},
+			}
+			for _, withInfo := range []bool{false, true} {
+				mockLoader := initLoaderMock()
+				mockLoader.addSOSymbols(so)
+				gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+					WatchedSymbols:   testCase.watchedSymbols,
+					ExcludedSymbols:  testCase.excludedSymbols,
+					ReportVisibility: withInfo,
+				})
+				require.NoError(t, err)
+				eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+				require.NoError(t, err)
+				if len(testCase.expectedSymbols) == 0 {
+					assert.Nil(t, eventArgs)
+					continue
+				}
+				require.NotNil(t, eventArgs)
+				assert.ElementsMatch(t, testCase.expectedSymbols, eventArgs[1])
+			}
+		})
+	}
+}
+
+func TestDeriveSharedObjectWatchedPrefixes(t *testing.T) {
+	testCases := []struct {
+		name            string
+		watchedSymbols  []string
+		excludedSymbols []string
+		expectedSymbols []string
+	}{
+		{
+			name:            "Prefix entry",
+			watchedSymbols:  []string{"EVP_*"},
+			expectedSymbols: []string{"EVP_EncryptInit", "EVP_DecryptInit"},
+		},
+		{
+			name:            "Prefix and exact entries",
+			watchedSymbols:  []string{"EVP_*", "SSL_read"},
+			expectedSymbols: []string{"EVP_EncryptInit", "EVP_DecryptInit", "SSL_read"},
+		},
+		{
+			name:            "Overlapping prefixes",
+			watchedSymbols:  []string{"SSL_*", "SSL_w*", "X509_*"},
+			expectedSymbols: []string{"SSL_read", "SSL_write"},
+		},
+		{
+			name:            "Excluded symbol starting with prefix",
+			watchedSymbols:  []string{"EVP_*"},
+			excludedSymbols: []string{"EVP_DecryptInit"},
+			expectedSymbols: []string{"EVP_EncryptInit"},
+		},
+		{
+			name:           "No matching prefix",
+			watchedSymbols: []string{"X509_*", "EVP_CIPHER*"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			so := soInstance{
+				info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libssl.so"},
+				syms: []string{"EVP_EncryptInit", "EVP_DecryptInit", "SSL_read", "SSL_write", "EVP"},
+			}
+			for _, withInfo := range []bool{false, true} {
+				mockLoader := initLoaderMock()
+				mockLoader.addSOSymbols(so)
+				gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+					WatchedSymbols:   testCase.watchedSymbols,
+					ExcludedSymbols:  testCase.excludedSymbols,
+					ReportVisibility: withInfo,
+				})
+				require.NoError(t, err)
+				eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+				require.NoError(t, err)
+				if len(testCase.expectedSymbols) == 0 {
+					assert.Nil(t, eventArgs)
+					continue
+				}
+				require.NotNil(t, eventArgs)
+				assert.ElementsMatch(t, testCase.expectedSymbols, eventArgs[1])
+			}
+		})
+	}
+}
+
+func TestDeriveSharedObjectInterpreter(t *testing.T) {
+	interpreterSO := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/ld-linux-x86-64.so.2"},
+		syms:        []string{"open"},
+		interpreter: true,
+	}
+	whitelistedInterpreterSO := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/lib64/ld-linux-x86-64.so.2"},
+		syms:        []string{"open"},
+		interpreter: true,
+	}
+	regularSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libevil.so"},
+		syms: []string{"open"},
+	}
+	testCases := []struct {
+		name              string
+		mode              InterpreterMode
+		report            bool
+		expectedDerived   map[string]bool
+		expectedDecisions []string
+	}{
+		{
+			name:              "Default mode",
+			mode:              InterpreterDefault,
+			expectedDerived:   map[string]bool{interpreterSO.info.Path: true, regularSO.info.Path: true},
+			expectedDecisions: []string{DecisionMatched, DecisionWhitelisted, DecisionMatched},
+		},
+		{
+			name:              "Exclude mode",
+			mode:              InterpreterExclude,
+			expectedDerived:   map[string]bool{regularSO.info.Path: true},
+			expectedDecisions: []string{DecisionInterpreter, DecisionWhitelisted, DecisionMatched},
+		},
+		{
+			name: "Include mode",
+			mode: InterpreterInclude,
+			expectedDerived: map[string]bool{
+				interpreterSO.info.Path:            true,
+				whitelistedInterpreterSO.info.Path: true,
+				regularSO.info.Path:                true,
+			},
+			expectedDecisions: []string{DecisionMatched, DecisionMatched, DecisionMatched},
+		},
+		{
+			name:              "Reported in default mode",
+			mode:              InterpreterDefault,
+			report:            true,
+			expectedDerived:   map[string]bool{interpreterSO.info.Path: true, regularSO.info.Path: true},
+			expectedDecisions: []string{DecisionMatched, DecisionWhitelisted, DecisionMatched},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			logger := &symbolsLoadedLoggerMock{}
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+				WatchedSymbols:    []string{"open"},
+				WhitelistedLibs:   []string{"/lib64/"},
+				Interpreter:       testCase.mode,
+				ReportInterpreter: testCase.report,
+				Logger:            logger,
+			})
+			require.NoError(t, err)
+			for _, so := range []soInstance{interpreterSO, whitelistedInterpreterSO, regularSO} {
+				mockLoader.addSOSymbols(so)
+				eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+				require.NoError(t, err)
+				if !testCase.expectedDerived[so.info.Path] {
+					assert.Nil(t, eventArgs, so.info.Path)
+					continue
+				}
+				if testCase.report {
+					require.Len(t, eventArgs, 3)
+					assert.Equal(t, so.interpreter, eventArgs[2], so.info.Path)
+				} else {
+					assert.Len(t, eventArgs, 2)
+				}
+			}
+			var decisions []string
+			for _, entry := range logger.entries {
+				decisions = append(decisions, entry.Decision)
+			}
+			assert.Equal(t, testCase.expectedDecisions, decisions)
+		})
+	}
+}
+
+func TestPrefixTree(t *testing.T) {
+	tree := newPrefixTree([]string{"SSL_", "SSL_CTX_", "EVP_Encrypt", "EVP_Decrypt", "X509", "EVP_"})
+	matching := []string{"SSL_read", "SSL_CTX_new", "EVP_EncryptInit", "EVP_MD_CTX_new", "X509", "X509_free"}
+	nonMatching := []string{"SSL", "EVP", "X50", "DTLS_method", "", "sSL_read"}
+	for _, sym := range matching {
+		assert.True(t, tree.matches(sym), sym)
+	}
+	for _, sym := range nonMatching {
+		assert.False(t, tree.matches(sym), sym)
+	}
+
+	assert.False(t, newPrefixTree(nil).matches("SSL_read"))
+	assert.True(t, newPrefixTree([]string{""}).matches("SSL_read"))
+}
+
+// opensslPrefixes are the prefixes of the OpenSSL API families, used as a realistic set of watched prefixes
+var opensslPrefixes = []string{
+	"AES_", "ASN1_", "BIO_", "BN_", "CMS_", "CONF_", "CRYPTO_", "DES_", "DH_", "DSA_", "DTLS_", "EC_", "ECDSA_",
+	"ENGINE_", "ERR_", "EVP_", "HMAC_", "MD5_", "OBJ_", "OCSP_", "OPENSSL_", "PEM_", "PKCS12_", "PKCS7_", "RAND_",
+	"RSA_", "SHA256_", "SSL_", "SSL_CTX_", "TLS_", "X509_", "X509V3_",
+}
+
+// benchmarkSymbols returns a realistic set of SO symbols, of which some start with the OpenSSL prefixes
+func benchmarkSymbols() []string {
+	var syms []string
+	for i := 0; i < 2000; i++ {
+		syms = append(syms, fmt.Sprintf("%sfunction_%d", opensslPrefixes[i%len(opensslPrefixes)], i))
+		syms = append(syms, fmt.Sprintf("unrelated_function_%d", i))
+	}
+	return syms
+}
+
+func BenchmarkWatchedPrefixes_Tree(b *testing.B) {
+	tree := newPrefixTree(opensslPrefixes)
+	syms := benchmarkSymbols()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, sym := range syms {
+			tree.matches(sym)
+		}
+	}
+}
+
+func BenchmarkWatchedPrefixes_Loop(b *testing.B) {
+	syms := benchmarkSymbols()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, sym := range syms {
+			for _, prefix := range opensslPrefixes {
+				if strings.HasPrefix(sym, prefix) {
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestMatchWatchedSymbols(t *testing.T) {
+	testCases := []struct {
+		name     string
+		soSyms   map[string]bool
+		watched  map[string]bool
+		expected []string
+	}{
+		{
+			name:     "No symbols",
+			soSyms:   map[string]bool{},
+			watched:  map[string]bool{"open": true},
+			expected: nil,
+		},
+		{
+			name:     "No watched symbols",
+			soSyms:   map[string]bool{"open": true},
+			watched:  map[string]bool{},
+			expected: nil,
+		},
+		{
+			name:     "Some symbols watched",
+			soSyms:   map[string]bool{"open": true, "close": true, "write": true},
+			watched:  map[string]bool{"open": true, "write": true, "read": true},
+			expected: []string{"open", "write"},
+		},
+		{
+			name:     "More watched symbols than symbols",
+			soSyms:   map[string]bool{"open": true, "close": true},
+			watched:  map[string]bool{"open": true, "write": true, "read": true},
+			expected: []string{"open"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.ElementsMatch(t, testCase.expected, MatchWatchedSymbols(testCase.soSyms, testCase.watched))
+		})
+	}
+}
+
+func TestDeriveSharedObjectWatchedTLSSymbols(t *testing.T) {
+	tlsSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libstash.so"},
+		syms: []string{"open"},
+		symsInfo: []sharedobjs.SymbolInfo{
+			{Name: "stash", Bind: elf.STB_GLOBAL, Type: elf.STT_TLS},
+			{Name: "counter", Bind: elf.STB_GLOBAL, Type: elf.STT_TLS},
+		},
+	}
+	// A data object with the name of a watched TLS symbol is not a TLS symbol
+	objectSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libdata.so"},
+		symsInfo: []sharedobjs.SymbolInfo{
+			{Name: "stash", Bind: elf.STB_GLOBAL, Type: elf.STT_OBJECT},
+		},
+	}
+	mockLoader := initLoaderMock()
+	mockLoader.addSOSymbols(tlsSO)
+	mockLoader.addSOSymbols(objectSO)
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:    []string{"open"},
+		WatchedTLSSymbols: []string{"stash", "counter", "missing"},
+	})
+	require.NoError(t, err)
+
+	eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, tlsSO.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{tlsSO.info.Path, []string{"open"}, []string{"counter", "stash"}}, eventArgs)
+
+	eventArgs, err = gen.deriveArgs(generateSOLoadedEvent(1, objectSO.info))
+	require.NoError(t, err)
+	assert.Nil(t, eventArgs)
+
+	// TLS symbols alone derive the event
+	tlsOnly, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedTLSSymbols: []string{"stash"},
+	})
+	require.NoError(t, err)
+	eventArgs, err = tlsOnly.deriveArgs(generateSOLoadedEvent(1, tlsSO.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{tlsSO.info.Path, []string(nil), []string{"stash"}}, eventArgs)
+}
+
+func TestDeriveSharedObjectWatchedVersionedSymbols(t *testing.T) {
+	// The host and a container have different glibc builds in the same path, and memcpy has a different default
+	// version in each of them
+	hostLibc := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1, Device: 1}, Path: "/usr/lib/libc.so.6", MountNS: 1},
+		symsInfo: []sharedobjs.SymbolInfo{
+			{Name: "memcpy", Type: elf.STT_FUNC, Versions: []string{"GLIBC_2.14", "GLIBC_2.2.5"}},
+			{Name: "open", Type: elf.STT_FUNC, Versions: []string{"GLIBC_2.2.5"}},
+		},
+	}
+	containerLibc := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1, Device: 2}, Path: "/usr/lib/libc.so.6", MountNS: 2},
+		symsInfo: []sharedobjs.SymbolInfo{
+			{Name: "memcpy", Type: elf.STT_FUNC, Versions: []string{"GLIBC_2.2.5"}},
+			{Name: "open", Type: elf.STT_FUNC, Versions: []string{"GLIBC_2.2.5"}},
+		},
+	}
+	mockLoader := initLoaderMock()
+	mockLoader.addSOSymbols(hostLibc)
+	mockLoader.addSOSymbols(containerLibc)
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedVersionedSymbols: []string{"memcpy@GLIBC_2.14", "open@GLIBC_2.2.5", "memcpy@GLIBC_2.3"},
+	})
+	require.NoError(t, err)
+
+	eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, hostLibc.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"/usr/lib/libc.so.6", []string(nil), []string{"memcpy@GLIBC_2.14", "open@GLIBC_2.2.5"}},
+		eventArgs)
+	// The versions of the container's build are matched, regardless of the host's build
+	eventArgs, err = gen.deriveArgs(generateSOLoadedEvent(2, containerLibc.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"/usr/lib/libc.so.6", []string(nil), []string{"open@GLIBC_2.2.5"}}, eventArgs)
+
+	for _, entry := range []string{"memcpy", "memcpy@", "@GLIBC_2.14", "mem*@GLIBC_2.14", "libc.so.6!memcpy@GLIBC_2.14"} {
+		problems := ValidateConfig(SymbolsLoadedConfig{WatchedVersionedSymbols: []string{entry}})
+		require.Len(t, problems, 1, entry)
+		assert.EqualError(t, problems[0], fmt.Sprintf("watched versioned symbol '%s' should be '<symbol>@<version>'", entry))
+	}
+}
+
+func TestDeriveSharedObjectWatchedDemangledSymbols(t *testing.T) {
+	cxxSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libfoo.so"},
+		// "_Z4foo" is mangled, but can't be demangled
+		syms: []string{"open", "_ZNK3Foo3getEi", "_ZN3Foo3getEv", "_ZN3Foo3setEi", "_Z3barPKc", "_Z4foo"},
+	}
+	mockLoader := initLoaderMock()
+	mockLoader.addSOSymbols(cxxSO)
+
+	// All the overloads of a watched name are matched, and reported once
+	names, err := NewSymbolsLoadedGenerator(mockLoader, WithWatchedSymbols("open"),
+		WithDemangledSymbols(MatchDemangledNames, "Foo::get", "bar", "baz"))
+	require.NoError(t, err)
+	eventArgs, err := names.deriveArgs(generateSOLoadedEvent(1, cxxSO.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{cxxSO.info.Path, []string{"open"}, []string{"Foo::get", "bar"}}, eventArgs)
+
+	// A watched signature matches a single overload, and demangled symbols alone derive the event
+	signatures, err := NewSymbolsLoadedGenerator(mockLoader,
+		WithDemangledSymbols(MatchDemangledSignatures, "Foo::get(int) const", "bar(char const*)", "Foo::set(long)"))
+	require.NoError(t, err)
+	eventArgs, err = signatures.deriveArgs(generateSOLoadedEvent(1, cxxSO.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{cxxSO.info.Path, []string(nil), []string{"Foo::get(int) const", "bar(char const*)"}},
+		eventArgs)
+	assert.Equal(t, []string{"Foo::get(int) const", "Foo::set(long)", "bar(char const*)"},
+		signatures.EffectiveConfig().WatchedDemangled)
+
+	testCases := []struct {
+		name     string
+		matching SymbolsMatchingConfig
+		expected string
+	}{
+		{
+			name:     "Empty entry",
+			matching: SymbolsMatchingConfig{WatchedDemangledSymbols: []string{""}},
+			expected: "empty watched demangled symbol entry",
+		},
+		{
+			name:     "Mangled entry",
+			matching: SymbolsMatchingConfig{WatchedDemangledSymbols: []string{"_ZN3Foo3getEv"}},
+			expected: "watched demangled symbol '_ZN3Foo3getEv' is mangled, and should be a watched symbol",
+		},
+		{
+			name: "Unknown match mode",
+			matching: SymbolsMatchingConfig{WatchedDemangledSymbols: []string{"Foo::get"},
+				DemangledMatch: MatchDemangledSignatures + 1},
+			expected: "unknown demangled match mode 2",
+		},
+		{
+			name:     "Signatures with no symbols",
+			matching: SymbolsMatchingConfig{WatchedSymbols: []string{"open"}, DemangledMatch: MatchDemangledSignatures},
+			expected: "demangled signatures are matched with no watched demangled symbols",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := NewSymbolsLoadedGenerator(mockLoader, WithMatching(testCase.matching))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), testCase.expected)
+		})
+	}
+}
+
+func TestDeriveSharedObjectExportWatchedSymbolsVisibility(t *testing.T) {
+	pid := 1
+	loadingSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "1.so"},
+		syms: []string{"open"},
+		symsInfo: []sharedobjs.SymbolInfo{
+			{Name: "close", Bind: elf.STB_GLOBAL, Type: elf.STT_FUNC, Visibility: elf.STV_HIDDEN},
+			{Name: "write", Bind: elf.STB_GLOBAL, Type: elf.STT_FUNC, Visibility: elf.STV_PROTECTED},
+		},
+	}
+	testCases := []struct {
+		name                 string
+		config               SymbolsLoadedConfig
+		expectedSymbols      []string
+		expectedVisibilities []string
+	}{
+		{
+			name: "Report visibility",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:   []string{"open", "close", "write"},
+				ReportVisibility: true,
+			},
+			expectedSymbols:      []string{"open", "close", "write"},
+			expectedVisibilities: []string{"STV_DEFAULT", "STV_HIDDEN", "STV_PROTECTED"},
+		},
+		{
+			name: "Watch hidden symbols",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:      []string{"open", "close", "write"},
+				WatchedVisibilities: []elf.SymVis{elf.STV_HIDDEN},
+				ReportVisibility:    true,
+			},
+			expectedSymbols:      []string{"close"},
+			expectedVisibilities: []string{"STV_HIDDEN"},
+		},
+		{
+			name: "Watch hidden and protected symbols without reporting",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:      []string{"open", "close", "write"},
+				WatchedVisibilities: []elf.SymVis{elf.STV_HIDDEN, elf.STV_PROTECTED},
+			},
+			expectedSymbols: []string{"close", "write"},
+		},
+		{
+			name: "No watched symbol with watched visibility",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:      []string{"open"},
+				WatchedVisibilities: []elf.SymVis{elf.STV_HIDDEN},
+			},
+			expectedSymbols: []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			mockLoader.addSOSymbols(loadingSO)
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, testCase.config)
+			require.NoError(t, err)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(pid, loadingSO.info))
+			require.NoError(t, err)
+			if len(testCase.expectedSymbols) == 0 {
+				assert.Len(t, eventArgs, 0)
+				return
+			}
+			require.Len(t, eventArgs, len(gen.skeleton.Params))
+			syms := eventArgs[1].([]string)
+			assert.ElementsMatch(t, testCase.expectedSymbols, syms)
+			if testCase.config.ReportVisibility {
+				require.Len(t, eventArgs, 3)
+				visibilities := eventArgs[2].([]string)
+				require.Len(t, visibilities, len(syms))
+				for i, sym := range syms {
+					for j, expectedSym := range testCase.expectedSymbols {
+						if sym == expectedSym {
+							assert.Equal(t, testCase.expectedVisibilities[j], visibilities[i])
+						}
+					}
+				}
+			} else {
+				assert.Len(t, eventArgs, 2)
+			}
+		})
+	}
+}
+
+func TestDeriveSharedObjectExportWatchedSymbolsSection(t *testing.T) {
+	loadingSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "1.so"},
+		symsInfo: []sharedobjs.SymbolInfo{
+			{Name: "open", Type: elf.STT_FUNC, SectionName: ".text", ExecutableSection: true},
+			{Name: "close", Type: elf.STT_OBJECT, SectionName: ".data"},
+		},
+	}
+	testCases := []struct {
+		name             string
+		config           SymbolsLoadedConfig
+		expectedSymbols  []string
+		expectedSections []string
+	}{
+		{
+			name: "Report section",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open", "close"},
+				ReportSection:  true,
+			},
+			expectedSymbols:  []string{"open", "close"},
+			expectedSections: []string{".text", ".data"},
+		},
+		{
+			name: "Executable sections only",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:         []string{"open", "close"},
+				ExecutableSectionsOnly: true,
+				ReportSection:          true,
+			},
+			expectedSymbols:  []string{"open"},
+			expectedSections: []string{".text"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			mockLoader.addSOSymbols(loadingSO)
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, testCase.config)
+			require.NoError(t, err)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, loadingSO.info))
+			require.NoError(t, err)
+			require.Len(t, eventArgs, 3)
+			syms := eventArgs[1].([]string)
+			sections := eventArgs[2].([]string)
+			require.Len(t, sections, len(syms))
+			symsSections := make(map[string]string)
+			for i, sym := range syms {
+				symsSections[sym] = sections[i]
+			}
+			expectedSymsSections := make(map[string]string)
+			for i, sym := range testCase.expectedSymbols {
+				expectedSymsSections[sym] = testCase.expectedSections[i]
+			}
+			assert.Equal(t, expectedSymsSections, symsSections)
+		})
+	}
+}
+
+func TestDeriveSharedObjectSymbolIndex(t *testing.T) {
+	loadingSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "1.so"},
+		symsInfo: []sharedobjs.SymbolInfo{
+			{Name: "open", Type: elf.STT_FUNC, Index: 3},
+			{Name: "close", Type: elf.STT_FUNC, Index: 4},
+			{Name: "read", Type: elf.STT_FUNC, Index: 5},
+		},
+	}
+	testCases := []struct {
+		name               string
+		config             SymbolsLoadedConfig
+		expectedIndexes    map[string]uint64
+		expectedUnexpected []string
+	}{
+		{
+			name: "Report index",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:    []string{"open", "close"},
+				ReportSymbolIndex: true,
+			},
+			expectedIndexes: map[string]uint64{"open": 3, "close": 4},
+		},
+		{
+			name: "Unexpected indexes",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:    []string{"open", "close", "read"},
+				ReportSymbolIndex: true,
+				// Symbols which are not exported are not reported
+				ExpectedSymbolIndexes: map[string]int{"open": 3, "close": 2, "read": 4, "write": 6},
+			},
+			expectedIndexes:    map[string]uint64{"open": 3, "close": 4, "read": 5},
+			expectedUnexpected: []string{"close=4", "read=5"},
+		},
+		{
+			name: "Expected indexes",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:        []string{"open", "close"},
+				ExpectedSymbolIndexes: map[string]int{"open": 3, "close": 4},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			mockLoader.addSOSymbols(loadingSO)
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, testCase.config)
+			require.NoError(t, err)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, loadingSO.info))
+			require.NoError(t, err)
+			syms := eventArgs[1].([]string)
+			args := eventArgs[2:]
+			if testCase.config.ReportSymbolIndex {
+				indexes := args[0].([]uint64)
+				require.Len(t, indexes, len(syms))
+				symsIndexes := make(map[string]uint64)
+				for i, sym := range syms {
+					symsIndexes[sym] = indexes[i]
+				}
+				assert.Equal(t, testCase.expectedIndexes, symsIndexes)
+				args = args[1:]
+			}
+			if testCase.config.ExpectedSymbolIndexes != nil {
+				require.Len(t, args, 1)
+				assert.Equal(t, testCase.expectedUnexpected, args[0])
+			} else {
+				assert.Empty(t, args)
+			}
+		})
+	}
+
+	// The indexes are loaded with the symbols information
+	var loader struct {
+		sharedobjs.DynamicSymbolsLoader
+	}
+	_, err := InitSymbolsLoadedEventGenerator(loader, SymbolsLoadedConfig{
+		WatchedSymbols:    []string{"open"},
+		ReportSymbolIndex: true,
+	})
+	assert.Error(t, err)
+}
+
+func TestDeriveSharedObjectSymbolSizes(t *testing.T) {
+	function := func(name string, size uint64) sharedobjs.SymbolInfo {
+		return sharedobjs.SymbolInfo{Name: name, Bind: elf.STB_GLOBAL, Type: elf.STT_FUNC, Size: size}
+	}
+	stubSO := soInstance{
+		info:     sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libstub.so"},
+		symsInfo: []sharedobjs.SymbolInfo{function("SSL_read", 1), function("SSL_write", 412)},
+	}
+	unsizedSO := soInstance{
+		info:     sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libasm.so"},
+		symsInfo: []sharedobjs.SymbolInfo{function("SSL_read", 0)},
+	}
+	oversizedSO := soInstance{
+		info:     sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libwrapper.so"},
+		symsInfo: []sharedobjs.SymbolInfo{function("SSL_write", 9000), function("open", 64)},
+	}
+	genuineSO := soInstance{
+		info:     sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/tmp/libssl.so"},
+		symsInfo: []sharedobjs.SymbolInfo{function("SSL_read", 380), function("SSL_write", 412)},
+	}
+	mixedSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 5}, Path: "/tmp/libmixed.so"},
+		symsInfo: []sharedobjs.SymbolInfo{function("SSL_read", 380), function("SSL_write", 2), function("open", 64),
+			function("close", 0)},
+	}
+	testCases := []struct {
+		name          string
+		so            soInstance
+		expectedSizes map[string]uint64 // The size of each matched symbol
+	}{
+		// Only the symbols with an expected range whose size is out of it are matched
+		{name: "Stub sized symbol", so: stubSO, expectedSizes: map[string]uint64{"SSL_read": 1}},
+		{name: "Symbol with no size", so: unsizedSO, expectedSizes: map[string]uint64{"SSL_read": 0}},
+		// Symbols with no expected range are matched regardless of their size
+		{name: "Oversized symbol", so: oversizedSO, expectedSizes: map[string]uint64{"SSL_write": 9000, "open": 64}},
+		{name: "Symbols within their ranges", so: genuineSO},
+		{name: "Ranged and unranged symbols", so: mixedSO,
+			expectedSizes: map[string]uint64{"SSL_write": 2, "open": 64, "close": 0}},
+	}
+
+	mockLoader := initLoaderMock()
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols: []string{"SSL_read", "SSL_write", "open", "close"},
+		ExpectedSymbolSizes: map[string]SymbolSizeRange{
+			"SSL_read":  {Min: 64},
+			"SSL_write": {Min: 64, Max: 4096},
+		},
+		MaxSymbolsPerEvent: 10,
+	})
+	require.NoError(t, err)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader.addSOSymbols(testCase.so)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.so.info))
+			require.NoError(t, err)
+			if testCase.expectedSizes == nil {
+				assert.Nil(t, eventArgs)
+				return
+			}
+			// The sizes are in the order of the symbols
+			require.Len(t, eventArgs, 5)
+			symbols, sizes := eventArgs[1].([]string), eventArgs[2].([]uint64)
+			require.Len(t, sizes, len(symbols))
+			matchedSizes := make(map[string]uint64, len(symbols))
+			for i, sym := range symbols {
+				matchedSizes[sym] = sizes[i]
+			}
+			assert.Equal(t, testCase.expectedSizes, matchedSizes)
+		})
+	}
+
+	// The sizes are loaded with the symbols information
+	var loader struct {
+		sharedobjs.DynamicSymbolsLoader
+	}
+	_, err = InitSymbolsLoadedEventGenerator(loader, SymbolsLoadedConfig{
+		WatchedSymbols:   []string{"open"},
+		ReportSymbolSize: true,
+	})
+	assert.Error(t, err)
+}
+
+func TestGetSharedObjectInfo(t *testing.T) {
+	testCases := []struct {
+		name         string
+		so           sharedobjs.ObjInfo
+		expectedInfo sharedobjs.ObjInfo
+	}{
+		{
+			name: "Existing SO",
+			so:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1, Device: 2, Ctime: 3}, Path: "/tmp/test.so"},
+			expectedInfo: sharedobjs.ObjInfo{
+				Id:   sharedobjs.ObjID{Inode: 1, Device: 2, Ctime: 3},
+				Path: "/tmp/test.so",
+				Pid:  1,
+			},
+		},
+		{
+			name: "Deleted SO",
+			so:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1, Device: 2, Ctime: 3}, Path: "/tmp/test.so (deleted)"},
+			expectedInfo: sharedobjs.ObjInfo{
+				Id:      sharedobjs.ObjID{Inode: 1, Device: 2, Ctime: 3},
+				Path:    "/tmp/test.so",
+				Pid:     1,
+				Deleted: true,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			info, err := getSharedObjectInfo(generateSOLoadedEvent(1, testCase.so))
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedInfo, info)
+		})
+	}
+
+	t.Run("Partially parsed SO", func(t *testing.T) {
+		event := generateSOLoadedEvent(1, sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1, Device: 2, Ctime: 3}, Path: "/tmp/test.so"})
+		event.Args[2].Value = "2"                   // dev
+		event.Args = event.Args[:len(event.Args)-1] // ctime
+		info, err := getSharedObjectInfo(event)
+		var infoErr *SharedObjectInfoError
+		require.ErrorAs(t, err, &infoErr)
+		assert.Len(t, infoErr.Errors, 2)
+		assert.Equal(t, "failed to parse 2 shared object fields: argument dev is not of type uint32; argument ctime not found", err.Error())
+		assert.Equal(t, sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/test.so", Pid: 1}, info)
+	})
+
+	t.Run("Deleted whitelisted SO", func(t *testing.T) {
+		deletedSO := soInstance{
+			info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/test.so (deleted)"},
+			syms: []string{"open"},
+		}
+		mockLoader := initLoaderMock()
+		mockLoader.addSOSymbols(deletedSO)
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols:  []string{"open"},
+			WhitelistedLibs: []string{"/tmp/test.so"},
+		})
+		require.NoError(t, err)
+		eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, deletedSO.info))
+		require.NoError(t, err)
+		assert.Len(t, eventArgs, 0)
+	})
+}
+
+func TestValidateConfig(t *testing.T) {
+	testCases := []struct {
+		name             string
+		config           SymbolsLoadedConfig
+		expectedProblems []string
+	}{
+		{
+			name: "Valid config",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:  []string{"open", "close"},
+				ExcludedSymbols: []string{"write"},
+				WhitelistedLibs: []string{"/tmp/", "libc"},
+			},
+			expectedProblems: nil,
+		},
+		{
+			name: "Library limited watched symbols",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"libc!write", "!open", "libc!", "/lib/libc!read", "libc!close!"},
+			},
+			expectedProblems: []string{
+				"watched symbol entry '!open' is missing its library or symbol",
+				"watched symbol entry 'libc!' is missing its library or symbol",
+				"watched symbol entry '/lib/libc!read' library should be a file name",
+				"watched symbol entry 'libc!close!' library should be a file name",
+			},
+		},
+		{
+			name: "Bad summary configuration",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:    []string{"open"},
+				SummaryInterval:   -time.Second,
+				SummaryKey:        SummaryKey(5),
+				MaxSummaryEntries: -1,
+			},
+			expectedProblems: []string{
+				"negative summary interval -1s",
+				"unknown summary key 5",
+				"negative maximal summary entries -1",
+			},
+		},
+		{
+			name: "Negative match history size",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:   []string{"open"},
+				ReportChanges:    true,
+				MatchHistorySize: -1,
+			},
+			expectedProblems: []string{"negative match history size -1"},
+		},
+		{
+			name: "Unknown interpreter mode",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				Interpreter:    InterpreterMode(7),
+			},
+			expectedProblems: []string{"unknown interpreter mode 7"},
+		},
+		{
+			name: "Library limited prefix",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"EVP_*", "libssl!EVP_*"},
+			},
+			expectedProblems: []string{
+				"watched symbol entry 'libssl!EVP_*' can't be both a prefix and limited to a library",
+			},
+		},
+		{
+			// Nothing is derived, but the events depending on the generator are
+			name: "No watched symbols",
+			config: SymbolsLoadedConfig{
+				WhitelistedLibs: []string{"libc"},
+			},
+			expectedProblems: nil,
+		},
+		{
+			// Paths and library names are prefixes, so only library globs are patterns
+			name: "Bad patterns",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:  []string{"open", "clo[se"},
+				WhitelistedLibs: []string{"/tmp/[a-", "lib\\x", "nss_[a-"},
+			},
+			expectedProblems: []string{
+				"whitelist entry 'nss_[a-' is not a valid pattern: syntax error in pattern",
+			},
+		},
+		{
+			name: "Empty entries",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:  []string{"open", ""},
+				WhitelistedLibs: []string{""},
+			},
+			expectedProblems: []string{
+				"empty watched symbol entry",
+				"empty whitelist entry",
+			},
+		},
+		{
+			name: "Contradicting rules",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:  []string{"open", "close"},
+				ExcludedSymbols: []string{"close"},
+			},
+			expectedProblems: []string{"symbol 'close' is both watched and excluded"},
+		},
+		{
+			name: "Only rules",
+			config: SymbolsLoadedConfig{
+				Rules: []SymbolsRule{{Name: "loader", Predicate: Imports("dlopen")}},
+			},
+			expectedProblems: nil,
+		},
+		{
+			name: "Bad rules",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				Rules: []SymbolsRule{
+					{Name: "", Predicate: Imports("dlopen")},
+					{Name: "loader", Predicate: Imports("dlopen")},
+					{Name: "loader"},
+				},
+			},
+			expectedProblems: []string{
+				"rule with no name",
+				"rule 'loader' is defined more than once",
+				"rule 'loader' has no predicate",
+			},
+		},
+		{
+			name: "Invalid whitelist regexp",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:     []string{"open"},
+				WhitelistedRegexps: []string{`^/usr/lib/.*/libc\.so\.[0-9]+$`, "libc(.so"},
+			},
+			expectedProblems: []string{"whitelist regexp entry 'libc(.so' is invalid: error parsing regexp: missing closing ): `libc(.so`"},
+		},
+		{
+			name: "Allowlist mode with no libraries",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				AllowlistMode:  true,
+			},
+			expectedProblems: []string{"allowlist mode is configured with no libraries - the event will never be derived"},
+		},
+		{
+			name: "Negative maximal symbols per event",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:     []string{"open"},
+				MaxSymbolsPerEvent: -1,
+			},
+			expectedProblems: []string{"negative maximal symbols per event -1"},
+		},
+		{
+			name: "PLT slots with no watched imports",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				ReportPLTSlots: true,
+			},
+			expectedProblems: []string{"PLT slots reporting is configured with no watched imports"},
+		},
+		{
+			name: "Derived event override",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				EventName:      "symbols_loaded",
+			},
+			expectedProblems: nil,
+		},
+		{
+			name: "Undefined derived event",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				EventName:      "no_such_event",
+			},
+			expectedProblems: []string{"derived event 'no_such_event' is not defined"},
+		},
+		{
+			name: "Derived event with other arguments",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				EventName:      "shared_object_loaded",
+			},
+			expectedProblems: []string{"derived event 'shared_object_loaded' arguments don't match the symbols_loaded arguments"},
+		},
+		{
+			name: "Bad watch groups",
+			config: SymbolsLoadedConfig{
+				WatchGroups: []SymbolsWatchGroup{
+					{Name: "hooks", Symbols: []string{"open", "EVP_*", "libc!write"}},
+					{Name: "hooks", Symbols: []string{"open"}, MinMatches: 2},
+					{Symbols: []string{""}},
+				},
+			},
+			expectedProblems: []string{
+				"watch group 'hooks' symbol 'EVP_*' should be a full symbol name",
+				"watch group 'hooks' symbol 'libc!write' should be a full symbol name",
+				"watch group 'hooks' is defined more than once",
+				"watch group 'hooks' minimal matches 2 is not between 0 and its 1 symbols",
+				"watch group with no name",
+				"empty symbol entry of watch group ''",
+			},
+		},
+		{
+			name: "Unknown W^X segments mode",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				WXSegments:     WXSegmentsMode(7),
+			},
+			expectedProblems: []string{"unknown W^X segments mode 7"},
+		},
+		{
+			name: "Bad symbol aliases",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"malloc"},
+				SymbolAliases: map[string][]string{
+					"malloc": {"__libc_malloc"},
+					"calloc": {"__libc_malloc", ""},
+					"open":   {"__open*"},
+				},
+			},
+			expectedProblems: []string{
+				"empty alias of symbol 'calloc'",
+				"symbol '__libc_malloc' is an alias of both 'calloc' and 'malloc'",
+				"alias '__open*' of symbol 'open' should be a full symbol name",
+			},
+		},
+		{
+			name: "Bad relocation thresholds",
+			config: SymbolsLoadedConfig{
+				RelocationThresholds: map[string]int{"R_X86_64_COPY": -1, "IRELATIVE": 4},
+			},
+			expectedProblems: []string{
+				"relocation type 'IRELATIVE' should be a relocation name (e.g. R_X86_64_COPY)",
+				"negative threshold -1 of relocation type 'R_X86_64_COPY'",
+			},
+		},
+		{
+			name: "Bad symbol indexes",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:        []string{"open"},
+				ExpectedSymbolIndexes: map[string]int{"open": 0, "close": 2, "": 3},
+			},
+			expectedProblems: []string{
+				"expected index of an empty symbol",
+				"expected index 0 of symbol 'open' should be at least 1",
+			},
+		},
+		{
+			name: "Bad entropy threshold",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:   []string{"open"},
+				EntropyThreshold: 9,
+			},
+			expectedProblems: []string{
+				"entropy threshold 9 is not between 0 and 8",
+				"entropy threshold is configured with no high entropy flagging",
+			},
+		},
+		{
+			name: "Bad symbol sets",
+			config: SymbolsLoadedConfig{
+				SymbolSets: []SymbolSet{
+					{Name: "libc", Symbols: []string{"malloc", "str*"}, MinRatio: 1.5},
+					{Name: "libc", LibraryNames: []string{"/lib/libc.so"}},
+				},
+			},
+			expectedProblems: []string{
+				"symbol set 'libc' symbol 'str*' should be a full symbol name",
+				"symbol set 'libc' minimal ratio 1.5 is not between 0 and 1",
+				"symbol set 'libc' is defined more than once",
+				"symbol set 'libc' has no symbols",
+				"symbol set 'libc' library name '/lib/libc.so' should be a file name prefix",
+			},
+		},
+		{
+			name: "Bad watched TLS symbols",
+			config: SymbolsLoadedConfig{
+				WatchedTLSSymbols: []string{"", "tls_*", "libc.so!errno"},
+			},
+			expectedProblems: []string{
+				"watched TLS symbol '' should be a full symbol name",
+				"watched TLS symbol 'tls_*' should be a full symbol name",
+				"watched TLS symbol 'libc.so!errno' should be a full symbol name",
+			},
+		},
+		{
+			name: "Bad user severities",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				UserSeverities: map[int]SymbolsSeverity{-1: {Severity: "high"}, 1000: {Action: "ticket"}},
+			},
+			expectedProblems: []string{
+				"severity user ID -1 is negative",
+				"severity of user ID 1000 is empty",
+			},
+		},
+		{
+			name: "Default severity with no user severities",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:  []string{"open"},
+				DefaultSeverity: SymbolsSeverity{Severity: "low"},
+			},
+			expectedProblems: []string{"default severity is configured with no severities by user ID"},
+		},
+		{
+			name: "Only unusual interpreters flagged",
+			config: SymbolsLoadedConfig{
+				FlagUnusualInterpreter: true,
+			},
+		},
+		{
+			name: "Negative capabilities processes",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:        []string{"open"},
+				TrackCapabilities:     true,
+				CapabilitiesProcesses: -1,
+			},
+			expectedProblems: []string{"negative capabilities processes -1"},
+		},
+		{
+			name: "Bad passthrough arguments",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:  []string{"open"},
+				ReportObjectID:  true,
+				PassthroughArgs: []string{"flags", "mode", "flags", "inode"},
+			},
+			expectedProblems: []string{
+				"passthrough argument 'mode' is not an argument of shared_object_loaded",
+				"passthrough argument 'flags' is configured more than once",
+				"passthrough argument 'inode' is already reported as the SO identity",
+			},
+		},
+		{
+			name: "Symbols in the metadata only mode",
+			config: SymbolsLoadedConfig{
+				MetadataOnly:       true,
+				WatchedSymbols:     []string{"open"},
+				WatchedImports:     []string{"dlopen"},
+				ReportSymbolsCount: true,
+			},
+			expectedProblems: []string{
+				"watched symbols can't be configured in the metadata only mode",
+				"watched imports can't be configured in the metadata only mode",
+				"symbols count can't be configured in the metadata only mode",
+			},
+		},
+		{
+			name: "Negative constructors baseline",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:       []string{"open"},
+				ReportConstructors:   true,
+				ConstructorsBaseline: -1,
+			},
+			expectedProblems: []string{"negative constructors baseline -1"},
+		},
+		{
+			name: "Negative fingerprint cache size",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:           []string{"open"},
+				ReportSymbolsFingerprint: true,
+				FingerprintCacheSize:     -1,
+			},
+			expectedProblems: []string{"negative fingerprint cache size -1"},
+		},
+		{
+			name: "Bad enrichment fields",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				Enrichment: SymbolsEnrichment{Fields: []trace.ArgMeta{
+					{Type: "const char*const*", Name: "tags"},
+					{Type: "const char*", Name: "tags"},
+					{Type: "int"},
+					{Type: "float", Name: "score"},
+				}},
+			},
+			expectedProblems: []string{
+				"enrichment fields are configured without an enrichment callback",
+				"enrichment field 'tags' is configured more than once",
+				"enrichment field of type 'int' has no name",
+				"enrichment field 'score' type 'float' is not supported",
+			},
+		},
+		{
+			name: "Bad versioned watched imports",
+			config: SymbolsLoadedConfig{
+				WatchedImports: []string{"memcpy@GLIBC_2.2.5", "memcpy@", "@GLIBC_2.14", "memcpy@GLIBC_2.2.5@GLIBC_2.14"},
+			},
+			expectedProblems: []string{
+				"watched import entry 'memcpy@' should be '<symbol>@<version>'",
+				"watched import entry '@GLIBC_2.14' should be '<symbol>@<version>'",
+				"watched import entry 'memcpy@GLIBC_2.2.5@GLIBC_2.14' should be '<symbol>@<version>'",
+			},
+		},
+		{
+			name: "Flagged DT_NULL",
+			config: SymbolsLoadedConfig{
+				FlaggedDynamicTags: []elf.DynTag{elf.DT_AUDIT, elf.DT_NULL},
+			},
+			expectedProblems: []string{
+				"DT_NULL can't be a flagged dynamic tag, as it terminates the dynamic section",
+			},
+		},
+		{
+			name: "Bad always watched symbols",
+			config: SymbolsLoadedConfig{
+				AlwaysWatchedSymbols: []string{"ptrace", "dl*", "libc.so.6!open", "mprotect"},
+				ExcludedSymbols:      []string{"mprotect"},
+			},
+			expectedProblems: []string{
+				"always watched symbol 'dl*' should be a full symbol name",
+				"always watched symbol 'libc.so.6!open' should be a full symbol name",
+				"symbol 'mprotect' is both watched and excluded",
+			},
+		},
+		{
+			name: "Negative profile bounds",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:       []string{"open"},
+				ProfileProcesses:     true,
+				MaxProfiledProcesses: -1,
+				MaxProfileEntries:    -2,
+			},
+			expectedProblems: []string{
+				"negative maximal profiled processes -1",
+				"negative maximal profile entries -2",
+			},
+		},
+		{
+			name: "Negative maximal seen build IDs",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:  []string{"open"},
+				TrackBuildIDs:   true,
+				MaxSeenBuildIDs: -1,
+			},
+			expectedProblems: []string{"negative maximal seen build IDs -1"},
+		},
+		{
+			name: "Negative soname paths bounds",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:    []string{"open"},
+				TrackSonamePaths:  true,
+				MaxTrackedSonames: -1,
+				MaxSonamePaths:    -2,
+			},
+			expectedProblems: []string{
+				"negative maximal tracked sonames -1",
+				"negative maximal soname paths -2",
+			},
+		},
+		{
+			name: "Bad process scope",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				ProcessScope:   SymbolsProcessScope{UserIDs: []int{-1}, HostProcessIDs: []int{0}, ContainerIDs: []string{""}},
+			},
+			expectedProblems: []string{
+				"process scope user ID -1 is negative",
+				"process scope PID 0 should be positive",
+				"empty process scope container ID",
+			},
+		},
+		{
+			name: "Bad symbols count boundaries",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:         []string{"open"},
+				ReportSymbolsCount:     true,
+				SymbolsCountBoundaries: []int{0, 100, 50},
+			},
+			expectedProblems: []string{
+				"symbols count boundary 0 should be positive",
+				"symbols count boundaries should be increasing, got 50 after 100",
+			},
+		},
+		{
+			name: "Symbols count boundaries with no symbols count",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:         []string{"open"},
+				SymbolsCountBoundaries: []int{10, 100},
+			},
+			expectedProblems: []string{
+				"symbols count boundaries should have 3 entries, got 2",
+				"symbols count boundaries are configured, but the count isn't reported",
+			},
+		},
+		{
+			name: "Negative extraction deadline",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:     []string{"open"},
+				ExtractionDeadline: -time.Second,
+			},
+			expectedProblems: []string{"negative extraction deadline -1s"},
+		},
+		{
+			name: "Negative async queue size",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				AsyncQueueSize: -1,
+			},
+			expectedProblems: []string{"negative async queue size -1"},
+		},
+		{
+			name: "Bad exec mapping correlation",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:       []string{"open"},
+				CorrelateExecMapping: true,
+				ExecMappingTimeout:   -time.Second,
+				MaxPendingLoads:      -1,
+				AsyncQueueSize:       16,
+			},
+			expectedProblems: []string{
+				"negative exec mapping timeout -1s",
+				"negative maximal pending loads -1",
+				"exec mapping correlation can't be used in the asynchronous mode",
+			},
+		},
+		{
+			name: "Exec mapping timeout with no correlation",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:     []string{"open"},
+				ExecMappingTimeout: time.Second,
+			},
+			expectedProblems: []string{"exec mapping correlation settings are configured, but loads aren't correlated"},
+		},
+		{
+			name: "Bad expected symbol sizes",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:      []string{"open"},
+				ExpectedSymbolSizes: map[string]SymbolSizeRange{"": {Min: 1}, "open": {Min: 64, Max: 16}},
+			},
+			expectedProblems: []string{
+				"expected size range of an empty symbol",
+				"expected size range 64-16 of symbol 'open' is empty",
+			},
+		},
+		{
+			name: "Relative self test library",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:  []string{"open"},
+				SelfTestLibrary: "lib/libc.so.6",
+			},
+			expectedProblems: []string{"self test library 'lib/libc.so.6' should be an absolute path or a file name"},
+		},
+		{
+			name: "Bad library limited watched imports",
+			config: SymbolsLoadedConfig{
+				WatchedImports: []string{"!crypt", "/lib/libcrypt!crypt", "libc!memcpy@"},
+			},
+			expectedProblems: []string{
+				"watched import entry '!crypt' is missing its library or symbol",
+				"watched import entry '/lib/libcrypt!crypt' library should be a file name",
+				"watched import entry 'libc!memcpy@' should be '<symbol>@<version>'",
+			},
+		},
+		{
+			name: "Bad confidence settings",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:    []string{"open"},
+				ReportConfidence:  true,
+				ConfidenceScorer:  func(ConfidenceSignals) float64 { return 1 },
+				ConfidenceWeights: ConfidenceWeights{Bind: 1, Groups: -0.5},
+				MinConfidence:     1.5,
+			},
+			expectedProblems: []string{
+				"confidence weights are configured with a custom confidence scorer",
+				"negative groups confidence weight -0.5",
+				"minimal confidence 1.5 is not between 0 and 1",
+			},
+		},
+		{
+			name: "Minimal confidence with no confidence",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				MinConfidence:  0.5,
+			},
+			expectedProblems: []string{"confidence settings are configured, but the confidence isn't reported"},
+		},
+		{
+			name: "Negative load order processes",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:     []string{"open"},
+				ReportLoadOrder:    true,
+				LoadOrderProcesses: -1,
+			},
+			expectedProblems: []string{"negative load order processes -1"},
+		},
+		{
+			name: "Relative suspicious path",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:  []string{"open"},
+				SuspiciousPaths: []string{"/tmp", "tmp"},
+			},
+			expectedProblems: []string{"suspicious path 'tmp' should be an absolute path or $HOME"},
+		},
+		{
+			name: "Bad symbols hash configuration",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				SymbolsHash:    SymbolsHashOnly,
+				SymbolsHashKey: []byte("short"),
+			},
+			expectedProblems: []string{"symbols hash key should be at least 16 bytes"},
+		},
+		{
+			name: "Stop on first match with no watch groups",
+			config: SymbolsLoadedConfig{
+				WatchedSymbols:   []string{"open"},
+				StopOnFirstMatch: true,
+			},
+			expectedProblems: []string{"stop on first match is configured with no watch groups"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			problems := ValidateConfig(testCase.config)
+			var problemsMessages []string
+			for _, problem := range problems {
+				problemsMessages = append(problemsMessages, problem.Error())
+			}
+			assert.ElementsMatch(t, testCase.expectedProblems, problemsMessages)
+
+			_, err := InitSymbolsLoadedEventGenerator(initLoaderMock(), testCase.config)
+			if len(testCase.expectedProblems) > 0 {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestInitSymbolsLoadedEventGeneratorNothingWatched(t *testing.T) {
+	so := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libhook.so"},
+		syms:    []string{"open"},
+		soname:  "libhook.so",
+		buildID: "1e2a",
+	}
+	mockLoader := initLoaderMock()
+	mockLoader.addSOSymbols(so)
+	logger := &symbolsLoadedLoggerMock{}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{TrackBuildIDs: true, Logger: logger})
+	require.NoError(t, err)
+	require.NotEmpty(t, logger.entries)
+	assert.Equal(t, LogLevelWarn, logger.entries[0].Level)
+	assert.Equal(t, DecisionNothingWatched, logger.entries[0].Decision)
+
+	// symbols_loaded is never derived, but the events depending on the generator are
+	eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+	require.NoError(t, err)
+	assert.Nil(t, eventArgs)
+	eventArgs, err = gen.deriveBuildIDSeenArgs(generateSOLoadedEvent(1, so.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{so.info.Path, "libhook.so", "1e2a", ""}, eventArgs)
+}
+
+func TestSymbolsLoadedEventGenerator_DeriveBatch(t *testing.T) {
+	mockLoader := initLoaderMock()
+	var sos []soInstance
+	for i := 0; i < 20; i++ {
+		so := soInstance{
+			info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: uint64(i)}, Path: fmt.Sprintf("/tmp/%d.so", i)},
+		}
+		// Only even SOs export a watched symbol
+		if i%2 == 0 {
+			so.syms = []string{"open"}
+		} else {
+			so.syms = []string{"sync"}
+		}
+		mockLoader.addSOSymbols(so)
+		sos = append(sos, so)
+	}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols: []string{"open"},
+		BatchWorkers:   3,
+	})
+	require.NoError(t, err)
+
+	t.Run("Happy flow", func(t *testing.T) {
+		var batch []trace.Event
+		for _, so := range sos {
+			batch = append(batch, generateSOLoadedEvent(1, so.info))
+		}
+		results, err := gen.DeriveBatch(batch)
+		require.NoError(t, err)
+		require.Len(t, results, len(sos))
+		for i, result := range results {
+			if i%2 == 0 {
+				require.Len(t, result, 2)
+				assert.Equal(t, sos[i].info.Path, result[0])
+				assert.Equal(t, []string{"open"}, result[1])
+			} else {
+				assert.Nil(t, result)
+			}
+		}
+	})
+
+	t.Run("Partial failure", func(t *testing.T) {
+		batch := []trace.Event{
+			generateSOLoadedEvent(1, sos[0].info),
+			{EventName: "shared_object_loaded"},
+			generateSOLoadedEvent(1, sos[2].info),
+		}
+		results, err := gen.DeriveBatch(batch)
+		require.Error(t, err)
+		require.Len(t, results, 3)
+		assert.Equal(t, sos[0].info.Path, results[0][0])
+		assert.Nil(t, results[1])
+		assert.Equal(t, sos[2].info.Path, results[2][0])
+	})
+
+	t.Run("Empty batch", func(t *testing.T) {
+		results, err := gen.DeriveBatch(nil)
+		require.NoError(t, err)
+		assert.Len(t, results, 0)
+	})
+}
+
+func TestDeriveSharedObjectMatchingRules(t *testing.T) {
+	packedLoaderRule := SymbolsRule{
+		Name:      "packed_loader",
+		Predicate: AllOf(Imports("dlopen"), Exports("__libc_start_main")),
+	}
+	noDlsymRule := SymbolsRule{
+		Name:      "no_dlsym",
+		Predicate: AllOf(AnyOf(Imports("dlopen"), Imports("dlmopen")), Not(Imports("dlsym"))),
+	}
+	testCases := []struct {
+		name            string
+		loadingSO       soInstance
+		expectedSymbols []string
+		expectedRules   []string
+	}{
+		{
+			name: "Matching all rules",
+			loadingSO: soInstance{
+				info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"},
+				syms:        []string{"__libc_start_main"},
+				importsSyms: []string{"dlopen"},
+			},
+			expectedSymbols: []string{},
+			expectedRules:   []string{"packed_loader", "no_dlsym"},
+		},
+		{
+			name: "Matching one rule and watched symbols",
+			loadingSO: soInstance{
+				info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"},
+				syms:        []string{"__libc_start_main", "open"},
+				importsSyms: []string{"dlopen", "dlsym"},
+			},
+			expectedSymbols: []string{"open"},
+			expectedRules:   []string{"packed_loader"},
+		},
+		{
+			name: "Partly matching rules",
+			loadingSO: soInstance{
+				info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"},
+				syms:        []string{"dlopen"},
+				importsSyms: []string{"__libc_start_main", "dlsym"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			mockLoader.addSOSymbols(testCase.loadingSO)
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				Rules:          []SymbolsRule{packedLoaderRule, noDlsymRule},
+			})
+			require.NoError(t, err)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.loadingSO.info))
+			require.NoError(t, err)
+			if len(testCase.expectedRules) == 0 && len(testCase.expectedSymbols) == 0 {
+				assert.Len(t, eventArgs, 0)
+				return
+			}
+			require.Len(t, eventArgs, 3)
+			assert.ElementsMatch(t, testCase.expectedSymbols, eventArgs[1])
+			assert.ElementsMatch(t, testCase.expectedRules, eventArgs[2])
+		})
+	}
+}
+
+func TestDeriveSharedObjectUnreadable(t *testing.T) {
+	testCases := []struct {
+		name               string
+		loadErr            error
+		expectedLoadedErr  bool
+		expectedUnreadable bool
+	}{
+		{
+			name:               "Permission denied",
+			loadErr:            &fs.PathError{Op: "open", Path: "/tmp/1.so", Err: syscall.EACCES},
+			expectedUnreadable: true,
+		},
+		{
+			name:               "Operation not permitted",
+			loadErr:            &fs.PathError{Op: "open", Path: "/tmp/1.so", Err: syscall.EPERM},
+			expectedUnreadable: true,
+		},
+		{
+			name:              "Other error",
+			loadErr:           &fs.PathError{Op: "open", Path: "/tmp/1.so", Err: syscall.ENOENT},
+			expectedLoadedErr: true,
+		},
+		{
+			name: "Readable SO",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			so := soInstance{
+				info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"},
+				syms:    []string{"open"},
+				loadErr: testCase.loadErr,
+			}
+			mockLoader := initLoaderMock()
+			mockLoader.addSOSymbols(so)
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{WatchedSymbols: []string{"open"}})
+			require.NoError(t, err)
+			event := generateSOLoadedEvent(1, so.info)
+
+			loadedArgs, err := gen.deriveArgs(event)
+			if testCase.expectedLoadedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			if testCase.loadErr != nil {
+				assert.Nil(t, loadedArgs)
+			} else {
+				assert.Len(t, loadedArgs, 2)
+			}
+
+			unreadableArgs, err := gen.deriveUnreadableArgs(event)
+			require.NoError(t, err)
+			if testCase.expectedUnreadable {
+				require.Len(t, unreadableArgs, 2)
+				assert.Equal(t, so.info.Path, unreadableArgs[0])
+				assert.Equal(t, testCase.loadErr.Error(), unreadableArgs[1])
+			} else {
+				assert.Nil(t, unreadableArgs)
+			}
+		})
+	}
+}
+
+func TestDeriveSharedObjectUnreadableDetector(t *testing.T) {
+	soInfo := sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}
+	permissionErr := &fs.PathError{Op: "open", Path: soInfo.Path, Err: syscall.EACCES}
+	// The symbols of the SO are never extracted, as the loader classifies its permission failure
+	mockLoader := unreadableDetectorMock{
+		extractionFailingLoaderMock: extractionFailingLoaderMock{symbolsLoaderMock: initLoaderMock()},
+		errs:                        map[sharedobjs.ObjID]error{soInfo.Id: permissionErr},
+	}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{WatchedSymbols: []string{"open"}})
+	require.NoError(t, err)
+
+	unreadableArgs, err := gen.deriveUnreadableArgs(generateSOLoadedEvent(1, soInfo))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{soInfo.Path, permissionErr.Error()}, unreadableArgs)
+
+	readableInfo := sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/2.so"}
+	unreadableArgs, err = gen.deriveUnreadableArgs(generateSOLoadedEvent(1, readableInfo))
+	require.NoError(t, err)
+	assert.Nil(t, unreadableArgs)
+}
+
+type unreadableDetectorMock struct {
+	extractionFailingLoaderMock
+	errs map[sharedobjs.ObjID]error
+}
+
+func (loader unreadableDetectorMock) GetPermissionError(info sharedobjs.ObjInfo) error {
+	return loader.errs[info.Id]
+}
+
+func TestDeriveSharedObjectPacked(t *testing.T) {
+	testCases := []struct {
+		name           string
+		so             soInstance
+		expectedPacker string
+	}{
+		{
+			name:           "Packed SO",
+			so:             soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}, packer: "UPX"},
+			expectedPacker: "UPX",
+		},
+		{
+			name: "Whitelisted packed SO",
+			so:   soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/1.so"}, packer: "UPX"},
+		},
+		{
+			name: "Not packed SO",
+			so:   soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}, syms: []string{"open"}},
+		},
+		{
+			name: "Unreadable SO",
+			so: soInstance{
+				info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"},
+				loadErr: &fs.PathError{Op: "open", Path: "/tmp/1.so", Err: syscall.EACCES},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			mockLoader.addSOSymbols(testCase.so)
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+				WatchedSymbols:  []string{"open"},
+				WhitelistedLibs: []string{"/usr/lib"},
+			})
+			require.NoError(t, err)
+			eventArgs, err := gen.derivePackedArgs(generateSOLoadedEvent(1, testCase.so.info))
+			require.NoError(t, err)
+			if testCase.expectedPacker == "" {
+				assert.Nil(t, eventArgs)
+				return
+			}
+			assert.Equal(t, []interface{}{testCase.so.info.Path, testCase.expectedPacker}, eventArgs)
+		})
+	}
+}
+
+func TestDeriveSharedObjectSlowExtraction(t *testing.T) {
+	testCases := []struct {
+		name           string
+		so             soInstance
+		threshold      time.Duration
+		expectedDerive bool
+	}{
+		{
+			name:           "Slow extraction with default threshold",
+			so:             soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}, extraction: time.Second},
+			expectedDerive: true,
+		},
+		{
+			name: "Fast extraction with default threshold",
+			so:   soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}, extraction: time.Millisecond},
+		},
+		{
+			name:           "Slow extraction with configured threshold",
+			so:             soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}, extraction: time.Millisecond},
+			threshold:      time.Microsecond,
+			expectedDerive: true,
+		},
+		{
+			name: "Whitelisted slow extraction",
+			so:   soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/1.so"}, extraction: time.Second},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			mockLoader.addSOSymbols(testCase.so)
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+				WatchedSymbols:          []string{"open"},
+				WhitelistedLibs:         []string{"/usr/lib"},
+				SlowExtractionThreshold: testCase.threshold,
+			})
+			require.NoError(t, err)
+			event := generateSOLoadedEvent(1, testCase.so.info)
+			eventArgs, err := gen.deriveSlowExtractionArgs(event)
+			require.NoError(t, err)
+			if !testCase.expectedDerive {
+				assert.Nil(t, eventArgs)
+				return
+			}
+			assert.Equal(t, []interface{}{testCase.so.info.Path, uint64(testCase.so.extraction.Nanoseconds())}, eventArgs)
+
+			// The extraction is reported only once
+			eventArgs, err = gen.deriveSlowExtractionArgs(event)
+			require.NoError(t, err)
+			assert.Nil(t, eventArgs)
+		})
+	}
+}
+
+func TestDeriveSharedObjectChanges(t *testing.T) {
+	so := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"},
+		syms: []string{"open", "write"},
+	}
+	swappedSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/1.so"},
+		syms: []string{"open"},
+	}
+	otherSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/2.so"},
+		syms: []string{"open"},
+	}
+	emptySO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/tmp/1.so"},
+		syms: []string{"close"},
+	}
+	type load struct {
+		pid             int
+		so              soInstance
+		exitedBefore    bool
+		expectedChanged bool
+		expectedNil     bool
+	}
+	loads := []load{
+		{pid: 1, so: so, expectedChanged: true},
+		{pid: 1, so: so, expectedChanged: false},
+		{pid: 2, so: so, expectedChanged: true},
+		{pid: 1, so: swappedSO, expectedChanged: true},
+		{pid: 1, so: so, expectedChanged: true},
+		{pid: 1, so: emptySO, expectedNil: true},
+		{pid: 1, so: so, expectedChanged: true},
+		{pid: 1, so: so, exitedBefore: true, expectedChanged: true},
+	}
+
+	t.Run("Reported", func(t *testing.T) {
+		mockLoader := initLoaderMock()
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols: []string{"open", "write"},
+			ReportChanges:  true,
+		})
+		require.NoError(t, err)
+		for i, l := range loads {
+			if l.exitedBefore {
+				gen.ProcessExited(l.pid)
+			}
+			mockLoader.addSOSymbols(l.so)
+			event := generateSOLoadedEvent(l.pid, l.so.info)
+			eventArgs, err := gen.deriveArgs(event)
+			require.NoError(t, err)
+			if l.expectedNil {
+				assert.Nil(t, eventArgs, i)
+				continue
+			}
+			require.Len(t, eventArgs, 3, i)
+			assert.Equal(t, l.expectedChanged, eventArgs[2], i)
+		}
+	})
+
+	t.Run("Suppressed", func(t *testing.T) {
+		mockLoader := initLoaderMock()
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols:    []string{"open", "write"},
+			SuppressUnchanged: true,
+		})
+		require.NoError(t, err)
+		for i, l := range loads {
+			if l.exitedBefore {
+				gen.ProcessExited(l.pid)
+			}
+			mockLoader.addSOSymbols(l.so)
+			event := generateSOLoadedEvent(l.pid, l.so.info)
+			eventArgs, err := gen.deriveArgs(event)
+			require.NoError(t, err)
+			if l.expectedNil || !l.expectedChanged {
+				assert.Nil(t, eventArgs, i)
+			} else {
+				assert.Len(t, eventArgs, 2, i)
+			}
+		}
+	})
+
+	t.Run("Bounded history", func(t *testing.T) {
+		mockLoader := initLoaderMock()
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols:   []string{"open", "write"},
+			ReportChanges:    true,
+			MatchHistorySize: 1,
+		})
+		require.NoError(t, err)
+		for _, l := range []load{
+			{pid: 1, so: so, expectedChanged: true},
+			{pid: 1, so: otherSO, expectedChanged: true},
+			// The first SO was evicted by the second
+			{pid: 1, so: so, expectedChanged: true},
+			{pid: 1, so: so, expectedChanged: false},
+		} {
+			mockLoader.addSOSymbols(l.so)
+			event := generateSOLoadedEvent(l.pid, l.so.info)
+			eventArgs, err := gen.deriveArgs(event)
+			require.NoError(t, err)
+			require.Len(t, eventArgs, 3)
+			assert.Equal(t, l.expectedChanged, eventArgs[2])
+		}
+		assert.Len(t, gen.history.processes[1], 1)
+		gen.ProcessExited(1)
+		assert.Empty(t, gen.history.processes)
+		assert.Equal(t, 0, gen.history.lastMatch.Len())
+	})
+}
+
+func TestDeriveSharedObjectSummary(t *testing.T) {
+	sos := []soInstance{
+		{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}, syms: []string{"open", "write"}},
+		{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/2.so"}, syms: []string{"open"}},
+		{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/3.so"}, syms: []string{"close"}},
+	}
+	loads := []struct {
+		pid int
+		so  soInstance
+	}{
+		{pid: 10, so: sos[0]},
+		{pid: 20, so: sos[0]},
+		{pid: 20, so: sos[1]},
+		{pid: 30, so: sos[2]},
+	}
+	testCases := []struct {
+		name              string
+		key               SummaryKey
+		maxEntries        int
+		expectedEntries   []string
+		expectedTruncated bool
+	}{
+		{
+			name:            "By symbol",
+			key:             SummaryBySymbol,
+			expectedEntries: []string{"open: 10,20", "write: 10,20"},
+		},
+		{
+			name:            "By process",
+			key:             SummaryByProcess,
+			expectedEntries: []string{"10: open,write", "20: open,write"},
+		},
+		{
+			name:              "Bounded",
+			key:               SummaryByProcess,
+			maxEntries:        3,
+			expectedEntries:   []string{"10: open,write", "20: open"},
+			expectedTruncated: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+				WatchedSymbols:    []string{"open", "write"},
+				SummaryInterval:   time.Hour,
+				SummaryKey:        testCase.key,
+				MaxSummaryEntries: testCase.maxEntries,
+			})
+			require.NoError(t, err)
+			defer gen.Close()
+			for _, l := range loads {
+				mockLoader.addSOSymbols(l.so)
+				_, err := gen.deriveArgs(generateSOLoadedEvent(l.pid, l.so.info))
+				require.NoError(t, err)
+			}
+			now := time.Now()
+			gen.emitSummary(now)
+			summary := <-gen.Summaries()
+			assert.Equal(t, "symbols_loaded_summary", summary.EventName)
+			assert.Equal(t, int(now.UnixNano()), summary.Timestamp)
+			assert.Equal(t, []interface{}{testCase.key.String(), testCase.expectedEntries, 3, testCase.expectedTruncated},
+				argsValues(summary))
+
+			// Nothing is emitted for an interval with no matches
+			gen.emitSummary(now)
+			assert.Len(t, gen.Summaries(), 0)
+		})
+	}
+}
+
+func TestSymbolsLoadedEventGenerator_SummaryTimer(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mockLoader := initLoaderMock()
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:  []string{"open"},
+		SummaryInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	so := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}, syms: []string{"open"}}
+	mockLoader.addSOSymbols(so)
+	_, err = gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+	require.NoError(t, err)
+
+	select {
+	case summary := <-gen.Summaries():
+		assert.Equal(t, []interface{}{"symbol", []string{"open: 1"}, 1, false}, argsValues(summary))
+	case <-time.After(5 * time.Second):
+		t.Fatal("no summary emitted")
+	}
+
+	require.NoError(t, gen.Close())
+	_, open := <-gen.Summaries()
+	assert.False(t, open)
+
+	// Summaries are not configured by default
+	gen, err = InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{WatchedSymbols: []string{"open"}})
+	require.NoError(t, err)
+	assert.Nil(t, gen.Summaries())
+	require.NoError(t, gen.Close())
+}
+
+// countingLoaderMock counts the calls to the loading methods of the loader mock
+type countingLoaderMock struct {
+	symbolsLoaderMock
+	calls map[string]int
+}
+
+func (loader countingLoaderMock) GetExportedSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
+	loader.calls["exported"]++
+	return loader.symbolsLoaderMock.GetExportedSymbols(info)
+}
+
+func (loader countingLoaderMock) GetImportedSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
+	loader.calls["imported"]++
+	return loader.symbolsLoaderMock.GetImportedSymbols(info)
+}
+
+func (loader countingLoaderMock) GetExportedSymbolsInfo(info sharedobjs.ObjInfo) (map[string]sharedobjs.SymbolInfo, error) {
+	loader.calls["exported-info"]++
+	return loader.symbolsLoaderMock.GetExportedSymbolsInfo(info)
+}
+
+func TestSymbolsLoadedCompositeGenerator(t *testing.T) {
+	mockLoader := countingLoaderMock{symbolsLoaderMock: initLoaderMock(), calls: make(map[string]int)}
+	composite, err := InitSymbolsLoadedCompositeGenerator(mockLoader, []SymbolsLoadedConfig{
+		{WatchedSymbols: []string{"open"}},
+		{WatchedSymbols: []string{"write"}, WhitelistedLibs: []string{"/tmp/"}},
+		{WatchedSymbols: []string{"open", "close"}, ReportVisibility: true},
+		{Rules: []SymbolsRule{{Name: "open-without-close", Predicate: AllOf(Exports("open"), Not(Exports("close")))}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, composite.Generators(), 4)
+	deriveFunc := SymbolsLoadedComposite(composite)
+
+	so := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"},
+		syms: []string{"open", "write"},
+	}
+	mockLoader.addSOSymbols(so)
+	derived, errs := deriveFunc(generateSOLoadedEvent(1, so.info))
+	require.Empty(t, errs)
+	require.Len(t, derived, 3)
+	assert.Equal(t, []interface{}{so.info.Path, []string{"open"}}, argsValues(derived[0]))
+	assert.Equal(t, []interface{}{so.info.Path, []string{"open"}, []string{"STV_DEFAULT"}}, argsValues(derived[1]))
+	assert.Equal(t, []interface{}{so.info.Path, []string(nil), []string{"open-without-close"}}, argsValues(derived[2]))
+	// The symbols of the SO are loaded once for all the configurations
+	assert.Equal(t, map[string]int{"exported": 1, "imported": 1, "exported-info": 1}, mockLoader.calls)
+
+	// Each event loads the symbols again
+	derived, errs = deriveFunc(generateSOLoadedEvent(2, so.info))
+	require.Empty(t, errs)
+	assert.Len(t, derived, 3)
+	assert.Equal(t, map[string]int{"exported": 2, "imported": 2, "exported-info": 2}, mockLoader.calls)
+
+	// The errors of each configuration are returned
+	failingSO := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/usr/lib/2.so"},
+		loadErr: errors.New("loading failed"),
+	}
+	mockLoader.addSOSymbols(failingSO)
+	derived, errs = deriveFunc(generateSOLoadedEvent(1, failingSO.info))
+	assert.Empty(t, derived)
+	assert.Len(t, errs, 4)
+
+	require.NoError(t, composite.Close())
+	derived, errs = deriveFunc(generateSOLoadedEvent(1, so.info))
+	assert.Empty(t, derived)
+	assert.Empty(t, errs)
+}
+
+// argsValues returns the values of the arguments of the event
+func argsValues(event trace.Event) []interface{} {
+	values := make([]interface{}, len(event.Args))
+	for i, arg := range event.Args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+func TestInitSymbolsLoadedCompositeGenerator_InvalidConfig(t *testing.T) {
+	_, err := InitSymbolsLoadedCompositeGenerator(initLoaderMock(), nil)
+	assert.Error(t, err)
+	_, err = InitSymbolsLoadedCompositeGenerator(initLoaderMock(), []SymbolsLoadedConfig{
+		{WatchedSymbols: []string{"open"}},
+		{WatchedSymbols: []string{"open", ""}},
+	})
+	assert.ErrorContains(t, err, "configuration 1")
+}
+
+func TestSymbolsLoadedCompositeGenerator_MemberModes(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	const protRead, protReadExec = int32(0x1), int32(0x5)
+	fastSO := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/fast.so"}, syms: []string{"open"}}
+	heldSO := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/held.so"}, syms: []string{"open"}}
+	slowSO := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/slow.so"}, syms: []string{"open"}}
+	mockLoader := slowLoaderMock{
+		symbolsLoaderMock: initLoaderMock(),
+		slow:              map[sharedobjs.ObjID]bool{slowSO.info.Id: true},
+		delay:             300 * time.Millisecond,
+	}
+	for _, so := range []soInstance{fastSO, heldSO, slowSO} {
+		mockLoader.addSOSymbols(so)
+	}
+	composite, err := InitSymbolsLoadedCompositeGenerator(mockLoader, []SymbolsLoadedConfig{
+		{WatchedSymbols: []string{"open"}, ExtractionDeadline: 30 * time.Millisecond},
+		{WatchedSymbols: []string{"open"}, AsyncQueueSize: 2},
+		{WatchedSymbols: []string{"open"}, CorrelateExecMapping: true, ExecMappingTimeout: time.Minute},
+	})
+	require.NoError(t, err)
+	deriveLoad, deriveMapping := SymbolsLoadedComposite(composite), SymbolsLoadedCompositeExecMapping(composite)
+
+	// The asynchronous configuration derives its event in the background
+	derived, errs := deriveLoad(generateSOLoadedEvent(1, fastSO.info))
+	require.Empty(t, errs)
+	require.Len(t, derived, 2)
+	assert.Equal(t, []interface{}{fastSO.info.Path, []string{"open"}}, argsValues(derived[0]))
+	assert.Equal(t, []interface{}{fastSO.info.Path, []string{"open"}, true}, argsValues(derived[1]))
+	asyncEvent := <-composite.Generators()[1].AsyncEvents()
+	assert.Equal(t, []interface{}{fastSO.info.Path, []string{"open"}}, argsValues(asyncEvent))
+
+	// The correlating configuration holds readable mappings until they are made executable
+	derived, errs = deriveMapping(generateMmapEvent(1, heldSO.info, protRead))
+	require.Empty(t, errs)
+	require.Empty(t, derived)
+	derived, errs = deriveMapping(generateMprotectEvent(1, heldSO.info, protReadExec))
+	require.Empty(t, errs)
+	require.Len(t, derived, 1)
+	assert.Equal(t, []interface{}{heldSO.info.Path, []string{"open"}, true}, argsValues(derived[0]))
+
+	// The configuration with a deadline abandons the slow SO, while the others wait for it
+	derived, errs = deriveLoad(generateSOLoadedEvent(1, slowSO.info))
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], ErrExtractionTimeout)
+	require.Len(t, derived, 1)
+	assert.Equal(t, []interface{}{slowSO.info.Path, []string{"open"}, true}, argsValues(derived[0]))
+
+	require.NoError(t, composite.Close())
+}
+
+func TestSymbolsLoadedCompositeGenerator_MetadataOnlyMember(t *testing.T) {
+	mockLoader := countingLoaderMock{symbolsLoaderMock: initLoaderMock(), calls: make(map[string]int)}
+	so := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libevil.so"},
+		syms:        []string{"open"},
+		soname:      "libevil.so.1",
+		buildID:     "0123abcd",
+		dynamicTags: []elf.DynTag{elf.DT_NEEDED, elf.DT_AUDIT},
+	}
+	mockLoader.addSOSymbols(so)
+	composite, err := InitSymbolsLoadedCompositeGenerator(mockLoader, []SymbolsLoadedConfig{
+		{MetadataOnly: true, FlaggedDynamicTags: DefaultFlaggedDynamicTags},
+	})
+	require.NoError(t, err)
+	deriveFunc := SymbolsLoadedComposite(composite)
+
+	// The member in the metadata only mode reads the SO through its metadata only
+	derived, errs := deriveFunc(generateSOLoadedEvent(1, so.info))
+	require.Empty(t, errs)
+	require.Len(t, derived, 1)
+	assert.Equal(t, []interface{}{so.info.Path, []string(nil), []string{"DT_AUDIT"}, true, "libevil.so.1", "0123abcd"},
+		argsValues(derived[0]))
+	assert.Empty(t, mockLoader.calls)
+
+	// The symbols are extracted for the other members only
+	composite, err = InitSymbolsLoadedCompositeGenerator(mockLoader, []SymbolsLoadedConfig{
+		{MetadataOnly: true, FlaggedDynamicTags: DefaultFlaggedDynamicTags},
+		{WatchedSymbols: []string{"open"}},
+	})
+	require.NoError(t, err)
+	derived, errs = SymbolsLoadedComposite(composite)(generateSOLoadedEvent(1, so.info))
+	require.Empty(t, errs)
+	require.Len(t, derived, 2)
+	assert.Equal(t, []interface{}{so.info.Path, []string{"open"}}, argsValues(derived[1]))
+	assert.Equal(t, map[string]int{"exported": 1}, mockLoader.calls)
+}
+
+type symbolsLoadedLoggerMock struct {
+	entries []SymbolsLoadedLogEntry
+}
+
+func (logger *symbolsLoadedLoggerMock) Log(entry SymbolsLoadedLogEntry) {
+	logger.entries = append(logger.entries, entry)
+}
+
+func TestDeriveSharedObjectLogging(t *testing.T) {
+	testCases := []struct {
+		name             string
+		so               soInstance
+		expectedLevel    LogLevel
+		expectedDecision string
+	}{
+		{
+			name:             "Whitelisted",
+			so:               soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/1.so"}, syms: []string{"open"}},
+			expectedLevel:    LogLevelDebug,
+			expectedDecision: DecisionWhitelisted,
+		},
+		{
+			name: "Not ELF",
+			so: soInstance{
+				info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"},
+				loadErr: &elf.FormatError{},
+			},
+			expectedLevel:    LogLevelDebug,
+			expectedDecision: DecisionNotELF,
+		},
+		{
+			name:             "No symbols",
+			so:               soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}, syms: []string{"close"}},
+			expectedLevel:    LogLevelDebug,
+			expectedDecision: DecisionNoSymbols,
+		},
+		{
+			name: "Unreadable",
+			so: soInstance{
+				info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"},
+				loadErr: &fs.PathError{Op: "open", Path: "/tmp/1.so", Err: syscall.EACCES},
+			},
+			expectedLevel:    LogLevelWarn,
+			expectedDecision: DecisionUnreadable,
+		},
+		{
+			name:             "Matched",
+			so:               soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}, syms: []string{"open"}},
+			expectedLevel:    LogLevelInfo,
+			expectedDecision: DecisionMatched,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			mockLoader.addSOSymbols(testCase.so)
+			logger := &symbolsLoadedLoggerMock{}
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+				WatchedSymbols:  []string{"open"},
+				WhitelistedLibs: []string{"/usr/lib"},
+				Logger:          logger,
+			})
+			require.NoError(t, err)
+
+			_, _ = gen.deriveArgs(generateSOLoadedEvent(1, testCase.so.info))
+			require.Len(t, logger.entries, 1)
+			assert.Equal(t, testCase.expectedLevel, logger.entries[0].Level)
+			assert.Equal(t, testCase.expectedDecision, logger.entries[0].Decision)
+			assert.Equal(t, testCase.so.info.Path, logger.entries[0].ObjInfo.Path)
+		})
+	}
+}
+
+func TestWriterSymbolsLoadedLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWriterSymbolsLoadedLogger(&buf, LogLevelInfo)
+	objInfo := sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 5}, Path: "/tmp/1.so", MountNS: 3}
+	logger.Log(SymbolsLoadedLogEntry{Level: LogLevelDebug, Decision: DecisionNoSymbols, ObjInfo: objInfo})
+	logger.Log(SymbolsLoadedLogEntry{Level: LogLevelWarn, Decision: DecisionUnreadable, ObjInfo: objInfo, Reason: "permission denied"})
+	assert.Equal(t,
+		"level=warn decision=unreadable path=\"/tmp/1.so\" inode=5 mount_ns=3 reason=\"permission denied\"\n",
+		buf.String())
+}
+
+type closingLoaderMock struct {
+	symbolsLoaderMock
+	closes int
+}
+
+func (loader *closingLoaderMock) Close() error {
+	loader.closes++
+	return nil
+}
+
+func TestSymbolsLoadedEventGenerator_Close(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("Close generator", func(t *testing.T) {
+		so := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}, syms: []string{"open"}}
+		mockLoader := &closingLoaderMock{symbolsLoaderMock: initLoaderMock()}
+		mockLoader.addSOSymbols(so)
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols: []string{"open"},
+			BatchWorkers:   4,
+		})
+		require.NoError(t, err)
+		events := make([]trace.Event, 10)
+		for i := range events {
+			events[i] = generateSOLoadedEvent(i, so.info)
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			results, err := gen.DeriveBatch(events)
+			assert.NoError(t, err)
+			// Derivations either completed before the generator was closed, or didn't derive the event
+			for _, result := range results {
+				if result != nil {
+					assert.Len(t, result, 2)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, gen.Close())
+		}()
+		wg.Wait()
+
+		require.NoError(t, gen.Close())
+		assert.Equal(t, 1, mockLoader.closes)
+		eventArgs, err := gen.deriveArgs(events[0])
+		assert.NoError(t, err)
+		assert.Nil(t, eventArgs)
+	})
+
+	t.Run("Zero value generator", func(t *testing.T) {
+		var gen SymbolsLoadedEventGenerator
+		so := sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}
+		eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so))
+		assert.NoError(t, err)
+		assert.Nil(t, eventArgs)
+		results, err := gen.DeriveBatch([]trace.Event{generateSOLoadedEvent(1, so)})
+		assert.NoError(t, err)
+		assert.Equal(t, [][]interface{}{nil}, results)
+		assert.NoError(t, gen.Close())
+	})
+}
+
+func TestDeriveSharedObjectBaseline(t *testing.T) {
+	genuineSO := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/libssl.so.3"},
+		syms:   []string{"SSL_read", "SSL_write"},
+		soname: "libssl.so.3",
+	}
+	trojanizedSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libssl.so.3"},
+		symsInfo: []sharedobjs.SymbolInfo{
+			{Name: "SSL_read", Visibility: elf.STV_DEFAULT},
+			{Name: "SSL_write", Visibility: elf.STV_DEFAULT},
+			{Name: "SSL_keylog", Visibility: elf.STV_HIDDEN},
+		},
+		soname: "libssl.so.3",
+	}
+	noBaselineSO := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/usr/lib/libcrypto.so.3"},
+		syms:   []string{"SSL_read"},
+		soname: "libcrypto.so.3",
+	}
+	noSonameSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/tmp/libevil.so"},
+		syms: []string{"SSL_write"},
+	}
+	baselines := map[string][]string{"libssl.so.3": {"SSL_read", "SSL_write", "SSL_new"}}
+
+	t.Run("Matches", func(t *testing.T) {
+		mockLoader := initLoaderMock()
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols:   []string{"SSL_read", "SSL_write", "SSL_keylog"},
+			BaselineSymbols:  baselines,
+			ReportVisibility: true,
+		})
+		require.NoError(t, err)
+		expected := map[string][]interface{}{
+			trojanizedSO.info.Path: {trojanizedSO.info.Path, []string{"SSL_keylog"}, []string{"STV_HIDDEN"}},
+			noBaselineSO.info.Path: {noBaselineSO.info.Path, []string{"SSL_read"}, []string{"STV_DEFAULT"}},
+			noSonameSO.info.Path:   {noSonameSO.info.Path, []string{"SSL_write"}, []string{"STV_DEFAULT"}},
+		}
+		for _, so := range []soInstance{genuineSO, trojanizedSO, noBaselineSO, noSonameSO} {
+			mockLoader.addSOSymbols(so)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+			require.NoError(t, err)
+			if expected[so.info.Path] == nil {
+				assert.Nil(t, eventArgs, so.info.Path)
+				continue
+			}
+			assert.Equal(t, expected[so.info.Path], eventArgs, so.info.Path)
+		}
+	})
+
+	t.Run("Loading error", func(t *testing.T) {
+		mockLoader := initLoaderMock()
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols:  []string{"SSL_read"},
+			BaselineSymbols: baselines,
+		})
+		require.NoError(t, err)
+		so := genuineSO
+		so.loadErr = errors.New("read failure")
+		mockLoader.addSOSymbols(so)
+		_, err = gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid baselines", func(t *testing.T) {
+		problems := ValidateConfig(SymbolsLoadedConfig{
+			WatchedSymbols:  []string{"SSL_read"},
+			BaselineSymbols: map[string][]string{"": {"SSL_read"}, "libssl.so.3": {""}},
+		})
+		assert.Len(t, problems, 2)
+	})
+
+}
+
+func TestBaselineSymbolsFromObjects(t *testing.T) {
+	_, err := BaselineSymbolsFromObjects([]string{"testdata/missing.so"})
+	assert.ErrorContains(t, err, "failed to read baseline object")
+}
+
+func TestNewTypedArgument(t *testing.T) {
+	testCases := []struct {
+		name          string
+		meta          trace.ArgMeta
+		value         interface{}
+		expectedValue interface{}
+		expectedError string
+	}{
+		{
+			name:          "Symbols",
+			meta:          trace.ArgMeta{Type: "const char*const*", Name: "symbols"},
+			value:         []string{"open"},
+			expectedValue: []string{"open"},
+		},
+		{
+			name:          "Untyped nil symbols",
+			meta:          trace.ArgMeta{Type: "const char*const*", Name: "symbols"},
+			value:         nil,
+			expectedValue: []string(nil),
+		},
+		{
+			name:          "Addresses",
+			meta:          trace.ArgMeta{Type: "unsigned long[]", Name: "addresses"},
+			value:         []uint64{0x1000},
+			expectedValue: []uint64{0x1000},
+		},
+		{
+			name:          "Wrong slice type",
+			meta:          trace.ArgMeta{Type: "unsigned long[]", Name: "addresses"},
+			value:         []int{0x1000},
+			expectedError: "argument 'addresses' of type 'unsigned long[]' has a value of type []int instead of []uint64",
+		},
+		{
+			name:          "Untyped nil scalar",
+			meta:          trace.ArgMeta{Type: "bool", Name: "truncated"},
+			value:         nil,
+			expectedError: "argument 'truncated' has no value",
+		},
+		{
+			name:          "Unsupported type",
+			meta:          trace.ArgMeta{Type: "struct sockaddr*", Name: "addr"},
+			value:         "127.0.0.1",
+			expectedError: "argument 'addr' type 'struct sockaddr*' is not supported",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			arg, err := newTypedArgument(testCase.meta, testCase.value)
+			if testCase.expectedError != "" {
+				assert.EqualError(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.meta, arg.ArgMeta)
+			assert.Equal(t, testCase.expectedValue, arg.Value)
+		})
+	}
+}
+
+func TestSymbolsLoadedTypedEvent(t *testing.T) {
+	mockLoader := initLoaderMock()
+	so := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libevil.so"}, syms: []string{"open"}}
+	mockLoader.addSOSymbols(so)
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:     []string{"open"},
+		MaxSymbolsPerEvent: 10,
+	})
+	require.NoError(t, err)
+
+	derived, errs := SymbolsLoadedFromGenerator(gen)(generateSOLoadedEvent(1, so.info))
+	require.Empty(t, errs)
+	require.Len(t, derived, 1)
+	args := make(map[string]trace.Argument, len(derived[0].Args))
+	for _, arg := range derived[0].Args {
+		args[arg.Name] = arg
+	}
+	assert.Equal(t, trace.Argument{ArgMeta: trace.ArgMeta{Type: "const char*", Name: "library_path"}, Value: so.info.Path},
+		args["library_path"])
+	assert.Equal(t, trace.Argument{ArgMeta: trace.ArgMeta{Type: "const char*const*", Name: "symbols"}, Value: []string{"open"}},
+		args["symbols"])
+	assert.Equal(t, trace.Argument{ArgMeta: trace.ArgMeta{Type: "bool", Name: "truncated"}, Value: false},
+		args["truncated"])
+	assert.Equal(t, trace.Argument{ArgMeta: trace.ArgMeta{Type: "int", Name: "symbols_count"}, Value: 1},
+		args["symbols_count"])
+
+	// Values which don't match the types of their arguments are not derived
+	gen.addExtraArg(trace.ArgMeta{Type: "int", Name: "wrong"}, func(match *symbolsMatch) interface{} {
+		return "not an int"
+	})
+	derived, errs = SymbolsLoadedFromGenerator(gen)(generateSOLoadedEvent(1, so.info))
+	assert.Empty(t, derived)
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "argument 'wrong' of type 'int' has a value of type string instead of int")
+}
+
+func TestSymbolsLoaded(t *testing.T) {
+	mockLoader := initLoaderMock()
+	watchedSO := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libevil.so"}, syms: []string{"open"}}
+	whitelistedSO := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/usr/lib/libc.so"}, syms: []string{"open"}}
+	mockLoader.addSOSymbols(watchedSO)
+	mockLoader.addSOSymbols(whitelistedSO)
+
+	deriveFunc := SymbolsLoaded(mockLoader, []string{"open"}, []string{"/usr/lib"})
+	derived, errs := deriveFunc(generateSOLoadedEvent(1, watchedSO.info))
+	require.Empty(t, errs)
+	require.Len(t, derived, 1)
+	derived, errs = deriveFunc(generateSOLoadedEvent(1, whitelistedSO.info))
+	require.Empty(t, errs)
+	assert.Empty(t, derived)
+
+	// The configuration errors are returned by the derive function
+	derived, errs = SymbolsLoaded(mockLoader, []string{"open"}, []string{""})(generateSOLoadedEvent(1, watchedSO.info))
+	assert.Empty(t, derived)
+	assert.NotEmpty(t, errs)
+}
+
+func TestDeriveSharedObjectTrustedNote(t *testing.T) {
+	trustedNote := sharedobjs.NoteID{Name: "tracee", Type: 1}
+	trustedSO := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/opt/inhouse/libtrusted.so"},
+		syms:   []string{"open"},
+		notes:  []sharedobjs.NoteID{{Name: "GNU", Type: 3}, trustedNote},
+		packer: "UPX",
+	}
+	otherNoteSO := soInstance{
+		info:  sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libevil.so"},
+		syms:  []string{"open"},
+		notes: []sharedobjs.NoteID{{Name: "tracee", Type: 2}},
+	}
+	unreadableSO := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libbroken.so"},
+		loadErr: errors.New("read failure"),
+	}
+
+	mockLoader := initLoaderMock()
+	logger := &symbolsLoadedLoggerMock{}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols: []string{"open"},
+		TrustedNote:    trustedNote,
+		Logger:         logger,
+	})
+	require.NoError(t, err)
+
+	mockLoader.addSOSymbols(trustedSO)
+	eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, trustedSO.info))
+	require.NoError(t, err)
+	assert.Nil(t, eventArgs)
+	// Trusted SOs are whitelisted for the other events of the configuration too
+	packedArgs, err := gen.derivePackedArgs(generateSOLoadedEvent(1, trustedSO.info))
+	require.NoError(t, err)
+	assert.Nil(t, packedArgs)
+
+	mockLoader.addSOSymbols(otherNoteSO)
+	eventArgs, err = gen.deriveArgs(generateSOLoadedEvent(1, otherNoteSO.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{otherNoteSO.info.Path, []string{"open"}}, eventArgs)
+
+	mockLoader.addSOSymbols(unreadableSO)
+	_, err = gen.deriveArgs(generateSOLoadedEvent(1, unreadableSO.info))
+	assert.Error(t, err)
+
+	var decisions []string
+	for _, entry := range logger.entries {
+		decisions = append(decisions, entry.Decision)
+	}
+	assert.Equal(t, []string{DecisionTrusted, DecisionMatched, DecisionFailed}, decisions)
+}
+
+func TestDeriveSharedObjectMissingSymbols(t *testing.T) {
+	expectedSymbols := map[string][]string{"libssl.so.3": {"SSL_new", "SSL_read", "SSL_write"}}
+	genuineSO := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/libssl.so.3"},
+		syms:   []string{"SSL_new", "SSL_read", "SSL_write", "SSL_free"},
+		soname: "libssl.so.3",
+	}
+	hollowedSO := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libssl.so.3"},
+		syms:   []string{"SSL_read"},
+		soname: "libssl.so.3",
+	}
+	stubSO := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/stub/libssl.so.3"},
+		syms:   []string{"open"},
+		soname: "libssl.so.3",
+	}
+	otherSonameSO := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/usr/lib/libcrypto.so.3"},
+		syms:   []string{"EVP_EncryptInit"},
+		soname: "libcrypto.so.3",
+	}
+	noSonameSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 5}, Path: "/tmp/libevil.so"},
+		syms: []string{"SSL_read"},
+	}
+
+	testCases := []struct {
+		name           string
+		watchedSymbols []string
+		expectedArgs   map[string][]interface{}
+	}{
+		{
+			name: "Only expected symbols",
+			expectedArgs: map[string][]interface{}{
+				hollowedSO.info.Path: {hollowedSO.info.Path, []string(nil), []string{"SSL_new", "SSL_write"}},
+				stubSO.info.Path:     {stubSO.info.Path, []string(nil), []string{"SSL_new", "SSL_read", "SSL_write"}},
+			},
+		},
+		{
+			name:           "With watched symbols",
+			watchedSymbols: []string{"open", "EVP_EncryptInit"},
+			expectedArgs: map[string][]interface{}{
+				hollowedSO.info.Path:    {hollowedSO.info.Path, []string(nil), []string{"SSL_new", "SSL_write"}},
+				stubSO.info.Path:        {stubSO.info.Path, []string{"open"}, []string{"SSL_new", "SSL_read", "SSL_write"}},
+				otherSonameSO.info.Path: {otherSonameSO.info.Path, []string{"EVP_EncryptInit"}, []string(nil)},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+				WatchedSymbols:  testCase.watchedSymbols,
+				ExpectedSymbols: expectedSymbols,
+			})
+			require.NoError(t, err)
+			for _, so := range []soInstance{genuineSO, hollowedSO, stubSO, otherSonameSO, noSonameSO} {
+				mockLoader.addSOSymbols(so)
+				eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+				require.NoError(t, err)
+				if testCase.expectedArgs[so.info.Path] == nil {
+					assert.Nil(t, eventArgs, so.info.Path)
+					continue
+				}
+				assert.Equal(t, testCase.expectedArgs[so.info.Path], eventArgs, so.info.Path)
+			}
+		})
+	}
+
+	t.Run("Invalid expected symbols", func(t *testing.T) {
+		problems := ValidateConfig(SymbolsLoadedConfig{
+			ExpectedSymbols: map[string][]string{"libssl.so.3": {"SSL_read", ""}},
+		})
+		require.Len(t, problems, 1)
+		assert.EqualError(t, problems[0], "empty expected symbol entry of soname 'libssl.so.3'")
+	})
+}
+
+// copyingLoaderMock copies the symbols it returns, like the host symbols loader does
+type copyingLoaderMock struct {
+	symbolsLoaderMock
+}
+
+func (loader copyingLoaderMock) GetExportedSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
+	syms, err := loader.symbolsLoaderMock.GetExportedSymbols(info)
+	if err != nil {
+		return nil, err
+	}
+	copied := make(map[string]bool, len(syms))
+	for sym := range syms {
+		copied[sym] = true
+	}
+	return copied, nil
+}
+
+// checkingLoaderMock can also check single symbols without copying the symbols
+type checkingLoaderMock struct {
+	copyingLoaderMock
+}
+
+func (loader checkingLoaderMock) IsSymbolExported(info sharedobjs.ObjInfo, symbol string) (bool, error) {
+	if err := loader.errs[info.Id]; err != nil {
+		return false, err
+	}
+	return loader.cache[info.Id][symbol], nil
+}
+
+func TestDeriveSharedObjectSymbolChecker(t *testing.T) {
+	so := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libevil.so"},
+		syms: []string{"open", "write", "close"},
+	}
+	brokenSO := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libbroken.so"},
+		loadErr: errors.New("read failure"),
+	}
+	mockLoader := checkingLoaderMock{copyingLoaderMock{initLoaderMock()}}
+	mockLoader.addSOSymbols(so)
+	mockLoader.addSOSymbols(brokenSO)
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols: []string{"open", "read", "libevil!close", "libc!write"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, gen.symbolChecker)
+
+	eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+	require.NoError(t, err)
+	require.Len(t, eventArgs, 2)
+	assert.Equal(t, so.info.Path, eventArgs[0])
+	assert.ElementsMatch(t, []string{"open", "close"}, eventArgs[1])
+
+	_, err = gen.deriveArgs(generateSOLoadedEvent(1, brokenSO.info))
+	assert.Error(t, err)
+
+	// Prefixes have to be matched against all the symbols of the SO
+	gen, err = InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{WatchedSymbols: []string{"op*"}})
+	require.NoError(t, err)
+	eventArgs, err = gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{so.info.Path, []string{"open"}}, eventArgs)
+}
+
+// BenchmarkDeriveNoMatch derives the event for a large SO which exports none of the watched symbols, which is the
+// common case of the derivation.
+func BenchmarkDeriveNoMatch(b *testing.B) {
+	so := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/libbig.so"}}
+	for i := 0; i < 5000; i++ {
+		so.syms = append(so.syms, fmt.Sprintf("exported_function_%d", i))
+	}
+	loaders := []struct {
+		name   string
+		loader sharedobjs.DynamicSymbolsLoader
+	}{
+		{name: "SymbolsCopy", loader: copyingLoaderMock{initLoaderMock()}},
+		{name: "SymbolChecker", loader: checkingLoaderMock{copyingLoaderMock{initLoaderMock()}}},
+	}
+	for _, l := range loaders {
+		b.Run(l.name, func(b *testing.B) {
+			l.loader.(interface{ addSOSymbols(soInstance) }).addSOSymbols(so)
+			gen, err := InitSymbolsLoadedEventGenerator(l.loader, SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open", "write", "dlopen", "libc!execve"},
+			})
+			require.NoError(b, err)
+			event := generateSOLoadedEvent(1, so.info)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := gen.deriveArgs(event); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMarshalSymbolsLoadedArgs(b *testing.B) {
+	so := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libhook.so"}}
+	var watched []string
+	for i := 0; i < 200; i++ {
+		sym := fmt.Sprintf("hooked_function_%d", i)
+		so.syms = append(so.syms, sym)
+		watched = append(watched, sym)
+	}
+	mockLoader := initLoaderMock()
+	mockLoader.addSOSymbols(so)
+	for _, compact := range []bool{false, true} {
+		name := "Structured"
+		if compact {
+			name = "Compact"
+		}
+		b.Run(name, func(b *testing.B) {
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+				WatchedSymbols: watched,
+				CompactSymbols: compact,
+			})
+			require.NoError(b, err)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+			require.NoError(b, err)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := json.Marshal(eventArgs); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDeriveSharedObjectSymbolSets(t *testing.T) {
+	libcSymbols := []string{"malloc", "free", "printf", "fopen", "strlen", "memcpy", "getenv", "execve", "fork",
+		"pthread_create"}
+	disguised := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libhelper.so"},
+		syms: libcSymbols[:9],
+	}
+	genuine := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/usr/lib/x86_64-linux-gnu/libc.so.6"},
+		syms: libcSymbols,
+	}
+	partial := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libcompat.so"},
+		syms: libcSymbols[:5],
+	}
+	threads := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/tmp/libthreads.so"},
+		syms: []string{"pthread_create", "fork", "malloc"},
+	}
+	mockLoader := initLoaderMock()
+	for _, so := range []soInstance{disguised, genuine, partial, threads} {
+		mockLoader.addSOSymbols(so)
+	}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		SymbolSets: []SymbolSet{
+			{Name: "libc", Symbols: libcSymbols, LibraryNames: []string{"libc.so", "libc-"}},
+			{Name: "libpthread", Symbols: []string{"pthread_create", "fork", "malloc", "pthread_join"},
+				MinRatio: 0.5},
+		},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		so       soInstance
+		expected []interface{}
+	}{
+		// The SO matches both sets, and the set matched with the highest ratio is reported
+		{name: "Disguised library", so: disguised,
+			expected: []interface{}{disguised.info.Path, []string(nil), "libc", 0.9}},
+		// The genuine library doesn't match its own set, but may match others
+		{name: "Genuine library", so: genuine,
+			expected: []interface{}{genuine.info.Path, []string(nil), "libpthread", 0.75}},
+		{name: "Below the minimal ratio", so: partial},
+		{name: "Configured minimal ratio", so: threads,
+			expected: []interface{}{threads.info.Path, []string(nil), "libpthread", 0.75}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.so.info))
+			require.NoError(t, err)
+			if testCase.expected == nil {
+				assert.Nil(t, eventArgs)
+				return
+			}
+			assert.Equal(t, testCase.expected, eventArgs)
+		})
+	}
+}
+
+func TestDeriveSharedObjectWatchGroups(t *testing.T) {
+	groups := []SymbolsWatchGroup{
+		{Name: "io-hooks", Symbols: []string{"open", "openat", "read", "write"}, MinMatches: 2},
+		{Name: "file-access", Symbols: []string{"open", "fopen"}},
+		{Name: "crypto", Symbols: []string{"EVP_EncryptInit", "SSL_write"}},
+	}
+	hookingSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libhook.so"},
+		syms: []string{"open", "openat", "fopen"},
+	}
+	fileSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libfile.so"},
+		syms: []string{"open"},
+	}
+	cryptoSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libcrypto.so"},
+		syms: []string{"SSL_write", "close"},
+	}
+	otherSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/tmp/libother.so"},
+		syms: []string{"close"},
+	}
+
+	testCases := []struct {
+		name             string
+		watchedSymbols   []string
+		stopOnFirstMatch bool
+		expectedArgs     map[string][]interface{}
+	}{
+		{
+			name: "All matching groups",
+			expectedArgs: map[string][]interface{}{
+				hookingSO.info.Path: {hookingSO.info.Path, []string(nil), []string{"io-hooks", "file-access"}},
+				// A single symbol of the io-hooks group is below its minimal matches
+				fileSO.info.Path:   {fileSO.info.Path, []string(nil), []string{"file-access"}},
+				cryptoSO.info.Path: {cryptoSO.info.Path, []string(nil), []string{"crypto"}},
+			},
+		},
+		{
+			name:             "Stop on first match",
+			stopOnFirstMatch: true,
+			expectedArgs: map[string][]interface{}{
+				hookingSO.info.Path: {hookingSO.info.Path, []string(nil), []string{"io-hooks"}},
+				fileSO.info.Path:    {fileSO.info.Path, []string(nil), []string{"file-access"}},
+				cryptoSO.info.Path:  {cryptoSO.info.Path, []string(nil), []string{"crypto"}},
+			},
+		},
+		{
+			name:             "With watched symbols",
+			watchedSymbols:   []string{"close"},
+			stopOnFirstMatch: true,
+			expectedArgs: map[string][]interface{}{
+				hookingSO.info.Path: {hookingSO.info.Path, []string(nil), []string{"io-hooks"}},
+				fileSO.info.Path:    {fileSO.info.Path, []string(nil), []string{"file-access"}},
+				cryptoSO.info.Path:  {cryptoSO.info.Path, []string{"close"}, []string{"crypto"}},
+				otherSO.info.Path:   {otherSO.info.Path, []string{"close"}, []string(nil)},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+				WatchedSymbols:   testCase.watchedSymbols,
+				WatchGroups:      groups,
+				StopOnFirstMatch: testCase.stopOnFirstMatch,
+			})
+			require.NoError(t, err)
+			for _, so := range []soInstance{hookingSO, fileSO, cryptoSO, otherSO} {
+				mockLoader.addSOSymbols(so)
+				eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+				require.NoError(t, err)
+				if testCase.expectedArgs[so.info.Path] == nil {
+					assert.Nil(t, eventArgs, so.info.Path)
+					continue
+				}
+				assert.Equal(t, testCase.expectedArgs[so.info.Path], eventArgs, so.info.Path)
+			}
+		})
+	}
+}
+
+func TestDeriveSharedObjectWXSegments(t *testing.T) {
+	wxSegment := sharedobjs.Segment{Flags: elf.PF_R | elf.PF_W | elf.PF_X, Offset: 0x2df8}
+	wxWatchedSO := soInstance{
+		info:       sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libwx.so"},
+		syms:       []string{"open"},
+		wxSegments: []sharedobjs.Segment{wxSegment},
+	}
+	wxSO := soInstance{
+		info:       sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libwx2.so"},
+		syms:       []string{"close"},
+		wxSegments: []sharedobjs.Segment{wxSegment},
+	}
+	watchedSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libopen.so"},
+		syms: []string{"open"},
+	}
+	otherSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/tmp/libother.so"},
+		syms: []string{"close"},
+	}
+	wxFormatted := []string{"PF_X+PF_W+PF_R:0x2df8"}
+
+	testCases := []struct {
+		name           string
+		watchedSymbols []string
+		mode           WXSegmentsMode
+		expectedArgs   map[string][]interface{}
+	}{
+		{
+			name: "Report",
+			mode: WXSegmentsReport,
+			expectedArgs: map[string][]interface{}{
+				wxWatchedSO.info.Path: {wxWatchedSO.info.Path, []string(nil), wxFormatted},
+				wxSO.info.Path:        {wxSO.info.Path, []string(nil), wxFormatted},
+			},
+		},
+		{
+			name:           "Report with watched symbols",
+			watchedSymbols: []string{"open"},
+			mode:           WXSegmentsReport,
+			expectedArgs: map[string][]interface{}{
+				wxWatchedSO.info.Path: {wxWatchedSO.info.Path, []string{"open"}, wxFormatted},
+				wxSO.info.Path:        {wxSO.info.Path, []string(nil), wxFormatted},
+				watchedSO.info.Path:   {watchedSO.info.Path, []string{"open"}, []string{}},
+			},
+		},
+		{
+			name:           "Report only matched",
+			watchedSymbols: []string{"open"},
+			mode:           WXSegmentsReportMatched,
+			expectedArgs: map[string][]interface{}{
+				wxWatchedSO.info.Path: {wxWatchedSO.info.Path, []string{"open"}, wxFormatted},
+				watchedSO.info.Path:   {watchedSO.info.Path, []string{"open"}, []string{}},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+				WatchedSymbols: testCase.watchedSymbols,
+				WXSegments:     testCase.mode,
+			})
+			require.NoError(t, err)
+			for _, so := range []soInstance{wxWatchedSO, wxSO, watchedSO, otherSO} {
+				mockLoader.addSOSymbols(so)
+				eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+				require.NoError(t, err)
+				if testCase.expectedArgs[so.info.Path] == nil {
+					assert.Nil(t, eventArgs, so.info.Path)
+					continue
+				}
+				assert.Equal(t, testCase.expectedArgs[so.info.Path], eventArgs, so.info.Path)
+			}
+		})
+	}
+}
+
+func TestSymbolsLoadedEventGenerator_EffectiveConfig(t *testing.T) {
+	gen, err := InitSymbolsLoadedEventGenerator(initLoaderMock(), SymbolsLoadedConfig{
+		WatchedSymbols:     []string{"open", "EVP_*", "EVP_Digest*", "libevil!write"},
+		ExcludedSymbols:    []string{"EVP_Cleanup"},
+		WatchedImports:     []string{"dlopen"},
+		WhitelistedLibs:    []string{"/usr/lib/", "libc", "libnss_*"},
+		WhitelistedRegexps: []string{"^/opt/.*\\.so$"},
+		SymbolAliases:      map[string][]string{"open": {"__open64"}},
+		MaxSymbolsPerEvent: 10,
+		AllowlistMode:      true,
+		Interpreter:        InterpreterExclude,
+	})
+	require.NoError(t, err)
+
+	config := gen.EffectiveConfig()
+	assert.True(t, config.Enabled)
+	assert.False(t, config.Closed)
+	// The aliases are expanded, and the redundant prefix is dropped
+	assert.Equal(t, []string{"__open64", "open"}, config.WatchedSymbols)
+	assert.Equal(t, []string{"EVP_"}, config.WatchedPrefixes)
+	assert.Equal(t, []string{"EVP_Cleanup"}, config.ExcludedSymbols)
+	assert.Equal(t, map[string][]string{"write": {"libevil"}}, config.LibrarySymbols)
+	assert.Equal(t, []string{"dlopen"}, config.WatchedImports)
+	assert.Equal(t, SymbolsLoadedWhitelist{
+		PathPrefixes: []string{"/usr/lib/"},
+		Libraries:    []string{"libc"},
+		Globs:        []string{"libnss_*"},
+		Regexps:      []string{"^/opt/.*\\.so$"},
+	}, config.Whitelist)
+	assert.Equal(t, knownLibrariesDirs, config.LibrariesDirs)
+	assert.Equal(t, InterpreterExclude, config.Interpreter)
+	assert.Equal(t, []string{"allowlist"}, config.Modes)
+	assert.Equal(t, []string{"library_path", "symbols", "truncated", "symbols_count", "imported_symbols",
+		"symbols_canonical"}, config.Arguments)
+
+	// The effective configuration follows the state of the generator
+	gen.SetEnabled(false)
+	assert.False(t, gen.EffectiveConfig().Enabled)
+	gen.SetEnabled(true)
+	assert.True(t, gen.EffectiveConfig().Enabled)
+	require.NoError(t, gen.Close())
+	config = gen.EffectiveConfig()
+	assert.False(t, config.Enabled)
+	assert.True(t, config.Closed)
+
+	// The returned lists are copies
+	config.LibrariesDirs[0] = "/changed/"
+	assert.Equal(t, "/usr/lib/x86_64-linux-gnu/", gen.EffectiveConfig().LibrariesDirs[0])
+}
+
+func TestSymbolsLoadedEventGenerator_SetEnabled(t *testing.T) {
+	so := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}, syms: []string{"open"}}
+	mockLoader := initLoaderMock()
+	mockLoader.addSOSymbols(so)
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{WatchedSymbols: []string{"open"}})
+	require.NoError(t, err)
+	event := generateSOLoadedEvent(1, so.info)
+	expectedArgs := []interface{}{so.info.Path, []string{"open"}}
+
+	gen.SetEnabled(false)
+	eventArgs, err := gen.deriveArgs(event)
+	require.NoError(t, err)
+	assert.Nil(t, eventArgs)
+	// Invalid events are not examined while disabled
+	eventArgs, err = gen.deriveArgs(trace.Event{})
+	require.NoError(t, err)
+	assert.Nil(t, eventArgs)
+
+	gen.SetEnabled(true)
+	eventArgs, err = gen.deriveArgs(event)
+	require.NoError(t, err)
+	assert.Equal(t, expectedArgs, eventArgs)
+
+	// Toggling the generator concurrently with derivations makes each derivation either derive the event or not
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				gen.SetEnabled(i%2 == 0)
+			}
+		}
+	}()
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				eventArgs, err := gen.deriveArgs(event)
+				assert.NoError(t, err)
+				if eventArgs != nil {
+					assert.Equal(t, expectedArgs, eventArgs)
+				}
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(done)
+	wg.Wait()
+
+	gen.SetEnabled(true)
+	eventArgs, err = gen.deriveArgs(event)
+	require.NoError(t, err)
+	assert.Equal(t, expectedArgs, eventArgs)
+}
+
+func TestDeriveSharedObjectSymbolsHash(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	hmacHex := func(sym string) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(sym))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	so := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libhook.so"},
+		syms:        []string{"EVP_EncryptInit", "close"},
+		importsSyms: []string{"dlopen", "malloc"},
+	}
+
+	testCases := []struct {
+		name         string
+		mode         SymbolsHashMode
+		expectedArgs []interface{}
+	}{
+		{
+			name:         "Alongside the names",
+			mode:         SymbolsHashAlongside,
+			expectedArgs: []interface{}{so.info.Path, []string{"EVP_EncryptInit"}, []string{"dlopen"}, []string{hmacHex("EVP_EncryptInit")}},
+		},
+		{
+			name:         "Instead of the names",
+			mode:         SymbolsHashOnly,
+			expectedArgs: []interface{}{so.info.Path, []string{hmacHex("EVP_EncryptInit")}, []string{hmacHex("dlopen")}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			mockLoader.addSOSymbols(so)
+			// The prefix matched symbol is not hashed in advance, unlike the watched import
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+				WatchedSymbols: []string{"EVP_*"},
+				WatchedImports: []string{"dlopen"},
+				SymbolsHash:    testCase.mode,
+				SymbolsHashKey: key,
+			})
+			require.NoError(t, err)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedArgs, eventArgs)
+		})
+	}
+}
+
+func TestDeriveSharedObjectSuspiciousPaths(t *testing.T) {
+	whitelistedSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/vendor/libhook.so"},
+		syms: []string{"open", "close"},
+	}
+	homeSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/home/user/.cache/libio.so"},
+		syms: []string{"read"},
+	}
+	systemSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/usr/lib/libio.so"},
+		syms: []string{"read"},
+	}
+	similarPathSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/tmpfs/libhook.so"},
+		syms: []string{"open", "read"},
+	}
+	whitelistedSystemSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 5}, Path: "/usr/lib/vendor/libhook.so"},
+		syms: []string{"open"},
+	}
+	expectedArgs := map[string][]interface{}{
+		// The whitelist is overridden in suspicious directories
+		whitelistedSO.info.Path: {whitelistedSO.info.Path, []string{"open"}, []string(nil), "/tmp"},
+		// A single symbol of the group is enough in suspicious directories
+		homeSO.info.Path:        {homeSO.info.Path, []string(nil), []string{"io-hooks"}, "/home"},
+		similarPathSO.info.Path: {similarPathSO.info.Path, []string{"open"}, []string(nil), ""},
+	}
+
+	mockLoader := initLoaderMock()
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:  []string{"open"},
+		WhitelistedLibs: []string{"/tmp/vendor", "/usr/lib/vendor"},
+		WatchGroups:     []SymbolsWatchGroup{{Name: "io-hooks", Symbols: []string{"read", "write"}, MinMatches: 2}},
+		SuspiciousPaths: DefaultSuspiciousPaths,
+	})
+	require.NoError(t, err)
+	for _, so := range []soInstance{whitelistedSO, homeSO, systemSO, similarPathSO, whitelistedSystemSO} {
+		mockLoader.addSOSymbols(so)
+		eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+		require.NoError(t, err)
+		if expectedArgs[so.info.Path] == nil {
+			assert.Nil(t, eventArgs, so.info.Path)
+			continue
+		}
+		assert.Equal(t, expectedArgs[so.info.Path], eventArgs, so.info.Path)
+	}
+}
+
+func TestDeriveSharedObjectLoadOrder(t *testing.T) {
+	libc := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/libc.so.6"},
+		syms: []string{"open", "write"},
+	}
+	hook := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libhook.so"},
+		syms: []string{"open"},
+	}
+	other := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libother.so"},
+		syms: []string{"close"},
+	}
+	type load struct {
+		pid              int
+		so               soInstance
+		expectedSequence interface{} // Nil if the event is not derived
+	}
+
+	t.Run("Sequence per process", func(t *testing.T) {
+		mockLoader := initLoaderMock()
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols:  []string{"open"},
+			WhitelistedLibs: []string{"/usr/lib"},
+			ReportLoadOrder: true,
+		})
+		require.NoError(t, err)
+		loads := []load{
+			// Whitelisted SOs and SOs with no match are counted
+			{pid: 1, so: libc},
+			{pid: 1, so: other},
+			{pid: 1, so: hook, expectedSequence: uint64(3)},
+			{pid: 2, so: hook, expectedSequence: uint64(1)},
+			{pid: 1, so: hook, expectedSequence: uint64(4)},
+		}
+		for _, l := range loads {
+			mockLoader.addSOSymbols(l.so)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(l.pid, l.so.info))
+			require.NoError(t, err)
+			if l.expectedSequence == nil {
+				assert.Nil(t, eventArgs)
+				continue
+			}
+			assert.Equal(t, []interface{}{l.so.info.Path, []string{"open"}, l.expectedSequence}, eventArgs)
+		}
+		gen.ProcessExited(1)
+		eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, hook.info))
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{hook.info.Path, []string{"open"}, uint64(1)}, eventArgs)
+	})
+
+	t.Run("Bounded processes", func(t *testing.T) {
+		mockLoader := initLoaderMock()
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols:     []string{"open"},
+			ReportLoadOrder:    true,
+			LoadOrderProcesses: 1,
+		})
+		require.NoError(t, err)
+		loads := []load{
+			{pid: 1, so: hook, expectedSequence: uint64(1)},
+			{pid: 1, so: hook, expectedSequence: uint64(2)},
+			{pid: 2, so: hook, expectedSequence: uint64(1)},
+			// The first process was evicted by the second, so its sequence restarts
+			{pid: 1, so: hook, expectedSequence: uint64(1)},
+		}
+		mockLoader.addSOSymbols(hook)
+		for _, l := range loads {
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(l.pid, l.so.info))
+			require.NoError(t, err)
+			require.Len(t, eventArgs, 3)
+			assert.Equal(t, l.expectedSequence, eventArgs[2])
+		}
+		assert.Equal(t, 1, gen.loadSequences.processes.Len())
+	})
+}
+
+func TestLoadLibrariesDirs(t *testing.T) {
+	confDir := t.TempDir()
+	confPath := filepath.Join(confDir, "ld.so.conf")
+	confFiles := map[string]string{
+		confPath: "include ld.so.conf.d/*.conf\n/opt/custom/lib # Custom libraries\n",
+		// Include loops are not followed
+		filepath.Join(confDir, "ld.so.conf.d", "local.conf"):  "# Local libraries\n/usr/local/lib\ninclude ../ld.so.conf\n",
+		filepath.Join(confDir, "ld.so.conf.d", "x86_64.conf"): "/usr/lib/x86_64-linux-gnu/\nhwcap 1 nosegneg\n",
+		filepath.Join(confDir, "ld.so.conf.d", "ignored"):     "/opt/ignored\n",
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(confDir, "ld.so.conf.d"), 0755))
+	for confFile, content := range confFiles {
+		require.NoError(t, os.WriteFile(confFile, []byte(content), 0644))
+	}
+
+	t.Run("Configuration and library path", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"/usr/lib/x86_64-linux-gnu/",
+			"/opt/custom/lib/",
+			"/usr/local/lib/",
+			"/home/user/lib/",
+			"/usr/lib64/",
+			"/usr/lib/",
+			"/lib64/",
+			"/lib/",
+		}, loadLibrariesDirs(confPath, "/home/user/lib::relative/lib"))
+	})
+
+	t.Run("Unreadable configuration", func(t *testing.T) {
+		assert.Equal(t, knownLibrariesDirs, loadLibrariesDirs(filepath.Join(confDir, "missing.conf"), ""))
+	})
+
+	t.Run("Whitelisted libraries", func(t *testing.T) {
+		mockLoader := initLoaderMock()
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols:  []string{"open"},
+			WhitelistedLibs: []string{"libcustom"},
+			LdSoConfPath:    confPath,
+		})
+		require.NoError(t, err)
+		for _, testCase := range []struct {
+			path     string
+			expected []interface{}
+		}{
+			{path: "/opt/custom/lib/libcustom.so.1"},
+			{path: "/opt/other/libcustom.so.1", expected: []interface{}{"/opt/other/libcustom.so.1", []string{"open"}}},
+		} {
+			so := soInstance{info: sharedobjs.ObjInfo{Path: testCase.path}, syms: []string{"open"}}
+			mockLoader.addSOSymbols(so)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, eventArgs, testCase.path)
+		}
+	})
+}
+
+func TestSymbolsLoadedWhitelistFiles(t *testing.T) {
+	dir := t.TempDir()
+	basePath := filepath.Join(dir, "base.whitelist")
+	teamPath := filepath.Join(dir, "team.whitelist")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "base.d"), 0755))
+	for file, content := range map[string]string{
+		basePath: "# Base whitelist\n/usr/lib/\nlibc\nregexp ^/opt/vendor/.*#1\\.so$\ninclude base.d/*.whitelist\n",
+		filepath.Join(dir, "base.d", "nss.whitelist"): "libnss_*\n",
+		// The team whitelist extends the base one, and overrides its entries of /usr/lib and libc
+		teamPath: "include base.whitelist\nremove libc\nremove /usr//lib/\n/usr/lib/x86_64-linux-gnu/\n",
+	} {
+		require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	}
+
+	t.Run("Merged entries", func(t *testing.T) {
+		merged, err := mergeWhitelistFiles(whitelistEntries{libs: []string{"libssl"}}, []string{teamPath})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"libssl", "libnss_*", "/usr/lib/x86_64-linux-gnu/"}, merged.libs)
+		assert.Equal(t, []string{"^/opt/vendor/.*#1\\.so$"}, merged.regexps)
+	})
+
+	t.Run("Generator", func(t *testing.T) {
+		mockLoader := initLoaderMock()
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols: []string{"open"},
+			WhitelistFiles: []string{teamPath},
+		})
+		require.NoError(t, err)
+		for _, testCase := range []struct {
+			path     string
+			expected []interface{}
+		}{
+			{path: "/usr/lib/x86_64-linux-gnu/libfoo.so"},
+			{path: "/usr/lib/libnss_files.so.2"},
+			{path: "/opt/vendor/lib#1.so"},
+			{path: "/usr/lib/libc.so.6", expected: []interface{}{"/usr/lib/libc.so.6", []string{"open"}}},
+		} {
+			so := soInstance{info: sharedobjs.ObjInfo{Path: testCase.path}, syms: []string{"open"}}
+			mockLoader.addSOSymbols(so)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, eventArgs, testCase.path)
+		}
+	})
+
+	t.Run("Bad files", func(t *testing.T) {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "cycle.d"), 0755))
+		for file, content := range map[string]string{
+			"cycle.whitelist":         "libc\ninclude cycle.d/inner.whitelist\n",
+			"cycle.d/inner.whitelist": "include ../cycle.whitelist\n",
+			"missing.whitelist":       "include absent.whitelist\n",
+			"empty.whitelist":         "libc\nremove\n",
+			"regexp.whitelist":        "regexp libc(.so\n",
+		} {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0644))
+		}
+		cyclePath, innerPath := filepath.Join(dir, "cycle.whitelist"), filepath.Join(dir, "cycle.d", "inner.whitelist")
+		absentPath := filepath.Join(dir, "absent.whitelist")
+		for _, testCase := range []struct {
+			file          string
+			expectedError string
+		}{
+			{
+				file:          "cycle.whitelist",
+				expectedError: fmt.Sprintf("%s -> %s -> %s", cyclePath, innerPath, cyclePath),
+			},
+			{
+				file:          "missing.whitelist",
+				expectedError: fmt.Sprintf("whitelist file '%s': open %s: no such file or directory", absentPath, absentPath),
+			},
+			{
+				file:          "empty.whitelist",
+				expectedError: fmt.Sprintf("whitelist file '%s' line 2: remove directive with no value", filepath.Join(dir, "empty.whitelist")),
+			},
+			// The merged entries are validated like the configured ones
+			{file: "regexp.whitelist", expectedError: "libc(.so"},
+		} {
+			_, err := InitSymbolsLoadedEventGenerator(initLoaderMock(), SymbolsLoadedConfig{
+				WatchedSymbols: []string{"open"},
+				WhitelistFiles: []string{filepath.Join(dir, testCase.file)},
+			})
+			require.Error(t, err, testCase.file)
+			assert.Contains(t, err.Error(), testCase.expectedError, testCase.file)
+		}
+	})
+}
+
+// blockingLoaderMock blocks the loading of exported symbols until it is released
+type blockingLoaderMock struct {
+	symbolsLoaderMock
+	started chan struct{}
+	release chan struct{}
+}
+
+func (loader blockingLoaderMock) GetExportedSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
+	select {
+	case loader.started <- struct{}{}:
+	default:
+	}
+	<-loader.release
+	return loader.symbolsLoaderMock.GetExportedSymbols(info)
+}
+
+func TestDeriveSharedObjectAsync(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	sos := []soInstance{
+		{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}, syms: []string{"open"}},
+		{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/2.so"}, syms: []string{"close"}},
+		{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/3.so"}, syms: []string{"open"}},
+		{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/tmp/4.so"}, syms: []string{"open"}},
+	}
+	mockLoader := blockingLoaderMock{
+		symbolsLoaderMock: initLoaderMock(),
+		started:           make(chan struct{}, 1),
+		release:           make(chan struct{}),
+	}
+	for _, so := range sos {
+		mockLoader.addSOSymbols(so)
+	}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols: []string{"open"},
+		AsyncQueueSize: 2,
+	})
+	require.NoError(t, err)
+	deriveFunc := SymbolsLoadedFromGenerator(gen)
+
+	// The first SO is examined by the worker, which is blocked until released
+	derived, errs := deriveFunc(generateSOLoadedEvent(1, sos[0].info))
+	assert.Empty(t, derived)
+	assert.Empty(t, errs)
+	<-mockLoader.started
+	for _, so := range sos[1:] {
+		derived, errs = deriveFunc(generateSOLoadedEvent(1, so.info))
+		assert.Empty(t, derived)
+		assert.Empty(t, errs)
+	}
+	stats := gen.Stats()
+	assert.Equal(t, int32(3), stats.AsyncQueued.Read())
+	// The last SO was dropped, as the queue was full
+	assert.Equal(t, int32(1), stats.AsyncDropped.Read())
+	assert.Equal(t, int32(2), stats.AsyncQueueDepth.Read())
+
+	close(mockLoader.release)
+	for _, expectedPath := range []string{sos[0].info.Path, sos[2].info.Path} {
+		event := <-gen.AsyncEvents()
+		assert.Equal(t, gen.skeleton.Name, event.EventName)
+		require.Len(t, event.Args, 2)
+		assert.Equal(t, expectedPath, event.Args[0].Value)
+		assert.Equal(t, []string{"open"}, event.Args[1].Value)
+	}
+	require.NoError(t, gen.Close())
+	_, open := <-gen.AsyncEvents()
+	assert.False(t, open)
+	assert.Equal(t, int32(0), stats.AsyncQueueDepth.Read())
+	assert.Equal(t, int32(0), stats.AsyncFailures.Read())
+}
+
+func TestDeriveSharedObjectSymbolAliases(t *testing.T) {
+	aliases := map[string][]string{
+		"malloc": {"__libc_malloc"},
+		"open":   {"__open", "__open64"},
+		"close":  {"__close"},
+	}
+	testCases := []struct {
+		name         string
+		so           soInstance
+		expectedArgs []interface{}
+	}{
+		{
+			name:         "Exported alias",
+			so:           soInstance{info: sharedobjs.ObjInfo{Path: "/usr/lib/libc.so.6"}, syms: []string{"__libc_malloc", "free"}},
+			expectedArgs: []interface{}{"/usr/lib/libc.so.6", []string{"__libc_malloc"}, []string{"malloc"}},
+		},
+		{
+			name:         "Exported canonical name",
+			so:           soInstance{info: sharedobjs.ObjInfo{Path: "/tmp/libhook.so"}, syms: []string{"malloc"}},
+			expectedArgs: []interface{}{"/tmp/libhook.so", []string{"malloc"}, []string{"malloc"}},
+		},
+		{
+			name:         "Class watched by its alias",
+			so:           soInstance{info: sharedobjs.ObjInfo{Path: "/tmp/libio.so"}, syms: []string{"__open64"}},
+			expectedArgs: []interface{}{"/tmp/libio.so", []string{"__open64"}, []string{"open"}},
+		},
+		{
+			name: "Excluded alias",
+			so:   soInstance{info: sharedobjs.ObjInfo{Path: "/tmp/libopen.so"}, syms: []string{"__open"}},
+		},
+		{
+			name: "Class with no watched name",
+			so:   soInstance{info: sharedobjs.ObjInfo{Path: "/tmp/libclose.so"}, syms: []string{"__close"}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			mockLoader.addSOSymbols(testCase.so)
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+				WatchedSymbols:  []string{"malloc", "__open64"},
+				ExcludedSymbols: []string{"__open"},
+				SymbolAliases:   aliases,
+			})
+			require.NoError(t, err)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.so.info))
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedArgs, eventArgs)
+		})
+	}
+}
+
+func TestWeakSymbolsFromObjects(t *testing.T) {
+	weakSymbols, err := WeakSymbolsFromObjects([]string{"../../utils/sharedobjs/testdata/weak.so"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"libweak.so.1": {"allocate"}}, weakSymbols)
+
+	_, err = WeakSymbolsFromObjects([]string{"testdata/missing.so"})
+	assert.ErrorContains(t, err, "failed to read standard library")
+}
+
+func TestDeriveSharedObjectWeakSymbolOverridden(t *testing.T) {
+	hookSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libhook.so"},
+		syms: []string{"malloc", "open"},
+		symsInfo: []sharedobjs.SymbolInfo{
+			{Name: "free", Bind: elf.STB_WEAK, Type: elf.STT_FUNC},
+		},
+	}
+	libcSO := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/usr/lib/libc.so.6"},
+		syms:   []string{"free", "malloc"},
+		soname: "libc.so.6",
+	}
+	unwatchedSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libother.so"},
+		syms: []string{"__malloc_hook"},
+	}
+	expectedArgs := map[string][]interface{}{
+		hookSO.info.Path: {hookSO.info.Path, []string{"malloc"}, []string{"libc.so.6,libjemalloc.so.2"}},
+		// A standard library doesn't override its own weak symbols
+		libcSO.info.Path: {libcSO.info.Path, []string{"malloc"}, []string{"libjemalloc.so.2"}},
+	}
+
+	mockLoader := initLoaderMock()
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols: []string{"malloc", "free", "open"},
+		WeakSymbols: map[string][]string{
+			"libc.so.6":        {"malloc", "free", "__malloc_hook"},
+			"libjemalloc.so.2": {"malloc"},
+		},
+	})
+	require.NoError(t, err)
+	deriveFunc := WeakSymbolOverridden(gen)
+	for _, so := range []soInstance{hookSO, libcSO, unwatchedSO} {
+		mockLoader.addSOSymbols(so)
+		derived, errs := deriveFunc(generateSOLoadedEvent(1, so.info))
+		require.Empty(t, errs)
+		if expectedArgs[so.info.Path] == nil {
+			assert.Empty(t, derived, so.info.Path)
+			continue
+		}
+		require.Len(t, derived, 1, so.info.Path)
+		assert.Equal(t, "weak_symbol_overridden", derived[0].EventName)
+		args := make([]interface{}, len(derived[0].Args))
+		for i, arg := range derived[0].Args {
+			args[i] = arg.Value
+		}
+		assert.Equal(t, expectedArgs[so.info.Path], args, so.info.Path)
+	}
+}
+
+// slowLoaderMock delays the loading of the exported symbols of the slow SOs
+type slowLoaderMock struct {
+	symbolsLoaderMock
+	slow  map[sharedobjs.ObjID]bool
+	delay time.Duration
+}
+
+func (loader slowLoaderMock) GetExportedSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
+	if loader.slow[info.Id] {
+		time.Sleep(loader.delay)
+	}
+	return loader.symbolsLoaderMock.GetExportedSymbols(info)
+}
+
+func TestDeriveSharedObjectExtractionDeadline(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	slowSO := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/slow.so"}, syms: []string{"open"}}
+	fastSO := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/fast.so"}, syms: []string{"open"}}
+	mockLoader := slowLoaderMock{
+		symbolsLoaderMock: initLoaderMock(),
+		slow:              map[sharedobjs.ObjID]bool{slowSO.info.Id: true},
+		delay:             300 * time.Millisecond,
+	}
+	mockLoader.addSOSymbols(slowSO)
+	mockLoader.addSOSymbols(fastSO)
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:     []string{"open"},
+		ExtractionDeadline: 30 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	deriveFunc := SymbolsLoadedFromGenerator(gen)
+
+	start := time.Now()
+	_, errs := deriveFunc(generateSOLoadedEvent(1, slowSO.info))
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], ErrExtractionTimeout)
+	assert.Less(t, time.Since(start), mockLoader.delay)
+
+	// The abandoned SO times out immediately until its derivation completes, and doesn't affect other SOs
+	start = time.Now()
+	_, errs = deriveFunc(generateSOLoadedEvent(2, slowSO.info))
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], ErrExtractionTimeout)
+	results, err := gen.DeriveBatch([]trace.Event{generateSOLoadedEvent(3, fastSO.info)})
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{fastSO.info.Path, []string{"open"}}}, results)
+	assert.Less(t, time.Since(start), mockLoader.delay)
+	assert.Equal(t, int32(2), gen.Stats().ExtractionTimeouts.Read())
+
+	// Closing waits for the abandoned derivation, as the loader can't stop reading the SO, so nothing is left running
+	require.NoError(t, gen.Close())
+	assert.Empty(t, gen.abandoned)
+}
+
+// contextLoaderMock is a loader which reads the slow SOs until their context is done
+type contextLoaderMock struct {
+	symbolsLoaderMock
+	slow      map[sharedobjs.ObjID]bool
+	reading   chan sharedobjs.ObjID
+	cancelled chan sharedobjs.ObjID
+}
+
+func (loader contextLoaderMock) LoadContext(ctx context.Context, info sharedobjs.ObjInfo) error {
+	if !loader.slow[info.Id] {
+		return nil
+	}
+	loader.reading <- info.Id
+	<-ctx.Done()
+	loader.cancelled <- info.Id
+	return ctx.Err()
+}
+
+func TestDeriveSharedObjectExtractionDeadlineCancellation(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	slowSO := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/slow.so"}, syms: []string{"open"}}
+	fastSO := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/fast.so"}, syms: []string{"open"}}
+	mockLoader := contextLoaderMock{
+		symbolsLoaderMock: initLoaderMock(),
+		slow:              map[sharedobjs.ObjID]bool{slowSO.info.Id: true},
+		reading:           make(chan sharedobjs.ObjID, 2),
+		cancelled:         make(chan sharedobjs.ObjID, 2),
+	}
+	mockLoader.addSOSymbols(slowSO)
+	mockLoader.addSOSymbols(fastSO)
+	gen, err := NewSymbolsLoadedGenerator(mockLoader, WithWatchedSymbols("open"),
+		WithExtraction(SymbolsExtractionConfig{ExtractionDeadline: 30 * time.Millisecond, DeadlineWorkers: 1}))
+	require.NoError(t, err)
+	deriveFunc := SymbolsLoadedFromGenerator(gen)
+
+	// The reading of the abandoned SO is cancelled, so the single worker is free for the next SO
+	_, errs := deriveFunc(generateSOLoadedEvent(1, slowSO.info))
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], ErrExtractionTimeout)
+	assert.Equal(t, slowSO.info.Id, <-mockLoader.reading)
+	assert.Equal(t, slowSO.info.Id, <-mockLoader.cancelled)
+	derived, errs := deriveFunc(generateSOLoadedEvent(1, fastSO.info))
+	require.Empty(t, errs)
+	require.Len(t, derived, 1)
+	assert.Equal(t, []interface{}{fastSO.info.Path, []string{"open"}}, argsValues(derived[0]))
+	require.NoError(t, gen.Close())
+
+	// Closing cancels the derivation in progress, well before its deadline, and nothing is derived from it
+	gen, err = NewSymbolsLoadedGenerator(mockLoader, WithWatchedSymbols("open"),
+		WithExtraction(SymbolsExtractionConfig{ExtractionDeadline: time.Hour}))
+	require.NoError(t, err)
+	type result struct {
+		derived []trace.Event
+		errs    []error
+	}
+	results := make(chan result, 1)
+	go func() {
+		derived, errs := SymbolsLoadedFromGenerator(gen)(generateSOLoadedEvent(2, slowSO.info))
+		results <- result{derived: derived, errs: errs}
+	}()
+	assert.Equal(t, slowSO.info.Id, <-mockLoader.reading)
+	require.NoError(t, gen.Close())
+	assert.Equal(t, slowSO.info.Id, <-mockLoader.cancelled)
+	closed := <-results
+	assert.Empty(t, closed.derived)
+	assert.Empty(t, closed.errs)
+
+	_, err = NewSymbolsLoadedGenerator(mockLoader, WithWatchedSymbols("open"),
+		WithExtraction(SymbolsExtractionConfig{DeadlineWorkers: 1}))
+	assert.Error(t, err)
+}
+
+func TestDeriveSharedObjectSymbolsCount(t *testing.T) {
+	manySyms := []string{"open"}
+	for i := 0; i < 99; i++ {
+		manySyms = append(manySyms, fmt.Sprintf("func%d", i))
+	}
+	sos := []soInstance{
+		{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/tiny.so"}, syms: []string{"open"}},
+		{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/small.so"}, syms: manySyms[:10]},
+		{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/medium.so"}, syms: manySyms[:50]},
+		{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/tmp/large.so"}, syms: manySyms},
+	}
+	expectedClasses := []string{"tiny", "small", "medium", "large"}
+
+	mockLoader := initLoaderMock()
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:         []string{"open"},
+		ReportSymbolsCount:     true,
+		SymbolsCountBoundaries: []int{10, 50, 100},
+	})
+	require.NoError(t, err)
+	for i, so := range sos {
+		mockLoader.addSOSymbols(so)
+		eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{so.info.Path, []string{"open"}, len(so.syms), expectedClasses[i]}, eventArgs,
+			so.info.Path)
+	}
+
+	// The default boundaries classify small SOs as tiny
+	gen, err = InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:     []string{"open"},
+		ReportSymbolsCount: true,
+	})
+	require.NoError(t, err)
+	eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, sos[1].info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{sos[1].info.Path, []string{"open"}, 10, "tiny"}, eventArgs)
+}
+
+func TestDeriveSharedObjectObjectID(t *testing.T) {
+	so := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1234, Device: 2049, Ctime: 1650000000}, Path: "/tmp/hook.so"},
+		syms: []string{"open"},
+	}
+	mockLoader := initLoaderMock()
+	mockLoader.addSOSymbols(so)
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols: []string{"open"},
+		ReportObjectID: true,
+	})
+	require.NoError(t, err)
+	eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{so.info.Path, []string{"open"}, uint32(2049), uint64(1234), uint64(1650000000)},
+		eventArgs)
+	params := gen.skeleton.Params[len(gen.skeleton.Params)-3:]
+	assert.Equal(t, []trace.ArgMeta{
+		{Type: "dev_t", Name: "dev"}, {Type: "unsigned long", Name: "inode"}, {Type: "unsigned long", Name: "ctime"},
+	}, params)
+}
+
+func TestDeriveSharedObjectProcessScope(t *testing.T) {
+	so := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/hook.so"},
+		syms: []string{"open"},
+	}
+	loadEvent := func(uid int, containerID string) trace.Event {
+		event := generateSOLoadedEvent(1, so.info)
+		event.UserID = uid
+		event.ContainerID = containerID
+		return event
+	}
+	testCases := []struct {
+		name    string
+		event   trace.Event
+		matched bool
+	}{
+		{name: "Root in the container", event: loadEvent(0, "3f9a5b1c7d2e8f4a"), matched: true},
+		{name: "User in the container", event: loadEvent(1000, "3f9a5b1c7d2e8f4a")},
+		{name: "Root in another container", event: loadEvent(0, "9e8d7c6b5a4f3e2d")},
+		{name: "Root on the host", event: loadEvent(0, "")},
+	}
+
+	mockLoader := initLoaderMock()
+	mockLoader.addSOSymbols(so)
+	logger := &symbolsLoadedLoggerMock{}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols: []string{"open"},
+		// Suspicious paths don't extend the scope
+		SuspiciousPaths: DefaultSuspiciousPaths,
+		ProcessScope:    SymbolsProcessScope{UserIDs: []int{0}, ContainerIDs: []string{"3f9a5b1c"}},
+		Logger:          logger,
+	})
+	require.NoError(t, err)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			eventArgs, err := gen.deriveArgs(testCase.event)
+			require.NoError(t, err)
+			if testCase.matched {
+				assert.Equal(t, []interface{}{so.info.Path, []string{"open"}, "/tmp"}, eventArgs)
+			} else {
+				assert.Nil(t, eventArgs)
+			}
+		})
+	}
+	var decisions []string
+	for _, entry := range logger.entries {
+		decisions = append(decisions, entry.Decision)
+	}
+	assert.Equal(t, []string{DecisionMatched, DecisionOutOfScope, DecisionOutOfScope, DecisionOutOfScope}, decisions)
+}
+
+func TestDeriveSharedObjectSonameBuildIDSeen(t *testing.T) {
+	libssl := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/libssl.so.3"},
+		soname:  "libssl.so.3",
+		buildID: "1e2a",
+	}
+	upgradedLibssl := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/usr/lib/libssl.so.3"},
+		soname:  "libssl.so.3",
+		buildID: "7c4f",
+	}
+	libz := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/opt/app/libz.so.1"},
+		soname:  "libz.so.1",
+		buildID: "90bd",
+	}
+	noBuildID := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/usr/lib/libstripped.so.1"},
+		soname: "libstripped.so.1",
+	}
+	testCases := []struct {
+		so           soInstance
+		expectedArgs []interface{}
+	}{
+		// Whitelisted SOs are inventoried too
+		{so: libssl, expectedArgs: []interface{}{libssl.info.Path, "libssl.so.3", "1e2a", ""}},
+		{so: libssl},
+		{so: libz, expectedArgs: []interface{}{libz.info.Path, "libz.so.1", "90bd", ""}},
+		{so: upgradedLibssl, expectedArgs: []interface{}{upgradedLibssl.info.Path, "libssl.so.3", "7c4f", "1e2a"}},
+		{so: libssl, expectedArgs: []interface{}{libssl.info.Path, "libssl.so.3", "1e2a", "7c4f"}},
+		{so: noBuildID},
+	}
+
+	mockLoader := initLoaderMock()
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:  []string{"open"},
+		WhitelistedLibs: []string{"/usr/lib"},
+		TrackBuildIDs:   true,
+		// The least recently seen pair is forgotten, so the first libssl build is seen again after the upgrade
+		MaxSeenBuildIDs: 2,
+	})
+	require.NoError(t, err)
+	for i, testCase := range testCases {
+		mockLoader.addSOSymbols(testCase.so)
+		eventArgs, err := gen.deriveBuildIDSeenArgs(generateSOLoadedEvent(1, testCase.so.info))
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expectedArgs, eventArgs, "load %d", i)
+	}
+
+	gen, err = InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{WatchedSymbols: []string{"open"}})
+	require.NoError(t, err)
+	eventArgs, err := gen.deriveBuildIDSeenArgs(generateSOLoadedEvent(1, libz.info))
+	require.NoError(t, err)
+	assert.Nil(t, eventArgs)
+}
+
+func TestDeriveSharedObjectSonamePathChanged(t *testing.T) {
+	libssl := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/libssl.so.3"},
+		syms:   []string{"open"},
+		soname: "libssl.so.3",
+	}
+	movedLibssl := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libssl.so.3"},
+		syms:   []string{"open"},
+		soname: "libssl.so.3",
+	}
+	bundledLibssl := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/opt/app/libssl.so.3"},
+		syms:   []string{"open"},
+		soname: "libssl.so.3",
+	}
+	otherLibssl := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/usr/lib/x86_64/libssl.so.3"},
+		syms:   []string{"open"},
+		soname: "libssl.so.3",
+	}
+	noSoname := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 5}, Path: "/tmp/plugin.so"},
+		syms: []string{"open"},
+	}
+	testCases := []struct {
+		so           soInstance
+		expectedArgs []interface{}
+	}{
+		// The first path of a soname is not a change
+		{so: libssl},
+		{so: libssl},
+		{so: movedLibssl, expectedArgs: []interface{}{movedLibssl.info.Path, "libssl.so.3", []string{libssl.info.Path}}},
+		{so: movedLibssl},
+		// Only the last 2 paths are remembered, so the first libssl path is seen again later
+		{so: bundledLibssl, expectedArgs: []interface{}{
+			bundledLibssl.info.Path, "libssl.so.3", []string{libssl.info.Path, movedLibssl.info.Path}}},
+		// Whitelisted SOs are recorded, but are not reported
+		{so: libssl},
+		{so: movedLibssl, expectedArgs: []interface{}{
+			movedLibssl.info.Path, "libssl.so.3", []string{bundledLibssl.info.Path, libssl.info.Path}}},
+		{so: otherLibssl},
+		{so: noSoname},
+	}
+
+	mockLoader := initLoaderMock()
+	logger := &symbolsLoadedLoggerMock{}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:   []string{"open"},
+		WhitelistedLibs:  []string{"/usr/lib"},
+		TrackSonamePaths: true,
+		MaxSonamePaths:   2,
+		Logger:           logger,
+	})
+	require.NoError(t, err)
+	for i, testCase := range testCases {
+		mockLoader.addSOSymbols(testCase.so)
+		eventArgs, err := gen.deriveSonamePathArgs(generateSOLoadedEvent(1, testCase.so.info))
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expectedArgs, eventArgs, "load %d", i)
+	}
+	var relocated int
+	for _, entry := range logger.entries {
+		if entry.Decision == DecisionRelocated {
+			assert.Equal(t, LogLevelWarn, entry.Level)
+			relocated++
+		}
+	}
+	assert.Equal(t, 3, relocated)
+
+	gen, err = InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{WatchedSymbols: []string{"open"}})
+	require.NoError(t, err)
+	eventArgs, err := gen.deriveSonamePathArgs(generateSOLoadedEvent(1, movedLibssl.info))
+	require.NoError(t, err)
+	assert.Nil(t, eventArgs)
+}
+
+func TestDeriveSharedObjectSonamePathChanged_WatchedObjects(t *testing.T) {
+	libz := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/libz.so.1"},
+		syms:   []string{"inflate"},
+		soname: "libz.so.1",
+	}
+	movedLibz := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libz.so.1"},
+		syms:   []string{"inflate"},
+		soname: "libz.so.1",
+	}
+	hookedLibz := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/opt/app/libz.so.1"},
+		syms:   []string{"inflate", "open"},
+		soname: "libz.so.1",
+	}
+	testCases := []struct {
+		name         string
+		reportAll    bool
+		expectedArgs map[string][]interface{}
+	}{
+		{
+			// SOs exporting no watched symbols are recorded, but are not reported
+			name: "Watched objects",
+			expectedArgs: map[string][]interface{}{
+				hookedLibz.info.Path: {hookedLibz.info.Path, "libz.so.1", []string{libz.info.Path, movedLibz.info.Path}},
+			},
+		},
+		{
+			name:      "All objects",
+			reportAll: true,
+			expectedArgs: map[string][]interface{}{
+				movedLibz.info.Path:  {movedLibz.info.Path, "libz.so.1", []string{libz.info.Path}},
+				hookedLibz.info.Path: {hookedLibz.info.Path, "libz.so.1", []string{libz.info.Path, movedLibz.info.Path}},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			gen, err := NewSymbolsLoadedGenerator(mockLoader, WithWatchedSymbols("open"), WithTracking(SymbolsTrackingConfig{
+				TrackSonamePaths:     true,
+				ReportAllSonamePaths: testCase.reportAll,
+			}))
+			require.NoError(t, err)
+			for _, so := range []soInstance{libz, movedLibz, hookedLibz} {
+				mockLoader.addSOSymbols(so)
+				eventArgs, err := gen.deriveSonamePathArgs(generateSOLoadedEvent(1, so.info))
+				require.NoError(t, err)
+				assert.Equal(t, testCase.expectedArgs[so.info.Path], eventArgs, so.info.Path)
+			}
+		})
+	}
+
+	_, err := NewSymbolsLoadedGenerator(initLoaderMock(), WithWatchedSymbols("open"), WithTracking(SymbolsTrackingConfig{
+		ReportAllSonamePaths: true,
+	}))
+	assert.Error(t, err)
+}
+
+func TestDeriveSharedObjectSonamePathChanged_Aliases(t *testing.T) {
+	// The library directory is reached through a linked directory too, as /lib is linked to /usr/lib
+	root := t.TempDir()
+	libDir := filepath.Join(root, "usr", "lib")
+	require.NoError(t, os.MkdirAll(libDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(libDir, "libssl.so.3"), nil, 0644))
+	require.NoError(t, os.Symlink(libDir, filepath.Join(root, "lib")))
+	libssl := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: filepath.Join(libDir, "libssl.so.3")},
+		syms:   []string{"open"},
+		soname: "libssl.so.3",
+	}
+	linkedLibssl := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: filepath.Join(root, "lib", "libssl.so.3")},
+		syms:   []string{"open"},
+		soname: "libssl.so.3",
+	}
+	uncleanLibssl := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: libDir + "/../lib//libssl.so.3"},
+		syms:   []string{"open"},
+		soname: "libssl.so.3",
+	}
+	// Paths which don't exist anymore are compared after cleaning them
+	deletedLibssl := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/nonexistent/libssl.so.3"},
+		syms:   []string{"open"},
+		soname: "libssl.so.3",
+	}
+	uncleanDeletedLibssl := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/./nonexistent/libssl.so.3"},
+		syms:   []string{"open"},
+		soname: "libssl.so.3",
+	}
+
+	mockLoader := initLoaderMock()
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:   []string{"open"},
+		TrackSonamePaths: true,
+	})
+	require.NoError(t, err)
+	for _, so := range []soInstance{libssl, linkedLibssl, uncleanLibssl} {
+		mockLoader.addSOSymbols(so)
+		eventArgs, err := gen.deriveSonamePathArgs(generateSOLoadedEvent(1, so.info))
+		require.NoError(t, err)
+		assert.Nil(t, eventArgs, so.info.Path)
+	}
+	// The previous paths are reported resolved
+	resolvedPath, err := filepath.EvalSymlinks(libssl.info.Path)
+	require.NoError(t, err)
+	mockLoader.addSOSymbols(deletedLibssl)
+	eventArgs, err := gen.deriveSonamePathArgs(generateSOLoadedEvent(1, deletedLibssl.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{deletedLibssl.info.Path, "libssl.so.3", []string{resolvedPath}}, eventArgs)
+	mockLoader.addSOSymbols(uncleanDeletedLibssl)
+	eventArgs, err = gen.deriveSonamePathArgs(generateSOLoadedEvent(1, uncleanDeletedLibssl.info))
+	require.NoError(t, err)
+	assert.Nil(t, eventArgs)
+}
+
+func TestDeriveSharedObjectFilesystemPolicies(t *testing.T) {
+	skippedSO := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/mnt/nfs/libremote.so"},
+		loadErr: sharedobjs.ErrFilesystemSkipped,
+	}
+	slowSO := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/mnt/fuse/libslow.so"},
+		loadErr: sharedobjs.ErrFilesystemTimeout,
+	}
+	mockLoader := initLoaderMock()
+	mockLoader.addSOSymbols(skippedSO)
+	mockLoader.addSOSymbols(slowSO)
+	logger := &symbolsLoadedLoggerMock{}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols: []string{"open"},
+		Logger:         logger,
+	})
+	require.NoError(t, err)
+
+	// Skipped SOs are not a failure of the derivation
+	eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, skippedSO.info))
+	require.NoError(t, err)
+	assert.Nil(t, eventArgs)
+	_, err = gen.deriveArgs(generateSOLoadedEvent(1, slowSO.info))
+	assert.ErrorIs(t, err, sharedobjs.ErrFilesystemTimeout)
+
+	require.Len(t, logger.entries, 2)
+	assert.Equal(t, DecisionSkippedFS, logger.entries[0].Decision)
+	assert.Equal(t, LogLevelDebug, logger.entries[0].Level)
+	assert.Equal(t, DecisionTimeout, logger.entries[1].Decision)
+	assert.Equal(t, LogLevelWarn, logger.entries[1].Level)
+}
+
+func TestDeriveSharedObjectNotRegularFile(t *testing.T) {
+	dirSO := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib"},
+		loadErr: fmt.Errorf("%w: '/usr/lib' is a directory", sharedobjs.ErrNotRegularFile),
+	}
+	fifoSO := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/fifo.so"},
+		loadErr: fmt.Errorf("%w: '/tmp/fifo.so' is a FIFO", sharedobjs.ErrNotRegularFile),
+	}
+	mockLoader := initLoaderMock()
+	mockLoader.addSOSymbols(dirSO)
+	mockLoader.addSOSymbols(fifoSO)
+	logger := &symbolsLoadedLoggerMock{}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:       []string{"open"},
+		AlwaysWatchedSymbols: []string{"dlopen"},
+		WhitelistedLibs:      []string{"/tmp"},
+		Logger:               logger,
+	})
+	require.NoError(t, err)
+
+	// Paths which are not regular files are skipped, whether the SO is examined or only its always watched symbols
+	for _, so := range []soInstance{dirSO, fifoSO} {
+		eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+		require.NoError(t, err, so.info.Path)
+		assert.Nil(t, eventArgs, so.info.Path)
+	}
+	var decisions []string
+	for _, entry := range logger.entries {
+		if entry.Level == LogLevelDebug && entry.Decision == DecisionNotRegular {
+			decisions = append(decisions, entry.ObjInfo.Path)
+		}
+	}
+	assert.Equal(t, []string{"/usr/lib", "/tmp/fifo.so"}, decisions)
+}
+
+func generateProcessExitEvent(pid int, groupExit bool) trace.Event {
+	return trace.Event{
+		EventName:     "sched_process_exit",
+		HostProcessID: pid,
+		ProcessID:     pid,
+		Args: []trace.Argument{
+			{ArgMeta: trace.ArgMeta{Type: "long", Name: "exit_code"}, Value: int64(0)},
+			{ArgMeta: trace.ArgMeta{Type: "bool", Name: "process_group_exit"}, Value: groupExit},
+		},
+	}
+}
+
+func TestDeriveSharedObjectProcessProfile(t *testing.T) {
+	libc := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/libc.so.6"},
+		syms: []string{"open", "write", "close"},
+	}
+	hook := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libhook.so"},
+		syms: []string{"open", "dlopen"},
+	}
+	unmatched := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/usr/lib/libm.so.6"},
+		syms: []string{"sin"},
+	}
+	mockLoader := initLoaderMock()
+	for _, so := range []soInstance{libc, hook, unmatched} {
+		mockLoader.addSOSymbols(so)
+	}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:    []string{"open", "write", "dlopen"},
+		ProfileProcesses:  true,
+		MaxProfileEntries: 4,
+	})
+	require.NoError(t, err)
+	for _, so := range []soInstance{libc, unmatched, hook} {
+		_, err = gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+		require.NoError(t, err)
+	}
+	_, err = gen.deriveArgs(generateSOLoadedEvent(2, unmatched.info))
+	require.NoError(t, err)
+
+	// The exit of a thread doesn't end the profile
+	eventArgs, err := gen.deriveProfileArgs(generateProcessExitEvent(1, false))
+	require.NoError(t, err)
+	assert.Nil(t, eventArgs)
+
+	// The symbol of the second library doesn't fit in the profile
+	eventArgs, err = gen.deriveProfileArgs(generateProcessExitEvent(1, true))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		[]string{"open", "write"}, []string{"/tmp/libhook.so", "/usr/lib/libc.so.6"}, 2, true,
+	}, eventArgs)
+
+	// The profile is taken on exit, and processes with no match have no profile
+	for _, pid := range []int{1, 2} {
+		eventArgs, err = gen.deriveProfileArgs(generateProcessExitEvent(pid, true))
+		require.NoError(t, err)
+		assert.Nil(t, eventArgs)
+	}
+}
+
+func TestDeriveSharedObjectCapabilityGained(t *testing.T) {
+	hook := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libhook.so"},
+		syms: []string{"open", "dlopen", "sin"},
+	}
+	opener := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libopen.so"},
+		syms: []string{"open"},
+	}
+	tracer := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libtrace.so"},
+		syms: []string{"open", "ptrace"},
+	}
+	libc := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/usr/lib/libc.so.6"},
+		syms: []string{"open", "ptrace", "dlopen"},
+	}
+	mockLoader := initLoaderMock()
+	for _, so := range []soInstance{hook, opener, tracer, libc} {
+		mockLoader.addSOSymbols(so)
+	}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:        []string{"open", "dlopen", "ptrace"},
+		WhitelistedLibs:       []string{"/usr/lib/"},
+		TrackCapabilities:     true,
+		CapabilitiesProcesses: 1,
+	})
+	require.NoError(t, err)
+
+	steps := []struct {
+		name     string
+		pid      int
+		so       soInstance
+		exited   bool // Whether the process exits before the load
+		expected []string
+	}{
+		{name: "First watched symbols", pid: 1, so: hook, expected: []string{"dlopen", "open"}},
+		{name: "Symbol already gained", pid: 1, so: opener},
+		{name: "Only the new symbol", pid: 1, so: tracer, expected: []string{"ptrace"}},
+		{name: "Whitelisted SO", pid: 2, so: libc},
+		{name: "Other process", pid: 2, so: opener, expected: []string{"open"}},
+		// The process evicted the first one, so its symbols are gained again
+		{name: "Evicted process", pid: 1, so: opener, expected: []string{"open"}},
+		{name: "Exited process", pid: 1, so: opener, exited: true, expected: []string{"open"}},
+	}
+	for _, step := range steps {
+		if step.exited {
+			gen.ProcessExited(step.pid)
+		}
+		eventArgs, err := gen.deriveCapabilityGainedArgs(generateSOLoadedEvent(step.pid, step.so.info))
+		require.NoError(t, err, step.name)
+		if step.expected == nil {
+			assert.Nil(t, eventArgs, step.name)
+			continue
+		}
+		assert.Equal(t, []interface{}{step.so.info.Path, step.expected}, eventArgs, step.name)
+	}
+
+	// Capabilities are not tracked by default
+	gen, err = InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{WatchedSymbols: []string{"open"}})
+	require.NoError(t, err)
+	eventArgs, err := gen.deriveCapabilityGainedArgs(generateSOLoadedEvent(1, hook.info))
+	require.NoError(t, err)
+	assert.Nil(t, eventArgs)
+}
+
+func TestDeriveSharedObjectAlwaysWatchedSymbols(t *testing.T) {
+	trustedNote := sharedobjs.NoteID{Name: "tracee", Type: 1}
+	whitelistedSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/libdebug.so"},
+		syms: []string{"open", "ptrace"},
+	}
+	trustedSO := soInstance{
+		info:  sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/opt/inhouse/libtrusted.so"},
+		syms:  []string{"open", "ptrace"},
+		notes: []sharedobjs.NoteID{trustedNote},
+	}
+	otherSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/opt/app/libhook.so"},
+		syms: []string{"open", "ptrace"},
+	}
+	cleanSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/usr/lib/libclean.so"},
+		syms: []string{"open"},
+	}
+	testCases := []struct {
+		name            string
+		so              soInstance
+		expectedSymbols []string
+	}{
+		// Only the always watched symbols override the whitelist
+		{name: "Whitelisted SO", so: whitelistedSO, expectedSymbols: []string{"ptrace"}},
+		{name: "Trusted SO", so: trustedSO, expectedSymbols: []string{"ptrace"}},
+		{name: "Other SO", so: otherSO, expectedSymbols: []string{"open", "ptrace"}},
+		{name: "Whitelisted SO without always watched symbols", so: cleanSO},
+	}
+
+	mockLoader := initLoaderMock()
+	logger := &symbolsLoadedLoggerMock{}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:       []string{"open"},
+		AlwaysWatchedSymbols: []string{"ptrace"},
+		WhitelistedLibs:      []string{"/usr/lib/"},
+		TrustedNote:          trustedNote,
+		Logger:               logger,
+	})
+	require.NoError(t, err)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader.addSOSymbols(testCase.so)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.so.info))
+			require.NoError(t, err)
+			if testCase.expectedSymbols == nil {
+				assert.Nil(t, eventArgs)
+				return
+			}
+			require.Len(t, eventArgs, 2)
+			assert.Equal(t, testCase.so.info.Path, eventArgs[0])
+			assert.ElementsMatch(t, testCase.expectedSymbols, eventArgs[1])
+		})
+	}
+	var decisions []string
+	for _, entry := range logger.entries {
+		decisions = append(decisions, entry.Decision)
+	}
+	assert.Equal(t, []string{DecisionWhitelisted, DecisionAlwaysMatched, DecisionTrusted, DecisionAlwaysMatched,
+		DecisionMatched, DecisionWhitelisted}, decisions)
+}
+
+// fakeClock is a clock whose time advances only when the test advances it
+type fakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []fakeClockWaiter
+}
+
+// fakeClockWaiter is a call to After of the fake clock, which didn't fire yet
+type fakeClockWaiter struct {
+	deadline time.Time
+	channel  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1700000000, 0)}
+}
+
+func (clock *fakeClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+func (clock *fakeClock) After(d time.Duration) <-chan time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	channel := make(chan time.Time, 1)
+	if d <= 0 {
+		channel <- clock.now
+		return channel
+	}
+	clock.waiters = append(clock.waiters, fakeClockWaiter{deadline: clock.now.Add(d), channel: channel})
+	return channel
+}
+
+// advance moves the time forward, and fires the waiters whose duration elapsed
+func (clock *fakeClock) advance(d time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = clock.now.Add(d)
+	var pending []fakeClockWaiter
+	for _, waiter := range clock.waiters {
+		if waiter.deadline.After(clock.now) {
+			pending = append(pending, waiter)
+			continue
+		}
+		waiter.channel <- clock.now
+	}
+	clock.waiters = pending
+}
+
+// waitForWaiters waits until the given amount of waiters wait for the time to advance
+func (clock *fakeClock) waitForWaiters(t *testing.T, count int) {
+	require.Eventually(t, func() bool {
+		clock.mutex.Lock()
+		defer clock.mutex.Unlock()
+		return len(clock.waiters) == count
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestDeriveSharedObjectClock(t *testing.T) {
+	t.Run("Extraction deadline", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		blockedSO := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/blocked.so"},
+			syms: []string{"open"}}
+		mockLoader := blockingLoaderMock{symbolsLoaderMock: initLoaderMock(), release: make(chan struct{})}
+		mockLoader.addSOSymbols(blockedSO)
+		clock := newFakeClock()
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols:     []string{"open"},
+			ExtractionDeadline: time.Second,
+			Clock:              clock,
+		})
+		require.NoError(t, err)
+
+		result := make(chan []error, 1)
+		go func() {
+			_, errs := SymbolsLoadedFromGenerator(gen)(generateSOLoadedEvent(1, blockedSO.info))
+			result <- errs
+		}()
+		clock.waitForWaiters(t, 1)
+		// The deadline doesn't expire before its whole duration elapses
+		clock.advance(time.Second - time.Nanosecond)
+		clock.waitForWaiters(t, 1)
+		clock.advance(time.Nanosecond)
+		errs := <-result
+		require.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], ErrExtractionTimeout)
+		assert.Equal(t, int32(1), gen.Stats().ExtractionTimeouts.Read())
+
+		close(mockLoader.release)
+		require.NoError(t, gen.Close())
+	})
+
+	t.Run("Summary interval", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+		so := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/1.so"}, syms: []string{"open"}}
+		mockLoader := initLoaderMock()
+		mockLoader.addSOSymbols(so)
+		clock := newFakeClock()
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols:  []string{"open"},
+			SummaryInterval: time.Minute,
+			Clock:           clock,
+		})
+		require.NoError(t, err)
+		_, err = gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+		require.NoError(t, err)
+
+		clock.waitForWaiters(t, 1)
+		clock.advance(time.Minute)
+		summary := <-gen.Summaries()
+		assert.Equal(t, int(clock.Now().UnixNano()), summary.Timestamp)
+		assert.Equal(t, []interface{}{"symbol", []string{"open: 1"}, 1, false}, argsValues(summary))
+
+		// The next interval starts when the summary is emitted
+		clock.waitForWaiters(t, 1)
+		require.NoError(t, gen.Close())
+	})
+}
+
+func generateMmapEvent(pid int, so sharedobjs.ObjInfo, prot int32) trace.Event {
+	event := generateSOLoadedEvent(pid, so)
+	event.EventName = "security_mmap_file"
+	event.EventID = int(events.SecurityMmapFile)
+	event.Args = append(event.Args, trace.Argument{ArgMeta: trace.ArgMeta{Type: "int", Name: "prot"}, Value: prot})
+	return event
+}
+
+func generateMprotectEvent(pid int, so sharedobjs.ObjInfo, prot int32) trace.Event {
+	return trace.Event{
+		EventName:     "security_file_mprotect",
+		EventID:       int(events.SecurityFileMprotect),
+		HostProcessID: pid,
+		ProcessID:     pid,
+		Args: []trace.Argument{
+			{ArgMeta: trace.ArgMeta{Type: "const char*", Name: "pathname"}, Value: so.Path},
+			{ArgMeta: trace.ArgMeta{Type: "int", Name: "prot"}, Value: prot},
+			{ArgMeta: trace.ArgMeta{Type: "unsigned long", Name: "ctime"}, Value: so.Id.Ctime},
+		},
+	}
+}
+
+func TestDeriveSharedObjectExecMapping(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	const protRead, protReadExec = int32(0x1), int32(0x5)
+	sos := make([]soInstance, 4)
+	for i := range sos {
+		sos[i] = soInstance{
+			info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: uint64(i + 1), Ctime: 10}, Path: fmt.Sprintf("/tmp/%d.so", i+1)},
+			syms: []string{"open"},
+		}
+	}
+	dataFile := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 5}, Path: "/tmp/data.bin"},
+		loadErr: &elf.FormatError{}}
+	mockLoader := initLoaderMock()
+	for _, so := range append(sos, dataFile) {
+		mockLoader.addSOSymbols(so)
+	}
+	clock := newFakeClock()
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:       []string{"open"},
+		CorrelateExecMapping: true,
+		ExecMappingTimeout:   time.Second,
+		MaxPendingLoads:      2,
+		Clock:                clock,
+	})
+	require.NoError(t, err)
+	deriveLoad, deriveMapping := SymbolsLoadedFromGenerator(gen), SymbolsLoadedExecMapping(gen)
+	requireDerived := func(derived []trace.Event, errs []error) []interface{} {
+		require.Empty(t, errs)
+		require.Len(t, derived, 1)
+		return argsValues(derived[0])
+	}
+	requireNone := func(derived []trace.Event, errs []error) {
+		require.Empty(t, errs)
+		require.Empty(t, derived)
+	}
+
+	// Loads which are executable when loaded are derived immediately
+	assert.Equal(t, []interface{}{"/tmp/1.so", []string{"open"}, true},
+		requireDerived(deriveLoad(generateSOLoadedEvent(1, sos[0].info))))
+	// Executable mappings of SOs which were not held derive nothing, as their loading event does
+	requireNone(deriveMapping(generateMmapEvent(1, sos[0].info, protReadExec)))
+	// Readable mappings of files which are not SOs are skipped
+	requireNone(deriveMapping(generateMmapEvent(1, dataFile.info, protRead)))
+
+	// A readable mapping is held until the process maps the SO executable, with the context of the readable mapping
+	readable := generateMmapEvent(1, sos[1].info, protRead)
+	readable.Timestamp = 1
+	requireNone(deriveMapping(readable))
+	requireNone(deriveMapping(generateMmapEvent(1, sos[1].info, protRead)))
+	requireNone(deriveMapping(generateMmapEvent(2, sos[1].info, protReadExec)))
+	derived, errs := deriveLoad(generateSOLoadedEvent(1, sos[1].info))
+	assert.Equal(t, []interface{}{"/tmp/2.so", []string{"open"}, true}, requireDerived(derived, errs))
+	assert.Equal(t, 1, derived[0].Timestamp)
+	assert.Equal(t, "symbols_loaded", derived[0].EventName)
+
+	// Protection changes of the file by the process grant execution too, but not readable ones
+	requireNone(deriveMapping(generateMmapEvent(1, sos[2].info, protRead)))
+	requireNone(deriveMapping(generateMprotectEvent(1, sos[2].info, protRead)))
+	requireNone(deriveMapping(generateMprotectEvent(2, sos[2].info, protReadExec)))
+	assert.Equal(t, []interface{}{"/tmp/3.so", []string{"open"}, true},
+		requireDerived(deriveMapping(generateMprotectEvent(1, sos[2].info, protReadExec))))
+
+	// Loads which are not made executable expire when the timeout elapses
+	requireNone(deriveMapping(generateMmapEvent(1, sos[3].info, protRead)))
+	// The timeouts of the loads which were made executable still wait in the fake clock
+	clock.waitForWaiters(t, 3)
+	clock.advance(time.Second)
+	expired := <-gen.ExpiredLoads()
+	assert.Equal(t, []interface{}{"/tmp/4.so", []string{"open"}, false}, argsValues(expired))
+	assert.Equal(t, int32(1), gen.Stats().ExecMappingTimeouts.Read())
+	requireNone(deriveMapping(generateMprotectEvent(1, sos[3].info, protReadExec)))
+
+	// Beyond the maximal amount of held loads, the load held longest expires early
+	for _, so := range sos[:3] {
+		requireNone(deriveMapping(generateMmapEvent(3, so.info, protRead)))
+	}
+	expired = <-gen.ExpiredLoads()
+	assert.Equal(t, "/tmp/1.so", argsValues(expired)[0])
+	clock.waitForWaiters(t, 3)
+	require.NoError(t, gen.Close())
+	_, ok := <-gen.ExpiredLoads()
+	assert.False(t, ok)
+}
+
+func TestDeriveSharedObjectFlaggedDynamicTags(t *testing.T) {
+	auditSO := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libaudit.so"},
+		syms:        []string{"la_version"},
+		dynamicTags: []elf.DynTag{elf.DT_NEEDED, elf.DT_SYMTAB, elf.DT_DEPAUDIT, elf.DT_AUDIT},
+	}
+	preinitSO := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libpreinit.so"},
+		syms:        []string{"open"},
+		dynamicTags: []elf.DynTag{elf.DT_NEEDED, elf.DT_PREINIT_ARRAY},
+	}
+	plainSO := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libplain.so"},
+		syms:        []string{"open"},
+		dynamicTags: []elf.DynTag{elf.DT_NEEDED, elf.DT_SYMTAB},
+	}
+	testCases := []struct {
+		name         string
+		so           soInstance
+		expectedArgs []interface{}
+	}{
+		// The flagged tags are reported even with no matched symbol
+		{name: "Audit tags", so: auditSO,
+			expectedArgs: []interface{}{auditSO.info.Path, []string(nil), []string{"DT_DEPAUDIT", "DT_AUDIT"}}},
+		{name: "Preinit array and symbol", so: preinitSO,
+			expectedArgs: []interface{}{preinitSO.info.Path, []string{"open"}, []string{"DT_PREINIT_ARRAY"}}},
+		{name: "No flagged tag", so: plainSO,
+			expectedArgs: []interface{}{plainSO.info.Path, []string{"open"}, []string(nil)}},
+	}
+
+	mockLoader := initLoaderMock()
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:     []string{"open"},
+		FlaggedDynamicTags: DefaultFlaggedDynamicTags,
+	})
+	require.NoError(t, err)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader.addSOSymbols(testCase.so)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.so.info))
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedArgs, eventArgs)
+		})
+	}
+}
+
+func TestDeriveSharedObjectVersionedImports(t *testing.T) {
+	downgradedSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libdowngraded.so"},
+		importsInfo: []sharedobjs.ImportedSymbolInfo{
+			{Name: "memcpy", Library: "libc.so.6", Version: "GLIBC_2.2.5"},
+			{Name: "dlopen", Library: "libc.so.6", Version: "GLIBC_2.34"},
+		},
+	}
+	currentSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libcurrent.so"},
+		importsInfo: []sharedobjs.ImportedSymbolInfo{
+			{Name: "memcpy", Library: "libc.so.6", Version: "GLIBC_2.14"},
+		},
+	}
+	unversionedSO := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libunversioned.so"},
+		importsSyms: []string{"memcpy", "dlopen"},
+	}
+	testCases := []struct {
+		name            string
+		so              soInstance
+		expectedImports []string
+	}{
+		{name: "Downgraded import", so: downgradedSO, expectedImports: []string{"memcpy@GLIBC_2.2.5", "dlopen"}},
+		{name: "Import of another version", so: currentSO},
+		// Imports with no version match only the plain entries
+		{name: "Unversioned imports", so: unversionedSO, expectedImports: []string{"dlopen"}},
+	}
+
+	mockLoader := initLoaderMock()
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedImports: []string{"memcpy@GLIBC_2.2.5", "dlopen"},
+	})
+	require.NoError(t, err)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader.addSOSymbols(testCase.so)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.so.info))
+			require.NoError(t, err)
+			if testCase.expectedImports == nil {
+				assert.Nil(t, eventArgs)
+				return
+			}
+			require.Len(t, eventArgs, 3)
+			assert.ElementsMatch(t, testCase.expectedImports, eventArgs[2])
+		})
+	}
+}
+
+func TestDeriveSharedObjectLibraryImports(t *testing.T) {
+	cryptSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libauth.so"},
+		importsInfo: []sharedobjs.ImportedSymbolInfo{
+			{Name: "crypt", Library: "libcrypt.so.1", Version: "XCRYPT_2.0"},
+			{Name: "memcpy", Library: "libc.so.6", Version: "GLIBC_2.2.5"},
+		},
+	}
+	otherLibrarySO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libhash.so"},
+		importsInfo: []sharedobjs.ImportedSymbolInfo{
+			{Name: "crypt", Library: "libmycrypt.so", Version: "V1"},
+			{Name: "memcpy", Library: "libc.so.6", Version: "GLIBC_2.14"},
+		},
+	}
+	unversionedSO := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libunversioned.so"},
+		importsSyms: []string{"crypt", "memcpy"},
+	}
+	testCases := []struct {
+		name            string
+		so              soInstance
+		expectedImports []string
+	}{
+		{name: "Imports from the expected libraries", so: cryptSO,
+			expectedImports: []string{"libcrypt!crypt", "libc!memcpy@GLIBC_2.2.5"}},
+		// Imports expected from another library, or of another version, don't match
+		{name: "Imports from other libraries", so: otherLibrarySO},
+		// Imports which are not versioned have no expected library
+		{name: "Unversioned imports", so: unversionedSO},
+	}
+
+	mockLoader := initLoaderMock()
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedImports: []string{"libcrypt!crypt", "libc!memcpy@GLIBC_2.2.5"},
+	})
+	require.NoError(t, err)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader.addSOSymbols(testCase.so)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.so.info))
+			require.NoError(t, err)
+			if testCase.expectedImports == nil {
+				assert.Nil(t, eventArgs)
+				return
+			}
+			require.Len(t, eventArgs, 3)
+			assert.ElementsMatch(t, testCase.expectedImports, eventArgs[2])
+		})
+	}
+
+	// Loaders which don't supply imports information can't match the expected libraries
+	var loader struct {
+		sharedobjs.DynamicSymbolsLoader
+	}
+	_, err = InitSymbolsLoadedEventGenerator(loader, SymbolsLoadedConfig{WatchedImports: []string{"libcrypt!crypt"}})
+	assert.Error(t, err)
+}
+
+func TestDeriveSharedObjectEnrichment(t *testing.T) {
+	taggedSO := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libtagged.so"},
+		syms:   []string{"open", "dlopen"},
+		soname: "libtagged.so.1",
+	}
+	untaggedSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libuntagged.so"},
+		syms: []string{"open"},
+	}
+	var contexts []MatchContext
+	enrichment := SymbolsEnrichment{
+		Fields: []trace.ArgMeta{
+			{Type: "const char*const*", Name: "intel_tags"},
+			{Type: "int", Name: "intel_score"},
+		},
+		Enrich: func(match MatchContext) map[string]interface{} {
+			contexts = append(contexts, match)
+			if match.Soname != "libtagged.so.1" {
+				return nil
+			}
+			// Values which are not of a field are ignored
+			return map[string]interface{}{"intel_tags": []string{"loader"}, "intel_score": 7, "unknown": true}
+		},
+	}
+
+	mockLoader := initLoaderMock()
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols: []string{"open", "dlopen"},
+		Enrichment:     enrichment,
+	})
+	require.NoError(t, err)
+
+	t.Run("Enriched", func(t *testing.T) {
+		contexts = nil
+		mockLoader.addSOSymbols(taggedSO)
+		eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(5, taggedSO.info))
+		require.NoError(t, err)
+		require.Len(t, eventArgs, 4)
+		assert.ElementsMatch(t, []string{"open", "dlopen"}, eventArgs[1])
+		assert.Equal(t, []string{"loader"}, eventArgs[2])
+		assert.Equal(t, 7, eventArgs[3])
+		require.Len(t, contexts, 1)
+		assert.Equal(t, taggedSO.info.Path, contexts[0].Path)
+		assert.Equal(t, "libtagged.so.1", contexts[0].Soname)
+		assert.Equal(t, 5, contexts[0].Pid)
+		assert.ElementsMatch(t, []string{"open", "dlopen"}, contexts[0].Symbols)
+	})
+	t.Run("Missing fields", func(t *testing.T) {
+		contexts = nil
+		mockLoader.addSOSymbols(untaggedSO)
+		eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(5, untaggedSO.info))
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{untaggedSO.info.Path, []string{"open"}, []string(nil), 0}, eventArgs)
+		assert.Len(t, contexts, 1)
+	})
+	t.Run("No match", func(t *testing.T) {
+		contexts = nil
+		noMatchSO := soInstance{
+			info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libother.so"},
+			syms: []string{"read"},
+		}
+		mockLoader.addSOSymbols(noMatchSO)
+		eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(5, noMatchSO.info))
+		require.NoError(t, err)
+		assert.Nil(t, eventArgs)
+		assert.Empty(t, contexts)
+	})
+	t.Run("Clashing field", func(t *testing.T) {
+		_, err := InitSymbolsLoadedEventGenerator(initLoaderMock(), SymbolsLoadedConfig{
+			WatchedSymbols: []string{"open"},
+			ReportObjectID: true,
+			Enrichment: SymbolsEnrichment{
+				Fields: []trace.ArgMeta{{Type: "unsigned long", Name: "inode"}},
+				Enrich: enrichment.Enrich,
+			},
+		})
+		assert.EqualError(t, err, "enrichment field 'inode' is already an argument of the event")
+	})
+}
+
+func TestDeriveSharedObjectSymbolsFingerprint(t *testing.T) {
+	so := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/libssl.so.3"},
+		syms: []string{"open", "SSL_read", "SSL_write"},
+	}
+	// The same symbols in another order
+	reorderedSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/opt/lib/libssl.so.3"},
+		syms: []string{"SSL_write", "open", "SSL_read"},
+	}
+	tamperedSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libssl.so.3"},
+		syms: []string{"open", "SSL_read", "SSL_write", "SSL_hook"},
+	}
+	expectedFingerprint := symbolsFingerprint([]string{"SSL_read", "SSL_write", "open"})
+
+	testCases := []struct {
+		name   string
+		config SymbolsLoadedConfig
+	}{
+		{name: "Exported symbols", config: SymbolsLoadedConfig{WatchedSymbols: []string{"open"},
+			ReportSymbolsFingerprint: true}},
+		{name: "Exported symbols information", config: SymbolsLoadedConfig{WatchedSymbols: []string{"open"},
+			ReportSymbolsFingerprint: true, ReportVisibility: true}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, testCase.config)
+			require.NoError(t, err)
+			fingerprints := make(map[string]string)
+			for _, so := range []soInstance{so, reorderedSO, tamperedSO} {
+				mockLoader.addSOSymbols(so)
+				eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+				require.NoError(t, err)
+				require.NotEmpty(t, eventArgs)
+				fingerprints[so.info.Path] = eventArgs[len(eventArgs)-1].(string)
+			}
+			assert.Equal(t, expectedFingerprint, fingerprints[so.info.Path])
+			assert.Equal(t, expectedFingerprint, fingerprints[reorderedSO.info.Path])
+			assert.NotEqual(t, expectedFingerprint, fingerprints[tamperedSO.info.Path])
+			assert.Len(t, fingerprints[tamperedSO.info.Path], 64)
+		})
+	}
+
+	t.Run("Cached by object", func(t *testing.T) {
+		cache := newFingerprintCache(2)
+		calculations := 0
+		names := func() []string {
+			calculations++
+			return []string{"open"}
+		}
+		id := sharedobjs.ObjID{Inode: 1}
+		first := cache.get(id, names)
+		assert.Equal(t, first, cache.get(id, names))
+		assert.Equal(t, 1, calculations)
+		cache.get(sharedobjs.ObjID{Inode: 2}, names)
+		cache.get(sharedobjs.ObjID{Inode: 3}, names)
+		// The first object was evicted
+		cache.get(id, names)
+		assert.Equal(t, 4, calculations)
+	})
+}
+
+func TestSymbolsLoadedFromObjInfo(t *testing.T) {
+	so := soInstance{info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libevil.so", Pid: 1},
+		syms: []string{"open"}}
+	testCases := []struct {
+		name   string
+		config SymbolsLoadedConfig
+	}{
+		{name: "No deadline", config: SymbolsLoadedConfig{WatchedSymbols: []string{"open"}}},
+		{name: "Extraction deadline", config: SymbolsLoadedConfig{WatchedSymbols: []string{"open"},
+			ExtractionDeadline: time.Minute}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mockLoader := initLoaderMock()
+			mockLoader.addSOSymbols(so)
+			gen, err := InitSymbolsLoadedEventGenerator(mockLoader, testCase.config)
+			require.NoError(t, err)
+			defer gen.Close()
+
+			// The arguments of the event are not parsed again, so an event with no arguments is derived
+			event := generateSOLoadedEvent(1, so.info)
+			event.Args = nil
+			_, errs := SymbolsLoadedFromGenerator(gen)(event)
+			require.Len(t, errs, 1)
+			derived, errs := SymbolsLoadedFromObjInfo(gen)(event, so.info)
+			require.Empty(t, errs)
+			require.Len(t, derived, 1)
+			assert.Equal(t, so.info.Path, derived[0].Args[0].Value)
+			assert.Equal(t, []string{"open"}, derived[0].Args[1].Value)
+			assert.Equal(t, event.HostProcessID, derived[0].HostProcessID)
+		})
+	}
+}
+
+func TestDeriveSharedObjectSelfExecuting(t *testing.T) {
+	selfExecutingSO := soInstance{
+		info:      sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libevil.so"},
+		syms:      []string{"open", "readdir"},
+		initArray: 3,
+	}
+	// Only the frame_dummy entry of GCC
+	plainSO := soInstance{
+		info:      sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libplain.so"},
+		syms:      []string{"open"},
+		initArray: 1,
+	}
+	constructorsOnlySO := soInstance{
+		info:      sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libinit.so"},
+		syms:      []string{"init"},
+		initArray: 2,
+	}
+	testCases := []struct {
+		name             string
+		so               soInstance
+		expectedArgs     []interface{}
+		expectedDecision string
+	}{
+		{name: "Watched symbols and constructors", so: selfExecutingSO,
+			expectedArgs:     []interface{}{selfExecutingSO.info.Path, []string{"open"}, 3, true},
+			expectedDecision: DecisionSelfExecuting},
+		{name: "Watched symbols and no constructors", so: plainSO,
+			expectedArgs:     []interface{}{plainSO.info.Path, []string{"open"}, 1, false},
+			expectedDecision: DecisionMatched},
+		// Constructors alone don't derive the event
+		{name: "Constructors and no watched symbols", so: constructorsOnlySO,
+			expectedDecision: DecisionNoSymbols},
+	}
+
+	mockLoader := initLoaderMock()
+	logger := &symbolsLoadedLoggerMock{}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:     []string{"open"},
+		ReportConstructors: true,
+		Logger:             logger,
+	})
+	require.NoError(t, err)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			logger.entries = nil
+			mockLoader.addSOSymbols(testCase.so)
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.so.info))
+			require.NoError(t, err)
+			if testCase.expectedArgs == nil {
+				assert.Nil(t, eventArgs)
+			} else {
+				assert.Equal(t, testCase.expectedArgs, eventArgs)
+			}
+			require.NotEmpty(t, logger.entries)
+			assert.Equal(t, testCase.expectedDecision, logger.entries[len(logger.entries)-1].Decision)
+		})
+	}
+}
+
+// extractionFailingLoaderMock fails every extraction of symbols, so only the metadata of the SOs can be read
+type extractionFailingLoaderMock struct {
+	symbolsLoaderMock
+}
+
+var errSymbolsExtracted = errors.New("symbols extracted")
+
+func (loader extractionFailingLoaderMock) GetDynamicSymbols(sharedobjs.ObjInfo) (map[string]bool, error) {
+	return nil, errSymbolsExtracted
+}
+
+func (loader extractionFailingLoaderMock) GetExportedSymbols(sharedobjs.ObjInfo) (map[string]bool, error) {
+	return nil, errSymbolsExtracted
+}
+
+func (loader extractionFailingLoaderMock) GetImportedSymbols(sharedobjs.ObjInfo) (map[string]bool, error) {
+	return nil, errSymbolsExtracted
+}
+
+func TestDeriveSharedObjectMetadataOnly(t *testing.T) {
+	suspiciousSO := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libevil.so"},
+		syms:        []string{"open"},
+		soname:      "libevil.so.1",
+		buildID:     "0123abcd",
+		dynamicTags: []elf.DynTag{elf.DT_NEEDED, elf.DT_AUDIT},
+	}
+	allowedSO := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/usr/lib/libc.so.6"},
+		syms:   []string{"open"},
+		soname: "libc.so.6",
+	}
+	unreadableSO := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libunreadable.so"},
+		loadErr: fs.ErrPermission,
+	}
+
+	mockLoader := extractionFailingLoaderMock{initLoaderMock()}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		MetadataOnly:       true,
+		WhitelistedLibs:    []string{"/usr/lib/"},
+		FlaggedDynamicTags: DefaultFlaggedDynamicTags,
+	})
+	require.NoError(t, err)
+	for _, so := range []soInstance{suspiciousSO, allowedSO, unreadableSO} {
+		mockLoader.addSOSymbols(so)
+	}
+
+	eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, suspiciousSO.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{suspiciousSO.info.Path, []string(nil), []string{"DT_AUDIT"}, true, "libevil.so.1",
+		"0123abcd"}, eventArgs)
+
+	eventArgs, err = gen.deriveArgs(generateSOLoadedEvent(1, allowedSO.info))
+	require.NoError(t, err)
+	assert.Nil(t, eventArgs)
+
+	eventArgs, err = gen.deriveArgs(generateSOLoadedEvent(1, unreadableSO.info))
+	require.NoError(t, err)
+	assert.Nil(t, eventArgs)
+
+	// Loaders which can't read metadata can't be used
+	var symbolsOnlyLoader struct {
+		sharedobjs.DynamicSymbolsLoader
+	}
+	symbolsOnlyLoader.DynamicSymbolsLoader = mockLoader
+	_, err = InitSymbolsLoadedEventGenerator(symbolsOnlyLoader, SymbolsLoadedConfig{MetadataOnly: true})
+	assert.ErrorContains(t, err, "can't read metadata")
+}
+
+func TestDeriveSharedObjectPassthroughArgs(t *testing.T) {
+	so := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1234, Device: 2049, Ctime: 1650000000}, Path: "/tmp/hook.so"},
+		syms: []string{"open"},
+	}
+	mockLoader := initLoaderMock()
+	mockLoader.addSOSymbols(so)
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:  []string{"open"},
+		PassthroughArgs: []string{"flags", "pathname", "dev"},
+	})
+	require.NoError(t, err)
+	loadEvent := generateSOLoadedEvent(1, so.info)
+	loadEvent.Args[1].Value = int32(0x2)
+
+	derived, errs := SymbolsLoadedFromGenerator(gen)(loadEvent)
+	require.Empty(t, errs)
+	require.Len(t, derived, 1)
+	assert.Equal(t, []trace.Argument{
+		{ArgMeta: trace.ArgMeta{Type: "const char*", Name: "library_path"}, Value: so.info.Path},
+		{ArgMeta: trace.ArgMeta{Type: "const char*const*", Name: "symbols"}, Value: []string{"open"}},
+		{ArgMeta: trace.ArgMeta{Type: "int", Name: "flags"}, Value: 0x2},
+		{ArgMeta: trace.ArgMeta{Type: "const char*", Name: "pathname"}, Value: so.info.Path},
+		{ArgMeta: trace.ArgMeta{Type: "dev_t", Name: "dev"}, Value: uint32(2049)},
+	}, derived[0].Args)
+
+	// Source events missing a passed through argument are not derived
+	loadEvent.Args = append(loadEvent.Args[:1], loadEvent.Args[2:]...)
+	derived, errs = SymbolsLoadedFromGenerator(gen)(loadEvent)
+	assert.Empty(t, derived)
+	assert.Len(t, errs, 1)
+
+	// No argument is passed through by default
+	gen, err = InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{WatchedSymbols: []string{"open"}})
+	require.NoError(t, err)
+	eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{so.info.Path, []string{"open"}}, eventArgs)
+}
+
+func TestDeriveSharedObjectSymbolsTruncation(t *testing.T) {
+	// The loader read only the first symbols of the truncated SO, so its watched symbols beyond them are not matched
+	truncated := soInstance{
+		info:      sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/libgenerated.so"},
+		syms:      []string{"generated_0", "open"},
+		truncated: true,
+	}
+	whole := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libhook.so"},
+		syms: []string{"open", "write"},
+	}
+	truncatedNoMatch := soInstance{
+		info:      sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/usr/lib/libother.so"},
+		syms:      []string{"generated_1"},
+		truncated: true,
+	}
+	mockLoader := initLoaderMock()
+	for _, so := range []soInstance{truncated, whole, truncatedNoMatch} {
+		mockLoader.addSOSymbols(so)
+	}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:          []string{"open", "write"},
+		ReportSymbolsTruncation: true,
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		so       soInstance
+		expected []interface{}
+	}{
+		{name: "Truncated symbols", so: truncated,
+			expected: []interface{}{truncated.info.Path, []string{"open"}, true}},
+		// The matched symbols are sorted, whether or not they were truncated
+		{name: "Whole symbols", so: whole,
+			expected: []interface{}{whole.info.Path, []string{"open", "write"}, false}},
+		// Truncation alone doesn't derive the event
+		{name: "Truncated symbols with no match", so: truncatedNoMatch},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.so.info))
+			require.NoError(t, err)
+			if testCase.expected == nil {
+				assert.Nil(t, eventArgs)
+				return
+			}
+			assert.Equal(t, testCase.expected, eventArgs)
+		})
+	}
+
+	// Loaders which can't detect truncation can't report it
+	var loader struct {
+		sharedobjs.DynamicSymbolsLoader
+	}
+	_, err = InitSymbolsLoadedEventGenerator(loader, SymbolsLoadedConfig{
+		WatchedSymbols:          []string{"open"},
+		ReportSymbolsTruncation: true,
+	})
+	assert.Error(t, err)
+}
+
+func TestDeriveSharedObjectUnusualInterpreter(t *testing.T) {
+	standard := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/bin/app"},
+		syms:   []string{"main"},
+		interp: "/lib64/ld-linux-x86-64.so.2",
+	}
+	unusual := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/app"},
+		syms:   []string{"main"},
+		interp: "/tmp/.x/ld.so",
+	}
+	escaping := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/other"},
+		interp: "/lib64/../tmp/ld.so",
+	}
+	nested := soInstance{
+		info:   sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 4}, Path: "/tmp/nested"},
+		interp: "/lib/evil/ld-linux.so.2",
+	}
+	noInterp := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 5}, Path: "/tmp/libhook.so"},
+		syms: []string{"open"},
+	}
+	mockLoader := initLoaderMock()
+	for _, so := range []soInstance{standard, unusual, escaping, nested, noInterp} {
+		mockLoader.addSOSymbols(so)
+	}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:         []string{"open"},
+		FlagUnusualInterpreter: true,
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		so       soInstance
+		expected []interface{}
+	}{
+		{name: "Standard interpreter", so: standard},
+		{name: "Unusual interpreter", so: unusual,
+			expected: []interface{}{unusual.info.Path, []string(nil), "/tmp/.x/ld.so", true}},
+		{name: "Interpreter escaping the libraries directory", so: escaping,
+			expected: []interface{}{escaping.info.Path, []string(nil), "/lib64/../tmp/ld.so", true}},
+		{name: "Interpreter nested in a libraries directory", so: nested,
+			expected: []interface{}{nested.info.Path, []string(nil), "/lib/evil/ld-linux.so.2", true}},
+		// SOs request no interpreter, and are derived for their symbols
+		{name: "No interpreter", so: noInterp,
+			expected: []interface{}{noInterp.info.Path, []string{"open"}, "", false}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.so.info))
+			require.NoError(t, err)
+			if testCase.expected == nil {
+				assert.Nil(t, eventArgs)
+				return
+			}
+			assert.Equal(t, testCase.expected, eventArgs)
+		})
+	}
+}
+
+func TestDeriveSharedObjectUserSeverity(t *testing.T) {
+	so := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libhook.so"},
+		syms: []string{"open"},
+	}
+	mockLoader := initLoaderMock()
+	mockLoader.addSOSymbols(so)
+	loadEvent := func(uid int) trace.Event {
+		event := generateSOLoadedEvent(1, so.info)
+		event.UserID = uid
+		return event
+	}
+
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols: []string{"open"},
+		UserSeverities: map[int]SymbolsSeverity{
+			0:    {Severity: "high", Action: "page"},
+			1000: {Severity: "medium"},
+		},
+		DefaultSeverity: SymbolsSeverity{Severity: "low", Action: "ticket"},
+	})
+	require.NoError(t, err)
+	testCases := []struct {
+		name     string
+		uid      int
+		severity string
+		action   string
+	}{
+		{name: "Root process", uid: 0, severity: "high", action: "page"},
+		{name: "User with no action", uid: 1000, severity: "medium"},
+		{name: "Default severity", uid: 1001, severity: "low", action: "ticket"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			eventArgs, err := gen.deriveArgs(loadEvent(testCase.uid))
+			require.NoError(t, err)
+			assert.Equal(t, []interface{}{so.info.Path, []string{"open"}, testCase.severity, testCase.action}, eventArgs)
+		})
+	}
+
+	// Users have an empty severity if no default is configured
+	gen, err = InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols: []string{"open"},
+		UserSeverities: map[int]SymbolsSeverity{0: {Severity: "high"}},
+	})
+	require.NoError(t, err)
+	eventArgs, err := gen.deriveArgs(loadEvent(1000))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{so.info.Path, []string{"open"}, "", ""}, eventArgs)
+}
+
+func TestDeriveSharedObjectReportContainer(t *testing.T) {
+	so := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libhook.so"},
+		syms: []string{"open"},
+	}
+	mockLoader := initLoaderMock()
+	mockLoader.addSOSymbols(so)
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:  []string{"open"},
+		ReportContainer: true,
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		containerID   string
+		image         string
+		expectedID    string
+		expectedImage string
+	}{
+		{name: "Container", containerID: "abc123", image: "nginx:1.25", expectedID: "abc123", expectedImage: "nginx:1.25"},
+		{name: "Host process", expectedImage: HostContainerImage},
+		{name: "Container with unresolved image", containerID: "abc123", expectedID: "abc123"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			event := generateSOLoadedEvent(1, so.info)
+			event.ContainerID = testCase.containerID
+			event.ContainerImage = testCase.image
+			eventArgs, err := gen.deriveArgs(event)
+			require.NoError(t, err)
+			assert.Equal(t, []interface{}{so.info.Path, []string{"open"}, testCase.expectedID, testCase.expectedImage},
+				eventArgs)
+		})
+	}
+
+	// The container is not reported unless configured
+	gen, err = InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{WatchedSymbols: []string{"open"}})
+	require.NoError(t, err)
+	event := generateSOLoadedEvent(1, so.info)
+	event.ContainerID = "abc123"
+	eventArgs, err := gen.deriveArgs(event)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{so.info.Path, []string{"open"}}, eventArgs)
+}
+
+func TestDeriveSharedObjectHighEntropy(t *testing.T) {
+	packed := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libpacked.so"},
+		syms:    []string{"init"},
+		entropy: 7.9,
+	}
+	compiled := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libhook.so"},
+		syms:    []string{"open", "close"},
+		entropy: 6.1,
+	}
+	plain := soInstance{
+		info:    sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libplain.so"},
+		syms:    []string{"close"},
+		entropy: 6.1,
+	}
+	mockLoader := initLoaderMock()
+	for _, so := range []soInstance{packed, compiled, plain} {
+		mockLoader.addSOSymbols(so)
+	}
+
+	t.Run("Default threshold", func(t *testing.T) {
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols:  []string{"open"},
+			FlagHighEntropy: true,
+		})
+		require.NoError(t, err)
+
+		testCases := []struct {
+			name     string
+			so       soInstance
+			expected []interface{}
+		}{
+			// High entropy code derives the event even with no matched symbols
+			{name: "High entropy", so: packed,
+				expected: []interface{}{packed.info.Path, []string(nil), 7.9, true}},
+			{name: "Matched symbols", so: compiled,
+				expected: []interface{}{compiled.info.Path, []string{"open"}, 6.1, false}},
+			{name: "No match", so: plain},
+		}
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.so.info))
+				require.NoError(t, err)
+				if testCase.expected == nil {
+					assert.Nil(t, eventArgs)
+					return
+				}
+				assert.Equal(t, testCase.expected, eventArgs)
+			})
+		}
+	})
+
+	t.Run("Configured threshold", func(t *testing.T) {
+		gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+			WatchedSymbols:   []string{"open"},
+			FlagHighEntropy:  true,
+			EntropyThreshold: 6,
+		})
+		require.NoError(t, err)
+		eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, plain.info))
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{plain.info.Path, []string(nil), 6.1, true}, eventArgs)
+	})
+
+	// Loaders which can't measure entropy can't flag it
+	var loader struct {
+		sharedobjs.DynamicSymbolsLoader
+	}
+	_, err := InitSymbolsLoadedEventGenerator(loader, SymbolsLoadedConfig{
+		WatchedSymbols:  []string{"open"},
+		FlagHighEntropy: true,
+	})
+	assert.Error(t, err)
+}
+
+func TestNewSymbolsLoadedGenerator(t *testing.T) {
+	t.Run("Composed options", func(t *testing.T) {
+		base := []SymbolsLoadedOption{
+			WithWatchedSymbols("open"),
+			WithWhitelist("/usr/lib/"),
+		}
+		config := newSymbolsLoadedSettings(append(base,
+			WithWatchedSymbols("EVP_*"),
+			WithExcludedSymbols("EVP_Cleanup"),
+			WithWhitelist("libc"),
+			WithMaxSymbolsPerEvent(5),
+		)...)
+		assert.Equal(t, symbolsLoadedSettings{
+			Matching: SymbolsMatchingConfig{
+				WatchedSymbols:  []string{"open", "EVP_*"},
+				ExcludedSymbols: []string{"EVP_Cleanup"},
+				WhitelistedLibs: []string{"/usr/lib/", "libc"},
+			},
+			Reporting: SymbolsReportingConfig{
+				MaxSymbolsPerEvent: 5,
+			},
+		}, config)
+		assert.Equal(t, symbolsLoadedSettings{}, newSymbolsLoadedSettings())
+	})
+
+	mockLoader := initLoaderMock()
+	whitelisted := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/usr/lib/libc.so.6"},
+		syms: []string{"open"},
+	}
+	hook := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libhook.so"},
+		syms: []string{"open", "dlopen"},
+	}
+	for _, so := range []soInstance{whitelisted, hook} {
+		mockLoader.addSOSymbols(so)
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []SymbolsLoadedOption
+		expected map[string][]interface{}
+	}{
+		{
+			name: "Watched symbols and whitelist",
+			opts: []SymbolsLoadedOption{WithWatchedSymbols("open"), WithWhitelist("/usr/lib/")},
+			expected: map[string][]interface{}{
+				hook.info.Path: {hook.info.Path, []string{"open"}},
+			},
+		},
+		{
+			name: "Allowlist",
+			opts: []SymbolsLoadedOption{WithWatchedSymbols("open"), WithAllowlist("/usr/lib/")},
+			expected: map[string][]interface{}{
+				whitelisted.info.Path: {whitelisted.info.Path, []string{"open"}},
+			},
+		},
+		{
+			name: "Excluded symbols",
+			opts: []SymbolsLoadedOption{WithWatchedSymbols("dl*"), WithExcludedSymbols("dlopen"),
+				WithWatchedSymbols("open")},
+			expected: map[string][]interface{}{
+				whitelisted.info.Path: {whitelisted.info.Path, []string{"open"}},
+				hook.info.Path:        {hook.info.Path, []string{"open"}},
+			},
+		},
+		{
+			name: "Group option",
+			opts: []SymbolsLoadedOption{WithWatchedSymbols("open"), WithReporting(SymbolsReportingConfig{
+				ReportSymbolsCount: true,
+			})},
+			expected: map[string][]interface{}{
+				whitelisted.info.Path: {whitelisted.info.Path, []string{"open"}, 1, "tiny"},
+				hook.info.Path:        {hook.info.Path, []string{"open"}, 2, "tiny"},
+			},
+		},
+		{
+			name: "Minimal matches",
+			opts: []SymbolsLoadedOption{WithWatchedSymbols("open", "dlopen"), WithMinMatches(2)},
+			expected: map[string][]interface{}{
+				hook.info.Path: {hook.info.Path, []string{"dlopen", "open"}},
+			},
+		},
+		{
+			name: "All symbols match mode",
+			opts: []SymbolsLoadedOption{WithWatchedSymbols("open", "dlopen"), WithMatchMode(MatchAllSymbols)},
+			expected: map[string][]interface{}{
+				hook.info.Path: {hook.info.Path, []string{"dlopen", "open"}},
+			},
+		},
+		{
+			// The always watched symbols are matched regardless of the minimal matches
+			name: "All symbols match mode with always watched symbols",
+			opts: []SymbolsLoadedOption{WithWatchedSymbols("open", "dlopen", "dlsym"), WithMatchMode(MatchAllSymbols),
+				WithAlwaysWatchedSymbols("dlopen")},
+			expected: map[string][]interface{}{
+				hook.info.Path: {hook.info.Path, []string{"dlopen"}},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			gen, err := NewSymbolsLoadedGenerator(mockLoader, testCase.opts...)
+			require.NoError(t, err)
+			for _, so := range []soInstance{whitelisted, hook} {
+				eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, so.info))
+				require.NoError(t, err)
+				expected, ok := testCase.expected[so.info.Path]
+				if !ok {
+					assert.Nil(t, eventArgs, so.info.Path)
+					continue
+				}
+				assert.Equal(t, expected, eventArgs, so.info.Path)
+			}
+		})
+	}
+
+	// The built configuration is validated like a given one
+	_, err := NewSymbolsLoadedGenerator(mockLoader, WithWatchedSymbols("open"), WithWhitelist("nss_[a-"))
+	assert.Error(t, err)
+	_, err = NewSymbolsLoadedGenerator(mockLoader, WithWatchedSymbols("open", "dl*"), WithMatchMode(MatchAllSymbols))
+	assert.Error(t, err)
+	_, err = NewSymbolsLoadedGenerator(mockLoader, WithWatchedSymbols("open"), WithMinMatches(-1))
+	assert.Error(t, err)
+}
+
+func TestDeriveSharedObjectRelocationThresholds(t *testing.T) {
+	irelative := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libifunc.so"},
+		syms:        []string{"init"},
+		relocations: map[string]int{"R_X86_64_IRELATIVE": 12, "R_X86_64_GLOB_DAT": 40},
+	}
+	copies := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/app"},
+		syms:        []string{"open"},
+		relocations: map[string]int{"R_X86_64_COPY": 3, "R_X86_64_IRELATIVE": 1},
+	}
+	usual := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/tmp/libplain.so"},
+		syms:        []string{"close"},
+		relocations: map[string]int{"R_X86_64_JUMP_SLOT": 100},
+	}
+	mockLoader := initLoaderMock()
+	for _, so := range []soInstance{irelative, copies, usual} {
+		mockLoader.addSOSymbols(so)
+	}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:       []string{"open"},
+		RelocationThresholds: map[string]int{"R_X86_64_IRELATIVE": 4, "R_X86_64_COPY": 8},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		so       soInstance
+		expected []interface{}
+	}{
+		// Exceeding a threshold derives the event even with no matched symbols
+		{name: "Exceeded threshold", so: irelative,
+			expected: []interface{}{irelative.info.Path, []string(nil), []string{"R_X86_64_IRELATIVE=12"},
+				[]string{"R_X86_64_IRELATIVE"}}},
+		{name: "Matched symbols within thresholds", so: copies,
+			expected: []interface{}{copies.info.Path, []string{"open"},
+				[]string{"R_X86_64_COPY=3", "R_X86_64_IRELATIVE=1"}, []string(nil)}},
+		{name: "No relocations of the types", so: usual},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.so.info))
+			require.NoError(t, err)
+			if testCase.expected == nil {
+				assert.Nil(t, eventArgs)
+				return
+			}
+			assert.Equal(t, testCase.expected, eventArgs)
+		})
+	}
+
+	// Loaders which can't count relocations can't apply thresholds
+	var loader struct {
+		sharedobjs.DynamicSymbolsLoader
+	}
+	_, err = InitSymbolsLoadedEventGenerator(loader, SymbolsLoadedConfig{
+		RelocationThresholds: map[string]int{"R_X86_64_COPY": 8},
+	})
+	assert.Error(t, err)
+}
+
+func TestDeriveSharedObjectCompactSymbols(t *testing.T) {
+	hook := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libhook.so"},
+		syms: []string{"close", "open", "write"},
+	}
+	imports := soInstance{
+		info:        sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/tmp/libloader.so"},
+		importsSyms: []string{"dlopen"},
+	}
+	mockLoader := initLoaderMock()
+	mockLoader.addSOSymbols(hook)
+	mockLoader.addSOSymbols(imports)
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:     []string{"open", "write", "close"},
+		WatchedImports:     []string{"dlopen"},
+		MaxSymbolsPerEvent: 2,
+		CompactSymbols:     true,
+	})
+	require.NoError(t, err)
+
+	// The symbols are joined after they are truncated, and the symbols argument is left empty
+	eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, hook.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{hook.info.Path, []string(nil), true, 3, "close,open", 2, []string(nil)}, eventArgs)
+
+	eventArgs, err = gen.deriveArgs(generateSOLoadedEvent(1, imports.info))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{imports.info.Path, []string(nil), false, 0, "", 0, []string{"dlopen"}}, eventArgs)
+}
+
+func TestDeriveSharedObjectConfidence(t *testing.T) {
+	executable := func(name string, bind elf.SymBind) sharedobjs.SymbolInfo {
+		return sharedobjs.SymbolInfo{Name: name, Bind: bind, Type: elf.STT_FUNC, ExecutableSection: true}
+	}
+	hookSO := soInstance{
+		info:     sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 1}, Path: "/tmp/libhook.so"},
+		symsInfo: []sharedobjs.SymbolInfo{executable("open", elf.STB_GLOBAL), executable("close", elf.STB_GLOBAL)},
+	}
+	mixedSO := soInstance{
+		info: sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 2}, Path: "/opt/app/libmixed.so"},
+		symsInfo: []sharedobjs.SymbolInfo{executable("open", elf.STB_GLOBAL),
+			{Name: "close", Bind: elf.STB_WEAK, Type: elf.STT_FUNC}},
+	}
+	stubSO := soInstance{
+		info:     sharedobjs.ObjInfo{Id: sharedobjs.ObjID{Inode: 3}, Path: "/usr/lib/libstub.so"},
+		symsInfo: []sharedobjs.SymbolInfo{{Name: "open", Bind: elf.STB_WEAK, Type: elf.STT_FUNC}},
+	}
+	mockLoader := initLoaderMock()
+	for _, so := range []soInstance{hookSO, mixedSO, stubSO} {
+		mockLoader.addSOSymbols(so)
+	}
+	logger := &symbolsLoadedLoggerMock{}
+	gen, err := InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:   []string{"open", "close"},
+		ReportConfidence: true,
+		MinConfidence:    0.4,
+		Logger:           logger,
+	})
+	require.NoError(t, err)
+
+	// With no versioned symbols and watch groups, the score combines the bind, section and path signals only
+	testCases := []struct {
+		name       string
+		so         soInstance
+		confidence float64
+	}{
+		{name: "Strong executable symbols", so: hookSO, confidence: (0.25 + 0.25 + 0.2*0.5) / 0.7},
+		{name: "Partly weak symbols", so: mixedSO, confidence: (0.25*0.5 + 0.25*0.5 + 0.2*0.5) / 0.7},
+		// Weak non executable symbols in the libraries directories score 0, and are dropped
+		{name: "Weak stub in the libraries directories", so: stubSO, confidence: -1},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, testCase.so.info))
+			require.NoError(t, err)
+			if testCase.confidence < 0 {
+				assert.Nil(t, eventArgs)
+				return
+			}
+			require.Len(t, eventArgs, 3)
+			assert.InDelta(t, testCase.confidence, eventArgs[2], 1e-9)
+		})
+	}
+	require.NotEmpty(t, logger.entries)
+	assert.Equal(t, DecisionLowConfidence, logger.entries[len(logger.entries)-1].Decision)
+
+	// A custom scorer receives the signals of the match, and its score is clamped
+	var signals ConfidenceSignals
+	gen, err = InitSymbolsLoadedEventGenerator(mockLoader, SymbolsLoadedConfig{
+		WatchedSymbols:   []string{"open", "close"},
+		ReportConfidence: true,
+		ConfidenceScorer: func(matchSignals ConfidenceSignals) float64 {
+			signals = matchSignals
+			return 2
+		},
+	})
+	require.NoError(t, err)
+	eventArgs, err := gen.deriveArgs(generateSOLoadedEvent(1, mixedSO.info))
+	require.NoError(t, err)
+	require.Len(t, eventArgs, 3)
+	assert.Equal(t, 1.0, eventArgs[2])
+	assert.Equal(t, ConfidenceSignals{Symbols: 2, SymbolsInfo: true, GlobalSymbols: 1, ExecutableSymbols: 1}, signals)
+}
+
+func TestWeightedConfidence(t *testing.T) {
+	testCases := []struct {
+		name       string
+		weights    ConfidenceWeights
+		signals    ConfidenceSignals
+		confidence float64
+	}{
+		// The bind and section signals are unknown, so only the path and groups signals are averaged
+		{name: "Suspicious SO matching a group", weights: DefaultConfidenceWeights,
+			signals:    ConfidenceSignals{Suspicious: true, GroupsWatched: true, Groups: 1},
+			confidence: 1},
+		{name: "Ignored SO with no versioned match", weights: DefaultConfidenceWeights,
+			signals:    ConfidenceSignals{Symbols: 1, Ignored: true, VersionedWatched: true},
+			confidence: 0},
+		{name: "Custom weights", weights: ConfidenceWeights{Bind: 1, Path: 1},
+			signals:    ConfidenceSignals{Symbols: 2, SymbolsInfo: true, GlobalSymbols: 1},
+			confidence: (0.5 + 0.5) / 2},
+		{name: "No known weighted signal", weights: ConfidenceWeights{Versioned: 1},
+			signals: ConfidenceSignals{Suspicious: true}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.InDelta(t, testCase.confidence, WeightedConfidence(testCase.weights)(testCase.signals), 1e-9)
+		})
+	}
+}
+
+type pathLoaderMock struct {
+	sharedobjs.DynamicSymbolsLoader
+	symbols map[string]map[string]bool
+	err     error
+}
+
+func (loader pathLoaderMock) GetExportedSymbols(info sharedobjs.ObjInfo) (map[string]bool, error) {
+	return loader.symbols[info.Path], loader.err
+}
+
+func TestSymbolsLoadedEventGenerator_SelfTest(t *testing.T) {
+	libsDir := t.TempDir()
+	libraryPath := filepath.Join(libsDir, "libtest.so.1")
+	require.NoError(t, os.WriteFile(libraryPath, []byte("\x7fELF"), 0644))
+	missingPath := filepath.Join(libsDir, "libmissing.so")
+
+	testCases := []struct {
+		name     string
+		loader   pathLoaderMock
+		config   SymbolsLoadedConfig
+		expected SymbolsSelfTestResult
+		healthy  bool
+	}{
+		{name: "Extracted symbols",
+			loader:   pathLoaderMock{symbols: map[string]map[string]bool{libraryPath: {"open": true, "close": true}}},
+			config:   SymbolsLoadedConfig{SelfTestLibrary: libraryPath},
+			expected: SymbolsSelfTestResult{Library: libraryPath, Readable: true, Parsed: true, Symbols: 2},
+			healthy:  true},
+		// Libraries configured by their file name are looked up in the libraries directories
+		{name: "Library in the libraries directories",
+			loader: pathLoaderMock{symbols: map[string]map[string]bool{libraryPath: {"open": true}}},
+			config: SymbolsLoadedConfig{SelfTestLibrary: "libtest.so.1", WhitelistedLibs: []string{"libignored"},
+				LibraryPath: libsDir},
+			expected: SymbolsSelfTestResult{Library: libraryPath, Readable: true, Parsed: true, Symbols: 1},
+			healthy:  true},
+		{name: "Missing library",
+			config:   SymbolsLoadedConfig{SelfTestLibrary: missingPath},
+			expected: SymbolsSelfTestResult{Library: missingPath}},
+		{name: "Failed extraction",
+			loader:   pathLoaderMock{err: fs.ErrPermission},
+			config:   SymbolsLoadedConfig{SelfTestLibrary: libraryPath},
+			expected: SymbolsSelfTestResult{Library: libraryPath, Readable: true}},
+		// Extracting no symbols at all indicates the extraction doesn't work
+		{name: "No symbols",
+			config:   SymbolsLoadedConfig{SelfTestLibrary: libraryPath},
+			expected: SymbolsSelfTestResult{Library: libraryPath, Readable: true, Parsed: true}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.config.WatchedSymbols = []string{"open"}
+			gen, err := InitSymbolsLoadedEventGenerator(testCase.loader, testCase.config)
+			require.NoError(t, err)
+			result := gen.SelfTest()
+			assert.Equal(t, testCase.healthy, result.Healthy(), result.Err)
+			result.Err = nil
+			assert.Equal(t, testCase.expected, result)
+		})
+	}
+
+	gen, err := InitSymbolsLoadedEventGenerator(pathLoaderMock{}, SymbolsLoadedConfig{
+		WatchedSymbols:  []string{"open"},
+		SelfTestLibrary: libraryPath,
+	})
+	require.NoError(t, err)
+	require.NoError(t, gen.Close())
+	assert.False(t, gen.SelfTest().Healthy())
+}
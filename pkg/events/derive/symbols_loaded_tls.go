@@ -0,0 +1,28 @@
+package derive
+
+import (
+	"debug/elf"
+	"sort"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// matchWatchedTLSSymbols returns the watched TLS symbols which the SO exports as thread-local symbols (STT_TLS), in
+// alphabetical order. Symbols of other types with a watched TLS symbol name are not matched.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchWatchedTLSSymbols(objInfo sharedobjs.ObjInfo) ([]string, error) {
+	if symbsLoadedGen.watchedTLS == nil {
+		return nil, nil
+	}
+	symbolsInfo, err := symbsLoadedGen.symbolsInfoLoader.GetExportedSymbolsInfo(objInfo)
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for sym := range symbsLoadedGen.watchedTLS {
+		if info, ok := symbolsInfo[sym]; ok && info.Type == elf.STT_TLS {
+			matched = append(matched, sym)
+		}
+	}
+	sort.Strings(matched)
+	return matched, nil
+}
@@ -0,0 +1,247 @@
+package derive
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// SymbolsTrackingConfig is the configuration of the state kept across the examined SOs (e.g. by process or by
+// soname), and of the events derived from it
+type SymbolsTrackingConfig struct {
+	// Add whether the match changed since the same SO path was last loaded by the same process to the event
+	ReportChanges bool
+	// Don't derive the event for SO paths reloaded by the same process with the same match
+	SuppressUnchanged bool
+	// Maximal amount of (process, SO path) pairs whose last match is kept for detecting changes.
+	// If 0, DefaultMatchHistorySize is used.
+	MatchHistorySize int
+	// Interval of emitting the symbols_loaded_summary event, which aggregates the matches in the interval.
+	// If 0, no summary is emitted.
+	SummaryInterval time.Duration
+	// By what the matches are aggregated in the summary
+	SummaryKey SummaryKey
+	// Maximal amount of (key, value) pairs kept for a summary. If 0, DefaultMaxSummaryEntries is used.
+	MaxSummaryEntries int
+	// Add the sequence number of each SO load in the loading process (starting from 1) to the event. Every load is
+	// counted, including loads of ignored SOs and SOs with no match.
+	ReportLoadOrder bool
+	// Maximal amount of processes whose loads sequence is kept. If 0, DefaultLoadOrderProcesses is used.
+	LoadOrderProcesses int
+	// Hold the event of each load of a SO whose loading event doesn't make it executable (a readable mapping of the
+	// SO, as the security_mmap_file events received by SymbolsLoadedExecMapping), until a mapping or protection
+	// change of the same process grants the SO execution. Whether execution was granted is added to the event.
+	// Loads which are not made executable within ExecMappingTimeout are emitted by ExpiredLoads.
+	CorrelateExecMapping bool
+	// Duration which a load is held for until its SO is made executable. If 0, DefaultExecMappingTimeout is used.
+	ExecMappingTimeout time.Duration
+	// Maximal amount of loads held at once. If 0, DefaultMaxPendingLoads is used.
+	MaxPendingLoads int
+	// Remember the (soname, build ID) pairs of the loaded SOs, to derive the soname_build_id_seen event the first
+	// time each pair is seen
+	TrackBuildIDs bool
+	// Maximal amount of (soname, build ID) pairs remembered as seen. If 0, DefaultMaxSeenBuildIDs is used.
+	MaxSeenBuildIDs int
+	// Remember the paths each soname of the loaded SOs was seen loaded from, to derive the soname_path_changed event
+	// when a soname appears from a new path
+	TrackSonamePaths bool
+	// Maximal amount of sonames whose paths are remembered. If 0, DefaultMaxTrackedSonames is used.
+	MaxTrackedSonames int
+	// Maximal amount of paths remembered for each soname. If 0, DefaultMaxSonamePaths is used.
+	MaxSonamePaths int
+	// Derive the soname_path_changed event for SOs exporting no watched symbols too. By default, the paths of all the
+	// SOs are remembered, but only SOs exporting watched symbols derive the event.
+	ReportAllSonamePaths bool
+	// Accumulate the matched symbols of each process during its lifetime, to derive the symbols_loaded_profile
+	// event when it exits
+	ProfileProcesses bool
+	// Maximal amount of processes whose profile is kept. If 0, DefaultMaxProfiledProcesses is used.
+	MaxProfiledProcesses int
+	// Keep the watched symbols which each process gained from the SOs it loaded, to derive the
+	// symbols_capability_gained event when a SO gives a process a watched symbol it didn't have
+	TrackCapabilities bool
+	// Maximal amount of processes whose gained symbols are kept. If 0, DefaultCapabilitiesProcesses is used.
+	CapabilitiesProcesses int
+	// Maximal amount of symbols and SOs kept in the profile of a process. If 0, DefaultMaxProfileEntries is used.
+	MaxProfileEntries int
+}
+
+// symbolsTracker is the tracking feature of the generator: the state kept across the examined SOs, and the events
+// derived from it
+type symbolsTracker struct {
+	history           *matchHistory // Set only if changes of matches are tracked
+	suppressUnchanged bool
+	loadSequences     *loadSequences           // Set only if the load order is reported
+	buildIDLoader     sharedobjs.BuildIDLoader // Set only if build IDs are tracked
+	inventory         *buildIDInventory        // Set only if build IDs are tracked
+	sonamePaths       *sonamePathsHistory      // Set only if the paths of sonames are tracked
+	reportAllPaths    bool                     // Derive soname_path_changed for SOs exporting no watched symbols
+	summary           *symbolsSummary          // Set only if summaries are configured
+	profiles          *processProfiles         // Set only if processes profiles are configured
+	capabilities      *processCapabilities     // Set only if gained capabilities are tracked
+	summaryEvents     chan trace.Event
+	summaryDone       chan struct{}
+	summaryWG         sync.WaitGroup
+	pending           *pendingLoads // Set only if loads are correlated with their executable mapping
+	execTimeout       time.Duration
+	execGrantedArg    int // The index of the exec_granted argument
+	expiredEvents     chan trace.Event
+	pendingDone       chan struct{}
+	pendingWG         sync.WaitGroup
+}
+
+// init sets up the state kept across the examined SOs with the given configuration. The summaries are emitted only
+// once the generator is started.
+func (tracker *symbolsTracker) init(soLoader sharedobjs.DynamicSymbolsLoader, config SymbolsTrackingConfig) error {
+	if config.ReportChanges || config.SuppressUnchanged {
+		historySize := config.MatchHistorySize
+		if historySize == 0 {
+			historySize = DefaultMatchHistorySize
+		}
+		tracker.history = newMatchHistory(historySize)
+		tracker.suppressUnchanged = config.SuppressUnchanged
+	}
+	if config.ReportLoadOrder {
+		processes := config.LoadOrderProcesses
+		if processes == 0 {
+			processes = DefaultLoadOrderProcesses
+		}
+		tracker.loadSequences = newLoadSequences(processes)
+	}
+	if config.SummaryInterval > 0 {
+		maxEntries := config.MaxSummaryEntries
+		if maxEntries == 0 {
+			maxEntries = DefaultMaxSummaryEntries
+		}
+		tracker.summary = newSymbolsSummary(config.SummaryKey, maxEntries)
+	}
+	if config.ProfileProcesses {
+		maxProcesses := config.MaxProfiledProcesses
+		if maxProcesses == 0 {
+			maxProcesses = DefaultMaxProfiledProcesses
+		}
+		maxEntries := config.MaxProfileEntries
+		if maxEntries == 0 {
+			maxEntries = DefaultMaxProfileEntries
+		}
+		tracker.profiles = newProcessProfiles(maxProcesses, maxEntries)
+	}
+	if config.TrackCapabilities {
+		processes := config.CapabilitiesProcesses
+		if processes == 0 {
+			processes = DefaultCapabilitiesProcesses
+		}
+		tracker.capabilities = newProcessCapabilities(processes)
+	}
+
+	if config.TrackBuildIDs {
+		buildIDLoader, ok := soLoader.(sharedobjs.BuildIDLoader)
+		if !ok {
+			return fmt.Errorf("build IDs tracking is configured, but the SO loader can't read build IDs")
+		}
+		tracker.buildIDLoader = buildIDLoader
+		maxSeen := config.MaxSeenBuildIDs
+		if maxSeen == 0 {
+			maxSeen = DefaultMaxSeenBuildIDs
+		}
+		tracker.inventory = newBuildIDInventory(maxSeen)
+	}
+	if config.TrackSonamePaths {
+		maxSonames := config.MaxTrackedSonames
+		if maxSonames == 0 {
+			maxSonames = DefaultMaxTrackedSonames
+		}
+		maxPaths := config.MaxSonamePaths
+		if maxPaths == 0 {
+			maxPaths = DefaultMaxSonamePaths
+		}
+		tracker.sonamePaths = newSonamePathsHistory(maxSonames, maxPaths)
+		tracker.reportAllPaths = config.ReportAllSonamePaths
+	}
+
+	if config.CorrelateExecMapping {
+		timeout := config.ExecMappingTimeout
+		if timeout == 0 {
+			timeout = DefaultExecMappingTimeout
+		}
+		maxPending := config.MaxPendingLoads
+		if maxPending == 0 {
+			maxPending = DefaultMaxPendingLoads
+		}
+		tracker.startCorrelation(timeout, maxPending)
+	}
+	return nil
+}
+
+// validate checks the tracking configuration for mistakes
+func (config SymbolsTrackingConfig) validate() []error {
+	var problems []error
+	if config.SummaryInterval < 0 {
+		problems = append(problems, fmt.Errorf("negative summary interval %v", config.SummaryInterval))
+	}
+	if config.SummaryKey != SummaryBySymbol && config.SummaryKey != SummaryByProcess {
+		problems = append(problems, fmt.Errorf("unknown summary key %d", config.SummaryKey))
+	}
+	if config.MaxSummaryEntries < 0 {
+		problems = append(problems, fmt.Errorf("negative maximal summary entries %d", config.MaxSummaryEntries))
+	}
+	if config.MaxProfiledProcesses < 0 {
+		problems = append(problems, fmt.Errorf("negative maximal profiled processes %d", config.MaxProfiledProcesses))
+	}
+	if config.MaxProfileEntries < 0 {
+		problems = append(problems, fmt.Errorf("negative maximal profile entries %d", config.MaxProfileEntries))
+	}
+	if config.MatchHistorySize < 0 {
+		problems = append(problems, fmt.Errorf("negative match history size %d", config.MatchHistorySize))
+	}
+	if config.ExecMappingTimeout < 0 {
+		problems = append(problems, fmt.Errorf("negative exec mapping timeout %v", config.ExecMappingTimeout))
+	}
+	if config.MaxPendingLoads < 0 {
+		problems = append(problems, fmt.Errorf("negative maximal pending loads %d", config.MaxPendingLoads))
+	}
+	if (config.ExecMappingTimeout != 0 || config.MaxPendingLoads != 0) && !config.CorrelateExecMapping {
+		problems = append(problems, fmt.Errorf("exec mapping correlation settings are configured, but loads aren't correlated"))
+	}
+	if config.MaxSeenBuildIDs < 0 {
+		problems = append(problems, fmt.Errorf("negative maximal seen build IDs %d", config.MaxSeenBuildIDs))
+	}
+	if config.MaxTrackedSonames < 0 {
+		problems = append(problems, fmt.Errorf("negative maximal tracked sonames %d", config.MaxTrackedSonames))
+	}
+	if config.MaxSonamePaths < 0 {
+		problems = append(problems, fmt.Errorf("negative maximal soname paths %d", config.MaxSonamePaths))
+	}
+	if config.ReportAllSonamePaths && !config.TrackSonamePaths {
+		problems = append(problems, fmt.Errorf("all soname paths are reported, but the soname paths aren't tracked"))
+	}
+	if config.LoadOrderProcesses < 0 {
+		problems = append(problems, fmt.Errorf("negative load order processes %d", config.LoadOrderProcesses))
+	}
+	if config.CapabilitiesProcesses < 0 {
+		problems = append(problems, fmt.Errorf("negative capabilities processes %d", config.CapabilitiesProcesses))
+	}
+	return problems
+}
+
+// recordMatch records the matched symbols of the match in the summary and in the profile of its process, if they
+// are configured
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) recordMatch(match *symbolsMatch) {
+	recordProfile := symbsLoadedGen.profiles != nil && len(match.symbols) > 0
+	if symbsLoadedGen.summary == nil && !recordProfile {
+		return
+	}
+	summarized := match.symbols
+	if symbsLoadedGen.hashOnly {
+		summarized = symbsLoadedGen.hasher.hashAll(summarized)
+	}
+	if symbsLoadedGen.summary != nil {
+		symbsLoadedGen.summary.record(match.objInfo.Pid, summarized)
+	}
+	if recordProfile {
+		symbsLoadedGen.profiles.record(match.objInfo.Pid, match.objInfo.Path, summarized)
+	}
+}
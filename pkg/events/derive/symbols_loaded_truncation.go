@@ -0,0 +1,13 @@
+package derive
+
+import (
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// isTruncated returns whether only the first symbols of the SO were read by the loader, if truncation is reported
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) isTruncated(objInfo sharedobjs.ObjInfo) (bool, error) {
+	if symbsLoadedGen.truncationDetector == nil {
+		return false, nil
+	}
+	return symbsLoadedGen.truncationDetector.IsTruncated(objInfo)
+}
@@ -0,0 +1,15 @@
+package derive
+
+import (
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// isTrusted checks if the SO carries the configured trust marker note, in which case it is treated as whitelisted.
+// If the SO can't be read it is not trusted, so the reading error is reported by the derivation.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) isTrusted(objInfo sharedobjs.ObjInfo) bool {
+	if symbsLoadedGen.noteChecker == nil {
+		return false
+	}
+	trusted, err := symbsLoadedGen.noteChecker.HasNote(objInfo, symbsLoadedGen.trustedNote)
+	return err == nil && trusted
+}
@@ -0,0 +1,38 @@
+package derive
+
+import (
+	"sort"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// matchWatchedVersionedSymbols returns the watched versioned symbols entries ("<symbol>@<version>") whose symbol the
+// SO defines in the version of the entry, sorted. The versions are read from the examined SO file, so SOs loaded in
+// a container are matched by the versions of the container's build of the library, even if the host has another
+// build of it in the same path.
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchWatchedVersionedSymbols(
+	objInfo sharedobjs.ObjInfo) ([]string, error) {
+	if symbsLoadedGen.watchedVersioned == nil {
+		return nil, nil
+	}
+	symbolsInfo, err := symbsLoadedGen.symbolsInfoLoader.GetExportedSymbolsInfo(objInfo)
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for entry := range symbsLoadedGen.watchedVersioned {
+		sym, version := splitImportVersion(entry)
+		info, ok := symbolsInfo[sym]
+		if !ok {
+			continue
+		}
+		for _, defined := range info.Versions {
+			if defined == version {
+				matched = append(matched, entry)
+				break
+			}
+		}
+	}
+	sort.Strings(matched)
+	return matched, nil
+}
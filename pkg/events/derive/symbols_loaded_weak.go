@@ -0,0 +1,123 @@
+package derive
+
+import (
+	"debug/elf"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/tracee/pkg/events"
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// WeakSymbolsFromObjects builds the WeakSymbols configuration from known-good copies of the standard libraries
+// (e.g. the libc of the host), keyed by their soname. All the given SOs must have a soname.
+func WeakSymbolsFromObjects(paths []string) (map[string][]string, error) {
+	weakSymbols := make(map[string][]string, len(paths))
+	for _, hostPath := range paths {
+		soname, weak, err := sharedobjs.GetSonameWeakSymbols(hostPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read standard library '%s': %v", hostPath, err)
+		}
+		if soname == "" {
+			return nil, fmt.Errorf("standard library '%s' has no soname", hostPath)
+		}
+		if _, ok := weakSymbols[soname]; ok {
+			return nil, fmt.Errorf("standard library '%s' soname '%s' is given more than once", hostPath, soname)
+		}
+		weakSymbols[soname] = weak
+	}
+	return weakSymbols, nil
+}
+
+// newWeakSymbols inverts the weak symbols by soname configuration, to the sonames which define each symbol weakly
+// (in alphabetical order)
+func newWeakSymbols(config map[string][]string) map[string][]string {
+	weakSymbols := make(map[string][]string)
+	for soname, syms := range config {
+		for _, sym := range syms {
+			weakSymbols[sym] = append(weakSymbols[sym], soname)
+		}
+	}
+	for _, sonames := range weakSymbols {
+		sort.Strings(sonames)
+	}
+	return weakSymbols
+}
+
+// WeakSymbolOverridden receives the generator of the symbols_loaded event as a closure argument.
+// If it receives a shared_object_loaded event of a SO which provides a strong definition of a watched symbol that
+// is weak in a standard library, it derives a weak_symbol_overridden event from it.
+func WeakSymbolOverridden(gen *SymbolsLoadedEventGenerator) events.DeriveFunction {
+	return singleSkeletonDeriveFunc(makeTypedEventSkeleton(events.WeakSymbolOverridden),
+		gen.withDeadline(gen.deriveWeakOverrideArgs))
+}
+
+// deriveWeakOverrideArgs derive the arguments of the weak_symbol_overridden event, if the loaded SO exports watched
+// symbols with a global binding which standard libraries other than the SO define weakly. Such a strong definition
+// takes precedence over the weak one of the standard library (e.g. interposing the allocator of libc).
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) deriveWeakOverrideArgs(event trace.Event) ([]interface{}, error) {
+	if !symbsLoadedGen.acquire() {
+		return nil, nil
+	}
+	defer symbsLoadedGen.release()
+
+	if symbsLoadedGen.weakSymbols == nil {
+		return nil, nil
+	}
+	loadingObjectInfo, err := getSharedObjectInfo(event)
+	if err != nil {
+		return nil, err
+	}
+
+	if !symbsLoadedGen.inScope(&event) || symbsLoadedGen.isIgnored(loadingObjectInfo.Path) ||
+		symbsLoadedGen.isTrusted(loadingObjectInfo) {
+		return nil, nil
+	}
+
+	// Errors are reported by the symbols_loaded event derivation
+	soSymsInfo, err := symbsLoadedGen.weakInfoLoader.GetExportedSymbolsInfo(loadingObjectInfo)
+	if err != nil {
+		return nil, nil
+	}
+	soname, err := symbsLoadedGen.sonameLoader.GetSoname(loadingObjectInfo)
+	if err != nil {
+		return nil, nil
+	}
+	var overridden []string
+	for sym, info := range soSymsInfo {
+		if info.Bind != elf.STB_GLOBAL || !symbsLoadedGen.isWatched(sym, loadingObjectInfo.Path) {
+			continue
+		}
+		if len(symbsLoadedGen.overriddenLibraries(sym, soname)) > 0 {
+			overridden = append(overridden, sym)
+		}
+	}
+	if len(overridden) == 0 {
+		return nil, nil
+	}
+	sort.Strings(overridden)
+	libraries := make([]string, len(overridden))
+	for i, sym := range overridden {
+		libraries[i] = strings.Join(symbsLoadedGen.overriddenLibraries(sym, soname), ",")
+	}
+	symbsLoadedGen.log(LogLevelWarn, DecisionWeakOverride, loadingObjectInfo,
+		fmt.Sprintf("symbols: %v, libraries: %v", overridden, libraries))
+	return []interface{}{loadingObjectInfo.Path, overridden, libraries}, nil
+}
+
+// overriddenLibraries returns the sonames of the standard libraries which define the symbol weakly, except for the
+// given soname of the SO defining it (so a standard library doesn't override itself)
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) overriddenLibraries(sym string, soname string) []string {
+	sonames := symbsLoadedGen.weakSymbols[sym]
+	for i, weakSoname := range sonames {
+		if weakSoname != soname {
+			continue
+		}
+		others := make([]string, 0, len(sonames)-1)
+		others = append(others, sonames[:i]...)
+		return append(others, sonames[i+1:]...)
+	}
+	return sonames
+}
@@ -0,0 +1,38 @@
+package derive
+
+import (
+	"path"
+	"strings"
+)
+
+// libraryPrefix is the conventional prefix of libraries file names, which library glob patterns may omit, so
+// "nss_*" matches "libnss_files.so.2"
+const libraryPrefix = "lib"
+
+// isLibraryGlob checks if a libraries whitelist entry is a glob pattern, rather than a prefix of the library name
+func isLibraryGlob(entry string) bool {
+	return strings.ContainsAny(entry, "*?[")
+}
+
+// matchLibraryGlob checks if a library glob pattern matches a SO, given its path relative to the libraries directory
+// it resides in (e.g. "i386-linux-gnu/libnss_files.so.2"). Patterns with no slash are matched against the file name
+// of the SO, so they match SOs in any nested directory (e.g. of an architecture or a version), and patterns with a
+// slash are matched against the whole relative path. Either way the "lib" prefix of the name may be omitted.
+func matchLibraryGlob(pattern string, relativePath string) bool {
+	name := relativePath
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(relativePath)
+	}
+	candidates := []string{name}
+	dir, file := path.Split(name)
+	if strings.HasPrefix(file, libraryPrefix) {
+		candidates = append(candidates, dir+strings.TrimPrefix(file, libraryPrefix))
+	}
+	for _, candidate := range candidates {
+		// The patterns are validated by ValidateConfig
+		if matched, _ := path.Match(pattern, candidate); matched {
+			return true
+		}
+	}
+	return false
+}
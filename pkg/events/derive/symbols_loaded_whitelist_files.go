@@ -0,0 +1,126 @@
+package derive
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// whitelistEntries are the whitelist entries merged from the configuration and the whitelist files, in their order
+type whitelistEntries struct {
+	libs    []string // Entries of SymbolsMatchingConfig.WhitelistedLibs
+	regexps []string // Entries of SymbolsMatchingConfig.WhitelistedRegexps
+}
+
+// mergeWhitelistFiles merges the entries of the whitelist files (see SymbolsMatchingConfig.WhitelistFiles) into the
+// given entries, in the order of the files and of their includes
+func mergeWhitelistFiles(entries whitelistEntries, files []string) (whitelistEntries, error) {
+	merged := whitelistEntries{
+		libs:    append([]string(nil), entries.libs...),
+		regexps: append([]string(nil), entries.regexps...),
+	}
+	for _, file := range files {
+		if err := merged.parseFile(file, nil); err != nil {
+			return whitelistEntries{}, err
+		}
+	}
+	return merged, nil
+}
+
+// parseFile merges the entries of the whitelist file, given the chain of files including it. Include directives are
+// glob patterns, relative to the directory of the including file if not absolute. A file including itself, directly
+// or through other files, is an error.
+func (entries *whitelistEntries) parseFile(file string, including []string) error {
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	for i, includer := range including {
+		if includer == absPath {
+			chain := append(append([]string(nil), including[i:]...), absPath)
+			return fmt.Errorf("cyclic include of whitelist file '%s': %s", file, strings.Join(chain, " -> "))
+		}
+	}
+	including = append(including, absPath)
+	content, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("whitelist file '%s': %v", file, err)
+	}
+	defer content.Close()
+
+	scanner := bufio.NewScanner(content)
+	for line := 1; scanner.Scan(); line++ {
+		// Comments take whole lines, as the entries (e.g. regular expressions) may contain '#'
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		directive, value := entry, ""
+		if space := strings.IndexAny(entry, " \t"); space >= 0 {
+			directive, value = entry[:space], strings.TrimSpace(entry[space:])
+		}
+		if (directive == "include" || directive == "regexp" || directive == "remove") && value == "" {
+			return fmt.Errorf("whitelist file '%s' line %d: %s directive with no value", file, line, directive)
+		}
+		switch directive {
+		case "include":
+			for _, pattern := range strings.Fields(value) {
+				if err := entries.include(absPath, pattern, including); err != nil {
+					return err
+				}
+			}
+		case "regexp":
+			entries.regexps = append(entries.regexps, value)
+		case "remove":
+			entries.remove(value)
+		default:
+			entries.libs = append(entries.libs, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("whitelist file '%s': %v", file, err)
+	}
+	return nil
+}
+
+// include merges the whitelist files matching the pattern of an include directive of the given file. Patterns with
+// no glob characters should name an existing file.
+func (entries *whitelistEntries) include(includer string, pattern string, including []string) error {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(includer), pattern)
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		return entries.parseFile(pattern, including)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("whitelist file '%s' include '%s': %v", includer, pattern, err)
+	}
+	for _, match := range matches {
+		if err := entries.parseFile(match, including); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove removes the earlier entries equal to the given entry, so files can override the files they include.
+// Paths are compared after cleaning them, as the whitelist normalization does.
+func (entries *whitelistEntries) remove(entry string) {
+	kept := entries.libs[:0]
+	for _, lib := range entries.libs {
+		if cleanWhitelistEntry(lib) != cleanWhitelistEntry(entry) {
+			kept = append(kept, lib)
+		}
+	}
+	entries.libs = kept
+	keptRegexps := entries.regexps[:0]
+	for _, expr := range entries.regexps {
+		if expr != entry {
+			keptRegexps = append(keptRegexps, expr)
+		}
+	}
+	entries.regexps = keptRegexps
+}
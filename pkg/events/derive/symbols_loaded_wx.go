@@ -0,0 +1,39 @@
+package derive
+
+import (
+	"fmt"
+
+	"github.com/aquasecurity/tracee/pkg/utils/sharedobjs"
+)
+
+// WXSegmentsMode configures how SOs with segments which are both writable and executable (violating W^X) are
+// reported by the symbols_loaded event
+type WXSegmentsMode int
+
+const (
+	// WXSegmentsIgnore doesn't examine the segments of the SOs
+	WXSegmentsIgnore WXSegmentsMode = iota
+	// WXSegmentsReport derives the event for every SO with such segments, and adds the segments to the event
+	WXSegmentsReport
+	// WXSegmentsReportMatched adds the segments to the events derived for matches, but a SO with such segments and
+	// no match doesn't derive the event
+	WXSegmentsReportMatched
+)
+
+// matchWXSegments returns the segments of the given SO which are both writable and executable, if they are examined
+func (symbsLoadedGen *SymbolsLoadedEventGenerator) matchWXSegments(objInfo sharedobjs.ObjInfo) (
+	[]sharedobjs.Segment, error) {
+	if symbsLoadedGen.wxDetector == nil {
+		return nil, nil
+	}
+	return symbsLoadedGen.wxDetector.GetWritableCodeSegments(objInfo)
+}
+
+// formatWXSegments formats each segment as "<flags>:<offset>" (e.g. "PF_X+PF_W+PF_R:0x2df8")
+func formatWXSegments(segments []sharedobjs.Segment) []string {
+	formatted := make([]string, len(segments))
+	for i, segment := range segments {
+		formatted[i] = fmt.Sprintf("%v:0x%x", segment.Flags, segment.Offset)
+	}
+	return formatted
+}
@@ -169,6 +169,15 @@ const (
 	ExistingContainer
 	HookedSyscalls
 	HookedSeqOps
+	SymbolsUnreadable
+	PackedObjectLoaded
+	SymbolsExtractionSlow
+	SymbolsLoadedSummary
+	WeakSymbolOverridden
+	SonameBuildIDSeen
+	SymbolsLoadedProfile
+	SymbolsCapabilityGained
+	SonamePathChanged
 	MaxUserSpace
 )
 
@@ -5892,6 +5901,150 @@ var Definitions = eventDefinitions{
 				{Type: "[]helpers.KernelSymbol", Name: "hooked_seq_ops"},
 			},
 		},
+		SymbolsUnreadable: {
+			ID32Bit: sys32undefined,
+			Name:    "symbols_unreadable",
+			DocPath: "security_alerts/symbols_loaded.md",
+			Dependencies: dependencies{
+				Events: []eventDependency{
+					{EventID: SymbolsLoaded}, // Reports the SOs whose symbols symbols_loaded can't read
+				},
+			},
+			Sets: []string{"derived", "fs"},
+			Params: []trace.ArgMeta{
+				{Type: "const char*", Name: "library_path"},
+				{Type: "const char*", Name: "reason"},
+			},
+		},
+		PackedObjectLoaded: {
+			ID32Bit: sys32undefined,
+			Name:    "packed_object_loaded",
+			DocPath: "security_alerts/symbols_loaded.md",
+			Dependencies: dependencies{
+				Events: []eventDependency{
+					{EventID: SymbolsLoaded}, // Reports the packed SOs, whose symbols symbols_loaded can't examine
+				},
+			},
+			Sets: []string{"derived", "fs", "security_alert"},
+			Params: []trace.ArgMeta{
+				{Type: "const char*", Name: "library_path"},
+				{Type: "const char*", Name: "packer"},
+			},
+		},
+		SymbolsExtractionSlow: {
+			ID32Bit: sys32undefined,
+			Name:    "symbols_extraction_slow",
+			DocPath: "security_alerts/symbols_loaded.md",
+			Dependencies: dependencies{
+				Events: []eventDependency{
+					{EventID: SymbolsLoaded}, // Measures the symbols extractions of symbols_loaded
+				},
+			},
+			Sets: []string{"derived", "fs"},
+			Params: []trace.ArgMeta{
+				{Type: "const char*", Name: "library_path"},
+				{Type: "unsigned long", Name: "duration_ns"},
+			},
+		},
+		SymbolsLoadedSummary: {
+			ID32Bit: sys32undefined,
+			Name:    "symbols_loaded_summary",
+			DocPath: "security_alerts/symbols_loaded.md",
+			Dependencies: dependencies{
+				Events: []eventDependency{
+					{EventID: SymbolsLoaded}, // The event summarizes the matches of symbols_loaded
+				},
+			},
+			Sets: []string{"derived", "fs"},
+			Params: []trace.ArgMeta{
+				{Type: "const char*", Name: "aggregation"},
+				{Type: "const char*const*", Name: "entries"},
+				{Type: "int", Name: "loads_count"},
+				{Type: "bool", Name: "truncated"},
+			},
+		},
+		WeakSymbolOverridden: {
+			ID32Bit: sys32undefined,
+			Name:    "weak_symbol_overridden",
+			DocPath: "security_alerts/symbols_loaded.md",
+			Dependencies: dependencies{
+				Events: []eventDependency{
+					{EventID: SymbolsLoaded}, // Matches the configured weak symbols in the SOs examined by symbols_loaded
+				},
+			},
+			Sets: []string{"derived", "fs", "security_alert"},
+			Params: []trace.ArgMeta{
+				{Type: "const char*", Name: "library_path"},
+				{Type: "const char*const*", Name: "symbols"},
+				{Type: "const char*const*", Name: "overridden_libraries"},
+			},
+		},
+		SonameBuildIDSeen: {
+			ID32Bit: sys32undefined,
+			Name:    "soname_build_id_seen",
+			DocPath: "security_alerts/symbols_loaded.md",
+			Dependencies: dependencies{
+				Events: []eventDependency{
+					{EventID: SymbolsLoaded}, // Tracks the build IDs of the SOs examined by symbols_loaded
+				},
+			},
+			Sets: []string{"derived", "fs"},
+			Params: []trace.ArgMeta{
+				{Type: "const char*", Name: "library_path"},
+				{Type: "const char*", Name: "soname"},
+				{Type: "const char*", Name: "build_id"},
+				{Type: "const char*", Name: "previous_build_id"},
+			},
+		},
+		SymbolsCapabilityGained: {
+			ID32Bit: sys32undefined,
+			Name:    "symbols_capability_gained",
+			DocPath: "security_alerts/symbols_loaded.md",
+			Dependencies: dependencies{
+				Events: []eventDependency{
+					{EventID: SymbolsLoaded}, // Tracks the watched symbols which each process gains, as matched by symbols_loaded
+				},
+			},
+			Sets: []string{"derived", "fs", "security_alert"},
+			Params: []trace.ArgMeta{
+				{Type: "const char*", Name: "library_path"},
+				{Type: "const char*const*", Name: "symbols"},
+			},
+		},
+		SonamePathChanged: {
+			ID32Bit: sys32undefined,
+			Name:    "soname_path_changed",
+			DocPath: "security_alerts/symbols_loaded.md",
+			Dependencies: dependencies{
+				Events: []eventDependency{
+					{EventID: SymbolsLoaded}, // Tracks the paths of the sonames of the SOs examined by symbols_loaded
+				},
+			},
+			Sets: []string{"derived", "fs", "security_alert"},
+			Params: []trace.ArgMeta{
+				{Type: "const char*", Name: "library_path"},
+				{Type: "const char*", Name: "soname"},
+				{Type: "const char*const*", Name: "previous_paths"},
+			},
+		},
+		SymbolsLoadedProfile: {
+			ID32Bit: sys32undefined,
+			Name:    "symbols_loaded_profile",
+			DocPath: "security_alerts/symbols_loaded.md",
+			Dependencies: dependencies{
+				Events: []eventDependency{
+					{EventID: SymbolsLoaded},    // The event aggregates the matches of symbols_loaded
+					{EventID: SchedProcessExit}, // The event is derived when the process exits
+				},
+			},
+			Sets: []string{"derived", "fs"},
+			Params: []trace.ArgMeta{
+				{Type: "const char*const*", Name: "symbols"},
+				{Type: "const char*const*", Name: "libraries"},
+				{Type: "int", Name: "loads_count"},
+				{Type: "bool", Name: "truncated"},
+			},
+		},
 		TaskRename: {
 			ID32Bit: sys32undefined,
 			Name:    "task_rename",
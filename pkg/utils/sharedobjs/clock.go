@@ -0,0 +1,33 @@
+package sharedobjs
+
+import "time"
+
+// Clock is the source of time of the time-dependent features of the loaders and the derivations using them, so
+// their timing can be controlled by tests
+type Clock interface {
+	Now() time.Time
+	// After waits for the duration to elapse, and then sends the current time on the returned channel
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock of the real time
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// SystemClock is the Clock of the real time, used if no other Clock is configured
+var SystemClock Clock = systemClock{}
+
+// timeSource returns the clock of the loader
+func (soLoader *HostSymbolsLoader) timeSource() Clock {
+	if soLoader.clock == nil {
+		return SystemClock
+	}
+	return soLoader.clock
+}
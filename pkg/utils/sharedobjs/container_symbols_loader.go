@@ -1,47 +1,148 @@
 package sharedobjs
 
 import (
+	"context"
+	"debug/elf"
+	"time"
+
 	"github.com/aquasecurity/tracee/pkg/containers"
 )
 
 // ContainersSymbolsLoader is a decorator for SO loaders that resolves containers-relative paths to
 // absolute host paths.
 // This object operation requires the CAP_DAC_OVERRIDE to access files across the system.
+// The paths are resolved only on cache misses, so SOs with the same ObjID share their cached symbols across
+// mount namespaces.
 type ContainersSymbolsLoader struct {
-	hostLoader   DynamicSymbolsLoader
+	hostLoader   *HostSymbolsLoader
 	pathResolver *containers.PathResolver
 }
 
 func InitContainersSymbolsLoader(pathResolver *containers.PathResolver, cacheSize int) *ContainersSymbolsLoader {
-	return &ContainersSymbolsLoader{
-		hostLoader:   InitHostSymbolsLoader(cacheSize),
+	return InitContainersSymbolsLoaderWithConfig(pathResolver, HostSymbolsLoaderConfig{CacheSize: cacheSize})
+}
+
+func InitContainersSymbolsLoaderWithConfig(pathResolver *containers.PathResolver, config HostSymbolsLoaderConfig) *ContainersSymbolsLoader {
+	cLoader := &ContainersSymbolsLoader{
+		hostLoader:   InitHostSymbolsLoaderWithConfig(config),
 		pathResolver: pathResolver,
 	}
+	cLoader.hostLoader.resolvePath = cLoader.resolveHostPath
+	return cLoader
+}
+
+// Stats return the statistics of the underlying host loader
+func (cLoader *ContainersSymbolsLoader) Stats() *LoaderStats {
+	return cLoader.hostLoader.Stats()
 }
 
 func (cLoader *ContainersSymbolsLoader) GetDynamicSymbols(soInfo ObjInfo) (map[string]bool, error) {
-	var err error
-	soInfo.Path, err = cLoader.pathResolver.ResolveAbsolutePath(soInfo.Path, soInfo.MountNS)
-	if err != nil {
-		return nil, err
-	}
 	return cLoader.hostLoader.GetDynamicSymbols(soInfo)
 }
 
 func (cLoader *ContainersSymbolsLoader) GetExportedSymbols(soInfo ObjInfo) (map[string]bool, error) {
-	var err error
-	soInfo.Path, err = cLoader.pathResolver.ResolveAbsolutePath(soInfo.Path, soInfo.MountNS)
-	if err != nil {
-		return nil, err
-	}
 	return cLoader.hostLoader.GetExportedSymbols(soInfo)
 }
 
 func (cLoader *ContainersSymbolsLoader) GetImportedSymbols(soInfo ObjInfo) (map[string]bool, error) {
+	return cLoader.hostLoader.GetImportedSymbols(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetExportedSymbolsInfo(soInfo ObjInfo) (map[string]SymbolInfo, error) {
+	return cLoader.hostLoader.GetExportedSymbolsInfo(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetImportedSymbolsInfo(soInfo ObjInfo) (map[string]ImportedSymbolInfo, error) {
+	return cLoader.hostLoader.GetImportedSymbolsInfo(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetFunctionRanges(soInfo ObjInfo) ([]FuncRange, error) {
+	return cLoader.hostLoader.GetFunctionRanges(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetPacker(soInfo ObjInfo) (string, error) {
+	return cLoader.hostLoader.GetPacker(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetDynamicTags(soInfo ObjInfo) ([]elf.DynTag, error) {
+	return cLoader.hostLoader.GetDynamicTags(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetConstructorsCount(soInfo ObjInfo) (int, error) {
+	return cLoader.hostLoader.GetConstructorsCount(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetMetadata(soInfo ObjInfo) (ObjMetadata, error) {
+	return cLoader.hostLoader.GetMetadata(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetWritableCodeSegments(soInfo ObjInfo) ([]Segment, error) {
+	return cLoader.hostLoader.GetWritableCodeSegments(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetInterpreter(soInfo ObjInfo) (string, error) {
+	return cLoader.hostLoader.GetInterpreter(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) IsTruncated(soInfo ObjInfo) (bool, error) {
+	return cLoader.hostLoader.IsTruncated(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetCodeEntropy(soInfo ObjInfo) (float64, error) {
+	return cLoader.hostLoader.GetCodeEntropy(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetRelocationCounts(soInfo ObjInfo) (map[string]int, error) {
+	return cLoader.hostLoader.GetRelocationCounts(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) DiffExportedSymbols(a, b ObjInfo) ([]string, []string, error) {
+	return cLoader.hostLoader.DiffExportedSymbols(a, b)
+}
+
+func (cLoader *ContainersSymbolsLoader) IsInterpreter(soInfo ObjInfo) (bool, error) {
+	return cLoader.hostLoader.IsInterpreter(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetSoname(soInfo ObjInfo) (string, error) {
+	return cLoader.hostLoader.GetSoname(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetBuildID(soInfo ObjInfo) (string, error) {
+	return cLoader.hostLoader.GetBuildID(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) HasNote(soInfo ObjInfo, note NoteID) (bool, error) {
+	return cLoader.hostLoader.HasNote(soInfo, note)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetExtractionDuration(soInfo ObjInfo) (time.Duration, bool, error) {
+	return cLoader.hostLoader.GetExtractionDuration(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) LoadContext(ctx context.Context, soInfo ObjInfo) error {
+	return cLoader.hostLoader.LoadContext(ctx, soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) GetPermissionError(soInfo ObjInfo) error {
+	return cLoader.hostLoader.GetPermissionError(soInfo)
+}
+
+func (cLoader *ContainersSymbolsLoader) IsSymbolExported(soInfo ObjInfo, symbol string) (bool, error) {
+	return cLoader.hostLoader.IsSymbolExported(soInfo, symbol)
+}
+
+func (cLoader *ContainersSymbolsLoader) Close() error {
+	return cLoader.hostLoader.Close()
+}
+
+// resolveHostPath changes the path of given SO to its path in the host mount namespace.
+// Deleted SOs are read through the procfs of the loading process, so their path needs no resolving.
+func (cLoader *ContainersSymbolsLoader) resolveHostPath(soInfo ObjInfo) (ObjInfo, error) {
+	if soInfo.Deleted {
+		return soInfo, nil
+	}
 	var err error
 	soInfo.Path, err = cLoader.pathResolver.ResolveAbsolutePath(soInfo.Path, soInfo.MountNS)
-	if err != nil {
-		return nil, err
-	}
-	return cLoader.hostLoader.GetImportedSymbols(soInfo)
+	return soInfo, err
 }
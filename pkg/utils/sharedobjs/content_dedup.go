@@ -0,0 +1,101 @@
+package sharedobjs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// contentSymbolsCache is a lru of shared objects symbols keyed by the hash of the SO content.
+// The cache is safe for concurrent use.
+type contentSymbolsCache struct {
+	lru   *simplelru.LRU
+	mutex sync.Mutex
+}
+
+func initContentSymbolsCache(size int) *contentSymbolsCache {
+	lru, _ := simplelru.NewLRU(size, nil)
+	return &contentSymbolsCache{lru: lru}
+}
+
+func (cache *contentSymbolsCache) Get(hash string) (*dynamicSymbols, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	syms, ok := cache.lru.Get(hash)
+	if !ok {
+		return nil, false
+	}
+	return syms.(*dynamicSymbols), true
+}
+
+func (cache *contentSymbolsCache) Add(hash string, syms *dynamicSymbols) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.lru.Add(hash, syms)
+}
+
+func (cache *contentSymbolsCache) Purge() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.lru.Purge()
+}
+
+// readDedupSOSymbols read the symbols of the SO, using the symbols of a SO with identical content if one was
+// already read. If the SO content can't be hashed, its symbols are read without deduplication.
+func (soLoader *HostSymbolsLoader) readDedupSOSymbols(ctx context.Context, soInfo ObjInfo, path string) (
+	*dynamicSymbols, error) {
+	hash, err := soLoader.hashingFunc(path)
+	if err != nil {
+		return soLoader.parseSOSymbols(ctx, soInfo, path)
+	}
+	soLoader.stats.DedupLookups.Increment()
+	if cachedSyms, ok := soLoader.contentCache.Get(hash); ok {
+		soLoader.stats.DedupHits.Increment()
+		// The symbols maps are shared, while the origin of the symbols is of the requested SO
+		return &dynamicSymbols{
+			Exported:     cachedSyms.Exported,
+			Imported:     cachedSyms.Imported,
+			ExportedInfo: cachedSyms.ExportedInfo,
+			ImportedInfo: cachedSyms.ImportedInfo,
+			Packer:       cachedSyms.Packer,
+			Interpreter:  cachedSyms.Interpreter,
+			Soname:       cachedSyms.Soname,
+			Notes:        cachedSyms.Notes,
+			BuildID:      cachedSyms.BuildID,
+			WXSegments:   cachedSyms.WXSegments,
+			FuncRanges:   cachedSyms.FuncRanges,
+			DynamicTags:  cachedSyms.DynamicTags,
+			Constructors: cachedSyms.Constructors,
+			Interp:       cachedSyms.Interp,
+			Truncated:    cachedSyms.Truncated,
+			CodeEntropy:  cachedSyms.CodeEntropy,
+			Relocations:  cachedSyms.Relocations,
+			loadedFrom:   soInfo,
+			checksum:     cachedSyms.checksum,
+		}, nil
+	}
+	syms, err := soLoader.parseSOSymbols(ctx, soInfo, path)
+	if err != nil {
+		return nil, err
+	}
+	soLoader.contentCache.Add(hash, syms)
+	return syms, nil
+}
+
+// hashFileContent calculates the sha256 hash of the content of the file in the given path
+func hashFileContent(path string) (string, error) {
+	file, err := openRegularFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
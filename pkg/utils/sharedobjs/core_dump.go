@@ -0,0 +1,527 @@
+package sharedobjs
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ntFile is the type of the note listing the files mapped by the process of a core dump (NT_FILE)
+const ntFile = 0x46494c45
+
+// maxCoreNotesSize is the maximal size of a notes segment of a core dump which is read. The notes of core dumps hold
+// the registers of every thread and the list of mapped files, so they are much larger than the notes of SOs.
+const maxCoreNotesSize = 16 * 1024 * 1024
+
+// maxCoreSymbols is the maximal amount of dynamic symbols read for an object of a core dump, so corrupted hash
+// tables can't make the loader read the whole core symbol by symbol
+const maxCoreSymbols = 1 << 20
+
+// maxCoreSymbolName is the maximal length of a symbol name read from the memory of a core dump
+const maxCoreSymbolName = 4096
+
+// errNotCaptured is returned when reading memory of the process which was not captured in the core dump
+var errNotCaptured = errors.New("memory not captured in the core dump")
+
+// CoreObject is an object (e.g. a SO or the executable) which was mapped by the process of a core dump
+type CoreObject struct {
+	Path  string
+	Start uint64 // The address of the mapping of the start of the object file, if it was found
+	// Whether the dynamic symbols of the object were fully captured in the core dump. The symbols of an incomplete
+	// object are only the ones which were captured, if any.
+	Complete bool
+}
+
+// CoreDumpSymbolsLoader loads the dynamic symbols of the objects mapped by the process of a core dump (an ET_CORE
+// file), for post-mortem analysis. The symbols are reconstructed from the memory of the process captured in the core
+// dump, and not read from the object files, so they are partial if some of the memory holding the dynamic symbols
+// table wasn't captured. The objects are identified by their path only, as core dumps don't hold their ObjID.
+type CoreDumpSymbolsLoader struct {
+	objects []CoreObject
+	symbols map[string]*dynamicSymbols
+}
+
+// InitCoreDumpSymbolsLoader reads the mapped objects of the core dump in the given path, and their symbols
+func InitCoreDumpSymbolsLoader(corePath string) (*CoreDumpSymbolsLoader, error) {
+	file, err := os.Open(corePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readCoreDump(file)
+}
+
+// Objects returns the objects mapped by the process of the core dump, in the order of their mappings
+func (loader *CoreDumpSymbolsLoader) Objects() []CoreObject {
+	return loader.objects
+}
+
+func (loader *CoreDumpSymbolsLoader) GetDynamicSymbols(soInfo ObjInfo) (map[string]bool, error) {
+	syms, err := loader.objectSymbols(soInfo)
+	if err != nil {
+		return nil, err
+	}
+	dynamicSymbols := copyMap(syms.Exported)
+	for sym := range syms.Imported {
+		dynamicSymbols[sym] = true
+	}
+	return dynamicSymbols, nil
+}
+
+func (loader *CoreDumpSymbolsLoader) GetExportedSymbols(soInfo ObjInfo) (map[string]bool, error) {
+	syms, err := loader.objectSymbols(soInfo)
+	if err != nil {
+		return nil, err
+	}
+	return copyMap(syms.Exported), nil
+}
+
+func (loader *CoreDumpSymbolsLoader) GetImportedSymbols(soInfo ObjInfo) (map[string]bool, error) {
+	syms, err := loader.objectSymbols(soInfo)
+	if err != nil {
+		return nil, err
+	}
+	return copyMap(syms.Imported), nil
+}
+
+// GetExportedSymbolsInfo returns the information of the exported symbols of the object. The sections of the symbols
+// are not known, as the sections headers are not mapped to the memory.
+func (loader *CoreDumpSymbolsLoader) GetExportedSymbolsInfo(soInfo ObjInfo) (map[string]SymbolInfo, error) {
+	syms, err := loader.objectSymbols(soInfo)
+	if err != nil {
+		return nil, err
+	}
+	symsInfo := make(map[string]SymbolInfo, len(syms.ExportedInfo))
+	for name, info := range syms.ExportedInfo {
+		symsInfo[name] = info
+	}
+	return symsInfo, nil
+}
+
+func (loader *CoreDumpSymbolsLoader) GetSoname(soInfo ObjInfo) (string, error) {
+	syms, err := loader.objectSymbols(soInfo)
+	if err != nil {
+		return "", err
+	}
+	return syms.Soname, nil
+}
+
+func (loader *CoreDumpSymbolsLoader) objectSymbols(soInfo ObjInfo) (*dynamicSymbols, error) {
+	syms, ok := loader.symbols[soInfo.Path]
+	if !ok {
+		return nil, fmt.Errorf("object '%s' is not mapped in the core dump", soInfo.Path)
+	}
+	return syms, nil
+}
+
+// coreMapping is a file mapping of the process of a core dump, as listed in its NT_FILE note
+type coreMapping struct {
+	start      uint64
+	end        uint64
+	fileOffset uint64
+	path       string
+}
+
+// readCoreDump reads the mapped objects of the given core dump, and their symbols
+func readCoreDump(reader io.ReaderAt) (*CoreDumpSymbolsLoader, error) {
+	core, err := elf.NewFile(reader)
+	if err != nil {
+		return nil, err
+	}
+	if core.Type != elf.ET_CORE {
+		return nil, fmt.Errorf("file is of type %v and not a core dump", core.Type)
+	}
+	if core.Class != elf.ELFCLASS64 {
+		return nil, fmt.Errorf("core dumps of class %v are not supported", core.Class)
+	}
+	var mappings []coreMapping
+	var notesErr error
+	for _, prog := range core.Progs {
+		if prog.Type != elf.PT_NOTE {
+			continue
+		}
+		parseNotes(prog.Open(), prog.Filesz, prog.Align, core.ByteOrder, maxCoreNotesSize,
+			func(note NoteID, desc []byte) {
+				if note == (NoteID{Name: "CORE", Type: ntFile}) {
+					mappings, notesErr = parseFileNote(desc, core.ByteOrder)
+				}
+			})
+	}
+	if notesErr != nil {
+		return nil, notesErr
+	}
+	if mappings == nil {
+		return nil, fmt.Errorf("core dump has no mapped files note")
+	}
+
+	memory := coreMemory{reader: reader}
+	for _, prog := range core.Progs {
+		if prog.Type == elf.PT_LOAD && prog.Filesz > 0 {
+			memory.segments = append(memory.segments, prog)
+		}
+	}
+	loader := &CoreDumpSymbolsLoader{symbols: make(map[string]*dynamicSymbols)}
+	for _, path := range mappedPaths(mappings) {
+		object, syms, isObject := readCoreObject(&memory, path, mappings)
+		if !isObject {
+			continue
+		}
+		loader.objects = append(loader.objects, object)
+		loader.symbols[path] = syms
+	}
+	return loader, nil
+}
+
+// parseFileNote parses the description of a 64 bit NT_FILE note, which is the amount of mappings and the page size,
+// the start, end and file offset (in pages) of each mapping, and then the paths of the mappings
+func parseFileNote(desc []byte, order binary.ByteOrder) ([]coreMapping, error) {
+	const wordSize = 8
+	if len(desc) < 2*wordSize {
+		return nil, fmt.Errorf("mapped files note is truncated")
+	}
+	count := order.Uint64(desc[0:])
+	pageSize := order.Uint64(desc[wordSize:])
+	desc = desc[2*wordSize:]
+	if count > uint64(len(desc))/(3*wordSize) {
+		return nil, fmt.Errorf("mapped files note is truncated")
+	}
+	mappings := make([]coreMapping, count)
+	for i := range mappings {
+		entry := desc[i*3*wordSize:]
+		mappings[i] = coreMapping{
+			start:      order.Uint64(entry[0:]),
+			end:        order.Uint64(entry[wordSize:]),
+			fileOffset: order.Uint64(entry[2*wordSize:]) * pageSize,
+		}
+	}
+	paths := bytes.Split(desc[count*3*wordSize:], []byte{0})
+	if uint64(len(paths)) < count {
+		return nil, fmt.Errorf("mapped files note is truncated")
+	}
+	for i := range mappings {
+		mappings[i].path = string(paths[i])
+	}
+	return mappings, nil
+}
+
+// mappedPaths returns the paths of the mapped files, in the order of their first mapping
+func mappedPaths(mappings []coreMapping) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, mapping := range mappings {
+		if !seen[mapping.path] {
+			seen[mapping.path] = true
+			paths = append(paths, mapping.path)
+		}
+	}
+	return paths
+}
+
+// readCoreObject reconstructs the dynamic symbols of the mapped file in the given path from the memory of the
+// process. It returns false if the mapped file is not an ELF object. Files whose start wasn't captured can't be
+// identified, so they are considered incomplete objects.
+func readCoreObject(memory *coreMemory, path string, mappings []coreMapping) (CoreObject, *dynamicSymbols, bool) {
+	object := CoreObject{Path: path}
+	emptySymbols := NewSOSymbols()
+	var objMappings []coreMapping
+	found := false
+	for _, mapping := range mappings {
+		if mapping.path != path {
+			continue
+		}
+		objMappings = append(objMappings, mapping)
+		if mapping.fileOffset == 0 && (!found || mapping.start < object.Start) {
+			object.Start = mapping.start
+			found = true
+		}
+	}
+	if !found {
+		return object, &emptySymbols, true
+	}
+	ident, err := memory.read(object.Start, elf.EI_NIDENT)
+	if err != nil {
+		return object, &emptySymbols, true
+	}
+	if !bytes.HasPrefix(ident, []byte(elf.ELFMAG)) {
+		return object, nil, false
+	}
+	reader := coreObjectReader{memory: memory, mappings: objMappings, start: object.Start}
+	syms, complete := reader.readSymbols(ident)
+	if syms == nil {
+		syms = &emptySymbols
+	}
+	object.Complete = complete
+	return object, syms, true
+}
+
+// coreMemory reads the memory of the process of a core dump, from its captured segments
+type coreMemory struct {
+	reader   io.ReaderAt
+	segments []*elf.Prog
+}
+
+// read reads the memory in the given address. All of the memory must be captured in the core dump.
+func (memory *coreMemory) read(addr uint64, size uint64) ([]byte, error) {
+	data := make([]byte, 0, size)
+	for uint64(len(data)) < size {
+		current := addr + uint64(len(data))
+		segment := memory.segmentOf(current)
+		if segment == nil {
+			return nil, errNotCaptured
+		}
+		chunk := size - uint64(len(data))
+		if available := segment.Vaddr + segment.Filesz - current; chunk > available {
+			chunk = available
+		}
+		buf := make([]byte, chunk)
+		if _, err := memory.reader.ReadAt(buf, int64(segment.Off+current-segment.Vaddr)); err != nil {
+			return nil, err
+		}
+		data = append(data, buf...)
+	}
+	return data, nil
+}
+
+// readString reads a null terminated string in the given address, of at most the given length
+func (memory *coreMemory) readString(addr uint64, maxLen uint64) (string, error) {
+	const chunkSize = 64
+	var str []byte
+	for uint64(len(str)) < maxLen {
+		size := uint64(chunkSize)
+		if remaining := maxLen - uint64(len(str)); size > remaining {
+			size = remaining
+		}
+		// The string may end right before the end of the captured memory, so it is read in chunks within segments
+		if segment := memory.segmentOf(addr + uint64(len(str))); segment != nil {
+			if available := segment.Vaddr + segment.Filesz - (addr + uint64(len(str))); size > available {
+				size = available
+			}
+		}
+		chunk, err := memory.read(addr+uint64(len(str)), size)
+		if err != nil {
+			return "", err
+		}
+		if end := bytes.IndexByte(chunk, 0); end >= 0 {
+			return string(append(str, chunk[:end]...)), nil
+		}
+		str = append(str, chunk...)
+	}
+	return "", fmt.Errorf("string in address 0x%x is longer than %d bytes", addr, maxLen)
+}
+
+// segmentOf returns the captured segment holding the given address, if there is one
+func (memory *coreMemory) segmentOf(addr uint64) *elf.Prog {
+	for _, segment := range memory.segments {
+		if addr >= segment.Vaddr && addr-segment.Vaddr < segment.Filesz {
+			return segment
+		}
+	}
+	return nil
+}
+
+// coreObjectReader reads the dynamic symbols of an object from the memory of the process it is mapped to
+type coreObjectReader struct {
+	memory   *coreMemory
+	mappings []coreMapping
+	start    uint64
+	order    binary.ByteOrder
+	bias     uint64 // The difference between the addresses of the object in the memory and in its file
+}
+
+// readSymbols reads the dynamic symbols of the object with the given ELF identification, and returns whether all of
+// them were read
+func (reader *coreObjectReader) readSymbols(ident []byte) (*dynamicSymbols, bool) {
+	if elf.Class(ident[elf.EI_CLASS]) != elf.ELFCLASS64 {
+		return nil, false
+	}
+	switch elf.Data(ident[elf.EI_DATA]) {
+	case elf.ELFDATA2LSB:
+		reader.order = binary.LittleEndian
+	case elf.ELFDATA2MSB:
+		reader.order = binary.BigEndian
+	default:
+		return nil, false
+	}
+	var header elf.Header64
+	if err := reader.readStruct(reader.start, &header); err != nil {
+		return nil, false
+	}
+	progs := make([]elf.Prog64, header.Phnum)
+	if err := reader.readStruct(reader.start+header.Phoff, progs); err != nil {
+		return nil, false
+	}
+	var dynamic *elf.Prog64
+	for i, prog := range progs {
+		switch elf.ProgType(prog.Type) {
+		case elf.PT_LOAD:
+			// The first segment is mapped from the start of the file
+			if prog.Off == 0 {
+				reader.bias = reader.start - prog.Vaddr
+			}
+		case elf.PT_DYNAMIC:
+			dynamic = &progs[i]
+		}
+	}
+	// The dynamic section holds a few dozens of entries, so larger sizes are of corrupted headers
+	if dynamic == nil || dynamic.Filesz > maxNotesSize {
+		return nil, false
+	}
+	dynEntries := make([]elf.Dyn64, dynamic.Filesz/uint64(binary.Size(elf.Dyn64{})))
+	if err := reader.readStruct(reader.bias+dynamic.Vaddr, dynEntries); err != nil {
+		return nil, false
+	}
+	tags := make(map[elf.DynTag]uint64)
+	for _, entry := range dynEntries {
+		tag := elf.DynTag(entry.Tag)
+		if tag == elf.DT_NULL {
+			break
+		}
+		if _, ok := tags[tag]; !ok {
+			tags[tag] = entry.Val
+		}
+	}
+	symtab, strtab, strsz := tags[elf.DT_SYMTAB], tags[elf.DT_STRTAB], tags[elf.DT_STRSZ]
+	if symtab == 0 || strtab == 0 {
+		return nil, false
+	}
+	symtab, strtab = reader.address(symtab), reader.address(strtab)
+	count, ok := reader.symbolsCount(tags, symtab, strtab)
+	if !ok {
+		return nil, false
+	}
+
+	complete := true
+	var symbols []elf.Symbol
+	var indexes []int
+	for i := uint64(1); i < count; i++ {
+		var sym elf.Sym64
+		if err := reader.readStruct(symtab+i*uint64(elf.Sym64Size), &sym); err != nil {
+			complete = false
+			continue
+		}
+		if uint64(sym.Name) >= strsz {
+			complete = false
+			continue
+		}
+		name, err := reader.memory.readString(strtab+uint64(sym.Name), minUint64(strsz-uint64(sym.Name), maxCoreSymbolName))
+		if err != nil {
+			complete = false
+			continue
+		}
+		symbols = append(symbols, elf.Symbol{
+			Name:    name,
+			Info:    sym.Info,
+			Other:   sym.Other,
+			Section: elf.SectionIndex(sym.Shndx),
+			Value:   sym.Value,
+			Size:    sym.Size,
+		})
+		indexes = append(indexes, int(i))
+	}
+	// Unreadable entries are skipped, so the symbols are not necessarily consecutive
+	syms := parseIndexedDynamicSymbols(symbols, indexes)
+	if soname, ok := tags[elf.DT_SONAME]; ok && soname < strsz {
+		syms.Soname, _ = reader.memory.readString(strtab+soname, minUint64(strsz-soname, maxCoreSymbolName))
+	}
+	return syms, complete
+}
+
+// address returns the address in the memory of a pointer of the dynamic section. Some dynamic loaders (e.g. glibc)
+// relocate the pointers in the dynamic section when loading the object, and some (e.g. musl) don't, so pointers
+// which are already inside the mappings of the object are not relocated again.
+func (reader *coreObjectReader) address(ptr uint64) uint64 {
+	for _, mapping := range reader.mappings {
+		if ptr >= mapping.start && ptr < mapping.end {
+			return ptr
+		}
+	}
+	return ptr + reader.bias
+}
+
+// symbolsCount returns the amount of entries of the dynamic symbols table, which is given by the symbols hash table
+// of the object. If the object has no hash table, the symbols table is assumed to end where the strings table
+// starts, as laid out by the linkers.
+func (reader *coreObjectReader) symbolsCount(tags map[elf.DynTag]uint64, symtab uint64, strtab uint64) (
+	uint64, bool) {
+	var count uint64
+	if hash, ok := tags[elf.DT_HASH]; ok {
+		// The hash table header is the amount of buckets and the amount of chains, which is the amount of symbols
+		var header [2]uint32
+		if err := reader.readStruct(reader.address(hash), &header); err != nil {
+			return 0, false
+		}
+		count = uint64(header[1])
+	} else if gnuHash, ok := tags[elf.DT_GNU_HASH]; ok {
+		var found bool
+		count, found = reader.gnuHashSymbolsCount(reader.address(gnuHash))
+		if !found {
+			return 0, false
+		}
+	} else if strtab > symtab {
+		count = (strtab - symtab) / uint64(elf.Sym64Size)
+	}
+	if count == 0 || count > maxCoreSymbols {
+		return 0, false
+	}
+	return count, true
+}
+
+// gnuHashSymbolsCount returns the amount of symbols of a GNU hash table. The table doesn't hold the amount of
+// symbols, so it is found by walking the chain of the last symbols bucket until its end.
+func (reader *coreObjectReader) gnuHashSymbolsCount(addr uint64) (uint64, bool) {
+	// Amount of buckets, index of the first hashed symbol, amount of bloom filter words and the bloom shift
+	var header [4]uint32
+	if err := reader.readStruct(addr, &header); err != nil {
+		return 0, false
+	}
+	nbuckets, symOffset, bloomSize := uint64(header[0]), uint64(header[1]), uint64(header[2])
+	if nbuckets > maxCoreSymbols {
+		return 0, false
+	}
+	bucketsAddr := addr + 16 + bloomSize*8
+	buckets := make([]uint32, nbuckets)
+	if err := reader.readStruct(bucketsAddr, buckets); err != nil {
+		return 0, false
+	}
+	last := uint64(0)
+	for _, bucket := range buckets {
+		if uint64(bucket) > last {
+			last = uint64(bucket)
+		}
+	}
+	if last < symOffset {
+		return symOffset, true
+	}
+	chainsAddr := bucketsAddr + nbuckets*4
+	for ; last < maxCoreSymbols; last++ {
+		var hash uint32
+		if err := reader.readStruct(chainsAddr+(last-symOffset)*4, &hash); err != nil {
+			return 0, false
+		}
+		// The lowest bit of the hash marks the end of the chain
+		if hash&1 != 0 {
+			return last + 1, true
+		}
+	}
+	return 0, false
+}
+
+// readStruct reads a fixed size structure (or a slice of them) from the memory in the given address
+func (reader *coreObjectReader) readStruct(addr uint64, data interface{}) error {
+	buf, err := reader.memory.read(addr, uint64(binary.Size(data)))
+	if err != nil {
+		return err
+	}
+	return binary.Read(bytes.NewReader(buf), reader.order, data)
+}
+
+func minUint64(a uint64, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
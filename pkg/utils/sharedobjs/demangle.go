@@ -0,0 +1,1079 @@
+package sharedobjs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNotMangled is returned when demangling a symbol which isn't a mangled C++ name
+var ErrNotMangled = errors.New("symbol is not a mangled C++ name")
+
+const (
+	maxDemangleDepth = 256       // Bounds the recursion over crafted symbols
+	maxDemangledSize = 16 * 1024 // Bounds the expansion of the substitutions of crafted symbols
+	maxDemangleSteps = 64 * 1024 // Bounds the parsing of the pack expansions of crafted symbols
+)
+
+// DemangleSignature demangles a symbol mangled by the Itanium C++ ABI to its full signature, as printed by
+// c++filt, e.g. "_ZNK3Foo3getEi" to "Foo::get(int) const".
+// Only the constructs found in the symbols of compiled code are supported, so symbols of templates depending on
+// expressions (e.g. decltype return types) are not demangled.
+func DemangleSignature(symbol string) (string, error) {
+	return demangle(symbol, true)
+}
+
+// DemangleName demangles a symbol mangled by the Itanium C++ ABI to its qualified name, without the parameters and
+// the return type, as printed by "c++filt -p", e.g. "_ZNK3Foo3getEi" to "Foo::get"
+func DemangleName(symbol string) (string, error) {
+	return demangle(symbol, false)
+}
+
+// demangleError is the panic value of the demangler, recovered when demangling the symbol
+type demangleError string
+
+func demangle(symbol string, signature bool) (demangled string, err error) {
+	if !strings.HasPrefix(symbol, "_Z") {
+		return "", ErrNotMangled
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			message, ok := r.(demangleError)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("can't demangle symbol '%s': %s", symbol, message)
+		}
+	}()
+	d := demangler{in: symbol, pos: 2}
+	full, name := d.encoding(false)
+	clones := d.cloneSuffixes()
+	if d.pos != len(d.in) {
+		d.fail("unexpected trailing characters")
+	}
+	if !signature {
+		return name, nil
+	}
+	return full + clones, nil
+}
+
+// demangler is the state of demangling a single symbol
+type demangler struct {
+	in           string
+	pos          int
+	depth        int
+	subs         []*demangledType // The substitution candidates, in their order of appearance
+	templateArgs []*demangledType // The template arguments referred by template parameters
+	lastName     string           // The last source name, which is the name of constructors and destructors
+	steps        int
+	expandedPack *demangledType // The pack referred by the type of a pack expansion
+	expanding    bool           // The type of a pack expansion is parsed again for the element of its pack
+	packElement  int
+}
+
+func (d *demangler) fail(format string, args ...interface{}) {
+	panic(demangleError(fmt.Sprintf("%s at offset %d", fmt.Sprintf(format, args...), d.pos)))
+}
+
+func (d *demangler) peek() byte {
+	return d.peekAt(0)
+}
+
+func (d *demangler) peekAt(offset int) byte {
+	if d.pos+offset >= len(d.in) {
+		return 0
+	}
+	return d.in[d.pos+offset]
+}
+
+func (d *demangler) next() byte {
+	c := d.peek()
+	if c == 0 {
+		d.fail("unexpected end")
+	}
+	d.pos++
+	return c
+}
+
+func (d *demangler) expect(c byte) {
+	if d.next() != c {
+		d.pos--
+		d.fail("expected '%c'", c)
+	}
+}
+
+// enter guards the recursion of a construct, to be followed by a deferred leave
+func (d *demangler) enter() {
+	d.depth++
+	d.steps++
+	if d.depth > maxDemangleDepth {
+		d.fail("nesting too deep")
+	}
+	if d.steps > maxDemangleSteps {
+		d.fail("too many constructs")
+	}
+}
+
+func (d *demangler) leave() {
+	d.depth--
+}
+
+func (d *demangler) addSub(t *demangledType) {
+	d.subs = append(d.subs, t)
+}
+
+// number parses a decimal number, negative if prefixed by 'n'
+func (d *demangler) number() int {
+	n, err := strconv.Atoi(d.numberText())
+	if err != nil {
+		d.fail("number out of range")
+	}
+	return n
+}
+
+// numberText parses a decimal number of any size, negative if prefixed by 'n', and returns its text
+func (d *demangler) numberText() string {
+	var sign string
+	if d.peek() == 'n' {
+		sign = "-"
+		d.pos++
+	}
+	start := d.pos
+	for isDigit(d.peek()) {
+		d.pos++
+	}
+	if start == d.pos {
+		d.fail("expected a number")
+	}
+	return sign + d.in[start:d.pos]
+}
+
+// seqID parses the optional base 36 number ending with '_' of substitutions and unnamed types, which is 0 when
+// missing and 1 more than the number otherwise
+func (d *demangler) seqID() int {
+	if d.peek() == '_' {
+		d.pos++
+		return 0
+	}
+	id := 0
+	for d.peek() != '_' {
+		c := d.next()
+		switch {
+		case isDigit(c):
+			id = id*36 + int(c-'0')
+		case c >= 'A' && c <= 'Z':
+			id = id*36 + int(c-'A') + 10
+		default:
+			d.pos--
+			d.fail("invalid sequence ID")
+		}
+		if id > len(d.in) {
+			d.fail("sequence ID out of range")
+		}
+	}
+	d.pos++
+	return id + 1
+}
+
+// encoding parses a function or data name, or a special name, and returns its full text and its name only. The
+// return types of the functions of local names aren't printed.
+func (d *demangler) encoding(local bool) (string, string) {
+	d.enter()
+	defer d.leave()
+	if c := d.peek(); c == 'T' || c == 'G' {
+		special := d.specialName()
+		return special, special
+	}
+	name := d.name(true)
+	if c := d.peek(); c == 0 || c == 'E' || c == '.' {
+		return name.text, name.text
+	}
+	var ret *demangledType
+	if name.template && !name.noReturn {
+		ret = d.typ()
+	}
+	sig := name.text + "(" + d.params() + ")" + name.quals
+	if ret != nil && !local {
+		sig = formatReturnType(ret, sig)
+	}
+	return checkSize(sig), name.text
+}
+
+// cloneSuffixes parses the suffixes of the clones made by the compiler, e.g. ".constprop.0"
+func (d *demangler) cloneSuffixes() string {
+	var clones string
+	for d.peek() == '.' {
+		start := d.pos
+		d.pos++
+		if c := d.peek(); isLower(c) || isDigit(c) || c == '_' {
+			for c = d.peek(); isLower(c) || isDigit(c) || c == '_'; c = d.peek() {
+				d.pos++
+			}
+		}
+		for d.peek() == '.' && isDigit(d.peekAt(1)) {
+			d.pos++
+			for isDigit(d.peek()) {
+				d.pos++
+			}
+		}
+		if d.pos == start+1 {
+			d.pos--
+			d.fail("invalid clone suffix")
+		}
+		clones += " [clone " + d.in[start:d.pos] + "]"
+	}
+	return clones
+}
+
+func (d *demangler) specialName() string {
+	if d.pos+2 > len(d.in) {
+		d.fail("unexpected end")
+	}
+	switch kind := d.in[d.pos : d.pos+2]; kind {
+	case "TV", "TT", "TI", "TS":
+		d.pos += 2
+		prefix := map[string]string{
+			"TV": "vtable for ", "TT": "VTT for ", "TI": "typeinfo for ", "TS": "typeinfo name for ",
+		}[kind]
+		return prefix + formatType(d.typ(), "")
+	case "Th":
+		d.pos += 2
+		d.callOffset('h')
+		full, _ := d.encoding(false)
+		return "non-virtual thunk to " + full
+	case "Tv":
+		d.pos += 2
+		d.callOffset('v')
+		full, _ := d.encoding(false)
+		return "virtual thunk to " + full
+	case "Tc":
+		d.pos += 2
+		d.callOffset(d.next())
+		d.callOffset(d.next())
+		full, _ := d.encoding(false)
+		return "covariant return thunk to " + full
+	case "TC":
+		d.pos += 2
+		derived := formatType(d.typ(), "")
+		d.number()
+		d.expect('_')
+		return "construction vtable for " + formatType(d.typ(), "") + "-in-" + derived
+	case "TW":
+		d.pos += 2
+		return "TLS wrapper function for " + d.name(true).text
+	case "TH":
+		d.pos += 2
+		return "TLS init function for " + d.name(true).text
+	case "GV":
+		d.pos += 2
+		return "guard variable for " + d.name(true).text
+	case "GT":
+		d.pos += 2
+		if c := d.next(); c != 't' && c != 'n' {
+			d.pos--
+			d.fail("unknown transaction clone")
+		}
+		full, _ := d.encoding(false)
+		return "transaction clone for " + full
+	}
+	d.fail("unsupported special name")
+	return ""
+}
+
+// callOffset parses the this adjustment of a thunk, whose kind is 'h' for non-virtual and 'v' for virtual
+func (d *demangler) callOffset(kind byte) {
+	switch kind {
+	case 'h':
+		d.number()
+		d.expect('_')
+	case 'v':
+		d.number()
+		d.expect('_')
+		d.number()
+		d.expect('_')
+	default:
+		d.pos--
+		d.fail("unknown call offset")
+	}
+}
+
+// demangledName is a parsed name of an entity
+type demangledName struct {
+	text     string
+	template bool   // The name ends with template arguments, so its function encoding has a return type
+	noReturn bool   // The unqualified name is a constructor, a destructor or a conversion operator
+	quals    string // The qualifiers of member functions, e.g. " const"
+}
+
+// name parses the name of an entity. The template arguments of the names of encodings are the ones referred by the
+// template parameters of the following types.
+func (d *demangler) name(encodingLevel bool) demangledName {
+	d.enter()
+	defer d.leave()
+	var name demangledName
+	switch d.peek() {
+	case 'N':
+		return d.nestedName(encodingLevel)
+	case 'Z':
+		return d.localName(encodingLevel)
+	case 'S':
+		if d.peekAt(1) != 't' {
+			name.text = formatType(d.substitution(), "")
+			if d.peek() == 'I' {
+				d.appendTemplateArgs(&name, encodingLevel)
+			}
+			return name
+		}
+		d.pos += 2
+		name.text, name.noReturn = d.unqualifiedName()
+		name.text = "std::" + name.text
+	default:
+		name.text, name.noReturn = d.unqualifiedName()
+	}
+	if d.peek() == 'I' {
+		d.addSub(namedType(name.text))
+		d.appendTemplateArgs(&name, encodingLevel)
+	}
+	return name
+}
+
+func (d *demangler) appendTemplateArgs(name *demangledName, encodingLevel bool) {
+	args := d.templateArgsList()
+	if encodingLevel {
+		d.templateArgs = args
+	}
+	name.text = appendTemplateArgs(name.text, args)
+	name.template = true
+}
+
+func (d *demangler) nestedName(encodingLevel bool) demangledName {
+	d.expect('N')
+	var name demangledName
+	name.quals = d.cvQualifiers()
+	switch d.peek() {
+	case 'R':
+		d.pos++
+		name.quals += " &"
+	case 'O':
+		d.pos++
+		name.quals += " &&"
+	}
+	for d.peek() != 'E' {
+		substitution := false
+		switch c := d.peek(); {
+		case c == 'S':
+			name.text = formatType(d.substitution(), "")
+			substitution = true
+		case c == 'I':
+			if name.text == "" {
+				d.fail("template arguments with no template")
+			}
+			d.appendTemplateArgs(&name, encodingLevel)
+		case c == 'T':
+			name.text = formatType(d.templateParam(), "")
+		case c == 'M':
+			// The initializer scope of a lambda, which isn't printed
+			d.pos++
+			continue
+		default:
+			var component string
+			component, name.noReturn = d.unqualifiedName()
+			if name.text != "" {
+				component = name.text + "::" + component
+			}
+			name.text = checkSize(component)
+			name.template = false
+		}
+		if !substitution && d.peek() != 'E' {
+			d.addSub(namedType(name.text))
+		}
+	}
+	d.pos++
+	return name
+}
+
+// localName parses the name of an entity local to a function, whose encoding is printed in full. The template
+// arguments of the function are referred only within the local name, unless it is the name of an encoding.
+func (d *demangler) localName(encodingLevel bool) demangledName {
+	d.expect('Z')
+	if !encodingLevel {
+		templateArgs := d.templateArgs
+		defer func() { d.templateArgs = templateArgs }()
+	}
+	function, _ := d.encoding(true)
+	d.expect('E')
+	var name demangledName
+	if d.peek() == 's' {
+		d.pos++
+		name.text = function + "::string literal"
+	} else {
+		if d.peek() == 'd' {
+			// The scope of a default argument
+			d.pos++
+			arg := 1
+			if d.peek() != '_' {
+				arg = d.number() + 2
+			}
+			d.expect('_')
+			function += fmt.Sprintf("::{default arg#%d}", arg)
+		}
+		name = d.name(true)
+		name.text = checkSize(function + "::" + name.text)
+	}
+	d.discriminator()
+	return name
+}
+
+func (d *demangler) discriminator() {
+	if d.peek() != '_' {
+		return
+	}
+	d.pos++
+	if d.peek() == '_' {
+		d.pos++
+		d.number()
+		d.expect('_')
+		return
+	}
+	if !isDigit(d.next()) {
+		d.pos--
+		d.fail("invalid discriminator")
+	}
+}
+
+// unqualifiedName parses an unqualified name, and returns if it is a constructor, a destructor or a conversion
+// operator
+func (d *demangler) unqualifiedName() (string, bool) {
+	var name string
+	noReturn := false
+	switch c := d.peek(); {
+	case isDigit(c):
+		name = d.sourceName()
+		d.lastName = name
+	case c == 'L':
+		// An internal linkage name
+		d.pos++
+		name = d.sourceName()
+		d.lastName = name
+		d.discriminator()
+	case c == 'C':
+		d.pos++
+		if d.peek() == 'I' {
+			// An inheriting constructor, naming the base class
+			d.pos++
+			d.typ()
+		}
+		if kind := d.next(); kind < '1' || kind > '5' {
+			d.pos--
+			d.fail("unknown constructor")
+		}
+		name, noReturn = d.lastName, true
+	case c == 'D' && d.peekAt(1) >= '0' && d.peekAt(1) <= '5':
+		d.pos += 2
+		name, noReturn = "~"+d.lastName, true
+	case c == 'U':
+		name = d.unnamedTypeName()
+	case isLower(c):
+		name, noReturn = d.operatorName()
+	default:
+		d.fail("unsupported name")
+	}
+	for d.peek() == 'B' {
+		d.pos++
+		name += "[abi:" + d.sourceName() + "]"
+	}
+	return name, noReturn
+}
+
+func (d *demangler) sourceName() string {
+	length := d.number()
+	if length <= 0 || d.pos+length > len(d.in) {
+		d.fail("invalid source name length")
+	}
+	name := d.in[d.pos : d.pos+length]
+	d.pos += length
+	if strings.HasPrefix(name, "_GLOBAL_") && len(name) > 9 && strings.ContainsRune("._$", rune(name[8])) &&
+		name[9] == 'N' {
+		return "(anonymous namespace)"
+	}
+	return name
+}
+
+func (d *demangler) unnamedTypeName() string {
+	d.expect('U')
+	switch d.next() {
+	case 't':
+		return fmt.Sprintf("{unnamed type#%d}", d.seqID()+1)
+	case 'l':
+		params := d.params()
+		d.expect('E')
+		return fmt.Sprintf("{lambda(%s)#%d}", params, d.seqID()+1)
+	}
+	d.pos--
+	d.fail("unsupported unnamed type")
+	return ""
+}
+
+// demangledOperators are the names of the operators by their codes
+var demangledOperators = map[string]string{
+	"nw": " new", "na": " new[]", "dl": " delete", "da": " delete[]", "ps": "+", "ng": "-", "ad": "&", "de": "*",
+	"co": "~", "pl": "+", "mi": "-", "ml": "*", "dv": "/", "rm": "%", "an": "&", "or": "|", "eo": "^", "aS": "=",
+	"pL": "+=", "mI": "-=", "mL": "*=", "dV": "/=", "rM": "%=", "aN": "&=", "oR": "|=", "eO": "^=", "ls": "<<",
+	"rs": ">>", "lS": "<<=", "rS": ">>=", "eq": "==", "ne": "!=", "lt": "<", "gt": ">", "le": "<=", "ge": ">=",
+	"ss": "<=>", "nt": "!", "aa": "&&", "oo": "||", "pp": "++", "mm": "--", "cm": ",", "pm": "->*", "pt": "->",
+	"cl": "()", "ix": "[]", "qu": "?", "aw": " co_await",
+}
+
+func (d *demangler) operatorName() (string, bool) {
+	if d.pos+2 > len(d.in) {
+		d.fail("unexpected end")
+	}
+	code := d.in[d.pos : d.pos+2]
+	d.pos += 2
+	switch {
+	case code == "cv":
+		return "operator " + formatType(d.typ(), ""), true
+	case code == "li":
+		return "operator\"\" " + d.sourceName(), false
+	case code[0] == 'v' && isDigit(code[1]):
+		return "operator " + d.sourceName(), false
+	}
+	operator, ok := demangledOperators[code]
+	if !ok {
+		d.pos -= 2
+		d.fail("unknown operator")
+	}
+	return "operator" + operator, false
+}
+
+// cvQualifiers parses the qualifiers of a type, and returns them in their printed order
+func (d *demangler) cvQualifiers() string {
+	var restrict, volatile, constant bool
+	if d.peek() == 'r' {
+		restrict = true
+		d.pos++
+	}
+	if d.peek() == 'V' {
+		volatile = true
+		d.pos++
+	}
+	if d.peek() == 'K' {
+		constant = true
+		d.pos++
+	}
+	var quals string
+	if constant {
+		quals += " const"
+	}
+	if volatile {
+		quals += " volatile"
+	}
+	if restrict {
+		quals += " restrict"
+	}
+	return quals
+}
+
+// substitution parses a reference to a substitution candidate or to a standard abbreviation
+func (d *demangler) substitution() *demangledType {
+	d.expect('S')
+	if c := d.peek(); c == '_' || isDigit(c) || (c >= 'A' && c <= 'Z') {
+		id := d.seqID()
+		if id >= len(d.subs) {
+			d.fail("substitution out of range")
+		}
+		if d.subs[id].kind == templateParamKind {
+			return d.resolveParam(d.subs[id])
+		}
+		return d.packElementOf(d.subs[id])
+	}
+	var text string
+	switch d.next() {
+	case 't':
+		return namedType("std")
+	case 'a':
+		text, d.lastName = "std::allocator", "allocator"
+	case 'b':
+		text, d.lastName = "std::basic_string", "basic_string"
+	case 's':
+		text, d.lastName = "std::basic_string<char, std::char_traits<char>, std::allocator<char> >", "basic_string"
+	case 'i':
+		text, d.lastName = "std::basic_istream<char, std::char_traits<char> >", "basic_istream"
+	case 'o':
+		text, d.lastName = "std::basic_ostream<char, std::char_traits<char> >", "basic_ostream"
+	case 'd':
+		text, d.lastName = "std::basic_iostream<char, std::char_traits<char> >", "basic_iostream"
+	default:
+		d.pos--
+		d.fail("unknown standard substitution")
+	}
+	return namedType(text)
+}
+
+func (d *demangler) templateParam() *demangledType {
+	return d.resolveParam(d.templateParamRef())
+}
+
+func (d *demangler) templateParamRef() *demangledType {
+	d.expect('T')
+	param := &demangledType{kind: templateParamKind}
+	if d.peek() != '_' {
+		param.index = d.number() + 1
+	}
+	d.expect('_')
+	return param
+}
+
+// resolveParam returns the template argument referred by the template parameter
+func (d *demangler) resolveParam(param *demangledType) *demangledType {
+	if param.index >= len(d.templateArgs) {
+		d.fail("template parameter out of range")
+	}
+	return d.packElementOf(d.templateArgs[param.index])
+}
+
+// packElementOf returns the element of the pack being expanded instead of the pack, and records the pack referred
+// by the type of a pack expansion
+func (d *demangler) packElementOf(t *demangledType) *demangledType {
+	if t.kind != packType {
+		return t
+	}
+	if !d.expanding {
+		d.expandedPack = t
+		return t
+	}
+	if d.packElement >= len(t.params) {
+		d.fail("pack expansion out of range")
+	}
+	return t.params[d.packElement]
+}
+
+func (d *demangler) templateArgsList() []*demangledType {
+	d.expect('I')
+	// The arguments don't name the following constructors and destructors
+	lastName := d.lastName
+	var args []*demangledType
+	for d.peek() != 'E' {
+		args = append(args, d.templateArg())
+	}
+	d.pos++
+	d.lastName = lastName
+	return args
+}
+
+func (d *demangler) templateArg() *demangledType {
+	d.enter()
+	defer d.leave()
+	switch d.peek() {
+	case 'L':
+		return d.literal()
+	case 'J':
+		d.pos++
+		pack := &demangledType{kind: packType}
+		for d.peek() != 'E' {
+			pack.params = append(pack.params, d.templateArg())
+		}
+		d.pos++
+		return pack
+	case 'X':
+		// Only the expressions of a template parameter are supported
+		d.pos++
+		if d.peek() != 'T' {
+			d.fail("unsupported expression")
+		}
+		arg := d.templateParam()
+		d.expect('E')
+		return arg
+	}
+	return d.typ()
+}
+
+func (d *demangler) literal() *demangledType {
+	d.expect('L')
+	if d.peek() == '_' && d.peekAt(1) == 'Z' {
+		d.pos++
+	}
+	if d.peek() == 'Z' {
+		d.pos++
+		full, _ := d.encoding(false)
+		d.expect('E')
+		return namedType(full)
+	}
+	t := d.typ()
+	value := d.numberText()
+	d.expect('E')
+	if !t.builtin {
+		return namedType("(" + formatType(t, "") + ")" + value)
+	}
+	switch t.text {
+	case "bool":
+		switch value {
+		case "0":
+			return namedType("false")
+		case "1":
+			return namedType("true")
+		}
+	case "int":
+		return namedType(value)
+	case "unsigned int":
+		return namedType(value + "u")
+	case "long":
+		return namedType(value + "l")
+	case "unsigned long":
+		return namedType(value + "ul")
+	case "long long":
+		return namedType(value + "ll")
+	case "unsigned long long":
+		return namedType(value + "ull")
+	}
+	return namedType("(" + t.text + ")" + value)
+}
+
+// params parses the parameter types of a function, until its end
+func (d *demangler) params() string {
+	var params []*demangledType
+	for {
+		c := d.peek()
+		if c == 0 || c == 'E' || c == '.' || ((c == 'R' || c == 'O') && d.peekAt(1) == 'E') {
+			break
+		}
+		params = append(params, d.typ())
+	}
+	if len(params) == 0 {
+		d.fail("expected function parameters")
+	}
+	if len(params) == 1 && params[0].builtin && params[0].text == "void" {
+		return ""
+	}
+	return formatTypes(params)
+}
+
+// demangledBuiltins are the names of the builtin types by their codes
+var demangledBuiltins = map[byte]string{
+	'v': "void", 'w': "wchar_t", 'b': "bool", 'c': "char", 'a': "signed char", 'h': "unsigned char", 's': "short",
+	't': "unsigned short", 'i': "int", 'j': "unsigned int", 'l': "long", 'm': "unsigned long", 'x': "long long",
+	'y': "unsigned long long", 'n': "__int128", 'o': "unsigned __int128", 'f': "float", 'd': "double",
+	'e': "long double", 'g': "__float128", 'z': "...",
+}
+
+// demangledExtendedBuiltins are the names of the builtin types by the codes following 'D'
+var demangledExtendedBuiltins = map[byte]string{
+	'd': "decimal64", 'e': "decimal128", 'f': "decimal32", 'h': "half", 'i': "char32_t", 's': "char16_t",
+	'u': "char8_t", 'a': "auto", 'c': "decltype(auto)", 'n': "decltype(nullptr)",
+}
+
+func (d *demangler) typ() *demangledType {
+	d.enter()
+	defer d.leave()
+	c := d.peek()
+	if name, ok := demangledBuiltins[c]; ok {
+		d.pos++
+		return &demangledType{kind: namedKind, text: name, builtin: true}
+	}
+	var t *demangledType
+	switch c {
+	case 'r', 'V', 'K':
+		t = &demangledType{kind: qualifiedType, text: d.cvQualifiers()}
+		if d.peek() == 'F' {
+			// The qualifiers of member functions types aren't qualifiers of a substitutable function type
+			t.inner = d.functionType()
+		} else {
+			t.inner = d.typ()
+		}
+	case 'P':
+		d.pos++
+		t = &demangledType{kind: pointerType, inner: d.typ()}
+	case 'R':
+		d.pos++
+		t = &demangledType{kind: referenceType, inner: d.typ()}
+	case 'O':
+		d.pos++
+		t = &demangledType{kind: rvalueReferenceType, inner: d.typ()}
+	case 'F':
+		t = d.functionType()
+	case 'A':
+		d.pos++
+		t = &demangledType{kind: arrayType}
+		if d.peek() != '_' {
+			t.text = strconv.Itoa(d.number())
+		}
+		d.expect('_')
+		t.inner = d.typ()
+	case 'M':
+		d.pos++
+		t = &demangledType{kind: memberPointerType, class: d.typ()}
+		t.inner = d.typ()
+	case 'T':
+		// The substitutions of template parameters refer to the template arguments where they are substituted
+		param := d.templateParamRef()
+		d.addSub(param)
+		t = d.resolveParam(param)
+		if d.peek() != 'I' {
+			return t
+		}
+		t = namedType(appendTemplateArgs(formatType(t, ""), d.templateArgsList()))
+	case 'S':
+		if d.peekAt(1) == 't' {
+			t = namedType(d.name(false).text)
+			break
+		}
+		t = d.substitution()
+		if d.peek() != 'I' {
+			return t
+		}
+		t = namedType(appendTemplateArgs(formatType(t, ""), d.templateArgsList()))
+	case 'D':
+		if name, ok := demangledExtendedBuiltins[d.peekAt(1)]; ok {
+			d.pos += 2
+			return &demangledType{kind: namedKind, text: name, builtin: true}
+		}
+		if d.peekAt(1) != 'p' {
+			d.fail("unsupported type")
+		}
+		d.pos += 2
+		t = d.packExpansion()
+	case 'u':
+		d.pos++
+		return &demangledType{kind: namedKind, text: d.sourceName(), builtin: true}
+	default:
+		t = namedType(d.name(false).text)
+	}
+	d.addSub(t)
+	return t
+}
+
+// packExpansion parses the type of a pack expansion, and parses it again for each element of the pack it refers to
+// return the pack of the expanded types
+func (d *demangler) packExpansion() *demangledType {
+	start, subs := d.pos, len(d.subs)
+	expanded := d.expandedPack
+	d.expandedPack = nil
+	t := d.typ()
+	pack := d.expandedPack
+	d.expandedPack = expanded
+	if pack == nil {
+		return t
+	}
+	end, parsedSubs := d.pos, d.subs
+	expanding, element := d.expanding, d.packElement
+	expansion := &demangledType{kind: packType}
+	for d.packElement = range pack.params {
+		d.pos, d.subs, d.expanding = start, append([]*demangledType(nil), parsedSubs[:subs]...), true
+		expansion.params = append(expansion.params, d.typ())
+	}
+	d.pos, d.subs, d.expanding, d.packElement = end, parsedSubs, expanding, element
+	return expansion
+}
+
+func (d *demangler) functionType() *demangledType {
+	d.expect('F')
+	if d.peek() == 'Y' {
+		d.pos++
+	}
+	t := &demangledType{kind: functionType, inner: d.typ()}
+	t.text = "(" + d.params() + ")"
+	switch d.peek() {
+	case 'R':
+		d.pos++
+		t.text += " &"
+	case 'O':
+		d.pos++
+		t.text += " &&"
+	}
+	d.expect('E')
+	return t
+}
+
+type demangledKind int
+
+const (
+	namedKind demangledKind = iota
+	pointerType
+	referenceType
+	rvalueReferenceType
+	qualifiedType
+	functionType
+	arrayType
+	memberPointerType
+	packType
+	templateParamKind // Only in the substitution candidates
+)
+
+// demangledType is a parsed type, or a template argument
+type demangledType struct {
+	kind    demangledKind
+	text    string           // The name, the qualifiers, the parameters of functions or the dimension of arrays
+	inner   *demangledType   // The pointed, referred, qualified, element or member type, or the return type
+	class   *demangledType   // The class of member pointers
+	params  []*demangledType // The elements of packs
+	index   int              // The index of template parameters
+	builtin bool
+}
+
+func namedType(text string) *demangledType {
+	return &demangledType{kind: namedKind, text: text}
+}
+
+// checkSize fails the demangling of symbols expanding beyond the maximal demangled size
+func checkSize(text string) string {
+	if len(text) > maxDemangledSize {
+		panic(demangleError("demangled symbol too long"))
+	}
+	return text
+}
+
+// formatType returns the text of a type declaring the given declarator, e.g. "void (*)(int)" for a pointer to a
+// function and "*"
+func formatType(t *demangledType, declarator string) string {
+	switch t.kind {
+	case pointerType:
+		return formatType(t.inner, "*"+declarator)
+	case referenceType, rvalueReferenceType:
+		// References to references collapse to rvalue references only if both are
+		kind := t.kind
+		for t.inner.kind == referenceType || t.inner.kind == rvalueReferenceType {
+			if t.inner.kind == referenceType {
+				kind = referenceType
+			}
+			t = t.inner
+		}
+		if kind == referenceType {
+			return formatType(t.inner, "&"+declarator)
+		}
+		return formatType(t.inner, "&&"+declarator)
+	case qualifiedType:
+		if t.inner.kind == qualifiedType {
+			// Qualifying a qualified template argument doesn't repeat its qualifiers
+			return formatType(mergeQualifiers(t, t.inner), declarator)
+		}
+		switch t.inner.kind {
+		case functionType:
+			return formatFunction(t.inner, declarator, t.text)
+		case arrayType:
+			// The qualifiers of arrays are the qualifiers of their elements
+			elem := &demangledType{kind: qualifiedType, text: t.text, inner: t.inner.inner}
+			return formatType(&demangledType{kind: arrayType, text: t.inner.text, inner: elem}, declarator)
+		}
+		return formatType(t.inner, t.text+declarator)
+	case functionType:
+		return formatFunction(t, declarator, "")
+	case arrayType:
+		dims := ""
+		elem := t
+		for ; elem.kind == arrayType; elem = elem.inner {
+			dims += "[" + elem.text + "]"
+		}
+		if declarator != "" {
+			dims = "(" + declarator + ") " + dims
+		}
+		return checkSize(formatType(elem, "") + " " + dims)
+	case memberPointerType:
+		return formatType(t.inner, formatType(t.class, "")+"::*"+declarator)
+	case packType:
+		return formatTypes(t.params)
+	}
+	return joinDeclarator(t.text, declarator)
+}
+
+// formatReturnType returns the text of a function returning the given type, whose declarator is the function name
+// and parameters. Only functions returning pointers to functions or to arrays are nested in their return type.
+func formatReturnType(ret *demangledType, function string) string {
+	for t := ret; ; t = t.inner {
+		switch t.kind {
+		case pointerType, referenceType, rvalueReferenceType, qualifiedType, memberPointerType:
+			continue
+		case functionType, arrayType:
+			return formatType(ret, function)
+		}
+		return checkSize(formatType(ret, "") + " " + function)
+	}
+}
+
+func mergeQualifiers(outer, inner *demangledType) *demangledType {
+	var quals string
+	for _, qual := range []string{" const", " volatile", " restrict"} {
+		if strings.Contains(outer.text, qual) || strings.Contains(inner.text, qual) {
+			quals += qual
+		}
+	}
+	return &demangledType{kind: qualifiedType, text: quals, inner: inner.inner}
+}
+
+func formatFunction(t *demangledType, declarator string, quals string) string {
+	if declarator != "" {
+		declarator = "(" + declarator + ")"
+	}
+	return formatReturnType(t.inner, declarator+t.text+quals)
+}
+
+func joinDeclarator(base string, declarator string) string {
+	if declarator == "" {
+		return base
+	}
+	switch declarator[0] {
+	case ' ', '*', '&', '[':
+		return checkSize(base + declarator)
+	}
+	return checkSize(base + " " + declarator)
+}
+
+// formatTypes returns the text of a list of types, with the elements of packs in place of the packs
+func formatTypes(types []*demangledType) string {
+	var texts []string
+	for _, t := range types {
+		if t.kind == packType {
+			if !isEmptyPack(t) {
+				texts = append(texts, formatTypes(t.params))
+			}
+			continue
+		}
+		texts = append(texts, formatType(t, ""))
+	}
+	return checkSize(strings.Join(texts, ", "))
+}
+
+// appendTemplateArgs returns the name followed by the template arguments, separating angle brackets which would
+// otherwise form an operator. As in c++filt, the closing angle brackets aren't separated after a trailing empty pack.
+func appendTemplateArgs(name string, args []*demangledType) string {
+	if strings.HasSuffix(name, "<") {
+		name += " "
+	}
+	text := formatTypes(args)
+	if strings.HasSuffix(text, ">") && !endsWithEmptyPack(args) {
+		text += " "
+	}
+	return checkSize(name + "<" + text + ">")
+}
+
+func endsWithEmptyPack(args []*demangledType) bool {
+	if len(args) == 0 || args[len(args)-1].kind != packType {
+		return false
+	}
+	last := args[len(args)-1]
+	if isEmptyPack(last) {
+		return len(args) > 1
+	}
+	return endsWithEmptyPack(last.params)
+}
+
+// isEmptyPack checks if the type is a pack with no elements, or with empty packs only
+func isEmptyPack(t *demangledType) bool {
+	if t.kind != packType {
+		return false
+	}
+	for _, elem := range t.params {
+		if !isEmptyPack(elem) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLower(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
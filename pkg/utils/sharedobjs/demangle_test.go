@@ -0,0 +1,127 @@
+package sharedobjs
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDemangle(t *testing.T) {
+	// The expected signatures and names are the ones printed by c++filt and "c++filt -p"
+	testCases := []struct {
+		Symbol            string
+		ExpectedSignature string
+		ExpectedName      string
+	}{
+		{"_ZNK3Foo3getEv", "Foo::get() const", "Foo::get"},
+		{"_ZZ3fooiE1x", "foo(int)::x", "foo(int)::x"},
+		{"_Z3maxIiET_S0_S0_", "int max<int>(int, int)", "max<int>"},
+		{"_ZN2ns3fooENS_3BarE", "ns::foo(ns::Bar)", "ns::foo"},
+		{"_ZN3FooC2Ev", "Foo::Foo()", "Foo::Foo"},
+		{"_ZN3FooD0Ev", "Foo::~Foo()", "Foo::~Foo"},
+		{"_Z3foov.constprop.0.isra.0", "foo() [clone .constprop.0] [clone .isra.0]", "foo"},
+		{"_ZNSsC1Ev", "std::basic_string<char, std::char_traits<char>, std::allocator<char> >::basic_string()", "std::basic_string<char, std::char_traits<char>, std::allocator<char> >::basic_string"},
+		{"_ZNKSs4sizeEv", "std::basic_string<char, std::char_traits<char>, std::allocator<char> >::size() const", "std::basic_string<char, std::char_traits<char>, std::allocator<char> >::size"},
+		{"_Z3fooB5cxx11v", "foo[abi:cxx11]()", "foo[abi:cxx11]"},
+		{"_ZTV3Foo", "vtable for Foo", "vtable for Foo"},
+		{"_ZTI3Foo", "typeinfo for Foo", "typeinfo for Foo"},
+		{"_ZThn8_N3Foo3barEv", "non-virtual thunk to Foo::bar()", "non-virtual thunk to Foo::bar()"},
+		{"_ZTv0_n24_N3Foo3barEv", "virtual thunk to Foo::bar()", "virtual thunk to Foo::bar()"},
+		{"_ZGVZ3foovE1x", "guard variable for foo()::x", "guard variable for foo()::x"},
+		{"_ZN9__gnu_cxx13new_allocatorIcED2Ev", "__gnu_cxx::new_allocator<char>::~new_allocator()", "__gnu_cxx::new_allocator<char>::~new_allocator"},
+		{"_ZNKSt6vectorIiSaIiEE4sizeEv", "std::vector<int, std::allocator<int> >::size() const", "std::vector<int, std::allocator<int> >::size"},
+		{"_ZSt4endlIcSt11char_traitsIcEERSt13basic_ostreamIT_T0_ES6_", "std::basic_ostream<char, std::char_traits<char> >& std::endl<char, std::char_traits<char> >(std::basic_ostream<char, std::char_traits<char> >&)", "std::endl<char, std::char_traits<char> >"},
+		{"_ZN12_GLOBAL__N_13fooEv", "(anonymous namespace)::foo()", "(anonymous namespace)::foo"},
+		{"_ZL3foov", "foo()", "foo"},
+		{"_Z3fooPFviE", "foo(void (*)(int))", "foo"},
+		{"_Z3fooM3FooKFvvE", "foo(void (Foo::*)() const)", "foo"},
+		{"_Z3fooM3Fooi", "foo(int Foo::*)", "foo"},
+		{"_Z3fooPA2_A3_i", "foo(int (*) [2][3])", "foo"},
+		{"_Z3fooRA3_i", "foo(int (&) [3])", "foo"},
+		{"_Z3fooA3_Pi", "foo(int* [3])", "foo"},
+		{"_Z3fooPKPc", "foo(char* const*)", "foo"},
+		{"_Z3fooPrVKi", "foo(int const volatile restrict*)", "foo"},
+		{"_Z3fooPFPFivEvE", "foo(int (*(*)())())", "foo"},
+		{"_ZNKR3Foo3getEv", "Foo::get() const &", "Foo::get"},
+		{"_ZN3FooltIiEEbi", "bool Foo::operator< <int>(int)", "Foo::operator< <int>"},
+		{"_ZN3FoocviEv", "Foo::operator int()", "Foo::operator int"},
+		{"_ZN3FoonwEm", "Foo::operator new(unsigned long)", "Foo::operator new"},
+		{"_ZN3FooaSEOS_", "Foo::operator=(Foo&&)", "Foo::operator="},
+		{"_Z3fooILin3EEvv", "void foo<-3>()", "foo<-3>"},
+		{"_Z3fooILb1EEvv", "void foo<true>()", "foo<true>"},
+		{"_Z3fooILj3EEvv", "void foo<3u>()", "foo<3u>"},
+		{"_Z3fooILc65EEvv", "void foo<(char)65>()", "foo<(char)65>"},
+		{"_Z3fooIJicEEvDpPT_", "void foo<int, char>(int*, char*)", "foo<int, char>"},
+		{"_Z3fooIJEEvDpT_", "void foo<>()", "foo<>"},
+		{"_Z3fooISt8functionIFviEEEvv", "void foo<std::function<void (int)> >()", "foo<std::function<void (int)> >"},
+		{"_Z3fooiz", "foo(int, ...)", "foo"},
+		{"_Z3fooDn", "foo(decltype(nullptr))", "foo"},
+		{"_ZZ4mainENKUliE_clEi", "main::{lambda(int)#1}::operator()(int) const", "main::{lambda(int)#1}::operator()"},
+		{"_ZN3FooUt_C1Ev", "Foo::{unnamed type#1}::Foo()", "Foo::{unnamed type#1}::Foo"},
+		{"_ZZ3foovEs", "foo()::string literal", "foo()::string literal"},
+		{"_ZNSirsEPFRSiS_E", "std::basic_istream<char, std::char_traits<char> >::operator>>(std::basic_istream<char, std::char_traits<char> >& (*)(std::basic_istream<char, std::char_traits<char> >&))", "std::basic_istream<char, std::char_traits<char> >::operator>>"},
+		{"_ZNSt12strstreambufC1EPFPvmEPFvS0_E", "std::strstreambuf::strstreambuf(void* (*)(unsigned long), void (*)(void*))", "std::strstreambuf::strstreambuf"},
+		{"_ZN4llvm11PassBuilder15parseModulePassERNS_11PassManagerINS_6ModuleENS_15AnalysisManagerIS2_JEEEJEEERKNS0_15PipelineElementE", "llvm::PassBuilder::parseModulePass(llvm::PassManager<llvm::Module, llvm::AnalysisManager<llvm::Module>>&, llvm::PassBuilder::PipelineElement const&)", "llvm::PassBuilder::parseModulePass"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Symbol, func(t *testing.T) {
+			signature, err := DemangleSignature(testCase.Symbol)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.ExpectedSignature, signature)
+			name, err := DemangleName(testCase.Symbol)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.ExpectedName, name)
+		})
+	}
+}
+
+func TestDemangle_NotMangled(t *testing.T) {
+	for _, symbol := range []string{"", "malloc", "_Z", "Z3foov"} {
+		_, err := DemangleSignature(symbol)
+		if symbol == "_Z" {
+			assert.Error(t, err)
+			assert.NotErrorIs(t, err, ErrNotMangled)
+			continue
+		}
+		assert.ErrorIs(t, err, ErrNotMangled, symbol)
+	}
+}
+
+func TestDemangle_Invalid(t *testing.T) {
+	for _, symbol := range []string{
+		"_Z4foo",                  // Truncated source name
+		"_Z3fooS_",                // Substitution with no candidate
+		"_Z3fooT_",                // Template parameter with no template
+		"_ZN3foo",                 // Unterminated nested name
+		"_Z3foov.",                // Empty clone suffix
+		"_Z3fooIXadL_Z3barvEEEvv", // Expression template argument
+		"_Z1fIiEDTcl1gfp_EET_",    // Decltype return type
+	} {
+		_, err := DemangleSignature(symbol)
+		assert.Error(t, err, symbol)
+		_, err = DemangleName(symbol)
+		assert.Error(t, err, symbol)
+	}
+}
+
+func TestDemangle_Bounds(t *testing.T) {
+	t.Run("Deep nesting", func(t *testing.T) {
+		symbol := "_Z3foo" + strings.Repeat("P", 10000) + "i"
+		_, err := DemangleSignature(symbol)
+		assert.Error(t, err)
+	})
+	t.Run("Substitutions expansion", func(t *testing.T) {
+		// Each pointer to function doubles the text of the previous one, which is its last substitution candidate
+		symbol := "_Z3fooPFviE"
+		for i := 0; i < 30; i++ {
+			previous := strings.ToUpper(strconv.FormatInt(int64(2*i), 36))
+			symbol += "PFvS" + previous + "_S" + previous + "_E"
+		}
+		_, err := DemangleSignature(symbol)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,270 @@
+package sharedobjs
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// DefaultDiskCacheMaxBytes is the default size budget of the on-disk symbols cache
+const DefaultDiskCacheMaxBytes = 256 << 20
+
+// diskCacheVersion is the version of the format of the entries of the on-disk symbols cache. Entries of other
+// versions are invalidated.
+const diskCacheVersion = 8
+
+const (
+	diskCacheEntrySuffix = ".symbols"
+	diskCacheTempSuffix  = ".tmp"
+)
+
+// diskCacheEntry is the content of a file of the on-disk symbols cache, encoded with encoding/gob
+type diskCacheEntry struct {
+	Version int
+	ID      ObjID
+	BuildID string // The GNU build ID of the SO whose symbols are kept, validated against the SO on every lookup
+	Symbols *dynamicSymbols
+}
+
+// diskSymbolsCache persists the symbols of SOs in a directory, so they are not parsed again after a restart.
+// Each SO is kept in its own file, named by its ObjID ("<device>-<inode>-<ctime>.symbols" in hex), holding a
+// diskCacheEntry. Only SOs with a GNU build ID are kept, and an entry is used only if the build ID of the SO
+// matches the one of the entry, so it is invalidated when the SO changes with the same ObjID.
+// The files are evicted by their last use (their modification time, which is updated on every hit) once their
+// total size exceeds the budget. The files are indexed when the cache is opened, and they are read on lookups.
+// It is safe for concurrent use.
+type diskSymbolsCache struct {
+	dir        string
+	maxBytes   int64
+	mutex      sync.Mutex
+	entries    *simplelru.LRU // File name -> its size
+	totalBytes int64
+}
+
+// indexedFile is a file of the cache found when the cache is opened
+type indexedFile struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+// openDiskSymbolsCache opens the cache in the given directory, creating the directory if it doesn't exist, and
+// indexes its files. Temporary files of writes which didn't complete are removed.
+func openDiskSymbolsCache(dir string, maxBytes int64) (*diskSymbolsCache, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultDiskCacheMaxBytes
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []indexedFile
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if strings.HasSuffix(name, diskCacheTempSuffix) {
+			_ = os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		if !dirEntry.Type().IsRegular() || !strings.HasSuffix(name, diskCacheEntrySuffix) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, indexedFile{name: name, size: info.Size(), modTime: info.ModTime()})
+	}
+	// The files are indexed from the least recently used, so they are evicted first
+	sort.SliceStable(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	entries, _ := simplelru.NewLRU(math.MaxInt32, nil)
+	cache := &diskSymbolsCache{dir: dir, maxBytes: maxBytes, entries: entries}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for _, file := range files {
+		cache.entries.Add(file.name, file.size)
+		cache.totalBytes += file.size
+	}
+	cache.evict()
+	return cache, nil
+}
+
+// entryName returns the name of the file of the SO with the given ObjID
+func entryName(id ObjID) string {
+	return fmt.Sprintf("%x-%x-%x%s", id.Device, id.Inode, id.Ctime, diskCacheEntrySuffix)
+}
+
+// load returns the kept symbols of the SO, if they were kept with the given build ID.
+// Entries of other build IDs, or which can't be decoded, are removed, which is reported by the third result.
+func (cache *diskSymbolsCache) load(id ObjID, buildID string) (*dynamicSymbols, bool, bool) {
+	name := entryName(id)
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if _, ok := cache.entries.Get(name); !ok {
+		return nil, false, false
+	}
+	path := filepath.Join(cache.dir, name)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		cache.remove(name)
+		return nil, false, true
+	}
+	var entry diskCacheEntry
+	err = gob.NewDecoder(bytes.NewReader(content)).Decode(&entry)
+	if err != nil || entry.Version != diskCacheVersion || entry.ID != id || entry.BuildID != buildID ||
+		entry.Symbols == nil {
+		cache.remove(name)
+		return nil, false, true
+	}
+	// The modification time persists the recency of the file across restarts
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	syms := entry.Symbols
+	// Empty maps are not encoded
+	if syms.Exported == nil {
+		syms.Exported = make(map[string]bool)
+	}
+	if syms.Imported == nil {
+		syms.Imported = make(map[string]bool)
+	}
+	if syms.ExportedInfo == nil {
+		syms.ExportedInfo = make(map[string]SymbolInfo)
+	}
+	if syms.ImportedInfo == nil {
+		syms.ImportedInfo = make(map[string]ImportedSymbolInfo)
+	}
+	return syms, true, false
+}
+
+// store keeps the symbols of the SO with its build ID, replacing its previous entry, and evicts the least recently
+// used files if the budget is exceeded. Entries larger than the whole budget are not kept.
+func (cache *diskSymbolsCache) store(id ObjID, buildID string, syms *dynamicSymbols) error {
+	var content bytes.Buffer
+	entry := diskCacheEntry{Version: diskCacheVersion, ID: id, BuildID: buildID, Symbols: syms}
+	if err := gob.NewEncoder(&content).Encode(&entry); err != nil {
+		return err
+	}
+	size := int64(content.Len())
+	if size > cache.maxBytes {
+		return nil
+	}
+
+	name := entryName(id)
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	// The entry is written to a temporary file and renamed, so a crash doesn't leave a partial entry
+	temp, err := os.CreateTemp(cache.dir, "*"+diskCacheTempSuffix)
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(content.Bytes())
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), filepath.Join(cache.dir, name))
+	}
+	if err != nil {
+		_ = os.Remove(temp.Name())
+		return err
+	}
+	if previous, ok := cache.entries.Peek(name); ok {
+		cache.totalBytes -= previous.(int64)
+	}
+	cache.entries.Add(name, size)
+	cache.totalBytes += size
+	cache.evict()
+	return nil
+}
+
+// remove removes the file of the cache with the given name. It should be called with the mutex held.
+func (cache *diskSymbolsCache) remove(name string) {
+	if size, ok := cache.entries.Peek(name); ok {
+		cache.totalBytes -= size.(int64)
+		cache.entries.Remove(name)
+	}
+	_ = os.Remove(filepath.Join(cache.dir, name))
+}
+
+// evict removes the least recently used files until their total size is within the budget. It should be called
+// with the mutex held.
+func (cache *diskSymbolsCache) evict() {
+	for cache.totalBytes > cache.maxBytes {
+		name, size, ok := cache.entries.RemoveOldest()
+		if !ok {
+			return
+		}
+		cache.totalBytes -= size.(int64)
+		_ = os.Remove(filepath.Join(cache.dir, name.(string)))
+	}
+}
+
+// readFileBuildID reads the GNU build ID of the ELF file in the given path (hex encoded), or an empty string if it
+// has none. Only the headers and the notes of the file are read.
+func readFileBuildID(path string) (string, error) {
+	osFile, err := openRegularFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer osFile.Close()
+	file, err := elf.NewFile(osFile)
+	if err != nil {
+		return "", err
+	}
+	_, buildID := readNotes(file)
+	return buildID, nil
+}
+
+// readDiskCachedSOSymbols reads the symbols of the SO from the on-disk cache if they are kept there with the build
+// ID of the SO, and otherwise parses them with the given function and writes them through to the cache
+func (soLoader *HostSymbolsLoader) readDiskCachedSOSymbols(soInfo ObjInfo, path string,
+	parse func() (*dynamicSymbols, error)) (*dynamicSymbols, error) {
+	buildID, err := readFileBuildID(path)
+	if err != nil || buildID == "" {
+		// The entries of SOs with no build ID can't be validated, so they are not kept
+		return parse()
+	}
+	syms, ok, invalidated := soLoader.diskCache.load(soInfo.Id, buildID)
+	if invalidated {
+		soLoader.stats.DiskCacheStale.Increment()
+	}
+	// Entries kept by a loader which didn't measure the entropy or count the relocations have none, so they are
+	// parsed again
+	if ok && (soLoader.config.MeasureEntropy && syms.CodeEntropy == 0 ||
+		soLoader.config.CountRelocations && len(syms.Relocations) == 0) {
+		ok = false
+	}
+	if ok {
+		soLoader.stats.DiskCacheHits.Increment()
+		syms.loadedFrom = soInfo
+		if soLoader.config.ValidateChecksum {
+			syms.checksum = syms.calcChecksum()
+		}
+		return syms, nil
+	}
+	syms, err = parse()
+	if err != nil {
+		return nil, err
+	}
+	// Symbols fetched from the symbol server are of the same build, so they are kept too. Truncated symbols depend
+	// on the symbols cap of the loader, so they are not kept.
+	if syms.BuildID == buildID && !syms.Truncated {
+		if err := soLoader.diskCache.store(soInfo.Id, buildID, syms); err != nil {
+			soLoader.stats.DiskCacheErrors.Increment()
+		}
+	}
+	return syms, nil
+}
@@ -0,0 +1,73 @@
+package sharedobjs
+
+import (
+	"debug/elf"
+	"io"
+	"sort"
+)
+
+// DynamicTagsLoader is implemented by loaders which can read the tags of the dynamic section of a SO (e.g.
+// DT_AUDIT), some of which are abused to hook the dynamic loader
+type DynamicTagsLoader interface {
+	GetDynamicTags(info ObjInfo) ([]elf.DynTag, error)
+}
+
+// ConstructorsCounter is implemented by loaders which can count the constructors of a SO, which the dynamic loader
+// runs when the SO is loaded: the entries of its init array (DT_INIT_ARRAY)
+type ConstructorsCounter interface {
+	GetConstructorsCount(info ObjInfo) (int, error)
+}
+
+// maxDynamicEntries is the maximal amount of entries read from the dynamic section. The dynamic section holds a few
+// dozens of entries, so larger sizes are of corrupted headers.
+const maxDynamicEntries = 4096
+
+// readDynamicTags returns the distinct tags of the entries of the dynamic segment of the ELF file, sorted by their
+// value, and the amount of entries of its init array (by DT_INIT_ARRAYSZ). The segment (PT_DYNAMIC) is read rather
+// than the section, as it is what the dynamic loader reads, and SOs may have no sections headers.
+func readDynamicTags(file *elf.File) ([]elf.DynTag, int) {
+	entrySize, pointerSize := 16, 8
+	if file.Class == elf.ELFCLASS32 {
+		entrySize, pointerSize = 8, 4
+	}
+	for _, prog := range file.Progs {
+		if prog.Type != elf.PT_DYNAMIC {
+			continue
+		}
+		size := prog.Filesz
+		if size > uint64(maxDynamicEntries*entrySize) {
+			size = uint64(maxDynamicEntries * entrySize)
+		}
+		data := make([]byte, size)
+		if _, err := prog.ReadAt(data, 0); err != nil && err != io.EOF {
+			return nil, 0
+		}
+		seen := make(map[elf.DynTag]bool)
+		var tags []elf.DynTag
+		constructors := 0
+		for ; len(data) >= entrySize; data = data[entrySize:] {
+			var tag elf.DynTag
+			var value uint64
+			if file.Class == elf.ELFCLASS32 {
+				tag = elf.DynTag(int32(file.ByteOrder.Uint32(data)))
+				value = uint64(file.ByteOrder.Uint32(data[4:]))
+			} else {
+				tag = elf.DynTag(int64(file.ByteOrder.Uint64(data)))
+				value = file.ByteOrder.Uint64(data[8:])
+			}
+			if tag == elf.DT_NULL {
+				break
+			}
+			if tag == elf.DT_INIT_ARRAYSZ && value/uint64(pointerSize) <= maxDynamicEntries {
+				constructors = int(value / uint64(pointerSize))
+			}
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+		sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+		return tags, constructors
+	}
+	return nil, 0
+}
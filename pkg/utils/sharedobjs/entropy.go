@@ -0,0 +1,82 @@
+package sharedobjs
+
+import (
+	"debug/elf"
+	"io"
+	"math"
+)
+
+// EntropyMeasurer is implemented by loaders which can measure the entropy of the code of a SO (see
+// HostSymbolsLoaderConfig.MeasureEntropy). Packed or encrypted code has a higher entropy than compiled code.
+type EntropyMeasurer interface {
+	GetCodeEntropy(info ObjInfo) (float64, error)
+}
+
+// entropyChunkSize is the size of the chunks which the code is read in while measuring its entropy
+const entropyChunkSize = 64 * 1024
+
+// measureCodeEntropy returns the highest Shannon entropy (in bits per byte, between 0 and 8) of the executable
+// sections of the ELF file. Files with no section headers (as packed files often are) are measured by their
+// executable loadable segments instead. Files with no code have an entropy of 0.
+func measureCodeEntropy(file *elf.File) float64 {
+	var highest float64
+	measured := false
+	for _, section := range file.Sections {
+		if section.Type != elf.SHT_PROGBITS || section.Flags&elf.SHF_EXECINSTR == 0 {
+			continue
+		}
+		measured = true
+		if entropy, err := readerEntropy(section.Open()); err == nil && entropy > highest {
+			highest = entropy
+		}
+	}
+	if measured {
+		return highest
+	}
+	for _, prog := range file.Progs {
+		if prog.Type != elf.PT_LOAD || prog.Flags&elf.PF_X == 0 {
+			continue
+		}
+		if entropy, err := readerEntropy(prog.Open()); err == nil && entropy > highest {
+			highest = entropy
+		}
+	}
+	return highest
+}
+
+// readerEntropy returns the Shannon entropy of the content of the reader, in bits per byte
+func readerEntropy(reader io.Reader) (float64, error) {
+	var counts [256]uint64
+	var total uint64
+	chunk := make([]byte, entropyChunkSize)
+	for {
+		n, err := reader.Read(chunk)
+		for _, b := range chunk[:n] {
+			counts[b]++
+		}
+		total += uint64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return shannonEntropy(counts, total), nil
+}
+
+// shannonEntropy calculates the Shannon entropy of the bytes with the given counts, in bits per byte
+func shannonEntropy(counts [256]uint64, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	var entropy float64
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
@@ -0,0 +1,72 @@
+package sharedobjs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aquasecurity/tracee/pkg/events/parse"
+	"github.com/aquasecurity/tracee/types/trace"
+)
+
+// ObjInfoError lists all the fields of a shared_object_loaded event which couldn't be parsed
+type ObjInfoError struct {
+	Errors []error
+}
+
+func (infoErr *ObjInfoError) Error() string {
+	messages := make([]string, len(infoErr.Errors))
+	for i, err := range infoErr.Errors {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("failed to parse %d shared object fields: %s", len(infoErr.Errors), strings.Join(messages, "; "))
+}
+
+// Is checks if any of the fields parsing errors matches the target
+func (infoErr *ObjInfoError) Is(target error) bool {
+	for _, err := range infoErr.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ObjInfoFromEvent extract from SO loading event (shared_object_loaded) the information available about the SO.
+// All the fields are parsed even if some of them fail, and the fields parsed are set in the returned info along
+// with an ObjInfoError listing the failed fields.
+func ObjInfoFromEvent(event trace.Event) (ObjInfo, error) {
+	var parseErrors []error
+	loadedObjectInode, err := parse.ArgUint64Val(&event, "inode")
+	if err != nil {
+		parseErrors = append(parseErrors, err)
+	}
+	loadedObjectDevice, err := parse.ArgUint32Val(&event, "dev")
+	if err != nil {
+		parseErrors = append(parseErrors, err)
+	}
+	loadedObjectCtime, err := parse.ArgUint64Val(&event, "ctime")
+	if err != nil {
+		parseErrors = append(parseErrors, err)
+	}
+	loadedObjectPath, err := parse.ArgStringVal(&event, "pathname")
+	if err != nil {
+		parseErrors = append(parseErrors, err)
+	}
+	// The path of loaded SOs which were deleted is reported with a suffix, which should not be part of the path
+	loadedObjectDeleted := strings.HasSuffix(loadedObjectPath, DeletedSuffix)
+	objInfo := ObjInfo{
+		Id: ObjID{
+			Inode:  loadedObjectInode,
+			Device: loadedObjectDevice,
+			Ctime:  loadedObjectCtime},
+		Path:    strings.TrimSuffix(loadedObjectPath, DeletedSuffix),
+		MountNS: event.MountNS,
+		Pid:     event.HostProcessID,
+		Deleted: loadedObjectDeleted,
+	}
+	if len(parseErrors) > 0 {
+		return objInfo, &ObjInfoError{Errors: parseErrors}
+	}
+	return objInfo, nil
+}
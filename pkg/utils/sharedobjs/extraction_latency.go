@@ -0,0 +1,55 @@
+package sharedobjs
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/aquasecurity/tracee/pkg/counter"
+)
+
+// LatencyBuckets are the upper bounds of the buckets of the LatencyHistogram.
+// The last bucket of the histogram counts the durations above the last bound.
+var LatencyBuckets = []time.Duration{
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// LatencyHistogram counts durations in the LatencyBuckets.
+// Observing a duration is lock free, so it is cheap enough to be done for every extraction.
+type LatencyHistogram struct {
+	counts [5]counter.Counter // One for each of the LatencyBuckets, and one for longer durations
+}
+
+// Observe counts the duration in its bucket
+func (histogram *LatencyHistogram) Observe(duration time.Duration) {
+	for i, bound := range LatencyBuckets {
+		if duration <= bound {
+			histogram.counts[i].Increment()
+			return
+		}
+	}
+	histogram.counts[len(LatencyBuckets)].Increment()
+}
+
+// Counts returns the amount of durations counted in each bucket
+func (histogram *LatencyHistogram) Counts() []int32 {
+	counts := make([]int32, len(histogram.counts))
+	for i := range histogram.counts {
+		counts[i] = histogram.counts[i].Read()
+	}
+	return counts
+}
+
+// GetExtractionDuration returns the time it took to extract the symbols of the SO (0 if its symbols were shared
+// with another SO), and whether it is the first time the duration of the extraction is requested.
+// If the SO is not cached, its symbols are extracted.
+func (soLoader *HostSymbolsLoader) GetExtractionDuration(soInfo ObjInfo) (time.Duration, bool, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return 0, false, err
+	}
+	first := atomic.CompareAndSwapInt32(&syms.durationTaken, 0, 1)
+	return syms.extractionDuration, first, nil
+}
@@ -1,26 +1,196 @@
 package sharedobjs
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"debug/elf"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/aquasecurity/tracee/pkg/counter"
 	"github.com/hashicorp/golang-lru/simplelru"
 )
 
+// DeletedSuffix is the suffix the kernel adds to paths of mapped files which were deleted
+const DeletedSuffix = " (deleted)"
+
 // HostSymbolsLoader is responsible for efficient reading of shared object's symbols.
 // The logic of the loader here is used on absolute paths, so container relative paths won't work here.
 // This object operation requires the CAP_DAC_OVERRIDE to access files across the system.
 type HostSymbolsLoader struct {
-	loadingFunc func(path string) (*dynamicSymbols, error)
+	loadingFunc func(ctx context.Context, path string) (*dynamicSymbols, error)
 	soCache     soDynamicSymbolsCache
+	fs          fs.FS // Used to find the mapping of deleted SOs in procfs
+	// Change the path of the SO to a path it can be read from by the loader. It is used only when the SO symbols
+	// are not cached, so identical SOs seen from different mount namespaces are read only once.
+	resolvePath func(soInfo ObjInfo) (ObjInfo, error)
+	config      HostSymbolsLoaderConfig
+	stats       LoaderStats
+	// Used to share the symbols of SOs with identical content, if content deduplication is configured
+	hashingFunc  func(path string) (string, error)
+	contentCache *contentSymbolsCache
+	// Used to cache the symbols fetched from the symbol server, if one is configured
+	fetchedCache *fetchedSymbolsCache
+	readOpts     readOptions // The options of reading the symbols of SOs, and of the objects fetched for them
+	// Used to persist the symbols across restarts, if an on-disk cache is configured
+	diskCache *diskSymbolsCache
+	// Used to apply the policies of the filesystems of the SOs, if any is configured
+	fsPolicies *filesystemsPolicy
+	// Used to cache the metadata of SOs read without their symbols
+	soMetadata soDynamicSymbolsCache
+	// Used to classify the SOs which can't be read because of missing permissions once, without reading them again
+	unreadable *unreadableCache
+	clock      Clock // If nil, the SystemClock is used
+	closed     int32 // Set atomically when the loader is closed
+}
+
+// ErrLoaderClosed is returned when symbols are requested from a closed loader
+var ErrLoaderClosed = errors.New("symbols loader is closed")
+
+// ErrNotRegularFile is returned for SOs whose path is not a regular file, e.g. a directory, a FIFO or a device node
+// delivered by a malformed event. Such files are never opened, as opening them may block or have side effects.
+var ErrNotRegularFile = errors.New("SO path is not a regular file")
+
+// HostSymbolsLoaderConfig is the configuration of the HostSymbolsLoader
+type HostSymbolsLoaderConfig struct {
+	CacheSize int
+	// Validate that SOs with the same ObjID which are loaded from different paths or mount namespaces have the
+	// same symbols. This requires reading the SO again on such cache hits, so it is expensive.
+	ValidateChecksum bool
+	// Share the symbols of SOs with identical content (e.g. copies of a library in different container images),
+	// even if their ObjID is different. This requires hashing the content of every SO which is not cached.
+	ContentDedup bool
+	// SOs of at least this size are read through a memory mapping instead of read calls. SOs which can't be mapped
+	// are read using read calls. If 0, SOs are never mapped.
+	MmapMinSize int64
+	// Normalizes the ObjID of SOs before it is used as the cache key, so equivalent SOs with different IDs share
+	// their cached symbols (e.g. the same files in different snapshots of a filesystem). If nil, the ObjID is used
+	// as it is. Collapsing IDs of SOs which are not identical makes the loader return the symbols of the wrong SO,
+	// unless ValidateChecksum is configured.
+	RemapID func(id ObjID) ObjID
+	// Fetch symbol-bearing copies of stripped SOs by their build ID (e.g. from a debuginfod server). The fetched
+	// symbols are cached by the build ID, and failures to fetch them fall back to the local SO. If nil, only the
+	// local SOs are read.
+	SymbolServer SymbolServer
+	// Alternative sources of the exported symbols of SOs (e.g. DWARFSymbolSource), consulted in their order after
+	// the dynamic symbols table and before the symbol server. The symbols which a source supplies are added to the
+	// symbols read before it, and SOs with no dynamic symbols table are fetched from the symbol server only if no
+	// source supplied symbols. If empty, the dynamic symbols table is the only local source.
+	SymbolSources []SymbolSource
+	// How SOs residing in filesystems of each type (the f_type of statfs, e.g. RemoteFilesystemTypes) are read,
+	// e.g. skipping SOs in network filesystems or limiting the duration of reading them (see
+	// RemoteFilesystemsPolicies). SOs in filesystems with no policy are read normally.
+	FilesystemPolicies map[uint32]FilesystemPolicy
+	// Persist the parsed symbols of SOs with a GNU build ID in this directory, so they are not parsed again after a
+	// restart. The kept symbols are used only if the build ID of the SO didn't change. If the directory can't be
+	// opened, the symbols are not persisted. If empty, the symbols are kept only in memory.
+	DiskCacheDir string
+	// The size budget of the files of the on-disk cache, beyond which the least recently used files are removed.
+	// If 0, DefaultDiskCacheMaxBytes is used.
+	DiskCacheMaxBytes int64
+	// Read only the first symbols of the symbols tables of SOs with more symbols than this, so huge tables (e.g. of
+	// generated code) are not parsed whole. The symbols of such SOs are marked as truncated (see
+	// TruncationDetector), and their imported symbols have no library, as the versions table is not read. This caps
+	// the amount of symbols regardless of the size of the SO itself. If 0, all the symbols are read.
+	MaxSymbols int
+	// Measure the Shannon entropy of the code sections of SOs while their symbols are read (see EntropyMeasurer),
+	// for detecting packed or encrypted code. This requires reading the code of every SO which is not cached.
+	MeasureEntropy bool
+	// Count the dynamic relocations of SOs by their type while their symbols are read (see RelocationsCounter), for
+	// detecting unusual GOT and PLT setups. This requires reading the relocation sections of every SO which is not
+	// cached.
+	CountRelocations bool
+	// The source of time of the extraction latency and the filesystems timeouts. If nil, the SystemClock is used.
+	Clock Clock
+}
+
+// LoaderStats are statistics of the symbols loader operation
+type LoaderStats struct {
+	ChecksumMismatches   counter.Counter // SOs with the same ObjID as a cached SO, but different symbols
+	DedupLookups         counter.Counter // SOs looked up by their content hash
+	DedupHits            counter.Counter // SOs whose symbols were found by their content hash
+	ExtractionLatency    LatencyHistogram
+	SymbolServerFetches  counter.Counter // Stripped SOs whose symbols were fetched from the symbol server
+	SymbolServerFailures counter.Counter // Stripped SOs whose symbols couldn't be fetched from the symbol server
+	SymbolSourceFailures counter.Counter // Failures of the symbol sources to supply the symbols of SOs
+	FilesystemSkips      counter.Counter // SOs which weren't read due to the policy of their filesystem
+	FilesystemTimeouts   counter.Counter // SOs whose reading timed out due to the policy of their filesystem
+	DiskCacheHits        counter.Counter // SOs whose symbols were read from the on-disk cache
+	DiskCacheStale       counter.Counter // On-disk cache entries of SOs which changed, or which were corrupted
+	DiskCacheErrors      counter.Counter // Failures to open the on-disk cache or to write to it
+}
+
+// DedupHitRate returns the part of the SOs looked up by their content hash which were found
+func (stats *LoaderStats) DedupHitRate() float64 {
+	lookups := stats.DedupLookups.Read()
+	if lookups == 0 {
+		return 0
+	}
+	return float64(stats.DedupHits.Read()) / float64(lookups)
 }
 
 func InitHostSymbolsLoader(cacheSize int) *HostSymbolsLoader {
+	return InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: cacheSize})
+}
+
+func InitHostSymbolsLoaderWithConfig(config HostSymbolsLoaderConfig) *HostSymbolsLoader {
 	lruCallback := simplelru.EvictCallback(func(key interface{}, value interface{}) {})
-	sharedObjectsLRU, _ := simplelru.NewLRU(cacheSize, lruCallback)
-	soCache := dynamicSymbolsLRUCache{sharedObjectsLRU}
-	return &HostSymbolsLoader{
+	sharedObjectsLRU, _ := simplelru.NewLRU(config.CacheSize, lruCallback)
+	soCache := dynamicSymbolsLRUCache{lru: sharedObjectsLRU}
+	metadataLRU, _ := simplelru.NewLRU(config.CacheSize, lruCallback)
+	soLoader := &HostSymbolsLoader{
 		soCache:     &soCache,
+		soMetadata:  &dynamicSymbolsLRUCache{lru: metadataLRU},
+		unreadable:  initUnreadableCache(config.CacheSize),
 		loadingFunc: loadSharedObjectDynamicSymbols,
+		fs:          os.DirFS("/"),
+		config:      config,
+		clock:       config.Clock,
+	}
+	if config.ContentDedup {
+		soLoader.hashingFunc = hashFileContent
+		soLoader.contentCache = initContentSymbolsCache(config.CacheSize)
+	}
+	if config.SymbolServer != nil {
+		soLoader.fetchedCache = initFetchedSymbolsCache(config.CacheSize)
 	}
+	if config.DiskCacheDir != "" {
+		diskCache, err := openDiskSymbolsCache(config.DiskCacheDir, config.DiskCacheMaxBytes)
+		if err != nil {
+			soLoader.stats.DiskCacheErrors.Increment()
+		} else {
+			soLoader.diskCache = diskCache
+		}
+	}
+	if len(config.FilesystemPolicies) > 0 {
+		soLoader.fsPolicies = newFilesystemsPolicy(config.FilesystemPolicies)
+	}
+	opts := readOptions{maxSymbols: config.MaxSymbols, entropy: config.MeasureEntropy,
+		relocations: config.CountRelocations}
+	soLoader.readOpts = opts
+	if opts != (readOptions{}) {
+		soLoader.loadingFunc = loadSharedObjectDynamicSymbolsWith(opts)
+	}
+	if config.MmapMinSize > 0 {
+		soLoader.loadingFunc = loadSharedObjectDynamicSymbolsMmap(config.MmapMinSize, opts)
+	}
+	return soLoader
+}
+
+// Stats return the statistics of the loader operation
+func (soLoader *HostSymbolsLoader) Stats() *LoaderStats {
+	return &soLoader.stats
 }
 
 // GetDynamicSymbols try to get shared objects dynamic symbols from lru, and if fails read needed information
@@ -57,32 +227,387 @@ func (soLoader *HostSymbolsLoader) GetImportedSymbols(soInfo ObjInfo) (map[strin
 	return copyMap(syms.Imported), nil
 }
 
+// GetExportedSymbolsInfo try to get shared objects exported symbols information from lru, and if fails read
+// needed information from ELF file.
+func (soLoader *HostSymbolsLoader) GetExportedSymbolsInfo(soInfo ObjInfo) (map[string]SymbolInfo, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return nil, err
+	}
+	symsInfo := make(map[string]SymbolInfo, len(syms.ExportedInfo))
+	for name, info := range syms.ExportedInfo {
+		symsInfo[name] = info
+	}
+	return symsInfo, nil
+}
+
+// GetImportedSymbolsInfo try to get shared objects imported symbols information from lru, and if fails read
+// needed information from ELF file.
+func (soLoader *HostSymbolsLoader) GetImportedSymbolsInfo(soInfo ObjInfo) (map[string]ImportedSymbolInfo, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return nil, err
+	}
+	importsInfo := make(map[string]ImportedSymbolInfo, len(syms.ImportedInfo))
+	for name, info := range syms.ImportedInfo {
+		importsInfo[name] = info
+	}
+	return importsInfo, nil
+}
+
+// GetFunctionRanges try to get the addresses ranges of the exported functions of the shared object from lru, and if
+// fails read needed information from ELF file. The ranges are sorted by address (and by name for aliases of the
+// same function).
+func (soLoader *HostSymbolsLoader) GetFunctionRanges(soInfo ObjInfo) ([]FuncRange, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return nil, err
+	}
+	ranges := make([]FuncRange, len(syms.FuncRanges))
+	copy(ranges, syms.FuncRanges)
+	return ranges, nil
+}
+
+// GetPacker try to get the packer which packed the shared object from lru, and if fails read needed information
+// from ELF file. An empty string is returned if the SO is not packed by a recognized packer.
+func (soLoader *HostSymbolsLoader) GetPacker(soInfo ObjInfo) (string, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return "", err
+	}
+	return syms.Packer, nil
+}
+
+// GetWritableCodeSegments try to get the segments of the shared object which are both writable and executable from
+// lru, and if fails read needed information from ELF file.
+func (soLoader *HostSymbolsLoader) GetWritableCodeSegments(soInfo ObjInfo) ([]Segment, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return nil, err
+	}
+	return syms.WXSegments, nil
+}
+
+// GetDynamicTags try to get the distinct tags of the dynamic section of the shared object from lru, and if fails
+// read needed information from ELF file. The tags are sorted by their value.
+func (soLoader *HostSymbolsLoader) GetDynamicTags(soInfo ObjInfo) ([]elf.DynTag, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]elf.DynTag, len(syms.DynamicTags))
+	copy(tags, syms.DynamicTags)
+	return tags, nil
+}
+
+// GetConstructorsCount try to get the amount of entries of the init array of the shared object from lru, and if
+// fails read needed information from ELF file.
+func (soLoader *HostSymbolsLoader) GetConstructorsCount(soInfo ObjInfo) (int, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return 0, err
+	}
+	return syms.Constructors, nil
+}
+
+// GetInterpreter try to get the interpreter which the shared object requests from lru, and if fails read needed
+// information from ELF file. An empty interpreter is returned if the shared object requests none.
+func (soLoader *HostSymbolsLoader) GetInterpreter(soInfo ObjInfo) (string, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return "", err
+	}
+	return syms.Interp, nil
+}
+
+// IsTruncated try to get whether only the first symbols of the shared object were read from lru, and if fails read
+// needed information from ELF file.
+func (soLoader *HostSymbolsLoader) IsTruncated(soInfo ObjInfo) (bool, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return false, err
+	}
+	return syms.Truncated, nil
+}
+
+// GetCodeEntropy try to get the entropy of the code of the shared object from lru, and if fails read needed
+// information from ELF file. The entropy is 0 unless the loader measures it (see
+// HostSymbolsLoaderConfig.MeasureEntropy).
+func (soLoader *HostSymbolsLoader) GetCodeEntropy(soInfo ObjInfo) (float64, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return 0, err
+	}
+	return syms.CodeEntropy, nil
+}
+
+// GetRelocationCounts try to get the amount of dynamic relocations of each type of the shared object from lru, and
+// if fails read needed information from ELF file. There are no counts unless the loader counts relocations (see
+// HostSymbolsLoaderConfig.CountRelocations).
+func (soLoader *HostSymbolsLoader) GetRelocationCounts(soInfo ObjInfo) (map[string]int, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(syms.Relocations))
+	for relType, count := range syms.Relocations {
+		counts[relType] = count
+	}
+	return counts, nil
+}
+
+// IsInterpreter try to get whether the shared object is the dynamic loader from lru, and if fails read needed
+// information from ELF file.
+func (soLoader *HostSymbolsLoader) IsInterpreter(soInfo ObjInfo) (bool, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return false, err
+	}
+	return syms.Interpreter, nil
+}
+
+// GetSoname try to get the DT_SONAME of the shared object from lru, and if fails read needed information from
+// ELF file. An empty soname is returned if the shared object has none.
+func (soLoader *HostSymbolsLoader) GetSoname(soInfo ObjInfo) (string, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return "", err
+	}
+	return syms.Soname, nil
+}
+
+// GetBuildID try to get the GNU build ID of the shared object (hex encoded) from lru, and if fails read needed
+// information from ELF file. An empty build ID is returned if the shared object has none.
+func (soLoader *HostSymbolsLoader) GetBuildID(soInfo ObjInfo) (string, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return "", err
+	}
+	return syms.BuildID, nil
+}
+
+// HasNote try to get whether the shared object carries the given ELF note from lru, and if fails read needed
+// information from ELF file.
+func (soLoader *HostSymbolsLoader) HasNote(soInfo ObjInfo, note NoteID) (bool, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return false, err
+	}
+	return syms.Notes[note], nil
+}
+
+// IsSymbolExported check if the given symbol is exported by the shared object.
+// The symbols are read from the lru, or loaded to it from the ELF file, so they are shared with the bulk methods.
+// The ELF reader has no targeted lookup of symbols, so on cache miss all the symbols are loaded (but not copied).
+func (soLoader *HostSymbolsLoader) IsSymbolExported(soInfo ObjInfo, symbol string) (bool, error) {
+	syms, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil {
+		return false, err
+	}
+	return syms.Exported[symbol], nil
+}
+
+// Close purges the cached symbols of the loader, after which it can't load symbols anymore.
+// Close can be called multiple times.
+func (soLoader *HostSymbolsLoader) Close() error {
+	if !atomic.CompareAndSwapInt32(&soLoader.closed, 0, 1) {
+		return nil
+	}
+	soLoader.soCache.Purge()
+	if soLoader.soMetadata != nil {
+		soLoader.soMetadata.Purge()
+	}
+	if soLoader.contentCache != nil {
+		soLoader.contentCache.Purge()
+	}
+	if soLoader.unreadable != nil {
+		soLoader.unreadable.Purge()
+	}
+	if soLoader.fetchedCache != nil {
+		soLoader.fetchedCache.Purge()
+	}
+	return nil
+}
+
+// LoadContext reads the symbols of the SO into the cache, unless they are already cached. Reading the file of the
+// SO stops once the context is done, and fails with the error of the context, which is not cached.
+func (soLoader *HostSymbolsLoader) LoadContext(ctx context.Context, soInfo ObjInfo) error {
+	_, err := soLoader.loadSOSymbolsContext(ctx, soInfo)
+	return err
+}
+
 func (soLoader *HostSymbolsLoader) loadSOSymbols(soInfo ObjInfo) (*dynamicSymbols, error) {
-	syms, ok := soLoader.soCache.Get(soInfo.Id)
+	return soLoader.loadSOSymbolsContext(context.Background(), soInfo)
+}
+
+// loadSOSymbolsContext loads the symbols of the SO from the cache, or reads them from its file under the context
+func (soLoader *HostSymbolsLoader) loadSOSymbolsContext(ctx context.Context, soInfo ObjInfo) (*dynamicSymbols, error) {
+	if atomic.LoadInt32(&soLoader.closed) != 0 {
+		return nil, ErrLoaderClosed
+	}
+	keyInfo := soInfo
+	if soLoader.config.RemapID != nil {
+		keyInfo.Id = soLoader.config.RemapID(soInfo.Id)
+	}
+	syms, ok := soLoader.soCache.Get(keyInfo.Id)
 	if ok {
+		if soLoader.config.ValidateChecksum && !syms.loadedFrom.samePath(soInfo) {
+			return soLoader.validateCachedSymbols(ctx, soInfo, syms)
+		}
 		return syms, nil
 	}
-	syms, err := soLoader.loadingFunc(soInfo.Path)
+	if soLoader.unreadable != nil {
+		if err, ok := soLoader.unreadable.Get(keyInfo.Id); ok {
+			return nil, err
+		}
+	}
+	syms, err := soLoader.readSOSymbols(ctx, soInfo)
 	if err != nil {
+		// Only permission failures are cached, as other failures (e.g. of the context) may not repeat
+		if soLoader.unreadable != nil && errors.Is(err, fs.ErrPermission) {
+			soLoader.unreadable.Add(keyInfo.Id, err)
+		}
 		return nil, err
 	}
-	soLoader.soCache.Add(soInfo, syms)
+	soLoader.soCache.Add(keyInfo, syms)
 	return syms, nil
 }
 
+// RemapDevices returns an ObjID remapping function (see HostSymbolsLoaderConfig.RemapID) which replaces the devices
+// in the given mapping, e.g. the devices of snapshots of a filesystem with the device of the filesystem.
+// The inodes are kept, so it should be used only for snapshots which preserve the inodes of their files.
+func RemapDevices(devices map[uint32]uint32) func(id ObjID) ObjID {
+	return func(id ObjID) ObjID {
+		if device, ok := devices[id.Device]; ok {
+			id.Device = device
+		}
+		return id
+	}
+}
+
+// resolveReadPath returns the SO as the loader reads it, and the path of the file it is read from
+func (soLoader *HostSymbolsLoader) resolveReadPath(soInfo ObjInfo) (ObjInfo, string, error) {
+	readInfo := soInfo
+	if soLoader.resolvePath != nil {
+		var err error
+		readInfo, err = soLoader.resolvePath(soInfo)
+		if err != nil {
+			return ObjInfo{}, "", err
+		}
+	}
+	path := readInfo.Path
+	if readInfo.Deleted {
+		var err error
+		path, err = findDeletedObjectMapping(soLoader.fs, readInfo)
+		if err != nil {
+			return ObjInfo{}, "", err
+		}
+	}
+	return readInfo, path, nil
+}
+
+// readSOSymbols read the symbols of the SO from its file under the context, without using the cache
+func (soLoader *HostSymbolsLoader) readSOSymbols(ctx context.Context, soInfo ObjInfo) (*dynamicSymbols, error) {
+	readInfo, path, err := soLoader.resolveReadPath(soInfo)
+	if err != nil {
+		return nil, err
+	}
+	parse := func() (*dynamicSymbols, error) {
+		if soLoader.contentCache != nil {
+			return soLoader.readDedupSOSymbols(ctx, soInfo, path)
+		}
+		return soLoader.parseSOSymbols(ctx, soInfo, path)
+	}
+	cachedParse := parse
+	if soLoader.diskCache != nil {
+		cachedParse = func() (*dynamicSymbols, error) {
+			return soLoader.readDiskCachedSOSymbols(soInfo, path, parse)
+		}
+	}
+	if soLoader.fsPolicies != nil {
+		return soLoader.applyFilesystemPolicy(readInfo, path, cachedParse)
+	}
+	return cachedParse()
+}
+
+// parseSOSymbols parse the symbols of the SO from the file in the given path. Once the context is done, the file
+// is no longer read, and the error of the context is returned rather than the error of parsing the partly read file.
+func (soLoader *HostSymbolsLoader) parseSOSymbols(ctx context.Context, soInfo ObjInfo, path string) (*dynamicSymbols, error) {
+	start := soLoader.timeSource().Now()
+	syms, err := soLoader.loadingFunc(ctx, path)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if len(soLoader.config.SymbolSources) > 0 {
+		syms, err = soLoader.consultSymbolSources(path, syms, err)
+	}
+	if err != nil {
+		syms, err = soLoader.fetchSOSymbols(err)
+		if err != nil {
+			return nil, err
+		}
+	}
+	syms.extractionDuration = soLoader.timeSource().Now().Sub(start)
+	soLoader.stats.ExtractionLatency.Observe(syms.extractionDuration)
+	syms.loadedFrom = soInfo
+	if syms.interpreterUndecided {
+		syms.Interpreter = isInterpreterName(filepath.Base(soInfo.Path))
+	}
+	if soLoader.config.ValidateChecksum {
+		syms.checksum = syms.calcChecksum()
+	}
+	return syms, nil
+}
+
+// openRegularFile opens the file in the given path for reading, and fails with ErrNotRegularFile if it is not a regular
+// file. The file is checked by its opened descriptor, so it is the file which is read, and it is opened without
+// blocking, as opening a FIFO with no writer would block.
+func openRegularFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.Mode().IsRegular() {
+		return file, nil
+	}
+	file.Close()
+	kind := "special file"
+	switch mode := info.Mode(); {
+	case mode.IsDir():
+		kind = "directory"
+	case mode&fs.ModeNamedPipe != 0:
+		kind = "FIFO"
+	case mode&fs.ModeSocket != 0:
+		kind = "socket"
+	case mode&fs.ModeDevice != 0:
+		kind = "device node"
+	}
+	return nil, fmt.Errorf("%w: '%s' is a %s", ErrNotRegularFile, path, kind)
+}
+
 type soDynamicSymbolsCache interface {
 	Get(ObjID) (*dynamicSymbols, bool)
 	Add(obj ObjInfo, dynamicSymbols *dynamicSymbols)
+	Purge()
 }
 
 // dynamicSymbolsLRUCache is a lru for examined shared objects symbols, in order to reduce file access.
+// The cache is safe for concurrent use.
 type dynamicSymbolsLRUCache struct {
-	lru *simplelru.LRU
+	lru   *simplelru.LRU
+	mutex sync.Mutex
 }
 
 // Get SO instance from the lru.
 // Return the SO symbols from lru and if the SO symbols were in the lru.
 func (soCache *dynamicSymbolsLRUCache) Get(objID ObjID) (*dynamicSymbols, bool) {
+	soCache.mutex.Lock()
+	defer soCache.mutex.Unlock()
 	objInfoIface, ok := soCache.lru.Get(objID)
 	if ok {
 		objInfo := objInfoIface.(*dynamicSymbols)
@@ -93,36 +618,291 @@ func (soCache *dynamicSymbolsLRUCache) Get(objID ObjID) (*dynamicSymbols, bool)
 }
 
 func (soCache *dynamicSymbolsLRUCache) Add(obj ObjInfo, dynamicSymbols *dynamicSymbols) {
+	soCache.mutex.Lock()
+	defer soCache.mutex.Unlock()
 	soCache.lru.Add(obj.Id, dynamicSymbols)
 }
 
+func (soCache *dynamicSymbolsLRUCache) Purge() {
+	soCache.mutex.Lock()
+	defer soCache.mutex.Unlock()
+	soCache.lru.Purge()
+}
+
+// findDeletedObjectMapping finds the path to the still mapped content of a deleted SO, using the
+// /proc/<pid>/map_files directory of the process which loaded it.
+// Notice - accessing the map_files directory requires the CAP_SYS_ADMIN capability in older kernels.
+func findDeletedObjectMapping(fsys fs.FS, soInfo ObjInfo) (string, error) {
+	pidDir := fmt.Sprintf("proc/%d", soInfo.Pid)
+	maps, err := fsys.Open(pidDir + "/maps")
+	if err != nil {
+		return "", err
+	}
+	defer maps.Close()
+	scanner := bufio.NewScanner(maps)
+	for scanner.Scan() {
+		// Line format: <start>-<end> <perms> <offset> <dev> <inode> <path>
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		// Inodes numbers are unique within a filesystem only, so the device is compared too
+		inode, err := strconv.ParseUint(fields[4], 10, 64)
+		if err != nil || inode != soInfo.Id.Inode {
+			continue
+		}
+		if device, ok := parseMapsDevice(fields[3]); !ok || device != soInfo.Id.Device {
+			continue
+		}
+		if !strings.HasSuffix(scanner.Text(), DeletedSuffix) {
+			continue
+		}
+		return fmt.Sprintf("/%s/map_files/%s", pidDir, fields[0]), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no mapping of deleted SO %s found for process %d", soInfo.Path, soInfo.Pid)
+}
+
+// parseMapsDevice parses the device of a mapping in the maps file ("<major>:<minor>" in hex), in the encoding of the
+// kernel which the device of the loading events has (the major in the bits above the 20 bits of the minor)
+func parseMapsDevice(field string) (uint32, bool) {
+	parts := strings.SplitN(field, ":", 2)
+	if len(parts) != 2 {
+		return 0, false
+	}
+	major, err := strconv.ParseUint(parts[0], 16, 12)
+	if err != nil {
+		return 0, false
+	}
+	minor, err := strconv.ParseUint(parts[1], 16, 20)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(major<<20 | minor), true
+}
+
 // loadSharedObjectDynamicSymbols load all dynamic symbols of a shared object file in given path.
-func loadSharedObjectDynamicSymbols(path string) (*dynamicSymbols, error) {
-	loadedObject, err := elf.Open(path)
+// The file is read until the context is done.
+func loadSharedObjectDynamicSymbols(ctx context.Context, path string) (*dynamicSymbols, error) {
+	file, err := openRegularFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readDynamicSymbols(readerWithContext(ctx, file))
+}
+
+// loadSharedObjectDynamicSymbolsWith returns a loading function which loads the dynamic symbols of a shared object
+// file in given path with the given reading options.
+func loadSharedObjectDynamicSymbolsWith(opts readOptions) func(ctx context.Context, path string) (*dynamicSymbols, error) {
+	return func(ctx context.Context, path string) (*dynamicSymbols, error) {
+		file, err := openRegularFile(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return readFirstDynamicSymbols(readerWithContext(ctx, file), opts)
+	}
+}
+
+// contextReader is a reader which fails with the error of its context once the context is done, so the parsing of
+// a file stops at its next read rather than reading the rest of the file
+type contextReader struct {
+	ctx    context.Context
+	reader io.ReaderAt
+}
+
+func (reader contextReader) ReadAt(p []byte, off int64) (int, error) {
+	if err := reader.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return reader.reader.ReadAt(p, off)
+}
+
+// readerWithContext returns a reader which stops reading once the context is done. Contexts which are never done
+// (e.g. context.Background) don't wrap the reader.
+func readerWithContext(ctx context.Context, reader io.ReaderAt) io.ReaderAt {
+	if ctx.Done() == nil {
+		return reader
+	}
+	return contextReader{ctx: ctx, reader: reader}
+}
+
+// GetExportedSymbolsFromBytes parses the exported dynamic symbols of the ELF in the given buffer.
+// It is meant for SOs which are already in memory (e.g. fetched from an image registry), so they don't have to
+// be written to the disk to be examined. The result is not cached.
+func GetExportedSymbolsFromBytes(content []byte) (map[string]bool, error) {
+	syms, err := readDynamicSymbols(bytes.NewReader(content))
 	if err != nil {
 		return nil, err
 	}
-	defer loadedObject.Close()
+	return syms.Exported, nil
+}
 
-	dynamicSymbols, err := loadedObject.DynamicSymbols()
+// GetImportedSymbolsFromBytes parses the imported dynamic symbols of the ELF in the given buffer.
+// The result is not cached.
+func GetImportedSymbolsFromBytes(content []byte) (map[string]bool, error) {
+	syms, err := readDynamicSymbols(bytes.NewReader(content))
 	if err != nil {
 		return nil, err
 	}
-	return parseDynamicSymbols(dynamicSymbols), nil
+	return syms.Imported, nil
+}
+
+// readDynamicSymbols parses the dynamic symbols of the given ELF content
+func readDynamicSymbols(reader io.ReaderAt) (*dynamicSymbols, error) {
+	return readFirstDynamicSymbols(reader, readOptions{})
+}
+
+// readOptions are the options of reading the dynamic symbols of an ELF
+type readOptions struct {
+	maxSymbols  int  // Read only the first symbols of the symbols table if it has more, unless it is 0
+	entropy     bool // Measure the entropy of the code sections
+	relocations bool // Count the dynamic relocations by their type
+}
+
+// readFirstDynamicSymbols parses the dynamic symbols of the ELF with the given options
+func readFirstDynamicSymbols(reader io.ReaderAt, opts readOptions) (*dynamicSymbols, error) {
+	loadedObject, err := elf.NewFile(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	packer := detectPacker(reader, loadedObject)
+	dynamicSymbols, truncated, err := readSymbolsTable(loadedObject, opts.maxSymbols)
+	if err != nil {
+		// Packed SOs have no symbols table until unpacked, which is not an error of reading them
+		if packer != "" && errors.Is(err, elf.ErrNoSymbols) {
+			objSymbols := NewSOSymbols()
+			objSymbols.Packer = packer
+			objSymbols.Notes, objSymbols.BuildID = readNotes(loadedObject)
+			objSymbols.WXSegments = findWritableCodeSegments(loadedObject)
+			objSymbols.DynamicTags, objSymbols.Constructors = readDynamicTags(loadedObject)
+			objSymbols.Interp = readInterp(loadedObject)
+			if opts.entropy {
+				objSymbols.CodeEntropy = measureCodeEntropy(loadedObject)
+			}
+			if opts.relocations {
+				objSymbols.Relocations = countRelocations(loadedObject)
+			}
+			return &objSymbols, nil
+		}
+		// The metadata of stripped SOs is kept, so their symbols can be fetched from a symbol server by their build
+		// ID while their metadata is still the one of the local file
+		if errors.Is(err, elf.ErrNoSymbols) {
+			objSymbols := NewSOSymbols()
+			readObjectMetadata(&objSymbols, loadedObject, opts)
+			return nil, &noSymbolsError{local: &objSymbols}
+		}
+		return nil, err
+	}
+	objSymbols := parseDynamicSymbols(dynamicSymbols)
+	objSymbols.Packer = packer
+	objSymbols.Truncated = truncated
+	readObjectMetadata(objSymbols, loadedObject, opts)
+	setSymbolsSections(objSymbols, loadedObject.Sections)
+	setPLTSlots(objSymbols, loadedObject, dynamicSymbols)
+	return objSymbols, nil
+}
+
+// readObjectMetadata reads the metadata of the ELF (everything but its symbols and packer) with the given options
+func readObjectMetadata(objSymbols *dynamicSymbols, loadedObject *elf.File, opts readOptions) {
+	interpreter, decided := detectInterpreter(loadedObject)
+	objSymbols.Interpreter = interpreter
+	objSymbols.interpreterUndecided = !decided
+	objSymbols.Soname = readSoname(loadedObject)
+	objSymbols.Notes, objSymbols.BuildID = readNotes(loadedObject)
+	objSymbols.WXSegments = findWritableCodeSegments(loadedObject)
+	objSymbols.DynamicTags, objSymbols.Constructors = readDynamicTags(loadedObject)
+	objSymbols.Interp = readInterp(loadedObject)
+	if opts.entropy {
+		objSymbols.CodeEntropy = measureCodeEntropy(loadedObject)
+	}
+	if opts.relocations {
+		objSymbols.Relocations = countRelocations(loadedObject)
+	}
 }
 
 func parseDynamicSymbols(dynamicSymbols []elf.Symbol) *dynamicSymbols {
+	return parseIndexedDynamicSymbols(dynamicSymbols, nil)
+}
+
+// parseIndexedDynamicSymbols parses the dynamic symbols, given the index of each of them in the dynamic symbols
+// table. If the indexes are nil, the symbols are the consecutive entries of the table after its null symbol.
+func parseIndexedDynamicSymbols(dynamicSymbols []elf.Symbol, indexes []int) *dynamicSymbols {
 	objSymbols := NewSOSymbols()
-	for _, sym := range dynamicSymbols {
-		if sym.Library != "" || sym.Value == 0 {
+	for i, sym := range dynamicSymbols {
+		index := i + 1
+		if indexes != nil {
+			index = indexes[i]
+		}
+		if isImportedSymbol(sym) {
 			objSymbols.Imported[sym.Name] = true
+			objSymbols.ImportedInfo[sym.Name] = ImportedSymbolInfo{Name: sym.Name, Library: sym.Library, Version: sym.Version}
 		} else {
 			objSymbols.Exported[sym.Name] = true
+			info := SymbolInfo{
+				Name:       sym.Name,
+				Bind:       elf.ST_BIND(sym.Info),
+				Type:       elf.ST_TYPE(sym.Info),
+				Visibility: elf.ST_VISIBILITY(sym.Other),
+				Section:    sym.Section,
+				Index:      index,
+				Size:       sym.Size,
+			}
+			// Symbols defined in several versions have an entry for each version
+			info.Versions = objSymbols.ExportedInfo[sym.Name].Versions
+			if sym.Version != "" {
+				info.Versions = append(info.Versions, sym.Version)
+			}
+			objSymbols.ExportedInfo[sym.Name] = info
+			// The value of indirect functions (STT_GNU_IFUNC) is their resolver, so only plain functions are ranged
+			if elf.ST_TYPE(sym.Info) == elf.STT_FUNC {
+				objSymbols.FuncRanges = append(objSymbols.FuncRanges,
+					FuncRange{Name: sym.Name, Addr: sym.Value, Size: sym.Size})
+			}
 		}
 	}
+	sort.Slice(objSymbols.FuncRanges, func(i, j int) bool {
+		first, second := objSymbols.FuncRanges[i], objSymbols.FuncRanges[j]
+		if first.Addr != second.Addr {
+			return first.Addr < second.Addr
+		}
+		return first.Name < second.Name
+	})
 	return &objSymbols
 }
 
+// isImportedSymbol checks if the dynamic symbol is imported by the SO, rather than defined by it. Undefined symbols
+// (SHN_UNDEF) are imported even if they have a value, as executables which take the address of a function they don't
+// define have the address of its PLT entry as the value of the symbol. The value of the thread-local symbols
+// (STT_TLS) is their offset in the TLS block of the SO, so the first of them may have a value of 0 while being
+// defined.
+func isImportedSymbol(sym elf.Symbol) bool {
+	if sym.Library != "" || sym.Section == elf.SHN_UNDEF {
+		return true
+	}
+	if elf.ST_TYPE(sym.Info) == elf.STT_TLS {
+		return false
+	}
+	return sym.Value == 0
+}
+
+// setSymbolsSections sets the section name of the exported symbols, according to their section index
+func setSymbolsSections(objSymbols *dynamicSymbols, sections []*elf.Section) {
+	for name, info := range objSymbols.ExportedInfo {
+		if int(info.Section) >= len(sections) || info.Section >= elf.SHN_LORESERVE {
+			continue
+		}
+		section := sections[info.Section]
+		info.SectionName = section.Name
+		info.ExecutableSection = section.Flags&elf.SHF_EXECINSTR != 0
+		objSymbols.ExportedInfo[name] = info
+	}
+}
+
 func copyMap(source map[string]bool) map[string]bool {
 	copiedMap := make(map[string]bool, len(source))
 	for k, v := range source {
@@ -0,0 +1,63 @@
+//go:build go1.18
+// +build go1.18
+
+package sharedobjs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// maxParsingDuration is the maximal time parsing a single input may take
+const maxParsingDuration = time.Second
+
+// FuzzExtractSymbols checks that parsing arbitrary content as an SO never panics, and returns in a reasonable time.
+// The SO fixtures in the testdata directory are used as the seed corpus.
+func FuzzExtractSymbols(f *testing.F) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "*.so"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, fixture := range fixtures {
+		content, err := os.ReadFile(fixture)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(content)
+	}
+	f.Add([]byte{})
+	f.Add([]byte("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x03\x00\x3e\x00\x01\x00\x00\x00"))
+
+	f.Fuzz(func(t *testing.T, content []byte) {
+		start := time.Now()
+		syms, err := readDynamicSymbols(bytes.NewReader(content))
+		if duration := time.Since(start); duration > maxParsingDuration {
+			t.Errorf("parsing took %v", duration)
+		}
+		if err == nil && syms == nil {
+			t.Error("no symbols and no error returned")
+		}
+	})
+}
+
+// FuzzDemangle checks that demangling arbitrary symbols never panics, and returns in a reasonable time
+func FuzzDemangle(f *testing.F) {
+	for _, symbol := range []string{
+		"_ZNK3Foo3getEv", "_Z3maxIiET_S0_S0_", "_ZSt4endlIcSt11char_traitsIcEERSt13basic_ostreamIT_T0_ES6_",
+		"_Z3fooIJicEEvDpPT_", "_ZZ4mainENKUliE_clEi", "_ZThn8_N3Foo3barEv", "_Z3foov.constprop.0",
+	} {
+		f.Add(symbol)
+	}
+
+	f.Fuzz(func(t *testing.T, symbol string) {
+		start := time.Now()
+		_, _ = DemangleSignature(symbol)
+		_, _ = DemangleName(symbol)
+		if duration := time.Since(start); duration > maxParsingDuration {
+			t.Errorf("demangling took %v", duration)
+		}
+	})
+}
@@ -1,12 +1,27 @@
 package sharedobjs
 
 import (
+	"bytes"
+	"context"
 	"debug/elf"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"github.com/aquasecurity/tracee/types/trace"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 type soCacheMock struct {
@@ -27,6 +42,8 @@ func (s soCacheMock) Add(obj ObjInfo, dynamicSymbols *dynamicSymbols) {
 	}
 }
 
+func (s soCacheMock) Purge() {}
+
 var testLoadedObjectInfo = ObjInfo{
 	Id: ObjID{
 		Inode:  10,
@@ -44,7 +61,7 @@ var testDynamicSymbols = &dynamicSymbols{
 
 func TestHostSharedObjectSymbolsLoader_GetDynamicSymbols(t *testing.T) {
 	t.Run("Happy flow", func(t *testing.T) {
-		failLoadingFunc := func(path string) (*dynamicSymbols, error) {
+		failLoadingFunc := func(ctx context.Context, path string) (*dynamicSymbols, error) {
 			return nil, errors.New("no SO")
 		}
 		cache := soCacheMock{
@@ -62,7 +79,7 @@ func TestHostSharedObjectSymbolsLoader_GetDynamicSymbols(t *testing.T) {
 	})
 
 	t.Run("Sad flow", func(t *testing.T) {
-		failLoadingFunc := func(path string) (*dynamicSymbols, error) {
+		failLoadingFunc := func(ctx context.Context, path string) (*dynamicSymbols, error) {
 			return nil, errors.New("no SO")
 		}
 		cache := soCacheMock{}
@@ -78,7 +95,7 @@ func TestHostSharedObjectSymbolsLoader_GetDynamicSymbols(t *testing.T) {
 
 func TestHostSharedObjectSymbolsLoader_GetExportedSymbols(t *testing.T) {
 	t.Run("Happy flow", func(t *testing.T) {
-		failLoadingFunc := func(path string) (*dynamicSymbols, error) {
+		failLoadingFunc := func(ctx context.Context, path string) (*dynamicSymbols, error) {
 			return nil, errors.New("no SO")
 		}
 		cache := soCacheMock{
@@ -96,7 +113,7 @@ func TestHostSharedObjectSymbolsLoader_GetExportedSymbols(t *testing.T) {
 	})
 
 	t.Run("Sad flow", func(t *testing.T) {
-		failLoadingFunc := func(path string) (*dynamicSymbols, error) {
+		failLoadingFunc := func(ctx context.Context, path string) (*dynamicSymbols, error) {
 			return nil, errors.New("no SO")
 		}
 		cache := soCacheMock{}
@@ -112,7 +129,7 @@ func TestHostSharedObjectSymbolsLoader_GetExportedSymbols(t *testing.T) {
 
 func TestHostSharedObjectSymbolsLoader_GetImportedSymbols(t *testing.T) {
 	t.Run("Happy flow", func(t *testing.T) {
-		failLoadingFunc := func(path string) (*dynamicSymbols, error) {
+		failLoadingFunc := func(ctx context.Context, path string) (*dynamicSymbols, error) {
 			return nil, errors.New("no SO")
 		}
 		cache := soCacheMock{
@@ -130,7 +147,7 @@ func TestHostSharedObjectSymbolsLoader_GetImportedSymbols(t *testing.T) {
 	})
 
 	t.Run("Sad flow", func(t *testing.T) {
-		failLoadingFunc := func(path string) (*dynamicSymbols, error) {
+		failLoadingFunc := func(ctx context.Context, path string) (*dynamicSymbols, error) {
 			return nil, errors.New("no SO")
 		}
 		cache := soCacheMock{}
@@ -144,10 +161,65 @@ func TestHostSharedObjectSymbolsLoader_GetImportedSymbols(t *testing.T) {
 	})
 }
 
+func TestHostSharedObjectSymbolsLoader_IsSymbolExported(t *testing.T) {
+	t.Run("Cache miss", func(t *testing.T) {
+		soLoader := InitHostSymbolsLoader(10)
+		loads := 0
+		soLoader.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+			loads++
+			return &dynamicSymbols{
+				Exported: map[string]bool{"open": true, "close": true},
+				Imported: map[string]bool{"syscall": true},
+			}, nil
+		}
+		exported, err := soLoader.IsSymbolExported(testLoadedObjectInfo, "open")
+		require.NoError(t, err)
+		assert.True(t, exported)
+		exported, err = soLoader.IsSymbolExported(testLoadedObjectInfo, "syscall")
+		require.NoError(t, err)
+		assert.False(t, exported)
+		syms, err := soLoader.GetExportedSymbols(testLoadedObjectInfo)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]bool{"open": true, "close": true}, syms)
+		assert.Equal(t, 1, loads)
+	})
+
+	t.Run("Cache hit", func(t *testing.T) {
+		soLoader := HostSymbolsLoader{
+			loadingFunc: func(ctx context.Context, path string) (*dynamicSymbols, error) {
+				return nil, errors.New("no SO")
+			},
+			soCache: soCacheMock{
+				get: func(identification ObjID) (*dynamicSymbols, bool) {
+					return testDynamicSymbols, true
+				},
+			},
+		}
+		exported, err := soLoader.IsSymbolExported(testLoadedObjectInfo, "close")
+		require.NoError(t, err)
+		assert.True(t, exported)
+		exported, err = soLoader.IsSymbolExported(testLoadedObjectInfo, "write")
+		require.NoError(t, err)
+		assert.False(t, exported)
+	})
+
+	t.Run("Non existing SO", func(t *testing.T) {
+		soLoader := HostSymbolsLoader{
+			loadingFunc: func(ctx context.Context, path string) (*dynamicSymbols, error) {
+				return nil, errors.New("no SO")
+			},
+			soCache: soCacheMock{},
+		}
+		exported, err := soLoader.IsSymbolExported(testLoadedObjectInfo, "open")
+		assert.Error(t, err)
+		assert.False(t, exported)
+	})
+}
+
 func TestHostSharedObjectSymbolsLoader_loadSOSymbols(t *testing.T) {
 
 	t.Run("Cached SO", func(t *testing.T) {
-		failLoadingFunc := func(path string) (*dynamicSymbols, error) {
+		failLoadingFunc := func(ctx context.Context, path string) (*dynamicSymbols, error) {
 			return nil, errors.New("no SO")
 		}
 		cache := soCacheMock{
@@ -165,7 +237,7 @@ func TestHostSharedObjectSymbolsLoader_loadSOSymbols(t *testing.T) {
 	})
 
 	t.Run("Uncached non existing SO", func(t *testing.T) {
-		failLoadingFunc := func(path string) (*dynamicSymbols, error) {
+		failLoadingFunc := func(ctx context.Context, path string) (*dynamicSymbols, error) {
 			return nil, errors.New("no SO")
 		}
 		cachedSymbols := make(map[ObjInfo]*dynamicSymbols)
@@ -192,7 +264,7 @@ func TestHostSharedObjectSymbolsLoader_loadSOSymbols(t *testing.T) {
 			},
 		}
 		soLoader := HostSymbolsLoader{
-			loadingFunc: func(path string) (*dynamicSymbols, error) {
+			loadingFunc: func(ctx context.Context, path string) (*dynamicSymbols, error) {
 				return testDynamicSymbols, nil
 			},
 			soCache: cache,
@@ -247,6 +319,16 @@ func TestLoadSharedObjectDynamicSymbols(t *testing.T) {
 				Imported: make(map[string]bool),
 			},
 		},
+		{
+			Name:  "Undefined symbol with value",
+			Input: []elf.Symbol{{Name: "exported_function", Info: 18, Section: elf.SHN_UNDEF, Value: 0x401030}},
+			ExpecteResult: dynamicSymbols{
+				Exported: make(map[string]bool),
+				Imported: map[string]bool{
+					"exported_function": true,
+				},
+			},
+		},
 		{
 			Name: "Mixed symbols",
 			Input: []elf.Symbol{
@@ -274,3 +356,1954 @@ func TestLoadSharedObjectDynamicSymbols(t *testing.T) {
 		})
 	}
 }
+
+func TestHostSharedObjectSymbolsLoader_GetExportedSymbolsInfo(t *testing.T) {
+	t.Run("Happy flow", func(t *testing.T) {
+		soLoader := HostSymbolsLoader{
+			loadingFunc: func(ctx context.Context, path string) (*dynamicSymbols, error) {
+				return parseDynamicSymbols([]elf.Symbol{
+					{Name: "open", Info: 18, Other: byte(elf.STV_DEFAULT), Section: elf.SHN_UNDEF + 12, Value: 55424},
+					{Name: "close", Info: 34, Other: byte(elf.STV_HIDDEN), Section: elf.SHN_UNDEF + 12, Value: 55500},
+					{Name: "syscall", Info: 18, Section: elf.SHN_UNDEF, Library: "libc.so.6"},
+				}), nil
+			},
+			soCache: soCacheMock{},
+		}
+		symsInfo, err := soLoader.GetExportedSymbolsInfo(testLoadedObjectInfo)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]SymbolInfo{
+			"open":  {Name: "open", Bind: elf.STB_GLOBAL, Type: elf.STT_FUNC, Visibility: elf.STV_DEFAULT, Section: elf.SHN_UNDEF + 12, Index: 1},
+			"close": {Name: "close", Bind: elf.STB_WEAK, Type: elf.STT_FUNC, Visibility: elf.STV_HIDDEN, Section: elf.SHN_UNDEF + 12, Index: 2},
+		}, symsInfo)
+	})
+
+	t.Run("Sad flow", func(t *testing.T) {
+		soLoader := HostSymbolsLoader{
+			loadingFunc: func(ctx context.Context, path string) (*dynamicSymbols, error) {
+				return nil, errors.New("no SO")
+			},
+			soCache: soCacheMock{},
+		}
+		symsInfo, err := soLoader.GetExportedSymbolsInfo(testLoadedObjectInfo)
+		assert.Error(t, err)
+		assert.Nil(t, symsInfo)
+	})
+}
+
+func TestHostSharedObjectSymbolsLoader_DeletedSO(t *testing.T) {
+	deletedObjectInfo := testLoadedObjectInfo
+	deletedObjectInfo.Pid = 20
+	deletedObjectInfo.Deleted = true
+
+	testCases := []struct {
+		Name         string
+		Maps         string
+		ExpectedPath string
+		ExpectError  bool
+	}{
+		{
+			Name: "Mapped deleted SO",
+			Maps: "7f4e1c000000-7f4e1c021000 r--p 00000000 00:0a 11 /tmp/other.so\n" +
+				"7f4e1c021000-7f4e1c042000 r-xp 00000000 00:0a 10 /tmp/test.so (deleted)\n",
+			ExpectedPath: "/proc/20/map_files/7f4e1c021000-7f4e1c042000",
+		},
+		{
+			// The same inode number on another filesystem is another file
+			Name: "Deleted SO with the same inode on another device",
+			Maps: "7f4e1c000000-7f4e1c021000 r-xp 00000000 08:01 10 /mnt/other.so (deleted)\n" +
+				"7f4e1c021000-7f4e1c042000 r-xp 00000000 00:0a 10 /tmp/test.so (deleted)\n",
+			ExpectedPath: "/proc/20/map_files/7f4e1c021000-7f4e1c042000",
+		},
+		{
+			Name:        "Only the same inode on another device mapped",
+			Maps:        "7f4e1c000000-7f4e1c021000 r-xp 00000000 08:01 10 /mnt/other.so (deleted)\n",
+			ExpectError: true,
+		},
+		{
+			Name:        "SO mapped but not deleted",
+			Maps:        "7f4e1c021000-7f4e1c042000 r-xp 00000000 00:0a 10 /tmp/test.so\n",
+			ExpectError: true,
+		},
+		{
+			Name:        "SO not mapped",
+			Maps:        "7f4e1c000000-7f4e1c021000 r--p 00000000 00:0a 11 /tmp/other.so (deleted)\n",
+			ExpectError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			var loadedPath string
+			soLoader := HostSymbolsLoader{
+				loadingFunc: func(ctx context.Context, path string) (*dynamicSymbols, error) {
+					loadedPath = path
+					return testDynamicSymbols, nil
+				},
+				soCache: soCacheMock{},
+				fs: fstest.MapFS{
+					"proc/20/maps": &fstest.MapFile{Data: []byte(testCase.Maps)},
+				},
+			}
+			syms, err := soLoader.loadSOSymbols(deletedObjectInfo)
+			if testCase.ExpectError {
+				assert.Error(t, err)
+				assert.Nil(t, syms)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testDynamicSymbols, syms)
+			assert.Equal(t, testCase.ExpectedPath, loadedPath)
+		})
+	}
+}
+
+func TestHostSharedObjectSymbolsLoader_ValidateChecksum(t *testing.T) {
+	otherPathObjectInfo := testLoadedObjectInfo
+	otherPathObjectInfo.Path = "/tmp/other/test.so"
+	otherPathObjectInfo.MountNS = 2
+
+	testCases := []struct {
+		Name               string
+		Validate           bool
+		SecondObject       ObjInfo
+		SecondSymbols      map[string]bool
+		ExpectedSymbols    map[string]bool
+		ExpectedMismatches int32
+		ExpectedLoads      int
+	}{
+		{
+			Name:               "Same symbols in other path",
+			Validate:           true,
+			SecondObject:       otherPathObjectInfo,
+			SecondSymbols:      map[string]bool{"close": true, "open": true},
+			ExpectedSymbols:    map[string]bool{"open": true, "close": true},
+			ExpectedMismatches: 0,
+			ExpectedLoads:      2,
+		},
+		{
+			Name:               "Different symbols in other path",
+			Validate:           true,
+			SecondObject:       otherPathObjectInfo,
+			SecondSymbols:      map[string]bool{"read": true},
+			ExpectedSymbols:    map[string]bool{"read": true},
+			ExpectedMismatches: 1,
+			ExpectedLoads:      2,
+		},
+		{
+			Name:               "Same path is not validated",
+			Validate:           true,
+			SecondObject:       testLoadedObjectInfo,
+			SecondSymbols:      map[string]bool{"read": true},
+			ExpectedSymbols:    map[string]bool{"open": true, "close": true},
+			ExpectedMismatches: 0,
+			ExpectedLoads:      1,
+		},
+		{
+			Name:               "Validation disabled",
+			Validate:           false,
+			SecondObject:       otherPathObjectInfo,
+			SecondSymbols:      map[string]bool{"read": true},
+			ExpectedSymbols:    map[string]bool{"open": true, "close": true},
+			ExpectedMismatches: 0,
+			ExpectedLoads:      1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			soLoader := InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{
+				CacheSize:        10,
+				ValidateChecksum: testCase.Validate,
+			})
+			loads := 0
+			soLoader.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+				loads++
+				syms := NewSOSymbols()
+				if path == testLoadedObjectInfo.Path {
+					syms.Exported = map[string]bool{"open": true, "close": true}
+				} else {
+					syms.Exported = testCase.SecondSymbols
+				}
+				return &syms, nil
+			}
+			_, err := soLoader.GetExportedSymbols(testLoadedObjectInfo)
+			require.NoError(t, err)
+			syms, err := soLoader.GetExportedSymbols(testCase.SecondObject)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.ExpectedSymbols, syms)
+			assert.Equal(t, testCase.ExpectedLoads, loads)
+			assert.Equal(t, testCase.ExpectedMismatches, soLoader.Stats().ChecksumMismatches.Read())
+		})
+	}
+}
+
+func TestHostSharedObjectSymbolsLoader_SharedAcrossMountNS(t *testing.T) {
+	otherNSObjectInfo := testLoadedObjectInfo
+	otherNSObjectInfo.MountNS = 2
+
+	var resolvedNS []int
+	var loadedPaths []string
+	soLoader := InitHostSymbolsLoader(10)
+	soLoader.resolvePath = func(soInfo ObjInfo) (ObjInfo, error) {
+		resolvedNS = append(resolvedNS, soInfo.MountNS)
+		soInfo.Path = fmt.Sprintf("/proc/%d/root%s", soInfo.MountNS, soInfo.Path)
+		return soInfo, nil
+	}
+	soLoader.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+		loadedPaths = append(loadedPaths, path)
+		syms := NewSOSymbols()
+		syms.Exported = map[string]bool{"open": true}
+		return &syms, nil
+	}
+
+	syms, err := soLoader.GetExportedSymbols(otherNSObjectInfo)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"open": true}, syms)
+	syms, err = soLoader.GetExportedSymbols(testLoadedObjectInfo)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"open": true}, syms)
+
+	assert.Equal(t, []int{2}, resolvedNS)
+	assert.Equal(t, []string{"/proc/2/root/tmp/test.so"}, loadedPaths)
+}
+
+func TestHostSharedObjectSymbolsLoader_SymbolVersionsPerMountNS(t *testing.T) {
+	// The host and the container have different builds of the library in the same path
+	roots := map[int]string{1: "testdata/exportver_host.so", 2: "testdata/exportver_container.so"}
+	soLoader := InitHostSymbolsLoader(10)
+	soLoader.resolvePath = func(soInfo ObjInfo) (ObjInfo, error) {
+		soInfo.Path = roots[soInfo.MountNS]
+		return soInfo, nil
+	}
+	hostInfo := ObjInfo{Id: ObjID{Inode: 1, Device: 1}, Path: "/usr/lib/libexportver.so.1", MountNS: 1}
+	containerInfo := ObjInfo{Id: ObjID{Inode: 1, Device: 2}, Path: "/usr/lib/libexportver.so.1", MountNS: 2}
+
+	hostSyms, err := soLoader.GetExportedSymbolsInfo(hostInfo)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"LIBEXPORTVER_2.0", "LIBEXPORTVER_1.0"}, hostSyms["copy_bytes"].Versions)
+	assert.Equal(t, []string{"LIBEXPORTVER_1.0"}, hostSyms["fill_bytes"].Versions)
+	// The versions are of the build in the mount namespace of the container, not of the host build
+	containerSyms, err := soLoader.GetExportedSymbolsInfo(containerInfo)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"LIBEXPORTVER_1.0"}, containerSyms["copy_bytes"].Versions)
+
+	// Unversioned symbols have no versions
+	syms, err := InitHostSymbolsLoader(10).GetExportedSymbolsInfo(ObjInfo{Id: ObjID{Inode: 3}, Path: "testdata/symbols.so"})
+	require.NoError(t, err)
+	assert.Empty(t, syms["exported_function"].Versions)
+}
+
+func TestSetSymbolsSections(t *testing.T) {
+	syms := parseDynamicSymbols([]elf.Symbol{
+		{Name: "open", Info: 18, Section: 1, Value: 55424},
+		{Name: "environ", Info: 17, Section: 2, Value: 55500},
+		{Name: "abs", Info: 17, Section: elf.SHN_ABS, Value: 10},
+	})
+	setSymbolsSections(syms, []*elf.Section{
+		{SectionHeader: elf.SectionHeader{Name: ""}},
+		{SectionHeader: elf.SectionHeader{Name: ".text", Flags: elf.SHF_ALLOC | elf.SHF_EXECINSTR}},
+		{SectionHeader: elf.SectionHeader{Name: ".data", Flags: elf.SHF_ALLOC | elf.SHF_WRITE}},
+	})
+	assert.Equal(t, ".text", syms.ExportedInfo["open"].SectionName)
+	assert.True(t, syms.ExportedInfo["open"].ExecutableSection)
+	assert.Equal(t, ".data", syms.ExportedInfo["environ"].SectionName)
+	assert.False(t, syms.ExportedInfo["environ"].ExecutableSection)
+	assert.Equal(t, "", syms.ExportedInfo["abs"].SectionName)
+}
+
+func TestLoadSharedObjectDynamicSymbols_Fixture(t *testing.T) {
+	syms, err := loadSharedObjectDynamicSymbols(context.Background(), "testdata/symbols.so")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]bool{"exported_function": true, "exported_counter": true}, syms.Exported)
+	assert.Equal(t, ".text", syms.ExportedInfo["exported_function"].SectionName)
+	assert.True(t, syms.ExportedInfo["exported_function"].ExecutableSection)
+	assert.Equal(t, elf.STT_OBJECT, syms.ExportedInfo["exported_counter"].Type)
+	assert.False(t, syms.ExportedInfo["exported_counter"].ExecutableSection)
+
+	assert.Equal(t, ImportedSymbolInfo{Name: "getenv", Library: "libc.so.6", Version: "GLIBC_2.2.5", HasPLTSlot: true,
+		PLTIndex: 0, GOTOffset: 0x4000}, syms.ImportedInfo["getenv"])
+	assert.Equal(t, ImportedSymbolInfo{Name: "puts", Library: "libc.so.6", Version: "GLIBC_2.2.5", HasPLTSlot: true,
+		PLTIndex: 1, GOTOffset: 0x4008}, syms.ImportedInfo["puts"])
+	assert.False(t, syms.ImportedInfo["__cxa_finalize"].HasPLTSlot)
+}
+
+func TestLoadSharedObjectDynamicSymbols_TLS(t *testing.T) {
+	syms, err := loadSharedObjectDynamicSymbols(context.Background(), "testdata/tls.so")
+	require.NoError(t, err)
+
+	// The first TLS symbol is in offset 0 of the TLS block, and is still exported
+	assert.Equal(t, map[string]bool{"tls_state": true, "tls_counter": true, "plain_function": true,
+		"plain_variable": true}, syms.Exported)
+	assert.False(t, syms.Imported["tls_state"])
+	assert.True(t, syms.Imported["__tls_get_addr"])
+	assert.Equal(t, elf.STT_TLS, syms.ExportedInfo["tls_state"].Type)
+	assert.Equal(t, elf.STT_TLS, syms.ExportedInfo["tls_counter"].Type)
+	assert.Equal(t, elf.STT_OBJECT, syms.ExportedInfo["plain_variable"].Type)
+
+	// Undefined TLS symbols are imported
+	undefined := parseDynamicSymbols([]elf.Symbol{
+		{Name: "errno_tls", Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_TLS), Section: elf.SHN_UNDEF},
+	})
+	assert.True(t, undefined.Imported["errno_tls"])
+}
+
+func TestLoadSharedObjectDynamicSymbols_UndefinedWithValue(t *testing.T) {
+	syms, err := loadSharedObjectDynamicSymbols(context.Background(), "testdata/canonical")
+	require.NoError(t, err)
+
+	// The referenced function has the address of its PLT entry, but is not defined by the executable
+	assert.False(t, syms.Exported["exported_function"])
+	assert.True(t, syms.Imported["exported_function"])
+	assert.Empty(t, syms.ImportedInfo["exported_function"].Library)
+}
+
+func TestHostSharedObjectSymbolsLoader_GetFunctionRanges(t *testing.T) {
+	soLoader := InitHostSymbolsLoader(10)
+	soInfo := ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/symbols.so"}
+	ranges, err := soLoader.GetFunctionRanges(soInfo)
+	require.NoError(t, err)
+	// Data objects (exported_counter) and imported functions have no range
+	assert.Equal(t, []FuncRange{{Name: "exported_function", Addr: 0x1119, Size: 79}}, ranges)
+
+	// The ranges are copied from the cache
+	ranges[0].Name = "modified"
+	ranges, err = soLoader.GetFunctionRanges(soInfo)
+	require.NoError(t, err)
+	assert.Equal(t, "exported_function", ranges[0].Name)
+
+	syms := parseDynamicSymbols([]elf.Symbol{
+		{Name: "second", Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_FUNC), Section: elf.SHN_UNDEF + 12, Value: 0x2000, Size: 16},
+		{Name: "first_alias", Info: elf.ST_INFO(elf.STB_WEAK, elf.STT_FUNC), Section: elf.SHN_UNDEF + 12, Value: 0x1000, Size: 32},
+		{Name: "first", Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_FUNC), Section: elf.SHN_UNDEF + 12, Value: 0x1000, Size: 32},
+		{Name: "resolver", Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_LOOS), Section: elf.SHN_UNDEF + 12, Value: 0x1800}, // STT_GNU_IFUNC
+	})
+	assert.Equal(t, []FuncRange{
+		{Name: "first", Addr: 0x1000, Size: 32},
+		{Name: "first_alias", Addr: 0x1000, Size: 32},
+		{Name: "second", Addr: 0x2000, Size: 16},
+	}, syms.FuncRanges)
+}
+
+func TestRebase(t *testing.T) {
+	soLoader := InitHostSymbolsLoader(10)
+	ranges, err := soLoader.GetFunctionRanges(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/pie"})
+	require.NoError(t, err)
+	// The ranges of a PIE are relative to its start
+	assert.Equal(t, []FuncRange{
+		{Name: "_start", Addr: 0x1050, Size: 34},
+		{Name: "pie_function", Addr: 0x1139, Size: 14},
+		{Name: "main", Addr: 0x1147, Size: 43},
+	}, ranges)
+
+	const loadBias = 0x55d4c3a00000
+	rebased := Rebase(ranges, loadBias)
+	assert.Equal(t, []FuncRange{
+		{Name: "_start", Addr: 0x55d4c3a01050, Size: 34},
+		{Name: "pie_function", Addr: 0x55d4c3a01139, Size: 14},
+		{Name: "main", Addr: 0x55d4c3a01147, Size: 43},
+	}, rebased)
+	// The ranges given are not modified
+	assert.Equal(t, uint64(0x1139), ranges[1].Addr)
+
+	// A runtime IP within a function is within its rebased range
+	ip := uint64(loadBias + 0x1140)
+	function := rebased[1]
+	assert.True(t, ip >= function.Addr && ip < function.Addr+function.Size)
+
+	assert.Empty(t, Rebase(nil, loadBias))
+}
+
+func TestHostSharedObjectSymbolsLoader_GetDynamicTags(t *testing.T) {
+	soLoader := InitHostSymbolsLoader(10)
+	auditTags, err := soLoader.GetDynamicTags(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/audit.so"})
+	require.NoError(t, err)
+	assert.Contains(t, auditTags, elf.DT_NEEDED)
+	assert.Contains(t, auditTags, elf.DT_AUDIT)
+	assert.Contains(t, auditTags, elf.DT_DEPAUDIT)
+	assert.NotContains(t, auditTags, elf.DT_NULL)
+	assert.IsIncreasing(t, auditTags)
+
+	tags, err := soLoader.GetDynamicTags(ObjInfo{Id: ObjID{Inode: 2}, Path: "testdata/symbols.so"})
+	require.NoError(t, err)
+	assert.Contains(t, tags, elf.DT_NEEDED)
+	assert.NotContains(t, tags, elf.DT_AUDIT)
+
+	// The tags are read from the dynamic segment, so they are read from SOs with no sections headers too
+	content, err := os.ReadFile("testdata/audit.so")
+	require.NoError(t, err)
+	file, err := elf.NewFile(bytes.NewReader(stripSectionHeaders(content)))
+	require.NoError(t, err)
+	strippedTags, _ := readDynamicTags(file)
+	assert.Equal(t, auditTags, strippedTags)
+}
+
+func TestHostSharedObjectSymbolsLoader_GetConstructorsCount(t *testing.T) {
+	soLoader := InitHostSymbolsLoader(10)
+	// The init array holds the frame_dummy entry of GCC besides the declared constructors
+	constructors, err := soLoader.GetConstructorsCount(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/constructors.so"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, constructors)
+
+	constructors, err = soLoader.GetConstructorsCount(ObjInfo{Id: ObjID{Inode: 2}, Path: "testdata/symbols.so"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, constructors)
+}
+
+func TestHostSharedObjectSymbolsLoader_GetInterpreter(t *testing.T) {
+	soLoader := InitHostSymbolsLoader(10)
+	interp, err := soLoader.GetInterpreter(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/pie"})
+	require.NoError(t, err)
+	assert.Equal(t, "/lib64/ld-linux-x86-64.so.2", interp)
+
+	interp, err = soLoader.GetInterpreter(ObjInfo{Id: ObjID{Inode: 2}, Path: "testdata/interp"})
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/.x/ld.so", interp)
+
+	// SOs request no interpreter
+	interp, err = soLoader.GetInterpreter(ObjInfo{Id: ObjID{Inode: 3}, Path: "testdata/symbols.so"})
+	require.NoError(t, err)
+	assert.Empty(t, interp)
+
+	metadata, err := soLoader.GetMetadata(ObjInfo{Id: ObjID{Inode: 4}, Path: "testdata/interp"})
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/.x/ld.so", metadata.Interp)
+}
+
+func TestHostSharedObjectSymbolsLoader_NotRegularFile(t *testing.T) {
+	dir := t.TempDir()
+	fifo := filepath.Join(dir, "fifo.so")
+	require.NoError(t, syscall.Mkfifo(fifo, 0644))
+	testCases := []struct {
+		name          string
+		path          string
+		expectedError string
+	}{
+		{name: "Directory", path: dir, expectedError: fmt.Sprintf("'%s' is a directory", dir)},
+		// Opening a FIFO with no writer would block
+		{name: "FIFO", path: fifo, expectedError: fmt.Sprintf("'%s' is a FIFO", fifo)},
+		{name: "Device node", path: "/dev/null", expectedError: "'/dev/null' is a device node"},
+	}
+	loaders := map[string]*HostSymbolsLoader{
+		"Read":         InitHostSymbolsLoader(10),
+		"Mmap":         InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, MmapMinSize: 1}),
+		"Deduplicated": InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, ContentDedup: true}),
+		"Disk cached":  InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, DiskCacheDir: t.TempDir()}),
+	}
+	for name, soLoader := range loaders {
+		for _, testCase := range testCases {
+			t.Run(name+"/"+testCase.name, func(t *testing.T) {
+				_, err := soLoader.GetExportedSymbols(ObjInfo{Id: ObjID{Inode: 1}, Path: testCase.path})
+				require.ErrorIs(t, err, ErrNotRegularFile)
+				assert.Contains(t, err.Error(), testCase.expectedError)
+			})
+		}
+	}
+	// The metadata is read from regular files only too
+	for _, testCase := range testCases {
+		t.Run("Metadata/"+testCase.name, func(t *testing.T) {
+			_, err := InitHostSymbolsLoader(10).GetMetadata(ObjInfo{Id: ObjID{Inode: 1}, Path: testCase.path})
+			require.ErrorIs(t, err, ErrNotRegularFile)
+			assert.Contains(t, err.Error(), testCase.expectedError)
+		})
+	}
+
+	// Missing files fail as they did before
+	_, err := InitHostSymbolsLoader(10).GetExportedSymbols(ObjInfo{Id: ObjID{Inode: 1}, Path: filepath.Join(dir, "missing.so")})
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestHostSharedObjectSymbolsLoader_SymbolIndex(t *testing.T) {
+	loaders := map[string]*HostSymbolsLoader{
+		"Read": InitHostSymbolsLoader(10),
+		"Mmap": InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, MmapMinSize: 1}),
+	}
+	for name, soLoader := range loaders {
+		t.Run(name, func(t *testing.T) {
+			// The indexes are the ones readelf --dyn-syms shows
+			exportedInfo, err := soLoader.GetExportedSymbolsInfo(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/symbols.so"})
+			require.NoError(t, err)
+			assert.Equal(t, 7, exportedInfo["exported_function"].Index)
+			assert.Equal(t, 8, exportedInfo["exported_counter"].Index)
+		})
+	}
+}
+
+func TestHostSharedObjectSymbolsLoader_SymbolSize(t *testing.T) {
+	loaders := map[string]*HostSymbolsLoader{
+		"Read": InitHostSymbolsLoader(10),
+		"Mmap": InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, MmapMinSize: 1}),
+	}
+	for name, soLoader := range loaders {
+		t.Run(name, func(t *testing.T) {
+			// The sizes are the ones readelf --dyn-syms shows, the stub being a single return instruction
+			exportedInfo, err := soLoader.GetExportedSymbolsInfo(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/sizes"})
+			require.NoError(t, err)
+			assert.Equal(t, uint64(1), exportedInfo["hollow_stub"].Size)
+			assert.Equal(t, uint64(68), exportedInfo["checksum"].Size)
+			assert.Equal(t, uint64(4), exportedInfo["sized_counter"].Size)
+		})
+	}
+}
+
+func TestHostSharedObjectSymbolsLoader_MaxSymbols(t *testing.T) {
+	full, err := loadSharedObjectDynamicSymbols(context.Background(), "testdata/large.so")
+	require.NoError(t, err)
+	require.False(t, full.Truncated)
+	require.Len(t, full.Exported, 512)
+
+	loaders := map[string]*HostSymbolsLoader{
+		"Read": InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, MaxSymbols: 10}),
+		"Mmap": InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, MaxSymbols: 10, MmapMinSize: 1}),
+	}
+	for name, soLoader := range loaders {
+		t.Run(name, func(t *testing.T) {
+			info := ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/large.so"}
+			truncated, err := soLoader.IsTruncated(info)
+			require.NoError(t, err)
+			assert.True(t, truncated)
+
+			exported, err := soLoader.GetExportedSymbols(info)
+			require.NoError(t, err)
+			imported, err := soLoader.GetImportedSymbols(info)
+			require.NoError(t, err)
+			assert.Equal(t, 10, len(exported)+len(imported))
+			assert.True(t, imported["puts"])
+			// The first symbols are read like the whole table is, except for their versions
+			exportedInfo, err := soLoader.GetExportedSymbolsInfo(info)
+			require.NoError(t, err)
+			for sym := range exported {
+				assert.Equal(t, full.ExportedInfo[sym], exportedInfo[sym], sym)
+			}
+			for sym := range imported {
+				assert.True(t, full.Imported[sym], sym)
+			}
+		})
+	}
+
+	// Tables within the cap are read whole
+	soLoader := InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, MaxSymbols: 517})
+	info := ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/large.so"}
+	truncated, err := soLoader.IsTruncated(info)
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	exported, err := soLoader.GetExportedSymbols(info)
+	require.NoError(t, err)
+	assert.Equal(t, full.Exported, exported)
+}
+
+func TestHostSharedObjectSymbolsLoader_GetCodeEntropy(t *testing.T) {
+	loaders := map[string]*HostSymbolsLoader{
+		"Read": InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, MeasureEntropy: true}),
+		"Mmap": InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, MeasureEntropy: true, MmapMinSize: 1}),
+	}
+	for name, soLoader := range loaders {
+		t.Run(name, func(t *testing.T) {
+			entropy, err := soLoader.GetCodeEntropy(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/entropy.so"})
+			require.NoError(t, err)
+			assert.Greater(t, entropy, 7.5)
+
+			entropy, err = soLoader.GetCodeEntropy(ObjInfo{Id: ObjID{Inode: 2}, Path: "testdata/symbols.so"})
+			require.NoError(t, err)
+			assert.Greater(t, entropy, 0.0)
+			assert.Less(t, entropy, 6.0)
+		})
+	}
+
+	// The entropy is not measured unless it is configured
+	soLoader := InitHostSymbolsLoader(10)
+	entropy, err := soLoader.GetCodeEntropy(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/entropy.so"})
+	require.NoError(t, err)
+	assert.Zero(t, entropy)
+}
+
+func TestHostSharedObjectSymbolsLoader_GetRelocationCounts(t *testing.T) {
+	loaders := map[string]*HostSymbolsLoader{
+		"Read": InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, CountRelocations: true}),
+		"Mmap": InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, CountRelocations: true, MmapMinSize: 1}),
+	}
+	for name, soLoader := range loaders {
+		t.Run(name, func(t *testing.T) {
+			counts, err := soLoader.GetRelocationCounts(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/relocs"})
+			require.NoError(t, err)
+			assert.Equal(t, map[string]int{"R_X86_64_GLOB_DAT": 2, "R_X86_64_COPY": 1, "R_X86_64_IRELATIVE": 1}, counts)
+
+			// The counts are copied from the cache
+			counts["R_X86_64_COPY"] = 10
+			counts, err = soLoader.GetRelocationCounts(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/relocs"})
+			require.NoError(t, err)
+			assert.Equal(t, 1, counts["R_X86_64_COPY"])
+		})
+	}
+
+	// The relocations are not counted unless it is configured
+	soLoader := InitHostSymbolsLoader(10)
+	counts, err := soLoader.GetRelocationCounts(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/relocs"})
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+}
+
+func TestHostSharedObjectSymbolsLoader_DiffExportedSymbols(t *testing.T) {
+	soLoader := InitHostSymbolsLoader(10)
+	v1 := ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/diff_v1.so"}
+	v2 := ObjInfo{Id: ObjID{Inode: 2}, Path: "testdata/diff_v2.so"}
+
+	added, removed, err := soLoader.DiffExportedSymbols(v1, v2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"added_function", "added_hook"}, added)
+	assert.Equal(t, []string{"legacy_counter", "legacy_function"}, removed)
+
+	// The symbols are read from the cache, and the diff is reversed with the order of the objects
+	added, removed, err = soLoader.DiffExportedSymbols(
+		ObjInfo{Id: v2.Id, Path: "testdata/missing_v2.so"},
+		ObjInfo{Id: v1.Id, Path: "testdata/missing_v1.so"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"legacy_counter", "legacy_function"}, added)
+	assert.Equal(t, []string{"added_function", "added_hook"}, removed)
+
+	added, removed, err = soLoader.DiffExportedSymbols(v1, v1)
+	require.NoError(t, err)
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+
+	_, _, err = soLoader.DiffExportedSymbols(v1, ObjInfo{Id: ObjID{Inode: 3}, Path: "testdata/missing.so"})
+	assert.Error(t, err)
+}
+
+// symbolSourceMock is a SymbolSource supplying the same symbols for every file, or failing
+type symbolSourceMock struct {
+	name    string
+	symbols []SymbolInfo
+	err     error
+}
+
+func (source symbolSourceMock) Name() string {
+	return source.name
+}
+
+func (source symbolSourceMock) ExportedSymbols(path string) ([]SymbolInfo, error) {
+	return source.symbols, source.err
+}
+
+func TestHostSharedObjectSymbolsLoader_SymbolSources(t *testing.T) {
+	dwarfSO := ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/dwarf"}
+
+	// The dynamic symbols table is the only source by default
+	syms, err := InitHostSymbolsLoader(10).GetExportedSymbols(dwarfSO)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"dwarf_exported": true}, syms)
+
+	failing := symbolSourceMock{name: "failing", err: errors.New("source failure")}
+	extra := symbolSourceMock{name: "extra", symbols: []SymbolInfo{{Name: "hidden_helper"}, {Name: "extra_function"}}}
+	soLoader := InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{
+		CacheSize:     10,
+		SymbolSources: []SymbolSource{failing, DWARFSymbolSource{}, extra},
+	})
+	symsInfo, err := soLoader.GetExportedSymbolsInfo(dwarfSO)
+	require.NoError(t, err)
+	// Symbols already read from the table or from an earlier source are kept as they were read
+	assert.Equal(t, "", symsInfo["dwarf_exported"].Source)
+	assert.Equal(t, 5, symsInfo["dwarf_exported"].Index)
+	for name, bind := range map[string]elf.SymBind{"hidden_helper": elf.STB_GLOBAL, "local_helper": elf.STB_LOCAL} {
+		info := symsInfo[name]
+		assert.Equal(t, "dwarf", info.Source, name)
+		assert.Equal(t, bind, info.Bind, name)
+		assert.Equal(t, elf.STT_FUNC, info.Type, name)
+		assert.Equal(t, ".text", info.SectionName, name)
+		assert.True(t, info.ExecutableSection, name)
+	}
+	// The sizes of the functions are the ranges of their code in the DWARF information
+	assert.Equal(t, uint64(26), symsInfo["hidden_helper"].Size)
+	assert.Equal(t, uint64(14), symsInfo["local_helper"].Size)
+	assert.Equal(t, SymbolInfo{Name: "extra_function", Source: "extra"}, symsInfo["extra_function"])
+	assert.Equal(t, int32(1), soLoader.Stats().SymbolSourceFailures.Read())
+
+	// SOs with no dynamic symbols table get the symbols of the sources, and keep their build ID
+	content, err := os.ReadFile("testdata/symbols.so")
+	require.NoError(t, err)
+	stripped := stripSectionHeaders(content)
+	for _, source := range []SymbolSource{extra, failing} {
+		soLoader = InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{
+			CacheSize:     10,
+			SymbolSources: []SymbolSource{source},
+		})
+		soLoader.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+			return readDynamicSymbols(bytes.NewReader(stripped))
+		}
+		syms, err = soLoader.GetExportedSymbols(testLoadedObjectInfo)
+		if source.Name() == failing.name {
+			// SOs which got no symbols fall back to the local SO
+			assert.ErrorIs(t, err, elf.ErrNoSymbols)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, map[string]bool{"hidden_helper": true, "extra_function": true}, syms)
+		buildID, err := soLoader.GetBuildID(testLoadedObjectInfo)
+		require.NoError(t, err)
+		assert.Equal(t, "d94f666a6334f0baed45391074e7ce827a1853e5", buildID)
+	}
+
+	// Files with no debug information have no DWARF symbols
+	dwarfSyms, err := DWARFSymbolSource{}.ExportedSymbols("testdata/symbols.so")
+	require.NoError(t, err)
+	assert.Empty(t, dwarfSyms)
+}
+
+func TestRelocationTypeName(t *testing.T) {
+	assert.Equal(t, "R_X86_64_IRELATIVE", relocationTypeName(elf.EM_X86_64, uint32(elf.R_X86_64_IRELATIVE)))
+	assert.Equal(t, "R_AARCH64_COPY", relocationTypeName(elf.EM_AARCH64, uint32(elf.R_AARCH64_COPY)))
+	assert.Equal(t, "7", relocationTypeName(elf.EM_NONE, 7))
+}
+
+func TestShannonEntropy(t *testing.T) {
+	var counts [256]uint64
+	assert.Zero(t, shannonEntropy(counts, 0))
+	counts['a'] = 10
+	assert.Zero(t, shannonEntropy(counts, 10))
+	counts['b'] = 10
+	assert.InDelta(t, 1.0, shannonEntropy(counts, 20), 1e-9)
+	for b := range counts {
+		counts[b] = 3
+	}
+	assert.InDelta(t, 8.0, shannonEntropy(counts, 3*256), 1e-9)
+}
+
+func TestHostSharedObjectSymbolsLoader_GetMetadata(t *testing.T) {
+	soLoader := InitHostSymbolsLoader(10)
+	auditInfo := ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/audit.so"}
+	metadata, err := soLoader.GetMetadata(auditInfo)
+	require.NoError(t, err)
+	assert.Contains(t, metadata.DynamicTags, elf.DT_AUDIT)
+	assert.Equal(t, 1, metadata.Constructors)
+	assert.False(t, metadata.Interpreter)
+
+	// The metadata doesn't replace the symbols of the SO
+	syms, err := soLoader.GetExportedSymbols(auditInfo)
+	require.NoError(t, err)
+	assert.True(t, syms["exported_function"])
+
+	// The metadata read without the symbols is the metadata parsed with them
+	for i, path := range []string{"testdata/trusted.so", "testdata/wx.so", "testdata/constructors.so"} {
+		metadataLoader := InitHostSymbolsLoader(10)
+		info := ObjInfo{Id: ObjID{Inode: uint64(i + 2)}, Path: path}
+		metadata, err := metadataLoader.GetMetadata(info)
+		require.NoError(t, err, path)
+		_, err = soLoader.GetExportedSymbols(info)
+		require.NoError(t, err, path)
+		parsed, err := soLoader.GetMetadata(info)
+		require.NoError(t, err, path)
+		assert.Equal(t, parsed, metadata, path)
+	}
+
+	_, err = soLoader.GetMetadata(ObjInfo{Id: ObjID{Inode: 10}, Path: "testdata/missing.so"})
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestHostSharedObjectSymbolsLoader_DiskCache(t *testing.T) {
+	dir := t.TempDir()
+	// Temporary files of writes which didn't complete are removed when the cache is opened
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "partial.tmp"), []byte("partial"), 0600))
+	symbolsInfo := ObjInfo{Id: ObjID{Device: 1, Inode: 1}, Path: "testdata/symbols.so"}
+	config := HostSymbolsLoaderConfig{CacheSize: 10, DiskCacheDir: dir}
+
+	soLoader := InitHostSymbolsLoaderWithConfig(config)
+	expected, err := soLoader.GetExportedSymbols(symbolsInfo)
+	require.NoError(t, err)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "1-1-0.symbols", files[0].Name())
+
+	// After a restart, the symbols are read from the disk rather than parsed
+	restarted := InitHostSymbolsLoaderWithConfig(config)
+	restarted.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+		return nil, errors.New("parsed again")
+	}
+	syms, err := restarted.GetExportedSymbols(symbolsInfo)
+	require.NoError(t, err)
+	assert.Equal(t, expected, syms)
+	buildID, err := restarted.GetBuildID(symbolsInfo)
+	require.NoError(t, err)
+	assert.Equal(t, "d94f666a6334f0baed45391074e7ce827a1853e5", buildID)
+	assert.Equal(t, int32(1), restarted.Stats().DiskCacheHits.Read())
+
+	// A SO with the same ObjID and another build ID invalidates the entry
+	restarted = InitHostSymbolsLoaderWithConfig(config)
+	syms, err = restarted.GetExportedSymbols(ObjInfo{Id: symbolsInfo.Id, Path: "testdata/audit.so"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"exported_function": true}, syms)
+	assert.Equal(t, int32(0), restarted.Stats().DiskCacheHits.Read())
+	assert.Equal(t, int32(1), restarted.Stats().DiskCacheStale.Read())
+
+	// The least recently used entries are evicted once the budget is exceeded
+	weakInfo := ObjInfo{Id: ObjID{Device: 1, Inode: 2}, Path: "testdata/weak.so"}
+	_, err = InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, DiskCacheDir: dir}).
+		GetExportedSymbols(weakInfo)
+	require.NoError(t, err)
+	auditInfo, err := os.Stat(filepath.Join(dir, "1-1-0.symbols"))
+	require.NoError(t, err)
+	weakFileInfo, err := os.Stat(filepath.Join(dir, "1-2-0.symbols"))
+	require.NoError(t, err)
+
+	// The budget fits only the entry of weak.so, which was used most recently, so the entry of audit.so is evicted
+	// when the cache is opened
+	budget := auditInfo.Size() + weakFileInfo.Size() - 1
+	evicting := InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{
+		CacheSize:         10,
+		DiskCacheDir:      dir,
+		DiskCacheMaxBytes: budget,
+	})
+	_, err = evicting.GetExportedSymbols(weakInfo)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), evicting.Stats().DiskCacheHits.Read())
+	_, err = os.Stat(filepath.Join(dir, "1-1-0.symbols"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestHostSharedObjectSymbolsLoader_ImportsVersions(t *testing.T) {
+	soLoader := InitHostSymbolsLoader(10)
+	importsInfo, err := soLoader.GetImportedSymbolsInfo(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/versioned.so"})
+	require.NoError(t, err)
+	testCases := []struct {
+		name            string
+		expectedLibrary string
+		expectedVersion string
+	}{
+		// memcpy is bound to its version before GLIBC_2.14
+		{name: "memcpy", expectedLibrary: "libc.so.6", expectedVersion: "GLIBC_2.2.5"},
+		{name: "puts", expectedLibrary: "libc.so.6", expectedVersion: "GLIBC_2.2.5"},
+		{name: "explicit_bzero", expectedLibrary: "libc.so.6", expectedVersion: "GLIBC_2.25"},
+		// Imports with no version needed entry
+		{name: "__gmon_start__"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			info, ok := importsInfo[testCase.name]
+			require.True(t, ok)
+			assert.Equal(t, testCase.expectedLibrary, info.Library)
+			assert.Equal(t, testCase.expectedVersion, info.Version)
+		})
+	}
+}
+
+func TestHostSharedObjectSymbolsLoader_Close(t *testing.T) {
+	soLoader := InitHostSymbolsLoader(10)
+	soLoader.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+		return testDynamicSymbols, nil
+	}
+	_, err := soLoader.GetExportedSymbols(testLoadedObjectInfo)
+	require.NoError(t, err)
+
+	require.NoError(t, soLoader.Close())
+	require.NoError(t, soLoader.Close())
+	_, ok := soLoader.soCache.Get(testLoadedObjectInfo.Id)
+	assert.False(t, ok)
+	_, err = soLoader.GetExportedSymbols(testLoadedObjectInfo)
+	assert.ErrorIs(t, err, ErrLoaderClosed)
+}
+
+func TestHostSharedObjectSymbolsLoader_FilesystemPolicies(t *testing.T) {
+	const localType, skippedType, slowType = 0xef53, 0x6969, 0x65735546
+	localSO := ObjInfo{Id: ObjID{Inode: 1, Device: 1}, Path: "/usr/lib/libc.so.6"}
+	skippedSO := ObjInfo{Id: ObjID{Inode: 2, Device: 2}, Path: "/mnt/nfs/libremote.so"}
+	slowSO := ObjInfo{Id: ObjID{Inode: 3, Device: 3}, Path: "/mnt/fuse/libslow.so"}
+	fsTypes := map[string]uint32{localSO.Path: localType, skippedSO.Path: skippedType, slowSO.Path: slowType}
+
+	soLoader := InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{
+		CacheSize: 10,
+		FilesystemPolicies: map[uint32]FilesystemPolicy{
+			skippedType: {Skip: true},
+			slowType:    {Timeout: 10 * time.Millisecond},
+		},
+	})
+	var statfsCalls int32
+	soLoader.fsPolicies.statfs = func(path string) (uint32, error) {
+		atomic.AddInt32(&statfsCalls, 1)
+		return fsTypes[path], nil
+	}
+	release := make(chan struct{})
+	var reads int32
+	soLoader.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+		atomic.AddInt32(&reads, 1)
+		if path == slowSO.Path {
+			<-release
+		}
+		return testDynamicSymbols, nil
+	}
+
+	_, err := soLoader.GetExportedSymbols(localSO)
+	require.NoError(t, err)
+	_, err = soLoader.GetExportedSymbols(skippedSO)
+	assert.ErrorIs(t, err, ErrFilesystemSkipped)
+	_, err = soLoader.GetExportedSymbols(skippedSO)
+	assert.ErrorIs(t, err, ErrFilesystemSkipped)
+	assert.Equal(t, int32(2), soLoader.Stats().FilesystemSkips.Read())
+
+	_, err = soLoader.GetExportedSymbols(slowSO)
+	assert.ErrorIs(t, err, ErrFilesystemTimeout)
+	// The read in progress isn't started again
+	_, err = soLoader.GetExportedSymbols(slowSO)
+	assert.ErrorIs(t, err, ErrFilesystemTimeout)
+	assert.Equal(t, int32(2), soLoader.Stats().FilesystemTimeouts.Read())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&reads))
+
+	// Once the abandoned read completes, the SO is read again
+	close(release)
+	assert.Eventually(t, func() bool {
+		_, err := soLoader.GetExportedSymbols(slowSO)
+		return err == nil
+	}, time.Second, time.Millisecond)
+	// The filesystem type of each device is detected once
+	assert.Equal(t, int32(3), atomic.LoadInt32(&statfsCalls))
+
+	fsType, err := statfsType("testdata")
+	require.NoError(t, err)
+	assert.NotZero(t, fsType)
+}
+
+func TestHostSharedObjectSymbolsLoader_ContentDedup(t *testing.T) {
+	content, err := os.ReadFile("testdata/symbols.so")
+	require.NoError(t, err)
+	dir := t.TempDir()
+	firstPath := filepath.Join(dir, "first.so")
+	secondPath := filepath.Join(dir, "second.so")
+	otherPath := filepath.Join(dir, "other.so")
+	require.NoError(t, os.WriteFile(firstPath, content, 0644))
+	require.NoError(t, os.WriteFile(secondPath, content, 0644))
+	require.NoError(t, os.WriteFile(otherPath, append(content, 0), 0644))
+
+	soLoader := InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, ContentDedup: true})
+	loads := 0
+	soLoader.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+		loads++
+		return loadSharedObjectDynamicSymbols(ctx, path)
+	}
+
+	for i, path := range []string{firstPath, secondPath, otherPath} {
+		soInfo := ObjInfo{Id: ObjID{Inode: uint64(i + 1)}, Path: path}
+		syms, err := soLoader.GetExportedSymbols(soInfo)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]bool{"exported_function": true, "exported_counter": true}, syms)
+	}
+	assert.Equal(t, 2, loads)
+	assert.Equal(t, int32(3), soLoader.Stats().DedupLookups.Read())
+	assert.Equal(t, int32(1), soLoader.Stats().DedupHits.Read())
+	assert.InDelta(t, 1.0/3, soLoader.Stats().DedupHitRate(), 0.001)
+}
+
+func TestReadDynamicSymbols_Packed(t *testing.T) {
+	content, err := os.ReadFile("testdata/symbols.so")
+	require.NoError(t, err)
+
+	syms, err := readDynamicSymbols(bytes.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, "", syms.Packer)
+
+	// Imitate a UPX packed SO, which has no sections headers and the UPX magic after the program headers
+	packed := append([]byte{}, content...)
+	binary.LittleEndian.PutUint64(packed[0x28:], 0) // e_shoff
+	binary.LittleEndian.PutUint16(packed[0x3c:], 0) // e_shnum
+	binary.LittleEndian.PutUint16(packed[0x3e:], 0) // e_shstrndx
+	copy(packed[0x260:], "UPX!")
+	syms, err = readDynamicSymbols(bytes.NewReader(packed))
+	require.NoError(t, err)
+	assert.Equal(t, "UPX", syms.Packer)
+	assert.Empty(t, syms.Exported)
+	assert.Empty(t, syms.Imported)
+}
+
+func TestGetSymbolsFromBytes(t *testing.T) {
+	content, err := os.ReadFile("testdata/symbols.so")
+	require.NoError(t, err)
+
+	exported, err := GetExportedSymbolsFromBytes(content)
+	require.NoError(t, err)
+	assert.Equal(t, true, exported["exported_function"])
+	assert.Equal(t, true, exported["exported_counter"])
+	assert.NotContains(t, exported, "getenv")
+
+	imported, err := GetImportedSymbolsFromBytes(content)
+	require.NoError(t, err)
+	assert.Equal(t, true, imported["getenv"])
+	assert.Equal(t, true, imported["puts"])
+	assert.NotContains(t, imported, "exported_function")
+
+	// The result should match parsing the same SO from its path
+	fromPath, err := loadSharedObjectDynamicSymbols(context.Background(), "testdata/symbols.so")
+	require.NoError(t, err)
+	assert.Equal(t, fromPath.Exported, exported)
+	assert.Equal(t, fromPath.Imported, imported)
+
+	// An ELF with no sections headers has no dynamic symbols table to parse
+	stripped := append([]byte{}, content...)
+	binary.LittleEndian.PutUint64(stripped[0x28:], 0) // e_shoff
+	binary.LittleEndian.PutUint16(stripped[0x3c:], 0) // e_shnum
+	binary.LittleEndian.PutUint16(stripped[0x3e:], 0) // e_shstrndx
+	badContents := map[string][]byte{
+		"empty":     {},
+		"not ELF":   []byte("#!/bin/sh\necho not an ELF\n"),
+		"truncated": content[:0x20],
+		"stripped":  stripped,
+	}
+	for name, badContent := range badContents {
+		_, err = GetExportedSymbolsFromBytes(badContent)
+		assert.Error(t, err, name)
+		_, err = GetImportedSymbolsFromBytes(badContent)
+		assert.Error(t, err, name)
+	}
+	_, err = GetExportedSymbolsFromBytes(stripped)
+	assert.ErrorIs(t, err, elf.ErrNoSymbols)
+}
+
+func TestHostSharedObjectSymbolsLoader_IsInterpreter(t *testing.T) {
+	content, err := os.ReadFile("testdata/symbols.so")
+	require.NoError(t, err)
+
+	// The fixture has no DT_SONAME, so it is recognized as the dynamic loader only by its path
+	dir := t.TempDir()
+	paths := map[string]bool{
+		"ld-linux-x86-64.so.2":  true,
+		"ld-musl-x86_64.so.1":   true,
+		"ld64.so.2":             true,
+		"libc.so.6":             false,
+		"ld-evil.so":            false,
+		"libld-linux-fake.so.1": false,
+		"libc.musl-x86_64.so.1": true,
+		"symbols.so":            false,
+	}
+	soLoader := InitHostSymbolsLoader(10)
+	inode := uint64(0)
+	for name, expected := range paths {
+		inode++
+		soPath := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(soPath, content, 0600))
+		interpreter, err := soLoader.IsInterpreter(ObjInfo{Id: ObjID{Inode: inode}, Path: soPath})
+		require.NoError(t, err)
+		assert.Equal(t, expected, interpreter, name)
+	}
+
+	// The dynamic loader of the host is recognized by its DT_SONAME
+	hostInterpreters, _ := filepath.Glob("/lib64/ld-linux-*.so.*")
+	if len(hostInterpreters) == 0 {
+		t.Skip("no dynamic loader found on the host")
+	}
+	interpreter, err := soLoader.IsInterpreter(ObjInfo{Id: ObjID{Inode: 1000}, Path: hostInterpreters[0]})
+	require.NoError(t, err)
+	assert.True(t, interpreter)
+}
+
+func TestHostSharedObjectSymbolsLoader_ExtractionLatency(t *testing.T) {
+	soLoader := InitHostSymbolsLoader(10)
+	soLoader.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+		time.Sleep(2 * time.Millisecond)
+		syms := NewSOSymbols()
+		return &syms, nil
+	}
+
+	duration, first, err := soLoader.GetExtractionDuration(testLoadedObjectInfo)
+	require.NoError(t, err)
+	assert.True(t, first)
+	assert.GreaterOrEqual(t, duration, 2*time.Millisecond)
+	cachedDuration, first, err := soLoader.GetExtractionDuration(testLoadedObjectInfo)
+	require.NoError(t, err)
+	assert.False(t, first)
+	assert.Equal(t, duration, cachedDuration)
+
+	counts := soLoader.Stats().ExtractionLatency.Counts()
+	require.Len(t, counts, len(LatencyBuckets)+1)
+	var total int32
+	for _, count := range counts {
+		total += count
+	}
+	assert.Equal(t, int32(1), total)
+	assert.Equal(t, int32(0), counts[0])
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	var histogram LatencyHistogram
+	for _, duration := range []time.Duration{0, time.Millisecond, 5 * time.Millisecond, time.Second, time.Minute} {
+		histogram.Observe(duration)
+	}
+	assert.Equal(t, []int32{2, 1, 0, 1, 1}, histogram.Counts())
+}
+
+func TestObjInfoFromEvent(t *testing.T) {
+	event := trace.Event{
+		HostProcessID: 10,
+		MountNS:       20,
+		Args: []trace.Argument{
+			{ArgMeta: trace.ArgMeta{Name: "pathname"}, Value: "/tmp/test.so" + DeletedSuffix},
+			{ArgMeta: trace.ArgMeta{Name: "flags"}, Value: int32(0)},
+			{ArgMeta: trace.ArgMeta{Name: "dev"}, Value: uint32(2)},
+			{ArgMeta: trace.ArgMeta{Name: "inode"}, Value: uint64(1)},
+			{ArgMeta: trace.ArgMeta{Name: "ctime"}, Value: uint64(3)},
+		},
+	}
+	info, err := ObjInfoFromEvent(event)
+	require.NoError(t, err)
+	assert.Equal(t, ObjInfo{
+		Id:      ObjID{Inode: 1, Device: 2, Ctime: 3},
+		Path:    "/tmp/test.so",
+		MountNS: 20,
+		Pid:     10,
+		Deleted: true,
+	}, info)
+
+	event.Args = event.Args[:3]
+	info, err = ObjInfoFromEvent(event)
+	var infoErr *ObjInfoError
+	require.ErrorAs(t, err, &infoErr)
+	assert.Len(t, infoErr.Errors, 2)
+	assert.Equal(t, ObjID{Device: 2}, info.Id)
+}
+
+func TestExpandRunpath(t *testing.T) {
+	testCases := []struct {
+		name         string
+		runpath      string
+		class        elf.Class
+		machine      elf.Machine
+		expectedDirs []string
+	}{
+		{
+			name:         "No tokens",
+			runpath:      "/opt/lib:/usr/local/lib/",
+			class:        elf.ELFCLASS64,
+			machine:      elf.EM_X86_64,
+			expectedDirs: []string{"/opt/lib", "/usr/local/lib"},
+		},
+		{
+			name:         "Origin",
+			runpath:      "$ORIGIN:$ORIGIN/../lib:${ORIGIN}/plugins",
+			class:        elf.ELFCLASS64,
+			machine:      elf.EM_X86_64,
+			expectedDirs: []string{"/app/bin", "/app/lib", "/app/bin/plugins"},
+		},
+		{
+			name:         "Lib and platform of 64-bit SO",
+			runpath:      "/opt/$LIB/${PLATFORM}",
+			class:        elf.ELFCLASS64,
+			machine:      elf.EM_AARCH64,
+			expectedDirs: []string{"/opt/lib64/aarch64"},
+		},
+		{
+			name:         "Lib and platform of 32-bit SO",
+			runpath:      "/opt/${LIB}/$PLATFORM",
+			class:        elf.ELFCLASS32,
+			machine:      elf.EM_386,
+			expectedDirs: []string{"/opt/lib/i686"},
+		},
+		{
+			name:         "Unknown tokens and platforms are dropped",
+			runpath:      "$ORIGIN/lib:/opt/$UNKNOWN:/opt/$PLATFORM:",
+			class:        elf.ELFCLASS64,
+			machine:      elf.EM_PPC64,
+			expectedDirs: []string{"/app/bin/lib"},
+		},
+		{
+			name:    "Empty runpath",
+			runpath: "",
+			class:   elf.ELFCLASS64,
+			machine: elf.EM_X86_64,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dirs := ExpandRunpath(testCase.runpath, "/app/bin/libtest.so", testCase.class, testCase.machine)
+			assert.Equal(t, testCase.expectedDirs, dirs)
+		})
+	}
+}
+
+func TestGetRunpath(t *testing.T) {
+	// The path of the SO in its mount namespace differs from the path it is accessed through
+	dirs, err := GetRunpath("testdata/runpath.so", "/app/lib/plugin/runpath.so")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/app/lib/lib", "/app/lib/plugin/plugins", "/opt/lib64/x86_64"}, dirs)
+
+	dirs, err = GetRunpath("testdata/symbols.so", "/app/lib/symbols.so")
+	require.NoError(t, err)
+	assert.Empty(t, dirs)
+}
+
+func TestLoadSharedObjectDynamicSymbolsMmap(t *testing.T) {
+	expected, err := loadSharedObjectDynamicSymbols(context.Background(), "testdata/symbols.so")
+	require.NoError(t, err)
+
+	t.Run("Mapped", func(t *testing.T) {
+		syms, err := loadSharedObjectDynamicSymbolsMmap(1, readOptions{})(context.Background(), "testdata/symbols.so")
+		require.NoError(t, err)
+		assert.Equal(t, expected, syms)
+	})
+	t.Run("Smaller than minimal size", func(t *testing.T) {
+		syms, err := loadSharedObjectDynamicSymbolsMmap(1<<30, readOptions{})(context.Background(), "testdata/symbols.so")
+		require.NoError(t, err)
+		assert.Equal(t, expected, syms)
+	})
+	t.Run("Non-existing file", func(t *testing.T) {
+		_, err := loadSharedObjectDynamicSymbolsMmap(1, readOptions{})(context.Background(), "testdata/missing.so")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+	t.Run("Not an ELF", func(t *testing.T) {
+		_, err := loadSharedObjectDynamicSymbolsMmap(1, readOptions{})(context.Background(), "testdata/symbols.c")
+		assert.Error(t, err)
+	})
+}
+
+func TestMmapFile(t *testing.T) {
+	t.Run("Non-regular file", func(t *testing.T) {
+		file, err := os.Open(os.DevNull)
+		require.NoError(t, err)
+		defer file.Close()
+		_, err = mmapFile(file, 1)
+		assert.Error(t, err)
+	})
+	t.Run("Empty file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "empty.so")
+		require.NoError(t, os.WriteFile(path, nil, 0600))
+		file, err := os.Open(path)
+		require.NoError(t, err)
+		defer file.Close()
+		_, err = mmapFile(file, 0)
+		assert.Error(t, err)
+	})
+	t.Run("Read beyond the end", func(t *testing.T) {
+		file, err := os.Open("testdata/symbols.so")
+		require.NoError(t, err)
+		defer file.Close()
+		reader, err := mmapFile(file, 1)
+		require.NoError(t, err)
+		size := int64(len(reader.data))
+
+		buf := make([]byte, 8)
+		n, err := reader.ReadAt(buf, size-4)
+		assert.Equal(t, 4, n)
+		assert.ErrorIs(t, err, io.EOF)
+		_, err = reader.ReadAt(buf, size)
+		assert.ErrorIs(t, err, io.EOF)
+		_, err = reader.ReadAt(buf, -1)
+		assert.Error(t, err)
+
+		require.NoError(t, reader.Close())
+		assert.NoError(t, reader.Close())
+	})
+	t.Run("Truncated after mapping", func(t *testing.T) {
+		content, err := os.ReadFile("testdata/symbols.so")
+		require.NoError(t, err)
+		path := filepath.Join(t.TempDir(), "truncated.so")
+		require.NoError(t, os.WriteFile(path, content, 0600))
+		file, err := os.Open(path)
+		require.NoError(t, err)
+		defer file.Close()
+		reader, err := mmapFile(file, 1)
+		require.NoError(t, err)
+		defer reader.Close()
+
+		// Accessing the pages beyond the new end of the file faults, which must not crash the process
+		require.NoError(t, os.Truncate(path, 0))
+		_, err = readMappedDynamicSymbols(context.Background(), reader, readOptions{})
+		assert.ErrorIs(t, err, errMappingFault)
+	})
+}
+
+// largeSharedObject returns the path of a large SO of the host, or skips the benchmark if none is found
+func largeSharedObject(b *testing.B) string {
+	candidates := []string{
+		"/usr/lib/x86_64-linux-gnu/libstdc++.so.6",
+		"/usr/lib64/libstdc++.so.6",
+		"/lib/x86_64-linux-gnu/libc.so.6",
+		"/usr/lib64/libc.so.6",
+	}
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+	}
+	b.Skip("no large SO found")
+	return ""
+}
+
+func BenchmarkLoadSharedObjectDynamicSymbols(b *testing.B) {
+	path := largeSharedObject(b)
+	info, err := os.Stat(path)
+	require.NoError(b, err)
+	loadingFuncs := map[string]func(ctx context.Context, path string) (*dynamicSymbols, error){
+		"Read": loadSharedObjectDynamicSymbols,
+		"Mmap": loadSharedObjectDynamicSymbolsMmap(1, readOptions{}),
+	}
+	for name, loadingFunc := range loadingFuncs {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(info.Size())
+			for i := 0; i < b.N; i++ {
+				if _, err := loadingFunc(context.Background(), path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestGetSonameSymbols(t *testing.T) {
+	soname, exported, err := GetSonameSymbols("testdata/runpath.so")
+	require.NoError(t, err)
+	assert.Equal(t, "librunpath.so.1", soname)
+	assert.Equal(t, map[string]bool{"exported_function": true, "exported_counter": true}, exported)
+
+	soname, _, err = GetSonameSymbols("testdata/symbols.so")
+	require.NoError(t, err)
+	assert.Empty(t, soname)
+
+	loader := InitHostSymbolsLoader(10)
+	soname, err = loader.GetSoname(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/runpath.so"})
+	require.NoError(t, err)
+	assert.Equal(t, "librunpath.so.1", soname)
+}
+
+func TestGetSonameWeakSymbols(t *testing.T) {
+	soname, weak, err := GetSonameWeakSymbols("testdata/weak.so")
+	require.NoError(t, err)
+	assert.Equal(t, "libweak.so.1", soname)
+	assert.Equal(t, []string{"allocate"}, weak)
+
+	_, weak, err = GetSonameWeakSymbols("testdata/symbols.so")
+	require.NoError(t, err)
+	assert.Empty(t, weak)
+}
+
+func TestHostSharedObjectSymbolsLoader_LoadContext(t *testing.T) {
+	soInfo := ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/symbols.so"}
+	configs := map[string]HostSymbolsLoaderConfig{
+		"Read": {CacheSize: 10},
+		"Mmap": {CacheSize: 10, MmapMinSize: 1},
+	}
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			soLoader := InitHostSymbolsLoaderWithConfig(config)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			// The file is not read once the context is done, and the failure is not cached
+			assert.ErrorIs(t, soLoader.LoadContext(ctx, soInfo), context.Canceled)
+			_, ok := soLoader.soCache.Get(soInfo.Id)
+			assert.False(t, ok)
+
+			require.NoError(t, soLoader.LoadContext(context.Background(), soInfo))
+			soLoader.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+				return nil, errors.New("read again")
+			}
+			// The loaded symbols are cached for the other methods, and cached symbols don't need the context
+			syms, err := soLoader.GetExportedSymbols(soInfo)
+			require.NoError(t, err)
+			assert.True(t, syms["exported_function"])
+			assert.NoError(t, soLoader.LoadContext(ctx, soInfo))
+		})
+	}
+}
+
+func TestContextReader(t *testing.T) {
+	content, err := os.ReadFile("testdata/symbols.so")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := readerWithContext(ctx, bytes.NewReader(content))
+	_, err = readDynamicSymbols(reader)
+	require.NoError(t, err)
+	// The ELF parser doesn't wrap the errors of the reader, which is why the loader returns the error of the context
+	cancel()
+	_, err = readDynamicSymbols(reader)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), context.Canceled.Error())
+
+	// Contexts which are never done don't wrap the reader
+	plain := bytes.NewReader(content)
+	assert.Equal(t, io.ReaderAt(plain), readerWithContext(context.Background(), plain))
+}
+
+func TestHostSharedObjectSymbolsLoader_GetPermissionError(t *testing.T) {
+	unreadableInfo := ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/symbols.so"}
+	failingInfo := ObjInfo{Id: ObjID{Inode: 2}, Path: "testdata/symbols.so"}
+	soLoader := InitHostSymbolsLoader(10)
+	reads := 0
+	readErr := fmt.Errorf("open %s: %w", unreadableInfo.Path, fs.ErrPermission)
+	soLoader.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+		reads++
+		return nil, readErr
+	}
+	// The permission failure is classified once, and the SO isn't read again by any method
+	err := soLoader.GetPermissionError(unreadableInfo)
+	assert.ErrorIs(t, err, fs.ErrPermission)
+	assert.Equal(t, err, soLoader.GetPermissionError(unreadableInfo))
+	_, err = soLoader.GetExportedSymbols(unreadableInfo)
+	assert.ErrorIs(t, err, fs.ErrPermission)
+	assert.Equal(t, 1, reads)
+
+	// Other failures are not permission errors, and are not cached
+	readErr = errors.New("malformed")
+	assert.NoError(t, soLoader.GetPermissionError(failingInfo))
+	_, err = soLoader.GetExportedSymbols(failingInfo)
+	assert.Error(t, err)
+	assert.Equal(t, 3, reads)
+
+	require.NoError(t, soLoader.Close())
+	_, ok := soLoader.unreadable.Get(unreadableInfo.Id)
+	assert.False(t, ok)
+}
+
+func TestHostSharedObjectSymbolsLoader_HasNote(t *testing.T) {
+	trustedNote := NoteID{Name: "tracee", Type: 1}
+	loader := InitHostSymbolsLoader(10)
+
+	trusted, err := loader.HasNote(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/trusted.so"}, trustedNote)
+	require.NoError(t, err)
+	assert.True(t, trusted)
+	buildID, err := loader.HasNote(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/trusted.so"},
+		NoteID{Name: "GNU", Type: 3}) // NT_GNU_BUILD_ID
+	require.NoError(t, err)
+	assert.True(t, buildID)
+	wrongType, err := loader.HasNote(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/trusted.so"},
+		NoteID{Name: "tracee", Type: 2})
+	require.NoError(t, err)
+	assert.False(t, wrongType)
+
+	trusted, err = loader.HasNote(ObjInfo{Id: ObjID{Inode: 2}, Path: "testdata/symbols.so"}, trustedNote)
+	require.NoError(t, err)
+	assert.False(t, trusted)
+}
+
+func TestHostSharedObjectSymbolsLoader_GetWritableCodeSegments(t *testing.T) {
+	loader := InitHostSymbolsLoader(10)
+
+	segments, err := loader.GetWritableCodeSegments(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/wx.so"})
+	require.NoError(t, err)
+	assert.Equal(t, []Segment{{Flags: elf.PF_R | elf.PF_W | elf.PF_X, Offset: 0x2df8}}, segments)
+
+	segments, err = loader.GetWritableCodeSegments(ObjInfo{Id: ObjID{Inode: 2}, Path: "testdata/symbols.so"})
+	require.NoError(t, err)
+	assert.Empty(t, segments)
+}
+
+func TestParseNotes(t *testing.T) {
+	note := func(name string, descSize int, noteType uint32) []byte {
+		var buf bytes.Buffer
+		nameBytes := append([]byte(name), 0)
+		_ = binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(nameBytes)), uint32(descSize), noteType})
+		buf.Write(nameBytes)
+		buf.Write(make([]byte, int(alignUp(uint64(len(nameBytes)), 4))-len(nameBytes)))
+		buf.Write(make([]byte, alignUp(uint64(descSize), 4)))
+		return buf.Bytes()
+	}
+	testCases := []struct {
+		name     string
+		data     []byte
+		expected map[NoteID]bool
+	}{
+		{
+			name:     "Multiple notes",
+			data:     append(note("GNU", 5, 3), note("tracee", 0, 1)...),
+			expected: map[NoteID]bool{{Name: "GNU", Type: 3}: true, {Name: "tracee", Type: 1}: true},
+		},
+		{
+			name:     "Truncated name",
+			data:     append(note("GNU", 4, 3), note("tracee", 0, 1)[:14]...),
+			expected: map[NoteID]bool{{Name: "GNU", Type: 3}: true},
+		},
+		{
+			name:     "Truncated description",
+			data:     append(note("GNU", 0, 3), note("tracee", 16, 1)[:24]...),
+			expected: map[NoteID]bool{{Name: "GNU", Type: 3}: true, {Name: "tracee", Type: 1}: true},
+		},
+		{
+			name:     "Huge name size",
+			data:     append([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 1, 0, 0, 0}, make([]byte, 8)...),
+			expected: map[NoteID]bool{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			notes := make(map[NoteID]bool)
+			parseNotes(bytes.NewReader(testCase.data), uint64(len(testCase.data)), 4, binary.LittleEndian, maxNotesSize,
+				func(note NoteID, desc []byte) {
+					notes[note] = true
+				})
+			assert.Equal(t, testCase.expected, notes)
+		})
+	}
+}
+
+func TestHostSharedObjectSymbolsLoader_RemapID(t *testing.T) {
+	snapshotObjectInfo := testLoadedObjectInfo
+	snapshotObjectInfo.Id.Device = 42
+	snapshotObjectInfo.Path = "/snapshots/1/tmp/test.so"
+	otherObjectInfo := testLoadedObjectInfo
+	otherObjectInfo.Id.Inode++
+
+	testCases := []struct {
+		name           string
+		remapID        func(id ObjID) ObjID
+		expectedLoaded []string
+	}{
+		{
+			name: "Identity",
+			expectedLoaded: []string{
+				testLoadedObjectInfo.Path, snapshotObjectInfo.Path, otherObjectInfo.Path,
+			},
+		},
+		{
+			name:           "Remapped snapshot device",
+			remapID:        RemapDevices(map[uint32]uint32{42: testLoadedObjectInfo.Id.Device}),
+			expectedLoaded: []string{testLoadedObjectInfo.Path, otherObjectInfo.Path},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var loadedPaths []string
+			soLoader := InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{CacheSize: 10, RemapID: testCase.remapID})
+			soLoader.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+				loadedPaths = append(loadedPaths, path)
+				syms := NewSOSymbols()
+				syms.Exported = map[string]bool{"open": true}
+				return &syms, nil
+			}
+			for _, soInfo := range []ObjInfo{testLoadedObjectInfo, snapshotObjectInfo, otherObjectInfo} {
+				syms, err := soLoader.GetExportedSymbols(soInfo)
+				require.NoError(t, err)
+				assert.Equal(t, map[string]bool{"open": true}, syms)
+			}
+			assert.Equal(t, testCase.expectedLoaded, loadedPaths)
+		})
+	}
+}
+
+// stripSectionHeaders imitates a stripped SO, which has no sections headers (so no dynamic symbols table) but keeps
+// its program headers, including the notes segment of its build ID
+func stripSectionHeaders(content []byte) []byte {
+	stripped := append([]byte{}, content...)
+	binary.LittleEndian.PutUint64(stripped[0x28:], 0) // e_shoff
+	binary.LittleEndian.PutUint16(stripped[0x3c:], 0) // e_shnum
+	binary.LittleEndian.PutUint16(stripped[0x3e:], 0) // e_shstrndx
+	return stripped
+}
+
+func TestReadDynamicSymbols_BuildID(t *testing.T) {
+	const symbolsBuildID = "d94f666a6334f0baed45391074e7ce827a1853e5"
+	content, err := os.ReadFile("testdata/symbols.so")
+	require.NoError(t, err)
+
+	syms, err := readDynamicSymbols(bytes.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, symbolsBuildID, syms.BuildID)
+
+	_, err = readDynamicSymbols(bytes.NewReader(stripSectionHeaders(content)))
+	require.ErrorIs(t, err, elf.ErrNoSymbols)
+	var noSymsErr *noSymbolsError
+	require.True(t, errors.As(err, &noSymsErr))
+	assert.Equal(t, symbolsBuildID, noSymsErr.local.BuildID)
+
+	loader := InitHostSymbolsLoader(10)
+	buildID, err := loader.GetBuildID(ObjInfo{Id: ObjID{Inode: 1}, Path: "testdata/symbols.so"})
+	require.NoError(t, err)
+	assert.Equal(t, symbolsBuildID, buildID)
+}
+
+func TestHostSharedObjectSymbolsLoader_SymbolServer(t *testing.T) {
+	content, err := os.ReadFile("testdata/symbols.so")
+	require.NoError(t, err)
+	stripped := stripSectionHeaders(content)
+	otherContent, err := os.ReadFile("testdata/runpath.so")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name             string
+		handler          http.HandlerFunc
+		timeout          time.Duration
+		expectedExported map[string]bool
+		expectedFetches  int32
+		expectedFailures int32
+	}{
+		{
+			name: "Fetched object",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(content)
+			},
+			expectedExported: map[string]bool{"exported_function": true, "exported_counter": true},
+			expectedFetches:  1,
+		},
+		{
+			name: "Object not found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			expectedFailures: 1,
+		},
+		{
+			name: "Object of another build ID",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(otherContent)
+			},
+			expectedFailures: 1,
+		},
+		{
+			name: "Timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			},
+			timeout:          50 * time.Millisecond,
+			expectedFailures: 1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// The handler may still run when the client gave up waiting for it (e.g. on timeout)
+			var requestedPathsMutex sync.Mutex
+			var requestedPaths []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestedPathsMutex.Lock()
+				requestedPaths = append(requestedPaths, r.URL.Path)
+				requestedPathsMutex.Unlock()
+				testCase.handler(w, r)
+			}))
+			defer server.Close()
+			timeout := testCase.timeout
+			if timeout == 0 {
+				timeout = DefaultSymbolServerTimeout
+			}
+			soLoader := InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{
+				CacheSize:    10,
+				SymbolServer: InitDebuginfodClient(server.URL+"/", timeout),
+			})
+			soLoader.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+				return readDynamicSymbols(bytes.NewReader(stripped))
+			}
+			otherObjectInfo := testLoadedObjectInfo
+			otherObjectInfo.Id.Inode++
+
+			// Both SOs have the same build ID, so the symbol server is requested once
+			for _, soInfo := range []ObjInfo{testLoadedObjectInfo, otherObjectInfo} {
+				syms, err := soLoader.GetExportedSymbols(soInfo)
+				if testCase.expectedExported == nil {
+					// Failing to fetch the symbols falls back to the local SO
+					assert.ErrorIs(t, err, elf.ErrNoSymbols)
+					continue
+				}
+				require.NoError(t, err)
+				for sym := range testCase.expectedExported {
+					assert.True(t, syms[sym])
+				}
+			}
+			requestedPathsMutex.Lock()
+			assert.Equal(t, []string{"/buildid/d94f666a6334f0baed45391074e7ce827a1853e5/executable"}, requestedPaths)
+			requestedPathsMutex.Unlock()
+			assert.Equal(t, testCase.expectedFetches, soLoader.Stats().SymbolServerFetches.Read())
+			assert.Equal(t, testCase.expectedFailures, soLoader.Stats().SymbolServerFailures.Read())
+		})
+	}
+}
+
+func TestHostSharedObjectSymbolsLoader_SymbolServerForgedBuildID(t *testing.T) {
+	const symbolsBuildID = "d94f666a6334f0baed45391074e7ce827a1853e5"
+	content, err := os.ReadFile("testdata/symbols.so")
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	soLoader := InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{
+		CacheSize:    10,
+		SymbolServer: InitDebuginfodClient(server.URL, DefaultSymbolServerTimeout),
+		// The last symbol of the fetched object is beyond the cap
+		MaxSymbols: 7,
+	})
+	// A stripped SO carrying the build ID of another object, with metadata of its own
+	wxSegments := []Segment{{Flags: elf.PF_R | elf.PF_W | elf.PF_X, Offset: 0x1000}}
+	soLoader.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+		local := NewSOSymbols()
+		local.BuildID = symbolsBuildID
+		local.WXSegments = wxSegments
+		local.Interp = "/tmp/ld.so"
+		local.Constructors = 7
+		return nil, &noSymbolsError{local: &local}
+	}
+
+	// Only the symbols are taken from the fetched object, which is read with the options of the loader
+	syms, err := soLoader.GetExportedSymbols(testLoadedObjectInfo)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"exported_function": true}, syms)
+	truncated, err := soLoader.IsTruncated(testLoadedObjectInfo)
+	require.NoError(t, err)
+	assert.True(t, truncated)
+	segments, err := soLoader.GetWritableCodeSegments(testLoadedObjectInfo)
+	require.NoError(t, err)
+	assert.Equal(t, wxSegments, segments)
+	interp, err := soLoader.GetInterpreter(testLoadedObjectInfo)
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/ld.so", interp)
+	constructors, err := soLoader.GetConstructorsCount(testLoadedObjectInfo)
+	require.NoError(t, err)
+	assert.Equal(t, 7, constructors)
+}
+
+// settableClock is a Clock whose current time is set by the test
+type settableClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (clock *settableClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+func (clock *settableClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (clock *settableClock) advance(d time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = clock.now.Add(d)
+}
+
+func TestHostSharedObjectSymbolsLoader_SymbolServerRetry(t *testing.T) {
+	content, err := os.ReadFile("testdata/symbols.so")
+	require.NoError(t, err)
+	stripped := stripSectionHeaders(content)
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server is unavailable on the first request only
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	clock := &settableClock{now: time.Unix(1000, 0)}
+	soLoader := InitHostSymbolsLoaderWithConfig(HostSymbolsLoaderConfig{
+		CacheSize:    10,
+		SymbolServer: InitDebuginfodClient(server.URL, DefaultSymbolServerTimeout),
+		Clock:        clock,
+	})
+	soLoader.loadingFunc = func(ctx context.Context, path string) (*dynamicSymbols, error) {
+		return readDynamicSymbols(bytes.NewReader(stripped))
+	}
+	otherObjectInfo, laterObjectInfo := testLoadedObjectInfo, testLoadedObjectInfo
+	otherObjectInfo.Id.Inode++
+	laterObjectInfo.Id.Inode += 2
+
+	// The failure is cached for a while, and then the server is requested again
+	_, err = soLoader.GetExportedSymbols(testLoadedObjectInfo)
+	assert.ErrorIs(t, err, elf.ErrNoSymbols)
+	clock.advance(fetchFailureTTL / 2)
+	_, err = soLoader.GetExportedSymbols(otherObjectInfo)
+	assert.ErrorIs(t, err, elf.ErrNoSymbols)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	clock.advance(fetchFailureTTL)
+	syms, err := soLoader.GetExportedSymbols(laterObjectInfo)
+	require.NoError(t, err)
+	assert.True(t, syms["exported_function"])
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Equal(t, int32(1), soLoader.Stats().SymbolServerFailures.Read())
+	assert.Equal(t, int32(1), soLoader.Stats().SymbolServerFetches.Read())
+}
+
+func TestDebuginfodClient_InvalidBuildID(t *testing.T) {
+	client := InitDebuginfodClient("http://127.0.0.1:1", DefaultSymbolServerTimeout)
+	_, err := client.FetchObject("../../etc/passwd")
+	assert.Error(t, err)
+	_, err = client.FetchObject("")
+	assert.Error(t, err)
+}
+
+// coreSegment is memory of the process of a core dump, which is captured in the core dump
+type coreSegment struct {
+	addr uint64
+	data []byte
+}
+
+// buildCoreDump builds a core dump of a process with the given mapped files and captured memory
+func buildCoreDump(t *testing.T, mappings []coreMapping, segments []coreSegment) []byte {
+	const pageSize = 0x1000
+	var fileNote bytes.Buffer
+	words := []uint64{uint64(len(mappings)), pageSize}
+	for _, mapping := range mappings {
+		words = append(words, mapping.start, mapping.end, mapping.fileOffset/pageSize)
+	}
+	require.NoError(t, binary.Write(&fileNote, binary.LittleEndian, words))
+	for _, mapping := range mappings {
+		fileNote.WriteString(mapping.path)
+		fileNote.WriteByte(0)
+	}
+	for fileNote.Len()%4 != 0 {
+		fileNote.WriteByte(0)
+	}
+	var notes bytes.Buffer
+	require.NoError(t, binary.Write(&notes, binary.LittleEndian, []uint32{5, uint32(fileNote.Len()), ntFile}))
+	notes.Write([]byte("CORE\x00\x00\x00\x00"))
+	notes.Write(fileNote.Bytes())
+
+	headerSize := uint64(binary.Size(elf.Header64{}))
+	progSize := uint64(binary.Size(elf.Prog64{}))
+	offset := headerSize + progSize*uint64(1+len(segments))
+	progs := []elf.Prog64{{Type: uint32(elf.PT_NOTE), Off: offset, Filesz: uint64(notes.Len()), Align: 4}}
+	offset += uint64(notes.Len())
+	for _, segment := range segments {
+		progs = append(progs, elf.Prog64{
+			Type: uint32(elf.PT_LOAD), Off: offset, Vaddr: segment.addr, Filesz: uint64(len(segment.data)),
+			Memsz: uint64(len(segment.data)), Align: 1,
+		})
+		offset += uint64(len(segment.data))
+	}
+	header := elf.Header64{
+		Type: uint16(elf.ET_CORE), Machine: uint16(elf.EM_X86_64), Version: uint32(elf.EV_CURRENT),
+		Phoff: headerSize, Ehsize: uint16(headerSize), Phentsize: uint16(progSize), Phnum: uint16(len(progs)),
+	}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	var core bytes.Buffer
+	require.NoError(t, binary.Write(&core, binary.LittleEndian, header))
+	require.NoError(t, binary.Write(&core, binary.LittleEndian, progs))
+	core.Write(notes.Bytes())
+	for _, segment := range segments {
+		core.Write(segment.data)
+	}
+	return core.Bytes()
+}
+
+// loadedObjectMemory returns the mappings and the memory of the given SO loaded to the given address. The dynamic
+// section pointers are relocated if relocate is set, as done by glibc.
+func loadedObjectMemory(t *testing.T, content []byte, path string, base uint64, relocate bool) (
+	[]coreMapping, []coreSegment) {
+	file, err := elf.NewFile(bytes.NewReader(content))
+	require.NoError(t, err)
+	var mappings []coreMapping
+	var segments []coreSegment
+	for _, prog := range file.Progs {
+		if prog.Type != elf.PT_LOAD {
+			continue
+		}
+		pageStart := prog.Vaddr &^ 0xfff
+		data := make([]byte, prog.Vaddr+prog.Memsz-pageStart)
+		copy(data[prog.Vaddr-pageStart:], content[prog.Off:prog.Off+prog.Filesz])
+		mappings = append(mappings, coreMapping{
+			start: base + pageStart, end: base + prog.Vaddr + prog.Memsz, fileOffset: prog.Off &^ 0xfff, path: path,
+		})
+		segments = append(segments, coreSegment{addr: base + pageStart, data: data})
+	}
+	if relocate {
+		dynamic := file.Section(".dynamic")
+		for _, segment := range segments {
+			if dynamic.Addr < segment.addr-base || dynamic.Addr >= segment.addr-base+uint64(len(segment.data)) {
+				continue
+			}
+			entries := segment.data[dynamic.Addr-(segment.addr-base):]
+			for i := 0; i+16 <= int(dynamic.Size); i += 16 {
+				switch elf.DynTag(binary.LittleEndian.Uint64(entries[i:])) {
+				case elf.DT_SYMTAB, elf.DT_STRTAB, elf.DT_GNU_HASH, elf.DT_HASH:
+					value := binary.LittleEndian.Uint64(entries[i+8:])
+					binary.LittleEndian.PutUint64(entries[i+8:], value+base)
+				}
+			}
+		}
+	}
+	return mappings, segments
+}
+
+func TestCoreDumpSymbolsLoader(t *testing.T) {
+	const base = 0x7f0000000000
+	const path = "/usr/lib/libsymbols.so"
+	content, err := os.ReadFile("testdata/symbols.so")
+	require.NoError(t, err)
+	expected, err := readDynamicSymbols(bytes.NewReader(content))
+	require.NoError(t, err)
+
+	dataMapping := coreMapping{start: 0x7f1000000000, end: 0x7f1000001000, path: "/usr/share/data.bin"}
+	dataSegment := coreSegment{addr: dataMapping.start, data: make([]byte, 0x1000)}
+
+	testCases := []struct {
+		name     string
+		relocate bool
+		// Modifies the captured memory of the SO, given its segments
+		capture          func(segments []coreSegment) []coreSegment
+		expectedComplete bool
+	}{
+		{
+			name:             "Fully captured",
+			expectedComplete: true,
+		},
+		{
+			name:             "Relocated dynamic section",
+			relocate:         true,
+			expectedComplete: true,
+		},
+		{
+			name: "Symbols table not captured",
+			capture: func(segments []coreSegment) []coreSegment {
+				// Only the headers and the hash table of the first page are captured
+				first := segments[0]
+				first.data = first.data[:0x288]
+				return append([]coreSegment{first}, segments[1:]...)
+			},
+		},
+		{
+			name: "Start of the SO not captured",
+			capture: func(segments []coreSegment) []coreSegment {
+				return segments[1:]
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mappings, segments := loadedObjectMemory(t, content, path, base, testCase.relocate)
+			if testCase.capture != nil {
+				segments = testCase.capture(segments)
+			}
+			core := buildCoreDump(t, append(mappings, dataMapping), append(segments, dataSegment))
+			loader, err := readCoreDump(bytes.NewReader(core))
+			require.NoError(t, err)
+
+			// Files which are not ELF objects are not listed
+			assert.Equal(t, []CoreObject{{Path: path, Start: base, Complete: testCase.expectedComplete}},
+				loader.Objects())
+			exported, err := loader.GetExportedSymbols(ObjInfo{Path: path})
+			require.NoError(t, err)
+			imported, err := loader.GetImportedSymbols(ObjInfo{Path: path})
+			require.NoError(t, err)
+			soname, err := loader.GetSoname(ObjInfo{Path: path})
+			require.NoError(t, err)
+			if testCase.expectedComplete {
+				assert.Equal(t, expected.Exported, exported)
+				assert.Equal(t, expected.Imported, imported)
+				assert.Equal(t, expected.Soname, soname)
+				// The indexes of the symbols are the ones of the table in the file
+				exportedInfo, err := loader.GetExportedSymbolsInfo(ObjInfo{Path: path})
+				require.NoError(t, err)
+				for sym, info := range expected.ExportedInfo {
+					assert.Equal(t, info.Index, exportedInfo[sym].Index, sym)
+				}
+			} else {
+				assert.Empty(t, exported)
+				assert.Empty(t, imported)
+			}
+			_, err = loader.GetExportedSymbols(ObjInfo{Path: dataMapping.path})
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("Not a core dump", func(t *testing.T) {
+		_, err := readCoreDump(bytes.NewReader(content))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,102 @@
+package sharedobjs
+
+import (
+	"bytes"
+	"context"
+	"debug/elf"
+	"io"
+	"strings"
+)
+
+// interpreterNames are the prefixes of the names of the known dynamic loaders:
+//   - glibc - ld-linux.so.2, ld-linux-x86-64.so.2, ld-linux-aarch64.so.1 etc., ld64.so.* on ppc64 and s390x
+//     and ld.so.1 on some other architectures.
+//   - musl - ld-musl-<arch>.so.1, which is a link to the musl libc (libc.musl-<arch>.so.1 in Alpine).
+var interpreterNames = []string{"ld-linux", "ld64.so.", "ld.so.", "ld-musl-", "libc.musl-"}
+
+// isInterpreterName checks if the given SO name is a name of a known dynamic loader
+func isInterpreterName(name string) bool {
+	for _, interpreterName := range interpreterNames {
+		if strings.HasPrefix(name, interpreterName) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectInterpreter checks if the ELF file is a dynamic loader. The dynamic loader is the interpreter of other
+// ELF files, so it has no interpreter (PT_INTERP segment) of its own, and is recognized by its DT_SONAME.
+// If the file has no DT_SONAME, the decision is left to its path, which is reported as undecided.
+func detectInterpreter(file *elf.File) (interpreter bool, decided bool) {
+	for _, prog := range file.Progs {
+		if prog.Type == elf.PT_INTERP {
+			return false, true
+		}
+	}
+	soname := readSoname(file)
+	if soname == "" {
+		return false, false
+	}
+	return isInterpreterName(soname), true
+}
+
+// maxInterpSize is the maximal size of the PT_INTERP segment read, which holds a path (bounded by PATH_MAX)
+const maxInterpSize = 4096
+
+// readInterp returns the path of the interpreter which the ELF file requests in its PT_INTERP segment, or an empty
+// string if it has none (as SOs usually don't)
+func readInterp(file *elf.File) string {
+	for _, prog := range file.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+		if prog.Filesz == 0 || prog.Filesz > maxInterpSize {
+			return ""
+		}
+		data := make([]byte, prog.Filesz)
+		if _, err := prog.ReadAt(data, 0); err != nil && err != io.EOF {
+			return ""
+		}
+		// The path is NUL terminated
+		if end := bytes.IndexByte(data, 0); end >= 0 {
+			data = data[:end]
+		}
+		return string(data)
+	}
+	return ""
+}
+
+// readSoname returns the DT_SONAME of the ELF file, or an empty string if it has none
+func readSoname(file *elf.File) string {
+	sonames, err := file.DynString(elf.DT_SONAME)
+	if err != nil || len(sonames) == 0 {
+		return ""
+	}
+	return sonames[0]
+}
+
+// GetSonameSymbols reads the DT_SONAME and the exported symbols of the SO in the given host path.
+// It is meant for reading known-good copies of SOs to compare the loaded SOs to. The result is not cached.
+func GetSonameSymbols(hostPath string) (string, map[string]bool, error) {
+	syms, err := loadSharedObjectDynamicSymbols(context.Background(), hostPath)
+	if err != nil {
+		return "", nil, err
+	}
+	return syms.Soname, syms.Exported, nil
+}
+
+// GetSonameWeakSymbols reads the DT_SONAME and the exported symbols with a weak binding of the SO in the given host
+// path. Like GetSonameSymbols, it is meant for reading known-good copies of SOs, and the result is not cached.
+func GetSonameWeakSymbols(hostPath string) (string, []string, error) {
+	syms, err := loadSharedObjectDynamicSymbols(context.Background(), hostPath)
+	if err != nil {
+		return "", nil, err
+	}
+	var weak []string
+	for name, info := range syms.ExportedInfo {
+		if info.Bind == elf.STB_WEAK {
+			weak = append(weak, name)
+		}
+	}
+	return syms.Soname, weak, nil
+}
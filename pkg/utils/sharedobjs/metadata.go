@@ -0,0 +1,118 @@
+package sharedobjs
+
+import (
+	"debug/elf"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// ObjMetadata is the metadata of a SO, read from its headers, dynamic segment and notes
+type ObjMetadata struct {
+	Soname       string          // The DT_SONAME of the SO, if it has one
+	BuildID      string          // The GNU build ID of the SO (hex encoded), if it has one
+	Notes        map[NoteID]bool // The IDs of the ELF notes the SO carries
+	Interpreter  bool            // Whether the SO is the dynamic loader
+	WXSegments   []Segment       // The loadable segments of the SO which are both writable and executable
+	DynamicTags  []elf.DynTag    // The distinct tags of the dynamic section, sorted by value
+	Constructors int             // The amount of entries of the init array (DT_INIT_ARRAY)
+	Interp       string          // The interpreter the ELF file requests (PT_INTERP), if it has one
+}
+
+// MetadataLoader is implemented by loaders which can read the metadata of a SO without parsing its symbols tables,
+// which is much cheaper for SOs with many symbols
+type MetadataLoader interface {
+	GetMetadata(info ObjInfo) (ObjMetadata, error)
+}
+
+// metadata returns the metadata of the SO the symbols were read from
+func (syms *dynamicSymbols) metadata() ObjMetadata {
+	return ObjMetadata{
+		Soname:       syms.Soname,
+		BuildID:      syms.BuildID,
+		Notes:        syms.Notes,
+		Interpreter:  syms.Interpreter,
+		WXSegments:   syms.WXSegments,
+		DynamicTags:  syms.DynamicTags,
+		Constructors: syms.Constructors,
+		Interp:       syms.Interp,
+	}
+}
+
+// readObjMetadata reads the metadata of the ELF file in the given path, leaving the symbols of the result empty, as
+// the symbols tables are not read
+func readObjMetadata(path string) (*dynamicSymbols, error) {
+	osFile, err := openRegularFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer osFile.Close()
+	file, err := elf.NewFile(osFile)
+	if err != nil {
+		return nil, err
+	}
+	syms := NewSOSymbols()
+	interpreter, decided := detectInterpreter(file)
+	syms.Interpreter = interpreter
+	syms.interpreterUndecided = !decided
+	syms.Soname = readSoname(file)
+	syms.Notes, syms.BuildID = readNotes(file)
+	syms.WXSegments = findWritableCodeSegments(file)
+	syms.DynamicTags, syms.Constructors = readDynamicTags(file)
+	syms.Interp = readInterp(file)
+	return &syms, nil
+}
+
+// GetMetadata try to get the metadata of the shared object from the symbols in lru, and if fails read it from the
+// headers of the ELF file, without parsing its symbols. The metadata read from the file is cached separately, so
+// it doesn't replace the symbols of the SO if they are requested later.
+func (soLoader *HostSymbolsLoader) GetMetadata(soInfo ObjInfo) (ObjMetadata, error) {
+	if atomic.LoadInt32(&soLoader.closed) != 0 {
+		return ObjMetadata{}, ErrLoaderClosed
+	}
+	keyInfo := soInfo
+	if soLoader.config.RemapID != nil {
+		keyInfo.Id = soLoader.config.RemapID(soInfo.Id)
+	}
+	if syms, ok := soLoader.soCache.Get(keyInfo.Id); ok {
+		return syms.metadata(), nil
+	}
+	if soLoader.soMetadata != nil {
+		if syms, ok := soLoader.soMetadata.Get(keyInfo.Id); ok {
+			return syms.metadata(), nil
+		}
+	}
+	syms, err := soLoader.readSOMetadata(soInfo)
+	if err != nil {
+		return ObjMetadata{}, err
+	}
+	if soLoader.soMetadata != nil {
+		soLoader.soMetadata.Add(keyInfo, syms)
+	}
+	return syms.metadata(), nil
+}
+
+// readSOMetadata reads the metadata of the SO from its file, without using the cache. The file is read like its
+// symbols are (e.g. from the mount namespace of the SO, and by the policy of its filesystem).
+func (soLoader *HostSymbolsLoader) readSOMetadata(soInfo ObjInfo) (*dynamicSymbols, error) {
+	readInfo, path, err := soLoader.resolveReadPath(soInfo)
+	if err != nil {
+		return nil, err
+	}
+	read := func() (*dynamicSymbols, error) {
+		return readObjMetadata(path)
+	}
+	var syms *dynamicSymbols
+	if soLoader.fsPolicies != nil {
+		syms, err = soLoader.applyFilesystemPolicy(readInfo, path, read)
+	} else {
+		syms, err = read()
+	}
+	if err != nil {
+		return nil, err
+	}
+	syms.loadedFrom = soInfo
+	if syms.interpreterUndecided {
+		syms.Interpreter = isInterpreterName(filepath.Base(soInfo.Path))
+	}
+	return syms, nil
+}
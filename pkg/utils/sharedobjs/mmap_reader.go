@@ -0,0 +1,108 @@
+package sharedobjs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+
+	"golang.org/x/sys/unix"
+)
+
+// errMappingFault is returned when the memory mapping of an SO couldn't be accessed while parsing it (e.g. the file
+// was truncated after it was mapped, so accessing the pages beyond its new end raised SIGBUS).
+var errMappingFault = errors.New("memory mapping fault")
+
+// mmapReader is an io.ReaderAt over a read-only memory mapping of a file.
+// The ELF parsing copies everything it reads, so nothing parsed references the mapping after it is closed.
+type mmapReader struct {
+	data []byte
+}
+
+// mmapFile maps the whole file to the memory for reading.
+// Only non-empty regular files can be mapped - other files (e.g. pipes or procfs files) should be read instead.
+func mmapFile(file *os.File, minSize int64) (*mmapReader, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("'%s' is not a regular file", file.Name())
+	}
+	size := info.Size()
+	if size == 0 || size < minSize || int64(int(size)) != size {
+		return nil, fmt.Errorf("size of '%s' (%d) can't be mapped", file.Name(), size)
+	}
+	data, err := unix.Mmap(int(file.Fd()), 0, int(size), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	return &mmapReader{data: data}, nil
+}
+
+func (reader *mmapReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	if off >= int64(len(reader.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, reader.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close unmaps the file. The reader must not be used after it is closed.
+func (reader *mmapReader) Close() error {
+	if reader.data == nil {
+		return nil
+	}
+	err := unix.Munmap(reader.data)
+	reader.data = nil
+	return err
+}
+
+// readMappedDynamicSymbols parses the symbols of an SO from its memory mapping.
+// Faults on accessing the mapping are returned as errMappingFault instead of crashing the process.
+func readMappedDynamicSymbols(ctx context.Context, reader *mmapReader, opts readOptions) (syms *dynamicSymbols,
+	err error) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if r := recover(); r != nil {
+			// Only memory faults are expected - any other panic is a bug which shouldn't be hidden
+			if _, ok := r.(interface{ Addr() uintptr }); !ok {
+				panic(r)
+			}
+			syms, err = nil, errMappingFault
+		}
+	}()
+	return readFirstDynamicSymbols(readerWithContext(ctx, reader), opts)
+}
+
+// loadSharedObjectDynamicSymbolsMmap returns a loading function which reads SOs of at least the given size through
+// a memory mapping. Smaller SOs, and SOs which can't be mapped or faulted while accessing their mapping, are read
+// using read calls. The SOs are read with the given reading options.
+func loadSharedObjectDynamicSymbolsMmap(minSize int64, opts readOptions) func(ctx context.Context,
+	path string) (*dynamicSymbols, error) {
+	return func(ctx context.Context, path string) (*dynamicSymbols, error) {
+		file, err := openRegularFile(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader, err := mmapFile(file, minSize)
+		if err != nil {
+			return readFirstDynamicSymbols(readerWithContext(ctx, file), opts)
+		}
+		syms, err := readMappedDynamicSymbols(ctx, reader, opts)
+		_ = reader.Close()
+		if errors.Is(err, errMappingFault) {
+			return readFirstDynamicSymbols(readerWithContext(ctx, file), opts)
+		}
+		return syms, err
+	}
+}
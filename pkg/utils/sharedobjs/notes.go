@@ -0,0 +1,107 @@
+package sharedobjs
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"strings"
+)
+
+// NoteID identifies an ELF note by its owner name (e.g. "GNU") and its type
+type NoteID struct {
+	Name string
+	Type uint32
+}
+
+// NoteChecker is implemented by loaders which can check if a SO carries an ELF note
+type NoteChecker interface {
+	HasNote(info ObjInfo, note NoteID) (bool, error)
+}
+
+// maxNotesSize is the maximal size of a notes segment or section which is read, so malformed SOs can't cause
+// reading large parts of them. Legitimate notes (build ID, ABI tag, properties) are much smaller.
+const maxNotesSize = 64 * 1024
+
+// noteHeaderSize is the size of the namesz, descsz and type fields of a note header
+const noteHeaderSize = 12
+
+// gnuBuildIDNote is the note of the build ID of an ELF file generated by the linker (NT_GNU_BUILD_ID)
+var gnuBuildIDNote = NoteID{Name: "GNU", Type: 3}
+
+// readNotes returns the IDs of the ELF notes of the file, and the build ID of the file (hex encoded) if it has one
+func readNotes(file *elf.File) (map[NoteID]bool, string) {
+	notes := make(map[NoteID]bool)
+	buildID := ""
+	visitNotes(file, func(note NoteID, desc []byte) {
+		notes[note] = true
+		if note == gnuBuildIDNote && len(desc) > 0 {
+			buildID = hex.EncodeToString(desc)
+		}
+	})
+	return notes, buildID
+}
+
+// visitNotes calls the visitor with each of the ELF notes of the file and its description.
+// The notes are read from the PT_NOTE segments, which are kept when the sections headers are stripped, or from the
+// SHT_NOTE sections if the file has no PT_NOTE segment. Malformed notes are ignored.
+func visitNotes(file *elf.File, visitor func(note NoteID, desc []byte)) {
+	found := false
+	for _, prog := range file.Progs {
+		if prog.Type != elf.PT_NOTE {
+			continue
+		}
+		found = true
+		parseNotes(prog.Open(), prog.Filesz, prog.Align, file.ByteOrder, maxNotesSize, visitor)
+	}
+	if found {
+		return
+	}
+	for _, section := range file.Sections {
+		if section.Type != elf.SHT_NOTE {
+			continue
+		}
+		parseNotes(section.Open(), section.Size, section.Addralign, file.ByteOrder, maxNotesSize, visitor)
+	}
+}
+
+// parseNotes calls the visitor with each of the notes in the given notes segment or section, if it is not larger than
+// the given maximal size
+func parseNotes(reader io.Reader, size uint64, align uint64, order binary.ByteOrder, maxSize uint64,
+	visitor func(note NoteID, desc []byte)) {
+	if size > maxSize {
+		return
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return
+	}
+	// Notes are aligned to 4 bytes, except for 8 bytes aligned segments (e.g. of GNU properties)
+	if align != 8 {
+		align = 4
+	}
+	for len(data) >= noteHeaderSize {
+		nameSize := uint64(order.Uint32(data[0:4]))
+		descSize := uint64(order.Uint32(data[4:8]))
+		noteType := order.Uint32(data[8:12])
+		data = data[noteHeaderSize:]
+		nameEnd := alignUp(nameSize, align)
+		if nameEnd > uint64(len(data)) {
+			return
+		}
+		// The name is null terminated, and the terminator is counted in its size
+		name := strings.TrimRight(string(data[:nameSize]), "\x00")
+		descEnd := nameEnd + alignUp(descSize, align)
+		if descEnd > uint64(len(data)) {
+			visitor(NoteID{Name: name, Type: noteType}, nil)
+			return
+		}
+		visitor(NoteID{Name: name, Type: noteType}, data[nameEnd:nameEnd+descSize])
+		data = data[descEnd:]
+	}
+}
+
+// alignUp rounds the size up to a multiple of the alignment
+func alignUp(size uint64, align uint64) uint64 {
+	return (size + align - 1) &^ (align - 1)
+}
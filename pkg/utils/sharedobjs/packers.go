@@ -0,0 +1,47 @@
+package sharedobjs
+
+import (
+	"bytes"
+	"debug/elf"
+	"io"
+)
+
+// packerSignature describes how an ELF file packed by some packer is recognized
+type packerSignature struct {
+	name     string
+	magic    []byte   // Magic written by the packer to the beginning of the file
+	sections []string // Names of sections created by the packer
+}
+
+// packerSignatures are the recognized packers signatures:
+//   - UPX - the "UPX!" magic in the packer header following the program headers, or the UPX0/UPX1 sections
+//     which are left when the sections headers are not stripped.
+var packerSignatures = []packerSignature{
+	{
+		name:     "UPX",
+		magic:    []byte("UPX!"),
+		sections: []string{"UPX0", "UPX1"},
+	},
+}
+
+// packerHeaderSize is the size of the beginning of the file in which the packers magic are searched
+const packerHeaderSize = 4096
+
+// detectPacker returns the name of the packer which packed the ELF file, or an empty string if it is not packed
+// by any recognized packer.
+func detectPacker(reader io.ReaderAt, file *elf.File) string {
+	header := make([]byte, packerHeaderSize)
+	n, _ := reader.ReadAt(header, 0)
+	header = header[:n]
+	for _, signature := range packerSignatures {
+		if bytes.Contains(header, signature.magic) {
+			return signature.name
+		}
+		for _, section := range signature.sections {
+			if file.Section(section) != nil {
+				return signature.name
+			}
+		}
+	}
+	return ""
+}
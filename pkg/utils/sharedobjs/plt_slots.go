@@ -0,0 +1,72 @@
+package sharedobjs
+
+import (
+	"debug/elf"
+)
+
+// setPLTSlots sets the PLT slots of the imported symbols, according to the jump slot relocations of the SO.
+// The symbols should be the dynamic symbols of the file, as returned by elf.File.DynamicSymbols.
+// Malformed relocations are ignored, as the PLT information is not essential.
+func setPLTSlots(objSymbols *dynamicSymbols, file *elf.File, symbols []elf.Symbol) {
+	for _, slot := range readJumpSlots(file) {
+		// DynamicSymbols omits the null symbol in index 0 of the symbols table
+		if slot.symIndex == 0 || int(slot.symIndex) > len(symbols) {
+			continue
+		}
+		name := symbols[slot.symIndex-1].Name
+		info, ok := objSymbols.ImportedInfo[name]
+		if !ok || info.HasPLTSlot {
+			continue
+		}
+		info.HasPLTSlot = true
+		info.PLTIndex = slot.index
+		info.GOTOffset = slot.gotOffset
+		objSymbols.ImportedInfo[name] = info
+	}
+}
+
+type jumpSlot struct {
+	index     int
+	gotOffset uint64
+	symIndex  uint32
+}
+
+// readJumpSlots reads the relocations of the PLT section of the file, which are the lazily resolved slots
+func readJumpSlots(file *elf.File) []jumpSlot {
+	var slots []jumpSlot
+	switch file.Class {
+	case elf.ELFCLASS64:
+		section := file.Section(".rela.plt")
+		if section == nil || section.Type != elf.SHT_RELA {
+			return nil
+		}
+		data, err := section.Data()
+		if err != nil {
+			return nil
+		}
+		const entrySize = 24 // Size of Rela64
+		for i := 0; (i+1)*entrySize <= len(data); i++ {
+			entry := data[i*entrySize : (i+1)*entrySize]
+			offset := file.ByteOrder.Uint64(entry[0:8])
+			info := file.ByteOrder.Uint64(entry[8:16])
+			slots = append(slots, jumpSlot{index: i, gotOffset: offset, symIndex: elf.R_SYM64(info)})
+		}
+	case elf.ELFCLASS32:
+		section := file.Section(".rel.plt")
+		if section == nil || section.Type != elf.SHT_REL {
+			return nil
+		}
+		data, err := section.Data()
+		if err != nil {
+			return nil
+		}
+		const entrySize = 8 // Size of Rel32
+		for i := 0; (i+1)*entrySize <= len(data); i++ {
+			entry := data[i*entrySize : (i+1)*entrySize]
+			offset := file.ByteOrder.Uint32(entry[0:4])
+			info := file.ByteOrder.Uint32(entry[4:8])
+			slots = append(slots, jumpSlot{index: i, gotOffset: uint64(offset), symIndex: elf.R_SYM32(info)})
+		}
+	}
+	return slots
+}
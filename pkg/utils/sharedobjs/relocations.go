@@ -0,0 +1,78 @@
+package sharedobjs
+
+import (
+	"bufio"
+	"debug/elf"
+	"io"
+	"strconv"
+)
+
+// RelocationsCounter is implemented by loaders which can count the dynamic relocations of a SO by their type (see
+// HostSymbolsLoaderConfig.CountRelocations). Unusual relocation setups are abused to run code early or to redirect
+// the GOT, e.g. many R_X86_64_IRELATIVE relocations, whose resolvers run before the constructors of the SO.
+type RelocationsCounter interface {
+	GetRelocationCounts(info ObjInfo) (map[string]int, error)
+}
+
+// relocationsBufferSize is the size of the buffer which the relocation sections are read through
+const relocationsBufferSize = 64 * 1024
+
+// countRelocations returns the amount of relocations of each type (by its name, e.g. "R_X86_64_COPY") in the dynamic
+// relocation sections of the ELF file: its loaded SHT_REL and SHT_RELA sections (e.g. .rela.dyn and .rela.plt).
+// Relocations packed in SHT_RELR sections are all relative, so they are not counted, and neither are relocations of
+// files with no section headers.
+func countRelocations(file *elf.File) map[string]int {
+	counts := make(map[string]int)
+	for _, section := range file.Sections {
+		if (section.Type != elf.SHT_REL && section.Type != elf.SHT_RELA) || section.Flags&elf.SHF_ALLOC == 0 {
+			continue
+		}
+		// The offset is followed by the info in both kinds of entries, and only RELA entries have an addend
+		entrySize, infoOffset := 16, 8
+		if file.Class == elf.ELFCLASS32 {
+			entrySize, infoOffset = 8, 4
+		}
+		if section.Type == elf.SHT_RELA {
+			entrySize += entrySize / 2
+		}
+		reader := bufio.NewReaderSize(section.Open(), relocationsBufferSize)
+		entry := make([]byte, entrySize)
+		for {
+			if _, err := io.ReadFull(reader, entry); err != nil {
+				break
+			}
+			var relType uint32
+			if file.Class == elf.ELFCLASS32 {
+				relType = elf.R_TYPE32(file.ByteOrder.Uint32(entry[infoOffset:]))
+			} else {
+				relType = elf.R_TYPE64(file.ByteOrder.Uint64(entry[infoOffset:]))
+			}
+			counts[relocationTypeName(file.Machine, relType)]++
+		}
+	}
+	return counts
+}
+
+// relocationTypeName returns the name of the relocation type of the given machine. Types of machines with no known
+// relocations are named by their number.
+func relocationTypeName(machine elf.Machine, relType uint32) string {
+	switch machine {
+	case elf.EM_X86_64:
+		return elf.R_X86_64(relType).String()
+	case elf.EM_386:
+		return elf.R_386(relType).String()
+	case elf.EM_AARCH64:
+		return elf.R_AARCH64(relType).String()
+	case elf.EM_ARM:
+		return elf.R_ARM(relType).String()
+	case elf.EM_PPC64:
+		return elf.R_PPC64(relType).String()
+	case elf.EM_RISCV:
+		return elf.R_RISCV(relType).String()
+	case elf.EM_S390:
+		return elf.R_390(relType).String()
+	case elf.EM_LOONGARCH:
+		return elf.R_LARCH(relType).String()
+	}
+	return strconv.FormatUint(uint64(relType), 10)
+}
@@ -0,0 +1,175 @@
+package sharedobjs
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrFilesystemSkipped is returned for SOs residing in a filesystem whose policy is to skip its SOs
+var ErrFilesystemSkipped = errors.New("SO filesystem is skipped")
+
+// ErrFilesystemTimeout is returned when reading a SO took longer than the timeout of the policy of its filesystem
+var ErrFilesystemTimeout = errors.New("reading SO from its filesystem timed out")
+
+// FilesystemPolicy is how SOs residing in filesystems of some type are read
+type FilesystemPolicy struct {
+	Skip bool // Don't read the SOs, and fail with ErrFilesystemSkipped
+	// Maximal duration of reading a SO, after which reading it fails with ErrFilesystemTimeout. If 0, there is no
+	// timeout.
+	Timeout time.Duration
+}
+
+// The filesystems types (the f_type of statfs, from linux/magic.h) of network and FUSE filesystems
+const (
+	nfsSuperMagic    = 0x6969
+	smbSuperMagic    = 0x517b
+	cifsSuperMagic   = 0xff534d42
+	smb2SuperMagic   = 0xfe534d42
+	cephSuperMagic   = 0x00c36400
+	v9fsMagic        = 0x01021997
+	afsSuperMagic    = 0x5346414f
+	codaSuperMagic   = 0x73757245
+	fuseSuperMagic   = 0x65735546
+	lustreSuperMagic = 0x0bd00bd0
+)
+
+// RemoteFilesystemTypes are the filesystems types (the f_type of statfs) of network and FUSE filesystems, whose
+// reads may hang or be very slow
+var RemoteFilesystemTypes = []uint32{
+	nfsSuperMagic,
+	smbSuperMagic,
+	cifsSuperMagic,
+	smb2SuperMagic,
+	cephSuperMagic,
+	v9fsMagic,
+	afsSuperMagic,
+	codaSuperMagic,
+	fuseSuperMagic,
+	lustreSuperMagic,
+}
+
+// RemoteFilesystemsPolicies returns filesystems policies (see HostSymbolsLoaderConfig.FilesystemPolicies) which
+// apply the given policy to all of the RemoteFilesystemTypes
+func RemoteFilesystemsPolicies(policy FilesystemPolicy) map[uint32]FilesystemPolicy {
+	policies := make(map[uint32]FilesystemPolicy, len(RemoteFilesystemTypes))
+	for _, fsType := range RemoteFilesystemTypes {
+		policies[fsType] = policy
+	}
+	return policies
+}
+
+// statfsType returns the filesystem type of the file in the given path
+func statfsType(path string) (uint32, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	// The type is a 32 bits magic number, which is signed on some architectures
+	return uint32(stat.Type), nil
+}
+
+// filesystemsPolicy applies the configured filesystems policies to the reads of SOs.
+// The type of the filesystem of each device is detected once, so a filesystem which hangs stalls a single statfs.
+// Reads which timed out are not interrupted, and until they complete, reads of the same path fail immediately,
+// so a hanging SO holds a single read rather than one per load. It is safe for concurrent use.
+type filesystemsPolicy struct {
+	policies map[uint32]FilesystemPolicy
+	statfs   func(path string) (uint32, error)
+	mutex    sync.Mutex
+	types    map[uint32]uint32 // The filesystem type of each device
+	pending  map[string]bool   // The paths whose reads timed out and are still in progress
+}
+
+func newFilesystemsPolicy(policies map[uint32]FilesystemPolicy) *filesystemsPolicy {
+	return &filesystemsPolicy{
+		policies: policies,
+		statfs:   statfsType,
+		types:    make(map[uint32]uint32),
+		pending:  make(map[string]bool),
+	}
+}
+
+// policyOf returns the policy of the filesystem of the SO, and whether it has a policy
+func (fsPolicy *filesystemsPolicy) policyOf(soInfo ObjInfo, path string) (FilesystemPolicy, bool) {
+	fsPolicy.mutex.Lock()
+	fsType, ok := fsPolicy.types[soInfo.Id.Device]
+	fsPolicy.mutex.Unlock()
+	if !ok {
+		var err error
+		fsType, err = fsPolicy.statfs(path)
+		if err != nil {
+			// The SO is read normally, and failures to read it are returned when it is read
+			return FilesystemPolicy{}, false
+		}
+		// Unknown devices (e.g. of SOs with no identity) are not cached by their device
+		if soInfo.Id.Device != 0 {
+			fsPolicy.mutex.Lock()
+			fsPolicy.types[soInfo.Id.Device] = fsType
+			fsPolicy.mutex.Unlock()
+		}
+	}
+	policy, ok := fsPolicy.policies[fsType]
+	return policy, ok
+}
+
+// symbolsRead is the result of reading the symbols of a SO
+type symbolsRead struct {
+	syms *dynamicSymbols
+	err  error
+}
+
+// applyFilesystemPolicy reads the symbols of the SO with the given function, according to the policy of its
+// filesystem, and counts the skipped and timed out reads
+func (soLoader *HostSymbolsLoader) applyFilesystemPolicy(soInfo ObjInfo, path string,
+	read func() (*dynamicSymbols, error)) (*dynamicSymbols, error) {
+	fsPolicy := soLoader.fsPolicies
+	policy, ok := fsPolicy.policyOf(soInfo, path)
+	if !ok {
+		return read()
+	}
+	if policy.Skip {
+		soLoader.stats.FilesystemSkips.Increment()
+		return nil, ErrFilesystemSkipped
+	}
+	if policy.Timeout <= 0 {
+		return read()
+	}
+
+	fsPolicy.mutex.Lock()
+	if fsPolicy.pending[path] {
+		fsPolicy.mutex.Unlock()
+		soLoader.stats.FilesystemTimeouts.Increment()
+		return nil, ErrFilesystemTimeout
+	}
+	fsPolicy.mutex.Unlock()
+
+	result := make(chan symbolsRead, 1)
+	go func() {
+		syms, err := read()
+		// The result is sent with the lock held, so a read either timed out before it completed, or its result is
+		// received
+		fsPolicy.mutex.Lock()
+		defer fsPolicy.mutex.Unlock()
+		delete(fsPolicy.pending, path)
+		result <- symbolsRead{syms: syms, err: err}
+	}()
+
+	select {
+	case completed := <-result:
+		return completed.syms, completed.err
+	case <-soLoader.timeSource().After(policy.Timeout):
+	}
+	fsPolicy.mutex.Lock()
+	defer fsPolicy.mutex.Unlock()
+	select {
+	case completed := <-result:
+		return completed.syms, completed.err
+	default:
+	}
+	fsPolicy.pending[path] = true
+	soLoader.stats.FilesystemTimeouts.Increment()
+	return nil, ErrFilesystemTimeout
+}
@@ -0,0 +1,88 @@
+package sharedobjs
+
+import (
+	"debug/elf"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// platformNames are the values of the $PLATFORM token (the AT_PLATFORM of the process) for each architecture.
+// Architectures whose platform depends on the CPU model (e.g. ARM and PowerPC) are not included.
+var platformNames = map[elf.Machine]string{
+	elf.EM_X86_64:  "x86_64",
+	elf.EM_386:     "i686",
+	elf.EM_AARCH64: "aarch64",
+}
+
+// ExpandRunpath splits the given DT_RUNPATH (or DT_RPATH) of a SO to its search directories, expanding the dynamic
+// string tokens in them like the dynamic loader:
+//   - $ORIGIN - the directory of the SO, given by its path.
+//   - $LIB - lib64 for 64-bit SOs, and lib for 32-bit ones.
+//   - $PLATFORM - the platform of the SO architecture (e.g. x86_64).
+//
+// Each token can also be given as ${TOKEN}. Directories with unknown tokens are dropped, as the dynamic loader
+// ignores them, and so are empty directories, which depend on the working directory of the loading process.
+// The directories are relative to the mount namespace the SO path is in, so file access on the host should be done
+// through the path resolver of the SO mount namespace.
+func ExpandRunpath(runpath string, soPath string, class elf.Class, machine elf.Machine) []string {
+	lib := "lib"
+	if class == elf.ELFCLASS64 {
+		lib = "lib64"
+	}
+	tokens := map[string]string{
+		"ORIGIN":   filepath.Dir(soPath),
+		"LIB":      lib,
+		"PLATFORM": platformNames[machine],
+	}
+	var dirs []string
+	for _, dir := range strings.Split(runpath, ":") {
+		if dir == "" {
+			continue
+		}
+		valid := true
+		expanded := os.Expand(dir, func(token string) string {
+			value, ok := tokens[token]
+			if !ok || value == "" {
+				valid = false
+			}
+			return value
+		})
+		if valid {
+			dirs = append(dirs, filepath.Clean(expanded))
+		}
+	}
+	return dirs
+}
+
+// readRunpath returns the search directories of the ELF file, with their tokens expanded relative to the given path.
+// DT_RUNPATH is used if it exists, as the dynamic loader ignores DT_RPATH in this case.
+func readRunpath(file *elf.File, soPath string) ([]string, error) {
+	runpaths, err := file.DynString(elf.DT_RUNPATH)
+	if err != nil {
+		return nil, err
+	}
+	if len(runpaths) == 0 {
+		runpaths, err = file.DynString(elf.DT_RPATH)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var dirs []string
+	for _, runpath := range runpaths {
+		dirs = append(dirs, ExpandRunpath(runpath, soPath, file.Class, file.Machine)...)
+	}
+	return dirs, nil
+}
+
+// GetRunpath reads the search directories of the SO in the given path, as the dynamic loader would use them to
+// resolve its needed libraries. The path should be accessible from the current mount namespace, while the
+// directories are expanded relative to the given SO path in its own mount namespace.
+func GetRunpath(hostPath string, soPath string) ([]string, error) {
+	file, err := elf.Open(hostPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readRunpath(file, soPath)
+}
@@ -0,0 +1,28 @@
+package sharedobjs
+
+import (
+	"debug/elf"
+)
+
+// Segment is a loadable segment of a SO, identified by its offset in the file
+type Segment struct {
+	Flags  elf.ProgFlag
+	Offset uint64
+}
+
+// WritableCodeDetector is implemented by loaders which can find the segments of a SO which are both writable and
+// executable (violating W^X), which legitimate SOs don't have.
+type WritableCodeDetector interface {
+	GetWritableCodeSegments(info ObjInfo) ([]Segment, error)
+}
+
+// findWritableCodeSegments returns the loadable segments of the ELF file which are both writable and executable
+func findWritableCodeSegments(file *elf.File) []Segment {
+	var segments []Segment
+	for _, prog := range file.Progs {
+		if prog.Type == elf.PT_LOAD && prog.Flags&elf.PF_W != 0 && prog.Flags&elf.PF_X != 0 {
+			segments = append(segments, Segment{Flags: prog.Flags, Offset: prog.Off})
+		}
+	}
+	return segments
+}
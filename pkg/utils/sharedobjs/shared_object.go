@@ -1,5 +1,11 @@
 package sharedobjs
 
+import (
+	"context"
+	"debug/elf"
+	"time"
+)
+
 // ObjID is the unique identification of a SO in the system
 type ObjID struct {
 	Inode  uint64
@@ -12,6 +18,8 @@ type ObjInfo struct {
 	Id      ObjID
 	Path    string
 	MountNS int
+	Pid     int  // Host PID of a process which loaded the SO
+	Deleted bool // The SO file was deleted after it was mapped
 }
 
 type DynamicSymbolsLoader interface {
@@ -20,14 +28,183 @@ type DynamicSymbolsLoader interface {
 	GetImportedSymbols(info ObjInfo) (map[string]bool, error)
 }
 
+// SymbolInfo is the information extracted from the ELF file about a dynamic symbol
+type SymbolInfo struct {
+	Name       string
+	Bind       elf.SymBind
+	Type       elf.SymType
+	Visibility elf.SymVis
+	Section    elf.SectionIndex
+	// The index of the symbol in the dynamic symbols table (.dynsym), starting at 1 as the table starts with the null
+	// symbol. The order of the table is part of the ABI of some libraries, e.g. ones built to match a vendor's layout.
+	Index int
+	// The size of the symbol as recorded in its symbols table entry, e.g. the size of the code of a function. Hollow
+	// stubs replacing a function have a tiny size, and hand written assembly often has no size (0).
+	Size uint64
+	// The versions the symbol is defined in (its version definitions, e.g. GLIBC_2.14), in the order of the table,
+	// if the SO versions its symbols. A symbol may be defined in several versions, e.g. a compatibility version and
+	// its default version. The versions are read from the file the loader reads, which for SOs loaded in containers
+	// is the file in the mount namespace of the loading process, so they are the versions of the container's build
+	// of the library. Symbols read from a truncated table or from a core dump have no versions.
+	Versions []string
+	// The name of the section the symbol resides in, and whether it is executable. Available only for symbols
+	// read from a file with sections headers.
+	SectionName       string
+	ExecutableSection bool
+	// The name of the symbol source which supplied the symbol (see SymbolSource), or empty for symbols read from the
+	// dynamic symbols table
+	Source string
+}
+
+// SymbolsInfoLoader is implemented by loaders which can supply the information of each symbol
+// in addition to its name.
+type SymbolsInfoLoader interface {
+	GetExportedSymbolsInfo(info ObjInfo) (map[string]SymbolInfo, error)
+}
+
+// FuncRange is the addresses range of a function exported by a SO, as recorded in its symbols table.
+// The address is the link time address of the function (the symbol value), which for SOs and position independent
+// executables is relative to the start of the object, so it should be rebased (see Rebase) to match runtime IPs.
+type FuncRange struct {
+	Name string
+	Addr uint64
+	Size uint64 // May be 0 for functions whose size isn't recorded (e.g. written in assembly)
+}
+
+// FunctionRangesLoader is implemented by loaders which can supply the addresses ranges of the exported functions
+// of a SO, sorted by their address, e.g. for resolving an instruction pointer to the function containing it.
+type FunctionRangesLoader interface {
+	GetFunctionRanges(info ObjInfo) ([]FuncRange, error)
+}
+
+// Rebase returns the ranges at the addresses the functions are mapped at in a process, given the load bias of the
+// mapping of the SO: the difference between the runtime and link time addresses of the SO (e.g. the base address it
+// was mapped at by ASLR, for SOs linked at address 0, as GCC links them). The given ranges are not modified, and
+// the rebased ranges keep their order.
+func Rebase(ranges []FuncRange, loadBias uint64) []FuncRange {
+	rebased := make([]FuncRange, len(ranges))
+	for i, funcRange := range ranges {
+		rebased[i] = funcRange
+		rebased[i].Addr += loadBias
+	}
+	return rebased
+}
+
+// ImportedSymbolInfo is the information extracted from the ELF file about an imported dynamic symbol
+type ImportedSymbolInfo struct {
+	Name string
+	// The library and the version the symbol is expected to be resolved from (its version needed entry, e.g.
+	// libc.so.6 and GLIBC_2.2.5), if the import is versioned
+	Library string
+	Version string
+	// The PLT slot through which calls to the symbol are resolved lazily at runtime, if the symbol has one.
+	// PLTIndex is the index of the slot relocation, and GOTOffset is the address of the GOT entry it patches.
+	HasPLTSlot bool
+	PLTIndex   int
+	GOTOffset  uint64
+}
+
+// ImportsInfoLoader is implemented by loaders which can supply the information of each imported symbol
+// in addition to its name.
+type ImportsInfoLoader interface {
+	GetImportedSymbolsInfo(info ObjInfo) (map[string]ImportedSymbolInfo, error)
+}
+
+// PackerDetector is implemented by loaders which can detect that a SO is packed (e.g. by UPX), in which
+// case its symbols are meaningless until it is unpacked.
+type PackerDetector interface {
+	GetPacker(info ObjInfo) (string, error)
+}
+
+// TruncationDetector is implemented by loaders which can cap the amount of symbols read from each SO, and detect
+// that only the first symbols of the symbols table of a SO were read (see HostSymbolsLoaderConfig.MaxSymbols)
+type TruncationDetector interface {
+	IsTruncated(info ObjInfo) (bool, error)
+}
+
+// InterpreterDetector is implemented by loaders which can detect that a SO is the dynamic loader (e.g. ld-linux.so)
+type InterpreterDetector interface {
+	IsInterpreter(info ObjInfo) (bool, error)
+}
+
+// InterpreterLoader is implemented by loaders which can read the interpreter which an ELF file requests (its
+// PT_INTERP segment, e.g. /lib64/ld-linux-x86-64.so.2 for executables)
+type InterpreterLoader interface {
+	GetInterpreter(info ObjInfo) (string, error)
+}
+
+// SonameLoader is implemented by loaders which can read the DT_SONAME of a SO
+type SonameLoader interface {
+	GetSoname(info ObjInfo) (string, error)
+}
+
+// BuildIDLoader is implemented by loaders which can read the GNU build ID (NT_GNU_BUILD_ID note) of a SO
+type BuildIDLoader interface {
+	GetBuildID(info ObjInfo) (string, error)
+}
+
+// ExtractionTimer is implemented by loaders which measure the time it takes to extract the symbols of each SO
+type ExtractionTimer interface {
+	GetExtractionDuration(info ObjInfo) (time.Duration, bool, error)
+}
+
+// ContextLoader is implemented by loaders which can read the symbols of a SO under a context, into the cache
+// which their other methods read
+type ContextLoader interface {
+	LoadContext(ctx context.Context, info ObjInfo) error
+}
+
+// UnreadableDetector is implemented by loaders which classify the SOs they can't read because of missing
+// permissions once, and return the cached classification instead of reading such SOs again
+type UnreadableDetector interface {
+	// GetPermissionError returns the error of reading the SO if it failed because of missing permissions, and nil
+	// if the SO can be read or failed to be read for another reason
+	GetPermissionError(info ObjInfo) error
+}
+
+// ExportedSymbolChecker is implemented by loaders which can check if a single symbol is exported by a SO,
+// without copying all of its symbols.
+type ExportedSymbolChecker interface {
+	IsSymbolExported(info ObjInfo, symbol string) (bool, error)
+}
+
+// samePath check if the given SO is located in the same path, in the same mount namespace
+func (info ObjInfo) samePath(other ObjInfo) bool {
+	return info.Path == other.Path && info.MountNS == other.MountNS && info.Deleted == other.Deleted
+}
+
 type dynamicSymbols struct {
-	Exported map[string]bool
-	Imported map[string]bool
+	Exported     map[string]bool
+	Imported     map[string]bool
+	ExportedInfo map[string]SymbolInfo
+	ImportedInfo map[string]ImportedSymbolInfo
+	Packer       string          // The name of the packer which packed the SO, if it is packed
+	Interpreter  bool            // Whether the SO is the dynamic loader
+	Soname       string          // The DT_SONAME of the SO, if it has one
+	Notes        map[NoteID]bool // The IDs of the ELF notes the SO carries
+	BuildID      string          // The GNU build ID of the SO (hex encoded), if it has one
+	WXSegments   []Segment       // The loadable segments of the SO which are both writable and executable
+	FuncRanges   []FuncRange     // The ranges of the exported functions, sorted by address
+	DynamicTags  []elf.DynTag    // The distinct tags of the dynamic section, sorted by value
+	Constructors int             // The amount of entries of the init array (DT_INIT_ARRAY)
+	Interp       string          // The interpreter the ELF file requests (PT_INTERP), if it has one
+	Truncated    bool            // Only the first symbols of the symbols table were read
+	CodeEntropy  float64         // The highest entropy of the code sections, if it was measured
+	Relocations  map[string]int  // The amount of dynamic relocations of each type, if they were counted
+	loadedFrom   ObjInfo         // The SO the symbols were read from
+	checksum     []byte          // Checksum of the symbols, calculated only if needed
+	// The SO has no DT_SONAME, so whether it is the dynamic loader is decided by its path
+	interpreterUndecided bool
+	// The time it took to extract the symbols, and whether it was already requested (set atomically)
+	extractionDuration time.Duration
+	durationTaken      int32
 }
 
 func NewSOSymbols() dynamicSymbols {
 	return dynamicSymbols{
-		Exported: make(map[string]bool),
-		Imported: make(map[string]bool),
+		Exported:     make(map[string]bool),
+		Imported:     make(map[string]bool),
+		ExportedInfo: make(map[string]SymbolInfo),
+		ImportedInfo: make(map[string]ImportedSymbolInfo),
 	}
 }
@@ -0,0 +1,191 @@
+package sharedobjs
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// SymbolServer fetches symbol-bearing copies of SOs by their build ID
+type SymbolServer interface {
+	FetchObject(buildID string) ([]byte, error)
+}
+
+// DefaultSymbolServerTimeout is the default timeout of a request to the symbol server
+const DefaultSymbolServerTimeout = 5 * time.Second
+
+// maxFetchedObjectSize is the maximal size of an object fetched from the symbol server, so a misbehaving server
+// can't make tracee read unbounded content to the memory
+const maxFetchedObjectSize = 256 * 1024 * 1024
+
+// fetchFailureTTL is the time a failure to fetch the object of a build ID is cached for, before the symbol server
+// is requested again, so a transient failure (e.g. of the network) doesn't disable the fetching for the whole run
+const fetchFailureTTL = time.Minute
+
+// noSymbolsError is returned when an SO has no dynamic symbols table (e.g. its sections headers were stripped).
+// It keeps the metadata read from the SO, including its build ID, so its symbols can be fetched from a symbol server.
+type noSymbolsError struct {
+	local *dynamicSymbols // The metadata of the SO, with no symbols
+}
+
+func (err *noSymbolsError) Error() string {
+	return elf.ErrNoSymbols.Error()
+}
+
+func (err *noSymbolsError) Unwrap() error {
+	return elf.ErrNoSymbols
+}
+
+// DebuginfodClient is a SymbolServer fetching the objects from a debuginfod server
+type DebuginfodClient struct {
+	url    string
+	client *http.Client
+}
+
+// InitDebuginfodClient creates a client of the debuginfod server in the given URL.
+// Each request to the server is canceled if it takes longer than the given timeout.
+func InitDebuginfodClient(url string, timeout time.Duration) *DebuginfodClient {
+	return &DebuginfodClient{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// FetchObject fetches the executable (the object with its symbols) of the given build ID from the server
+func (client *DebuginfodClient) FetchObject(buildID string) ([]byte, error) {
+	if _, err := hex.DecodeString(buildID); err != nil || buildID == "" {
+		return nil, fmt.Errorf("invalid build ID '%s'", buildID)
+	}
+	resp, err := client.client.Get(fmt.Sprintf("%s/buildid/%s/executable", client.url, buildID))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("symbol server returned status %d for build ID '%s'", resp.StatusCode, buildID)
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchedObjectSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxFetchedObjectSize {
+		return nil, fmt.Errorf("object of build ID '%s' is larger than %d bytes", buildID, maxFetchedObjectSize)
+	}
+	return content, nil
+}
+
+// fetchedSymbolsCache is a lru of the symbols fetched from the symbol server, keyed by their build ID.
+// Failures to fetch symbols are cached as well (as nil symbols) for fetchFailureTTL, so unavailable objects are not
+// requested on every load of the SO. The cache is safe for concurrent use.
+type fetchedSymbolsCache struct {
+	lru   *simplelru.LRU
+	mutex sync.Mutex
+}
+
+// fetchedSymbols is the result of fetching the object of a build ID
+type fetchedSymbols struct {
+	syms      *dynamicSymbols // Nil if the object couldn't be fetched
+	fetchedAt time.Time
+}
+
+func initFetchedSymbolsCache(size int) *fetchedSymbolsCache {
+	lru, _ := simplelru.NewLRU(size, nil)
+	return &fetchedSymbolsCache{lru: lru}
+}
+
+func (cache *fetchedSymbolsCache) Get(buildID string) (fetchedSymbols, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	fetched, ok := cache.lru.Get(buildID)
+	if !ok {
+		return fetchedSymbols{}, false
+	}
+	return fetched.(fetchedSymbols), true
+}
+
+func (cache *fetchedSymbolsCache) Add(buildID string, fetched fetchedSymbols) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.lru.Add(buildID, fetched)
+}
+
+func (cache *fetchedSymbolsCache) Purge() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.lru.Purge()
+}
+
+// fetchSOSymbols fetches the symbols of a stripped SO from the symbol server, given the error of reading the local
+// SO. If the SO is not stripped, no symbol server is configured or the symbols couldn't be fetched, the local error
+// is returned.
+func (soLoader *HostSymbolsLoader) fetchSOSymbols(localErr error) (*dynamicSymbols, error) {
+	var noSymsErr *noSymbolsError
+	if soLoader.fetchedCache == nil || !errors.As(localErr, &noSymsErr) || noSymsErr.local.BuildID == "" {
+		return nil, localErr
+	}
+	buildID := noSymsErr.local.BuildID
+	now := soLoader.timeSource().Now()
+	fetched, ok := soLoader.fetchedCache.Get(buildID)
+	if !ok || (fetched.syms == nil && now.Sub(fetched.fetchedAt) >= fetchFailureTTL) {
+		fetched = fetchedSymbols{syms: soLoader.fetchBuildIDSymbols(buildID), fetchedAt: now}
+		soLoader.fetchedCache.Add(buildID, fetched)
+	}
+	if fetched.syms == nil {
+		return nil, localErr
+	}
+	return noSymsErr.local.withFetchedSymbols(fetched.syms), nil
+}
+
+// fetchBuildIDSymbols fetches and parses the object of the given build ID from the symbol server.
+// It returns nil if the object couldn't be fetched, or if it is not of the requested build ID.
+func (soLoader *HostSymbolsLoader) fetchBuildIDSymbols(buildID string) *dynamicSymbols {
+	content, err := soLoader.config.SymbolServer.FetchObject(buildID)
+	if err != nil {
+		soLoader.stats.SymbolServerFailures.Increment()
+		return nil
+	}
+	// The object is read like the local SOs, so it is bound by the same symbols cap
+	syms, err := readFirstDynamicSymbols(bytes.NewReader(content), soLoader.readOpts)
+	if err != nil || syms.BuildID != buildID {
+		soLoader.stats.SymbolServerFailures.Increment()
+		return nil
+	}
+	soLoader.stats.SymbolServerFetches.Increment()
+	return syms
+}
+
+// withFetchedSymbols returns the metadata of the stripped SO with the symbols fetched for it from the symbol server.
+// Only the symbols are taken from the fetched object, and the rest of the metadata (e.g. the packer, the W^X
+// segments, the interpreter and the constructors) is the one read from the local file, as the build ID which the
+// object was fetched by is read from the local file too, so it may be forged. The symbols maps are shared.
+func (syms *dynamicSymbols) withFetchedSymbols(fetched *dynamicSymbols) *dynamicSymbols {
+	return &dynamicSymbols{
+		Exported:             fetched.Exported,
+		Imported:             fetched.Imported,
+		ExportedInfo:         fetched.ExportedInfo,
+		ImportedInfo:         fetched.ImportedInfo,
+		FuncRanges:           fetched.FuncRanges,
+		Truncated:            fetched.Truncated,
+		Packer:               syms.Packer,
+		Interpreter:          syms.Interpreter,
+		Soname:               syms.Soname,
+		Notes:                syms.Notes,
+		BuildID:              syms.BuildID,
+		WXSegments:           syms.WXSegments,
+		DynamicTags:          syms.DynamicTags,
+		Constructors:         syms.Constructors,
+		Interp:               syms.Interp,
+		CodeEntropy:          syms.CodeEntropy,
+		Relocations:          syms.Relocations,
+		interpreterUndecided: syms.interpreterUndecided,
+	}
+}
@@ -0,0 +1,138 @@
+package sharedobjs
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"errors"
+)
+
+// SymbolSource is an alternative source of the exported symbols of SOs, consulted after their dynamic symbols table
+// (see HostSymbolsLoaderConfig.SymbolSources), e.g. the DWARF debug information of SOs with few dynamic symbols
+type SymbolSource interface {
+	// Name identifies the source in the information of the symbols it supplies (see SymbolInfo.Source)
+	Name() string
+	// ExportedSymbols returns the symbols of the ELF file in the given path. Files for which the source has no
+	// symbols (e.g. with no debug information) have no symbols, and are not an error.
+	ExportedSymbols(path string) ([]SymbolInfo, error)
+}
+
+// consultSymbolSources adds the symbols supplied by the symbol sources of the loader to the symbols read from the
+// dynamic symbols table of the SO in the given path, in the order of the sources. Symbols which were already read
+// from the table or from an earlier source are kept as they were read. SOs with no dynamic symbols table get the
+// symbols of the sources only. Other errors of reading the table, and SOs which got no symbols, are returned as
+// they are, so the rest of the fallback chain is consulted.
+func (soLoader *HostSymbolsLoader) consultSymbolSources(path string, syms *dynamicSymbols, tableErr error) (
+	*dynamicSymbols, error) {
+	if tableErr != nil && !errors.Is(tableErr, elf.ErrNoSymbols) {
+		return nil, tableErr
+	}
+	merged := syms
+	var noSymsErr *noSymbolsError
+	if merged == nil && errors.As(tableErr, &noSymsErr) {
+		// SOs with no dynamic symbols table keep the metadata read from their file
+		local := *noSymsErr.local
+		merged = &local
+	} else if merged == nil {
+		objSymbols := NewSOSymbols()
+		merged = &objSymbols
+	}
+	for _, source := range soLoader.config.SymbolSources {
+		supplied, err := source.ExportedSymbols(path)
+		if err != nil {
+			soLoader.stats.SymbolSourceFailures.Increment()
+			continue
+		}
+		for _, info := range supplied {
+			if merged.Exported[info.Name] {
+				continue
+			}
+			info.Source = source.Name()
+			merged.Exported[info.Name] = true
+			merged.ExportedInfo[info.Name] = info
+		}
+	}
+	if tableErr != nil && len(merged.Exported) == 0 {
+		return nil, tableErr
+	}
+	return merged, nil
+}
+
+// DWARFSymbolSource is a SymbolSource supplying the functions defined in the DWARF debug information (.debug_info)
+// of SOs, including functions which their dynamic symbols table doesn't have, e.g. of SOs built with hidden
+// visibility. Functions are named by their linkage name if they have one (as C++ functions do), so they are named
+// as their dynamic symbols are. Declarations and inlined instances, which have no code of their own, are skipped.
+type DWARFSymbolSource struct{}
+
+// Name returns the name of the DWARF source, "dwarf"
+func (DWARFSymbolSource) Name() string {
+	return "dwarf"
+}
+
+// ExportedSymbols returns the functions defined in the DWARF debug information of the ELF file in the given path
+func (DWARFSymbolSource) ExportedSymbols(path string) ([]SymbolInfo, error) {
+	file, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if file.Section(".debug_info") == nil && file.Section(".zdebug_info") == nil {
+		return nil, nil
+	}
+	data, err := file.DWARF()
+	if err != nil {
+		return nil, err
+	}
+	var symbols []SymbolInfo
+	reader := data.Reader()
+	for {
+		entry, err := reader.Next()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return symbols, nil
+		}
+		if entry.Tag != dwarf.TagSubprogram {
+			continue
+		}
+		name, _ := entry.Val(dwarf.AttrLinkageName).(string)
+		if name == "" {
+			name, _ = entry.Val(dwarf.AttrName).(string)
+		}
+		lowPC, ok := entry.Val(dwarf.AttrLowpc).(uint64)
+		if name == "" || !ok {
+			continue
+		}
+		bind := elf.STB_LOCAL
+		if external, _ := entry.Val(dwarf.AttrExternal).(bool); external {
+			bind = elf.STB_GLOBAL
+		}
+		info := SymbolInfo{Name: name, Bind: bind, Type: elf.STT_FUNC, Visibility: elf.STV_DEFAULT}
+		// The high PC is either the address after the function, or its size (an offset from the low PC)
+		switch highPC := entry.Val(dwarf.AttrHighpc).(type) {
+		case uint64:
+			if highPC > lowPC {
+				info.Size = highPC - lowPC
+			}
+		case int64:
+			if highPC > 0 {
+				info.Size = uint64(highPC)
+			}
+		}
+		setAddressSection(&info, file.Sections, lowPC)
+		symbols = append(symbols, info)
+	}
+}
+
+// setAddressSection sets the section of the symbol to the allocated section holding the given address, if any
+func setAddressSection(info *SymbolInfo, sections []*elf.Section, address uint64) {
+	for i, section := range sections {
+		if section.Flags&elf.SHF_ALLOC == 0 || address < section.Addr || address >= section.Addr+section.Size {
+			continue
+		}
+		info.Section = elf.SectionIndex(i)
+		info.SectionName = section.Name
+		info.ExecutableSection = section.Flags&elf.SHF_EXECINSTR != 0
+		return
+	}
+}
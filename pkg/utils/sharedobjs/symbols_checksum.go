@@ -0,0 +1,42 @@
+package sharedobjs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"sort"
+)
+
+// calcChecksum calculates a checksum of the symbols, which doesn't depend on the order of the symbols maps
+func (syms *dynamicSymbols) calcChecksum() []byte {
+	names := make([]string, 0, len(syms.Exported)+len(syms.Imported))
+	for sym := range syms.Exported {
+		names = append(names, "E:"+sym)
+	}
+	for sym := range syms.Imported {
+		names = append(names, "I:"+sym)
+	}
+	sort.Strings(names)
+	hash := sha256.New()
+	for _, name := range names {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+	}
+	return hash.Sum(nil)
+}
+
+// validateCachedSymbols reads the symbols of the SO again, and checks that they match the cached symbols of the
+// SO with the same ObjID.
+// On mismatch, the symbols read from the SO are returned, and the mismatch is counted in the loader stats.
+func (soLoader *HostSymbolsLoader) validateCachedSymbols(ctx context.Context, soInfo ObjInfo,
+	cachedSyms *dynamicSymbols) (*dynamicSymbols, error) {
+	syms, err := soLoader.readSOSymbols(ctx, soInfo)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(syms.checksum, cachedSyms.checksum) {
+		soLoader.stats.ChecksumMismatches.Increment()
+		return syms, nil
+	}
+	return cachedSyms, nil
+}
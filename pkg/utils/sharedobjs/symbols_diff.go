@@ -0,0 +1,44 @@
+package sharedobjs
+
+import (
+	"sort"
+)
+
+// ExportedSymbolsDiffer is implemented by loaders which can compare the exported symbols of two SOs, e.g. of two
+// versions of a library when auditing an upgrade, or of a SO and its known-good copy.
+type ExportedSymbolsDiffer interface {
+	DiffExportedSymbols(a, b ObjInfo) (added, removed []string, err error)
+}
+
+// DiffExportedSymbols returns the symbols exported by b and not by a (added), and the symbols exported by a and not
+// by b (removed), both sorted. The symbols of both SOs are read from the lru, or loaded to it from their ELF files.
+func (soLoader *HostSymbolsLoader) DiffExportedSymbols(a, b ObjInfo) ([]string, []string, error) {
+	symsA, err := soLoader.loadSOSymbols(a)
+	if err != nil {
+		return nil, nil, err
+	}
+	symsB, err := soLoader.loadSOSymbols(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	added, removed := diffSymbols(symsA.Exported, symsB.Exported)
+	return added, removed, nil
+}
+
+// diffSymbols returns the sorted symbols of b missing from a, and of a missing from b
+func diffSymbols(a, b map[string]bool) ([]string, []string) {
+	added, removed := []string{}, []string{}
+	for sym := range b {
+		if !a[sym] {
+			added = append(added, sym)
+		}
+	}
+	for sym := range a {
+		if !b[sym] {
+			removed = append(removed, sym)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,98 @@
+package sharedobjs
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// readSymbolsTable reads the dynamic symbols of the ELF file, like DynamicSymbols, and returns whether the table was
+// truncated. If the table has more than maxSymbols symbols (and maxSymbols is not 0), only its first maxSymbols
+// symbols are read, with no version - the versions table is indexed by the whole symbols table, so it isn't read.
+func readSymbolsTable(file *elf.File, maxSymbols int) ([]elf.Symbol, bool, error) {
+	section := file.SectionByType(elf.SHT_DYNSYM)
+	if maxSymbols <= 0 || section == nil || section.Entsize == 0 {
+		symbols, err := file.DynamicSymbols()
+		return symbols, false, err
+	}
+	// The first entry of the table is the null symbol, which is not counted (as DynamicSymbols omits it)
+	if section.Size/section.Entsize <= uint64(maxSymbols)+1 {
+		symbols, err := file.DynamicSymbols()
+		return symbols, false, err
+	}
+	symbols, err := readFirstSymbols(file, section, maxSymbols)
+	if err != nil {
+		return nil, false, err
+	}
+	return symbols, true, nil
+}
+
+// readFirstSymbols reads the given amount of symbols from the start of the symbols table section, skipping its null
+// symbol. The table must have at least this amount of symbols.
+func readFirstSymbols(file *elf.File, section *elf.Section, count int) ([]elf.Symbol, error) {
+	var entrySize uint64
+	switch file.Class {
+	case elf.ELFCLASS64:
+		entrySize = elf.Sym64Size
+	case elf.ELFCLASS32:
+		entrySize = elf.Sym32Size
+	default:
+		return nil, fmt.Errorf("unsupported ELF class %v", file.Class)
+	}
+	if section.Entsize < entrySize {
+		return nil, fmt.Errorf("symbols table entry size %d is too small", section.Entsize)
+	}
+	if int(section.Link) >= len(file.Sections) {
+		return nil, errors.New("symbols table has no strings table")
+	}
+	strtab, err := file.Sections[section.Link].Data()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load strings table: %w", err)
+	}
+
+	entries := make([]byte, uint64(count+1)*section.Entsize)
+	if _, err := section.ReadAt(entries, 0); err != nil {
+		return nil, fmt.Errorf("cannot load symbols table: %w", err)
+	}
+	symbols := make([]elf.Symbol, count)
+	for i := range symbols {
+		entry := bytes.NewReader(entries[uint64(i+1)*section.Entsize:])
+		var symbol elf.Symbol
+		var name uint32
+		switch file.Class {
+		case elf.ELFCLASS64:
+			var sym elf.Sym64
+			if err := binary.Read(entry, file.ByteOrder, &sym); err != nil {
+				return nil, err
+			}
+			name = sym.Name
+			symbol = elf.Symbol{Info: sym.Info, Other: sym.Other, Section: elf.SectionIndex(sym.Shndx),
+				Value: sym.Value, Size: sym.Size}
+		case elf.ELFCLASS32:
+			var sym elf.Sym32
+			if err := binary.Read(entry, file.ByteOrder, &sym); err != nil {
+				return nil, err
+			}
+			name = sym.Name
+			symbol = elf.Symbol{Info: sym.Info, Other: sym.Other, Section: elf.SectionIndex(sym.Shndx),
+				Value: uint64(sym.Value), Size: uint64(sym.Size)}
+		}
+		symbol.Name = tableString(strtab, name)
+		symbols[i] = symbol
+	}
+	return symbols, nil
+}
+
+// tableString returns the NUL terminated string in the given offset of the strings table
+func tableString(strtab []byte, offset uint32) string {
+	if uint64(offset) >= uint64(len(strtab)) {
+		return ""
+	}
+	str := strtab[offset:]
+	if end := bytes.IndexByte(str, 0); end >= 0 {
+		str = str[:end]
+	}
+	return string(str)
+}
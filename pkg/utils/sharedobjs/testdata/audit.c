@@ -0,0 +1,8 @@
+// Source of the audit.so fixture, which declares the DT_AUDIT and DT_DEPAUDIT dynamic tags, built with:
+// gcc -shared -fPIC -O0 -s -Wl,--audit,libaudit.so.1 -Wl,--depaudit,libdepaudit.so.1 -o audit.so audit.c
+#include <stdio.h>
+
+int exported_function(const char *message)
+{
+	return puts(message);
+}
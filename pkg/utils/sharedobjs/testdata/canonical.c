@@ -0,0 +1,13 @@
+// Source of the canonical fixture, a position dependent executable which takes the address of exported_function of
+// the unversioned symbols.so without defining it, so the undefined symbol has the address of its PLT entry as its
+// value, built with:
+// gcc -no-pie -fno-pic -O0 -s -o canonical canonical.c symbols.so
+#include <stdint.h>
+
+int exported_function(const char *message);
+
+int main(void)
+{
+	uintptr_t function = (uintptr_t)&exported_function;
+	return ((int (*)(const char *))function)("canonical");
+}
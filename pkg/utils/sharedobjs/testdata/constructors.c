@@ -0,0 +1,19 @@
+// Source of the constructors.so fixture, which declares two constructors in its init array (besides the frame_dummy
+// entry which GCC adds to every SO), built with:
+// gcc -shared -fPIC -O0 -s -o constructors.so constructors.c
+#include <stdio.h>
+
+__attribute__((constructor)) static void first_constructor(void)
+{
+	puts("first");
+}
+
+__attribute__((constructor)) static void second_constructor(void)
+{
+	puts("second");
+}
+
+int exported_function(const char *message)
+{
+	return puts(message);
+}
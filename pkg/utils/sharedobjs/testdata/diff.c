@@ -0,0 +1,29 @@
+// Source of the diff_v1.so and diff_v2.so fixtures, two versions of a library exporting different symbols, built
+// with:
+// gcc -shared -fPIC -O0 -s -o diff_v1.so diff.c
+// gcc -shared -fPIC -O0 -s -DV2 -o diff_v2.so diff.c
+int common_counter = 0;
+
+int common_function(int value)
+{
+	return value + common_counter;
+}
+
+#ifndef V2
+int legacy_function(int value)
+{
+	return value - 1;
+}
+
+int legacy_counter = 0;
+#else
+int added_function(int value)
+{
+	return value * 2;
+}
+
+int added_hook(int value)
+{
+	return value + 2;
+}
+#endif
@@ -0,0 +1,17 @@
+// Source of the dwarf fixture, a SO built with hidden visibility and with debug information, so most of its functions
+// are named only in its DWARF information, built with:
+// gcc -shared -fPIC -O0 -g -fvisibility=hidden -o dwarf dwarf.c
+static int local_helper(int value)
+{
+	return value * 2;
+}
+
+int hidden_helper(int value)
+{
+	return local_helper(value) + 1;
+}
+
+__attribute__((visibility("default"))) int dwarf_exported(int value)
+{
+	return hidden_helper(value);
+}
@@ -0,0 +1,12 @@
+// Source of the entropy.so fixture, whose code is mostly pseudo-random bytes (as the code of packed or encrypted SOs
+// is) besides an exported function, built with:
+// gcc -shared -fPIC -O0 -s -o entropy.so entropy.c
+asm(".section .text.random,\"ax\",@progbits\n"
+    ".set seed, 12345\n"
+    ".rept 16384\n"
+    ".set seed, (seed * 1103515245 + 12345) & 0x7fffffff\n"
+    ".byte (seed >> 16) & 0xff\n"
+    ".endr\n"
+    ".previous\n");
+
+int entropy_func(void) { return 0; }
@@ -0,0 +1,31 @@
+// Source of the exportver_host.so and exportver_container.so fixtures, two builds of a library defining versioned
+// symbols, as a host and a container may have different builds of the same library (e.g. glibc). The host build
+// defines copy_bytes in a compatibility version and in a newer default version, as glibc defines memcpy, and the
+// container build defines it in the old version only. Built with:
+// gcc -shared -fPIC -O0 -s -Wl,--version-script=exportver.map -o exportver_host.so exportver.c
+// gcc -shared -fPIC -O0 -s -DCONTAINER -Wl,--version-script=exportver.map -o exportver_container.so exportver.c
+#include <string.h>
+
+void fill_bytes(char *dest, char value, size_t size)
+{
+	memset(dest, value, size);
+}
+
+#ifdef CONTAINER
+void copy_bytes(char *dest, const char *src, size_t size)
+{
+	memmove(dest, src, size);
+}
+#else
+void copy_bytes_old(char *dest, const char *src, size_t size)
+{
+	memmove(dest, src, size);
+}
+__asm__(".symver copy_bytes_old,copy_bytes@LIBEXPORTVER_1.0");
+
+void copy_bytes_new(char *dest, const char *src, size_t size)
+{
+	memcpy(dest, src, size);
+}
+__asm__(".symver copy_bytes_new,copy_bytes@@LIBEXPORTVER_2.0");
+#endif
@@ -0,0 +1,10 @@
+// Source of the large.so fixture, which exports 512 generated functions (large_000 to large_777, numbered in
+// octal) besides importing puts, built with:
+// gcc -shared -fPIC -O0 -s -o large.so large.c
+#include <stdio.h>
+
+#define FUNC(n) void large_##n(void) { puts(#n); }
+#define FUNCS8(n) FUNC(n##0) FUNC(n##1) FUNC(n##2) FUNC(n##3) FUNC(n##4) FUNC(n##5) FUNC(n##6) FUNC(n##7)
+#define FUNCS64(n) FUNCS8(n##0) FUNCS8(n##1) FUNCS8(n##2) FUNCS8(n##3) FUNCS8(n##4) FUNCS8(n##5) FUNCS8(n##6) FUNCS8(n##7)
+
+FUNCS64(0) FUNCS64(1) FUNCS64(2) FUNCS64(3) FUNCS64(4) FUNCS64(5) FUNCS64(6) FUNCS64(7)
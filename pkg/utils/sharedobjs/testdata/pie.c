@@ -0,0 +1,17 @@
+// Source of the pie fixture, a position independent executable which exports its functions to the dynamic symbols
+// table, built with:
+// gcc -fPIE -pie -rdynamic -O0 -s -o pie pie.c
+// and of the interp fixture, which requests an interpreter outside of the libraries directories, built with:
+// gcc -fPIE -pie -rdynamic -O0 -s -Wl,--dynamic-linker=/tmp/.x/ld.so -o interp pie.c
+#include <stdio.h>
+
+int pie_function(int value)
+{
+    return value * 2;
+}
+
+int main(void)
+{
+    printf("%d\n", pie_function(21));
+    return 0;
+}
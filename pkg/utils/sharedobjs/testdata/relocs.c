@@ -0,0 +1,21 @@
+// Source of the relocs fixture, a position dependent executable with a copy relocation of exported_counter of
+// symbols.so, and an irelative relocation of its own indirect function, built with:
+// gcc -no-pie -fno-pic -O0 -s -o relocs relocs.c symbols.so
+extern int exported_counter;
+
+static int implementation(void)
+{
+	return exported_counter;
+}
+
+static int (*resolve_indirect(void))(void)
+{
+	return implementation;
+}
+
+int indirect(void) __attribute__((ifunc("resolve_indirect")));
+
+int main(void)
+{
+	return indirect();
+}
@@ -0,0 +1,23 @@
+// Source of the sizes fixture, a SO exporting a hollow stub next to a function of real size, built with:
+// gcc -shared -fPIC -O2 -o sizes sizes.c
+#include <string.h>
+
+// A hollow stub, as hooks replacing a function often are, whose code is a single return instruction
+void hollow_stub(void)
+{
+}
+
+int checksum(const char *data)
+{
+    int sum = 0;
+    size_t length = strlen(data);
+    for (size_t i = 0; i < length; i++) {
+        sum = sum * 31 + data[i];
+        if (sum < 0) {
+            sum = -sum;
+        }
+    }
+    return sum;
+}
+
+int sized_counter = 1;
@@ -0,0 +1,15 @@
+// Source of the symbols.so fixture, built with:
+// gcc -shared -fPIC -O0 -s -Wl,-z,lazy -o symbols.so symbols.c
+// and of the runpath.so fixture, built with:
+// gcc -shared -fPIC -O0 -s -Wl,-z,lazy -Wl,--enable-new-dtags,-rpath,'$ORIGIN/../lib:${ORIGIN}/plugins:/opt/$LIB/${PLATFORM}' -Wl,-soname,librunpath.so.1 -o runpath.so symbols.c
+#include <stdio.h>
+#include <stdlib.h>
+
+int exported_counter = 0;
+
+int exported_function(const char *message)
+{
+	exported_counter++;
+	puts(message);
+	return exported_counter + (getenv(message) != NULL);
+}
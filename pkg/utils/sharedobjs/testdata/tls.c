@@ -0,0 +1,10 @@
+// Source of the tls.so fixture, which exports thread-local variables (the first of which has a value of 0, its offset
+// in the TLS block) besides a plain function and variable, built with:
+// gcc -shared -fPIC -O0 -s -o tls.so tls.c
+__thread int tls_state = 1;
+__thread int tls_counter;
+int plain_variable = 2;
+
+int plain_function(void) {
+    return tls_state + tls_counter + plain_variable;
+}
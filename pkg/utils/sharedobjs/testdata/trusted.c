@@ -0,0 +1,17 @@
+// Source of the trusted.so fixture, which carries a trust marker note, built with:
+// gcc -shared -fPIC -O0 -s -o trusted.so trusted.c
+#include <stdio.h>
+
+// The trust marker note - an ELF note owned by "tracee" of type 1, with no description
+__attribute__((section(".note.tracee.trusted"), aligned(4), used))
+static const struct {
+	unsigned int namesz;
+	unsigned int descsz;
+	unsigned int type;
+	char name[8];
+} trusted_note = {sizeof("tracee"), 0, 1, "tracee"};
+
+int exported_function(const char *message)
+{
+	return puts(message);
+}
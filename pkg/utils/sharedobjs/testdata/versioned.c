@@ -0,0 +1,16 @@
+// Source of the versioned.so fixture, which imports an old version of memcpy (as downgrade attacks do) and symbols of
+// newer versions, built with:
+// gcc -shared -fPIC -O0 -s -o versioned.so versioned.c
+#include <stdio.h>
+#include <string.h>
+
+// Bind memcpy to its version before GLIBC_2.14, which has memmove semantics
+__asm__(".symver memcpy,memcpy@GLIBC_2.2.5");
+
+int exported_function(char *destination, const char *source, size_t size)
+{
+	memcpy(destination, source, size);
+	puts(destination);
+	explicit_bzero(destination, size);
+	return 0;
+}
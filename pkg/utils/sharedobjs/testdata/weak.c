@@ -0,0 +1,14 @@
+// Source of the weak.so fixture, which defines both weak and strong exported functions, built with:
+// gcc -shared -fPIC -O0 -s -Wl,-soname,libweak.so.1 -o weak.so weak.c
+#include <stdlib.h>
+
+// A default implementation, which other SOs may override with a strong definition
+__attribute__((weak)) void *allocate(size_t size)
+{
+	return malloc(size);
+}
+
+void release(void *ptr)
+{
+	free(ptr);
+}
@@ -0,0 +1,11 @@
+// Source of the wx.so fixture, which has a segment which is both writable and executable, built with:
+// gcc -shared -fPIC -O0 -s -Wl,--no-warn-rwx-segments -o wx.so wx.c
+#include <stdio.h>
+
+// A function in a section which is writable and executable, which the linker maps to an RWX segment.
+// The section flags are given to the assembler, and the flags GCC appends are commented out.
+__attribute__((section(".wxtext,\"awx\",@progbits #")))
+int exported_function(const char *message)
+{
+	return puts(message);
+}
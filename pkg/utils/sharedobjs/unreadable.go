@@ -0,0 +1,53 @@
+package sharedobjs
+
+import (
+	"errors"
+	"io/fs"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+// unreadableCache is a lru of the errors of reading the SOs which can't be read because of missing permissions, so
+// they are classified once. The cache is safe for concurrent use.
+type unreadableCache struct {
+	lru   *simplelru.LRU
+	mutex sync.Mutex
+}
+
+func initUnreadableCache(size int) *unreadableCache {
+	lru, _ := simplelru.NewLRU(size, nil)
+	return &unreadableCache{lru: lru}
+}
+
+func (cache *unreadableCache) Get(objID ObjID) (error, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	err, ok := cache.lru.Get(objID)
+	if !ok {
+		return nil, false
+	}
+	return err.(error), true
+}
+
+func (cache *unreadableCache) Add(objID ObjID, err error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.lru.Add(objID, err)
+}
+
+func (cache *unreadableCache) Purge() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.lru.Purge()
+}
+
+// GetPermissionError returns the error of reading the SO if it failed because of missing permissions. The SO is
+// read only if it isn't cached, as its symbols or as its permission error.
+func (soLoader *HostSymbolsLoader) GetPermissionError(soInfo ObjInfo) error {
+	_, err := soLoader.loadSOSymbols(soInfo)
+	if err != nil && errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+	return nil
+}